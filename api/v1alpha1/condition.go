@@ -0,0 +1,90 @@
+package v1alpha1
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MaxConditionTransitions is the number of the latest transitions kept in the condition history.
+	MaxConditionTransitions = 10
+	// conditionProbeInterval is how often the LastProbeTime of an unchanged condition is written,
+	// so that the condition is known to be evaluated without updating the status at every reconciliation.
+	conditionProbeInterval = time.Minute
+)
+
+// nowFunc returns the current time, it is replaced in tests.
+var nowFunc = time.Now
+
+// ConditionTransition records a transition of a condition from one status to another.
+type ConditionTransition struct {
+	// Type of the condition.
+	Type string `json:"type"`
+	// Status of the condition after the transition.
+	Status corev1.ConditionStatus `json:"status"`
+	// Reason for the transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Time of the transition.
+	Time metav1.Time `json:"time"`
+}
+
+// conditionFields holds the fields shared by the conditions of all the kinds.
+type conditionFields struct {
+	typ                string
+	status             corev1.ConditionStatus
+	reason             string
+	message            string
+	lastTransitionTime metav1.Time
+	lastProbeTime      metav1.Time
+}
+
+// mergeCondition merges the new condition into the old one, which is nil if the condition is added.
+// The transition time is kept unless the status changes, and the transition is recorded in the history.
+// It returns the merged condition and whether it needs writing: the status, the reason or the message
+// has changed, or the probe time is stale.
+func mergeCondition(old *conditionFields, new conditionFields, history *[]ConditionTransition) (conditionFields, bool) {
+	if old == nil {
+		now := ConditionTime()
+		new.lastTransitionTime, new.lastProbeTime = now, now
+		recordTransition(history, new)
+		return new, true
+	}
+
+	new.lastProbeTime = ConditionTime(old.lastProbeTime, old.lastTransitionTime)
+	if new.status != old.status {
+		new.lastTransitionTime = ConditionTime(old.lastTransitionTime, old.lastProbeTime)
+		recordTransition(history, new)
+		return new, true
+	}
+	new.lastTransitionTime = old.lastTransitionTime
+	changed := new.reason != old.reason || new.message != old.message ||
+		new.lastProbeTime.Sub(old.lastProbeTime.Time) >= conditionProbeInterval
+	return new, changed
+}
+
+func recordTransition(history *[]ConditionTransition, c conditionFields) {
+	*history = append(*history, ConditionTransition{
+		Type:   c.typ,
+		Status: c.status,
+		Reason: c.reason,
+		Time:   c.lastTransitionTime,
+	})
+	if n := len(*history); n > MaxConditionTransitions {
+		*history = append([]ConditionTransition(nil), (*history)[n-MaxConditionTransitions:]...)
+	}
+}
+
+// ConditionTime returns the current time in seconds, as it is serialized, but not before any of the given times.
+// The timestamps of a condition never go backwards when the operator fails over to a node whose clock is behind.
+func ConditionTime(notBefore ...metav1.Time) metav1.Time {
+	now := metav1.NewTime(nowFunc()).Rfc3339Copy()
+	for _, t := range notBefore {
+		if now.Before(&t) {
+			now = t
+		}
+	}
+	return now
+}
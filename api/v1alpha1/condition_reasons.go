@@ -0,0 +1,149 @@
+package v1alpha1
+
+// The reasons of the conditions of rainbondcluster, rainbondpackage and rbdcomponent. A reason is a machine-readable
+// code in CamelCase that the UIs can branch on, while the message of the condition is for humans.
+// The values are part of the API, do not change them.
+const (
+	// ReasonInProgress means the check or the step is in progress.
+	ReasonInProgress = "InProgress"
+	// ReasonUnknownError is used if the error can't be classified.
+	ReasonUnknownError = "UnknownErr"
+)
+
+// Reasons of the prechecks of rainbondcluster.
+const (
+	ReasonDatabaseUnreachable          = "DatabaseFailed"
+	ReasonEtcdUnreachable              = "EtcdUnreachable"
+	ReasonImageRepoFailed              = "ImageRepoFailed"
+	ReasonDefaultImageRepoFailed       = "DefaultImageRepoFailed"
+	ReasonKubernetesVersionFailed      = "KubernetesVersionFailed"
+	ReasonUnsupportedKubernetesVersion = "UnsupportedKubernetesVersion"
+	ReasonKubernetesStatusFailed       = "KubernetesStatusFailed"
+	ReasonStorageFailed                = "StorageFailed"
+	// ReasonStorageNotRWX means the shared storage can't be mounted by the pods on different nodes.
+	ReasonStorageNotRWX             = "StorageNotRWX"
+	ReasonDNSFailed                 = "DNSFailed"
+	ReasonSentinelNotReady          = "SentinelNotReady"
+	ReasonContainerNetworkFailed    = "ContainerNetworkFailed"
+	ReasonInsufficientMemory        = "MemoryFailed"
+	ReasonInvalidPortRanges         = "InvalidPortRanges"
+	ReasonPortConflict              = "PortConflict"
+	ReasonGatewayPortsFailed        = "GatewayPortsFailed"
+	ReasonInvalidChaosNodeSelector  = "InvalidChaosNodeSelector"
+	ReasonListNodesFailed           = "ListNodesFailed"
+	ReasonNoChaosNodes              = "NoChaosNodes"
+	ReasonHostPortsFailed           = "HostPortsFailed"
+	ReasonHostPortConflict          = "HostPortConflict"
+	ReasonListRbdComponentFailed    = "ListRbdComponentFailed"
+	ReasonInsufficientRbdComponent  = "InsufficientRbdComponent"
+	ReasonRbdComponentReadyNotFound = "RbdComponentReadyNotFound"
+	ReasonRbdComponentNotReady      = "RbdComponentNotReady"
+	// ReasonEtcdInvalidConfig means the endpoints don't match the certificates, such as https without the certificates.
+	ReasonEtcdInvalidConfig = "EtcdInvalidConfig"
+	// ReasonEtcdAuthFailed means etcd rejected the client, such as the user of the client certificate not found.
+	ReasonEtcdAuthFailed = "EtcdAuthFailed"
+	// ReasonEtcdPrefixNotWritable means the keys under the prefixes written by the region can't be written.
+	ReasonEtcdPrefixNotWritable = "EtcdPrefixNotWritable"
+	// ReasonDatabaseNotFound means the database doesn't exist, and can't be created by the user.
+	ReasonDatabaseNotFound = "DatabaseNotFound"
+	// ReasonDatabaseInsufficientPrivileges means the user lacks the privileges on the database to migrate the tables.
+	ReasonDatabaseInsufficientPrivileges = "DatabaseInsufficientPrivileges"
+	// ReasonDatabaseIncompatibleCharset means the charset of the database is not utf8 or utf8mb4.
+	ReasonDatabaseIncompatibleCharset = "DatabaseIncompatibleCharset"
+	// ReasonDNSNotResolved means the domain of the apps or the console can't be resolved.
+	ReasonDNSNotResolved = "DNSNotResolved"
+	// ReasonDNSMismatch means the domain of the apps or the console resolves to the addresses other than the
+	// gateway ingress IPs.
+	ReasonDNSMismatch = "DNSMismatch"
+	// ReasonDNSNotConfigured means the region is not running, since the domains don't resolve to the gateway.
+	ReasonDNSNotConfigured = "DNSNotConfigured"
+)
+
+// Reasons of the health checks of rainbondcluster and rbdcomponent.
+const (
+	// ReasonHealthCheckPassed means the probe got the expected response.
+	ReasonHealthCheckPassed = "HealthCheckPassed"
+	// ReasonHealthCheckUnreachable means the probe failed to connect or timed out.
+	ReasonHealthCheckUnreachable = "HealthCheckUnreachable"
+	// ReasonHealthCheckUnexpectedResponse means the probe got a response other than the expected one.
+	ReasonHealthCheckUnexpectedResponse = "HealthCheckUnexpectedResponse"
+)
+
+// Reasons of the conditions of rainbondpackage.
+const (
+	ReasonInitFailed            = "InitFailed"
+	ReasonClusterNotReady       = "ClusterNotReady"
+	ReasonDownloadFailed        = "DownloadFailed"
+	ReasonInsufficientDiskSpace = "InsufficientDiskSpace"
+	ReasonUnpackFailed          = "UnpackFailed"
+	ReasonListImagesFailed      = "ListImagesFailed"
+	ReasonImagePushFailed       = "ImagePushFailed"
+	// ReasonImagePushAuthFailed means the credential of the image repository is rejected.
+	ReasonImagePushAuthFailed = "ImagePushAuthFailed"
+	// ReasonImagesMissing means some images are missing in the image repository after they were pushed.
+	ReasonImagesMissing     = "ImagesMissing"
+	ReasonImageVerifyFailed = "ImageVerifyFailed"
+)
+
+// Reasons of the conditions of rbdcomponent.
+const (
+	ReasonReady                        = "Ready"
+	ReasonUnsupportedType              = "UnsupportedType"
+	ReasonClusterNotFound              = "ClusterNotFound"
+	ReasonConfigNotCompleted           = "ConfigNotCompleted"
+	ReasonConfigCompleted              = "ConfigCompleted"
+	ReasonPackageNotFound              = "PackageNotFound"
+	ReasonPackageReady                 = "PackageReady"
+	ReasonPackageNotReady              = "PackageNotReady"
+	ReasonPrerequisitesFailed          = "PrerequisitesFailed"
+	ReasonSetControllerReferenceFailed = "SetControllerReferenceFailed"
+	ReasonCreateResourcesFailed        = "ErrCreateResources"
+	ReasonDeleteResourceFailed         = "ErrDeleteResource"
+	ReasonAfterProcessFailed           = "ErrAfterProcess"
+	ReasonListPodsFailed               = "ErrListPods"
+	ReasonExpandVolumesFailed          = "ErrExpandVolumes"
+	ReasonExpansionNotAllowed          = "ExpansionNotAllowed"
+	ReasonStorageResizing              = "StorageResizing"
+	ReasonResized                      = "Resized"
+	// ReasonRetryBudgetExhausted means the component failed the readiness checks too many times in a row.
+	ReasonRetryBudgetExhausted = "RetryBudgetExhausted"
+	ReasonSpecChanged          = "SpecChanged"
+	ReasonResumed              = "Resumed"
+	// ReasonRecreating means the mandatory component is deleted, and will be recreated.
+	ReasonRecreating = "Recreating"
+	// ReasonNoCompatibleNodes means no node has an architecture supported by the image of the component.
+	ReasonNoCompatibleNodes = "NoCompatibleNodes"
+	// ReasonImagePullVerified means the image is pulled from the image hub on all the nodes.
+	ReasonImagePullVerified = "ImagePullVerified"
+	// ReasonImagePullFailed means the image can't be pulled from the image hub on some nodes.
+	ReasonImagePullFailed = "ImagePullFailed"
+	// ReasonGarbageCollecting means the image hub is read-only for the garbage collection.
+	ReasonGarbageCollecting = "GarbageCollecting"
+	// ReasonImageFallback means the pods run the fallback image since the image can't be pulled.
+	ReasonImageFallback = "ImageFallback"
+	// ReasonImageRecovered means the pods run the image again after running the fallback image.
+	ReasonImageRecovered = "ImageRecovered"
+	// ReasonInvalidSpec means the spec of the component or the cluster is invalid, the reconciliation stops until
+	// either of them is changed.
+	ReasonInvalidSpec = "InvalidSpec"
+	// ReasonEtcdQuotaApproaching means the database of etcd approaches the quota, it should be defragmented or the
+	// quota should be raised.
+	ReasonEtcdQuotaApproaching = "EtcdQuotaApproaching"
+	// ReasonEtcdStatusUnavailable means the size of the database can't be read from the members of etcd.
+	ReasonEtcdStatusUnavailable = "EtcdStatusUnavailable"
+	// ReasonMetricsAPIUnavailable means the metrics API, metrics.k8s.io, doesn't serve the metrics of the nodes and
+	// the pods.
+	ReasonMetricsAPIUnavailable = "MetricsAPIUnavailable"
+	// ReasonMetricsServerTooOld means the metrics-server serving the metrics API is older than the one Rainbond needs.
+	ReasonMetricsServerTooOld = "MetricsServerTooOld"
+	// ReasonMetricsServerAdopted means the metrics API is taken over by the metrics-server of Rainbond.
+	ReasonMetricsServerAdopted = "MetricsServerAdopted"
+)
+
+// Reasons of the migration of the stored custom resources.
+const (
+	// ReasonSchemaMigrated means the custom resources are migrated to the schema of the running operator.
+	ReasonSchemaMigrated = "SchemaMigrated"
+	// ReasonSchemaMigrationFailed means some custom resources failed to be migrated, and are kept as they are.
+	ReasonSchemaMigrationFailed = "SchemaMigrationFailed"
+)
@@ -0,0 +1,41 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestUpdateCondition(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	status := &RbdComponentStatus{}
+	assert.True(t, status.UpdateCondition(NewRbdComponentCondition(RbdComponentReady, corev1.ConditionFalse, "Pending", "")))
+
+	// unchanged, the probe time is not written until it is stale.
+	now = now.Add(10 * time.Second)
+	assert.False(t, status.UpdateCondition(NewRbdComponentCondition(RbdComponentReady, corev1.ConditionFalse, "Pending", "")))
+	now = now.Add(time.Minute)
+	assert.True(t, status.UpdateCondition(NewRbdComponentCondition(RbdComponentReady, corev1.ConditionFalse, "Pending", "")))
+	_, condition := status.GetCondition(RbdComponentReady)
+	assert.Equal(t, now, condition.LastProbeTime.Time)
+	assert.Equal(t, now.Add(-70*time.Second), condition.LastTransitionTime.Time)
+
+	// the clock of the operator goes backwards.
+	transition := now
+	now = now.Add(-time.Hour)
+	assert.True(t, status.UpdateCondition(NewRbdComponentCondition(RbdComponentReady, corev1.ConditionTrue, ReasonReady, "")))
+	_, condition = status.GetCondition(RbdComponentReady)
+	assert.Equal(t, transition, condition.LastTransitionTime.Time)
+	assert.Len(t, status.ConditionHistory, 2)
+
+	for i := 0; i < MaxConditionTransitions; i++ {
+		status.UpdateCondition(NewRbdComponentCondition(RbdComponentHalted, corev1.ConditionStatus([]string{"True", "False"}[i%2]), "", ""))
+	}
+	assert.Len(t, status.ConditionHistory, MaxConditionTransitions)
+	assert.Equal(t, string(RbdComponentHalted), status.ConditionHistory[0].Type)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageSyncPhase is the phase of an image being synchronized.
+type ImageSyncPhase string
+
+const (
+	// ImageSyncPhasePending means the image is waiting to be synchronized.
+	ImageSyncPhasePending ImageSyncPhase = "Pending"
+	// ImageSyncPhaseSyncing means the image is being synchronized.
+	ImageSyncPhaseSyncing ImageSyncPhase = "Syncing"
+	// ImageSyncPhaseSynced means the image has been synchronized into the image hub.
+	ImageSyncPhaseSynced ImageSyncPhase = "Synced"
+	// ImageSyncPhaseFailed means the last synchronization failed, it will be retried later.
+	ImageSyncPhaseFailed ImageSyncPhase = "Failed"
+)
+
+// ImageBundle is an image tarball uploaded to a node.
+type ImageBundle struct {
+	// Path is the absolute path of the image tarball on the node.
+	Path string `json:"path"`
+	// NodeName is the node where the image tarball is located.
+	NodeName string `json:"nodeName"`
+}
+
+// ImageSyncSpec defines the desired state of ImageSync
+type ImageSyncSpec struct {
+	// Images are the upstream images to be mirrored into the image hub, such as
+	// the images of plugins, runtimes and services.
+	// +optional
+	Images []string `json:"images,omitempty"`
+	// Mirror is a reachable image repository that replaces the repository of the
+	// upstream images, such as registry.cn-hangzhou.aliyuncs.com/goodrain.
+	// +optional
+	Mirror string `json:"mirror,omitempty"`
+	// MirrorCredentialSecretName is the name of the secret in the same namespace that contains the username and
+	// the password of the mirror with the keys username and password.
+	// +optional
+	MirrorCredentialSecretName string `json:"mirrorCredentialSecretName,omitempty"`
+	// Bundles are the uploaded image tarballs to be pushed into the image hub.
+	// +optional
+	Bundles []ImageBundle `json:"bundles,omitempty"`
+	// Interval is how often the images are synchronized again, 24h by default.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// JobImage is the image of the jobs that synchronize images, docker:20.10 in the image repository of rainbond
+	// by default.
+	// +optional
+	JobImage string `json:"jobImage,omitempty"`
+}
+
+// ImageSyncImageStatus is the synchronization status of an image.
+type ImageSyncImageStatus struct {
+	// Name is the upstream image or the path of the image tarball.
+	Name string `json:"name"`
+	// Target is the image in the image hub.
+	// +optional
+	Target string `json:"target,omitempty"`
+	// Phase of the image.
+	Phase ImageSyncPhase `json:"phase"`
+	// LastSyncTime is the last time the image was synchronized successfully.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// LastAttemptTime is the last time the synchronization was started.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+	// Message is a human readable message indicating details about the last synchronization.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ImageSyncStatus defines the observed state of ImageSync
+type ImageSyncStatus struct {
+	// Images contains the synchronization status of each image.
+	// +optional
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	Images []ImageSyncImageStatus `json:"images,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+	// The number of images that have been synchronized.
+	SyncedNumber int32 `json:"syncedNumber"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Synced",type=integer,JSONPath=`.status.syncedNumber`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageSync is the Schema for the imagesyncs API
+type ImageSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageSyncSpec   `json:"spec,omitempty"`
+	Status ImageSyncStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ImageSyncList contains a list of ImageSync
+type ImageSyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageSync `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageSync{}, &ImageSyncList{})
+}
+
+// GetImage returns the status of the given image.
+func (in *ImageSyncStatus) GetImage(name string) *ImageSyncImageStatus {
+	for i := range in.Images {
+		if in.Images[i].Name == name {
+			return &in.Images[i]
+		}
+	}
+	return nil
+}
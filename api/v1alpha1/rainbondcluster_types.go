@@ -18,9 +18,11 @@ package v1alpha1
 
 import (
 	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -57,6 +59,34 @@ const (
 	RainbondClusterConditionTypeContainerNetwork  = "ContainerNetwork"
 	RainbondClusterConditionTypeRunning           = "Running"
 	RainbondClusterConditionTypeMemory            = "Memory"
+	RainbondClusterConditionTypeGatewayPorts      = "GatewayPorts"
+	RainbondClusterConditionTypeChaosNodes        = "ChaosNodes"
+	// Deprecated: RainbondClusterConditionTypeEtcd is replaced by RainbondClusterConditionTypeEtcdReachable.
+	RainbondClusterConditionTypeEtcd = "Etcd"
+	// RainbondClusterConditionTypeEtcdReachable means the external etcd is reachable with the endpoints and the
+	// certificates in etcdConfig, and the prefixes of the keys of the region are writable.
+	RainbondClusterConditionTypeEtcdReachable = "EtcdReachable"
+	// RainbondClusterConditionTypeHostPorts means the ports of the host network listened by rbd-gateway and rbd-node
+	// are not used by the rainbonds installed before in the other namespaces on the same nodes.
+	RainbondClusterConditionTypeHostPorts = "HostPorts"
+	// RainbondClusterConditionTypeGatewayUDPPorts means the udp ports of rbd-gateway are not used by the other pods
+	// on the gateway nodes. It is checked all the time, since the pods may be scheduled after the installation.
+	RainbondClusterConditionTypeGatewayUDPPorts = "GatewayUDPPorts"
+	// RainbondClusterConditionTypeDNSConfigured means the wildcard domain of the apps and the domain of the console
+	// resolve to the gateway ingress IPs. The message tells the mismatches otherwise.
+	RainbondClusterConditionTypeDNSConfigured = "DNSConfigured"
+	// RainbondClusterConditionTypeRegionAPIHealthy means rbd-api responds to its health endpoint.
+	RainbondClusterConditionTypeRegionAPIHealthy = "RegionAPIHealthy"
+	// RainbondClusterConditionTypeGatewayHealthy means rbd-gateway serves the default backend.
+	RainbondClusterConditionTypeGatewayHealthy = "GatewayHealthy"
+	// RainbondClusterConditionTypeImageHubHealthy means the image hub can be pulled from as rbd-chaos does.
+	RainbondClusterConditionTypeImageHubHealthy = "ImageHubHealthy"
+	// RainbondClusterConditionTypeDependencyHealthy means the dependencies of the region, the region database,
+	// etcd and the image hub, are reachable. The message names the unreachable ones.
+	RainbondClusterConditionTypeDependencyHealthy = "DependencyHealthy"
+	// RainbondClusterConditionTypeSchemaMigration means the stored custom resources are migrated to the schema of
+	// the running operator. It is only present once a migration failed.
+	RainbondClusterConditionTypeSchemaMigration = "SchemaMigration"
 )
 
 // RainbondClusterCondition contains condition information for rainbondcluster.
@@ -85,6 +115,102 @@ type ImageHub struct {
 	Namespace string `json:"namespace,omitempty"`
 	Username  string `json:"username,omitempty"`
 	Password  string `json:"password,omitempty"`
+	// GarbageCollection removes the layers no longer referenced from the image hub periodically.
+	// It only works for the image hub managed by the operator.
+	// +optional
+	GarbageCollection *ImageHubGarbageCollection `json:"garbageCollection,omitempty"`
+	// NodeTrust installs the certificate of the image hub into the certs.d of docker, and of containerd whose
+	// config_path includes /etc/containerd/certs.d, on all the nodes by the daemonset rbd-hub-trust, then verifies
+	// each node pulls from the image hub. It changes the configuration of the container runtimes of the nodes, so it
+	// is disabled by default. It only works for the image hub managed by the operator.
+	// +optional
+	NodeTrust bool `json:"nodeTrust,omitempty"`
+	// ObjectStorage stores the images of the image hub in an S3 compatible object storage instead of the RWX
+	// volume. The volume is kept once it is switched to the object storage, the images in it are not migrated.
+	// It only works for the image hub managed by the operator.
+	// +optional
+	ObjectStorage *ImageHubObjectStorage `json:"objectStorage,omitempty"`
+	// Replication copies the images of the image hub to a secondary registry periodically, such as the registry of
+	// the DR site or of a cloud, so the images of the tenants are not lost along with the node of the image hub.
+	// It only works for the image hub managed by the operator.
+	// +optional
+	Replication *ImageHubReplication `json:"replication,omitempty"`
+}
+
+// ImageHubObjectStorage defines the S3 compatible object storage of the image hub. The blobs are served through
+// the image hub rather than redirected to the object storage, so the nodes needn't reach it.
+type ImageHubObjectStorage struct {
+	// Endpoint is the url of the S3 compatible service, such as http://minio.minio:9000.
+	// The endpoint of AWS S3 in the region is used if it is not specified.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// Region of the bucket, us-east-1 by default.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Bucket is the name of the bucket that stores the images, which must exist.
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+	// RootDirectory is the prefix of the keys of the images in the bucket, the root of the bucket by default.
+	// +optional
+	RootDirectory string `json:"rootDirectory,omitempty"`
+	// CredentialSecretName is the name of the secret in the same namespace that contains the accessKey and
+	// the secretKey of the bucket.
+	// +kubebuilder:validation:MinLength=1
+	CredentialSecretName string `json:"credentialSecretName"`
+	// InsecureSkipVerify skips the verification of the certificate of the endpoint, such as a self-signed one.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// ImageHubGarbageCollection defines the CronJob that runs the garbage collection of the image hub.
+// The image hub is read-only while the garbage collection runs, so the pushes fail during the window. The registry
+// reads the read-only switch from the environment at the start, so the Deployment of the image hub is rolled out
+// twice per garbage collection, once to become read-only and once to become writable again.
+type ImageHubGarbageCollection struct {
+	// Schedule in Cron format, 0 2 * * 0 by default.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// DeleteUntagged removes the manifests that are not referenced by any tag, along with their layers.
+	// Only the layers not referenced by any manifest are removed if it is false.
+	// +optional
+	DeleteUntagged bool `json:"deleteUntagged,omitempty"`
+	// MaxReadOnlyDuration is the longest time the image hub is kept read-only by a garbage collection,
+	// which fails if it has not completed by then. 1h by default.
+	// +optional
+	MaxReadOnlyDuration *metav1.Duration `json:"maxReadOnlyDuration,omitempty"`
+	// HistoryLimit is the number of the finished garbage collection jobs to keep, 3 by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+}
+
+// ImageHubReplication defines the CronJob that copies the images of the image hub to a secondary registry. Only the
+// blobs missing in the secondary registry are copied, and the images removed from the image hub are kept in it.
+type ImageHubReplication struct {
+	// Registry is the domain of the secondary registry, such as registry.example.com:5000.
+	// +kubebuilder:validation:MinLength=1
+	Registry string `json:"registry"`
+	// Namespace is the path prepended to the repositories in the secondary registry, such as rainbond/dr.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// CredentialSecretName is the name of the secret in the same namespace that contains the username and
+	// the password of the secondary registry. The registry is accessed anonymously if it is not specified.
+	// +optional
+	CredentialSecretName string `json:"credentialSecretName,omitempty"`
+	// InsecureSkipVerify skips the verification of the certificate of the secondary registry, and falls back to
+	// http if it doesn't serve https.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// Schedule in Cron format, */15 * * * * by default.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// Image is the image of skopeo that copies the images, skopeo:v1.9.2 in the image repository of rainbond by default.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// HistoryLimit is the number of the finished replication jobs to keep, 3 by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
 }
 
 // Database defines the connection information of database.
@@ -104,6 +230,117 @@ type EtcdConfig struct {
 	SecretName string `json:"secretName,omitempty"`
 }
 
+// EtcdCompactionMode is the mode of the auto compaction of etcd.
+type EtcdCompactionMode string
+
+const (
+	// EtcdCompactionPeriodic keeps the revisions of the latest retention window, such as 1h.
+	EtcdCompactionPeriodic EtcdCompactionMode = "periodic"
+	// EtcdCompactionRevision keeps the latest number of revisions, such as 10000.
+	EtcdCompactionRevision EtcdCompactionMode = "revision"
+)
+
+// EtcdMaintenance defines the maintenance of the etcd managed by the operator, which becomes read-only once its
+// database exceeds the quota. The compaction drops the old revisions, while the space they took is only freed by
+// the defragmentation.
+type EtcdMaintenance struct {
+	// AutoCompactionMode is the mode of the auto compaction, periodic by default.
+	// +kubebuilder:validation:Enum=periodic;revision
+	// +optional
+	AutoCompactionMode EtcdCompactionMode `json:"autoCompactionMode,omitempty"`
+	// AutoCompactionRetention is the window of the periodic compaction, such as 1h or 30m, the number is in hours,
+	// or the number of the revisions to keep for the revision compaction. 1 by default.
+	// +optional
+	AutoCompactionRetention string `json:"autoCompactionRetention,omitempty"`
+	// QuotaBackendBytes is the quota of the database of etcd, 4Gi by default.
+	// The EtcdSpaceAvailable condition of rbd-etcd turns false once the database approaches it.
+	// +optional
+	QuotaBackendBytes *resource.Quantity `json:"quotaBackendBytes,omitempty"`
+	// Defragmentation frees the space of the compacted revisions periodically, it is disabled by default.
+	// +optional
+	Defragmentation *EtcdDefragmentation `json:"defragmentation,omitempty"`
+}
+
+// EtcdDefragmentation defines the CronJob that defragments the members of etcd one by one, each member
+// blocks the reads and writes while it is defragmented.
+type EtcdDefragmentation struct {
+	// Schedule in Cron format, 0 3 * * * by default.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// HistoryLimit is the number of the finished defragmentation jobs to keep, 3 by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+}
+
+// PortRange defines a range of ports, both ends are included.
+type PortRange struct {
+	// Protocol of the ports, TCP or UDP. Defaults to TCP.
+	// +optional
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+	// The first port of the range.
+	Start int32 `json:"start"`
+	// The last port of the range. Defaults to Start.
+	// +optional
+	End int32 `json:"end,omitempty"`
+}
+
+// GetProtocol returns the protocol of the port range, TCP by default.
+func (in PortRange) GetProtocol() corev1.Protocol {
+	if in.Protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return in.Protocol
+}
+
+// GetEnd returns the last port of the range.
+func (in PortRange) GetEnd() int32 {
+	if in.End == 0 {
+		return in.Start
+	}
+	return in.End
+}
+
+// String returns the port range in the format of tcp:20000-30000.
+func (in PortRange) String() string {
+	return fmt.Sprintf("%s:%d-%d", strings.ToLower(string(in.GetProtocol())), in.Start, in.GetEnd())
+}
+
+// GatewayConfig defines the configuration of rbd-gateway.
+type GatewayConfig struct {
+	// PortRanges are the TCP/UDP port ranges of the applications exposed through rbd-gateway. The ports are
+	// published by a Service and allowed by a NetworkPolicy, both named rbd-gateway, and must not conflict with
+	// the ports of rbd-gateway itself or the node ports of the Services. At most 1000 ports are allowed.
+	// +optional
+	PortRanges []PortRange `json:"portRanges,omitempty"`
+	// DefaultCertificate is the name of a kubernetes.io/tls secret in the same namespace, which is mounted into
+	// rbd-gateway at /run/ssl/default as tls.crt and tls.key. rbd-gateway is restarted to reload the certificate
+	// once the secret changes.
+	// +optional
+	DefaultCertificate string `json:"defaultCertificate,omitempty"`
+	// Sysctls are the kernel parameters tuned for rbd-gateway, such as net.core.somaxconn,
+	// net.ipv4.ip_local_port_range and net.netfilter.nf_conntrack_max.
+	// rbd-gateway uses the host network, in which the sysctls can not be set by the pod security
+	// context, so they are set by a privileged init container and take effect on the whole node.
+	// +optional
+	Sysctls []corev1.Sysctl `json:"sysctls,omitempty"`
+	// DrainSeconds is how long rbd-gateway keeps serving after it is asked to stop, so that the load
+	// balancers in front of it have time to stop sending new connections to it. After that, rbd-gateway
+	// stops accepting new connections and waits for the in-flight requests to complete. Defaults to 15.
+	// +optional
+	DrainSeconds *int32 `json:"drainSeconds,omitempty"`
+	// MinReadySeconds is how long a new rbd-gateway must be ready before the next one is replaced
+	// during a rollout. Defaults to 10.
+	// +optional
+	MinReadySeconds *int32 `json:"minReadySeconds,omitempty"`
+	// UDPPorts are the ports of the host network reserved for the UDP rules of rbd-gateway, such as the ones of
+	// DNS and game servers. rbd-gateway listens on a port once a UDP rule of rbd-api uses it. The ports are
+	// declared as the host ports of rbd-gateway, and the GatewayUDPPorts condition turns false once they are
+	// used by the other pods on the gateway nodes.
+	// +optional
+	UDPPorts []int32 `json:"udpPorts,omitempty"`
+}
+
 // RainbondClusterSpec defines the desired state of RainbondCluster
 type RainbondClusterSpec struct {
 	// EnableHA is a highly available switch.
@@ -113,6 +350,10 @@ type RainbondClusterSpec struct {
 	RainbondImageRepository string `json:"rainbondImageRepository,omitempty"`
 	// Suffix of component default domain name
 	SuffixHTTPHost string `json:"suffixHTTPHost"`
+	// ConsoleDomain is the domain of the console served by rbd-gateway. It is verified to resolve to the gateway
+	// ingress IPs, as well as the wildcard domain of SuffixHTTPHost, before the region is running.
+	// +optional
+	ConsoleDomain string `json:"consoleDomain,omitempty"`
 	// Ingress IP addresses of rbd-gateway. If not specified,
 	// the GatewayVIP or IP of the node where the rbd-gateway is located will be used.
 	GatewayIngressIPs []string `json:"gatewayIngressIPs,omitempty"`
@@ -120,8 +361,26 @@ type RainbondClusterSpec struct {
 	GatewayVIP string `json:"gatewayVIP,omitempty"`
 	// Specify the nodes where the rbd-gateway will running.
 	NodesForGateway []*K8sNode `json:"nodesForGateway,omitempty"`
+	// Gateway is the configuration of rbd-gateway.
+	// +optional
+	Gateway *GatewayConfig `json:"gateway,omitempty"`
 	// Specify the nodes where the rbd-gateway will running.
 	NodesForChaos []*K8sNode `json:"nodesForChaos,omitempty"`
+	// ChaosNodeSelector selects the nodes where the rbd-chaos will running. If specified,
+	// the selected nodes will be labeled with rainbond.io/chaos, and NodesForChaos will be
+	// resolved from them, so that the source builds won't take up the resources of app nodes.
+	// +optional
+	ChaosNodeSelector *metav1.LabelSelector `json:"chaosNodeSelector,omitempty"`
+	// NodeSelectorForNode selects the nodes where the rbd-node-proxy will running, it runs on every node if not specified.
+	// The nodes labeled with rainbond.io/exclude-rbd-node=true are always excluded, such as the GPU or edge nodes
+	// not meant for Rainbond workloads.
+	// +optional
+	NodeSelectorForNode *metav1.LabelSelector `json:"nodeSelectorForNode,omitempty"`
+	// NodeRoles assigns the roles of Rainbond to the nodes by their names, instead of labeling the nodes by hand.
+	// The operator labels the assigned nodes, removes the labels it added from the nodes no longer assigned, and
+	// keeps the nodes of the components in sync.
+	// +optional
+	NodeRoles *NodeRoles `json:"nodeRoles,omitempty"`
 	// InstallMode is the mode of Rainbond cluster installation.
 	InstallMode InstallMode `json:"installMode,omitempty"`
 	// User-specified private image repository, replacing goodrain.me.
@@ -132,6 +391,10 @@ type RainbondClusterSpec struct {
 	// the etcd connection information that rainbond component will be used.
 	// rainbond-operator will create one if EtcdConfig is empty
 	EtcdConfig *EtcdConfig `json:"etcdConfig,omitempty"`
+	// EtcdMaintenance tunes the compaction and the defragmentation of the etcd managed by the operator,
+	// it is ignored if EtcdConfig is specified.
+	// +optional
+	EtcdMaintenance *EtcdMaintenance `json:"etcdMaintenance,omitempty"`
 	// define install rainbond version, This is usually image tag
 	InstallVersion string `json:"installVersion,omitempty"`
 	// CIVersion define builder and runner version
@@ -148,11 +411,441 @@ type RainbondClusterSpec struct {
 	SentinelImage string `json:"sentinelImage,omitempty"`
 
 	CacheMode string `json:"cacheMode,omitempty"`
+	// BuildCache is the configuration of the build cache of rbd-chaos.
+	// +optional
+	BuildCache *BuildCache `json:"buildCache,omitempty"`
 
 	// CoreComponent core components are required for initial installation.
 	CoreComponent CoreComponent `json:"coreComponent,omitempty"`
 	// AddonComponent Installation is optional.
 	AddonComponent AddonComponent `json:"addonComponent,omitempty"`
+
+	// LicenseSecretName is the name of the secret in the same namespace that contains the license file with the key license.
+	// +optional
+	LicenseSecretName string `json:"licenseSecretName,omitempty"`
+
+	// Telemetry reports the anonymous installation result to the endpoint, it is disabled by default.
+	// +optional
+	Telemetry *Telemetry `json:"telemetry,omitempty"`
+
+	// Notification is the webhook notified when the phase of the region changes or a component degrades.
+	// +optional
+	Notification *Notification `json:"notification,omitempty"`
+
+	// HealthCheck configures the periodic health check of the region once it is running.
+	// +optional
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	// Proxy is the proxy of the outbound requests, such as downloading the package, checking the images
+	// in the mirrors and the builds of rbd-chaos.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+
+	// CoreDNS resolves the default image hub goodrain.me and the builder domains to the gateway by the server
+	// block the operator manages in the coredns-custom ConfigMap, instead of the host aliases of the components.
+	// +optional
+	CoreDNS *CoreDNS `json:"coreDNS,omitempty"`
+
+	// SharedStorage is the storage shared by the rainbond components, known as grdata.
+	// +optional
+	SharedStorage *SharedStorage `json:"sharedStorage,omitempty"`
+
+	// Timezone is the timezone of the pods of the rainbond components, UTC by default.
+	// +optional
+	Timezone *Timezone `json:"timezone,omitempty"`
+
+	// InitContainerImage is the image of the init containers that wait for the dependencies of the rainbond
+	// components, such as rbd-db and rbd-etcd. It must provide the sh, nc and wget commands.
+	// Defaults to busybox in RainbondImageRepository.
+	// +optional
+	InitContainerImage string `json:"initContainerImage,omitempty"`
+
+	// WebsocketTLS enables TLS for the websocket of rbd-api, which serves the web terminal (webcli) and the logs
+	// of the applications. The websocket is exposed by rbd-gateway at the layer 4, so the connections are
+	// encrypted end to end, from the browser to rbd-api.
+	// +optional
+	WebsocketTLS *WebsocketTLS `json:"websocketTLS,omitempty"`
+
+	// ControlPlaneScheduling allows the core components to run on the control-plane nodes, which is common in
+	// small clusters.
+	// +optional
+	ControlPlaneScheduling *ControlPlaneScheduling `json:"controlPlaneScheduling,omitempty"`
+
+	// GPU enables the GPU scheduling of the applications. The operator creates the nvidia-device-plugin component,
+	// which deploys the NVIDIA device plugin on the GPU nodes, and deletes it once the GPU scheduling is disabled.
+	// The applications request the GPUs by the extended resource nvidia.com/gpu.
+	// +optional
+	GPU *GPU `json:"gpu,omitempty"`
+
+	// LogShipping ships the logs of the Rainbond components and the applications to Loki or Elasticsearch
+	// by the rbd-log-shipper component.
+	// +optional
+	LogShipping *LogShipping `json:"logShipping,omitempty"`
+
+	// ComponentDeletionPolicy decides what happens when a mandatory rbdcomponent, such as rbd-api or rbd-worker,
+	// is deleted. Honor disables the component, and Recreate recreates it with the same spec, keeping its volumes.
+	// Defaults to Honor.
+	// +kubebuilder:validation:Enum=Honor;Recreate
+	// +optional
+	ComponentDeletionPolicy ComponentDeletionPolicy `json:"componentDeletionPolicy,omitempty"`
+
+	// Suspend stops all the rainbond components in order, from the gateway to the data stores, keeping their data,
+	// to save the cost of an idle region or to maintain the nodes. The nfs-provisioner keeps running, since the
+	// storage is still mounted by the workloads of the tenants. Unset it to start them again in reverse order.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// AdditionalLabels are added to all the objects generated for the rainbond components, such as the workloads,
+	// their pods, the services and the claims, to satisfy the policies of the organization, such as the cost-center
+	// labels required by the admission controllers. The labels generated by the operator take precedence.
+	// +optional
+	AdditionalLabels map[string]string `json:"additionalLabels,omitempty"`
+
+	// AdditionalAnnotations are added to all the objects generated for the rainbond components, as well as
+	// AdditionalLabels, such as the annotations of the monitoring scrapes. The annotations generated by the
+	// operator take precedence.
+	// +optional
+	AdditionalAnnotations map[string]string `json:"additionalAnnotations,omitempty"`
+
+	// ServiceMesh declares the service mesh injecting the sidecars into the pods of the namespace, such as Istio
+	// or Linkerd. The operator disables the injection of the components the sidecars break, and excludes the
+	// traffic to them from the sidecars of the others.
+	// +optional
+	ServiceMesh *ServiceMesh `json:"serviceMesh,omitempty"`
+}
+
+// ComponentDeletionPolicy is the policy of the deletion of the mandatory rbdcomponents.
+type ComponentDeletionPolicy string
+
+const (
+	// ComponentDeletionHonor honors the deletion, the component is disabled.
+	ComponentDeletionHonor ComponentDeletionPolicy = "Honor"
+	// ComponentDeletionRecreate reverts the deletion, the component is recreated by the operator.
+	ComponentDeletionRecreate ComponentDeletionPolicy = "Recreate"
+)
+
+// ServiceMeshType is the type of the service mesh.
+type ServiceMeshType string
+
+const (
+	// ServiceMeshIstio is the Istio service mesh.
+	ServiceMeshIstio ServiceMeshType = "Istio"
+	// ServiceMeshLinkerd is the Linkerd service mesh.
+	ServiceMeshLinkerd ServiceMeshType = "Linkerd"
+)
+
+// ServiceMesh defines the service mesh in use and how the components coexist with it.
+type ServiceMesh struct {
+	// Type is the service mesh in use, Istio or Linkerd.
+	// +kubebuilder:validation:Enum=Istio;Linkerd
+	Type ServiceMeshType `json:"type"`
+	// UninjectedComponents are the names of the components whose sidecar injection is disabled. Defaults to
+	// rbd-etcd, whose peer traffic is broken by the sidecars. The components on the host network, such as
+	// rbd-gateway, and the jobs are never injected.
+	// +optional
+	UninjectedComponents []string `json:"uninjectedComponents,omitempty"`
+	// ExcludeOutboundPorts are the ports not intercepted by the sidecars of the injected components, in addition
+	// to the ports of rbd-etcd and rbd-db.
+	// +optional
+	ExcludeOutboundPorts []int32 `json:"excludeOutboundPorts,omitempty"`
+}
+
+// GPU defines the GPU nodes of the cluster.
+type GPU struct {
+	// Enabled is the switch of the GPU scheduling.
+	Enabled bool `json:"enabled"`
+	// NodeSelector selects the GPU nodes, defaults to rainbond.io/gpu: "true".
+	// The device plugin won't be deployed until one of the nodes is selected.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Image of the NVIDIA device plugin, defaults to k8s-device-plugin:v0.9.0 in RainbondImageRepository.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// LogShippingOutput is the type of the log storage.
+type LogShippingOutput string
+
+// These are valid outputs of the log shipping.
+const (
+	// LogShippingOutputLoki ships the logs to Loki.
+	LogShippingOutputLoki LogShippingOutput = "loki"
+	// LogShippingOutputElasticsearch ships the logs to Elasticsearch.
+	LogShippingOutputElasticsearch LogShippingOutput = "elasticsearch"
+)
+
+// LogShipping defines where the logs are shipped to.
+type LogShipping struct {
+	// Output is the type of the log storage.
+	// +kubebuilder:validation:Enum=loki;elasticsearch
+	Output LogShippingOutput `json:"output"`
+	// Endpoint is the URL of the log storage, such as http://loki.logging:3100 or https://elasticsearch.logging:9200.
+	Endpoint string `json:"endpoint"`
+	// CredentialSecretName is the secret with the keys username and password for the basic auth of the log storage.
+	// +optional
+	CredentialSecretName string `json:"credentialSecretName,omitempty"`
+	// Index is the prefix of the daily indexes of Elasticsearch, rainbond by default.
+	// +optional
+	Index string `json:"index,omitempty"`
+}
+
+// NodeRoles defines the nodes of the roles of Rainbond.
+type NodeRoles struct {
+	// Gateway are the names of the nodes running rbd-gateway, which are labeled with rainbond.io/gateway.
+	// NodesForGateway is resolved from them.
+	// +optional
+	Gateway []string `json:"gateway,omitempty"`
+	// Build are the names of the nodes running the builds of rbd-chaos, which are labeled with rainbond.io/chaos.
+	// NodesForChaos is resolved from them. It is ignored if ChaosNodeSelector is specified.
+	// +optional
+	Build []string `json:"build,omitempty"`
+	// Storage are the names of the nodes running the data stores, rbd-db, rbd-etcd, rbd-hub, rbd-monitor and
+	// nfs-provisioner, which are labeled with rainbond.io/storage. The pods of the data stores are restricted to them.
+	// +optional
+	Storage []string `json:"storage,omitempty"`
+}
+
+// ControlPlaneScheduling defines how the core components are scheduled on the control-plane nodes.
+type ControlPlaneScheduling struct {
+	// Enabled applies the tolerations of the control-plane taints to the core components.
+	Enabled bool `json:"enabled"`
+	// NodeSelector restricts the core components to the selected nodes if specified,
+	// such as node-role.kubernetes.io/master: "".
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Components are the names of the components scheduled on the control-plane nodes. Defaults to
+	// rbd-api, rbd-worker, rbd-mq, rbd-eventlog, rbd-db, rbd-etcd and rbd-hub.
+	// +optional
+	Components []string `json:"components,omitempty"`
+}
+
+// WebsocketTLS defines the certificate of the websocket of rbd-api.
+type WebsocketTLS struct {
+	// SecretName is the name of a kubernetes.io/tls secret in the same namespace. If it is empty, a self-signed
+	// certificate for the gateway ingress IPs is generated by the operator, which is not trusted by the browsers.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// SharedStorage defines the storage shared by the rainbond components, known as grdata.
+type SharedStorage struct {
+	// ClaimName is the name of an existing PersistentVolumeClaim in the same namespace used as the shared storage.
+	// If it is empty, the claim rbd-cpt-grdata will be created by the operator.
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+	// StorageClassName is the storage class of the claim created by the operator.
+	// Defaults to the storage class of the RWX rainbondvolume.
+	// Once it is changed, the data of the shared storage is migrated into a new claim of the storage class.
+	// Only the shared storage is migrated, the data volumes of rbd-hub, rbd-eventlog, rbd-monitor, rbd-db
+	// and rbd-etcd keep their storage classes.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// StorageRequest is the size of the claim created by the operator, in GiB.
+	// +optional
+	StorageRequest *int32 `json:"storageRequest,omitempty"`
+	// MountPath is the path where the shared storage is mounted in the containers, /grdata by default.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// ConfirmMigration confirms the completed migration of the shared storage, so that the claim
+	// migrated from is deleted. The claim is retained until the migration is confirmed.
+	// +optional
+	ConfirmMigration bool `json:"confirmMigration,omitempty"`
+	// MigrationImage is the image of the job that copies the data with rsync, rsync-ssh:latest in the image
+	// repository of rainbond by default.
+	// +optional
+	MigrationImage string `json:"migrationImage,omitempty"`
+}
+
+// StorageMigrationPhase is the phase of the migration of the shared storage.
+type StorageMigrationPhase string
+
+const (
+	// StorageMigrationPhaseCopying means the components using the shared storage are stopped,
+	// and the data is being copied into the new claim.
+	StorageMigrationPhaseCopying StorageMigrationPhase = "Copying"
+	// StorageMigrationPhaseCompleted means the components have been switched to the new claim,
+	// and the old claim is retained until the migration is confirmed.
+	StorageMigrationPhaseCompleted StorageMigrationPhase = "Completed"
+	// StorageMigrationPhaseFailed means the data failed to be copied, the components keep using the old claim.
+	// Change the storage class back and forth to retry.
+	StorageMigrationPhaseFailed StorageMigrationPhase = "Failed"
+)
+
+// SuspensionPhase is the phase of the suspension of the region.
+type SuspensionPhase string
+
+const (
+	// SuspensionPhaseSuspending means the components are being stopped.
+	SuspensionPhaseSuspending SuspensionPhase = "Suspending"
+	// SuspensionPhaseSuspended means all the components have been stopped.
+	SuspensionPhaseSuspended SuspensionPhase = "Suspended"
+	// SuspensionPhaseResuming means the components are being started.
+	SuspensionPhaseResuming SuspensionPhase = "Resuming"
+)
+
+// SuspensionStatus is the status of the suspension of the region.
+type SuspensionStatus struct {
+	// Phase of the suspension.
+	Phase SuspensionPhase `json:"phase"`
+	// Stopped are the names of the rbdcomponents stopped.
+	// +optional
+	Stopped []string `json:"stopped,omitempty"`
+	// Human readable message indicating details about the suspension.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SharedStorageStatus is the status of the shared storage.
+type SharedStorageStatus struct {
+	// ClaimName is the claim of the shared storage in use, if it is created by a migration.
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+	// Migration is the status of the last migration of the shared storage.
+	// +optional
+	Migration *StorageMigrationStatus `json:"migration,omitempty"`
+}
+
+// StorageMigrationStatus is the status of the migration of the shared storage between storage classes.
+type StorageMigrationStatus struct {
+	// Phase of the migration.
+	Phase StorageMigrationPhase `json:"phase"`
+	// SourceClaimName is the claim migrated from.
+	SourceClaimName string `json:"sourceClaimName"`
+	// TargetClaimName is the claim migrated to.
+	TargetClaimName string `json:"targetClaimName"`
+	// StorageClassName is the storage class migrated to.
+	StorageClassName string `json:"storageClassName"`
+	// The time the migration started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// The time the migration completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Human readable message indicating details about the migration.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// BuildCache defines the build cache of rbd-chaos.
+type BuildCache struct {
+	// StorageClassName is the storage class of the cache claim rbd-chaos-cache.
+	// Defaults to the storage class of the RWX rainbondvolume.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// StorageRequest is the size of the cache claim, in GiB, 10 by default.
+	// It only takes effect when the claim is created.
+	// +optional
+	StorageRequest *int32 `json:"storageRequest,omitempty"`
+	// Cleanup prunes the old build caches and the dangling images on the nodes for rbd-chaos periodically.
+	// +optional
+	Cleanup *BuildCacheCleanup `json:"cleanup,omitempty"`
+}
+
+// BuildCacheCleanup defines the CronJob that prunes the build caches.
+type BuildCacheCleanup struct {
+	// Schedule in Cron format, 0 3 * * * by default.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// MaxAge is how long the build caches are kept since they were last modified, 168h by default.
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+	// Image is the image of the cleanup jobs, docker:20.10 in the image repository of rainbond by default.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// Timezone defines the timezone of the pods of the rainbond components.
+type Timezone struct {
+	// Name is the IANA timezone, such as Asia/Shanghai. The zoneinfo of the host, which must exist on the nodes,
+	// is mounted beside the one of the images, and the TZ environment variable of the containers is set to the path
+	// of the timezone in it, so that it works for the images without tzdata.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// HostLocaltime mounts /etc/localtime of the host into the containers.
+	// +optional
+	HostLocaltime bool `json:"hostLocaltime,omitempty"`
+}
+
+// Telemetry defines the configuration of the anonymous installation telemetry.
+type Telemetry struct {
+	// Enabled is the switch of telemetry, nothing will be reported unless it is true.
+	Enabled bool `json:"enabled"`
+	// Endpoint is the url that the reports are sent to.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// NotificationFormat is the format of the body of the notifications.
+type NotificationFormat string
+
+const (
+	// NotificationFormatJSON posts the event as it is in JSON.
+	NotificationFormatJSON NotificationFormat = "JSON"
+	// NotificationFormatSlack posts a message to a Slack incoming webhook.
+	NotificationFormatSlack NotificationFormat = "Slack"
+	// NotificationFormatDingTalk posts a text message to a DingTalk robot.
+	NotificationFormatDingTalk NotificationFormat = "DingTalk"
+)
+
+// Notification defines the webhook notified of the changes of the region.
+type Notification struct {
+	// URL is the url of the webhook, which the notifications are posted to.
+	URL string `json:"url"`
+	// Format of the body of the notifications, one of JSON, Slack and DingTalk. Defaults to JSON.
+	// +kubebuilder:validation:Enum=JSON;Slack;DingTalk
+	// +optional
+	Format NotificationFormat `json:"format,omitempty"`
+}
+
+// HealthCheck defines the periodic end-to-end health check of the region. The region API, the default backend
+// of the gateway and the image hub are probed, and the results are reported as conditions and metrics.
+type HealthCheck struct {
+	// Disabled disables the health check, it is enabled by default.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// IntervalSeconds is the interval between the health checks. Defaults to 300.
+	// +kubebuilder:validation:Minimum=60
+	// +optional
+	IntervalSeconds int32 `json:"intervalSeconds,omitempty"`
+	// DegradeOnDependencyFailure turns the phase of the region into Degraded once a dependency is unreachable.
+	// Only the condition DependencyHealthy is false by default.
+	// +optional
+	DegradeOnDependencyFailure bool `json:"degradeOnDependencyFailure,omitempty"`
+}
+
+// Proxy defines the proxy of the outbound requests.
+type Proxy struct {
+	// HTTPProxy is the proxy of the http requests, such as http://proxy.example.com:3128.
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the proxy of the https requests.
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy are the hosts, domains, IP addresses and CIDRs that are requested directly, such as .example.com
+	// and 10.0.0.0/8. The addresses in the cluster, such as localhost, .svc and the services of the region,
+	// are always requested directly.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+	// CredentialSecretName is the name of the secret in the same namespace that contains the username and
+	// the password of the proxy with the keys username and password.
+	// +optional
+	CredentialSecretName string `json:"credentialSecretName,omitempty"`
+}
+
+// CoreDNS defines the server block of the domains of the region in the Corefile of CoreDNS.
+type CoreDNS struct {
+	// Enabled writes the server block to the ConfigMap, it is removed once it is disabled.
+	Enabled bool `json:"enabled"`
+	// Namespace of the ConfigMap, kube-system by default.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// ConfigMapName is the name of the ConfigMap imported by the Corefile, coredns-custom by default,
+	// whose keys ending with .server are imported as the server blocks, as k3s and AKS do.
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// BuilderDomains are the domains requested by the builds of rbd-chaos, which are served by the gateway.
+	// Defaults to lang.goodrain.me and maven.goodrain.me.
+	// +optional
+	BuilderDomains []string `json:"builderDomains,omitempty"`
 }
 
 //CoreComponent Define deployment parameters for Rainbond region core components
@@ -200,8 +893,35 @@ type AvailableNodes struct {
 	MasterNodes []*K8sNode `json:"masterNodes,omitempty"`
 }
 
+// RainbondClusterPhase is a summary of the state of the region.
+type RainbondClusterPhase string
+
+const (
+	// RainbondClusterPending means the prechecks are running, and the rbdcomponents are not created yet.
+	RainbondClusterPending RainbondClusterPhase = "Pending"
+	// RainbondClusterInstalling means the rbdcomponents are created, but some of them are not ready yet, or the
+	// rainbondpackage is not completed.
+	RainbondClusterInstalling RainbondClusterPhase = "Installing"
+	// RainbondClusterRunning means all the components are ready, and the rainbondpackage, if any, is completed.
+	RainbondClusterRunning RainbondClusterPhase = "Running"
+	// RainbondClusterDegraded means some components are not ready after the region was installed.
+	RainbondClusterDegraded RainbondClusterPhase = "Degraded"
+	// RainbondClusterSuspending means the components are being stopped, see the suspension.
+	RainbondClusterSuspending RainbondClusterPhase = "Suspending"
+	// RainbondClusterSuspended means all the components have been stopped.
+	RainbondClusterSuspended RainbondClusterPhase = "Suspended"
+	// RainbondClusterResuming means the components are being started.
+	RainbondClusterResuming RainbondClusterPhase = "Resuming"
+)
+
 // RainbondClusterStatus defines the observed state of RainbondCluster
 type RainbondClusterStatus struct {
+	// Phase is a summary of the conditions and the components, computed by the operator.
+	// +optional
+	Phase RainbondClusterPhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec the conditions are checked against.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// Versoin of Kubernetes
 	KubernetesVersoin string `json:"kubernetesVersoin,omitempty"`
 	// List of existing StorageClasses in the cluster
@@ -220,11 +940,193 @@ type RainbondClusterStatus struct {
 	// ImagePullSecret is an optional references to secret in the same namespace to use for pulling any of the images used by PodSpec.
 	ImagePullSecret *corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 
-	Conditions []RainbondClusterCondition `json:"conditions,omitempty"`
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []RainbondClusterCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// ConditionHistory is the latest transitions of the conditions.
+	// +optional
+	ConditionHistory []ConditionTransition `json:"conditionHistory,omitempty"`
+
+	// License is the status of the license.
+	// +optional
+	License *LicenseStatus `json:"license,omitempty"`
+
+	// SharedStorage is the status of the shared storage.
+	// +optional
+	SharedStorage *SharedStorageStatus `json:"sharedStorage,omitempty"`
+
+	// ImageInventory is the summary of the images running in the region.
+	// +optional
+	ImageInventory *ImageInventoryStatus `json:"imageInventory,omitempty"`
+
+	// Kubernetes is the information about the kubernetes cluster.
+	// +optional
+	Kubernetes *KubernetesStatus `json:"kubernetes,omitempty"`
+
+	// ImageHubGC is the result of the last garbage collection of the image hub.
+	// +optional
+	ImageHubGC *ImageHubGCStatus `json:"imageHubGC,omitempty"`
+
+	// ImageHubReplication is the result of the replication of the image hub to the secondary registry.
+	// +optional
+	ImageHubReplication *ImageHubReplicationStatus `json:"imageHubReplication,omitempty"`
+
+	// Suspension is the status of the suspension of the region, nil if it is not suspended.
+	// +optional
+	Suspension *SuspensionStatus `json:"suspension,omitempty"`
+
+	// InstalledVersion is the install version that all the components have been rolled out with and are ready,
+	// it lags behind spec.installVersion until an upgrade fully completes.
+	// +optional
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	// Components are the images and versions of the rbdcomponents, keyed by the names of the rbdcomponents.
+	// +optional
+	Components map[string]ComponentVersionStatus `json:"components,omitempty"`
+}
+
+// ComponentVersionStatus holds the image and the version of a rbdcomponent.
+type ComponentVersionStatus struct {
+	// Image is the image in the spec of the rbdcomponent.
+	Image string `json:"image"`
+	// Version is the tag of the image.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Ready is whether the rbdcomponent is ready with all its replicas.
+	Ready bool `json:"ready"`
+	// UpToDate is whether all the pods of the rbdcomponent are running the image.
+	UpToDate bool `json:"upToDate"`
+}
+
+// KubernetesStatus holds the information about the kubernetes cluster, for the overview of the cluster
+// and the diagnostics.
+type KubernetesStatus struct {
+	// Version is the kubelet version of the nodes, the lowest one if the nodes are of different versions.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Nodes is the number of the nodes.
+	Nodes int `json:"nodes"`
+	// ReadyNodes is the number of the ready nodes.
+	ReadyNodes int `json:"readyNodes"`
+	// AllocatableCPU is the total allocatable cpu of the ready nodes.
+	// +optional
+	AllocatableCPU resource.Quantity `json:"allocatableCPU,omitempty"`
+	// AllocatableMemory is the total allocatable memory of the ready nodes.
+	// +optional
+	AllocatableMemory resource.Quantity `json:"allocatableMemory,omitempty"`
+	// ContainerRuntimes are the distinct container runtimes of the nodes, such as containerd://1.4.3.
+	// +optional
+	ContainerRuntimes []string `json:"containerRuntimes,omitempty"`
+	// CNI is the detected network plugin, such as calico, flannel or cilium, empty if it is unknown.
+	// +optional
+	CNI string `json:"cni,omitempty"`
+	// Message is the reason why the information can't be collected.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ImageHubGCPhase is the phase of a garbage collection of the image hub.
+type ImageHubGCPhase string
+
+const (
+	// ImageHubGCRunning means the garbage collection is waiting for the image hub to be read-only, or running.
+	ImageHubGCRunning ImageHubGCPhase = "Running"
+	// ImageHubGCSucceeded means the garbage collection has completed.
+	ImageHubGCSucceeded ImageHubGCPhase = "Succeeded"
+	// ImageHubGCFailed means the garbage collection has failed, or not completed within the max read-only duration.
+	ImageHubGCFailed ImageHubGCPhase = "Failed"
+)
+
+// ImageHubGCStatus is the result of the last garbage collection of the image hub.
+type ImageHubGCStatus struct {
+	// JobName is the name of the job of the garbage collection.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+	// Phase is the phase of the garbage collection.
+	// +optional
+	Phase ImageHubGCPhase `json:"phase,omitempty"`
+	// StartTime is when the garbage collection started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the garbage collection succeeded or failed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message is the reason why the garbage collection failed, or its result can't be collected.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ImageHubReplicationPhase is the phase of a replication of the image hub.
+type ImageHubReplicationPhase string
+
+const (
+	// ImageHubReplicationRunning means the replication is running.
+	ImageHubReplicationRunning ImageHubReplicationPhase = "Running"
+	// ImageHubReplicationSucceeded means the replication has completed.
+	ImageHubReplicationSucceeded ImageHubReplicationPhase = "Succeeded"
+	// ImageHubReplicationFailed means the replication has failed.
+	ImageHubReplicationFailed ImageHubReplicationPhase = "Failed"
+)
+
+// ImageHubReplicationStatus is the result of the replication of the image hub to the secondary registry.
+type ImageHubReplicationStatus struct {
+	// JobName is the name of the job of the last replication.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+	// Phase is the phase of the last replication.
+	// +optional
+	Phase ImageHubReplicationPhase `json:"phase,omitempty"`
+	// LastSucceededTime is when the last succeeded replication started, the images pushed to the image hub before
+	// it are in the secondary registry.
+	// +optional
+	LastSucceededTime *metav1.Time `json:"lastSucceededTime,omitempty"`
+	// Lag is how long the secondary registry falls behind the image hub, the time since LastSucceededTime.
+	// The images pushed within it may be lost along with the image hub.
+	// +optional
+	Lag string `json:"lag,omitempty"`
+	// Message is the reason why the last replication failed, or its result can't be collected.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ImageInventoryStatus is the summary of the images running in the region,
+// the complete list of the images with their digests is recorded in the ConfigMap.
+type ImageInventoryStatus struct {
+	// ConfigMapName is the name of the ConfigMap that records the complete list of the images.
+	ConfigMapName string `json:"configMapName"`
+	// InstallVersion is the install version when the images are recorded.
+	// +optional
+	InstallVersion string `json:"installVersion,omitempty"`
+	// Images is the number of the distinct images running.
+	Images int `json:"images"`
+	// Message is the reason why the images can't be recorded.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// LicenseStatus holds the information about the license.
+type LicenseStatus struct {
+	// Whether the license is valid.
+	Valid bool `json:"valid"`
+	// The company that the license is issued to.
+	// +optional
+	Company string `json:"company,omitempty"`
+	// The time the license expires.
+	// +optional
+	ExpireTime *metav1.Time `json:"expireTime,omitempty"`
+	// The maximum number of nodes allowed by the license, 0 means no limit.
+	// +optional
+	NodeCount int `json:"nodeCount,omitempty"`
+	// The reason why the license is invalid.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.installVersion`
+// +kubebuilder:printcolumn:name="Installed",type=string,JSONPath=`.status.installedVersion`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // RainbondCluster is the Schema for the rainbondclusters API
 type RainbondCluster struct {
@@ -305,17 +1207,7 @@ func NewRainbondClusterCondition(condType RainbondClusterConditionType, status v
 
 // SetCondition setups the given rainbondcluster condition.
 func (r *RainbondClusterStatus) SetCondition(c RainbondClusterCondition) {
-	pos, cp := r.GetCondition(c.Type)
-	if cp != nil &&
-		cp.Status == c.Status && cp.Reason == c.Reason && cp.Message == c.Message {
-		return
-	}
-
-	if cp != nil {
-		r.Conditions[pos] = c
-	} else {
-		r.Conditions = append(r.Conditions, c)
-	}
+	r.UpdateCondition(&c)
 }
 
 // GetCondition returns a rbdcomponent condition based on the given type.
@@ -328,32 +1220,35 @@ func (r *RainbondClusterStatus) GetCondition(t RainbondClusterConditionType) (in
 	return -1, nil
 }
 
-// UpdateCondition updates existing rbdcomponent condition or creates a new
-// one. Sets LastTransitionTime to now if the status has changed.
-// Returns true if rbdcomponent condition has changed or has been added.
+// UpdateCondition updates existing rainbondcluster condition or creates a new
+// one. Sets LastTransitionTime to now if the status has changed, and LastHeartbeatTime to now.
+// Returns true if rainbondcluster condition has changed, has been added or its heartbeat is stale.
 func (r *RainbondClusterStatus) UpdateCondition(condition *RainbondClusterCondition) bool {
-	condition.LastTransitionTime = metav1.Now()
-	// Try to find this RainbondVolume condition.
 	conditionIndex, oldCondition := r.GetCondition(condition.Type)
+	var old *conditionFields
+	if oldCondition != nil {
+		old = &conditionFields{
+			status:             oldCondition.Status,
+			reason:             oldCondition.Reason,
+			message:            oldCondition.Message,
+			lastTransitionTime: oldCondition.LastTransitionTime,
+			lastProbeTime:      oldCondition.LastHeartbeatTime,
+		}
+	}
+	merged, changed := mergeCondition(old, conditionFields{
+		typ:     string(condition.Type),
+		status:  condition.Status,
+		reason:  condition.Reason,
+		message: condition.Message,
+	}, &r.ConditionHistory)
+	condition.LastTransitionTime, condition.LastHeartbeatTime = merged.lastTransitionTime, merged.lastProbeTime
 
 	if oldCondition == nil {
-		// We are adding new RainbondVolume condition.
 		r.Conditions = append(r.Conditions, *condition)
-		return true
-	}
-	// We are updating an existing condition, so we need to check if it has changed.
-	if condition.Status == oldCondition.Status {
-		condition.LastTransitionTime = oldCondition.LastTransitionTime
+	} else {
+		r.Conditions[conditionIndex] = *condition
 	}
-
-	isEqual := condition.Status == oldCondition.Status &&
-		condition.Reason == oldCondition.Reason &&
-		condition.Message == oldCondition.Message &&
-		condition.LastTransitionTime.Equal(&oldCondition.LastTransitionTime)
-
-	r.Conditions[conditionIndex] = *condition
-	// Return true if one of the fields have changed.
-	return !isEqual
+	return changed
 }
 
 //DeleteCondition -
@@ -364,3 +1259,34 @@ func (r *RainbondClusterStatus) DeleteCondition(typ3 RainbondClusterConditionTyp
 	}
 	r.Conditions = append(r.Conditions[:idx], r.Conditions[idx+1:]...)
 }
+
+// ComponentSuspended returns true if the rbdcomponent with the name is stopped by the suspension of the region.
+func (in *RainbondCluster) ComponentSuspended(name string) bool {
+	if in.Status.Suspension == nil {
+		return false
+	}
+	for _, stopped := range in.Status.Suspension.Stopped {
+		if stopped == name {
+			return true
+		}
+	}
+	return false
+}
+
+// InStorageMaintenance returns true if the components using the shared storage should be stopped,
+// and the claim of the shared storage being migrated from.
+func (in *RainbondCluster) InStorageMaintenance() (bool, string) {
+	if in.Status.SharedStorage == nil || in.Status.SharedStorage.Migration == nil {
+		return false, ""
+	}
+	migration := in.Status.SharedStorage.Migration
+	return migration.Phase == StorageMigrationPhaseCopying, migration.SourceClaimName
+}
+
+// Installed returns true once the region has been installed, that is all the rbdcomponents were ready at least once.
+// The prechecks of the environment and the bootstrap of the installation are not run again after that, while the
+// prechecks of the spec are run again once the spec is changed.
+func (in *RainbondCluster) Installed() bool {
+	_, running := in.Status.GetCondition(RainbondClusterConditionTypeRunning)
+	return running != nil && running.Status == corev1.ConditionTrue
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RainbondOperatorConfigSpec defines the desired state of RainbondOperatorConfig
+type RainbondOperatorConfigSpec struct {
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles of each controller, 1 by default.
+	// The controllers are set up with it, so the operator exits to apply the change and is restarted by its Deployment.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrentReconciles int32 `json:"maxConcurrentReconciles,omitempty"`
+	// LogLevel is the default verbosity of the logs, the larger the more verbose. It replaces the one given by
+	// the flags, and the verbosities in the ConfigMap rainbond-operator-log take precedence over it.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LogLevel *int32 `json:"logLevel,omitempty"`
+	// WatchNamespaces are the namespaces whose custom resources are reconciled, all the namespaces if empty,
+	// or the namespace of the operator if it runs with --single-namespace. Only the objects in the namespaces are
+	// cached, so the operator exits to apply the change and is restarted by its Deployment.
+	// +optional
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+	// FeatureGates enable or disable the features of the operator by name. The unknown ones are ignored.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// RainbondOperatorConfigStatus defines the observed state of RainbondOperatorConfig
+type RainbondOperatorConfigStatus struct {
+	// ObservedGeneration is the generation of the spec applied by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// FeatureGates are the effective feature gates, including the ones not given in the spec.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// Message is a human readable message indicating details about the applied spec, such as the unknown feature gates.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Concurrency",type=integer,JSONPath=`.spec.maxConcurrentReconciles`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RainbondOperatorConfig is the Schema for the rainbondoperatorconfigs API, which holds the settings of the operator.
+// Only the one named rainbondoperatorconfig is applied.
+type RainbondOperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RainbondOperatorConfigSpec   `json:"spec,omitempty"`
+	Status RainbondOperatorConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RainbondOperatorConfigList contains a list of RainbondOperatorConfig
+type RainbondOperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RainbondOperatorConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RainbondOperatorConfig{}, &RainbondOperatorConfigList{})
+}
@@ -23,6 +23,22 @@ import (
 // RainbondPackagePhase is a label for the condition of a rainbondcluster at the current time.
 type RainbondPackagePhase string
 
+// These are valid phases of rainbondpackage.
+const (
+	// RainbondPackageWaiting means the package is waiting to be handled.
+	RainbondPackageWaiting RainbondPackagePhase = "Waiting"
+	// RainbondPackageDownloading means the package is being downloaded.
+	RainbondPackageDownloading RainbondPackagePhase = "Downloading"
+	// RainbondPackageUnpacking means the package is being unpacked.
+	RainbondPackageUnpacking RainbondPackagePhase = "Unpacking"
+	// RainbondPackagePushing means the images are being pushed.
+	RainbondPackagePushing RainbondPackagePhase = "Pushing"
+	// RainbondPackageCompleted means all the steps are completed.
+	RainbondPackageCompleted RainbondPackagePhase = "Completed"
+	// RainbondPackageFailed means one of the steps failed, see the reason of its condition.
+	RainbondPackageFailed RainbondPackagePhase = "Failed"
+)
+
 //PackageConditionType PackageConditionType
 type PackageConditionType string
 
@@ -71,6 +87,21 @@ type PackageCondition struct {
 	// The progress of the condition
 	// +optional
 	Progress int `json:"progress,omitempty"`
+	// The time the step started running, it is reset once the step is retried.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// The time the step completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// The number of bytes processed by the step.
+	// +optional
+	BytesProcessed int64 `json:"bytesProcessed,omitempty"`
+	// The throughput of the step, such as 12.50MB/s.
+	// +optional
+	Throughput string `json:"throughput,omitempty"`
+	// The hash of the inputs of the step when it completed, the step is redone once its inputs are changed.
+	// +optional
+	InputHash string `json:"inputHash,omitempty"`
 }
 
 //RainbondPackageImage image
@@ -81,18 +112,43 @@ type RainbondPackageImage struct {
 
 // RainbondPackageSpec defines the desired state of RainbondPackage
 type RainbondPackageSpec struct {
-	// Deprecated: The path where the rainbond package is located.
+	// The path where the rainbond package is located. If it is a directory, such as /opt/rainbond/pkg/images,
+	// the image tarballs in it are loaded and pushed one by one, and the new tarballs are discovered incrementally.
+	// The directory should be on the node of the operator, under /opt/rainbond/pkg which is mounted by the operator.
 	PkgPath string `json:"pkgPath"`
 	// install source image hub user
 	ImageHubUser string `json:"imageHubUser"`
 	// install source image hub password
 	ImageHubPass string `json:"imageHubPass"`
+	// JobImage is the image of the jobs that load and push images, docker:20.10 in the image repository of rainbond
+	// by default.
+	// +optional
+	JobImage string `json:"jobImage,omitempty"`
+	// ImagePreload loads the images directly into the container runtime of each node, which is
+	// useful for air-gapped clusters without a fast internal registry.
+	// +optional
+	ImagePreload *ImagePreload `json:"imagePreload,omitempty"`
+}
+
+// ImagePreload defines the DaemonSet that loads images into the container runtime of each node.
+type ImagePreload struct {
+	// HostPath is the directory on each node that contains the image tarballs, /opt/rainbond/pkg/images by default.
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+	// NodeSelector selects the nodes to preload images, all nodes by default.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 }
 
 // RainbondPackageStatus defines the observed state of RainbondPackage
 type RainbondPackageStatus struct {
+	// Phase is a summary of the conditions, computed by the operator.
+	// +optional
+	Phase RainbondPackagePhase `json:"phase,omitempty"`
 	//worker and master maintenance
-	Conditions []PackageCondition `json:"conditions,omitempty"`
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []PackageCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 	// The number of images that should be load and pushed.
 	ImagesNumber int32 `json:"imagesNumber"`
 	// ImagesPushed contains the images have been pushed.
@@ -101,6 +157,11 @@ type RainbondPackageStatus struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rbdpkg
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Images",type=integer,JSONPath=`.status.imagesNumber`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // RainbondPackage is the Schema for the rainbondpackages API
 type RainbondPackage struct {
@@ -133,3 +194,29 @@ func (r *RainbondPackageStatus) GetCondition(t PackageConditionType) (int, *Pack
 	}
 	return -1, nil
 }
+
+// ComputePhase returns the phase of the rainbondpackage based on the conditions of the steps.
+func (r *RainbondPackageStatus) ComputePhase() RainbondPackagePhase {
+	for _, c := range r.Conditions {
+		if c.Status == Failed {
+			return RainbondPackageFailed
+		}
+	}
+	if _, ready := r.GetCondition(Ready); ready != nil && ready.Status == Completed {
+		return RainbondPackageCompleted
+	}
+	steps := []struct {
+		condition PackageConditionType
+		phase     RainbondPackagePhase
+	}{
+		{DownloadPackage, RainbondPackageDownloading},
+		{UnpackPackage, RainbondPackageUnpacking},
+		{PushImage, RainbondPackagePushing},
+	}
+	for _, step := range steps {
+		if _, c := r.GetCondition(step.condition); c != nil && c.Status == Running {
+			return step.phase
+		}
+	}
+	return RainbondPackageWaiting
+}
@@ -44,6 +44,46 @@ type AliyunNasCSIPluginSource struct {
 // NFSCSIPluginSource represents a nfs CSI plugin.
 // More info: https://github.com/kubernetes-incubator/external-storage/tree/master/nfs
 type NFSCSIPluginSource struct {
+	// NodeName is the node of the backing disk of the nfs server, which is a host path on the node.
+	// The nfs server always runs on the node, so that it comes back with the data after a restart.
+	// Defaults to the node with the largest ephemeral storage when the nfs server is created.
+	// Ignored if BackingStorageClassName is set.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+	// HostPath is the path of the backing disk on the node. Defaults to /opt/rainbond/data/nfs.
+	// Ignored if BackingStorageClassName is set.
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+	// BackingStorageClassName is the storage class of the backing disk instead of the host path, e.g. a replicated
+	// block storage, whose size is the storageRequest in GiB, 100 by default. The nfs server is not pinned to a node
+	// then, and is rescheduled with its disk to another node once its node fails, which makes it highly available.
+	// The nfs server keeps a single replica either way, for its exports can't be shared by the replicas.
+	// The backing disk is created with the nfs server, delete the statefulset nfs-provisioner to apply the changes.
+	// +optional
+	BackingStorageClassName string `json:"backingStorageClassName,omitempty"`
+	// Export holds the options of the exports of the nfs server.
+	// +optional
+	Export *NFSExportOptions `json:"export,omitempty"`
+}
+
+// NFSExportOptions are the options of the exports of the nfs server. The server options are applied when
+// the nfs server is created, delete the statefulset nfs-provisioner to apply the changes.
+type NFSExportOptions struct {
+	// Version is the nfs version the volumes are mounted with. Defaults to 4.0, which is supported by
+	// the old kernels lacking 4.1 and 4.2.
+	// +kubebuilder:validation:Enum="3";"4.0";"4.1";"4.2"
+	// +optional
+	Version string `json:"version,omitempty"`
+	// RootSquash maps the root user of the clients to the anonymous user. Defaults to false.
+	// +optional
+	RootSquash *bool `json:"rootSquash,omitempty"`
+	// GracePeriodSeconds is the period the clients reclaim their locks in after the nfs server restarts,
+	// during which the other requests are blocked. The lower it is, the sooner the nfs server serves again.
+	// Defaults to 90.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=180
+	// +optional
+	GracePeriodSeconds *int32 `json:"gracePeriodSeconds,omitempty"`
 }
 
 // StorageClassParameters describes the parameters for a class of storage for
@@ -78,6 +118,47 @@ type CSIPluginSource struct {
 	NFS *NFSCSIPluginSource `json:"nfs,omitempty"`
 }
 
+// StoragePreset generates the StorageClassParameters for a common shared storage backend, whose csi driver
+// is installed beforehand. Only one of its members may be specified.
+type StoragePreset struct {
+	// CephFS is the CephFS of a ceph cluster, provisioned by ceph-csi.
+	CephFS *CephFSPreset `json:"cephfs,omitempty"`
+	// Longhorn is the shared volumes of longhorn, which are exported by its share managers.
+	Longhorn *LonghornPreset `json:"longhorn,omitempty"`
+	// JuiceFS is the JuiceFS file system, provisioned by juicefs-csi-driver.
+	JuiceFS *JuiceFSPreset `json:"juicefs,omitempty"`
+}
+
+// CephFSPreset is the preset of CephFS.
+type CephFSPreset struct {
+	// ClusterID is the id of the ceph cluster in the configuration of ceph-csi.
+	ClusterID string `json:"clusterID"`
+	// FSName is the name of the CephFS file system.
+	FSName string `json:"fsName"`
+	// SecretName is the name of the secret holding the adminID and adminKey of the ceph cluster.
+	SecretName string `json:"secretName"`
+	// SecretNamespace is the namespace of the secret, defaults to the namespace of the rainbondvolume.
+	// +optional
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+}
+
+// LonghornPreset is the preset of longhorn.
+type LonghornPreset struct {
+	// NumberOfReplicas is the number of the replicas of each volume. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	NumberOfReplicas *int32 `json:"numberOfReplicas,omitempty"`
+}
+
+// JuiceFSPreset is the preset of JuiceFS.
+type JuiceFSPreset struct {
+	// SecretName is the name of the secret holding the name, metaurl and storage of the file system.
+	SecretName string `json:"secretName"`
+	// SecretNamespace is the namespace of the secret, defaults to the namespace of the rainbondvolume.
+	// +optional
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+}
+
 // RainbondVolumeSpec defines the desired state of RainbondVolume
 type RainbondVolumeSpec struct {
 	// The name of StorageClass, which is a kind of kubernetes resource.
@@ -89,8 +170,31 @@ type RainbondVolumeSpec struct {
 	CSIPlugin       *CSIPluginSource `json:"csiPlugin,omitempty"`
 	StorageRequest  *int32           `json:"storageRequest,omitempty"`
 	ImageRepository string           `json:"imageRepository"`
+	// StorageClassNaming decides the name of the StorageClass created with the StorageClassParameters,
+	// in case a StorageClass with the same name but a different provisioner, or of the rainbond in another namespace
+	// exists. Defaults to Default in the namespace rbd-system, and Suffix in the others.
+	// +kubebuilder:validation:Enum=Default;Suffix
+	// +optional
+	StorageClassNaming StorageClassNamingStrategy `json:"storageClassNaming,omitempty"`
+	// Preset generates the StorageClassParameters for a common shared storage backend if they are not given,
+	// and the storage class is verified to be mountable by a probe pod before the rainbondvolume is ready.
+	// +optional
+	Preset *StoragePreset `json:"preset,omitempty"`
 }
 
+// StorageClassNamingStrategy is the strategy to name the StorageClass created for the rainbondvolume.
+type StorageClassNamingStrategy string
+
+const (
+	// StorageClassNamingDefault names the StorageClass after the rainbondvolume, and refuses to
+	// use the existing one with a different provisioner, or of the rainbond in another namespace.
+	StorageClassNamingDefault StorageClassNamingStrategy = "Default"
+	// StorageClassNamingSuffix names the StorageClass after the rainbondvolume, or suffixes the name
+	// with the namespace of the rainbondvolume if the former is taken by a different provisioner,
+	// or by the rainbond in another namespace.
+	StorageClassNamingSuffix StorageClassNamingStrategy = "Suffix"
+)
+
 // RainbondVolumeConditionType -
 type RainbondVolumeConditionType string
 
@@ -99,6 +203,14 @@ const (
 	RainbondVolumeReady RainbondVolumeConditionType = "Ready"
 	// RainbondVolumeProgressing means the raionbondvolume is progressing.
 	RainbondVolumeProgressing RainbondVolumeConditionType = "Progressing"
+	// RainbondVolumeStorageClassConflict means the StorageClass can't be created, for the names are
+	// taken by the StorageClasses with different provisioners, or of the rainbonds in other namespaces.
+	RainbondVolumeStorageClassConflict RainbondVolumeConditionType = "StorageClassConflict"
+	// RainbondVolumePluginReady means the csi plugin is ready.
+	RainbondVolumePluginReady RainbondVolumeConditionType = "PluginReady"
+	// RainbondVolumeMountable means the storage class of the preset or the nfs plugin is verified to be mountable
+	// by a probe pod, or why it is not, such as the missing nfs client of the node.
+	RainbondVolumeMountable RainbondVolumeConditionType = "Mountable"
 )
 
 // RainbondVolumeCondition represents one current condition of an rainbondvolume.
@@ -109,6 +221,9 @@ type RainbondVolumeCondition struct {
 	Status v1.ConditionStatus `json:"status"`
 	// Last time the condition transitioned from one status to another.
 	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Last time the condition was evaluated, it is written at most once a minute if the condition is unchanged.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
 	// The reason for the condition's last transition.
 	Reason string `json:"reason,omitempty"`
 	// A human readable message indicating details about the transition.
@@ -118,7 +233,16 @@ type RainbondVolumeCondition struct {
 // RainbondVolumeStatus defines the observed state of RainbondVolume
 type RainbondVolumeStatus struct {
 	// Condition keeps track of all rainbondvolume conditions, if they exist.
-	Conditions []RainbondVolumeCondition `json:"conditions,omitempty"`
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []RainbondVolumeCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// ConditionHistory is the latest transitions of the conditions.
+	// +optional
+	ConditionHistory []ConditionTransition `json:"conditionHistory,omitempty"`
+	// StorageClassName is the StorageClass created or adopted with the StorageClassParameters, which is suffixed
+	// with the namespace by the naming Suffix if the name of the rainbondvolume is taken.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -157,43 +281,32 @@ func (in *RainbondVolumeStatus) GetRainbondVolumeCondition(t RainbondVolumeCondi
 }
 
 // UpdateRainbondVolumeCondition updates existing RainbondVolume condition or creates a new
-// one. Sets LastTransitionTime to now if the status has changed.
-// Returns true if RainbondVolume condition has changed or has been added.
+// one. Sets LastTransitionTime to now if the status has changed, and LastProbeTime to now.
+// Returns true if RainbondVolume condition has changed, has been added or its probe time is stale.
 func (in *RainbondVolumeStatus) UpdateRainbondVolumeCondition(condition *RainbondVolumeCondition) bool {
-	condition.LastTransitionTime = metav1.Now()
-	// Try to find this RainbondVolume condition.
 	conditionIndex, oldCondition := in.GetRainbondVolumeCondition(condition.Type)
+	var old *conditionFields
+	if oldCondition != nil {
+		old = &conditionFields{
+			status:             oldCondition.Status,
+			reason:             oldCondition.Reason,
+			message:            oldCondition.Message,
+			lastTransitionTime: oldCondition.LastTransitionTime,
+			lastProbeTime:      oldCondition.LastProbeTime,
+		}
+	}
+	merged, changed := mergeCondition(old, conditionFields{
+		typ:     string(condition.Type),
+		status:  condition.Status,
+		reason:  condition.Reason,
+		message: condition.Message,
+	}, &in.ConditionHistory)
+	condition.LastTransitionTime, condition.LastProbeTime = merged.lastTransitionTime, merged.lastProbeTime
 
 	if oldCondition == nil {
-		// We are adding new RainbondVolume condition.
 		in.Conditions = append(in.Conditions, *condition)
-		return true
-	}
-	// We are updating an existing condition, so we need to check if it has changed.
-	if condition.Status == oldCondition.Status {
-		condition.LastTransitionTime = oldCondition.LastTransitionTime
-	}
-
-	isEqual := condition.Status == oldCondition.Status &&
-		condition.Reason == oldCondition.Reason &&
-		condition.Message == oldCondition.Message &&
-		condition.LastTransitionTime.Equal(&oldCondition.LastTransitionTime)
-
-	in.Conditions[conditionIndex] = *condition
-	// Return true if one of the fields have changed.
-	return !isEqual
-}
-
-func (in *RainbondVolumeStatus) setRainbondVolumeCondition(r RainbondVolumeCondition) {
-	pos, cp := in.GetRainbondVolumeCondition(r.Type)
-	if cp != nil &&
-		cp.Status == r.Status && cp.Reason == r.Reason && cp.Message == r.Message {
-		return
-	}
-
-	if cp != nil {
-		in.Conditions[pos] = r
 	} else {
-		in.Conditions = append(in.Conditions, r)
+		in.Conditions[conditionIndex] = *condition
 	}
+	return changed
 }
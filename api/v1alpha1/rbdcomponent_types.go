@@ -19,7 +19,9 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // RbdComponentSpec defines the desired state of RbdComponent
@@ -69,6 +71,259 @@ type RbdComponentSpec struct {
 	// +patchMergeKey=name
 	// +patchStrategy=merge,retainKeys
 	Volumes []corev1.Volume `json:"volumes,omitempty" patchStrategy:"merge,retainKeys" patchMergeKey:"name" protobuf:"bytes,1,rep,name=volumes"`
+	// Optional duration in seconds the pod needs to terminate gracefully.
+	// Defaults to 30 seconds for the components serving traffic, such as rbd-gateway and rbd-api,
+	// and 0 for the others.
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// Actions that the management system should take in response to container lifecycle events.
+	// Defaults to a preStop hook that sleeps 5 seconds for the components serving traffic.
+	// +optional
+	Lifecycle *corev1.Lifecycle `json:"lifecycle,omitempty"`
+	// SecurityContext overrides the security context of the container, so that the privileges of
+	// the component can be reduced to the specific capabilities it requires.
+	// rbd-gateway runs privileged by default. Only rbd-gateway and rbd-node support it for now.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// SharedStorageMounts are the subdirectories of the shared storage mounted by the component.
+	// The whole shared storage is mounted if it is empty, which is not a good fit for the NAS
+	// backends with quota per directory.
+	// +optional
+	SharedStorageMounts []SharedStorageMount `json:"sharedStorageMounts,omitempty"`
+	// Log defines the access log and error log of the component, only rbd-gateway supports it for now.
+	// +optional
+	Log *ComponentLog `json:"log,omitempty"`
+	// Build defines the limits of the source builds, only rbd-chaos supports it for now.
+	// +optional
+	Build *BuildLimits `json:"build,omitempty"`
+	// DataVolume defines the claim template of the data volume of each replica, so that the data
+	// follows the replica when it is rescheduled. Only rbd-db, rbd-monitor and rbd-eventlog support it
+	// for now, and it only takes effect when the StatefulSet of the component is created.
+	// rbd-hub only supports its existingClaim.
+	// +optional
+	DataVolume *DataVolume `json:"dataVolume,omitempty"`
+	// Probes tunes the liveness and readiness probes of the component.
+	// +optional
+	Probes *ComponentProbes `json:"probes,omitempty"`
+	// UpdateStrategy defines how the old pods are replaced by the new ones when the component is updated.
+	// It only applies to the components running as deployments or daemonsets, the pods of statefulsets
+	// are always replaced one by one, and the old pod is stopped before the new one is started.
+	// +optional
+	UpdateStrategy *UpdateStrategy `json:"updateStrategy,omitempty"`
+	// RemoteWrite are the remote storages the metrics are written to, such as a central Prometheus,
+	// Thanos or VictoriaMetrics, only rbd-monitor supports it for now.
+	// +optional
+	RemoteWrite []RemoteWrite `json:"remoteWrite,omitempty"`
+	// Architectures are the CPU architectures supported by the image, such as amd64 and arm64.
+	// They are detected from the manifest of the image if empty. In a cluster of mixed architectures,
+	// the pods are scheduled on the nodes of the supported architectures.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// Retention limits the data kept by the component on its storage, the oldest data is removed first.
+	// Only rbd-eventlog and rbd-monitor support it for now.
+	// +optional
+	Retention *DataRetention `json:"retention,omitempty"`
+	// FallbackImage is the same image in a public or mirror registry, such as
+	// registry.cn-hangzhou.aliyuncs.com/goodrain/rbd-api:v5.3.3. The pods run it instead once the image
+	// can't be pulled, such as when the image hub is down, and run the image again once it is available.
+	// The fallback image is pinned to the digest of the image pulled before if it is known.
+	// +optional
+	FallbackImage string `json:"fallbackImage,omitempty"`
+	// HostFiles are the files copied from the image of the component to the hosts by the init containers before
+	// the component starts, such as the binaries and the configuration required on each node. The files are
+	// copied again whenever the pods are recreated, such as by the upgrade of the image.
+	// Only rbd-node supports it for now.
+	// +optional
+	HostFiles []HostFiles `json:"hostFiles,omitempty"`
+}
+
+// HostFiles defines the files copied from the image of a component to a directory of the hosts.
+type HostFiles struct {
+	// Name of the files, which is used in the names of the init container and the volume.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+	// Source is the file or the directory in the image, such as /run/bin/grctl. The contents of the directory
+	// are copied.
+	// +kubebuilder:validation:MinLength=1
+	Source string `json:"source"`
+	// HostPath is the directory on the hosts the files are copied into, such as /usr/local/bin. It is created if
+	// it doesn't exist. The existing files of the same names are replaced, while the others are kept.
+	// +kubebuilder:validation:MinLength=1
+	HostPath string `json:"hostPath"`
+	// Mode of the copied files in octal, such as 0755. The modes in the image are kept if it is empty.
+	// +kubebuilder:validation:Pattern=`^0?[0-7]{3}$`
+	// +optional
+	Mode string `json:"mode,omitempty"`
+}
+
+// DataRetention defines how much data of a component is kept.
+type DataRetention struct {
+	// Days is how many days the data is kept, 7 by default. It is the logs of the applications
+	// for rbd-eventlog, and the metrics for rbd-monitor.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Days *int32 `json:"days,omitempty"`
+	// Size is the maximum size of the data, such as 50Gi, no limit by default. It should be less than the
+	// storage request of the data volume. Only rbd-monitor supports it for now.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+}
+
+// RemoteWrite defines a remote storage of the metrics.
+type RemoteWrite struct {
+	// URL of the endpoint to send the samples to, such as http://thanos-receive:19291/api/v1/receive.
+	URL string `json:"url"`
+	// Name of the remote write, which is used in the metrics of the remote write. Defaults to the index.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// AuthSecretName is the name of the secret in the same namespace to authenticate the requests,
+	// with either the keys username and password for the basic auth, or the key token for the bearer token.
+	// +optional
+	AuthSecretName string `json:"authSecretName,omitempty"`
+}
+
+// SharedStorageMount is a subdirectory of the shared storage mounted by a component.
+type SharedStorageMount struct {
+	// SubPath is the subdirectory of the shared storage, such as build/tenant.
+	SubPath string `json:"subPath"`
+	// MountPath is the path where the subdirectory is mounted in the container.
+	// Defaults to the subdirectory under the mount path of the shared storage, such as /grdata/build/tenant.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// ReadOnly mounts the subdirectory read-only.
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// LogDestination is where the logs of a component are written.
+type LogDestination string
+
+const (
+	// LogDestinationStdout writes the logs to stdout and stderr.
+	LogDestinationStdout LogDestination = "stdout"
+	// LogDestinationFile writes the logs to the files on the host, which are rotated by a sidecar.
+	LogDestinationFile LogDestination = "file"
+)
+
+// ComponentLog defines the log settings of a component.
+type ComponentLog struct {
+	// Whether to write the access log.
+	// +optional
+	AccessLogEnabled bool `json:"accessLogEnabled,omitempty"`
+	// Format of the access log, such as main or json.
+	// +optional
+	AccessLogFormat string `json:"accessLogFormat,omitempty"`
+	// Level of the error log. Defaults to error.
+	// +optional
+	ErrorLogLevel string `json:"errorLogLevel,omitempty"`
+	// Destination of the logs, stdout or file. Defaults to stdout.
+	// +optional
+	Destination LogDestination `json:"destination,omitempty"`
+	// HostPath is the directory on the host to store the log files. Defaults to /var/log/rainbond/<component name>.
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+	// MaxSize is the size in megabytes of a log file before it gets rotated. Defaults to 100.
+	// +optional
+	MaxSize int32 `json:"maxSize,omitempty"`
+	// MaxAge is the number of days to retain the rotated log files. Defaults to 7.
+	// +optional
+	MaxAge int32 `json:"maxAge,omitempty"`
+}
+
+// DataVolume defines the claim template of the data volume of a component.
+type DataVolume struct {
+	// StorageClassName is the storage class of the claims.
+	// Defaults to the storage class of the RWO rainbondvolume.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// StorageRequest is the size of each claim, in GiB. If it is increased and the storage class
+	// allows volume expansion, the existing claims will be expanded by the operator.
+	// +optional
+	StorageRequest *int32 `json:"storageRequest,omitempty"`
+	// ExistingClaim is the name of a PersistentVolumeClaim created beforehand in the same namespace, such as
+	// the one restored from a snapshot, which is mounted instead of the claims created by the operator, and
+	// StorageClassName and StorageRequest are ignored. Only rbd-db, rbd-hub and rbd-monitor support it.
+	// The claim must be ReadWriteOnce or ReadWriteMany, and ReadWriteMany for rbd-hub, whose pods and
+	// garbage collection jobs may run on different nodes.
+	// +optional
+	ExistingClaim string `json:"existingClaim,omitempty"`
+}
+
+// ComponentProbes tunes the probes of the containers of a component.
+type ComponentProbes struct {
+	// Liveness tunes the liveness probes, which restart the containers that fail them.
+	// +optional
+	Liveness *ProbeThresholds `json:"liveness,omitempty"`
+	// Readiness tunes the readiness probes, which remove the pods that fail them from the services.
+	// +optional
+	Readiness *ProbeThresholds `json:"readiness,omitempty"`
+	// Startup tunes the startup probes, which hold the liveness and readiness probes until they succeed,
+	// so that the containers starting slowly are not restarted during the first boot.
+	// +optional
+	Startup *ProbeThresholds `json:"startup,omitempty"`
+}
+
+// ProbeThresholds overrides the thresholds of a probe. The fields not set keep the defaults of the component.
+type ProbeThresholds struct {
+	// Disabled removes the probe.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// Number of seconds after the container has started before the probe is initiated.
+	// +optional
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+	// Number of seconds after which the probe times out.
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// How often (in seconds) to perform the probe.
+	// +optional
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+	// Minimum consecutive successes for the probe to be considered successful after having failed.
+	// Must be 1 for liveness.
+	// +optional
+	SuccessThreshold *int32 `json:"successThreshold,omitempty"`
+	// Minimum consecutive failures for the probe to be considered failed after having succeeded.
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// UpdateStrategyType is the type of the update strategy.
+type UpdateStrategyType string
+
+const (
+	// UpdateStrategyRollingUpdate replaces the old pods gradually.
+	UpdateStrategyRollingUpdate UpdateStrategyType = "RollingUpdate"
+	// UpdateStrategyRecreate stops all the old pods before starting the new ones, which is for the
+	// components that can not run two instances at the same time. Only deployments support it.
+	UpdateStrategyRecreate UpdateStrategyType = "Recreate"
+)
+
+// UpdateStrategy defines how the old pods of a component are replaced by the new ones.
+type UpdateStrategy struct {
+	// Type is RollingUpdate or Recreate. Defaults to the strategy of the component, which is RollingUpdate
+	// except for rbd-resource-proxy.
+	// +optional
+	// +kubebuilder:validation:Enum=RollingUpdate;Recreate
+	Type UpdateStrategyType `json:"type,omitempty"`
+	// MaxSurge is the maximum number or percentage of pods that can be created over the desired number
+	// of pods during the rolling update. Only deployments support it.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// MaxUnavailable is the maximum number or percentage of pods that can be unavailable during the rolling update.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// BuildLimits defines the limits of the source builds of rbd-chaos.
+type BuildLimits struct {
+	// MaxConcurrentTasks is the maximum number of builds running at the same time on each rbd-chaos.
+	// The builds exceeding it wait in the queue.
+	// +optional
+	MaxConcurrentTasks *int32 `json:"maxConcurrentTasks,omitempty"`
+	// Resources of each build job, which are passed to rbd-chaos as the environment variables
+	// BUILD_CPU_REQUEST, BUILD_CPU_LIMIT, BUILD_MEMORY_REQUEST and BUILD_MEMORY_LIMIT.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // RbdComponentConditionType is a valid value for RbdComponentCondition.Type
@@ -82,6 +337,29 @@ const (
 	RainbondPackageReady RbdComponentConditionType = "RainbondPackageReady"
 	// RbdComponentReady means all pods related to the rbdcomponent are ready.
 	RbdComponentReady RbdComponentConditionType = "Ready"
+	// StorageResized indicates whether the claims of the data volumes have been expanded to the size in the spec.
+	// The reason is StorageResizing while the claims are being expanded, and Resized once they are done.
+	StorageResized RbdComponentConditionType = "StorageResized"
+	// RbdComponentHalted means the operator stopped reconciling the rbdcomponent after too many consecutive readiness failures.
+	// The reconciliation is resumed once the spec is changed or the rbdcomponent is annotated with rainbond.io/resume.
+	RbdComponentHalted RbdComponentConditionType = "Halted"
+	// ImageHubTrusted indicates whether the container runtimes of the nodes trust the certificate of the image hub
+	// managed by the operator, which is verified by pulling an image from the image hub on each node.
+	// It is only reported if imageHub.nodeTrust of the rainbondcluster is enabled.
+	ImageHubTrusted RbdComponentConditionType = "ImageHubTrusted"
+	// ImageHubWritable indicates whether the image hub managed by the operator accepts the pushes,
+	// it is read-only while the garbage collection runs.
+	ImageHubWritable RbdComponentConditionType = "ImageHubWritable"
+	// EtcdSpaceAvailable indicates whether the databases of the etcd managed by the operator are well below the
+	// quota, etcd becomes read-only once any of them exceeds it.
+	EtcdSpaceAvailable RbdComponentConditionType = "EtcdSpaceAvailable"
+	// ServiceHealthy indicates whether the health endpoints on the ready pods of the component report healthy,
+	// it catches the pods that are ready but can't serve. It is only reported by the components with health endpoints.
+	ServiceHealthy RbdComponentConditionType = "ServiceHealthy"
+	// MetricsAPIAvailable indicates whether the metrics API serves the metrics of the nodes and the pods, which is
+	// probed at /apis/metrics.k8s.io. It is only reported by metrics-server, and the reason is MetricsAPIUnavailable
+	// or MetricsServerTooOld if the metrics API is served by a broken or too old metrics-server.
+	MetricsAPIAvailable RbdComponentConditionType = "MetricsAPIAvailable"
 )
 
 // RbdComponentCondition contains details for the current condition of this rbdcomponent.
@@ -95,6 +373,9 @@ type RbdComponentCondition struct {
 	// Last time the condition transitioned from one status to another.
 	// +optional
 	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,4,opt,name=lastTransitionTime"`
+	// Last time the condition was evaluated, it is written at most once a minute if the condition is unchanged.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
 	// Unique, one-word, CamelCase reason for the condition's last transition.
 	// +optional
 	Reason string `json:"reason,omitempty" protobuf:"bytes,5,opt,name=reason"`
@@ -103,8 +384,30 @@ type RbdComponentCondition struct {
 	Message string `json:"message,omitempty" protobuf:"bytes,6,opt,name=message"`
 }
 
+// RbdComponentPhase is a label for the condition of a rbdcomponent at the current time.
+type RbdComponentPhase string
+
+// These are valid phases of rbdcomponent.
+const (
+	// RbdComponentPending means the component is waiting for the prerequisites or the pods to be ready.
+	RbdComponentPending RbdComponentPhase = "Pending"
+	// RbdComponentRunning means the pods of the component are ready.
+	RbdComponentRunning RbdComponentPhase = "Running"
+	// RbdComponentFailed means the operator failed to reconcile the component, see the reason of the Ready condition.
+	RbdComponentFailed RbdComponentPhase = "Failed"
+	// RbdComponentHalted means the operator stopped reconciling the component, see the Halted condition.
+	RbdComponentHalted RbdComponentPhase = "Halted"
+)
+
 // RbdComponentStatus defines the observed state of RbdComponent
 type RbdComponentStatus struct {
+	// Phase is a summary of the conditions, computed by the operator.
+	// +optional
+	Phase RbdComponentPhase `json:"phase,omitempty"`
+	// HaltedGeneration is the generation of the rbdcomponent when the reconciliation was halted.
+	// +optional
+	HaltedGeneration int64 `json:"haltedGeneration,omitempty"`
+
 	// Total number of non-terminated pods targeted by this deployment (their labels match the selector).
 	// +optional
 	Replicas int32 `json:"replicas,omitempty" protobuf:"varint,2,opt,name=replicas"`
@@ -114,14 +417,84 @@ type RbdComponentStatus struct {
 	ReadyReplicas int32 `json:"readyReplicas,omitempty" protobuf:"varint,7,opt,name=readyReplicas"`
 
 	// Current state of rainbond component.
-	Conditions []RbdComponentCondition `json:"conditions,omitempty"`
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []RbdComponentCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 
 	// A list of pods
 	Pods []corev1.LocalObjectReference `json:"pods,omitempty"`
+
+	// ConditionHistory is the latest transitions of the conditions.
+	// +optional
+	ConditionHistory []ConditionTransition `json:"conditionHistory,omitempty"`
+
+	// ImageArchitectures are the architectures detected from the manifest of the image.
+	// +optional
+	ImageArchitectures *ImageArchitectures `json:"imageArchitectures,omitempty"`
+
+	// ImageFallback is the fallback image the pods run instead of the image, nil if they run the image.
+	// +optional
+	ImageFallback *ImageFallback `json:"imageFallback,omitempty"`
+
+	// HostFiles is the distribution of the host files to each node the pods run on.
+	// +optional
+	HostFiles []NodeHostFiles `json:"hostFiles,omitempty"`
+}
+
+// NodeHostFiles is the distribution of the host files of a component to a node.
+type NodeHostFiles struct {
+	// NodeName is the name of the node.
+	NodeName string `json:"nodeName"`
+	// Image is the image the files are copied from.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Distributed is whether all the files have been copied to the node.
+	Distributed bool `json:"distributed"`
+	// Message is the reason why the files have not been copied.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ImageFallback is the fallback image in use since the image can't be pulled.
+type ImageFallback struct {
+	// Image is the image in the spec that can't be pulled.
+	Image string `json:"image"`
+	// FallbackImage is the image the pods run instead.
+	FallbackImage string `json:"fallbackImage"`
+	// Digest is the digest of the image pulled before, which the fallback image is pinned to.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// Message is the error of pulling the image.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// FallbackTime is when the pods started to run the fallback image.
+	FallbackTime metav1.Time `json:"fallbackTime"`
+	// CheckTime is the last time the image was checked whether it is available again.
+	CheckTime metav1.Time `json:"checkTime"`
+}
+
+// ImageArchitectures are the architectures supported by an image.
+type ImageArchitectures struct {
+	// Image is the image detected.
+	Image string `json:"image"`
+	// Architectures supported by the image, empty if the detection failed.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// DetectTime is the last time the manifest of the image was inspected.
+	DetectTime metav1.Time `json:"detectTime"`
+	// Message is the error of the detection.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rbdc
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.replicas`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // RbdComponent is the Schema for the rbdcomponents API
 type RbdComponent struct {
@@ -166,17 +539,7 @@ func NewRbdComponentCondition(condType RbdComponentConditionType, status v1.Cond
 
 // SetCondition setups the given rbdcomponent condition.
 func (r *RbdComponentStatus) SetCondition(c RbdComponentCondition) {
-	pos, cp := r.GetCondition(c.Type)
-	if cp != nil &&
-		cp.Status == c.Status && cp.Reason == c.Reason && cp.Message == c.Message {
-		return
-	}
-
-	if cp != nil {
-		r.Conditions[pos] = c
-	} else {
-		r.Conditions = append(r.Conditions, c)
-	}
+	r.UpdateCondition(&c)
 }
 
 // GetCondition returns a rbdcomponent condition based on the given type.
@@ -190,29 +553,58 @@ func (r *RbdComponentStatus) GetCondition(t RbdComponentConditionType) (int, *Rb
 }
 
 // UpdateCondition updates existing rbdcomponent condition or creates a new
-// one. Sets LastTransitionTime to now if the status has changed.
-// Returns true if rbdcomponent condition has changed or has been added.
+// one. Sets LastTransitionTime to now if the status has changed, and LastProbeTime to now.
+// Returns true if rbdcomponent condition has changed, has been added or its probe time is stale.
 func (r *RbdComponentStatus) UpdateCondition(condition *RbdComponentCondition) bool {
-	condition.LastTransitionTime = metav1.Now()
-	// Try to find this RainbondVolume condition.
 	conditionIndex, oldCondition := r.GetCondition(condition.Type)
+	var old *conditionFields
+	if oldCondition != nil {
+		old = &conditionFields{
+			status:             oldCondition.Status,
+			reason:             oldCondition.Reason,
+			message:            oldCondition.Message,
+			lastTransitionTime: oldCondition.LastTransitionTime,
+			lastProbeTime:      oldCondition.LastProbeTime,
+		}
+	}
+	merged, changed := mergeCondition(old, conditionFields{
+		typ:     string(condition.Type),
+		status:  condition.Status,
+		reason:  condition.Reason,
+		message: condition.Message,
+	}, &r.ConditionHistory)
+	condition.LastTransitionTime, condition.LastProbeTime = merged.lastTransitionTime, merged.lastProbeTime
 
 	if oldCondition == nil {
-		// We are adding new RainbondVolume condition.
 		r.Conditions = append(r.Conditions, *condition)
-		return true
-	}
-	// We are updating an existing condition, so we need to check if it has changed.
-	if condition.Status == oldCondition.Status {
-		condition.LastTransitionTime = oldCondition.LastTransitionTime
+	} else {
+		r.Conditions[conditionIndex] = *condition
 	}
+	return changed
+}
 
-	isEqual := condition.Status == oldCondition.Status &&
-		condition.Reason == oldCondition.Reason &&
-		condition.Message == oldCondition.Message &&
-		condition.LastTransitionTime.Equal(&oldCondition.LastTransitionTime)
+// ComputePhase returns the phase of the rbdcomponent based on the Ready condition and the replicas.
+func (r *RbdComponentStatus) ComputePhase() RbdComponentPhase {
+	if r.IsHalted() {
+		return RbdComponentHalted
+	}
+	_, condition := r.GetCondition(RbdComponentReady)
+	if condition == nil {
+		return RbdComponentPending
+	}
+	if condition.Status == v1.ConditionTrue && r.ReadyReplicas >= r.Replicas {
+		return RbdComponentRunning
+	}
+	switch condition.Reason {
+	case ReasonUnsupportedType, ReasonSetControllerReferenceFailed, ReasonCreateResourcesFailed,
+		ReasonDeleteResourceFailed, ReasonAfterProcessFailed, ReasonListPodsFailed:
+		return RbdComponentFailed
+	}
+	return RbdComponentPending
+}
 
-	r.Conditions[conditionIndex] = *condition
-	// Return true if one of the fields have changed.
-	return !isEqual
+// IsHalted returns true if the reconciliation of the rbdcomponent is halted.
+func (r *RbdComponentStatus) IsHalted() bool {
+	_, condition := r.GetCondition(RbdComponentHalted)
+	return condition != nil && condition.Status == v1.ConditionTrue
 }
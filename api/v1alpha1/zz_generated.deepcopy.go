@@ -24,7 +24,9 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -115,6 +117,76 @@ func (in *AvailableNodes) DeepCopy() *AvailableNodes {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildCache) DeepCopyInto(out *BuildCache) {
+	*out = *in
+	if in.StorageRequest != nil {
+		in, out := &in.StorageRequest, &out.StorageRequest
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = new(BuildCacheCleanup)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildCache.
+func (in *BuildCache) DeepCopy() *BuildCache {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildCacheCleanup) DeepCopyInto(out *BuildCacheCleanup) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildCacheCleanup.
+func (in *BuildCacheCleanup) DeepCopy() *BuildCacheCleanup {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildCacheCleanup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildLimits) DeepCopyInto(out *BuildLimits) {
+	*out = *in
+	if in.MaxConcurrentTasks != nil {
+		in, out := &in.MaxConcurrentTasks, &out.MaxConcurrentTasks
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildLimits.
+func (in *BuildLimits) DeepCopy() *BuildLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CSIPluginSource) DeepCopyInto(out *CSIPluginSource) {
 	*out = *in
@@ -131,7 +203,7 @@ func (in *CSIPluginSource) DeepCopyInto(out *CSIPluginSource) {
 	if in.NFS != nil {
 		in, out := &in.NFS, &out.NFS
 		*out = new(NFSCSIPluginSource)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -145,6 +217,124 @@ func (in *CSIPluginSource) DeepCopy() *CSIPluginSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CephFSPreset) DeepCopyInto(out *CephFSPreset) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CephFSPreset.
+func (in *CephFSPreset) DeepCopy() *CephFSPreset {
+	if in == nil {
+		return nil
+	}
+	out := new(CephFSPreset)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentLog) DeepCopyInto(out *ComponentLog) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentLog.
+func (in *ComponentLog) DeepCopy() *ComponentLog {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentLog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentProbes) DeepCopyInto(out *ComponentProbes) {
+	*out = *in
+	if in.Liveness != nil {
+		in, out := &in.Liveness, &out.Liveness
+		*out = new(ProbeThresholds)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Readiness != nil {
+		in, out := &in.Readiness, &out.Readiness
+		*out = new(ProbeThresholds)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Startup != nil {
+		in, out := &in.Startup, &out.Startup
+		*out = new(ProbeThresholds)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentProbes.
+func (in *ComponentProbes) DeepCopy() *ComponentProbes {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentProbes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentVersionStatus) DeepCopyInto(out *ComponentVersionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentVersionStatus.
+func (in *ComponentVersionStatus) DeepCopy() *ComponentVersionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentVersionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConditionTransition) DeepCopyInto(out *ConditionTransition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConditionTransition.
+func (in *ConditionTransition) DeepCopy() *ConditionTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(ConditionTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneScheduling) DeepCopyInto(out *ControlPlaneScheduling) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneScheduling.
+func (in *ControlPlaneScheduling) DeepCopy() *ControlPlaneScheduling {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneScheduling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CoreComponent) DeepCopyInto(out *CoreComponent) {
 	*out = *in
@@ -168,99 +358,906 @@ func (in *CoreComponent) DeepCopy() *CoreComponent {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoreDNS) DeepCopyInto(out *CoreDNS) {
+	*out = *in
+	if in.BuilderDomains != nil {
+		in, out := &in.BuilderDomains, &out.BuilderDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoreDNS.
+func (in *CoreDNS) DeepCopy() *CoreDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(CoreDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataRetention) DeepCopyInto(out *DataRetention) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataRetention.
+func (in *DataRetention) DeepCopy() *DataRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(DataRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVolume) DeepCopyInto(out *DataVolume) {
+	*out = *in
+	if in.StorageRequest != nil {
+		in, out := &in.StorageRequest, &out.StorageRequest
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVolume.
+func (in *DataVolume) DeepCopy() *DataVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Database) DeepCopyInto(out *Database) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Database.
-func (in *Database) DeepCopy() *Database {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Database.
+func (in *Database) DeepCopy() *Database {
+	if in == nil {
+		return nil
+	}
+	out := new(Database)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdConfig) DeepCopyInto(out *EtcdConfig) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdConfig.
+func (in *EtcdConfig) DeepCopy() *EtcdConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdDefragmentation) DeepCopyInto(out *EtcdDefragmentation) {
+	*out = *in
+	if in.HistoryLimit != nil {
+		in, out := &in.HistoryLimit, &out.HistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdDefragmentation.
+func (in *EtcdDefragmentation) DeepCopy() *EtcdDefragmentation {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdDefragmentation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdMaintenance) DeepCopyInto(out *EtcdMaintenance) {
+	*out = *in
+	if in.QuotaBackendBytes != nil {
+		in, out := &in.QuotaBackendBytes, &out.QuotaBackendBytes
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Defragmentation != nil {
+		in, out := &in.Defragmentation, &out.Defragmentation
+		*out = new(EtcdDefragmentation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdMaintenance.
+func (in *EtcdMaintenance) DeepCopy() *EtcdMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPU) DeepCopyInto(out *GPU) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPU.
+func (in *GPU) DeepCopy() *GPU {
+	if in == nil {
+		return nil
+	}
+	out := new(GPU)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewayConfig) DeepCopyInto(out *GatewayConfig) {
+	*out = *in
+	if in.PortRanges != nil {
+		in, out := &in.PortRanges, &out.PortRanges
+		*out = make([]PortRange, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make([]v1.Sysctl, len(*in))
+		copy(*out, *in)
+	}
+	if in.DrainSeconds != nil {
+		in, out := &in.DrainSeconds, &out.DrainSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinReadySeconds != nil {
+		in, out := &in.MinReadySeconds, &out.MinReadySeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UDPPorts != nil {
+		in, out := &in.UDPPorts, &out.UDPPorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayConfig.
+func (in *GatewayConfig) DeepCopy() *GatewayConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewayConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFiles) DeepCopyInto(out *HostFiles) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFiles.
+func (in *HostFiles) DeepCopy() *HostFiles {
+	if in == nil {
+		return nil
+	}
+	out := new(HostFiles)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageArchitectures) DeepCopyInto(out *ImageArchitectures) {
+	*out = *in
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.DetectTime.DeepCopyInto(&out.DetectTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageArchitectures.
+func (in *ImageArchitectures) DeepCopy() *ImageArchitectures {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageArchitectures)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageBundle) DeepCopyInto(out *ImageBundle) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageBundle.
+func (in *ImageBundle) DeepCopy() *ImageBundle {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageBundle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageFallback) DeepCopyInto(out *ImageFallback) {
+	*out = *in
+	in.FallbackTime.DeepCopyInto(&out.FallbackTime)
+	in.CheckTime.DeepCopyInto(&out.CheckTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageFallback.
+func (in *ImageFallback) DeepCopy() *ImageFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageHub) DeepCopyInto(out *ImageHub) {
+	*out = *in
+	if in.GarbageCollection != nil {
+		in, out := &in.GarbageCollection, &out.GarbageCollection
+		*out = new(ImageHubGarbageCollection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(ImageHubObjectStorage)
+		**out = **in
+	}
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		*out = new(ImageHubReplication)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageHub.
+func (in *ImageHub) DeepCopy() *ImageHub {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageHub)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageHubGCStatus) DeepCopyInto(out *ImageHubGCStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageHubGCStatus.
+func (in *ImageHubGCStatus) DeepCopy() *ImageHubGCStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageHubGCStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageHubGarbageCollection) DeepCopyInto(out *ImageHubGarbageCollection) {
+	*out = *in
+	if in.MaxReadOnlyDuration != nil {
+		in, out := &in.MaxReadOnlyDuration, &out.MaxReadOnlyDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HistoryLimit != nil {
+		in, out := &in.HistoryLimit, &out.HistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageHubGarbageCollection.
+func (in *ImageHubGarbageCollection) DeepCopy() *ImageHubGarbageCollection {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageHubGarbageCollection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageHubObjectStorage) DeepCopyInto(out *ImageHubObjectStorage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageHubObjectStorage.
+func (in *ImageHubObjectStorage) DeepCopy() *ImageHubObjectStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageHubObjectStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageHubReplication) DeepCopyInto(out *ImageHubReplication) {
+	*out = *in
+	if in.HistoryLimit != nil {
+		in, out := &in.HistoryLimit, &out.HistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageHubReplication.
+func (in *ImageHubReplication) DeepCopy() *ImageHubReplication {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageHubReplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageHubReplicationStatus) DeepCopyInto(out *ImageHubReplicationStatus) {
+	*out = *in
+	if in.LastSucceededTime != nil {
+		in, out := &in.LastSucceededTime, &out.LastSucceededTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageHubReplicationStatus.
+func (in *ImageHubReplicationStatus) DeepCopy() *ImageHubReplicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageHubReplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageInventoryStatus) DeepCopyInto(out *ImageInventoryStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageInventoryStatus.
+func (in *ImageInventoryStatus) DeepCopy() *ImageInventoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageInventoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePreload) DeepCopyInto(out *ImagePreload) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePreload.
+func (in *ImagePreload) DeepCopy() *ImagePreload {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePreload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSync) DeepCopyInto(out *ImageSync) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSync.
+func (in *ImageSync) DeepCopy() *ImageSync {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageSync) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSyncImageStatus) DeepCopyInto(out *ImageSyncImageStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncImageStatus.
+func (in *ImageSyncImageStatus) DeepCopy() *ImageSyncImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSyncImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSyncList) DeepCopyInto(out *ImageSyncList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageSync, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncList.
+func (in *ImageSyncList) DeepCopy() *ImageSyncList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSyncList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageSyncList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSyncSpec) DeepCopyInto(out *ImageSyncSpec) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Bundles != nil {
+		in, out := &in.Bundles, &out.Bundles
+		*out = make([]ImageBundle, len(*in))
+		copy(*out, *in)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncSpec.
+func (in *ImageSyncSpec) DeepCopy() *ImageSyncSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSyncSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSyncStatus) DeepCopyInto(out *ImageSyncStatus) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ImageSyncImageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncStatus.
+func (in *ImageSyncStatus) DeepCopy() *ImageSyncStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSyncStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JuiceFSPreset) DeepCopyInto(out *JuiceFSPreset) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JuiceFSPreset.
+func (in *JuiceFSPreset) DeepCopy() *JuiceFSPreset {
+	if in == nil {
+		return nil
+	}
+	out := new(JuiceFSPreset)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K8sNode) DeepCopyInto(out *K8sNode) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sNode.
+func (in *K8sNode) DeepCopy() *K8sNode {
+	if in == nil {
+		return nil
+	}
+	out := new(K8sNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesStatus) DeepCopyInto(out *KubernetesStatus) {
+	*out = *in
+	out.AllocatableCPU = in.AllocatableCPU.DeepCopy()
+	out.AllocatableMemory = in.AllocatableMemory.DeepCopy()
+	if in.ContainerRuntimes != nil {
+		in, out := &in.ContainerRuntimes, &out.ContainerRuntimes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesStatus.
+func (in *KubernetesStatus) DeepCopy() *KubernetesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LicenseStatus) DeepCopyInto(out *LicenseStatus) {
+	*out = *in
+	if in.ExpireTime != nil {
+		in, out := &in.ExpireTime, &out.ExpireTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LicenseStatus.
+func (in *LicenseStatus) DeepCopy() *LicenseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LicenseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogShipping) DeepCopyInto(out *LogShipping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogShipping.
+func (in *LogShipping) DeepCopy() *LogShipping {
+	if in == nil {
+		return nil
+	}
+	out := new(LogShipping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LonghornPreset) DeepCopyInto(out *LonghornPreset) {
+	*out = *in
+	if in.NumberOfReplicas != nil {
+		in, out := &in.NumberOfReplicas, &out.NumberOfReplicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LonghornPreset.
+func (in *LonghornPreset) DeepCopy() *LonghornPreset {
+	if in == nil {
+		return nil
+	}
+	out := new(LonghornPreset)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NFSCSIPluginSource) DeepCopyInto(out *NFSCSIPluginSource) {
+	*out = *in
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = new(NFSExportOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSCSIPluginSource.
+func (in *NFSCSIPluginSource) DeepCopy() *NFSCSIPluginSource {
+	if in == nil {
+		return nil
+	}
+	out := new(NFSCSIPluginSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NFSExportOptions) DeepCopyInto(out *NFSExportOptions) {
+	*out = *in
+	if in.RootSquash != nil {
+		in, out := &in.RootSquash, &out.RootSquash
+		*out = new(bool)
+		**out = **in
+	}
+	if in.GracePeriodSeconds != nil {
+		in, out := &in.GracePeriodSeconds, &out.GracePeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSExportOptions.
+func (in *NFSExportOptions) DeepCopy() *NFSExportOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(NFSExportOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeHostFiles) DeepCopyInto(out *NodeHostFiles) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeHostFiles.
+func (in *NodeHostFiles) DeepCopy() *NodeHostFiles {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeHostFiles)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeRoles) DeepCopyInto(out *NodeRoles) {
+	*out = *in
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Build != nil {
+		in, out := &in.Build, &out.Build
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeRoles.
+func (in *NodeRoles) DeepCopy() *NodeRoles {
 	if in == nil {
 		return nil
 	}
-	out := new(Database)
+	out := new(NodeRoles)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *EtcdConfig) DeepCopyInto(out *EtcdConfig) {
+func (in *Notification) DeepCopyInto(out *Notification) {
 	*out = *in
-	if in.Endpoints != nil {
-		in, out := &in.Endpoints, &out.Endpoints
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdConfig.
-func (in *EtcdConfig) DeepCopy() *EtcdConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notification.
+func (in *Notification) DeepCopy() *Notification {
 	if in == nil {
 		return nil
 	}
-	out := new(EtcdConfig)
+	out := new(Notification)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageHub) DeepCopyInto(out *ImageHub) {
+func (in *PackageCondition) DeepCopyInto(out *PackageCondition) {
 	*out = *in
+	in.LastHeartbeatTime.DeepCopyInto(&out.LastHeartbeatTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageHub.
-func (in *ImageHub) DeepCopy() *ImageHub {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageCondition.
+func (in *PackageCondition) DeepCopy() *PackageCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageHub)
+	out := new(PackageCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *K8sNode) DeepCopyInto(out *K8sNode) {
+func (in *PortRange) DeepCopyInto(out *PortRange) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K8sNode.
-func (in *K8sNode) DeepCopy() *K8sNode {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRange.
+func (in *PortRange) DeepCopy() *PortRange {
 	if in == nil {
 		return nil
 	}
-	out := new(K8sNode)
+	out := new(PortRange)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NFSCSIPluginSource) DeepCopyInto(out *NFSCSIPluginSource) {
+func (in *ProbeThresholds) DeepCopyInto(out *ProbeThresholds) {
 	*out = *in
+	if in.InitialDelaySeconds != nil {
+		in, out := &in.InitialDelaySeconds, &out.InitialDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PeriodSeconds != nil {
+		in, out := &in.PeriodSeconds, &out.PeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SuccessThreshold != nil {
+		in, out := &in.SuccessThreshold, &out.SuccessThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailureThreshold != nil {
+		in, out := &in.FailureThreshold, &out.FailureThreshold
+		*out = new(int32)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSCSIPluginSource.
-func (in *NFSCSIPluginSource) DeepCopy() *NFSCSIPluginSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeThresholds.
+func (in *ProbeThresholds) DeepCopy() *ProbeThresholds {
 	if in == nil {
 		return nil
 	}
-	out := new(NFSCSIPluginSource)
+	out := new(ProbeThresholds)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageCondition) DeepCopyInto(out *PackageCondition) {
+func (in *Proxy) DeepCopyInto(out *Proxy) {
 	*out = *in
-	in.LastHeartbeatTime.DeepCopyInto(&out.LastHeartbeatTime)
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageCondition.
-func (in *PackageCondition) DeepCopy() *PackageCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageCondition)
+	out := new(Proxy)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -360,6 +1357,11 @@ func (in *RainbondClusterSpec) DeepCopyInto(out *RainbondClusterSpec) {
 			}
 		}
 	}
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(GatewayConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.NodesForChaos != nil {
 		in, out := &in.NodesForChaos, &out.NodesForChaos
 		*out = make([]*K8sNode, len(*in))
@@ -371,10 +1373,25 @@ func (in *RainbondClusterSpec) DeepCopyInto(out *RainbondClusterSpec) {
 			}
 		}
 	}
+	if in.ChaosNodeSelector != nil {
+		in, out := &in.ChaosNodeSelector, &out.ChaosNodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelectorForNode != nil {
+		in, out := &in.NodeSelectorForNode, &out.NodeSelectorForNode
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeRoles != nil {
+		in, out := &in.NodeRoles, &out.NodeRoles
+		*out = new(NodeRoles)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ImageHub != nil {
 		in, out := &in.ImageHub, &out.ImageHub
 		*out = new(ImageHub)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.RegionDatabase != nil {
 		in, out := &in.RegionDatabase, &out.RegionDatabase
@@ -386,74 +1403,320 @@ func (in *RainbondClusterSpec) DeepCopyInto(out *RainbondClusterSpec) {
 		*out = new(EtcdConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.EtcdMaintenance != nil {
+		in, out := &in.EtcdMaintenance, &out.EtcdMaintenance
+		*out = new(EtcdMaintenance)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RainbondVolumeSpecRWX != nil {
 		in, out := &in.RainbondVolumeSpecRWX, &out.RainbondVolumeSpecRWX
 		*out = new(RainbondVolumeSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.RainbondVolumeSpecRWO != nil {
-		in, out := &in.RainbondVolumeSpecRWO, &out.RainbondVolumeSpecRWO
-		*out = new(RainbondVolumeSpec)
-		(*in).DeepCopyInto(*out)
+	if in.RainbondVolumeSpecRWO != nil {
+		in, out := &in.RainbondVolumeSpecRWO, &out.RainbondVolumeSpecRWO
+		*out = new(RainbondVolumeSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BuildCache != nil {
+		in, out := &in.BuildCache, &out.BuildCache
+		*out = new(BuildCache)
+		(*in).DeepCopyInto(*out)
+	}
+	in.CoreComponent.DeepCopyInto(&out.CoreComponent)
+	in.AddonComponent.DeepCopyInto(&out.AddonComponent)
+	if in.Telemetry != nil {
+		in, out := &in.Telemetry, &out.Telemetry
+		*out = new(Telemetry)
+		**out = **in
+	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(Notification)
+		**out = **in
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheck)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CoreDNS != nil {
+		in, out := &in.CoreDNS, &out.CoreDNS
+		*out = new(CoreDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SharedStorage != nil {
+		in, out := &in.SharedStorage, &out.SharedStorage
+		*out = new(SharedStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timezone != nil {
+		in, out := &in.Timezone, &out.Timezone
+		*out = new(Timezone)
+		**out = **in
+	}
+	if in.WebsocketTLS != nil {
+		in, out := &in.WebsocketTLS, &out.WebsocketTLS
+		*out = new(WebsocketTLS)
+		**out = **in
+	}
+	if in.ControlPlaneScheduling != nil {
+		in, out := &in.ControlPlaneScheduling, &out.ControlPlaneScheduling
+		*out = new(ControlPlaneScheduling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GPU != nil {
+		in, out := &in.GPU, &out.GPU
+		*out = new(GPU)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogShipping != nil {
+		in, out := &in.LogShipping, &out.LogShipping
+		*out = new(LogShipping)
+		**out = **in
+	}
+	if in.AdditionalLabels != nil {
+		in, out := &in.AdditionalLabels, &out.AdditionalLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AdditionalAnnotations != nil {
+		in, out := &in.AdditionalAnnotations, &out.AdditionalAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceMesh != nil {
+		in, out := &in.ServiceMesh, &out.ServiceMesh
+		*out = new(ServiceMesh)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondClusterSpec.
+func (in *RainbondClusterSpec) DeepCopy() *RainbondClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RainbondClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RainbondClusterStatus) DeepCopyInto(out *RainbondClusterStatus) {
+	*out = *in
+	if in.StorageClasses != nil {
+		in, out := &in.StorageClasses, &out.StorageClasses
+		*out = make([]*StorageClass, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(StorageClass)
+				**out = **in
+			}
+		}
+	}
+	if in.GatewayAvailableNodes != nil {
+		in, out := &in.GatewayAvailableNodes, &out.GatewayAvailableNodes
+		*out = new(AvailableNodes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ChaosAvailableNodes != nil {
+		in, out := &in.ChaosAvailableNodes, &out.ChaosAvailableNodes
+		*out = new(AvailableNodes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecret != nil {
+		in, out := &in.ImagePullSecret, &out.ImagePullSecret
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]RainbondClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ConditionHistory != nil {
+		in, out := &in.ConditionHistory, &out.ConditionHistory
+		*out = make([]ConditionTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.License != nil {
+		in, out := &in.License, &out.License
+		*out = new(LicenseStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SharedStorage != nil {
+		in, out := &in.SharedStorage, &out.SharedStorage
+		*out = new(SharedStorageStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageInventory != nil {
+		in, out := &in.ImageInventory, &out.ImageInventory
+		*out = new(ImageInventoryStatus)
+		**out = **in
+	}
+	if in.Kubernetes != nil {
+		in, out := &in.Kubernetes, &out.Kubernetes
+		*out = new(KubernetesStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageHubGC != nil {
+		in, out := &in.ImageHubGC, &out.ImageHubGC
+		*out = new(ImageHubGCStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageHubReplication != nil {
+		in, out := &in.ImageHubReplication, &out.ImageHubReplication
+		*out = new(ImageHubReplicationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make(map[string]ComponentVersionStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Suspension != nil {
+		in, out := &in.Suspension, &out.Suspension
+		*out = new(SuspensionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondClusterStatus.
+func (in *RainbondClusterStatus) DeepCopy() *RainbondClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RainbondClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RainbondOperatorConfig) DeepCopyInto(out *RainbondOperatorConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondOperatorConfig.
+func (in *RainbondOperatorConfig) DeepCopy() *RainbondOperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RainbondOperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RainbondOperatorConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RainbondOperatorConfigList) DeepCopyInto(out *RainbondOperatorConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RainbondOperatorConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondOperatorConfigList.
+func (in *RainbondOperatorConfigList) DeepCopy() *RainbondOperatorConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(RainbondOperatorConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RainbondOperatorConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RainbondOperatorConfigSpec) DeepCopyInto(out *RainbondOperatorConfigSpec) {
+	*out = *in
+	if in.LogLevel != nil {
+		in, out := &in.LogLevel, &out.LogLevel
+		*out = new(int32)
+		**out = **in
+	}
+	if in.WatchNamespaces != nil {
+		in, out := &in.WatchNamespaces, &out.WatchNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
 	}
-	in.CoreComponent.DeepCopyInto(&out.CoreComponent)
-	in.AddonComponent.DeepCopyInto(&out.AddonComponent)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondClusterSpec.
-func (in *RainbondClusterSpec) DeepCopy() *RainbondClusterSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondOperatorConfigSpec.
+func (in *RainbondOperatorConfigSpec) DeepCopy() *RainbondOperatorConfigSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RainbondClusterSpec)
+	out := new(RainbondOperatorConfigSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RainbondClusterStatus) DeepCopyInto(out *RainbondClusterStatus) {
+func (in *RainbondOperatorConfigStatus) DeepCopyInto(out *RainbondOperatorConfigStatus) {
 	*out = *in
-	if in.StorageClasses != nil {
-		in, out := &in.StorageClasses, &out.StorageClasses
-		*out = make([]*StorageClass, len(*in))
-		for i := range *in {
-			if (*in)[i] != nil {
-				in, out := &(*in)[i], &(*out)[i]
-				*out = new(StorageClass)
-				**out = **in
-			}
-		}
-	}
-	if in.GatewayAvailableNodes != nil {
-		in, out := &in.GatewayAvailableNodes, &out.GatewayAvailableNodes
-		*out = new(AvailableNodes)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ChaosAvailableNodes != nil {
-		in, out := &in.ChaosAvailableNodes, &out.ChaosAvailableNodes
-		*out = new(AvailableNodes)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ImagePullSecret != nil {
-		in, out := &in.ImagePullSecret, &out.ImagePullSecret
-		*out = new(v1.LocalObjectReference)
-		**out = **in
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]RainbondClusterCondition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondClusterStatus.
-func (in *RainbondClusterStatus) DeepCopy() *RainbondClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondOperatorConfigStatus.
+func (in *RainbondOperatorConfigStatus) DeepCopy() *RainbondOperatorConfigStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RainbondClusterStatus)
+	out := new(RainbondOperatorConfigStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -463,7 +1726,7 @@ func (in *RainbondPackage) DeepCopyInto(out *RainbondPackage) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -535,6 +1798,11 @@ func (in *RainbondPackageList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RainbondPackageSpec) DeepCopyInto(out *RainbondPackageSpec) {
 	*out = *in
+	if in.ImagePreload != nil {
+		in, out := &in.ImagePreload, &out.ImagePreload
+		*out = new(ImagePreload)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondPackageSpec.
@@ -605,6 +1873,7 @@ func (in *RainbondVolume) DeepCopyObject() runtime.Object {
 func (in *RainbondVolumeCondition) DeepCopyInto(out *RainbondVolumeCondition) {
 	*out = *in
 	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondVolumeCondition.
@@ -667,6 +1936,11 @@ func (in *RainbondVolumeSpec) DeepCopyInto(out *RainbondVolumeSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Preset != nil {
+		in, out := &in.Preset, &out.Preset
+		*out = new(StoragePreset)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondVolumeSpec.
@@ -689,6 +1963,13 @@ func (in *RainbondVolumeStatus) DeepCopyInto(out *RainbondVolumeStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ConditionHistory != nil {
+		in, out := &in.ConditionHistory, &out.ConditionHistory
+		*out = make([]ConditionTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RainbondVolumeStatus.
@@ -732,6 +2013,7 @@ func (in *RbdComponent) DeepCopyObject() runtime.Object {
 func (in *RbdComponentCondition) DeepCopyInto(out *RbdComponentCondition) {
 	*out = *in
 	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RbdComponentCondition.
@@ -811,6 +2093,71 @@ func (in *RbdComponentSpec) DeepCopyInto(out *RbdComponentSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(v1.Lifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SharedStorageMounts != nil {
+		in, out := &in.SharedStorageMounts, &out.SharedStorageMounts
+		*out = make([]SharedStorageMount, len(*in))
+		copy(*out, *in)
+	}
+	if in.Log != nil {
+		in, out := &in.Log, &out.Log
+		*out = new(ComponentLog)
+		**out = **in
+	}
+	if in.Build != nil {
+		in, out := &in.Build, &out.Build
+		*out = new(BuildLimits)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DataVolume != nil {
+		in, out := &in.DataVolume, &out.DataVolume
+		*out = new(DataVolume)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
+		*out = new(ComponentProbes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(UpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = make([]RemoteWrite, len(*in))
+		copy(*out, *in)
+	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(DataRetention)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostFiles != nil {
+		in, out := &in.HostFiles, &out.HostFiles
+		*out = make([]HostFiles, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RbdComponentSpec.
@@ -838,6 +2185,28 @@ func (in *RbdComponentStatus) DeepCopyInto(out *RbdComponentStatus) {
 		*out = make([]v1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.ConditionHistory != nil {
+		in, out := &in.ConditionHistory, &out.ConditionHistory
+		*out = make([]ConditionTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImageArchitectures != nil {
+		in, out := &in.ImageArchitectures, &out.ImageArchitectures
+		*out = new(ImageArchitectures)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageFallback != nil {
+		in, out := &in.ImageFallback, &out.ImageFallback
+		*out = new(ImageFallback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostFiles != nil {
+		in, out := &in.HostFiles, &out.HostFiles
+		*out = make([]NodeHostFiles, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RbdComponentStatus.
@@ -850,6 +2219,101 @@ func (in *RbdComponentStatus) DeepCopy() *RbdComponentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteWrite) DeepCopyInto(out *RemoteWrite) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteWrite.
+func (in *RemoteWrite) DeepCopy() *RemoteWrite {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteWrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMesh) DeepCopyInto(out *ServiceMesh) {
+	*out = *in
+	if in.UninjectedComponents != nil {
+		in, out := &in.UninjectedComponents, &out.UninjectedComponents
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeOutboundPorts != nil {
+		in, out := &in.ExcludeOutboundPorts, &out.ExcludeOutboundPorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMesh.
+func (in *ServiceMesh) DeepCopy() *ServiceMesh {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMesh)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedStorage) DeepCopyInto(out *SharedStorage) {
+	*out = *in
+	if in.StorageRequest != nil {
+		in, out := &in.StorageRequest, &out.StorageRequest
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedStorage.
+func (in *SharedStorage) DeepCopy() *SharedStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedStorageMount) DeepCopyInto(out *SharedStorageMount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedStorageMount.
+func (in *SharedStorageMount) DeepCopy() *SharedStorageMount {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedStorageMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedStorageStatus) DeepCopyInto(out *SharedStorageStatus) {
+	*out = *in
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(StorageMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedStorageStatus.
+func (in *SharedStorageStatus) DeepCopy() *SharedStorageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedStorageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageClass) DeepCopyInto(out *StorageClass) {
 	*out = *in
@@ -891,3 +2355,146 @@ func (in *StorageClassParameters) DeepCopy() *StorageClassParameters {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageMigrationStatus) DeepCopyInto(out *StorageMigrationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageMigrationStatus.
+func (in *StorageMigrationStatus) DeepCopy() *StorageMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoragePreset) DeepCopyInto(out *StoragePreset) {
+	*out = *in
+	if in.CephFS != nil {
+		in, out := &in.CephFS, &out.CephFS
+		*out = new(CephFSPreset)
+		**out = **in
+	}
+	if in.Longhorn != nil {
+		in, out := &in.Longhorn, &out.Longhorn
+		*out = new(LonghornPreset)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JuiceFS != nil {
+		in, out := &in.JuiceFS, &out.JuiceFS
+		*out = new(JuiceFSPreset)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoragePreset.
+func (in *StoragePreset) DeepCopy() *StoragePreset {
+	if in == nil {
+		return nil
+	}
+	out := new(StoragePreset)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SuspensionStatus) DeepCopyInto(out *SuspensionStatus) {
+	*out = *in
+	if in.Stopped != nil {
+		in, out := &in.Stopped, &out.Stopped
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SuspensionStatus.
+func (in *SuspensionStatus) DeepCopy() *SuspensionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SuspensionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Telemetry) DeepCopyInto(out *Telemetry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Telemetry.
+func (in *Telemetry) DeepCopy() *Telemetry {
+	if in == nil {
+		return nil
+	}
+	out := new(Telemetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Timezone) DeepCopyInto(out *Timezone) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Timezone.
+func (in *Timezone) DeepCopy() *Timezone {
+	if in == nil {
+		return nil
+	}
+	out := new(Timezone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
+	*out = *in
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateStrategy.
+func (in *UpdateStrategy) DeepCopy() *UpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebsocketTLS) DeepCopyInto(out *WebsocketTLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebsocketTLS.
+func (in *WebsocketTLS) DeepCopy() *WebsocketTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(WebsocketTLS)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -1,57 +1,39 @@
 package controllers
 
 import (
-	"context"
-	"sort"
-	"strings"
-
-	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/controllers/handler"
 	"github.com/goodrain/rainbond-operator/util/constants"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func init() {
-	AddHandlerFunc(handler.EtcdName, handler.NewETCD)
-	AddHandlerFunc(handler.GatewayName, handler.NewGateway)
-	AddHandlerFunc(handler.HubName, handler.NewHub)
-	AddHandlerFunc(handler.APIName, handler.NewAPI)
-	AddHandlerFunc(handler.ChaosName, handler.NewChaos)
-	AddHandlerFunc(handler.EventLogName, handler.NewEventLog)
-	AddHandlerFunc(handler.MonitorName, handler.NewMonitor)
-	AddHandlerFunc(handler.WorkerName, handler.NewWorker)
-	AddHandlerFunc(handler.MQName, handler.NewMQ)
-	AddHandlerFunc(handler.ResourceProxyName, handler.NewResourceProxy)
-	AddHandlerFunc(handler.NodeName, handler.NewNode)
-	AddHandlerFunc(handler.DBName, handler.NewDB)
-	AddHandlerFunc(handler.MetricsServerName, handler.NewMetricsServer)
-	AddHandlerFunc(handler.NFSName, handler.NewNFS)
-	AddHandlerFunc(handler.KubernetesDashboardName, handler.NewK8sDashboard)
-	AddHandlerFunc(handler.DashboardMetricsScraperName, handler.NewDashboardMetricsScraper)
-	AddHandlerFunc(handler.NFSName, handler.NewNFS)
-	AddHandlerFunc(constants.AliyunCSINasPlugin, handler.NewAliyunCSINasPlugin)
-	AddHandlerFunc(constants.AliyunCSINasProvisioner, handler.NewAliyunCSINasProvisioner)
-	AddHandlerFunc(constants.AliyunCSIDiskPlugin, handler.NewAliyunCSIDiskPlugin)
-	AddHandlerFunc(constants.AliyunCSIDiskProvisioner, handler.NewaliyunCSIDiskProvisioner)
-}
-
-type handlerFunc func(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) handler.ComponentHandler
-
-var handlerFuncs map[string]handlerFunc
-
-// AddHandlerFunc adds handlerFunc to handlerFuncs.
-func AddHandlerFunc(name string, fn handlerFunc) {
-	if handlerFuncs == nil {
-		handlerFuncs = map[string]handlerFunc{}
-	}
-	handlerFuncs[name] = fn
+	handler.Register(handler.EtcdName, handler.NewETCD)
+	handler.Register(handler.GatewayName, handler.NewGateway)
+	handler.Register(handler.HubName, handler.NewHub)
+	handler.Register(handler.APIName, handler.NewAPI)
+	handler.Register(handler.ChaosName, handler.NewChaos)
+	handler.Register(handler.EventLogName, handler.NewEventLog)
+	handler.Register(handler.MonitorName, handler.NewMonitor)
+	handler.Register(handler.WorkerName, handler.NewWorker)
+	handler.Register(handler.MQName, handler.NewMQ)
+	handler.Register(handler.ResourceProxyName, handler.NewResourceProxy)
+	handler.Register(handler.NodeName, handler.NewNode)
+	handler.Register(handler.DBName, handler.NewDB)
+	handler.Register(handler.MetricsServerName, handler.NewMetricsServer)
+	handler.Register(handler.NFSName, handler.NewNFS)
+	handler.Register(handler.KubernetesDashboardName, handler.NewK8sDashboard)
+	handler.Register(handler.DashboardMetricsScraperName, handler.NewDashboardMetricsScraper)
+	handler.Register(handler.NvidiaDevicePluginName, handler.NewNvidiaDevicePlugin)
+	handler.Register(handler.LogShipperName, handler.NewLogShipper)
+	handler.Register(handler.KubeStateMetricsName, handler.NewKubeStateMetrics)
+	handler.Register(constants.AliyunCSINasPlugin, handler.NewAliyunCSINasPlugin)
+	handler.Register(constants.AliyunCSINasProvisioner, handler.NewAliyunCSINasProvisioner)
+	handler.Register(constants.AliyunCSIDiskPlugin, handler.NewAliyunCSIDiskPlugin)
+	handler.Register(constants.AliyunCSIDiskProvisioner, handler.NewaliyunCSIDiskProvisioner)
 }
 
-func supportedComponents() string {
-	var supported []string
-	for name := range handlerFuncs {
-		supported = append(supported, name)
-	}
-	sort.Strings(supported)
-	return strings.Join(supported, ",")
+// AddHandlerFunc adds the handler to the handler.DefaultRegistry.
+//
+// Deprecated: use handler.Register instead.
+func AddHandlerFunc(name string, fn handler.Factory) {
+	handler.Register(name, fn)
 }
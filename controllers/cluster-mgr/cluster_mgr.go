@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -13,12 +15,15 @@ import (
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/licenseutil"
+	"github.com/goodrain/rainbond-operator/util/proxyutil"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	"github.com/pquerna/ffjson/ffjson"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -72,6 +77,8 @@ type RainbondClusteMgr struct {
 	client client.Client
 	scheme *runtime.Scheme
 	log    logr.Logger
+	// apiReader reads the objects out of the watched namespaces, which are not in the cache of the client.
+	apiReader client.Reader
 
 	cluster *rainbondv1alpha1.RainbondCluster
 }
@@ -88,6 +95,18 @@ func NewClusterMgr(ctx context.Context, client client.Client, log logr.Logger, c
 	return mgr
 }
 
+// SetAPIReader sets the reader of the objects out of the watched namespaces, the client is used if not set.
+func (r *RainbondClusteMgr) SetAPIReader(reader client.Reader) {
+	r.apiReader = reader
+}
+
+func (r *RainbondClusteMgr) reader() client.Reader {
+	if r.apiReader != nil {
+		return r.apiReader
+	}
+	return r.client
+}
+
 func (r *RainbondClusteMgr) listStorageClasses() []*rainbondv1alpha1.StorageClass {
 	r.log.V(6).Info("start listing available storage classes")
 
@@ -147,12 +166,65 @@ func (r *RainbondClusteMgr) GenerateRainbondClusterStatus() (*rainbondv1alpha1.R
 		MasterNodes:    masterNodesForChaos,
 	}
 
+	s.License = r.licenseStatus()
+	s.SharedStorage = r.sharedStorageStatus()
+	s.ImageInventory = r.imageInventoryStatus()
+	s.Kubernetes = r.kubernetesStatus()
+	s.ImageHubGC = r.imageHubGCStatus()
+	s.ImageHubReplication = r.imageHubReplicationStatus()
+	s.KubernetesVersoin = s.Kubernetes.Version
+	s.Components, s.InstalledVersion = r.componentVersionsStatus()
+	// the suspension is recorded by ReconcileSuspension.
+	s.Suspension = r.cluster.Status.Suspension
+
 	// conditions for rainbond cluster status
 	s.Conditions = r.generateConditions()
+	s.ObservedGeneration = r.cluster.Generation
+	// the history is kept across the rewrites of the status.
+	s.ConditionHistory = r.cluster.Status.ConditionHistory
+	s.Phase = clusterPhase(s, r.cluster.Spec.HealthCheck)
 	r.log.V(6).Info("generating status success")
 	return s, nil
 }
 
+// licenseStatus returns the status of the license, or nil if there is no license.
+func (r *RainbondClusteMgr) licenseStatus() *rainbondv1alpha1.LicenseStatus {
+	name := r.cluster.Spec.LicenseSecretName
+	if name == "" {
+		return nil
+	}
+	status := &rainbondv1alpha1.LicenseStatus{}
+
+	secret := &corev1.Secret{}
+	if err := r.client.Get(r.ctx, types.NamespacedName{Namespace: r.cluster.Namespace, Name: name}, secret); err != nil {
+		status.Message = fmt.Sprintf("get license secret %s: %v", name, err)
+		return status
+	}
+	license, err := licenseutil.Parse(secret.Data[licenseutil.SecretKey])
+	if err != nil {
+		status.Message = err.Error()
+		return status
+	}
+	status.Company = license.Company
+	status.NodeCount = license.Node
+	if !license.ExpireTime.IsZero() {
+		expireTime := metav1.NewTime(license.ExpireTime)
+		status.ExpireTime = &expireTime
+	}
+
+	nodes, err := k8sutil.ListNodes(r.ctx, r.client)
+	if err != nil {
+		status.Message = fmt.Sprintf("list nodes: %v", err)
+		return status
+	}
+	if err := license.Validate(time.Now(), len(nodes)); err != nil {
+		status.Message = err.Error()
+		return status
+	}
+	status.Valid = true
+	return status
+}
+
 func (r *RainbondClusteMgr) getMasterRoleLabel() (string, error) {
 	nodes := &corev1.NodeList{}
 	if err := r.client.List(r.ctx, nodes); err != nil {
@@ -200,6 +272,23 @@ func (r *RainbondClusteMgr) listNodesByLabels(labels map[string]string) []*rainb
 		return nil
 	}
 
+	return k8sNodes(nodeList.Items)
+}
+
+// LabelChaosNodes labels the nodes selected by the chaos node selector with rainbond.io/chaos,
+// and returns the selected nodes that are schedulable. The label is removed from the nodes no longer selected,
+// if it was added by the operator of the same namespace rather than by the administrators.
+func (r *RainbondClusteMgr) LabelChaosNodes() ([]*rainbondv1alpha1.K8sNode, error) {
+	selector, err := metav1.LabelSelectorAsSelector(r.cluster.Spec.ChaosNodeSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chaos node selector: %v", err)
+	}
+	return r.labelRoleNodes("rbd-chaos", constants.SpecialChaosLabelKey, constants.ChaosLabeledAnnotation, func(node *corev1.Node) bool {
+		return selector.Matches(labels.Set(node.Labels))
+	})
+}
+
+func k8sNodes(nodes []corev1.Node) []*rainbondv1alpha1.K8sNode {
 	findIP := func(addresses []corev1.NodeAddress, addressType corev1.NodeAddressType) string {
 		for _, address := range addresses {
 			if address.Type == addressType {
@@ -209,19 +298,23 @@ func (r *RainbondClusteMgr) listNodesByLabels(labels map[string]string) []*rainb
 		return ""
 	}
 
-	var k8sNodes []*rainbondv1alpha1.K8sNode
-	for _, node := range nodeList.Items {
+	var res []*rainbondv1alpha1.K8sNode
+	for _, node := range nodes {
+		// the rainbond components can not run on the windows nodes.
+		if !k8sutil.IsLinuxNode(&node) {
+			continue
+		}
 		k8sNode := &rainbondv1alpha1.K8sNode{
 			Name:       node.Name,
 			InternalIP: findIP(node.Status.Addresses, corev1.NodeInternalIP),
 			ExternalIP: findIP(node.Status.Addresses, corev1.NodeExternalIP),
 		}
-		k8sNodes = append(k8sNodes, k8sNode)
+		res = append(res, k8sNode)
 	}
 
-	sort.Sort(k8sNodesSortByName(k8sNodes))
+	sort.Sort(k8sNodesSortByName(res))
 
-	return k8sNodes
+	return res
 }
 
 func (r *RainbondClusteMgr) listMasterNodesForGateway(masterLabel string) []*rainbondv1alpha1.K8sNode {
@@ -326,21 +419,97 @@ func (r *RainbondClusteMgr) checkIfRbdNodeReady() error {
 }
 
 func (r *RainbondClusteMgr) generateConditions() []rainbondv1alpha1.RainbondClusterCondition {
+	// the prechecks of the environment are kept as they were once the region is installed, while the ones of the
+	// spec are run again once the spec is changed, such as the etcd, the database and the ports of the host network.
+	specChanged := r.cluster.Status.ObservedGeneration != r.cluster.Generation
+	if !r.cluster.Installed() {
+		r.precheckEnvironment()
+	}
+	if !r.cluster.Installed() || specChanged {
+		r.precheckSpec(specChanged)
+	}
+
+	// the udp ports can be taken by the pods scheduled to the gateway nodes at any time.
+	if len(precheck.GatewayUDPPorts(r.cluster)) > 0 {
+		udpPorts := precheck.NewUDPPortsPrechecker(r.ctx, r.reader(), r.cluster)
+		udpPortsCondition := udpPorts.Check()
+		r.cluster.Status.UpdateCondition(&udpPortsCondition)
+	} else {
+		r.cluster.Status.DeleteCondition(rainbondv1alpha1.RainbondClusterConditionTypeGatewayUDPPorts)
+	}
+
+	if r.cluster.Spec.ChaosNodeSelector != nil {
+		chaosNodes := precheck.NewChaosNodesPrechecker(r.ctx, r.client, r.cluster)
+		chaosNodesCondition := chaosNodes.Check()
+		r.cluster.Status.UpdateCondition(&chaosNodesCondition)
+	} else {
+		r.cluster.Status.DeleteCondition(rainbondv1alpha1.RainbondClusterConditionTypeChaosNodes)
+	}
+
+	// health checks of the running region
+	r.healthCheck()
+
+	if idx, condition := r.cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeRunning); idx == -1 || condition.Status != corev1.ConditionTrue {
+		// the domains are verified before the region is declared running, the public dns of the default
+		// domains is not reachable in the offline installation.
+		if r.cluster.Spec.InstallMode != rainbondv1alpha1.InstallationModeOffline {
+			dnsConfigured := precheck.NewDNSConfiguredPrechecker(r.ctx, r.cluster)
+			dnsConfiguredCondition := dnsConfigured.Check()
+			r.cluster.Status.UpdateCondition(&dnsConfiguredCondition)
+		} else {
+			r.cluster.Status.DeleteCondition(rainbondv1alpha1.RainbondClusterConditionTypeDNSConfigured)
+		}
+		running := r.runningCondition()
+		r.cluster.Status.UpdateCondition(&running)
+	}
+
+	return r.cluster.Status.Conditions
+}
+
+// precheckSpec checks the prerequisites configured by the spec. The ones that passed are checked again only if the
+// spec is changed.
+func (r *RainbondClusteMgr) precheckSpec(specChanged bool) {
 	// region database
 	spec := r.cluster.Spec
-	if spec.RegionDatabase != nil && !r.isConditionTrue(rainbondv1alpha1.RainbondClusterConditionTypeDatabaseRegion) {
+	if spec.RegionDatabase != nil && (specChanged || !r.isConditionTrue(rainbondv1alpha1.RainbondClusterConditionTypeDatabaseRegion)) {
 		preChecker := precheck.NewDatabasePrechecker(rainbondv1alpha1.RainbondClusterConditionTypeDatabaseRegion, spec.RegionDatabase)
 		condition := preChecker.Check()
 		r.cluster.Status.UpdateCondition(&condition)
 	}
 
+	// external etcd
+	r.cluster.Status.DeleteCondition(rainbondv1alpha1.RainbondClusterConditionTypeEtcd)
+	if spec.EtcdConfig != nil && (specChanged || !r.isConditionTrue(rainbondv1alpha1.RainbondClusterConditionTypeEtcdReachable)) {
+		preChecker := precheck.NewEtcdPrechecker(r.ctx, r.client, r.cluster)
+		condition := preChecker.Check()
+		r.cluster.Status.UpdateCondition(&condition)
+	}
+
 	// image repository
-	if spec.ImageHub != nil && !r.isConditionTrue(rainbondv1alpha1.RainbondClusterConditionTypeImageRepository) {
+	if spec.ImageHub != nil && (specChanged || !r.isConditionTrue(rainbondv1alpha1.RainbondClusterConditionTypeImageRepository)) {
 		preChecker := precheck.NewImageRepoPrechecker(r.ctx, r.log, r.cluster)
 		condition := preChecker.Check()
 		r.cluster.Status.UpdateCondition(&condition)
 	}
 
+	// the port ranges of rbd-gateway, which may be taken by the node ports of the new services.
+	if spec.Gateway != nil && len(spec.Gateway.PortRanges) > 0 {
+		gatewayPorts := precheck.NewGatewayPortsPrechecker(r.ctx, r.reader(), r.cluster)
+		gatewayPortsCondition := gatewayPorts.Check()
+		r.cluster.Status.UpdateCondition(&gatewayPortsCondition)
+	} else {
+		r.cluster.Status.DeleteCondition(rainbondv1alpha1.RainbondClusterConditionTypeGatewayPorts)
+	}
+
+	// the ports of the host network shared with the rainbonds in the other namespaces.
+	hostPorts := precheck.NewHostPortsPrechecker(r.ctx, r.reader(), r.cluster)
+	hostPortsCondition := hostPorts.Check()
+	r.cluster.Status.UpdateCondition(&hostPortsCondition)
+}
+
+// precheckEnvironment checks the prerequisites of the environment for the installation, such as the kubernetes
+// version, the storage, the dns and the memory.
+func (r *RainbondClusteMgr) precheckEnvironment() {
 	// kubernetes version
 	if !r.isConditionTrue(rainbondv1alpha1.RainbondClusterConditionTypeKubernetesVersion) {
 		k8sVersion := precheck.NewK8sVersionPrechecker(r.ctx, r.log, r.client)
@@ -348,7 +517,7 @@ func (r *RainbondClusteMgr) generateConditions() []rainbondv1alpha1.RainbondClus
 		r.cluster.Status.UpdateCondition(&condition)
 	}
 
-	storagePreChecker := precheck.NewStorage(r.ctx, r.client, r.cluster.GetNamespace(), r.cluster.Spec.RainbondVolumeSpecRWX)
+	storagePreChecker := precheck.NewStorage(r.ctx, r.client, r.cluster.GetNamespace(), r.cluster.Spec.RainbondVolumeSpecRWX, r.cluster.Spec.SharedStorage)
 	storageCondition := storagePreChecker.Check()
 	r.cluster.Status.UpdateCondition(&storageCondition)
 
@@ -372,13 +541,6 @@ func (r *RainbondClusteMgr) generateConditions() []rainbondv1alpha1.RainbondClus
 		containerNetworkCondition := containerNetworkPrechecker.Check()
 		r.cluster.Status.UpdateCondition(&containerNetworkCondition)
 	}
-
-	if idx, condition := r.cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeRunning); idx == -1 || condition.Status != corev1.ConditionTrue {
-		running := r.runningCondition()
-		r.cluster.Status.UpdateCondition(&running)
-	}
-
-	return r.cluster.Status.Conditions
 }
 
 func (r *RainbondClusteMgr) isConditionTrue(typ3 rainbondv1alpha1.RainbondClusterConditionType) bool {
@@ -391,6 +553,19 @@ func (r *RainbondClusteMgr) isConditionTrue(typ3 rainbondv1alpha1.RainbondCluste
 	return false
 }
 
+// Bootstrap prepares the resources for the installation of the region, which are not touched again once the region
+// is installed, so that they can be changed by hand afterwards.
+func (r *RainbondClusteMgr) Bootstrap() error {
+	// the pvc for grdata is used to check the storage class.
+	if err := r.CreateFoobarPVCIfNotExists(); err != nil {
+		return fmt.Errorf("create pvc for grdata: %v", err)
+	}
+	if err := r.CreateConsoleAdminSecretIfNotExists(); err != nil {
+		return fmt.Errorf("create secret for console administrator: %v", err)
+	}
+	return nil
+}
+
 //CreateFoobarPVCIfNotExists -
 func (r *RainbondClusteMgr) CreateFoobarPVCIfNotExists() error {
 	var storageClassName string
@@ -424,6 +599,37 @@ func (r *RainbondClusteMgr) CreateFoobarPVCIfNotExists() error {
 	return nil
 }
 
+// CreateConsoleAdminSecretIfNotExists generates a random password for the console administrator
+// at the first boot, instead of a well-known default password.
+func (r *RainbondClusteMgr) CreateConsoleAdminSecretIfNotExists() error {
+	secret := &corev1.Secret{}
+	err := r.client.Get(r.ctx, types.NamespacedName{Namespace: r.cluster.Namespace, Name: constants.ConsoleAdminSecretName}, secret)
+	if err == nil || !k8sErrors.IsNotFound(err) {
+		return err
+	}
+
+	password, err := commonutil.RandomPassword(16)
+	if err != nil {
+		return fmt.Errorf("generate password: %v", err)
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.ConsoleAdminSecretName,
+			Namespace: r.cluster.Namespace,
+			Labels:    rbdutil.LabelsForRainbond(nil),
+		},
+		Data: map[string][]byte{
+			"username": []byte("admin"),
+			"password": []byte(password),
+		},
+	}
+	if err := controllerutil.SetControllerReference(r.cluster, secret, r.scheme); err != nil {
+		return fmt.Errorf("set controller reference: %v", err)
+	}
+	r.log.Info("create secret for console administrator", "name", secret.Name)
+	return k8sutil.CreateIfNotExists(r.ctx, r.client, secret)
+}
+
 func (r *RainbondClusteMgr) createPVCForFoobar(storageClassName string) error {
 	if storageClassName == "" {
 		return nil
@@ -447,7 +653,7 @@ func (r *RainbondClusteMgr) falseConditionNow(typ3 rainbondv1alpha1.RainbondClus
 		Type:              typ3,
 		Status:            corev1.ConditionTrue,
 		LastHeartbeatTime: metav1.NewTime(time.Now()),
-		Reason:            "InProgress",
+		Reason:            rainbondv1alpha1.ReasonInProgress,
 		Message:           fmt.Sprintf("precheck for %s is in progress", string(typ3)),
 	}
 }
@@ -462,34 +668,129 @@ func (r *RainbondClusteMgr) runningCondition() rainbondv1alpha1.RainbondClusterC
 	// list all rbdcomponents
 	rbdcomponents, err := r.listRbdComponents()
 	if err != nil {
-		return rbdutil.FailCondition(condition, "ListRbdComponentFailed", err.Error())
+		return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonListRbdComponentFailed, err.Error())
 	}
 
 	if len(rbdcomponents) < 10 {
-		return rbdutil.FailCondition(condition, "InsufficientRbdComponent",
+		return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonInsufficientRbdComponent,
 			fmt.Sprintf("insufficient number of rbdcomponents. expect %d rbdcomponents, but got %d", 10, len(rbdcomponents)))
 	}
 
+	// the region is usable once all the components are ready, not only their resources are created.
+	var notReady []string
 	for _, cpt := range rbdcomponents {
 		idx, c := cpt.Status.GetCondition(rainbondv1alpha1.RbdComponentReady)
 		if idx == -1 {
-			return rbdutil.FailCondition(condition, "RbdComponentReadyNotFound",
+			return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonRbdComponentReadyNotFound,
 				fmt.Sprintf("condition 'RbdComponentReady' not found for %s", cpt.GetName()))
 		}
-		if c.Status == corev1.ConditionFalse {
-			return rbdutil.FailCondition(condition, "RbdComponentNotReady",
-				fmt.Sprintf("rbdcomponent(%s) not ready", cpt.GetName()))
+		if c.Status != corev1.ConditionTrue {
+			notReady = append(notReady, cpt.GetName())
 		}
 	}
+	if len(notReady) > 0 {
+		sort.Strings(notReady)
+		return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonRbdComponentNotReady,
+			fmt.Sprintf("rbdcomponents not ready: %s", strings.Join(notReady, ", ")))
+	}
+
+	// the images of the package are pushed to the image hub for the region to run the apps.
+	if err := r.checkPackageCompleted(); err != nil {
+		return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonPackageNotReady, err.Error())
+	}
+
+	// the apps and the console are not accessible until the domains resolve to the gateway.
+	if _, dns := r.cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeDNSConfigured); dns != nil && dns.Status == corev1.ConditionFalse {
+		return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonDNSNotConfigured, dns.Message)
+	}
 
 	return condition
 }
 
+// checkPackageCompleted checks the rainbondpackage is completed, if any.
+func (r *RainbondClusteMgr) checkPackageCompleted() error {
+	pkg := &rainbondv1alpha1.RainbondPackage{}
+	if err := r.client.Get(r.ctx, types.NamespacedName{Namespace: r.cluster.Namespace, Name: constants.RainbondPackageName}, pkg); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("get rainbondpackage: %v", err)
+	}
+	if phase := pkg.Status.ComputePhase(); phase != rainbondv1alpha1.RainbondPackageCompleted {
+		return fmt.Errorf("rainbondpackage is %s", phase)
+	}
+	return nil
+}
+
 func (r *RainbondClusteMgr) listRbdComponents() ([]rainbondv1alpha1.RbdComponent, error) {
 	rbdcomponentList := &rainbondv1alpha1.RbdComponentList{}
 	err := r.client.List(r.ctx, rbdcomponentList, client.InNamespace(r.cluster.Namespace))
 	if err != nil {
 		return nil, err
 	}
-	return rbdcomponentList.Items, nil
+	// the components being deleted are no longer part of the cluster.
+	var rbdcomponents []rainbondv1alpha1.RbdComponent
+	for _, cpt := range rbdcomponentList.Items {
+		if cpt.DeletionTimestamp.IsZero() {
+			rbdcomponents = append(rbdcomponents, cpt)
+		}
+	}
+	return rbdcomponents, nil
+}
+
+// CreateProxySecret creates or updates the secret of the proxy environment variables for the pods of the region,
+// or deletes it if there is no proxy. The services of the region are requested directly.
+func (r *RainbondClusteMgr) CreateProxySecret() error {
+	key := types.NamespacedName{Namespace: r.cluster.Namespace, Name: proxyutil.SecretName}
+	services := &corev1.ServiceList{}
+	if err := r.client.List(r.ctx, services, client.InNamespace(r.cluster.Namespace)); err != nil {
+		return fmt.Errorf("list services: %v", err)
+	}
+	var names []string
+	for _, svc := range services.Items {
+		names = append(names, svc.Name)
+	}
+	sort.Strings(names)
+	proxy, err := proxyutil.Get(r.ctx, r.client, r.cluster, names...)
+	if err != nil {
+		return err
+	}
+
+	var secret corev1.Secret
+	if err := r.client.Get(r.ctx, key, &secret); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		if proxy == nil {
+			return nil
+		}
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+			},
+			Data: proxy.SecretData(),
+		}
+		if err := controllerutil.SetControllerReference(r.cluster, &secret, r.scheme); err != nil {
+			return fmt.Errorf("set controller reference for secret %s: %v", key.Name, err)
+		}
+		if err := r.client.Create(r.ctx, &secret); err != nil {
+			return fmt.Errorf("create secret for proxy: %v", err)
+		}
+		return nil
+	}
+
+	if proxy == nil {
+		r.log.Info("delete proxy secret", "name", key.Name)
+		return client.IgnoreNotFound(r.client.Delete(r.ctx, &secret))
+	}
+	data := proxy.SecretData()
+	if reflect.DeepEqual(secret.Data, data) {
+		return nil
+	}
+	secret.Data = data
+	if err := r.client.Update(r.ctx, &secret); err != nil {
+		return fmt.Errorf("update secret for proxy: %v", err)
+	}
+	return nil
 }
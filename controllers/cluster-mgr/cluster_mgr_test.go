@@ -0,0 +1,245 @@
+package clustermgr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLabelChaosNodes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	node := func(name string, labels, annotations map[string]string, unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		}
+	}
+	labeledBy := map[string]string{constants.ChaosLabeledAnnotation: "rbd-system"}
+	cli := fake.NewFakeClientWithScheme(scheme,
+		node("selected", map[string]string{"build": "true"}, nil, false),
+		node("cordoned", map[string]string{"build": "true"}, nil, true),
+		node("deselected", map[string]string{constants.SpecialChaosLabelKey: ""}, labeledBy, false),
+		node("manual", map[string]string{constants.SpecialChaosLabelKey: ""}, nil, false),
+		node("other", map[string]string{constants.SpecialChaosLabelKey: ""}, map[string]string{constants.ChaosLabeledAnnotation: "rbd-other"}, false),
+	)
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			ChaosNodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"build": "true"}},
+		},
+	}
+	mgr := NewClusterMgr(context.Background(), cli, logr.Discard(), cluster, scheme)
+
+	nodes, err := mgr.LabelChaosNodes()
+	assert.Nil(t, err)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "selected", nodes[0].Name)
+	}
+
+	labeled := func(name string) bool {
+		got := &corev1.Node{}
+		if err := cli.Get(context.Background(), types.NamespacedName{Name: name}, got); err != nil {
+			t.Fatal(err)
+		}
+		_, ok := got.Labels[constants.SpecialChaosLabelKey]
+		return ok
+	}
+	assert.True(t, labeled("selected"))
+	assert.False(t, labeled("cordoned"))
+	assert.False(t, labeled("deselected"))
+	// the labels added by the administrators or the other rainbonds are kept.
+	assert.True(t, labeled("manual"))
+	assert.True(t, labeled("other"))
+}
+
+func TestGenerateConditionsInstalled(t *testing.T) {
+	memory := rainbondv1alpha1.RainbondClusterCondition{
+		Type:    rainbondv1alpha1.RainbondClusterConditionTypeMemory,
+		Status:  corev1.ConditionFalse,
+		Message: "checked at the installation",
+	}
+	tests := []struct {
+		name               string
+		observedGeneration int64
+		wantHostPorts      bool
+	}{
+		{
+			name:               "spec unchanged",
+			observedGeneration: 2,
+		},
+		{
+			name:               "spec changed",
+			observedGeneration: 1,
+			wantHostPorts:      true,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			for _, add := range []func(*runtime.Scheme) error{rainbondv1alpha1.AddToScheme, corev1.AddToScheme} {
+				if err := add(scheme); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cluster := &rainbondv1alpha1.RainbondCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system", Generation: 2},
+				Spec: rainbondv1alpha1.RainbondClusterSpec{
+					HealthCheck: &rainbondv1alpha1.HealthCheck{Disabled: true},
+				},
+				Status: rainbondv1alpha1.RainbondClusterStatus{
+					ObservedGeneration: tc.observedGeneration,
+					Conditions: []rainbondv1alpha1.RainbondClusterCondition{
+						memory,
+						{Type: rainbondv1alpha1.RainbondClusterConditionTypeRunning, Status: corev1.ConditionTrue},
+					},
+				},
+			}
+			assert.True(t, cluster.Installed())
+			mgr := NewClusterMgr(context.Background(), fake.NewFakeClientWithScheme(scheme, cluster.DeepCopy()), logr.Discard(), cluster, scheme)
+
+			// the prechecks of the environment are not run again once the region is installed.
+			mgr.generateConditions()
+			_, got := cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeMemory)
+			if assert.NotNil(t, got) {
+				assert.Equal(t, memory, *got)
+			}
+			// the prechecks of the spec are run again once the spec is changed.
+			_, hostPorts := cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeHostPorts)
+			assert.Equal(t, tc.wantHostPorts, hostPorts != nil)
+		})
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{rainbondv1alpha1.AddToScheme, corev1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme)
+	mgr := NewClusterMgr(context.Background(), cli, logr.Discard(), cluster, scheme)
+	assert.Nil(t, mgr.Bootstrap())
+
+	secret := &corev1.Secret{}
+	if assert.Nil(t, cli.Get(context.Background(), types.NamespacedName{Namespace: "rbd-system", Name: constants.ConsoleAdminSecretName}, secret)) {
+		assert.Equal(t, "admin", string(secret.Data["username"]))
+		assert.Len(t, secret.Data["password"], 16)
+	}
+}
+
+func TestRunningCondition(t *testing.T) {
+	component := func(name string, ready corev1.ConditionStatus) runtime.Object {
+		return &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "rbd-system"},
+			Status: rainbondv1alpha1.RbdComponentStatus{
+				Conditions: []rainbondv1alpha1.RbdComponentCondition{
+					{Type: rainbondv1alpha1.RbdComponentReady, Status: ready},
+				},
+			},
+		}
+	}
+	pkg := func(ready rainbondv1alpha1.PackageConditionStatus) runtime.Object {
+		return &rainbondv1alpha1.RainbondPackage{
+			ObjectMeta: metav1.ObjectMeta{Name: constants.RainbondPackageName, Namespace: "rbd-system"},
+			Status: rainbondv1alpha1.RainbondPackageStatus{
+				Conditions: []rainbondv1alpha1.PackageCondition{
+					{Type: rainbondv1alpha1.Ready, Status: ready},
+				},
+			},
+		}
+	}
+	components := func(notReady ...string) []runtime.Object {
+		var objs []runtime.Object
+		for _, name := range []string{"rbd-api", "rbd-chaos", "rbd-db", "rbd-etcd", "rbd-eventlog", "rbd-gateway",
+			"rbd-hub", "rbd-monitor", "rbd-mq", "rbd-worker"} {
+			ready := corev1.ConditionTrue
+			for _, n := range notReady {
+				if n == name {
+					ready = corev1.ConditionFalse
+				}
+			}
+			objs = append(objs, component(name, ready))
+		}
+		return objs
+	}
+	tests := []struct {
+		name       string
+		existing   []runtime.Object
+		conditions []rainbondv1alpha1.RainbondClusterCondition
+		wantStatus corev1.ConditionStatus
+		wantReason string
+		wantMsg    string
+	}{
+		{
+			name:       "components not ready",
+			existing:   append(components("rbd-worker", "rbd-api"), pkg(rainbondv1alpha1.Completed)),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonRbdComponentNotReady,
+			wantMsg:    "rbdcomponents not ready: rbd-api, rbd-worker",
+		},
+		{
+			name:       "package not completed",
+			existing:   append(components(), pkg(rainbondv1alpha1.Waiting)),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonPackageNotReady,
+		},
+		{
+			name:       "package completed",
+			existing:   append(components(), pkg(rainbondv1alpha1.Completed)),
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:       "no package",
+			existing:   components(),
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:     "dns not configured",
+			existing: components(),
+			conditions: []rainbondv1alpha1.RainbondClusterCondition{
+				{Type: rainbondv1alpha1.RainbondClusterConditionTypeDNSConfigured, Status: corev1.ConditionFalse, Message: "console.example.com resolves to 10.0.0.1"},
+			},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonDNSNotConfigured,
+			wantMsg:    "console.example.com resolves to 10.0.0.1",
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			cluster := &rainbondv1alpha1.RainbondCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+				Status:     rainbondv1alpha1.RainbondClusterStatus{Conditions: tc.conditions},
+			}
+			mgr := NewClusterMgr(context.Background(), fake.NewFakeClientWithScheme(scheme, tc.existing...), logr.Discard(), cluster, scheme)
+			condition := mgr.runningCondition()
+			assert.Equal(t, tc.wantStatus, condition.Status)
+			assert.Equal(t, tc.wantReason, condition.Reason)
+			if tc.wantMsg != "" {
+				assert.Equal(t, tc.wantMsg, condition.Message)
+			}
+		})
+	}
+}
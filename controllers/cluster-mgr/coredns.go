@@ -0,0 +1,135 @@
+package clustermgr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defCoreDNSNamespace     = "kube-system"
+	defCoreDNSConfigMapName = "coredns-custom"
+)
+
+// defBuilderDomains are the domains requested by the builds of rbd-chaos.
+var defBuilderDomains = []string{"lang.goodrain.me", "maven.goodrain.me"}
+
+// coreDNSKey is the key of the server block of the region in the ConfigMap, the regions in different namespaces
+// have their own blocks.
+func coreDNSKey(cluster *rainbondv1alpha1.RainbondCluster) string {
+	return fmt.Sprintf("rainbond-%s.server", cluster.Namespace)
+}
+
+// coreDNSDomains returns the domains resolved to the gateway, the image hub is only included if it is the default
+// one served by rbd-hub.
+func coreDNSDomains(cluster *rainbondv1alpha1.RainbondCluster) []string {
+	var domains []string
+	if rbdutil.GetImageRepository(cluster) == constants.DefImageRepository {
+		domains = append(domains, constants.DefImageRepository)
+	}
+	builderDomains := cluster.Spec.CoreDNS.BuilderDomains
+	if len(builderDomains) == 0 {
+		builderDomains = defBuilderDomains
+	}
+	for _, domain := range builderDomains {
+		if !containsName(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// coreDNSServerBlock returns the server block resolving the domains to the ip, the other names in the zones are
+// forwarded to the upstream of the node.
+func coreDNSServerBlock(domains []string, ip string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Join(domains, " ") + " {\n")
+	sb.WriteString("    errors\n")
+	sb.WriteString("    hosts {\n")
+	for _, domain := range domains {
+		sb.WriteString(fmt.Sprintf("        %s %s\n", ip, domain))
+	}
+	sb.WriteString("        fallthrough\n")
+	sb.WriteString("    }\n")
+	sb.WriteString("    forward . /etc/resolv.conf\n")
+	sb.WriteString("    cache 30\n")
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// ConfigureCoreDNS writes the server block of the region into the ConfigMap imported by the Corefile of CoreDNS,
+// or removes it once CoreDNS is disabled.
+func (r *RainbondClusteMgr) ConfigureCoreDNS() error {
+	config := r.cluster.Spec.CoreDNS
+	namespace, name := defCoreDNSNamespace, defCoreDNSConfigMapName
+	if config != nil && config.Namespace != "" {
+		namespace = config.Namespace
+	}
+	if config != nil && config.ConfigMapName != "" {
+		name = config.ConfigMapName
+	}
+	key := coreDNSKey(r.cluster)
+
+	var block string
+	if config != nil && config.Enabled {
+		ip := r.cluster.InnerGatewayIngressIP()
+		if ip == "" {
+			return fmt.Errorf("no ip of the gateway for coredns")
+		}
+		block = coreDNSServerBlock(coreDNSDomains(r.cluster), ip)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.client.Get(r.ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("get configmap %s/%s: %v", namespace, name, err)
+		}
+		if block == "" {
+			return nil
+		}
+		// the ConfigMap is shared with the other regions and the administrators, it is not owned by the cluster.
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Data: map[string]string{key: block},
+		}
+		if err := r.client.Create(r.ctx, cm); err != nil {
+			return fmt.Errorf("create configmap %s/%s: %v", namespace, name, err)
+		}
+		r.log.Info("add the server block of the region to coredns", "configmap", name, "key", key)
+		return nil
+	}
+
+	old, ok := cm.Data[key]
+	if block == "" {
+		if !ok {
+			return nil
+		}
+		delete(cm.Data, key)
+		r.log.Info("remove the server block of the region from coredns", "configmap", name, "key", key)
+	} else {
+		if old == block {
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[key] = block
+		r.log.Info("update the server block of the region in coredns", "configmap", name, "key", key)
+	}
+	if err := r.client.Update(r.ctx, cm); err != nil {
+		return fmt.Errorf("update configmap %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package clustermgr
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoreDNSDomains(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			CoreDNS: &rainbondv1alpha1.CoreDNS{Enabled: true},
+		},
+	}
+	assert.Equal(t, []string{"goodrain.me", "lang.goodrain.me", "maven.goodrain.me"}, coreDNSDomains(cluster))
+
+	// the external image hub is not served by the gateway.
+	cluster.Spec.ImageHub = &rainbondv1alpha1.ImageHub{Domain: "registry.example.com"}
+	cluster.Spec.CoreDNS.BuilderDomains = []string{"maven.example.com", "maven.example.com"}
+	assert.Equal(t, []string{"maven.example.com"}, coreDNSDomains(cluster))
+}
+
+func TestCoreDNSServerBlock(t *testing.T) {
+	block := coreDNSServerBlock([]string{"goodrain.me", "lang.goodrain.me"}, "192.168.0.1")
+	assert.Equal(t, `goodrain.me lang.goodrain.me {
+    errors
+    hosts {
+        192.168.0.1 goodrain.me
+        192.168.0.1 lang.goodrain.me
+        fallthrough
+    }
+    forward . /etc/resolv.conf
+    cache 30
+}
+`, block)
+}
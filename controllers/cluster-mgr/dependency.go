@@ -0,0 +1,118 @@
+package clustermgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/goodrain/rainbond-operator/controllers/cluster-mgr/precheck"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// dependency is a service the components of the region depend on.
+type dependency struct {
+	name  string
+	probe func(ctx context.Context) error
+}
+
+// dependencies returns the region database, etcd and the image hub. The external ones are probed as the
+// components connect to them, and the ones installed with the region are probed through their services.
+func (r *RainbondClusteMgr) dependencies() []dependency {
+	spec := r.cluster.Spec
+	ns := r.cluster.Namespace
+	var dependencies []dependency
+
+	if db := spec.RegionDatabase; db != nil {
+		dependencies = append(dependencies, dependency{
+			name: fmt.Sprintf("region database %s", net.JoinHostPort(db.Host, fmt.Sprint(db.Port))),
+			probe: func(ctx context.Context) error {
+				return precheck.PingDatabase(ctx, db)
+			},
+		})
+	} else {
+		dependencies = append(dependencies, tcpDependency(handler.DBName, net.JoinHostPort(handler.DBName+"-rw."+ns, "3306")))
+	}
+
+	if spec.EtcdConfig != nil {
+		dependencies = append(dependencies, dependency{
+			name: fmt.Sprintf("etcd %s", strings.Join(spec.EtcdConfig.Endpoints, ",")),
+			probe: func(ctx context.Context) error {
+				condition := precheck.NewEtcdPrechecker(ctx, r.client, r.cluster).Check()
+				if condition.Status != corev1.ConditionTrue {
+					return errors.New(condition.Message)
+				}
+				return nil
+			},
+		})
+	} else {
+		url := fmt.Sprintf("http://%s.%s:2379/health", handler.EtcdName, ns)
+		dependencies = append(dependencies, dependency{
+			name: handler.EtcdName,
+			probe: func(ctx context.Context) error {
+				return probeHTTP(ctx, http.DefaultClient, url, nil, func(code int) bool {
+					return code == http.StatusOK
+				})
+			},
+		})
+	}
+
+	if hub := spec.ImageHub; hub != nil && hub.Domain != constants.DefImageRepository {
+		dependencies = append(dependencies, dependency{
+			name:  fmt.Sprintf("image hub %s", hub.Domain),
+			probe: r.probeImageHub,
+		})
+	} else {
+		dependencies = append(dependencies, tcpDependency(handler.HubName, net.JoinHostPort(handler.HubName+"."+ns, "5000")))
+	}
+	return dependencies
+}
+
+func tcpDependency(name, address string) dependency {
+	return dependency{
+		name: name,
+		probe: func(ctx context.Context) error {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+	}
+}
+
+// probeDependencies probes the dependencies of the region, the error names the unreachable ones.
+func (r *RainbondClusteMgr) probeDependencies(ctx context.Context) error {
+	return probeDependencies(ctx, r.dependencies())
+}
+
+// The dependencies are probed at the same time, so that an unreachable one doesn't use up the timeout of the others.
+func probeDependencies(ctx context.Context, dependencies []dependency) error {
+	errs := make([]error, len(dependencies))
+	var wg sync.WaitGroup
+	for i := range dependencies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = dependencies[i].probe(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	var failures []string
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", dependencies[i].name, err))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unreachable dependencies: %s", strings.Join(failures, "; "))
+}
@@ -0,0 +1,73 @@
+package clustermgr
+
+import (
+	"fmt"
+	"path"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// DefNvidiaDevicePluginImage is the image of the NVIDIA device plugin in the image repository of rainbond.
+const DefNvidiaDevicePluginImage = "k8s-device-plugin:v0.9.0"
+
+// nvidiaDevicePluginComponent returns the rbdcomponent of the NVIDIA device plugin.
+func nvidiaDevicePluginComponent(cluster *rainbondv1alpha1.RainbondCluster) *rainbondv1alpha1.RbdComponent {
+	image := cluster.Spec.GPU.Image
+	if image == "" {
+		image = path.Join(cluster.Spec.RainbondImageRepository, DefNvidiaDevicePluginImage)
+	}
+	return &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      handler.NvidiaDevicePluginName,
+			Namespace: cluster.Namespace,
+			Labels:    rbdutil.LabelsForRainbond(map[string]string{"name": handler.NvidiaDevicePluginName}),
+		},
+		Spec: rainbondv1alpha1.RbdComponentSpec{
+			Image: image,
+		},
+	}
+}
+
+// ConfigureGPU creates the rbdcomponent of the NVIDIA device plugin once the GPU scheduling is enabled, and deletes
+// it once disabled. The applications request the GPUs by the extended resource advertised by the device plugin.
+func (r *RainbondClusteMgr) ConfigureGPU() error {
+	key := types.NamespacedName{Namespace: r.cluster.Namespace, Name: handler.NvidiaDevicePluginName}
+	cpt := &rainbondv1alpha1.RbdComponent{}
+	if err := r.client.Get(r.ctx, key, cpt); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("get rbdcomponent %s: %v", key.Name, err)
+		}
+		cpt = nil
+	}
+
+	if r.cluster.Spec.GPU == nil || !r.cluster.Spec.GPU.Enabled {
+		if cpt == nil || !metav1.IsControlledBy(cpt, r.cluster) {
+			// the component created by the users is kept.
+			return nil
+		}
+		r.log.Info("delete the rbdcomponent of the NVIDIA device plugin")
+		if err := r.client.Delete(r.ctx, cpt); err != nil && !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("delete rbdcomponent %s: %v", key.Name, err)
+		}
+		return nil
+	}
+
+	if cpt != nil {
+		return nil
+	}
+	cpt = nvidiaDevicePluginComponent(r.cluster)
+	if err := controllerutil.SetControllerReference(r.cluster, cpt, r.scheme); err != nil {
+		return fmt.Errorf("set controller reference: %v", err)
+	}
+	r.log.Info("create the rbdcomponent of the NVIDIA device plugin", "image", cpt.Spec.Image)
+	if err := r.client.Create(r.ctx, cpt); err != nil && !k8sErrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create rbdcomponent %s: %v", key.Name, err)
+	}
+	return nil
+}
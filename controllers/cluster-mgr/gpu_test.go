@@ -0,0 +1,43 @@
+package clustermgr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigureGPU(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system", UID: "uid"},
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			RainbondImageRepository: "registry.example.com/rainbond",
+			GPU:                     &rainbondv1alpha1.GPU{Enabled: true},
+		},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme, cluster)
+	mgr := NewClusterMgr(context.Background(), cli, logr.Discard(), cluster, scheme)
+	key := types.NamespacedName{Namespace: "rbd-system", Name: "nvidia-device-plugin"}
+
+	assert.Nil(t, mgr.ConfigureGPU())
+	cpt := &rainbondv1alpha1.RbdComponent{}
+	assert.Nil(t, cli.Get(context.Background(), key, cpt))
+	assert.Equal(t, "registry.example.com/rainbond/k8s-device-plugin:v0.9.0", cpt.Spec.Image)
+	assert.True(t, metav1.IsControlledBy(cpt, cluster))
+
+	cluster.Spec.GPU.Enabled = false
+	assert.Nil(t, mgr.ConfigureGPU())
+	err := cli.Get(context.Background(), key, cpt)
+	assert.True(t, k8sErrors.IsNotFound(err))
+}
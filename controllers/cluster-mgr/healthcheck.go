@@ -0,0 +1,348 @@
+package clustermgr
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defaultHealthCheckInterval = 5 * time.Minute
+	healthCheckTimeout         = 10 * time.Second
+)
+
+// healthCheckTypes are the conditions of the health checks.
+var healthCheckTypes = []rainbondv1alpha1.RainbondClusterConditionType{
+	rainbondv1alpha1.RainbondClusterConditionTypeRegionAPIHealthy,
+	rainbondv1alpha1.RainbondClusterConditionTypeGatewayHealthy,
+	rainbondv1alpha1.RainbondClusterConditionTypeImageHubHealthy,
+	rainbondv1alpha1.RainbondClusterConditionTypeDependencyHealthy,
+}
+
+// healthCheckKey identifies a health check of a cluster.
+type healthCheckKey struct {
+	uid types.UID
+	typ rainbondv1alpha1.RainbondClusterConditionType
+}
+
+var (
+	// healthCheckResults are the conditions of the completed health checks, which are applied by the following
+	// reconciliations, since the health checks run in the background.
+	healthCheckResults sync.Map
+	// healthCheckRunning are the health checks running in the background, which are not started again.
+	healthCheckRunning sync.Map
+)
+
+// unexpectedResponseError means the probed endpoint is reachable, but doesn't respond as expected.
+type unexpectedResponseError struct {
+	msg string
+}
+
+func (e *unexpectedResponseError) Error() string {
+	return e.msg
+}
+
+// HealthCheckInterval returns the interval between the health checks, 0 if they are disabled.
+func (r *RainbondClusteMgr) HealthCheckInterval() time.Duration {
+	config := r.cluster.Spec.HealthCheck
+	if config == nil {
+		return defaultHealthCheckInterval
+	}
+	if config.Disabled {
+		return 0
+	}
+	if config.IntervalSeconds > 0 {
+		return time.Duration(config.IntervalSeconds) * time.Second
+	}
+	return defaultHealthCheckInterval
+}
+
+// HealthCheckRequeueAfter returns when the cluster is reconciled again for the health checks, which is soon after
+// the running ones complete to apply their results, or the interval. 0 if they are disabled.
+func (r *RainbondClusteMgr) HealthCheckRequeueAfter() time.Duration {
+	interval := r.HealthCheckInterval()
+	if interval == 0 {
+		return 0
+	}
+	for _, typ := range healthCheckTypes {
+		if _, running := healthCheckRunning.Load(healthCheckKey{uid: r.cluster.UID, typ: typ}); running {
+			return healthCheckTimeout
+		}
+	}
+	return interval
+}
+
+// healthCheck probes the region API, the gateway, the image hub and the dependencies once the region is running,
+// each of them at most once an interval, and updates the conditions and the metrics with the results.
+// The probes run in the background so that the reconciliation is not blocked by the unreachable endpoints,
+// their results are applied by the following reconciliations.
+// The conditions are removed while the region is installed or suspended.
+func (r *RainbondClusteMgr) healthCheck() {
+	interval := r.HealthCheckInterval()
+	phase := r.cluster.Status.Phase
+	if interval == 0 || (phase != rainbondv1alpha1.RainbondClusterRunning && phase != rainbondv1alpha1.RainbondClusterDegraded) {
+		for _, typ := range healthCheckTypes {
+			r.cluster.Status.DeleteCondition(typ)
+			healthCheckResults.Delete(healthCheckKey{uid: r.cluster.UID, typ: typ})
+		}
+		deleteHealthCheckMetrics(r.cluster)
+		return
+	}
+
+	for _, typ := range healthCheckTypes {
+		if typ == rainbondv1alpha1.RainbondClusterConditionTypeImageHubHealthy && r.cluster.Spec.ImageHub == nil {
+			continue
+		}
+		key := healthCheckKey{uid: r.cluster.UID, typ: typ}
+		_, condition := r.cluster.Status.GetCondition(typ)
+		if result, ok := healthCheckResults.Load(key); ok {
+			result := result.(rainbondv1alpha1.RainbondClusterCondition)
+			if condition == nil || condition.LastHeartbeatTime.Before(&result.LastHeartbeatTime) {
+				r.cluster.Status.UpdateCondition(&result)
+				_, condition = r.cluster.Status.GetCondition(typ)
+			}
+		}
+		if condition != nil && time.Since(condition.LastHeartbeatTime.Time) < interval {
+			continue
+		}
+		r.startHealthCheck(key)
+	}
+}
+
+// startHealthCheck runs the health check in the background unless it is running, and stores its result.
+func (r *RainbondClusteMgr) startHealthCheck(key healthCheckKey) {
+	if _, running := healthCheckRunning.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+	// the probes read the cluster updated by the reconciliation meanwhile.
+	mgr := *r
+	mgr.cluster = r.cluster.DeepCopy()
+	probes := map[rainbondv1alpha1.RainbondClusterConditionType]func(ctx context.Context) error{
+		rainbondv1alpha1.RainbondClusterConditionTypeRegionAPIHealthy:  mgr.probeRegionAPI,
+		rainbondv1alpha1.RainbondClusterConditionTypeGatewayHealthy:    mgr.probeGateway,
+		rainbondv1alpha1.RainbondClusterConditionTypeImageHubHealthy:   mgr.probeImageHub,
+		rainbondv1alpha1.RainbondClusterConditionTypeDependencyHealthy: mgr.probeDependencies,
+	}
+	go func() {
+		defer healthCheckRunning.Delete(key)
+		healthCheckResults.Store(key, mgr.runHealthCheck(key.typ, probes[key.typ]))
+	}()
+}
+
+func (r *RainbondClusteMgr) runHealthCheck(typ rainbondv1alpha1.RainbondClusterConditionType, probe func(ctx context.Context) error) rainbondv1alpha1.RainbondClusterCondition {
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:              typ,
+		Status:            corev1.ConditionTrue,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+		Reason:            rainbondv1alpha1.ReasonHealthCheckPassed,
+	}
+
+	// the context of the reconciliation is canceled once it returns.
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := probe(ctx)
+	observeHealthCheck(r.cluster, typ, time.Since(start), err == nil)
+	if err == nil {
+		return condition
+	}
+
+	r.log.Info("health check failed", "check", typ, "error", err.Error())
+	if _, ok := err.(*unexpectedResponseError); ok {
+		return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonHealthCheckUnexpectedResponse, err.Error())
+	}
+	return rbdutil.FailCondition(condition, rainbondv1alpha1.ReasonHealthCheckUnreachable, err.Error())
+}
+
+// probeRegionAPI checks the health endpoint of rbd-api through its inner service.
+func (r *RainbondClusteMgr) probeRegionAPI(ctx context.Context) error {
+	url := fmt.Sprintf("http://%s-api-inner.%s:8888/v2/health", handler.APIName, r.cluster.Namespace)
+	return probeHTTP(ctx, http.DefaultClient, url, nil, func(code int) bool {
+		return code == http.StatusOK
+	})
+}
+
+// probeGateway requests the gateway without a known host, which is served by the default backend.
+// The default backend responds 404, a 5xx means the gateway can't serve the requests.
+func (r *RainbondClusteMgr) probeGateway(ctx context.Context) error {
+	ip := r.cluster.InnerGatewayIngressIP()
+	if ip == "" {
+		return &unexpectedResponseError{msg: "no ingress ip of the gateway"}
+	}
+	url := fmt.Sprintf("http://%s/", net.JoinHostPort(ip, "80"))
+	return probeHTTP(ctx, http.DefaultClient, url, nil, func(code int) bool {
+		return code < http.StatusInternalServerError
+	})
+}
+
+// probeImageHub logs in the image hub with the credential of rbd-chaos by the Docker Registry HTTP API V2, then
+// resolves the manifest of an image as a pull does. The default image hub, goodrain.me, is requested through
+// the gateway as rbd-chaos does.
+func (r *RainbondClusteMgr) probeImageHub(ctx context.Context) error {
+	imageHub := r.cluster.Spec.ImageHub
+	var gatewayIP string
+	if imageHub.Domain == constants.DefImageRepository {
+		if gatewayIP = r.cluster.InnerGatewayIngressIP(); gatewayIP == "" {
+			return &unexpectedResponseError{msg: "no ingress ip of the gateway to access " + constants.DefImageRepository}
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if host, port, err := net.SplitHostPort(addr); err == nil && host == constants.DefImageRepository && gatewayIP != "" {
+					addr = net.JoinHostPort(gatewayIP, port)
+				}
+				return dialer.DialContext(ctx, network, addr)
+			},
+			// the image repositories are usually signed by themselves, such as goodrain.me.
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	base := fmt.Sprintf("https://%s", imageHub.Domain)
+	login := func(req *http.Request) {
+		if imageHub.Username != "" {
+			req.SetBasicAuth(imageHub.Username, imageHub.Password)
+		}
+	}
+	if err := probeHTTP(ctx, client, base+"/v2/", login, func(code int) bool {
+		return code == http.StatusOK
+	}); err != nil {
+		return err
+	}
+	return probeManifest(ctx, client, base, login)
+}
+
+// manifestMediaTypes are the media types of the manifests accepted by the pulls.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// probeManifest resolves the manifest of the first tag of the first repository in the catalog of the image hub by
+// a HEAD request, as a pull does. It passes if the image hub is empty, or doesn't serve the catalog to the user,
+// such as the ones only serving it to the administrators.
+func probeManifest(ctx context.Context, client *http.Client, base string, login func(req *http.Request)) error {
+	var catalog struct {
+		Repositories []string `json:"repositories"`
+	}
+	if served, err := getJSON(ctx, client, base+"/v2/_catalog?n=1", login, &catalog); err != nil || !served {
+		return err
+	}
+	if len(catalog.Repositories) == 0 {
+		return nil
+	}
+	repository := catalog.Repositories[0]
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if served, err := getJSON(ctx, client, fmt.Sprintf("%s/v2/%s/tags/list?n=1", base, repository), login, &tags); err != nil || !served {
+		return err
+	}
+	if len(tags.Tags) == 0 {
+		return nil
+	}
+
+	manifest := fmt.Sprintf("%s/v2/%s/manifests/%s", base, repository, url.PathEscape(tags.Tags[0]))
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifest, nil)
+	if err != nil {
+		return err
+	}
+	login(req)
+	for _, mediaType := range manifestMediaTypes {
+		req.Header.Add("Accept", mediaType)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &unexpectedResponseError{msg: fmt.Sprintf("unexpected status code %d resolving the manifest of %s:%s", resp.StatusCode, repository, tags.Tags[0])}
+	}
+	return nil
+}
+
+// getJSON decodes the response of a GET request to the endpoint into v, served is false if the status code is not 200.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, prepare func(req *http.Request), v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	prepare(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, &unexpectedResponseError{msg: fmt.Sprintf("decode the response from %s: %v", endpoint, err)}
+	}
+	return true, nil
+}
+
+// probeHTTP sends a GET request to the url, the error is an unexpectedResponseError if the status code
+// is not expected.
+func probeHTTP(ctx context.Context, client *http.Client, url string, prepare func(req *http.Request), expected func(code int) bool) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if !expected(resp.StatusCode) {
+		return &unexpectedResponseError{msg: fmt.Sprintf("unexpected status code %d from %s", resp.StatusCode, url)}
+	}
+	return nil
+}
+
+// degradingChecks returns the health checks failed that make the region degraded, the failure of the dependencies
+// only counts if it is configured to.
+func degradingChecks(status *rainbondv1alpha1.RainbondClusterStatus, config *rainbondv1alpha1.HealthCheck) []string {
+	var checks []string
+	for _, check := range unhealthyChecks(status) {
+		if check == string(rainbondv1alpha1.RainbondClusterConditionTypeDependencyHealthy) && (config == nil || !config.DegradeOnDependencyFailure) {
+			continue
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// unhealthyChecks returns the health checks failed.
+func unhealthyChecks(status *rainbondv1alpha1.RainbondClusterStatus) []string {
+	var checks []string
+	for _, typ := range healthCheckTypes {
+		if _, condition := status.GetCondition(typ); condition != nil && condition.Status != corev1.ConditionTrue {
+			checks = append(checks, string(typ))
+		}
+	}
+	return checks
+}
@@ -0,0 +1,199 @@
+package clustermgr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProbeHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	ok := func(code int) bool { return code == http.StatusOK }
+	err := probeHTTP(context.Background(), server.Client(), server.URL, func(req *http.Request) {
+		req.SetBasicAuth("admin", "")
+	}, ok)
+	assert.Nil(t, err)
+
+	err = probeHTTP(context.Background(), server.Client(), server.URL, nil, ok)
+	_, unexpected := err.(*unexpectedResponseError)
+	assert.True(t, unexpected)
+
+	server.Close()
+	err = probeHTTP(context.Background(), server.Client(), server.URL, nil, ok)
+	_, unexpected = err.(*unexpectedResponseError)
+	assert.NotNil(t, err)
+	assert.False(t, unexpected)
+}
+
+func TestClusterPhaseUnhealthy(t *testing.T) {
+	status := &rainbondv1alpha1.RainbondClusterStatus{
+		Conditions: []rainbondv1alpha1.RainbondClusterCondition{
+			{Type: rainbondv1alpha1.RainbondClusterConditionTypeRunning, Status: corev1.ConditionTrue},
+			{Type: rainbondv1alpha1.RainbondClusterConditionTypeGatewayHealthy, Status: corev1.ConditionTrue},
+		},
+	}
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterRunning, clusterPhase(status, nil))
+
+	status.Conditions[1].Status = corev1.ConditionFalse
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterDegraded, clusterPhase(status, nil))
+	assert.Equal(t, "unhealthy: [GatewayHealthy]", degradedMessage(status))
+}
+
+func TestClusterPhaseDependencyUnhealthy(t *testing.T) {
+	status := &rainbondv1alpha1.RainbondClusterStatus{
+		Conditions: []rainbondv1alpha1.RainbondClusterCondition{
+			{Type: rainbondv1alpha1.RainbondClusterConditionTypeRunning, Status: corev1.ConditionTrue},
+			{Type: rainbondv1alpha1.RainbondClusterConditionTypeDependencyHealthy, Status: corev1.ConditionFalse},
+		},
+	}
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterRunning, clusterPhase(status, nil))
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterRunning, clusterPhase(status, &rainbondv1alpha1.HealthCheck{}))
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterDegraded, clusterPhase(status, &rainbondv1alpha1.HealthCheck{DegradeOnDependencyFailure: true}))
+}
+
+func TestProbeDependencies(t *testing.T) {
+	ok := dependency{name: "rbd-etcd", probe: func(ctx context.Context) error { return nil }}
+	failed := dependency{name: "rbd-db", probe: func(ctx context.Context) error { return errors.New("connection refused") }}
+
+	assert.Nil(t, probeDependencies(context.Background(), []dependency{ok}))
+	err := probeDependencies(context.Background(), []dependency{ok, failed})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "unreachable dependencies: rbd-db: connection refused", err.Error())
+	}
+}
+
+func TestProbeManifest(t *testing.T) {
+	tests := []struct {
+		name       string
+		catalog    int
+		manifest   int
+		wantErr    bool
+		wantMethod string
+	}{
+		{name: "pulled", catalog: http.StatusOK, manifest: http.StatusOK, wantMethod: http.MethodHead},
+		{name: "manifest unknown", catalog: http.StatusOK, manifest: http.StatusNotFound, wantErr: true, wantMethod: http.MethodHead},
+		{name: "catalog forbidden", catalog: http.StatusForbidden},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var method, accept string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v2/_catalog":
+					w.WriteHeader(tc.catalog)
+					_, _ = w.Write([]byte(`{"repositories":["rainbond/runner"]}`))
+				case "/v2/rainbond/runner/tags/list":
+					_, _ = w.Write([]byte(`{"name":"rainbond/runner","tags":["v5.3.3"]}`))
+				case "/v2/rainbond/runner/manifests/v5.3.3":
+					method, accept = r.Method, r.Header.Get("Accept")
+					w.WriteHeader(tc.manifest)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			err := probeManifest(context.Background(), server.Client(), server.URL, func(req *http.Request) {})
+			if tc.wantErr {
+				_, unexpected := err.(*unexpectedResponseError)
+				assert.True(t, unexpected)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tc.wantMethod, method)
+			if tc.wantMethod != "" {
+				assert.Equal(t, manifestMediaTypes[0], accept)
+			}
+		})
+	}
+}
+
+func TestHealthCheckResults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system", UID: "health-check"},
+		Status:     rainbondv1alpha1.RainbondClusterStatus{Phase: rainbondv1alpha1.RainbondClusterRunning},
+	}
+	mgr := NewClusterMgr(context.Background(), fake.NewFakeClientWithScheme(scheme, cluster), logr.Discard(), cluster, scheme)
+	// the probes are not started while they are running.
+	for _, typ := range healthCheckTypes {
+		healthCheckRunning.Store(healthCheckKey{uid: cluster.UID, typ: typ}, struct{}{})
+	}
+	defer func() {
+		for _, typ := range healthCheckTypes {
+			healthCheckRunning.Delete(healthCheckKey{uid: cluster.UID, typ: typ})
+		}
+	}()
+
+	mgr.healthCheck()
+	assert.Empty(t, unhealthyChecks(&cluster.Status))
+	assert.Equal(t, healthCheckTimeout, mgr.HealthCheckRequeueAfter())
+
+	gateway := healthCheckKey{uid: cluster.UID, typ: rainbondv1alpha1.RainbondClusterConditionTypeGatewayHealthy}
+	healthCheckResults.Store(gateway, rainbondv1alpha1.RainbondClusterCondition{
+		Type:              gateway.typ,
+		Status:            corev1.ConditionFalse,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+		Reason:            rainbondv1alpha1.ReasonHealthCheckUnreachable,
+	})
+	healthCheckRunning.Delete(gateway)
+	mgr.healthCheck()
+	assert.Equal(t, []string{string(gateway.typ)}, unhealthyChecks(&cluster.Status))
+	// the result is fresh, the probe is not started again.
+	_, running := healthCheckRunning.Load(gateway)
+	assert.False(t, running)
+
+	cluster.Status.Phase = rainbondv1alpha1.RainbondClusterPending
+	mgr.healthCheck()
+	assert.Empty(t, unhealthyChecks(&cluster.Status))
+	_, stored := healthCheckResults.Load(gateway)
+	assert.False(t, stored)
+}
+
+func TestDeleteHealthCheckMetrics(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "metrics"}}
+	typ := rainbondv1alpha1.RainbondClusterConditionTypeGatewayHealthy
+	observeHealthCheck(cluster, typ, time.Second, false)
+
+	deleteHealthCheckMetrics(cluster)
+	labels := prometheus.Labels{"namespace": cluster.Namespace, "name": cluster.Name, "check": string(typ)}
+	assert.False(t, healthCheckStatus.Delete(labels))
+	assert.False(t, healthCheckDuration.Delete(labels))
+	assert.False(t, healthCheckFailures.Delete(labels))
+}
+
+func TestClusterPhaseInstalling(t *testing.T) {
+	status := &rainbondv1alpha1.RainbondClusterStatus{}
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterPending, clusterPhase(status, nil))
+
+	status.Components = map[string]rainbondv1alpha1.ComponentVersionStatus{"rbd-api": {}}
+	status.Conditions = []rainbondv1alpha1.RainbondClusterCondition{
+		{Type: rainbondv1alpha1.RainbondClusterConditionTypeRunning, Status: corev1.ConditionFalse},
+	}
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterInstalling, clusterPhase(status, nil))
+
+	status.Conditions[0].Status = corev1.ConditionTrue
+	assert.Equal(t, rainbondv1alpha1.RainbondClusterRunning, clusterPhase(status, nil))
+}
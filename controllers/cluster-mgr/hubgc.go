@@ -0,0 +1,63 @@
+package clustermgr
+
+import (
+	"fmt"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// imageHubGCStatus returns the result of the last garbage collection of the image hub, or nil if there is none.
+func (r *RainbondClusteMgr) imageHubGCStatus() *rainbondv1alpha1.ImageHubGCStatus {
+	jobs := &batchv1.JobList{}
+	if err := r.client.List(r.ctx, jobs, client.InNamespace(r.cluster.Namespace), client.MatchingLabels{"name": handler.HubGCName}); err != nil {
+		status := r.cluster.Status.ImageHubGC.DeepCopy()
+		if status == nil {
+			status = &rainbondv1alpha1.ImageHubGCStatus{}
+		}
+		status.Message = fmt.Sprintf("list jobs of %s: %v", handler.HubGCName, err)
+		return status
+	}
+	return lastHubGCStatus(jobs.Items)
+}
+
+// lastHubGCStatus returns the status of the latest job of the garbage collection.
+func lastHubGCStatus(jobs []batchv1.Job) *rainbondv1alpha1.ImageHubGCStatus {
+	var last *batchv1.Job
+	for i := range jobs {
+		if last == nil || last.CreationTimestamp.Before(&jobs[i].CreationTimestamp) {
+			last = &jobs[i]
+		}
+	}
+	if last == nil {
+		return nil
+	}
+
+	status := &rainbondv1alpha1.ImageHubGCStatus{
+		JobName:        last.Name,
+		Phase:          rainbondv1alpha1.ImageHubGCRunning,
+		StartTime:      last.Status.StartTime,
+		CompletionTime: last.Status.CompletionTime,
+	}
+	for _, condition := range last.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.Phase = rainbondv1alpha1.ImageHubGCSucceeded
+		case batchv1.JobFailed:
+			status.Phase = rainbondv1alpha1.ImageHubGCFailed
+			// the completion time is only set for the succeeded jobs.
+			status.CompletionTime = condition.LastTransitionTime.DeepCopy()
+			status.Message = condition.Message
+			if status.Message == "" {
+				status.Message = condition.Reason
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,47 @@
+package clustermgr
+
+import (
+	"testing"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLastHubGCStatus(t *testing.T) {
+	assert.Nil(t, lastHubGCStatus(nil))
+
+	now := metav1.Now()
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+	job := func(name string, created metav1.Time, conditions ...batchv1.JobCondition) batchv1.Job {
+		return batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created},
+			Status:     batchv1.JobStatus{StartTime: &created, Conditions: conditions},
+		}
+	}
+	succeeded := job("rbd-hub-gc-1", earlier, batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue})
+	failed := job("rbd-hub-gc-2", now, batchv1.JobCondition{
+		Type:               batchv1.JobFailed,
+		Status:             corev1.ConditionTrue,
+		Reason:             "DeadlineExceeded",
+		LastTransitionTime: now,
+	})
+	running := job("rbd-hub-gc-2", now)
+
+	status := lastHubGCStatus([]batchv1.Job{succeeded})
+	assert.Equal(t, "rbd-hub-gc-1", status.JobName)
+	assert.Equal(t, rainbondv1alpha1.ImageHubGCSucceeded, status.Phase)
+
+	status = lastHubGCStatus([]batchv1.Job{failed, succeeded})
+	assert.Equal(t, "rbd-hub-gc-2", status.JobName)
+	assert.Equal(t, rainbondv1alpha1.ImageHubGCFailed, status.Phase)
+	assert.Equal(t, "DeadlineExceeded", status.Message)
+	assert.NotNil(t, status.CompletionTime)
+
+	status = lastHubGCStatus([]batchv1.Job{succeeded, running})
+	assert.Equal(t, rainbondv1alpha1.ImageHubGCRunning, status.Phase)
+	assert.Nil(t, status.CompletionTime)
+}
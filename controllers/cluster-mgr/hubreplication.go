@@ -0,0 +1,106 @@
+package clustermgr
+
+import (
+	"fmt"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/prometheus/client_golang/prometheus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// imageHubReplicationStatus returns the result of the replication of the image hub, or nil if it is disabled.
+// The lag is also exposed by the metric rainbond_image_hub_replication_lag_seconds.
+func (r *RainbondClusteMgr) imageHubReplicationStatus() *rainbondv1alpha1.ImageHubReplicationStatus {
+	labels := prometheus.Labels{"namespace": r.cluster.Namespace, "name": r.cluster.Name}
+	if r.cluster.Spec.ImageHub == nil || r.cluster.Spec.ImageHub.Replication == nil {
+		imageHubReplicationLag.Delete(labels)
+		return nil
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := r.client.List(r.ctx, jobs, client.InNamespace(r.cluster.Namespace), client.MatchingLabels{"name": handler.HubReplicationName}); err != nil {
+		status := r.cluster.Status.ImageHubReplication.DeepCopy()
+		if status == nil {
+			status = &rainbondv1alpha1.ImageHubReplicationStatus{}
+		}
+		status.Message = fmt.Sprintf("list jobs of %s: %v", handler.HubReplicationName, err)
+		return status
+	}
+	status := lastHubReplicationStatus(jobs.Items, r.cluster.Status.ImageHubReplication, time.Now())
+	if status.LastSucceededTime != nil {
+		imageHubReplicationLag.With(labels).Set(hubReplicationLag(status.LastSucceededTime.Time, time.Now()).Seconds())
+	}
+	return status
+}
+
+// lastHubReplicationStatus returns the status of the latest job of the replication. The last succeeded time is kept
+// from the previous status if the succeeded jobs have been removed from the history, such as by the failed ones.
+func lastHubReplicationStatus(jobs []batchv1.Job, previous *rainbondv1alpha1.ImageHubReplicationStatus, now time.Time) *rainbondv1alpha1.ImageHubReplicationStatus {
+	status := &rainbondv1alpha1.ImageHubReplicationStatus{}
+	if previous != nil {
+		status.LastSucceededTime = previous.LastSucceededTime.DeepCopy()
+	}
+
+	var last *batchv1.Job
+	for i := range jobs {
+		job := &jobs[i]
+		if last == nil || last.CreationTimestamp.Before(&job.CreationTimestamp) {
+			last = job
+		}
+		if succeeded, _ := jobResult(job); succeeded && job.Status.StartTime != nil &&
+			(status.LastSucceededTime == nil || status.LastSucceededTime.Before(job.Status.StartTime)) {
+			status.LastSucceededTime = job.Status.StartTime.DeepCopy()
+		}
+	}
+
+	if last != nil {
+		status.JobName = last.Name
+		status.Phase = rainbondv1alpha1.ImageHubReplicationRunning
+		succeeded, failed := jobResult(last)
+		if succeeded {
+			status.Phase = rainbondv1alpha1.ImageHubReplicationSucceeded
+		}
+		if failed != nil {
+			status.Phase = rainbondv1alpha1.ImageHubReplicationFailed
+			status.Message = failed.Message
+			if status.Message == "" {
+				status.Message = failed.Reason
+			}
+		}
+	}
+	if status.LastSucceededTime != nil {
+		status.Lag = hubReplicationLag(status.LastSucceededTime.Time, now).String()
+	}
+	return status
+}
+
+// hubReplicationLag returns how long the replication lags behind the image hub, from the start of the last succeeded
+// job to now, rounded to seconds. It is zero if the clock of the job is ahead of the operator.
+func hubReplicationLag(lastSucceeded, now time.Time) time.Duration {
+	lag := now.Sub(lastSucceeded).Round(time.Second)
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// jobResult returns whether the job has completed, or the condition it has failed with.
+func jobResult(job *batchv1.Job) (bool, *batchv1.JobCondition) {
+	for i := range job.Status.Conditions {
+		condition := &job.Status.Conditions[i]
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return false, condition
+		}
+	}
+	return false, nil
+}
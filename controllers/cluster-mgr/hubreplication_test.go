@@ -0,0 +1,137 @@
+package clustermgr
+
+import (
+	"testing"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLastHubReplicationStatus(t *testing.T) {
+	now := time.Now()
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+	later := metav1.NewTime(now.Add(-15 * time.Minute))
+	job := func(name string, created metav1.Time, conditions ...batchv1.JobCondition) batchv1.Job {
+		return batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created},
+			Status:     batchv1.JobStatus{StartTime: &created, Conditions: conditions},
+		}
+	}
+	succeeded := job("rbd-hub-replication-1", earlier, batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue})
+	failed := job("rbd-hub-replication-2", later, batchv1.JobCondition{
+		Type:   batchv1.JobFailed,
+		Status: corev1.ConditionTrue,
+		Reason: "BackoffLimitExceeded",
+	})
+	running := job("rbd-hub-replication-2", later)
+	laterSucceeded := job("rbd-hub-replication-2", later, batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue})
+
+	tests := []struct {
+		name              string
+		jobs              []batchv1.Job
+		previous          *rainbondv1alpha1.ImageHubReplicationStatus
+		wantJob           string
+		wantPhase         rainbondv1alpha1.ImageHubReplicationPhase
+		wantLastSucceeded *metav1.Time
+		wantLag           string
+		wantMsg           string
+	}{
+		{
+			name: "no jobs",
+		},
+		{
+			name:              "succeeded",
+			jobs:              []batchv1.Job{succeeded},
+			wantJob:           "rbd-hub-replication-1",
+			wantPhase:         rainbondv1alpha1.ImageHubReplicationSucceeded,
+			wantLastSucceeded: &earlier,
+			wantLag:           "1h0m0s",
+		},
+		{
+			name:              "failed after succeeded",
+			jobs:              []batchv1.Job{failed, succeeded},
+			wantJob:           "rbd-hub-replication-2",
+			wantPhase:         rainbondv1alpha1.ImageHubReplicationFailed,
+			wantLastSucceeded: &earlier,
+			wantLag:           "1h0m0s",
+			wantMsg:           "BackoffLimitExceeded",
+		},
+		{
+			name:      "running",
+			jobs:      []batchv1.Job{running},
+			wantJob:   "rbd-hub-replication-2",
+			wantPhase: rainbondv1alpha1.ImageHubReplicationRunning,
+		},
+		{
+			name:              "running after succeeded",
+			jobs:              []batchv1.Job{succeeded, running},
+			wantJob:           "rbd-hub-replication-2",
+			wantPhase:         rainbondv1alpha1.ImageHubReplicationRunning,
+			wantLastSucceeded: &earlier,
+			wantLag:           "1h0m0s",
+		},
+		{
+			name:              "the later of the succeeded jobs",
+			jobs:              []batchv1.Job{succeeded, laterSucceeded},
+			previous:          &rainbondv1alpha1.ImageHubReplicationStatus{LastSucceededTime: &earlier},
+			wantJob:           "rbd-hub-replication-2",
+			wantPhase:         rainbondv1alpha1.ImageHubReplicationSucceeded,
+			wantLastSucceeded: &later,
+			wantLag:           "15m0s",
+		},
+		{
+			name:              "succeeded jobs removed from the history",
+			jobs:              []batchv1.Job{failed},
+			previous:          &rainbondv1alpha1.ImageHubReplicationStatus{LastSucceededTime: &earlier},
+			wantJob:           "rbd-hub-replication-2",
+			wantPhase:         rainbondv1alpha1.ImageHubReplicationFailed,
+			wantLastSucceeded: &earlier,
+			wantLag:           "1h0m0s",
+			wantMsg:           "BackoffLimitExceeded",
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			status := lastHubReplicationStatus(tc.jobs, tc.previous, now)
+			assert.Equal(t, tc.wantJob, status.JobName)
+			assert.Equal(t, tc.wantPhase, status.Phase)
+			assert.Equal(t, tc.wantLastSucceeded, status.LastSucceededTime)
+			assert.Equal(t, tc.wantLag, status.Lag)
+			assert.Equal(t, tc.wantMsg, status.Message)
+		})
+	}
+}
+
+func TestHubReplicationLag(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		lastSucceeded time.Time
+		want          time.Duration
+	}{
+		{
+			name:          "rounded to seconds",
+			lastSucceeded: now.Add(-90*time.Second - 600*time.Millisecond),
+			want:          91 * time.Second,
+		},
+		{
+			name:          "just succeeded",
+			lastSucceeded: now,
+		},
+		{
+			name:          "clock of the job ahead",
+			lastSucceeded: now.Add(3 * time.Second),
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, hubReplicationLag(tc.lastSucceeded, now))
+		})
+	}
+}
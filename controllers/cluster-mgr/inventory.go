@@ -0,0 +1,174 @@
+package clustermgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// inventoryImagesKey is the key of the images in the ConfigMap of the image inventory, in JSON.
+	inventoryImagesKey = "images.json"
+	// inventoryVersionKey is the key of the install version in the ConfigMap of the image inventory.
+	inventoryVersionKey = "installVersion"
+)
+
+// inventoryImage is an image running in the region.
+type inventoryImage struct {
+	Name string `json:"name"`
+	Tag  string `json:"tag,omitempty"`
+	// Digest is the digest of the image pulled by the container runtime, empty if it is not pulled from a registry.
+	Digest string `json:"digest,omitempty"`
+	// Components are the rbdcomponents using the image.
+	Components []string `json:"components,omitempty"`
+}
+
+// imageInventoryStatus records the images running in the region in the ConfigMap, and returns the summary.
+func (r *RainbondClusteMgr) imageInventoryStatus() *rainbondv1alpha1.ImageInventoryStatus {
+	status := &rainbondv1alpha1.ImageInventoryStatus{
+		ConfigMapName:  constants.ImageInventoryConfigMapName,
+		InstallVersion: r.cluster.Spec.InstallVersion,
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.client.List(r.ctx, pods, client.InNamespace(r.cluster.Namespace), client.MatchingLabels(rbdutil.LabelsForRainbond(nil))); err != nil {
+		status.Message = fmt.Sprintf("list pods: %v", err)
+		return status
+	}
+	images := imageInventory(pods.Items)
+	status.Images = len(images)
+
+	if err := r.updateImageInventory(images); err != nil {
+		status.Message = err.Error()
+	}
+	return status
+}
+
+func (r *RainbondClusteMgr) updateImageInventory(images []inventoryImage) error {
+	body, err := json.MarshalIndent(images, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal images: %v", err)
+	}
+	data := map[string]string{
+		inventoryVersionKey: r.cluster.Spec.InstallVersion,
+		inventoryImagesKey:  string(body),
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = r.client.Get(r.ctx, types.NamespacedName{Namespace: r.cluster.Namespace, Name: constants.ImageInventoryConfigMapName}, cm)
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("get configmap %s: %v", constants.ImageInventoryConfigMapName, err)
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.ImageInventoryConfigMapName,
+				Namespace: r.cluster.Namespace,
+				Labels:    rbdutil.LabelsForRainbond(nil),
+			},
+			Data: data,
+		}
+		if err := controllerutil.SetControllerReference(r.cluster, cm, r.scheme); err != nil {
+			return fmt.Errorf("set controller reference: %v", err)
+		}
+		if err := r.client.Create(r.ctx, cm); err != nil {
+			return fmt.Errorf("create configmap %s: %v", constants.ImageInventoryConfigMapName, err)
+		}
+		return nil
+	}
+	if reflect.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	cm.Data = data
+	if err := r.client.Update(r.ctx, cm); err != nil {
+		return fmt.Errorf("update configmap %s: %v", constants.ImageInventoryConfigMapName, err)
+	}
+	return nil
+}
+
+// imageInventory returns the distinct images of the running pods, sorted by the names and the tags.
+func imageInventory(pods []corev1.Pod) []inventoryImage {
+	indexes := make(map[string]int)
+	images := []inventoryImage{}
+	add := func(image inventoryImage, component string) {
+		key := image.Name + ":" + image.Tag + "@" + image.Digest
+		idx, ok := indexes[key]
+		if !ok {
+			idx = len(images)
+			indexes[key] = idx
+			images = append(images, image)
+		}
+		if component == "" {
+			return
+		}
+		for _, c := range images[idx].Components {
+			if c == component {
+				return
+			}
+		}
+		images[idx].Components = append(images[idx].Components, component)
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		imageIDs := make(map[string]string)
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				imageIDs[status.Name] = status.ImageID
+			}
+		}
+		for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+			for _, container := range containers {
+				add(parseInventoryImage(container.Image, imageIDs[container.Name]), pod.Labels["name"])
+			}
+		}
+	}
+
+	for i := range images {
+		sort.Strings(images[i].Components)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Name != images[j].Name {
+			return images[i].Name < images[j].Name
+		}
+		if images[i].Tag != images[j].Tag {
+			return images[i].Tag < images[j].Tag
+		}
+		return images[i].Digest < images[j].Digest
+	})
+	return images
+}
+
+// parseInventoryImage parses the image of the container, and the digest from the image id
+// reported by the container runtime, such as docker-pullable://goodrain.me/rbd-api@sha256:xxx.
+func parseInventoryImage(image, imageID string) inventoryImage {
+	result := inventoryImage{Name: image}
+	if named, err := reference.ParseNormalizedNamed(image); err == nil {
+		result.Name = named.Name()
+		if tagged, ok := named.(reference.Tagged); ok {
+			result.Tag = tagged.Tag()
+		}
+		if digested, ok := named.(reference.Digested); ok {
+			result.Digest = digested.Digest().String()
+		}
+	}
+	if idx := strings.LastIndex(imageID, "@"); idx != -1 {
+		result.Digest = imageID[idx+1:]
+	}
+	return result
+}
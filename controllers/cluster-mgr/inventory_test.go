@@ -0,0 +1,36 @@
+package clustermgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImageInventory(t *testing.T) {
+	pod := func(name, component, image, imageID string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"name": component}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: component, Image: image}},
+			},
+			Status: corev1.PodStatus{
+				Phase:             phase,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: component, ImageID: imageID}},
+			},
+		}
+	}
+	pods := []corev1.Pod{
+		pod("rbd-api-0", "rbd-api", "goodrain.me/rbd-api:v5.3.3", "docker-pullable://goodrain.me/rbd-api@sha256:aaa", corev1.PodRunning),
+		pod("rbd-api-1", "rbd-api", "goodrain.me/rbd-api:v5.3.3", "docker-pullable://goodrain.me/rbd-api@sha256:aaa", corev1.PodRunning),
+		pod("rbd-worker-0", "rbd-worker", "goodrain.me/rbd-worker:v5.3.3", "sha256:bbb", corev1.PodRunning),
+		pod("rbd-package-image-x", "", "docker:20.10", "docker-pullable://docker@sha256:ccc", corev1.PodSucceeded),
+	}
+
+	images := imageInventory(pods)
+	assert.Equal(t, []inventoryImage{
+		{Name: "goodrain.me/rbd-api", Tag: "v5.3.3", Digest: "sha256:aaa", Components: []string{"rbd-api"}},
+		{Name: "goodrain.me/rbd-worker", Tag: "v5.3.3", Components: []string{"rbd-worker"}},
+	}, images)
+}
@@ -0,0 +1,105 @@
+package clustermgr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// cniDaemonSets maps the name prefixes of the daemonsets of the network plugins to the plugins.
+// canal is checked before calico and flannel, since it is made of both.
+var cniDaemonSets = []struct {
+	prefix string
+	cni    string
+}{
+	{"canal", "canal"},
+	{"calico-node", "calico"},
+	{"kube-flannel", "flannel"},
+	{"cilium", "cilium"},
+	{"weave-net", "weave"},
+	{"kube-ovn-cni", "kube-ovn"},
+	{"antrea-agent", "antrea"},
+}
+
+// kubernetesStatus returns the information about the kubernetes cluster.
+func (r *RainbondClusteMgr) kubernetesStatus() *rainbondv1alpha1.KubernetesStatus {
+	nodes, err := k8sutil.ListNodes(r.ctx, r.client)
+	if err != nil {
+		return &rainbondv1alpha1.KubernetesStatus{Message: fmt.Sprintf("list nodes: %v", err)}
+	}
+	status := kubernetesNodesStatus(nodes)
+
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := r.client.List(r.ctx, daemonSets); err != nil {
+		status.Message = fmt.Sprintf("list daemonsets: %v", err)
+		return status
+	}
+	status.CNI = detectCNI(daemonSets.Items)
+	return status
+}
+
+// kubernetesNodesStatus summarizes the nodes. Only the ready nodes count towards the allocatable resources.
+func kubernetesNodesStatus(nodes []corev1.Node) *rainbondv1alpha1.KubernetesStatus {
+	status := &rainbondv1alpha1.KubernetesStatus{
+		Nodes: len(nodes),
+	}
+	var lowest *version.Version
+	runtimes := make(map[string]struct{})
+	for _, node := range nodes {
+		info := node.Status.NodeInfo
+		if v, err := version.ParseGeneric(info.KubeletVersion); err == nil && (lowest == nil || v.LessThan(lowest)) {
+			lowest = v
+			status.Version = info.KubeletVersion
+		}
+		if info.ContainerRuntimeVersion != "" {
+			runtimes[info.ContainerRuntimeVersion] = struct{}{}
+		}
+
+		if !nodeReady(node) {
+			continue
+		}
+		status.ReadyNodes++
+		if cpu, ok := node.Status.Allocatable[corev1.ResourceCPU]; ok {
+			status.AllocatableCPU.Add(cpu)
+		}
+		if memory, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+			status.AllocatableMemory.Add(memory)
+		}
+	}
+	for runtime := range runtimes {
+		status.ContainerRuntimes = append(status.ContainerRuntimes, runtime)
+	}
+	sort.Strings(status.ContainerRuntimes)
+	// drop the format of the first added quantity, such as 4 cores plus 3900m.
+	status.AllocatableCPU = *resource.NewMilliQuantity(status.AllocatableCPU.MilliValue(), resource.DecimalSI)
+	status.AllocatableMemory = *resource.NewQuantity(status.AllocatableMemory.Value(), resource.BinarySI)
+	return status
+}
+
+func nodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// detectCNI returns the network plugin running as one of the daemonsets, or empty if it is unknown.
+func detectCNI(daemonSets []appsv1.DaemonSet) string {
+	for _, candidate := range cniDaemonSets {
+		for _, ds := range daemonSets {
+			if strings.HasPrefix(ds.Name, candidate.prefix) {
+				return candidate.cni
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package clustermgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKubernetesNodesStatus(t *testing.T) {
+	node := func(kubelet, runtime, cpu, memory string, ready corev1.ConditionStatus) corev1.Node {
+		return corev1.Node{
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{KubeletVersion: kubelet, ContainerRuntimeVersion: runtime},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				},
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			},
+		}
+	}
+	status := kubernetesNodesStatus([]corev1.Node{
+		node("v1.20.1", "containerd://1.4.3", "4", "8Gi", corev1.ConditionTrue),
+		node("v1.19.7", "docker://19.3.14", "3900m", "8Gi", corev1.ConditionTrue),
+		node("v1.20.1", "containerd://1.4.3", "8", "16Gi", corev1.ConditionFalse),
+	})
+
+	assert.Equal(t, "v1.19.7", status.Version)
+	assert.Equal(t, 3, status.Nodes)
+	assert.Equal(t, 2, status.ReadyNodes)
+	assert.Equal(t, "7900m", status.AllocatableCPU.String())
+	assert.Equal(t, "16Gi", status.AllocatableMemory.String())
+	assert.Equal(t, []string{"containerd://1.4.3", "docker://19.3.14"}, status.ContainerRuntimes)
+}
+
+func TestDetectCNI(t *testing.T) {
+	ds := func(names ...string) []appsv1.DaemonSet {
+		var items []appsv1.DaemonSet
+		for _, name := range names {
+			items = append(items, appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+		return items
+	}
+	assert.Equal(t, "calico", detectCNI(ds("kube-proxy", "calico-node")))
+	assert.Equal(t, "flannel", detectCNI(ds("kube-flannel-ds-amd64")))
+	assert.Equal(t, "canal", detectCNI(ds("kube-flannel", "canal")))
+	assert.Equal(t, "", detectCNI(ds("kube-proxy")))
+}
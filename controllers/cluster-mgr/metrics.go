@@ -0,0 +1,53 @@
+package clustermgr
+
+import (
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	healthCheckStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rainbond_region_health_check_status",
+		Help: "Result of the last health check of the region, 1 if it passed and 0 if it failed.",
+	}, []string{"namespace", "name", "check"})
+	healthCheckDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rainbond_region_health_check_duration_seconds",
+		Help: "Duration of the last health check of the region in seconds.",
+	}, []string{"namespace", "name", "check"})
+	healthCheckFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rainbond_region_health_check_failures_total",
+		Help: "Total number of the failed health checks of the region.",
+	}, []string{"namespace", "name", "check"})
+	imageHubReplicationLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rainbond_image_hub_replication_lag_seconds",
+		Help: "Time since the start of the last succeeded replication of the image hub to the secondary registry.",
+	}, []string{"namespace", "name"})
+)
+
+func init() {
+	// served on the metrics endpoint of the manager.
+	metrics.Registry.MustRegister(healthCheckStatus, healthCheckDuration, healthCheckFailures, imageHubReplicationLag)
+}
+
+func observeHealthCheck(cluster *rainbondv1alpha1.RainbondCluster, typ rainbondv1alpha1.RainbondClusterConditionType, duration time.Duration, passed bool) {
+	labels := prometheus.Labels{"namespace": cluster.Namespace, "name": cluster.Name, "check": string(typ)}
+	healthCheckDuration.With(labels).Set(duration.Seconds())
+	if passed {
+		healthCheckStatus.With(labels).Set(1)
+		return
+	}
+	healthCheckStatus.With(labels).Set(0)
+	healthCheckFailures.With(labels).Inc()
+}
+
+func deleteHealthCheckMetrics(cluster *rainbondv1alpha1.RainbondCluster) {
+	for _, typ := range healthCheckTypes {
+		labels := prometheus.Labels{"namespace": cluster.Namespace, "name": cluster.Name, "check": string(typ)}
+		healthCheckStatus.Delete(labels)
+		healthCheckDuration.Delete(labels)
+		healthCheckFailures.Delete(labels)
+	}
+}
@@ -0,0 +1,104 @@
+package clustermgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LabelRoleNodes labels the nodes assigned to the roles in spec.nodeRoles, and returns the schedulable nodes of the
+// gateway and the build roles, which are nil if no nodes are assigned to the roles. The build role is left to
+// LabelChaosNodes if the chaos node selector is specified.
+func (r *RainbondClusteMgr) LabelRoleNodes() (gateway, build []*rainbondv1alpha1.K8sNode, err error) {
+	roles := r.cluster.Spec.NodeRoles
+	if roles == nil {
+		return nil, nil, nil
+	}
+	gatewayNodes, err := r.labelRoleNodes("rbd-gateway", constants.SpecialGatewayLabelKey, constants.GatewayLabeledAnnotation, nodeNamed(roles.Gateway))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(roles.Gateway) > 0 {
+		gateway = gatewayNodes
+	}
+	if r.cluster.Spec.ChaosNodeSelector == nil {
+		buildNodes, err := r.labelRoleNodes("rbd-chaos", constants.SpecialChaosLabelKey, constants.ChaosLabeledAnnotation, nodeNamed(roles.Build))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(roles.Build) > 0 {
+			build = buildNodes
+		}
+	}
+	if _, err := r.labelRoleNodes("storage", constants.SpecialStorageLabelKey, constants.StorageLabeledAnnotation, nodeNamed(roles.Storage)); err != nil {
+		return nil, nil, err
+	}
+	return gateway, build, nil
+}
+
+func nodeNamed(names []string) func(node *corev1.Node) bool {
+	return func(node *corev1.Node) bool {
+		for _, name := range names {
+			if node.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// labelRoleNodes labels the selected nodes with the label of the role, and returns the selected nodes that are
+// schedulable. The annotation records the namespace of the operator that added the label, which is removed from
+// the nodes no longer selected. The labels added by the administrators or the other rainbonds are kept.
+func (r *RainbondClusteMgr) labelRoleNodes(role, labelKey, labeledAnnotation string, selected func(node *corev1.Node) bool) ([]*rainbondv1alpha1.K8sNode, error) {
+	nodeList := &corev1.NodeList{}
+	ctx, cancel := context.WithTimeout(r.ctx, time.Second*10)
+	defer cancel()
+	if err := r.client.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("list nodes: %v", err)
+	}
+
+	var nodes []corev1.Node
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		_, labeled := node.Labels[labelKey]
+		if !selected(node) {
+			if !labeled || node.Annotations[labeledAnnotation] != r.cluster.Namespace {
+				continue
+			}
+			patch := client.MergeFrom(node.DeepCopy())
+			delete(node.Labels, labelKey)
+			delete(node.Annotations, labeledAnnotation)
+			if err := r.client.Patch(ctx, node, patch); err != nil {
+				return nil, fmt.Errorf("unlabel node %s: %v", node.Name, err)
+			}
+			r.log.Info("unlabel node deselected for "+role, "node", node.Name)
+			continue
+		}
+		if node.Spec.Unschedulable {
+			continue
+		}
+		if !labeled {
+			patch := client.MergeFrom(node.DeepCopy())
+			if node.Labels == nil {
+				node.Labels = make(map[string]string)
+			}
+			node.Labels[labelKey] = ""
+			if node.Annotations == nil {
+				node.Annotations = make(map[string]string)
+			}
+			node.Annotations[labeledAnnotation] = r.cluster.Namespace
+			if err := r.client.Patch(ctx, node, patch); err != nil {
+				return nil, fmt.Errorf("label node %s: %v", node.Name, err)
+			}
+			r.log.Info("label node for "+role, "node", node.Name)
+		}
+		nodes = append(nodes, *node)
+	}
+	return k8sNodes(nodes), nil
+}
@@ -0,0 +1,65 @@
+package clustermgr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLabelRoleNodes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	node := func(name string, labels, annotations map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations}}
+	}
+	cli := fake.NewFakeClientWithScheme(scheme,
+		node("node1", nil, nil),
+		node("node2", map[string]string{constants.SpecialGatewayLabelKey: ""},
+			map[string]string{constants.GatewayLabeledAnnotation: "rbd-system"}),
+		node("node3", map[string]string{constants.SpecialStorageLabelKey: ""}, nil),
+	)
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			NodeRoles: &rainbondv1alpha1.NodeRoles{
+				Gateway: []string{"node1"},
+				Storage: []string{"node1"},
+			},
+		},
+	}
+	mgr := NewClusterMgr(context.Background(), cli, logr.Discard(), cluster, scheme)
+
+	gateway, build, err := mgr.LabelRoleNodes()
+	assert.Nil(t, err)
+	if assert.Len(t, gateway, 1) {
+		assert.Equal(t, "node1", gateway[0].Name)
+	}
+	// the nodes of rbd-chaos are kept if no nodes are assigned to the build role.
+	assert.Nil(t, build)
+
+	labels := func(name string) map[string]string {
+		got := &corev1.Node{}
+		if err := cli.Get(context.Background(), types.NamespacedName{Name: name}, got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Labels
+	}
+	assert.Contains(t, labels("node1"), constants.SpecialGatewayLabelKey)
+	assert.Contains(t, labels("node1"), constants.SpecialStorageLabelKey)
+	assert.NotContains(t, labels("node1"), constants.SpecialChaosLabelKey)
+	// the label added by the operator is removed once the node is no longer assigned.
+	assert.NotContains(t, labels("node2"), constants.SpecialGatewayLabelKey)
+	// the label added by the administrators is kept.
+	assert.Contains(t, labels("node3"), constants.SpecialStorageLabelKey)
+}
@@ -0,0 +1,212 @@
+package clustermgr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/notification"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxPendingNotifications bounds the events queued while the webhook is unreachable, the oldest ones are dropped.
+const maxPendingNotifications = 100
+
+var (
+	// notificationRetryInterval is the interval before the first retry of a failed notification, which is doubled
+	// by each retry up to maxNotificationRetryInterval.
+	notificationRetryInterval    = 5 * time.Second
+	maxNotificationRetryInterval = 5 * time.Minute
+)
+
+// notificationQueues are the queues of the notifications by the uids of the rainbondclusters.
+var notificationQueues sync.Map
+
+// notificationQueue are the events of a region to be sent in order. They are sent in the background, and the
+// failed one is retried until it is sent, so that the events are neither lost nor reordered by a flaky webhook.
+type notificationQueue struct {
+	mu      sync.Mutex
+	config  rainbondv1alpha1.Notification
+	events  []*notification.Event
+	sending bool
+}
+
+// clusterPhase summarizes the status of the region. The region is installing from the creation of the rbdcomponents
+// until the Running condition is true, and degraded if some components are not ready or some health checks fail
+// after it has been running.
+func clusterPhase(status *rainbondv1alpha1.RainbondClusterStatus, healthCheck *rainbondv1alpha1.HealthCheck) rainbondv1alpha1.RainbondClusterPhase {
+	if suspension := status.Suspension; suspension != nil {
+		switch suspension.Phase {
+		case rainbondv1alpha1.SuspensionPhaseSuspended:
+			return rainbondv1alpha1.RainbondClusterSuspended
+		case rainbondv1alpha1.SuspensionPhaseResuming:
+			return rainbondv1alpha1.RainbondClusterResuming
+		}
+		return rainbondv1alpha1.RainbondClusterSuspending
+	}
+	if _, running := status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeRunning); running == nil || running.Status != corev1.ConditionTrue {
+		if len(status.Components) > 0 {
+			return rainbondv1alpha1.RainbondClusterInstalling
+		}
+		return rainbondv1alpha1.RainbondClusterPending
+	}
+	if len(notReadyComponents(status)) > 0 || len(degradingChecks(status, healthCheck)) > 0 {
+		return rainbondv1alpha1.RainbondClusterDegraded
+	}
+	return rainbondv1alpha1.RainbondClusterRunning
+}
+
+// Notify queues the events between the old and the new status to be posted to the webhook of the notification.
+// The pending events are discarded once the notification is disabled.
+func (r *RainbondClusteMgr) Notify(old, new *rainbondv1alpha1.RainbondClusterStatus) error {
+	config := r.cluster.Spec.Notification
+	if config == nil || config.URL == "" {
+		if v, ok := notificationQueues.Load(r.cluster.UID); ok {
+			q := v.(*notificationQueue)
+			q.mu.Lock()
+			q.events = nil
+			q.mu.Unlock()
+		}
+		return nil
+	}
+	events := notificationEvents(r.cluster.Namespace+"/"+r.cluster.Name, old, new)
+	if len(events) == 0 {
+		return nil
+	}
+
+	v, _ := notificationQueues.LoadOrStore(r.cluster.UID, &notificationQueue{})
+	q := v.(*notificationQueue)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.config = *config
+	q.events = append(q.events, events...)
+	if !q.sending {
+		q.sending = true
+		key := types.NamespacedName{Namespace: r.cluster.Namespace, Name: r.cluster.Name}
+		go q.send(r.client, key, r.log)
+	}
+	if dropped := len(q.events) - maxPendingNotifications; dropped > 0 {
+		q.events = q.events[dropped:]
+		return fmt.Errorf("drop %d notifications, the webhook %s is unreachable", dropped, config.URL)
+	}
+	return nil
+}
+
+// send posts the queued events in order until the queue is empty. The failed event is retried with backoff,
+// unless the rainbondcluster is deleted.
+func (q *notificationQueue) send(reader client.Reader, key types.NamespacedName, log logr.Logger) {
+	interval := notificationRetryInterval
+	for {
+		q.mu.Lock()
+		if len(q.events) == 0 {
+			q.sending = false
+			q.mu.Unlock()
+			return
+		}
+		event, config := q.events[0], q.config
+		q.mu.Unlock()
+
+		err := notification.Send(context.Background(), config.URL, string(config.Format), event)
+		if err == nil {
+			log.Info("notified", "reason", event.Reason, "component", event.Component)
+			interval = notificationRetryInterval
+			q.mu.Lock()
+			if len(q.events) > 0 && q.events[0] == event {
+				q.events = q.events[1:]
+			}
+			q.mu.Unlock()
+			continue
+		}
+
+		if k8sErrors.IsNotFound(reader.Get(context.Background(), key, &rainbondv1alpha1.RainbondCluster{})) {
+			q.mu.Lock()
+			q.events = nil
+			q.mu.Unlock()
+			continue
+		}
+		log.Error(err, "send notification, retry later", "reason", event.Reason, "retryAfter", interval.String())
+		time.Sleep(interval)
+		if interval *= 2; interval > maxNotificationRetryInterval {
+			interval = maxNotificationRetryInterval
+		}
+	}
+}
+
+// notificationEvents returns the phase change of the region, and the components degraded or recovered
+// while the region is running. The components are not watched while it is installed or suspended.
+func notificationEvents(region string, old, new *rainbondv1alpha1.RainbondClusterStatus) []*notification.Event {
+	now := time.Now()
+	var events []*notification.Event
+	if old.Phase != new.Phase && old.Phase != "" {
+		event := &notification.Event{
+			Reason: notification.ReasonPhaseChanged,
+			Region: region,
+			From:   string(old.Phase),
+			To:     string(new.Phase),
+			Time:   now,
+		}
+		if new.Phase == rainbondv1alpha1.RainbondClusterDegraded {
+			event.Message = degradedMessage(new)
+		}
+		events = append(events, event)
+	}
+
+	watched := func(phase rainbondv1alpha1.RainbondClusterPhase) bool {
+		return phase == rainbondv1alpha1.RainbondClusterRunning || phase == rainbondv1alpha1.RainbondClusterDegraded
+	}
+	if !watched(old.Phase) || !watched(new.Phase) {
+		return events
+	}
+	var names []string
+	for name := range new.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		previous, ok := old.Components[name]
+		if !ok || previous.Ready == new.Components[name].Ready {
+			continue
+		}
+		reason := notification.ReasonComponentDegraded
+		if new.Components[name].Ready {
+			reason = notification.ReasonComponentRecovered
+		}
+		events = append(events, &notification.Event{
+			Reason:    reason,
+			Region:    region,
+			Component: name,
+			Time:      now,
+		})
+	}
+	return events
+}
+
+func degradedMessage(status *rainbondv1alpha1.RainbondClusterStatus) string {
+	var msgs []string
+	if names := notReadyComponents(status); len(names) > 0 {
+		msgs = append(msgs, fmt.Sprintf("not ready: %v", names))
+	}
+	if checks := unhealthyChecks(status); len(checks) > 0 {
+		msgs = append(msgs, fmt.Sprintf("unhealthy: %v", checks))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func notReadyComponents(status *rainbondv1alpha1.RainbondClusterStatus) []string {
+	var names []string
+	for name, cpt := range status.Components {
+		if !cpt.Ready {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
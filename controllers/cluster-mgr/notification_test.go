@@ -0,0 +1,101 @@
+package clustermgr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/notification"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNotificationEvents(t *testing.T) {
+	status := func(phase rainbondv1alpha1.RainbondClusterPhase, apiReady bool) *rainbondv1alpha1.RainbondClusterStatus {
+		return &rainbondv1alpha1.RainbondClusterStatus{
+			Phase: phase,
+			Components: map[string]rainbondv1alpha1.ComponentVersionStatus{
+				"rbd-api":    {Ready: apiReady},
+				"rbd-worker": {Ready: true},
+			},
+		}
+	}
+
+	events := notificationEvents("rbd-system/rainbondcluster", status(rainbondv1alpha1.RainbondClusterRunning, true), status(rainbondv1alpha1.RainbondClusterDegraded, false))
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, notification.ReasonPhaseChanged, events[0].Reason)
+		assert.Equal(t, "not ready: [rbd-api]", events[0].Message)
+		assert.Equal(t, notification.ReasonComponentDegraded, events[1].Reason)
+		assert.Equal(t, "rbd-api", events[1].Component)
+	}
+
+	// the components are not watched while the region is being installed.
+	events = notificationEvents("rbd-system/rainbondcluster", status(rainbondv1alpha1.RainbondClusterPending, false), status(rainbondv1alpha1.RainbondClusterPending, true))
+	assert.Len(t, events, 0)
+}
+
+func TestNotifyRetry(t *testing.T) {
+	defer func(interval time.Duration) { notificationRetryInterval = interval }(notificationRetryInterval)
+	notificationRetryInterval = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	var requests int
+	var received []notification.Reason
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		event := &notification.Event{}
+		if err := json.NewDecoder(r.Body).Decode(event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, event.Reason)
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system", UID: "notify-retry"},
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			Notification: &rainbondv1alpha1.Notification{URL: server.URL},
+		},
+	}
+	mgr := NewClusterMgr(context.Background(), fake.NewFakeClientWithScheme(scheme, cluster), logr.Discard(), cluster, scheme)
+	defer notificationQueues.Delete(cluster.UID)
+
+	running := &rainbondv1alpha1.RainbondClusterStatus{
+		Phase:      rainbondv1alpha1.RainbondClusterRunning,
+		Components: map[string]rainbondv1alpha1.ComponentVersionStatus{"rbd-api": {Ready: true}},
+	}
+	degraded := &rainbondv1alpha1.RainbondClusterStatus{
+		Phase:      rainbondv1alpha1.RainbondClusterDegraded,
+		Components: map[string]rainbondv1alpha1.ComponentVersionStatus{"rbd-api": {Ready: false}},
+	}
+	assert.Nil(t, mgr.Notify(running, degraded))
+
+	// the failed events are sent in order.
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []notification.Reason{notification.ReasonPhaseChanged, notification.ReasonComponentDegraded}, received)
+	mu.Unlock()
+}
@@ -0,0 +1,59 @@
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type chaosNodes struct {
+	ctx     context.Context
+	client  client.Client
+	cluster *rainbondv1alpha1.RainbondCluster
+}
+
+// NewChaosNodesPrechecker creates a new prechecker for the nodes selected by the chaos node selector.
+func NewChaosNodesPrechecker(ctx context.Context, client client.Client, cluster *rainbondv1alpha1.RainbondCluster) PreChecker {
+	return &chaosNodes{
+		ctx:     ctx,
+		client:  client,
+		cluster: cluster,
+	}
+}
+
+func (c *chaosNodes) Check() rainbondv1alpha1.RainbondClusterCondition {
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:              rainbondv1alpha1.RainbondClusterConditionTypeChaosNodes,
+		Status:            corev1.ConditionTrue,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+	}
+
+	if c.cluster.Spec.ChaosNodeSelector == nil {
+		return condition
+	}
+	selector, err := metav1.LabelSelectorAsSelector(c.cluster.Spec.ChaosNodeSelector)
+	if err != nil {
+		return failConditoin(condition, rainbondv1alpha1.ReasonInvalidChaosNodeSelector, err.Error())
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := c.client.List(c.ctx, nodeList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return failConditoin(condition, rainbondv1alpha1.ReasonListNodesFailed, err.Error())
+	}
+	var schedulable int
+	for _, node := range nodeList.Items {
+		if !node.Spec.Unschedulable {
+			schedulable++
+		}
+	}
+	if schedulable == 0 {
+		return failConditoin(condition, rainbondv1alpha1.ReasonNoChaosNodes, fmt.Sprintf("no schedulable nodes match the chaos node selector %s", selector.String()))
+	}
+
+	return condition
+}
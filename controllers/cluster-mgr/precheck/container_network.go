@@ -11,7 +11,6 @@ import (
 	"github.com/go-logr/logr"
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
-	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	appsv1 "k8s.io/api/apps/v1"
@@ -60,7 +59,7 @@ func (c *containerNetwork) Check() rainbondv1alpha1.RainbondClusterCondition {
 	if msg, err := c.isSentinelReady(); err != nil {
 		if err == ErrSentinelNotReady {
 			condition.Status = corev1.ConditionFalse
-			condition.Reason = "SentinelNotReady"
+			condition.Reason = rainbondv1alpha1.ReasonSentinelNotReady
 			condition.Message = msg
 			return condition
 		}
@@ -76,7 +75,7 @@ func (c *containerNetwork) Check() rainbondv1alpha1.RainbondClusterCondition {
 }
 
 func (c *containerNetwork) failCondition(condition rainbondv1alpha1.RainbondClusterCondition, msg string) rainbondv1alpha1.RainbondClusterCondition {
-	return failConditoin(condition, "ContainerNetworkFailed", msg)
+	return failConditoin(condition, rainbondv1alpha1.ReasonContainerNetworkFailed, msg)
 }
 
 func (c *containerNetwork) isSentinelReady() (string, error) {
@@ -151,7 +150,7 @@ func (c *containerNetwork) daemonsetForSentinel() *appsv1.DaemonSet {
 	labels := rbdutil.LabelsForRainbond(map[string]string{
 		"name": SentinelName,
 	})
-	return &appsv1.DaemonSet{
+	ds := &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      SentinelName,
 			Namespace: c.cluster.GetNamespace(),
@@ -170,7 +169,7 @@ func (c *containerNetwork) daemonsetForSentinel() *appsv1.DaemonSet {
 				},
 				Spec: corev1.PodSpec{
 					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					ServiceAccountName:            constants.ServiceAccountName,
+					ServiceAccountName:            rbdutil.ServiceAccountName(),
 					Tolerations: []corev1.Toleration{
 						{
 							Operator: corev1.TolerationOpExists, // tolerate everything.
@@ -187,4 +186,6 @@ func (c *containerNetwork) daemonsetForSentinel() *appsv1.DaemonSet {
 			},
 		},
 	}
+	k8sutil.SetLinuxNodeSelector(&ds.Spec.Template.Spec)
+	return ds
 }
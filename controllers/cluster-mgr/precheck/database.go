@@ -1,20 +1,27 @@
 package precheck
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
+
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"time"
 )
 
+// defDatabaseName is the name of the region database if it is not specified, the same as the components.
+const defDatabaseName = "region"
+
 type database struct {
 	typ3 rainbondv1alpha1.RainbondClusterConditionType
 	db   *rainbondv1alpha1.Database
 }
 
-// NewDatabasePrechecker creates a new prechecker.
+// NewDatabasePrechecker creates a new prechecker, which checks the database exists or can be created, the user has
+// the required privileges on it, and its charset is compatible.
 func NewDatabasePrechecker(typ3 rainbondv1alpha1.RainbondClusterConditionType, db *rainbondv1alpha1.Database) PreChecker {
 	return &database{
 		typ3: typ3,
@@ -31,23 +38,123 @@ func (d *database) Check() rainbondv1alpha1.RainbondClusterCondition {
 	err := d.check(d.db)
 	if err != nil {
 		condition.Status = corev1.ConditionFalse
-		condition.Reason = "DatabaseFailed"
+		condition.Reason = reasonOf(err, rainbondv1alpha1.ReasonDatabaseUnreachable)
 		condition.Message = err.Error()
 	}
 	return condition
 }
 
 func (d *database) check(db *rainbondv1alpha1.Database) error {
-	db2, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", db.Username, db.Password, db.Host, db.Port, db.Name))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// connect to the server, the database may not exist.
+	conn, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/", db.Username, db.Password, db.Host, db.Port))
 	if err != nil {
 		return err
 	}
-	defer db2.Close()
+	defer conn.Close()
+	if err := conn.PingContext(ctx); err != nil {
+		return err
+	}
 
-	err = db2.Ping()
+	name := db.Name
+	if name == "" {
+		name = defDatabaseName
+	}
+	grants, hasRoles, err := showGrants(ctx, conn)
 	if err != nil {
-		return err
+		return fmt.Errorf("show grants of user %s: %v", db.Username, err)
+	}
+	// the privileges granted by the roles are not shown, leave them to the components.
+	var missing []string
+	if !hasRoles {
+		missing = missingPrivileges(grants, name)
 	}
 
+	charset, collation, err := databaseCharset(ctx, conn, name)
+	if err != nil {
+		return fmt.Errorf("get the charset of database %s: %v", name, err)
+	}
+	if charset == "" {
+		// the database doesn't exist, or the user has no privilege on it.
+		if containsString(missing, "CREATE") {
+			return newCheckError(rainbondv1alpha1.ReasonDatabaseNotFound, "database %s not found, and user %s has no privilege to create it: %s",
+				name, db.Username, grantStatement(missing, name, db.Username))
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci", quoteIdentifier(name))); err != nil {
+			return newCheckError(rainbondv1alpha1.ReasonDatabaseNotFound, "database %s not found, and failed to create it: %v", name, err)
+		}
+		charset, collation = "utf8mb4", "utf8mb4_general_ci"
+	}
+	if !compatibleCharsets[strings.ToLower(charset)] {
+		return newCheckError(rainbondv1alpha1.ReasonDatabaseIncompatibleCharset, "the charset of database %s is %s with collation %s, utf8mb4 is expected: "+
+			"ALTER DATABASE %s CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci", name, charset, collation, quoteIdentifier(name))
+	}
+	if len(missing) > 0 {
+		return newCheckError(rainbondv1alpha1.ReasonDatabaseInsufficientPrivileges, "user %s has no privileges %s on database %s: %s",
+			db.Username, strings.Join(missing, ", "), name, grantStatement(missing, name, db.Username))
+	}
 	return nil
 }
+
+// showGrants returns the grants of the current user.
+func showGrants(ctx context.Context, conn *sql.DB) ([]grant, bool, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW GRANTS FOR CURRENT_USER()")
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, false, err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	grants, hasRoles := parseGrants(lines)
+	return grants, hasRoles, nil
+}
+
+// databaseCharset returns the default charset and collation of the database, empty if it is not visible to the user.
+func databaseCharset(ctx context.Context, conn *sql.DB, name string) (charset, collation string, err error) {
+	err = conn.QueryRowContext(ctx, "SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", name).
+		Scan(&charset, &collation)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	return charset, collation, err
+}
+
+// grantStatement returns the statement to grant the missing privileges to the user.
+func grantStatement(privileges []string, database, username string) string {
+	return fmt.Sprintf("GRANT %s ON %s.* TO '%s'@'%%'", strings.Join(privileges, ", "), quoteIdentifier(database), username)
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PingDatabase connects to the database and pings it, until the context is done.
+func PingDatabase(ctx context.Context, db *rainbondv1alpha1.Database) error {
+	db2, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", db.Username, db.Password, db.Host, db.Port, db.Name))
+	if err != nil {
+		return err
+	}
+	defer db2.Close()
+
+	return db2.PingContext(ctx)
+}
@@ -0,0 +1,136 @@
+package precheck
+
+import (
+	"regexp"
+	"strings"
+)
+
+// requiredDatabasePrivileges are the privileges on the database required by the components of the region, which
+// migrate the tables on startup.
+var requiredDatabasePrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "ALTER", "INDEX"}
+
+// compatibleCharsets are the charsets of the database that the tables of the region can be stored in.
+var compatibleCharsets = map[string]bool{
+	"utf8":    true,
+	"utf8mb3": true,
+	"utf8mb4": true,
+}
+
+// grant is the privileges granted on a database, the database is a pattern that may contain the wildcards % and _,
+// and * for all the databases.
+type grant struct {
+	privileges map[string]bool
+	database   string
+}
+
+// parseGrants parses the output of SHOW GRANTS, such as:
+//
+//	GRANT USAGE ON *.* TO 'rainbond'@'%'
+//	GRANT SELECT, INSERT, UPDATE ON `region`.* TO 'rainbond'@'%'
+//
+// The grants on the tables and the columns are ignored. hasRoles is true if the user is granted any role, the
+// privileges of which are not shown.
+func parseGrants(lines []string) (grants []grant, hasRoles bool) {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(strings.ToUpper(line), "GRANT ") {
+			continue
+		}
+		line = line[len("GRANT "):]
+		on := strings.Index(strings.ToUpper(line), " ON ")
+		if on == -1 {
+			// such as GRANT `developer`@`%` TO `rainbond`@`%`
+			hasRoles = true
+			continue
+		}
+		privileges := make(map[string]bool)
+		for _, privilege := range strings.Split(line[:on], ",") {
+			privilege = strings.ToUpper(strings.TrimSpace(privilege))
+			if strings.Contains(privilege, "(") {
+				// the privilege on the columns.
+				continue
+			}
+			if privilege == "ALL" || privilege == "ALL PRIVILEGES" {
+				for _, p := range requiredDatabasePrivileges {
+					privileges[p] = true
+				}
+				continue
+			}
+			privileges[privilege] = true
+		}
+		target := line[on+len(" ON "):]
+		if to := strings.Index(strings.ToUpper(target), " TO "); to != -1 {
+			target = target[:to]
+		}
+		database, ok := grantDatabase(strings.TrimSpace(target))
+		if !ok {
+			continue
+		}
+		grants = append(grants, grant{privileges: privileges, database: database})
+	}
+	return grants, hasRoles
+}
+
+// grantDatabase returns the database of the target of the grant, which is ok if the target is all the tables of it.
+func grantDatabase(target string) (string, bool) {
+	target = strings.TrimPrefix(target, "TABLE ")
+	if target == "*.*" || target == "*" {
+		return "*", true
+	}
+	if !strings.HasSuffix(target, ".*") {
+		return "", false
+	}
+	database := strings.TrimSuffix(target, ".*")
+	if strings.HasPrefix(database, "`") && strings.HasSuffix(database, "`") && len(database) >= 2 {
+		database = strings.ReplaceAll(database[1:len(database)-1], "``", "`")
+	}
+	return database, true
+}
+
+// matchDatabase returns whether the database pattern of the grant matches the name.
+func matchDatabase(pattern, name string) bool {
+	if pattern == "*" {
+		return true
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		case '%':
+			expr.WriteString(".*")
+		case '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	matched, err := regexp.MatchString(expr.String(), name)
+	return err == nil && matched
+}
+
+// missingPrivileges returns the required privileges not granted on the database, in the order of
+// requiredDatabasePrivileges.
+func missingPrivileges(grants []grant, database string) []string {
+	granted := make(map[string]bool)
+	for _, g := range grants {
+		if !matchDatabase(g.database, database) {
+			continue
+		}
+		for privilege := range g.privileges {
+			granted[privilege] = true
+		}
+	}
+	var missing []string
+	for _, privilege := range requiredDatabasePrivileges {
+		if !granted[privilege] {
+			missing = append(missing, privilege)
+		}
+	}
+	return missing
+}
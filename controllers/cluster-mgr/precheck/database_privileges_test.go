@@ -0,0 +1,88 @@
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingPrivileges(t *testing.T) {
+	tests := []struct {
+		name     string
+		grants   []string
+		database string
+		want     []string
+		wantRole bool
+	}{
+		{
+			name: "all on all databases",
+			grants: []string{
+				"GRANT ALL PRIVILEGES ON *.* TO 'root'@'%' WITH GRANT OPTION",
+			},
+			database: "region",
+		},
+		{
+			name: "all on the database",
+			grants: []string{
+				"GRANT USAGE ON *.* TO `rainbond`@`%`",
+				"GRANT ALL PRIVILEGES ON `region`.* TO `rainbond`@`%`",
+			},
+			database: "region",
+		},
+		{
+			name: "partial",
+			grants: []string{
+				"GRANT USAGE ON *.* TO 'rainbond'@'%'",
+				"GRANT SELECT, INSERT, UPDATE, DELETE ON `region`.* TO 'rainbond'@'%'",
+				"GRANT DROP ON `region`.`tenants` TO 'rainbond'@'%'",
+			},
+			database: "region",
+			want:     []string{"CREATE", "DROP", "ALTER", "INDEX"},
+		},
+		{
+			name: "wildcard",
+			grants: []string{
+				"GRANT SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, ALTER, INDEX ON `reg\\_%`.* TO 'rainbond'@'%'",
+			},
+			database: "reg_a",
+		},
+		{
+			name: "escaped wildcard",
+			grants: []string{
+				"GRANT ALL ON `reg\\_%`.* TO 'rainbond'@'%'",
+			},
+			database: "regxa",
+			want:     requiredDatabasePrivileges,
+		},
+		{
+			name: "other database",
+			grants: []string{
+				"GRANT ALL PRIVILEGES ON `console`.* TO 'rainbond'@'%'",
+			},
+			database: "region",
+			want:     requiredDatabasePrivileges,
+		},
+		{
+			name: "role",
+			grants: []string{
+				"GRANT USAGE ON *.* TO `rainbond`@`%`",
+				"GRANT `developer`@`%` TO `rainbond`@`%`",
+			},
+			database: "region",
+			want:     requiredDatabasePrivileges,
+			wantRole: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			grants, hasRoles := parseGrants(tc.grants)
+			assert.Equal(t, tc.wantRole, hasRoles)
+			assert.Equal(t, tc.want, missingPrivileges(grants, tc.database))
+		})
+	}
+}
+
+func TestGrantStatement(t *testing.T) {
+	assert.Equal(t, "GRANT CREATE, ALTER ON `region`.* TO 'rainbond'@'%'", grantStatement([]string{"CREATE", "ALTER"}, "region", "rainbond"))
+}
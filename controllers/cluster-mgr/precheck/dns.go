@@ -55,5 +55,5 @@ func nslookup(target string) error {
 }
 
 func (d *dns) failCondition(condition rainbondv1alpha1.RainbondClusterCondition, msg string) rainbondv1alpha1.RainbondClusterCondition {
-	return failConditoin(condition, "DNSFailed", msg)
+	return failConditoin(condition, rainbondv1alpha1.ReasonDNSFailed, msg)
 }
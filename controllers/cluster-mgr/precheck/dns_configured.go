@@ -0,0 +1,109 @@
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// wildcardCheckLabel is the label prepended to the suffix of the app domains, which resolves only if the
+// wildcard record is configured.
+const wildcardCheckLabel = "rbd-dns-check"
+
+type dnsConfigured struct {
+	ctx        context.Context
+	cluster    *rainbondv1alpha1.RainbondCluster
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// NewDNSConfiguredPrechecker creates a new prechecker, which checks the wildcard domain of the apps and the domain
+// of the console resolve to the gateway ingress IPs.
+func NewDNSConfiguredPrechecker(ctx context.Context, cluster *rainbondv1alpha1.RainbondCluster) PreChecker {
+	return &dnsConfigured{
+		ctx:        ctx,
+		cluster:    cluster,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+func (d *dnsConfigured) Check() rainbondv1alpha1.RainbondClusterCondition {
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:              rainbondv1alpha1.RainbondClusterConditionTypeDNSConfigured,
+		Status:            corev1.ConditionTrue,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+	}
+
+	gatewayIPs := d.gatewayIPs()
+	if len(gatewayIPs) == 0 {
+		condition.Message = "no gateway ingress IPs to verify the domains against"
+		return condition
+	}
+
+	var unresolved, mismatched []string
+	for _, domain := range d.domains() {
+		ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
+		addrs, err := d.lookupHost(ctx, domain.host)
+		cancel()
+		if err != nil {
+			unresolved = append(unresolved, fmt.Sprintf("%s is not resolved: %v", domain.name, err))
+			continue
+		}
+		if !containsAny(gatewayIPs, addrs) {
+			mismatched = append(mismatched, fmt.Sprintf("%s resolves to %s", domain.name, strings.Join(addrs, ", ")))
+		}
+	}
+	expected := fmt.Sprintf("expect the gateway ingress IPs %s", strings.Join(gatewayIPs, ", "))
+	if len(unresolved) > 0 {
+		return failConditoin(condition, rainbondv1alpha1.ReasonDNSNotResolved,
+			fmt.Sprintf("%s, %s", strings.Join(append(unresolved, mismatched...), "; "), expected))
+	}
+	if len(mismatched) > 0 {
+		return failConditoin(condition, rainbondv1alpha1.ReasonDNSMismatch,
+			fmt.Sprintf("%s, %s", strings.Join(mismatched, "; "), expected))
+	}
+	return condition
+}
+
+// gatewayIPs returns the addresses the domains are expected to resolve to, the ingress IPs and the VIP of the gateway.
+func (d *dnsConfigured) gatewayIPs() []string {
+	ips := d.cluster.GatewayIngressIPs()
+	if vip := d.cluster.Spec.GatewayVIP; vip != "" && !containsAny(ips, []string{vip}) {
+		ips = append(ips, vip)
+	}
+	return ips
+}
+
+type domainToCheck struct {
+	// name is the domain in the messages, such as *.example.com.
+	name string
+	// host is the host to resolve.
+	host string
+}
+
+func (d *dnsConfigured) domains() []domainToCheck {
+	var domains []domainToCheck
+	if suffix := strings.TrimPrefix(d.cluster.Spec.SuffixHTTPHost, "*."); suffix != "" {
+		domains = append(domains, domainToCheck{name: "*." + suffix, host: wildcardCheckLabel + "." + suffix})
+	}
+	if console := d.cluster.Spec.ConsoleDomain; console != "" {
+		domains = append(domains, domainToCheck{name: console, host: console})
+	}
+	return domains
+}
+
+func containsAny(list, items []string) bool {
+	for _, item := range items {
+		for _, s := range list {
+			if s == item {
+				return true
+			}
+		}
+	}
+	return false
+}
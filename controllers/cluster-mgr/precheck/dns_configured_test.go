@@ -0,0 +1,94 @@
+package precheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestDNSConfigured(t *testing.T) {
+	records := map[string][]string{
+		"rbd-dns-check.apps.example.com": {"192.168.0.1"},
+		"console.example.com":            {"10.0.0.1"},
+		"rbd-dns-check.vip.example.com":  {"192.168.0.10"},
+	}
+	lookupHost := func(ctx context.Context, host string) ([]string, error) {
+		if addrs, ok := records[host]; ok {
+			return addrs, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	tests := []struct {
+		name       string
+		spec       rainbondv1alpha1.RainbondClusterSpec
+		wantStatus corev1.ConditionStatus
+		wantReason string
+		wantMsg    string
+	}{
+		{
+			name:       "no gateway ingress IPs",
+			spec:       rainbondv1alpha1.RainbondClusterSpec{SuffixHTTPHost: "unknown.example.com"},
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "configured",
+			spec: rainbondv1alpha1.RainbondClusterSpec{
+				SuffixHTTPHost:    "apps.example.com",
+				GatewayIngressIPs: []string{"192.168.0.1", "192.168.0.2"},
+			},
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "vip",
+			spec: rainbondv1alpha1.RainbondClusterSpec{
+				SuffixHTTPHost:    "vip.example.com",
+				GatewayIngressIPs: []string{"192.168.0.1"},
+				GatewayVIP:        "192.168.0.10",
+			},
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "mismatch",
+			spec: rainbondv1alpha1.RainbondClusterSpec{
+				SuffixHTTPHost:    "apps.example.com",
+				ConsoleDomain:     "console.example.com",
+				GatewayIngressIPs: []string{"192.168.0.1"},
+			},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonDNSMismatch,
+			wantMsg:    "console.example.com resolves to 10.0.0.1, expect the gateway ingress IPs 192.168.0.1",
+		},
+		{
+			name: "not resolved",
+			spec: rainbondv1alpha1.RainbondClusterSpec{
+				SuffixHTTPHost:    "unknown.example.com",
+				ConsoleDomain:     "console.example.com",
+				GatewayIngressIPs: []string{"192.168.0.1"},
+			},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonDNSNotResolved,
+			wantMsg: "*.unknown.example.com is not resolved: no such host; console.example.com resolves to 10.0.0.1, " +
+				"expect the gateway ingress IPs 192.168.0.1",
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			d := &dnsConfigured{
+				ctx:        context.Background(),
+				cluster:    &rainbondv1alpha1.RainbondCluster{Spec: tc.spec},
+				lookupHost: lookupHost,
+			}
+			condition := d.Check()
+			assert.Equal(t, tc.wantStatus, condition.Status)
+			assert.Equal(t, tc.wantReason, condition.Reason)
+			if tc.wantMsg != "" {
+				assert.Equal(t, tc.wantMsg, condition.Message)
+			}
+		})
+	}
+}
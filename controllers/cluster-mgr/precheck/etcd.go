@@ -0,0 +1,161 @@
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/etcdutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// etcdRequiredPrefixes are the prefixes of the keys written by the components of the region, which must be writable.
+var etcdRequiredPrefixes = []string{"/rainbond/"}
+
+// etcdTLSKeys are the keys of the secret of the certificates to connect to etcd.
+var etcdTLSKeys = []string{"ca-file", "cert-file", "key-file"}
+
+type etcd struct {
+	ctx     context.Context
+	client  client.Client
+	cluster *rainbondv1alpha1.RainbondCluster
+}
+
+// NewEtcdPrechecker creates a new prechecker for the external etcd, which checks the endpoints, the status of the
+// members, the authentication and the required prefixes are writable.
+func NewEtcdPrechecker(ctx context.Context, client client.Client, cluster *rainbondv1alpha1.RainbondCluster) PreChecker {
+	return &etcd{
+		ctx:     ctx,
+		client:  client,
+		cluster: cluster,
+	}
+}
+
+func (e *etcd) Check() rainbondv1alpha1.RainbondClusterCondition {
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:              rainbondv1alpha1.RainbondClusterConditionTypeEtcdReachable,
+		Status:            corev1.ConditionTrue,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+	}
+
+	if err := e.check(e.cluster.Spec.EtcdConfig); err != nil {
+		return failConditoin(condition, reasonOf(err, rainbondv1alpha1.ReasonEtcdUnreachable), err.Error())
+	}
+	return condition
+}
+
+func (e *etcd) check(config *rainbondv1alpha1.EtcdConfig) error {
+	secret, err := e.validate(config)
+	if err != nil {
+		return err
+	}
+	var cli *clientv3.Client
+	if secret != nil {
+		cli, err = etcdutil.NewTLSClient(config.Endpoints, secret.Data["ca-file"], secret.Data["cert-file"], secret.Data["key-file"])
+	} else {
+		cli, err = etcdutil.NewClient(config.Endpoints)
+	}
+	if err != nil {
+		return newCheckError(rainbondv1alpha1.ReasonEtcdInvalidConfig, "create etcd client: %v", err)
+	}
+	defer cli.Close()
+
+	if err := e.checkStatus(cli, config.Endpoints); err != nil {
+		return err
+	}
+	return e.checkPrefixes(cli)
+}
+
+// validate checks the schemes of the endpoints match whether the certificates are provided, and returns the secret
+// of the certificates, if any.
+func (e *etcd) validate(config *rainbondv1alpha1.EtcdConfig) (*corev1.Secret, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, newCheckError(rainbondv1alpha1.ReasonEtcdInvalidConfig, "no endpoints of etcd")
+	}
+	wantScheme := "http"
+	if config.SecretName != "" {
+		wantScheme = "https"
+	}
+	for _, endpoint := range config.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, newCheckError(rainbondv1alpha1.ReasonEtcdInvalidConfig, "invalid endpoint %s, such as http://192.168.0.1:2379 is expected", endpoint)
+		}
+		if u.Scheme != wantScheme {
+			if config.SecretName != "" {
+				return nil, newCheckError(rainbondv1alpha1.ReasonEtcdInvalidConfig, "endpoint %s is not https, but the certificates are provided by secret %s", endpoint, config.SecretName)
+			}
+			return nil, newCheckError(rainbondv1alpha1.ReasonEtcdInvalidConfig, "endpoint %s is not http, set secretName for the certificates to connect to it", endpoint)
+		}
+	}
+
+	if config.SecretName == "" {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := e.client.Get(e.ctx, types.NamespacedName{Namespace: e.cluster.Namespace, Name: config.SecretName}, secret); err != nil {
+		return nil, newCheckError(rainbondv1alpha1.ReasonEtcdInvalidConfig, "get secret %s: %v", config.SecretName, err)
+	}
+	for _, key := range etcdTLSKeys {
+		if len(secret.Data[key]) == 0 {
+			return nil, newCheckError(rainbondv1alpha1.ReasonEtcdInvalidConfig, "no %s in secret %s", key, config.SecretName)
+		}
+	}
+	return secret, nil
+}
+
+// checkStatus checks etcd is reachable, if one of the endpoints responds.
+func (e *etcd) checkStatus(cli *clientv3.Client, endpoints []string) error {
+	var errs []string
+	for _, endpoint := range endpoints {
+		ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+		_, err := cli.Status(ctx, endpoint)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if isEtcdAuthError(err) {
+			return newCheckError(rainbondv1alpha1.ReasonEtcdAuthFailed, "authenticate to %s: %v", endpoint, err)
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", endpoint, err))
+	}
+	return newCheckError(rainbondv1alpha1.ReasonEtcdUnreachable, "etcd is unreachable: %s", strings.Join(errs, ", "))
+}
+
+// checkPrefixes writes and deletes a key under each of the required prefixes.
+func (e *etcd) checkPrefixes(cli *clientv3.Client) error {
+	for _, prefix := range etcdRequiredPrefixes {
+		key := fmt.Sprintf("%s.precheck-%s", prefix, e.cluster.UID)
+		ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+		_, err := cli.Put(ctx, key, time.Now().Format(time.RFC3339))
+		if err == nil {
+			_, err = cli.Delete(ctx, key)
+		}
+		cancel()
+		if err == nil {
+			continue
+		}
+		if isEtcdAuthError(err) {
+			return newCheckError(rainbondv1alpha1.ReasonEtcdAuthFailed, "authenticate to write %s: %v", prefix, err)
+		}
+		// such as permission denied, or no space.
+		return newCheckError(rainbondv1alpha1.ReasonEtcdPrefixNotWritable, "write prefix %s: %v", prefix, err)
+	}
+	return nil
+}
+
+func isEtcdAuthError(err error) bool {
+	switch err {
+	case rpctypes.ErrUserEmpty, rpctypes.ErrAuthFailed, rpctypes.ErrInvalidAuthToken:
+		return true
+	}
+	return false
+}
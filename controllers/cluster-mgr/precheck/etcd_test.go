@@ -0,0 +1,97 @@
+package precheck
+
+import (
+	"context"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEtcdValidate(t *testing.T) {
+	secret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "rbd-etcd-secret", Namespace: "rbd-system"},
+			Data:       data,
+		}
+	}
+	certs := map[string][]byte{
+		"ca-file":   []byte("ca"),
+		"cert-file": []byte("cert"),
+		"key-file":  []byte("key"),
+	}
+	tests := []struct {
+		name     string
+		config   *rainbondv1alpha1.EtcdConfig
+		existing []runtime.Object
+		wantErr  bool
+	}{
+		{
+			name:    "no endpoints",
+			config:  &rainbondv1alpha1.EtcdConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "no scheme",
+			config:  &rainbondv1alpha1.EtcdConfig{Endpoints: []string{"192.168.0.1:2379"}},
+			wantErr: true,
+		},
+		{
+			name:   "http",
+			config: &rainbondv1alpha1.EtcdConfig{Endpoints: []string{"http://192.168.0.1:2379"}},
+		},
+		{
+			name:    "https without certificates",
+			config:  &rainbondv1alpha1.EtcdConfig{Endpoints: []string{"https://192.168.0.1:2379"}},
+			wantErr: true,
+		},
+		{
+			name:     "http with certificates",
+			config:   &rainbondv1alpha1.EtcdConfig{Endpoints: []string{"http://192.168.0.1:2379"}, SecretName: "rbd-etcd-secret"},
+			existing: []runtime.Object{secret(certs)},
+			wantErr:  true,
+		},
+		{
+			name:    "secret not found",
+			config:  &rainbondv1alpha1.EtcdConfig{Endpoints: []string{"https://192.168.0.1:2379"}, SecretName: "rbd-etcd-secret"},
+			wantErr: true,
+		},
+		{
+			name:     "no key",
+			config:   &rainbondv1alpha1.EtcdConfig{Endpoints: []string{"https://192.168.0.1:2379"}, SecretName: "rbd-etcd-secret"},
+			existing: []runtime.Object{secret(map[string][]byte{"ca-file": []byte("ca")})},
+			wantErr:  true,
+		},
+		{
+			name:     "https",
+			config:   &rainbondv1alpha1.EtcdConfig{Endpoints: []string{"https://192.168.0.1:2379"}, SecretName: "rbd-etcd-secret"},
+			existing: []runtime.Object{secret(certs)},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			e := &etcd{
+				ctx:     context.Background(),
+				client:  fake.NewFakeClientWithScheme(scheme, tc.existing...),
+				cluster: &rainbondv1alpha1.RainbondCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system"}},
+			}
+			_, err := e.validate(tc.config)
+			if !tc.wantErr {
+				assert.Nil(t, err)
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.Equal(t, rainbondv1alpha1.ReasonEtcdInvalidConfig, reasonOf(err, ""))
+			}
+		})
+	}
+}
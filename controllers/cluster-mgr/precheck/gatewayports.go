@@ -0,0 +1,90 @@
+package precheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type gatewayPorts struct {
+	ctx     context.Context
+	reader  client.Reader
+	cluster *rainbondv1alpha1.RainbondCluster
+}
+
+// NewGatewayPortsPrechecker creates a new prechecker for the port ranges of rbd-gateway, which must not be used
+// as the node ports by the Services of any namespace. The reader must read the Services of all the namespaces.
+func NewGatewayPortsPrechecker(ctx context.Context, reader client.Reader, cluster *rainbondv1alpha1.RainbondCluster) PreChecker {
+	return &gatewayPorts{
+		ctx:     ctx,
+		reader:  reader,
+		cluster: cluster,
+	}
+}
+
+func (g *gatewayPorts) Check() rainbondv1alpha1.RainbondClusterCondition {
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:              rainbondv1alpha1.RainbondClusterConditionTypeGatewayPorts,
+		Status:            corev1.ConditionTrue,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+	}
+
+	if g.cluster.Spec.Gateway == nil {
+		return condition
+	}
+	ranges := g.cluster.Spec.Gateway.PortRanges
+	if err := rbdutil.ValidatePortRanges(ranges); err != nil {
+		if errors.Is(err, rbdutil.ErrPortConflict) {
+			return failConditoin(condition, rainbondv1alpha1.ReasonPortConflict, err.Error())
+		}
+		return failConditoin(condition, rainbondv1alpha1.ReasonInvalidPortRanges, err.Error())
+	}
+	if len(ranges) == 0 {
+		return condition
+	}
+
+	services := &corev1.ServiceList{}
+	if err := g.reader.List(g.ctx, services); err != nil {
+		return failConditoin(condition, rainbondv1alpha1.ReasonGatewayPortsFailed, fmt.Sprintf("list services: %v", err))
+	}
+	if conflicts := nodePortConflicts(ranges, services.Items); len(conflicts) > 0 {
+		return failConditoin(condition, rainbondv1alpha1.ReasonPortConflict,
+			"the port ranges conflict with the node ports of the services: "+strings.Join(conflicts, ", "))
+	}
+
+	return condition
+}
+
+// nodePortConflicts returns the sorted node ports of the services in the port ranges of the same protocol, in the
+// format of namespace/name:port/protocol.
+func nodePortConflicts(ranges []rainbondv1alpha1.PortRange, services []corev1.Service) []string {
+	var conflicts []string
+	for _, svc := range services {
+		for _, port := range svc.Spec.Ports {
+			if port.NodePort == 0 {
+				continue
+			}
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			for _, r := range ranges {
+				if r.GetProtocol() == protocol && port.NodePort >= r.Start && port.NodePort <= r.GetEnd() {
+					conflicts = append(conflicts, fmt.Sprintf("%s/%s:%d/%s", svc.Namespace, svc.Name, port.NodePort, strings.ToLower(string(protocol))))
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
@@ -0,0 +1,76 @@
+package precheck
+
+import (
+	"context"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGatewayPortsCheck(t *testing.T) {
+	nodePortService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeNodePort,
+			Ports: []corev1.ServicePort{
+				{Port: 80, NodePort: 20080},
+				{Port: 53, NodePort: 20053, Protocol: corev1.ProtocolUDP},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		ranges  []rainbondv1alpha1.PortRange
+		want    corev1.ConditionStatus
+		reason  string
+		message string
+	}{
+		{
+			name:   "no conflicts",
+			ranges: []rainbondv1alpha1.PortRange{{Start: 21000, End: 21099}},
+			want:   corev1.ConditionTrue,
+		},
+		{
+			name:    "node port",
+			ranges:  []rainbondv1alpha1.PortRange{{Start: 20000, End: 20099}},
+			want:    corev1.ConditionFalse,
+			reason:  rainbondv1alpha1.ReasonPortConflict,
+			message: "default/app:20080/tcp",
+		},
+		{
+			name:   "node port of the other protocol",
+			ranges: []rainbondv1alpha1.PortRange{{Protocol: corev1.ProtocolUDP, Start: 20060, End: 20099}},
+			want:   corev1.ConditionTrue,
+		},
+		{
+			name:   "invalid ranges",
+			ranges: []rainbondv1alpha1.PortRange{{Start: 20099, End: 20000}},
+			want:   corev1.ConditionFalse,
+			reason: rainbondv1alpha1.ReasonInvalidPortRanges,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			cli := fake.NewFakeClientWithScheme(scheme, nodePortService)
+			cluster := &rainbondv1alpha1.RainbondCluster{
+				Spec: rainbondv1alpha1.RainbondClusterSpec{
+					Gateway: &rainbondv1alpha1.GatewayConfig{PortRanges: tc.ranges},
+				},
+			}
+
+			condition := NewGatewayPortsPrechecker(context.Background(), cli, cluster).Check()
+			assert.Equal(t, tc.want, condition.Status)
+			assert.Equal(t, tc.reason, condition.Reason)
+			assert.Contains(t, condition.Message, tc.message)
+		})
+	}
+}
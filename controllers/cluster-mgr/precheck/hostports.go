@@ -0,0 +1,158 @@
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nodePorts are the ports of the host network listened by rbd-node.
+var nodePorts = []int{6100}
+
+type hostPorts struct {
+	ctx     context.Context
+	reader  client.Reader
+	cluster *rainbondv1alpha1.RainbondCluster
+}
+
+// NewHostPortsPrechecker creates a new prechecker for the ports of the host network listened by rbd-gateway and
+// rbd-node, which must not be used by the rainbonds in the other namespaces on the same nodes. The reader must
+// read the rainbondclusters of all the namespaces, rather than the cache limited to the watched namespaces.
+func NewHostPortsPrechecker(ctx context.Context, reader client.Reader, cluster *rainbondv1alpha1.RainbondCluster) PreChecker {
+	return &hostPorts{
+		ctx:     ctx,
+		reader:  reader,
+		cluster: cluster,
+	}
+}
+
+func (h *hostPorts) Check() rainbondv1alpha1.RainbondClusterCondition {
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:              rainbondv1alpha1.RainbondClusterConditionTypeHostPorts,
+		Status:            corev1.ConditionTrue,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+	}
+
+	clusters := &rainbondv1alpha1.RainbondClusterList{}
+	if err := h.reader.List(h.ctx, clusters); err != nil {
+		return failConditoin(condition, rainbondv1alpha1.ReasonHostPortsFailed, fmt.Sprintf("list rainbondclusters: %v", err))
+	}
+	var others []*rainbondv1alpha1.RainbondCluster
+	for i := range clusters.Items {
+		other := &clusters.Items[i]
+		if other.Namespace != h.cluster.Namespace && installedBefore(other, h.cluster) {
+			others = append(others, other)
+		}
+	}
+	if len(others) == 0 {
+		return condition
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := h.reader.List(h.ctx, nodes); err != nil {
+		return failConditoin(condition, rainbondv1alpha1.ReasonListNodesFailed, err.Error())
+	}
+	ports := hostPortsOnNodes(h.cluster, nodes.Items)
+	var conflicts []string
+	for _, other := range others {
+		for _, conflict := range conflictPorts(ports, hostPortsOnNodes(other, nodes.Items)) {
+			conflicts = append(conflicts, fmt.Sprintf("%s (namespace %s)", conflict, other.Namespace))
+		}
+	}
+	if len(conflicts) > 0 {
+		return failConditoin(condition, rainbondv1alpha1.ReasonHostPortConflict,
+			"the ports of the host network are used by the other rainbonds: "+strings.Join(conflicts, ", "))
+	}
+	return condition
+}
+
+// installedBefore checks if the cluster a is installed before b, whose ports are kept.
+func installedBefore(a, b *rainbondv1alpha1.RainbondCluster) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Namespace < b.Namespace
+}
+
+// hostPortsOnNodes returns the ports of the host network listened by rbd-gateway, including the port ranges of the
+// applications, and rbd-node of the cluster, by the names of the nodes. The tcp ports are the numbers, and the udp ones are suffixed with /udp, so that the
+// same number of the different protocols is not a conflict.
+func hostPortsOnNodes(cluster *rainbondv1alpha1.RainbondCluster, nodes []corev1.Node) map[string][]string {
+	var gatewayPorts []string
+	for _, port := range rbdutil.GatewayPorts {
+		gatewayPorts = append(gatewayPorts, strconv.Itoa(port))
+	}
+	if cluster.Spec.Gateway != nil {
+		for _, port := range cluster.Spec.Gateway.UDPPorts {
+			gatewayPorts = append(gatewayPorts, fmt.Sprintf("%d/udp", port))
+		}
+		// the invalid port ranges are reported by the GatewayPorts condition.
+		if rbdutil.ValidatePortRanges(cluster.Spec.Gateway.PortRanges) == nil {
+			for _, r := range cluster.Spec.Gateway.PortRanges {
+				suffix := ""
+				if r.GetProtocol() == corev1.ProtocolUDP {
+					suffix = "/udp"
+				}
+				for port := r.Start; port <= r.GetEnd(); port++ {
+					gatewayPorts = append(gatewayPorts, fmt.Sprintf("%d%s", port, suffix))
+				}
+			}
+		}
+	}
+
+	ports := make(map[string][]string)
+	for _, node := range cluster.Spec.NodesForGateway {
+		if node == nil {
+			continue
+		}
+		ports[node.Name] = append(ports[node.Name], gatewayPorts...)
+	}
+
+	selector := labels.Everything()
+	if cluster.Spec.NodeSelectorForNode != nil {
+		s, err := metav1.LabelSelectorAsSelector(cluster.Spec.NodeSelectorForNode)
+		if err != nil {
+			// rbd-node is not deployed with the invalid selector.
+			return ports
+		}
+		selector = s
+	}
+	for _, node := range nodes {
+		if node.Labels[constants.ExcludeNodeLabelKey] == "true" || !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		for _, port := range nodePorts {
+			ports[node.Name] = append(ports[node.Name], strconv.Itoa(port))
+		}
+	}
+	return ports
+}
+
+// conflictPorts returns the sorted ports of the nodes used by both a and b, in the format of node:port.
+func conflictPorts(a, b map[string][]string) []string {
+	var conflicts []string
+	for node, ports := range a {
+		used := make(map[string]bool)
+		for _, port := range b[node] {
+			used[port] = true
+		}
+		for _, port := range ports {
+			if used[port] {
+				conflicts = append(conflicts, node+":"+port)
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
@@ -0,0 +1,93 @@
+package precheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHostPortsCheck(t *testing.T) {
+	now := time.Now()
+	newCluster := func(namespace string, created time.Time, gatewayNodes ...string) *rainbondv1alpha1.RainbondCluster {
+		cluster := &rainbondv1alpha1.RainbondCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "rainbondcluster",
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+		}
+		for _, name := range gatewayNodes {
+			cluster.Spec.NodesForGateway = append(cluster.Spec.NodesForGateway, &rainbondv1alpha1.K8sNode{Name: name})
+		}
+		// rbd-node runs on the nodes labeled with the namespace only.
+		cluster.Spec.NodeSelectorForNode = &metav1.LabelSelector{MatchLabels: map[string]string{"region": namespace}}
+		return cluster
+	}
+	nodes := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"region": "region-a"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"region": "region-b"}}},
+	}
+
+	tests := []struct {
+		name    string
+		cluster *rainbondv1alpha1.RainbondCluster
+		others  []*rainbondv1alpha1.RainbondCluster
+		want    corev1.ConditionStatus
+		message string
+	}{
+		{
+			name:    "alone",
+			cluster: newCluster("region-a", now, "node1"),
+			want:    corev1.ConditionTrue,
+		},
+		{
+			name:    "different nodes",
+			cluster: newCluster("region-b", now, "node2"),
+			others:  []*rainbondv1alpha1.RainbondCluster{newCluster("region-a", now.Add(-time.Hour), "node1")},
+			want:    corev1.ConditionTrue,
+		},
+		{
+			name:    "gateway on the same node",
+			cluster: newCluster("region-b", now, "node1"),
+			others:  []*rainbondv1alpha1.RainbondCluster{newCluster("region-a", now.Add(-time.Hour), "node1")},
+			want:    corev1.ConditionFalse,
+			message: "node1:10254 (namespace region-a)",
+		},
+		{
+			name:    "installed after",
+			cluster: newCluster("region-a", now.Add(-time.Hour), "node1"),
+			others:  []*rainbondv1alpha1.RainbondCluster{newCluster("region-b", now, "node1")},
+			want:    corev1.ConditionTrue,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			_ = rainbondv1alpha1.AddToScheme(scheme)
+			objs := append([]runtime.Object{tc.cluster}, nodes...)
+			for _, other := range tc.others {
+				objs = append(objs, other)
+			}
+			cli := fake.NewFakeClientWithScheme(scheme, objs...)
+
+			condition := NewHostPortsPrechecker(context.Background(), cli, tc.cluster).Check()
+			assert.Equal(t, tc.want, condition.Status)
+			assert.Contains(t, condition.Message, tc.message)
+		})
+	}
+}
+
+func TestConflictPorts(t *testing.T) {
+	a := map[string][]string{"node1": {"80", "53/udp", "6100"}, "node2": {"6100"}}
+	b := map[string][]string{"node1": {"53", "53/udp", "6100"}, "node3": {"6100"}}
+	assert.Equal(t, []string{"node1:53/udp", "node1:6100"}, conflictPorts(a, b))
+}
@@ -42,9 +42,9 @@ func (d *imagerepo) Check() rainbondv1alpha1.RainbondClusterCondition {
 
 	imageRepo := rbdutil.GetImageRepository(d.cluster)
 
-	if idx, cdt := d.cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeImageRepository); (idx == -1 || cdt.Reason == "DefaultImageRepoFailed") && imageRepo != constants.DefImageRepository {
+	if idx, cdt := d.cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeImageRepository); (idx == -1 || cdt.Reason == rainbondv1alpha1.ReasonDefaultImageRepoFailed) && imageRepo != constants.DefImageRepository {
 		condition.Status = corev1.ConditionFalse
-		condition.Reason = "InProgress"
+		condition.Reason = rainbondv1alpha1.ReasonInProgress
 		condition.Message =
 			fmt.Sprintf("precheck for %s is in progress", rainbondv1alpha1.RainbondClusterConditionTypeImageRepository)
 	}
@@ -78,7 +78,7 @@ func (d *imagerepo) Check() rainbondv1alpha1.RainbondClusterCondition {
 		d.cluster.Spec.ImageHub.Username, d.cluster.Spec.ImageHub.Password); err != nil {
 		condition = d.failConditoin(condition, fmt.Errorf("push image: %v", err))
 		if imageRepo == constants.DefImageRepository {
-			condition.Reason = "DefaultImageRepoFailed"
+			condition.Reason = rainbondv1alpha1.ReasonDefaultImageRepoFailed
 		}
 		return condition
 	}
@@ -87,5 +87,5 @@ func (d *imagerepo) Check() rainbondv1alpha1.RainbondClusterCondition {
 }
 
 func (d *imagerepo) failConditoin(condition rainbondv1alpha1.RainbondClusterCondition, err error) rainbondv1alpha1.RainbondClusterCondition {
-	return failConditoin(condition, "ImageRepoFailed", err.Error())
+	return failConditoin(condition, rainbondv1alpha1.ReasonImageRepoFailed, err.Error())
 }
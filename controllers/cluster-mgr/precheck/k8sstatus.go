@@ -76,7 +76,7 @@ func (k *k8sStatus) listNotReadyPods() ([]corev1.Pod, error) {
 }
 
 func (k *k8sStatus) failCondition(condition rainbondv1alpha1.RainbondClusterCondition, msg string) rainbondv1alpha1.RainbondClusterCondition {
-	return failConditoin(condition, "KubernetesStatusFailed", msg)
+	return failConditoin(condition, rainbondv1alpha1.ReasonKubernetesStatusFailed, msg)
 }
 
 func notReadyPodsToString(pods []corev1.Pod) string {
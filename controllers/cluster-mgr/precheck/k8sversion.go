@@ -38,14 +38,14 @@ func (k *k8sversion) Check() rainbondv1alpha1.RainbondClusterCondition {
 	version, err := k.getKubernetesVersion()
 	if err != nil {
 		condition.Status = corev1.ConditionFalse
-		condition.Reason = "KubernetesVersionFailed"
+		condition.Reason = rainbondv1alpha1.ReasonKubernetesVersionFailed
 		condition.Message = err.Error()
 		return condition
 	}
 
 	if version < "v1.13.0" {
 		condition.Status = corev1.ConditionFalse
-		condition.Reason = "UnsupportedKubernetesVersion"
+		condition.Reason = rainbondv1alpha1.ReasonUnsupportedKubernetesVersion
 		condition.Message = "expect the version of k8s to be greater than or equal to 1.13.0, but got " + version
 		return condition
 	}
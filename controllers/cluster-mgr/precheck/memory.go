@@ -55,7 +55,7 @@ func (m *memory) Check() rainbondv1alpha1.RainbondClusterCondition {
 }
 
 func (m *memory) failCondition(condition rainbondv1alpha1.RainbondClusterCondition, msg string) rainbondv1alpha1.RainbondClusterCondition {
-	return failConditoin(condition, "MemoryFailed", msg)
+	return failConditoin(condition, rainbondv1alpha1.ReasonInsufficientMemory, msg)
 }
 
 func (m *memory) filterOut(nodes []corev1.Node) []corev1.Node {
@@ -12,6 +12,7 @@ import (
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -20,15 +21,17 @@ type storage struct {
 	client client.Client
 	ns     string
 	rwx    *rainbondv1alpha1.RainbondVolumeSpec
+	shared *rainbondv1alpha1.SharedStorage
 }
 
 //NewStorage -
-func NewStorage(ctx context.Context, client client.Client, ns string, rwx *rainbondv1alpha1.RainbondVolumeSpec) PreChecker {
+func NewStorage(ctx context.Context, client client.Client, ns string, rwx *rainbondv1alpha1.RainbondVolumeSpec, shared *rainbondv1alpha1.SharedStorage) PreChecker {
 	return &storage{
 		ctx:    ctx,
 		client: client,
 		ns:     ns,
 		rwx:    rwx,
+		shared: shared,
 	}
 }
 
@@ -39,6 +42,18 @@ func (s *storage) Check() rainbondv1alpha1.RainbondClusterCondition {
 		LastHeartbeatTime: metav1.NewTime(time.Now()),
 	}
 
+	// the existing claim used as the shared storage must be mounted by the pods on different nodes.
+	if s.shared != nil && s.shared.ClaimName != "" {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := s.client.Get(s.ctx, types.NamespacedName{Namespace: s.ns, Name: s.shared.ClaimName}, pvc); err != nil {
+			return s.failConditoin(condition, fmt.Sprintf("get the claim %s of the shared storage: %v", s.shared.ClaimName, err))
+		}
+		if !hasAccessMode(pvc, corev1.ReadWriteMany) {
+			return failConditoin(condition, rainbondv1alpha1.ReasonStorageNotRWX,
+				fmt.Sprintf("the claim %s of the shared storage does not support ReadWriteMany", s.shared.ClaimName))
+		}
+	}
+
 	if s.rwx != nil && s.rwx.StorageClassName != "" {
 		if s.rwx.StorageClassName != "" {
 			// check if pvc exists
@@ -61,7 +76,7 @@ func (s *storage) Check() rainbondv1alpha1.RainbondClusterCondition {
 
 	if s.rwx == nil {
 		condition.Status = corev1.ConditionFalse
-		condition.Reason = "InProgress"
+		condition.Reason = rainbondv1alpha1.ReasonInProgress
 		condition.Message =
 			fmt.Sprintf("precheck for %s is in progress", rainbondv1alpha1.RainbondClusterConditionTypeStorage)
 		return condition
@@ -77,13 +92,27 @@ func (s *storage) isPVCBound(pvc *corev1.PersistentVolumeClaim) bool {
 	return false
 }
 
+func hasAccessMode(pvc *corev1.PersistentVolumeClaim, mode corev1.PersistentVolumeAccessMode) bool {
+	// the access modes of the bound volume take precedence.
+	modes := pvc.Status.AccessModes
+	if len(modes) == 0 {
+		modes = pvc.Spec.AccessModes
+	}
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *storage) pvcForGrdata(accessModes []corev1.PersistentVolumeAccessMode, storageClassName string) *corev1.PersistentVolumeClaim {
 	labels := rbdutil.LabelsForRainbond(nil)
 	return k8sutil.PersistentVolumeClaimForGrdata(s.ns, constants.GrDataPVC, accessModes, labels, storageClassName, 1)
 }
 
 func (s *storage) failConditoin(condition rainbondv1alpha1.RainbondClusterCondition, msg string) rainbondv1alpha1.RainbondClusterCondition {
-	return failConditoin(condition, "StorageFailed", msg)
+	return failConditoin(condition, rainbondv1alpha1.ReasonStorageFailed, msg)
 }
 
 func eventListToString(eventList *corev1.EventList) string {
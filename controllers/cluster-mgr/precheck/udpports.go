@@ -0,0 +1,122 @@
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type udpPorts struct {
+	ctx     context.Context
+	reader  client.Reader
+	cluster *rainbondv1alpha1.RainbondCluster
+}
+
+// NewUDPPortsPrechecker creates a new prechecker for the udp ports of rbd-gateway, which must not be used by the
+// other pods on the gateway nodes, such as the pods of the host network or with the host ports. Unlike the tcp ports,
+// the udp ports can't be probed by dialing, so the ports declared by the pods are checked. The reader must read the
+// pods of all the namespaces.
+func NewUDPPortsPrechecker(ctx context.Context, reader client.Reader, cluster *rainbondv1alpha1.RainbondCluster) PreChecker {
+	return &udpPorts{
+		ctx:     ctx,
+		reader:  reader,
+		cluster: cluster,
+	}
+}
+
+func (u *udpPorts) Check() rainbondv1alpha1.RainbondClusterCondition {
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:              rainbondv1alpha1.RainbondClusterConditionTypeGatewayUDPPorts,
+		Status:            corev1.ConditionTrue,
+		LastHeartbeatTime: metav1.NewTime(time.Now()),
+	}
+
+	ports := GatewayUDPPorts(u.cluster)
+	if len(ports) == 0 {
+		return condition
+	}
+	nodes := make(map[string]bool)
+	for _, node := range u.cluster.Spec.NodesForGateway {
+		if node != nil {
+			nodes[node.Name] = true
+		}
+	}
+
+	pods := &corev1.PodList{}
+	if err := u.reader.List(u.ctx, pods); err != nil {
+		return failConditoin(condition, rainbondv1alpha1.ReasonHostPortsFailed, fmt.Sprintf("list pods: %v", err))
+	}
+	var conflicts []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !nodes[pod.Spec.NodeName] || u.isGateway(pod) || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, port := range podUDPPorts(pod) {
+			if ports[port] {
+				conflicts = append(conflicts, fmt.Sprintf("%s:%d/udp (pod %s/%s)", pod.Spec.NodeName, port, pod.Namespace, pod.Name))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return failConditoin(condition, rainbondv1alpha1.ReasonHostPortConflict,
+			"the udp ports of rbd-gateway are used by the other pods: "+strings.Join(conflicts, ", "))
+	}
+	return condition
+}
+
+// isGateway checks if the pod is rbd-gateway of the cluster.
+func (u *udpPorts) isGateway(pod *corev1.Pod) bool {
+	return pod.Namespace == u.cluster.Namespace && pod.Labels["name"] == "rbd-gateway"
+}
+
+// GatewayUDPPorts returns the udp ports and the ports of the udp port ranges of rbd-gateway.
+func GatewayUDPPorts(cluster *rainbondv1alpha1.RainbondCluster) map[int32]bool {
+	if cluster.Spec.Gateway == nil {
+		return nil
+	}
+	ports := make(map[int32]bool)
+	for _, port := range cluster.Spec.Gateway.UDPPorts {
+		ports[port] = true
+	}
+	// the invalid port ranges are reported by the GatewayPorts condition.
+	if rbdutil.ValidatePortRanges(cluster.Spec.Gateway.PortRanges) == nil {
+		for _, r := range cluster.Spec.Gateway.PortRanges {
+			if r.GetProtocol() != corev1.ProtocolUDP {
+				continue
+			}
+			for port := r.Start; port <= r.GetEnd(); port++ {
+				ports[port] = true
+			}
+		}
+	}
+	return ports
+}
+
+// podUDPPorts returns the udp ports of the host network used by the pod, which are the host ports, or the container
+// ports if the pod uses the host network.
+func podUDPPorts(pod *corev1.Pod) []int32 {
+	var ports []int32
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Protocol != corev1.ProtocolUDP {
+				continue
+			}
+			if port.HostPort != 0 {
+				ports = append(ports, port.HostPort)
+			} else if pod.Spec.HostNetwork {
+				ports = append(ports, port.ContainerPort)
+			}
+		}
+	}
+	return ports
+}
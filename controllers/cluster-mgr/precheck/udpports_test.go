@@ -0,0 +1,98 @@
+package precheck
+
+import (
+	"context"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUDPPortsCheck(t *testing.T) {
+	newPod := func(namespace, name, node string, hostNetwork bool, port corev1.ContainerPort) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"name": name}},
+			Spec: corev1.PodSpec{
+				NodeName:    node,
+				HostNetwork: hostNetwork,
+				Containers:  []corev1.Container{{Name: name, Ports: []corev1.ContainerPort{port}}},
+			},
+		}
+	}
+	dns := corev1.ContainerPort{ContainerPort: 53, Protocol: corev1.ProtocolUDP}
+
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		want    corev1.ConditionStatus
+		message string
+	}{
+		{
+			name: "host port",
+			pod: newPod("default", "dns", "node1", false,
+				corev1.ContainerPort{ContainerPort: 5353, HostPort: 53, Protocol: corev1.ProtocolUDP}),
+			want:    corev1.ConditionFalse,
+			message: "node1:53/udp (pod default/dns)",
+		},
+		{
+			name:    "host network",
+			pod:     newPod("default", "dns", "node1", true, dns),
+			want:    corev1.ConditionFalse,
+			message: "node1:53/udp (pod default/dns)",
+		},
+		{
+			name: "port range",
+			pod: newPod("default", "game", "node1", true,
+				corev1.ContainerPort{ContainerPort: 27016, Protocol: corev1.ProtocolUDP}),
+			want:    corev1.ConditionFalse,
+			message: "node1:27016/udp (pod default/game)",
+		},
+		{
+			name: "container port",
+			pod:  newPod("default", "dns", "node1", false, dns),
+			want: corev1.ConditionTrue,
+		},
+		{
+			name: "tcp port",
+			pod: newPod("default", "dns", "node1", true,
+				corev1.ContainerPort{ContainerPort: 53, Protocol: corev1.ProtocolTCP}),
+			want: corev1.ConditionTrue,
+		},
+		{
+			name: "other node",
+			pod:  newPod("default", "dns", "node2", true, dns),
+			want: corev1.ConditionTrue,
+		},
+		{
+			name: "rbd-gateway itself",
+			pod:  newPod("rbd-system", "rbd-gateway", "node1", true, dns),
+			want: corev1.ConditionTrue,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			cli := fake.NewFakeClientWithScheme(scheme, tc.pod)
+			cluster := &rainbondv1alpha1.RainbondCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+				Spec: rainbondv1alpha1.RainbondClusterSpec{
+					NodesForGateway: []*rainbondv1alpha1.K8sNode{{Name: "node1"}},
+					Gateway: &rainbondv1alpha1.GatewayConfig{
+						UDPPorts:   []int32{53},
+						PortRanges: []rainbondv1alpha1.PortRange{{Protocol: corev1.ProtocolUDP, Start: 27015, End: 27020}},
+					},
+				},
+			}
+
+			condition := NewUDPPortsPrechecker(context.Background(), cli, cluster).Check()
+			assert.Equal(t, tc.want, condition.Status)
+			assert.Contains(t, condition.Message, tc.message)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package precheck
 
 import (
+	"fmt"
+
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -11,3 +13,25 @@ func failConditoin(condition rainbondv1alpha1.RainbondClusterCondition, reason,
 	condition.Message = msg
 	return condition
 }
+
+// checkError is the failure of a check, with the reason of the condition.
+type checkError struct {
+	reason string
+	msg    string
+}
+
+func (e *checkError) Error() string {
+	return e.msg
+}
+
+func newCheckError(reason, format string, args ...interface{}) error {
+	return &checkError{reason: reason, msg: fmt.Sprintf(format, args...)}
+}
+
+// reasonOf returns the reason of the check error, or the fallback if err is not a check error.
+func reasonOf(err error, fallback string) string {
+	if checkErr, ok := err.(*checkError); ok {
+		return checkErr.reason
+	}
+	return fallback
+}
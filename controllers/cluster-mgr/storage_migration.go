@@ -0,0 +1,296 @@
+package clustermgr
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// storageMigrationName is the name of the job that copies the data of the shared storage.
+	storageMigrationName = "rbd-storage-migration"
+	// DefStorageMigrationImage is the default image of the job that copies the data with rsync, in the image
+	// repository of rainbond.
+	DefStorageMigrationImage = "rsync-ssh:latest"
+)
+
+// sharedStorageStatus migrates the shared storage to the storage class in the spec step by step,
+// and returns the status of the shared storage. The data volumes of the components are not migrated,
+// since the claim templates of the statefulsets can not be changed.
+func (r *RainbondClusteMgr) sharedStorageStatus() *rainbondv1alpha1.SharedStorageStatus {
+	status := &rainbondv1alpha1.SharedStorageStatus{}
+	if r.cluster.Status.SharedStorage != nil {
+		status = r.cluster.Status.SharedStorage.DeepCopy()
+	}
+	if err := r.migrateSharedStorage(status); err != nil {
+		r.log.Error(err, "migrate shared storage")
+		if status.Migration != nil {
+			status.Migration.Message = err.Error()
+		}
+	}
+	if status.ClaimName == "" && status.Migration == nil {
+		return nil
+	}
+	return status
+}
+
+func (r *RainbondClusteMgr) migrateSharedStorage(status *rainbondv1alpha1.SharedStorageStatus) error {
+	storage := r.cluster.Spec.SharedStorage
+	if migration := status.Migration; migration != nil {
+		switch migration.Phase {
+		case rainbondv1alpha1.StorageMigrationPhaseCopying:
+			return r.copySharedStorage(status)
+		case rainbondv1alpha1.StorageMigrationPhaseCompleted:
+			if storage == nil || !storage.ConfirmMigration {
+				// retain the old claim until the migration is confirmed.
+				return nil
+			}
+			r.log.Info("migration of shared storage confirmed, delete the old claim", "claim", migration.SourceClaimName)
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      migration.SourceClaimName,
+					Namespace: r.cluster.Namespace,
+				},
+			}
+			if err := r.client.Delete(r.ctx, pvc); err != nil && !k8sErrors.IsNotFound(err) {
+				return fmt.Errorf("delete claim %s: %v", pvc.Name, err)
+			}
+			status.Migration = nil
+			return nil
+		}
+	}
+
+	if storage == nil || storage.ClaimName != "" || storage.StorageClassName == "" {
+		return nil
+	}
+	claimName := status.ClaimName
+	if claimName == "" {
+		claimName = constants.GrDataPVC
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.client.Get(r.ctx, types.NamespacedName{Namespace: r.cluster.Namespace, Name: claimName}, pvc); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			// not created yet.
+			return nil
+		}
+		return fmt.Errorf("get claim %s: %v", claimName, err)
+	}
+	if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName == storage.StorageClassName {
+		if status.Migration != nil && status.Migration.Phase == rainbondv1alpha1.StorageMigrationPhaseFailed {
+			// the storage class has been changed back.
+			status.Migration = nil
+		}
+		return nil
+	}
+	if status.Migration != nil && status.Migration.StorageClassName == storage.StorageClassName {
+		// failed to migrate to the storage class.
+		return nil
+	}
+
+	size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if storage.StorageRequest != nil {
+		size = *resource.NewQuantity(int64(*storage.StorageRequest)*1024*1024*1024, resource.BinarySI)
+	}
+	target := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", constants.GrDataPVC, time.Now().Format("20060102150405")),
+			Namespace: r.cluster.Namespace,
+			Labels:    rbdutil.LabelsForRainbond(nil),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: pvc.Spec.AccessModes,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+			StorageClassName: commonutil.String(storage.StorageClassName),
+		},
+	}
+	r.log.Info("migrate shared storage", "from", claimName, "to", target.Name, "storageClass", storage.StorageClassName)
+	if err := r.client.Create(r.ctx, target); err != nil {
+		return fmt.Errorf("create claim %s: %v", target.Name, err)
+	}
+	now := metav1.Now()
+	status.Migration = &rainbondv1alpha1.StorageMigrationStatus{
+		Phase:            rainbondv1alpha1.StorageMigrationPhaseCopying,
+		SourceClaimName:  claimName,
+		TargetClaimName:  target.Name,
+		StorageClassName: storage.StorageClassName,
+		StartTime:        &now,
+	}
+	return nil
+}
+
+// copySharedStorage copies the data into the new claim once the components using the old claim are stopped,
+// and switches the components to the new claim once the data is copied.
+func (r *RainbondClusteMgr) copySharedStorage(status *rainbondv1alpha1.SharedStorageStatus) error {
+	migration := status.Migration
+
+	job := &batchv1.Job{}
+	err := r.client.Get(r.ctx, types.NamespacedName{Namespace: r.cluster.Namespace, Name: storageMigrationName}, job)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return fmt.Errorf("get job %s: %v", storageMigrationName, err)
+	}
+	if k8sErrors.IsNotFound(err) {
+		pods, err := r.podsUsingClaim(migration.SourceClaimName)
+		if err != nil {
+			return err
+		}
+		if len(pods) > 0 {
+			migration.Message = fmt.Sprintf("waiting for %d pods using claim %s to stop", len(pods), migration.SourceClaimName)
+			return nil
+		}
+		job = r.storageMigrationJob(migration)
+		if err := controllerutil.SetControllerReference(r.cluster, job, r.scheme); err != nil {
+			return fmt.Errorf("set controller reference: %v", err)
+		}
+		r.log.Info("create job to copy the shared storage", "from", migration.SourceClaimName, "to", migration.TargetClaimName)
+		if err := r.client.Create(r.ctx, job); err != nil {
+			return fmt.Errorf("create job %s: %v", storageMigrationName, err)
+		}
+		migration.Message = "copying data"
+		return nil
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		r.log.Info("shared storage migrated", "to", migration.TargetClaimName)
+		now := metav1.Now()
+		status.ClaimName = migration.TargetClaimName
+		migration.Phase = rainbondv1alpha1.StorageMigrationPhaseCompleted
+		migration.CompletionTime = &now
+		migration.Message = fmt.Sprintf("claim %s is retained until the migration is confirmed, "+
+			"the data volumes of the components are not migrated", migration.SourceClaimName)
+	case isJobFailed(job):
+		migration.Phase = rainbondv1alpha1.StorageMigrationPhaseFailed
+		migration.Message = fmt.Sprintf("job %s failed", job.Name)
+		// the data copied partially is useless.
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      migration.TargetClaimName,
+				Namespace: r.cluster.Namespace,
+			},
+		}
+		if err := r.client.Delete(r.ctx, pvc); err != nil && !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("delete claim %s: %v", pvc.Name, err)
+		}
+	default:
+		return nil
+	}
+	if err := r.client.Delete(r.ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8sErrors.IsNotFound(err) {
+		return fmt.Errorf("delete job %s: %v", job.Name, err)
+	}
+	return nil
+}
+
+// podsUsingClaim returns the running pods in the namespace of the cluster that use the given claim.
+func (r *RainbondClusteMgr) podsUsingClaim(claimName string) ([]string, error) {
+	podList := &corev1.PodList{}
+	if err := r.client.List(r.ctx, podList, client.InNamespace(r.cluster.Namespace)); err != nil {
+		return nil, fmt.Errorf("list pods: %v", err)
+	}
+	var pods []string
+	for _, pod := range podList.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+				pods = append(pods, pod.Name)
+				break
+			}
+		}
+	}
+	return pods, nil
+}
+
+func (r *RainbondClusteMgr) storageMigrationJob(migration *rainbondv1alpha1.StorageMigrationStatus) *batchv1.Job {
+	image := path.Join(r.cluster.Spec.RainbondImageRepository, DefStorageMigrationImage)
+	if storage := r.cluster.Spec.SharedStorage; storage != nil && storage.MigrationImage != "" {
+		image = storage.MigrationImage
+	}
+	labels := rbdutil.LabelsForRainbond(map[string]string{
+		"name": storageMigrationName,
+	})
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      storageMigrationName,
+			Namespace: r.cluster.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: commonutil.Int32(2),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:            storageMigrationName,
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"rsync", "-a", "--delete", "/source/", "/target/"},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "source",
+									MountPath: "/source",
+									ReadOnly:  true,
+								},
+								{
+									Name:      "target",
+									MountPath: "/target",
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "source",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: migration.SourceClaimName,
+								},
+							},
+						},
+						{
+							Name: "target",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: migration.TargetClaimName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	k8sutil.SetLinuxNodeSelector(&job.Spec.Template.Spec)
+	return job
+}
+
+func isJobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package clustermgr
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// suspensionTiers are the rbdcomponents in the order they are stopped, and started in reverse order.
+// The traffic is stopped first, and the data stores are stopped last.
+// The nil tier stands for all the other rbdcomponents.
+var suspensionTiers = [][]string{
+	{handler.GatewayName},
+	nil,
+	{handler.HubName, handler.EtcdName, handler.DBName},
+}
+
+// suspensionExcluded are the rbdcomponents kept running while the region is suspended,
+// since the storage is still mounted by the workloads of the tenants.
+var suspensionExcluded = []string{handler.NFSName}
+
+// ReconcileSuspension stops or starts the rbdcomponents tier by tier according to spec.suspend, and records the
+// stopped ones in status.suspension, by which the rbdcomponents stop their pods. The stopped tiers are derived
+// from the spec and the pods each time, rather than the recorded status.
+// It returns true if the region is being suspended or resumed.
+func (r *RainbondClusteMgr) ReconcileSuspension() (bool, error) {
+	old := r.cluster.Status.Suspension
+	status, err := r.suspensionStatus()
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case old == nil && status != nil:
+		r.log.Info("suspend the region")
+	case old != nil && old.Phase != rainbondv1alpha1.SuspensionPhaseResuming && status != nil &&
+		status.Phase == rainbondv1alpha1.SuspensionPhaseResuming:
+		r.log.Info("resume the region")
+	case old != nil && status == nil:
+		r.log.Info("region resumed")
+	}
+
+	if !reflect.DeepEqual(old, status) {
+		cluster := r.cluster.DeepCopy()
+		if err := k8sutil.UpdateStatus(r.ctx, r.client, cluster, func(latest client.Object) {
+			latest.(*rainbondv1alpha1.RainbondCluster).Status.Suspension = status
+		}); err != nil {
+			return false, fmt.Errorf("update status of suspension: %v", err)
+		}
+		r.cluster.Status.Suspension = status
+	}
+	return status != nil && status.Phase != rainbondv1alpha1.SuspensionPhaseSuspended, nil
+}
+
+// suspensionStatus returns the status of the suspension according to spec.suspend and the pods of the
+// rbdcomponents, nil if the region is not suspended.
+func (r *RainbondClusteMgr) suspensionStatus() (*rainbondv1alpha1.SuspensionStatus, error) {
+	if !r.cluster.Spec.Suspend && r.cluster.Status.Suspension == nil {
+		return nil, nil
+	}
+
+	cpts, err := r.listRbdComponents()
+	if err != nil {
+		return nil, fmt.Errorf("list rbdcomponents: %v", err)
+	}
+	var names []string
+	for _, cpt := range cpts {
+		names = append(names, cpt.Name)
+	}
+	tiers := componentTiers(names)
+	var all []string
+	for _, tier := range tiers {
+		all = append(all, tier...)
+	}
+	pods, err := r.podsOfComponents(all)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &rainbondv1alpha1.SuspensionStatus{}
+	if r.cluster.Spec.Suspend {
+		status.Stopped, status.Message = suspendingNames(tiers, pods)
+		status.Phase = rainbondv1alpha1.SuspensionPhaseSuspending
+		if status.Message == "" {
+			status.Phase = rainbondv1alpha1.SuspensionPhaseSuspended
+		}
+		return status, nil
+	}
+
+	status.Stopped, status.Message = resumingNames(tiers, pods)
+	if status.Message == "" {
+		return nil, nil
+	}
+	status.Phase = rainbondv1alpha1.SuspensionPhaseResuming
+	return status, nil
+}
+
+// suspendingNames returns the rbdcomponents to be stopped, each tier is stopped once the pods of the previous ones
+// are gone, and the message of the progress, empty once all the pods are gone.
+func suspendingNames(tiers [][]string, pods []corev1.Pod) ([]string, string) {
+	var stopped []string
+	for _, tier := range tiers {
+		stopped = append(stopped, tier...)
+		if n := countPods(pods, tier); n > 0 {
+			return stopped, fmt.Sprintf("waiting for %d pods of %s to stop", n, strings.Join(tier, ","))
+		}
+	}
+	return stopped, ""
+}
+
+// resumingNames returns the rbdcomponents to be kept stopped, and the message of the progress, empty once all the
+// tiers are started. The tiers are started in reverse order, each of the data stores is waited to have a ready pod
+// before starting the previous tiers. The other rbdcomponents wait for their dependencies by themselves.
+func resumingNames(tiers [][]string, pods []corev1.Pod) ([]string, string) {
+	for i := len(tiers) - 1; i >= 0; i-- {
+		if suspensionTiers[i] == nil {
+			continue
+		}
+		for _, name := range tiers[i] {
+			if hasReadyPod(pods, name) {
+				continue
+			}
+			var stopped []string
+			for _, tier := range tiers[:i] {
+				stopped = append(stopped, tier...)
+			}
+			return stopped, fmt.Sprintf("waiting for %s to be ready", name)
+		}
+	}
+	return nil, ""
+}
+
+// podsOfComponents returns the pods of the rbdcomponents, including the terminating ones.
+func (r *RainbondClusteMgr) podsOfComponents(names []string) ([]corev1.Pod, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	pods := &corev1.PodList{}
+	if err := r.client.List(r.ctx, pods, client.InNamespace(r.cluster.Namespace), client.MatchingLabels(rbdutil.LabelsForRainbond(nil))); err != nil {
+		return nil, fmt.Errorf("list pods: %v", err)
+	}
+	var result []corev1.Pod
+	for _, pod := range pods.Items {
+		if containsName(names, pod.Labels["name"]) {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+// componentTiers returns the existing rbdcomponents grouped by the suspension tiers.
+func componentTiers(names []string) [][]string {
+	tiered := make(map[string]bool)
+	for _, name := range suspensionExcluded {
+		tiered[name] = true
+	}
+	for _, tier := range suspensionTiers {
+		for _, name := range tier {
+			tiered[name] = true
+		}
+	}
+	exists := make(map[string]bool)
+	for _, name := range names {
+		exists[name] = true
+	}
+
+	tiers := make([][]string, len(suspensionTiers))
+	for i, tier := range suspensionTiers {
+		if tier != nil {
+			for _, name := range tier {
+				if exists[name] {
+					tiers[i] = append(tiers[i], name)
+				}
+			}
+			continue
+		}
+		for _, name := range names {
+			if !tiered[name] {
+				tiers[i] = append(tiers[i], name)
+			}
+		}
+		sort.Strings(tiers[i])
+	}
+	return tiers
+}
+
+func countPods(pods []corev1.Pod, names []string) int {
+	var count int
+	for _, pod := range pods {
+		if containsName(names, pod.Labels["name"]) {
+			count++
+		}
+	}
+	return count
+}
+
+func hasReadyPod(pods []corev1.Pod, name string) bool {
+	for i := range pods {
+		if pods[i].Labels["name"] == name && pods[i].DeletionTimestamp.IsZero() && k8sutil.IsPodReady(&pods[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
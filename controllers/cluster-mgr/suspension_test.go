@@ -0,0 +1,193 @@
+package clustermgr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestComponentTiers(t *testing.T) {
+	tiers := componentTiers([]string{"rbd-worker", "rbd-db", "rbd-gateway", "rbd-api", "rbd-hub", "nfs-provisioner"})
+	assert.Equal(t, [][]string{
+		{"rbd-gateway"},
+		{"rbd-api", "rbd-worker"},
+		{"rbd-hub", "rbd-db"},
+	}, tiers)
+}
+
+func suspensionPod(name string, ready bool) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-0",
+			Namespace: "rbd-system",
+			Labels:    rbdutil.LabelsForRainbond(map[string]string{"name": name}),
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestSuspendingNames(t *testing.T) {
+	tiers := [][]string{{"rbd-gateway"}, {"rbd-api"}, {"rbd-db"}}
+	tests := []struct {
+		name        string
+		pods        []corev1.Pod
+		wantStopped []string
+		wantMessage string
+	}{
+		{
+			name:        "gateway running",
+			pods:        []corev1.Pod{suspensionPod("rbd-gateway", true), suspensionPod("rbd-api", true)},
+			wantStopped: []string{"rbd-gateway"},
+			wantMessage: "waiting for 1 pods of rbd-gateway to stop",
+		},
+		{
+			name:        "gateway stopped",
+			pods:        []corev1.Pod{suspensionPod("rbd-api", true), suspensionPod("rbd-db", true)},
+			wantStopped: []string{"rbd-gateway", "rbd-api"},
+			wantMessage: "waiting for 1 pods of rbd-api to stop",
+		},
+		{
+			name:        "all stopped",
+			wantStopped: []string{"rbd-gateway", "rbd-api", "rbd-db"},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			stopped, message := suspendingNames(tiers, tc.pods)
+			assert.Equal(t, tc.wantStopped, stopped)
+			assert.Equal(t, tc.wantMessage, message)
+		})
+	}
+}
+
+func TestResumingNames(t *testing.T) {
+	tiers := [][]string{{"rbd-gateway"}, {"rbd-api"}, {"rbd-db"}}
+	tests := []struct {
+		name        string
+		pods        []corev1.Pod
+		wantStopped []string
+		wantMessage string
+	}{
+		{
+			name:        "all stopped",
+			wantStopped: []string{"rbd-gateway", "rbd-api"},
+			wantMessage: "waiting for rbd-db to be ready",
+		},
+		{
+			name:        "data store not ready",
+			pods:        []corev1.Pod{suspensionPod("rbd-db", false)},
+			wantStopped: []string{"rbd-gateway", "rbd-api"},
+			wantMessage: "waiting for rbd-db to be ready",
+		},
+		{
+			name:        "data store ready",
+			pods:        []corev1.Pod{suspensionPod("rbd-db", true)},
+			wantMessage: "waiting for rbd-gateway to be ready",
+		},
+		{
+			name: "all ready",
+			pods: []corev1.Pod{suspensionPod("rbd-db", true), suspensionPod("rbd-gateway", true)},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			stopped, message := resumingNames(tiers, tc.pods)
+			assert.Equal(t, tc.wantStopped, stopped)
+			assert.Equal(t, tc.wantMessage, message)
+		})
+	}
+}
+
+func TestReconcileSuspension(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+		Spec:       rainbondv1alpha1.RainbondClusterSpec{Suspend: true},
+	}
+	objs := []runtime.Object{cluster}
+	for _, name := range []string{"rbd-gateway", "rbd-db", "nfs-provisioner"} {
+		objs = append(objs, &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "rbd-system"},
+		})
+		pod := suspensionPod(name, true)
+		objs = append(objs, &pod)
+	}
+	cli := fake.NewFakeClientWithScheme(scheme, objs...)
+	ctx := context.Background()
+	deletePods := func(name string) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name + "-0", Namespace: "rbd-system"}}
+		assert.Nil(t, client.IgnoreNotFound(cli.Delete(ctx, pod)))
+	}
+	reconcile := func() (bool, *rainbondv1alpha1.SuspensionStatus) {
+		current := &rainbondv1alpha1.RainbondCluster{}
+		if err := cli.Get(ctx, types.NamespacedName{Namespace: "rbd-system", Name: "rainbondcluster"}, current); err != nil {
+			t.Fatal(err)
+		}
+		current.Spec.Suspend = cluster.Spec.Suspend
+		mgr := NewClusterMgr(ctx, cli, logr.Discard(), current, scheme)
+		suspending, err := mgr.ReconcileSuspension()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cli.Get(ctx, types.NamespacedName{Namespace: "rbd-system", Name: "rainbondcluster"}, current); err != nil {
+			t.Fatal(err)
+		}
+		return suspending, current.Status.Suspension
+	}
+
+	suspending, status := reconcile()
+	assert.True(t, suspending)
+	assert.Equal(t, rainbondv1alpha1.SuspensionPhaseSuspending, status.Phase)
+	assert.Equal(t, []string{"rbd-gateway"}, status.Stopped)
+
+	deletePods("rbd-gateway")
+	suspending, status = reconcile()
+	assert.True(t, suspending)
+	assert.Equal(t, []string{"rbd-gateway", "rbd-db"}, status.Stopped)
+
+	// the storage keeps running.
+	deletePods("rbd-db")
+	suspending, status = reconcile()
+	assert.False(t, suspending)
+	assert.Equal(t, rainbondv1alpha1.SuspensionPhaseSuspended, status.Phase)
+	assert.Equal(t, []string{"rbd-gateway", "rbd-db"}, status.Stopped)
+
+	cluster.Spec.Suspend = false
+	suspending, status = reconcile()
+	assert.True(t, suspending)
+	assert.Equal(t, rainbondv1alpha1.SuspensionPhaseResuming, status.Phase)
+	assert.Equal(t, []string{"rbd-gateway"}, status.Stopped)
+	assert.Equal(t, "waiting for rbd-db to be ready", status.Message)
+
+	for _, name := range []string{"rbd-db", "rbd-gateway"} {
+		pod := suspensionPod(name, true)
+		assert.Nil(t, cli.Create(ctx, &pod))
+	}
+	suspending, status = reconcile()
+	assert.False(t, suspending)
+	assert.Nil(t, status)
+}
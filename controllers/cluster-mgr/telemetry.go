@@ -0,0 +1,154 @@
+package clustermgr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/telemetry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// telemetryReportedAnnotation records the last reported result, so that the same result will not be reported again.
+const telemetryReportedAnnotation = "rainbond.io/telemetry-reported"
+
+// telemetryTimeout is the timeout of sending a report, including recording it.
+const telemetryTimeout = 30 * time.Second
+
+// terminalFailureReasons are the reasons of the conditions which fail the installation until the environment or
+// the spec is changed, rather than the ones that may pass by waiting, such as the unready components.
+var terminalFailureReasons = map[string]bool{
+	rainbondv1alpha1.ReasonUnsupportedKubernetesVersion: true,
+	rainbondv1alpha1.ReasonInsufficientMemory:           true,
+	rainbondv1alpha1.ReasonStorageNotRWX:                true,
+	rainbondv1alpha1.ReasonInvalidChaosNodeSelector:     true,
+	rainbondv1alpha1.ReasonNoChaosNodes:                 true,
+	rainbondv1alpha1.ReasonHostPortConflict:             true,
+}
+
+// telemetrySending are the reports being sent, which are not sent again by the following reconciliations.
+var telemetrySending sync.Map
+
+// ReportTelemetry reports the anonymous installation result if telemetry is enabled.
+// Each result of each version is reported only once, in the background so that the reconciliation is not blocked.
+func (r *RainbondClusteMgr) ReportTelemetry() error {
+	config := r.cluster.Spec.Telemetry
+	if config == nil || !config.Enabled || config.Endpoint == "" {
+		return nil
+	}
+
+	report := r.telemetryReport()
+	if report == nil {
+		// still installing
+		return nil
+	}
+	reported := fmt.Sprintf("%s-%s", report.Result, report.InstallVersion)
+	if r.cluster.Annotations[telemetryReportedAnnotation] == reported {
+		return nil
+	}
+	key := string(r.cluster.UID) + "/" + reported
+	if _, sending := telemetrySending.LoadOrStore(key, struct{}{}); sending {
+		return nil
+	}
+
+	go func() {
+		defer telemetrySending.Delete(key)
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+		defer cancel()
+		if err := r.sendTelemetry(ctx, config.Endpoint, report, reported); err != nil {
+			// telemetry must not affect the installation, it is sent again by the next reconciliation.
+			r.log.V(4).Info(fmt.Sprintf("report telemetry: %v", err))
+		}
+	}()
+	return nil
+}
+
+// sendTelemetry sends the report, and records the reported result in the annotation.
+func (r *RainbondClusteMgr) sendTelemetry(ctx context.Context, endpoint string, report *telemetry.Report, reported string) error {
+	if err := telemetry.Send(ctx, endpoint, report); err != nil {
+		return fmt.Errorf("send telemetry report: %v", err)
+	}
+	r.log.Info("telemetry reported", "result", report.Result)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster := &rainbondv1alpha1.RainbondCluster{}
+		if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.cluster.Namespace, Name: r.cluster.Name}, cluster); err != nil {
+			return err
+		}
+		if cluster.Annotations == nil {
+			cluster.Annotations = make(map[string]string)
+		}
+		cluster.Annotations[telemetryReportedAnnotation] = reported
+		return r.client.Update(ctx, cluster)
+	})
+}
+
+// telemetryReport returns the report of the installation, or nil if the installation is neither finished nor failed
+// for the terminal reasons.
+func (r *RainbondClusteMgr) telemetryReport() *telemetry.Report {
+	uid := sha256.Sum256([]byte(r.cluster.UID))
+	report := &telemetry.Report{
+		InstallID:         hex.EncodeToString(uid[:]),
+		InstallVersion:    r.cluster.Spec.InstallVersion,
+		InstallMode:       string(r.cluster.Spec.InstallMode),
+		KubernetesVersion: r.kubernetesVersion(),
+		ComponentVersions: r.componentVersions(),
+	}
+
+	running := false
+	for _, condition := range r.cluster.Status.Conditions {
+		if condition.Type == rainbondv1alpha1.RainbondClusterConditionTypeRunning {
+			running = condition.Status == corev1.ConditionTrue
+			continue
+		}
+		if condition.Status == corev1.ConditionFalse && terminalFailureReasons[condition.Reason] {
+			report.FailureReasons = append(report.FailureReasons, condition.Reason)
+		}
+	}
+	if len(report.FailureReasons) > 0 {
+		sort.Strings(report.FailureReasons)
+		report.Result = telemetry.ResultFailure
+		return report
+	}
+	if running {
+		report.Result = telemetry.ResultSuccess
+		return report
+	}
+	return nil
+}
+
+func (r *RainbondClusteMgr) kubernetesVersion() string {
+	nodes, err := k8sutil.ListNodes(r.ctx, r.client)
+	if err != nil {
+		return ""
+	}
+	for _, node := range nodes {
+		if node.Status.NodeInfo.KubeletVersion != "" {
+			return node.Status.NodeInfo.KubeletVersion
+		}
+	}
+	return ""
+}
+
+func (r *RainbondClusteMgr) componentVersions() map[string]string {
+	cpts := &rainbondv1alpha1.RbdComponentList{}
+	if err := r.client.List(r.ctx, cpts, client.InNamespace(r.cluster.Namespace)); err != nil {
+		return nil
+	}
+	versions := make(map[string]string, len(cpts.Items))
+	for _, cpt := range cpts.Items {
+		if tag := imageTag(cpt.Spec.Image); tag != "" {
+			versions[cpt.Name] = tag
+		}
+	}
+	return versions
+}
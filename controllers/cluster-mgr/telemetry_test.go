@@ -0,0 +1,82 @@
+package clustermgr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/telemetry"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTelemetryReport(t *testing.T) {
+	condition := func(typ rainbondv1alpha1.RainbondClusterConditionType, status corev1.ConditionStatus, reason string) rainbondv1alpha1.RainbondClusterCondition {
+		return rainbondv1alpha1.RainbondClusterCondition{Type: typ, Status: status, Reason: reason}
+	}
+	tests := []struct {
+		name       string
+		conditions []rainbondv1alpha1.RainbondClusterCondition
+		wantResult telemetry.Result
+		wantReason []string
+	}{
+		{
+			name: "installing",
+			conditions: []rainbondv1alpha1.RainbondClusterCondition{
+				condition(rainbondv1alpha1.RainbondClusterConditionTypeRunning, corev1.ConditionFalse, ""),
+			},
+		},
+		{
+			name: "transient failure",
+			conditions: []rainbondv1alpha1.RainbondClusterCondition{
+				condition(rainbondv1alpha1.RainbondClusterConditionTypeRunning, corev1.ConditionFalse, ""),
+				condition(rainbondv1alpha1.RainbondClusterConditionTypeHostPorts, corev1.ConditionFalse, rainbondv1alpha1.ReasonHostPortsFailed),
+			},
+		},
+		{
+			name: "terminal failure",
+			conditions: []rainbondv1alpha1.RainbondClusterCondition{
+				condition(rainbondv1alpha1.RainbondClusterConditionTypeRunning, corev1.ConditionFalse, ""),
+				condition(rainbondv1alpha1.RainbondClusterConditionTypeHostPorts, corev1.ConditionFalse, rainbondv1alpha1.ReasonHostPortConflict),
+			},
+			wantResult: telemetry.ResultFailure,
+			wantReason: []string{rainbondv1alpha1.ReasonHostPortConflict},
+		},
+		{
+			name: "running",
+			conditions: []rainbondv1alpha1.RainbondClusterCondition{
+				condition(rainbondv1alpha1.RainbondClusterConditionTypeRunning, corev1.ConditionTrue, ""),
+			},
+			wantResult: telemetry.ResultSuccess,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			cluster := &rainbondv1alpha1.RainbondCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system", UID: "uid"},
+				Status:     rainbondv1alpha1.RainbondClusterStatus{Conditions: tc.conditions},
+			}
+			cli := fake.NewFakeClientWithScheme(scheme, cluster)
+			mgr := NewClusterMgr(context.Background(), cli, logr.Discard(), cluster, scheme)
+
+			report := mgr.telemetryReport()
+			if tc.wantResult == "" {
+				assert.Nil(t, report)
+				return
+			}
+			if assert.NotNil(t, report) {
+				assert.Equal(t, tc.wantResult, report.Result)
+				assert.Equal(t, tc.wantReason, report.FailureReasons)
+			}
+		})
+	}
+}
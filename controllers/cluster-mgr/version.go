@@ -0,0 +1,89 @@
+package clustermgr
+
+import (
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// componentVersionsStatus returns the versions of the rbdcomponents, and the installed version, which is the install
+// version once all the rbdcomponents are ready and up to date, or the previous installed version otherwise.
+func (r *RainbondClusteMgr) componentVersionsStatus() (map[string]rainbondv1alpha1.ComponentVersionStatus, string) {
+	installed := r.cluster.Status.InstalledVersion
+
+	cpts, err := r.listRbdComponents()
+	if err != nil {
+		r.log.V(6).Info("list rbdcomponents", "error", err.Error())
+		return r.cluster.Status.Components, installed
+	}
+	pods := &corev1.PodList{}
+	if err := r.client.List(r.ctx, pods, client.InNamespace(r.cluster.Namespace), client.MatchingLabels(rbdutil.LabelsForRainbond(nil))); err != nil {
+		r.log.V(6).Info("list pods", "error", err.Error())
+		return r.cluster.Status.Components, installed
+	}
+
+	versions := componentVersions(cpts, pods.Items)
+	if len(versions) == 0 {
+		return nil, installed
+	}
+	for _, version := range versions {
+		if !version.Ready || !version.UpToDate {
+			return versions, installed
+		}
+	}
+	return versions, r.cluster.Spec.InstallVersion
+}
+
+// componentVersions returns the versions of the rbdcomponents, the pods are matched to the rbdcomponents by the label name.
+func componentVersions(cpts []rainbondv1alpha1.RbdComponent, pods []corev1.Pod) map[string]rainbondv1alpha1.ComponentVersionStatus {
+	podsOfComponents := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		name := pod.Labels["name"]
+		podsOfComponents[name] = append(podsOfComponents[name], pod)
+	}
+
+	versions := make(map[string]rainbondv1alpha1.ComponentVersionStatus, len(cpts))
+	for _, cpt := range cpts {
+		version := rainbondv1alpha1.ComponentVersionStatus{
+			Image:    cpt.Spec.Image,
+			Version:  imageTag(cpt.Spec.Image),
+			UpToDate: true,
+		}
+		version.Ready = cpt.Status.ComputePhase() == rainbondv1alpha1.RbdComponentRunning
+		for _, pod := range podsOfComponents[cpt.Name] {
+			if !runningImage(pod, cpt.Spec.Image) {
+				version.UpToDate = false
+				break
+			}
+		}
+		versions[cpt.Name] = version
+	}
+	return versions
+}
+
+// runningImage returns true if one of the containers of the pod runs the image.
+func runningImage(pod corev1.Pod, image string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Image == image {
+			return true
+		}
+	}
+	return false
+}
+
+// imageTag returns the tag of the image, or empty if it has no tag.
+func imageTag(image string) string {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		return image[idx+1:]
+	}
+	return ""
+}
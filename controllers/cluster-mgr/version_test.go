@@ -0,0 +1,50 @@
+package clustermgr
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComponentVersions(t *testing.T) {
+	cpt := func(name, image string, ready corev1.ConditionStatus) rainbondv1alpha1.RbdComponent {
+		return rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       rainbondv1alpha1.RbdComponentSpec{Image: image},
+			Status: rainbondv1alpha1.RbdComponentStatus{
+				Conditions: []rainbondv1alpha1.RbdComponentCondition{{Type: rainbondv1alpha1.RbdComponentReady, Status: ready}},
+			},
+		}
+	}
+	pod := func(component, image string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"name": component}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: component, Image: image}}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	cpts := []rainbondv1alpha1.RbdComponent{
+		cpt("rbd-api", "goodrain.me/rbd-api:v5.3.3", corev1.ConditionTrue),
+		cpt("rbd-worker", "goodrain.me/rbd-worker:v5.3.3", corev1.ConditionTrue),
+	}
+	pods := []corev1.Pod{
+		pod("rbd-api", "goodrain.me/rbd-api:v5.3.3", corev1.PodRunning),
+		pod("rbd-worker", "goodrain.me/rbd-worker:v5.3.2", corev1.PodRunning),
+		pod("rbd-api", "goodrain.me/rbd-api:v5.3.2", corev1.PodFailed),
+	}
+
+	versions := componentVersions(cpts, pods)
+	assert.Equal(t, map[string]rainbondv1alpha1.ComponentVersionStatus{
+		"rbd-api":    {Image: "goodrain.me/rbd-api:v5.3.3", Version: "v5.3.3", Ready: true, UpToDate: true},
+		"rbd-worker": {Image: "goodrain.me/rbd-worker:v5.3.3", Version: "v5.3.3", Ready: true, UpToDate: false},
+	}, versions)
+}
+
+func TestImageTag(t *testing.T) {
+	assert.Equal(t, "v5.3.3", imageTag("goodrain.me/rbd-api:v5.3.3"))
+	assert.Equal(t, "", imageTag("registry:5000/rbd-api"))
+	assert.Equal(t, "v1", imageTag("rbd-api:v1@sha256:aaa"))
+}
@@ -0,0 +1,142 @@
+package componentmgr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/imageutil"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/operatorconfig"
+	"github.com/goodrain/rainbond-operator/util/proxyutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// architectureRetryInterval is how long the failed detection of an image is retried after.
+	architectureRetryInterval = 5 * time.Minute
+	architectureDetectTimeout = 10 * time.Second
+)
+
+// Architectures returns the architectures the pods of the rbdcomponent are pinned to, nil if they can run on
+// all the nodes or the architectures of the image are unknown. The architectures of the image are detected
+// only if the nodes are of mixed architectures and the feature gate ImageArchitectureDetection is enabled,
+// unless they are given in the spec.
+// An error is returned if no node is of the architectures of the image.
+func (r *RbdcomponentMgr) Architectures(cluster *rainbondv1alpha1.RainbondCluster) ([]string, error) {
+	nodes, err := k8sutil.ListNodes(r.ctx, r.client)
+	if err != nil {
+		// the pods are scheduled without the architectures as before.
+		r.log.Info("list nodes for the architectures", "error", err.Error())
+		return nil, nil
+	}
+	nodeArchitectures := nodesArchitectures(nodes)
+
+	supported := r.cpt.Spec.Architectures
+	if len(supported) == 0 && len(nodeArchitectures) > 1 && operatorconfig.Enabled(operatorconfig.ImageArchitectureDetection) {
+		supported = r.imageArchitectures(cluster)
+	}
+	return pinnedArchitectures(supported, nodeArchitectures)
+}
+
+// imageArchitectures returns the architectures of the image of the rbdcomponent, which are detected from
+// the manifest and cached in the status. The failed detection is retried after architectureRetryInterval.
+func (r *RbdcomponentMgr) imageArchitectures(cluster *rainbondv1alpha1.RainbondCluster) []string {
+	image := r.cpt.Spec.Image
+	if image == "" {
+		return nil
+	}
+	if status := r.cpt.Status.ImageArchitectures; status != nil && status.Image == image &&
+		(len(status.Architectures) > 0 || time.Since(status.DetectTime.Time) < architectureRetryInterval) {
+		return status.Architectures
+	}
+
+	status := &rainbondv1alpha1.ImageArchitectures{
+		Image:      image,
+		DetectTime: metav1.Now(),
+	}
+	r.cpt.Status.ImageArchitectures = status
+
+	checker, err := r.manifestChecker(cluster)
+	if err != nil {
+		status.Message = err.Error()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, architectureDetectTimeout)
+	defer cancel()
+	architectures, err := checker.Architectures(ctx, image)
+	if err != nil {
+		r.log.Info("detect the architectures of the image", "image", image, "error", err.Error())
+		status.Message = err.Error()
+		return nil
+	}
+	r.log.Info("detected the architectures of the image", "image", image, "architectures", architectures)
+	status.Architectures = architectures
+	return architectures
+}
+
+// manifestChecker returns the checker of the manifests of the images, which requests the image hub as the components do.
+func (r *RbdcomponentMgr) manifestChecker(cluster *rainbondv1alpha1.RainbondCluster) (*imageutil.ManifestChecker, error) {
+	var username, password string
+	if imageHub := cluster.Spec.ImageHub; imageHub != nil {
+		username, password = imageHub.Username, imageHub.Password
+	}
+	// goodrain.me is resolved to the gateway by the host aliases of the components, but not the operator.
+	addresses := make(map[string]string)
+	if ip := cluster.InnerGatewayIngressIP(); ip != "" {
+		addresses[constants.DefImageRepository] = ip
+	}
+	proxy, err := proxyutil.Get(r.ctx, r.client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return imageutil.NewManifestChecker(username, password, addresses, proxy.ProxyFunc()), nil
+}
+
+// nodesArchitectures returns the architectures of the nodes.
+func nodesArchitectures(nodes []corev1.Node) []string {
+	seen := make(map[string]bool)
+	var architectures []string
+	for _, node := range nodes {
+		arch := node.Labels[corev1.LabelArchStable]
+		if arch == "" {
+			arch = node.Status.NodeInfo.Architecture
+		}
+		if arch == "" || seen[arch] {
+			continue
+		}
+		seen[arch] = true
+		architectures = append(architectures, arch)
+	}
+	sort.Strings(architectures)
+	return architectures
+}
+
+// pinnedArchitectures returns the architectures of the nodes supported by the image, nil if the image supports
+// all of them or the architectures of the image are unknown.
+func pinnedArchitectures(supported, nodeArchitectures []string) ([]string, error) {
+	if len(supported) == 0 || len(nodeArchitectures) == 0 {
+		return nil, nil
+	}
+	var compatible []string
+	for _, arch := range nodeArchitectures {
+		for _, s := range supported {
+			if s == arch {
+				compatible = append(compatible, arch)
+				break
+			}
+		}
+	}
+	if len(compatible) == 0 {
+		return nil, fmt.Errorf("no compatible nodes: the image supports %v, but the nodes are %v", supported, nodeArchitectures)
+	}
+	if len(compatible) == len(nodeArchitectures) {
+		return nil, nil
+	}
+	return compatible, nil
+}
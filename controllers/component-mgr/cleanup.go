@@ -0,0 +1,136 @@
+package componentmgr
+
+import (
+	"fmt"
+
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CleanupResources deletes the resources generated for the rbdcomponent, which are found by the labels of the component.
+// The persistent volume claims and volumes are kept if preserveVolumes is true, and they are released from the
+// rbdcomponent so that they won't be garbage collected either.
+func (r *RbdcomponentMgr) CleanupResources(preserveVolumes bool) error {
+	labels := client.MatchingLabels(handler.LabelsForRainbondComponent(r.cpt))
+	inNamespace := client.InNamespace(r.cpt.Namespace)
+
+	namespaced := []client.ObjectList{
+		&appsv1.DeploymentList{},
+		&appsv1.StatefulSetList{},
+		&appsv1.DaemonSetList{},
+		&batchv1beta1.CronJobList{},
+		&batchv1.JobList{},
+		&corev1.ServiceList{},
+		&networkingv1.IngressList{},
+		&networkingv1.NetworkPolicyList{},
+		&mv1.ServiceMonitorList{},
+		&corev1.ConfigMapList{},
+		&corev1.SecretList{},
+		&corev1.ServiceAccountList{},
+		&rbacv1.RoleBindingList{},
+		&rbacv1.RoleList{},
+	}
+	clusterScoped := []client.ObjectList{
+		&rbacv1.ClusterRoleBindingList{},
+		&rbacv1.ClusterRoleList{},
+	}
+	if !preserveVolumes {
+		namespaced = append(namespaced, &corev1.PersistentVolumeClaimList{})
+		clusterScoped = append(clusterScoped, &corev1.PersistentVolumeList{})
+	}
+
+	for _, list := range namespaced {
+		if err := r.deleteAllOf(list, inNamespace, labels); err != nil {
+			return err
+		}
+	}
+	for _, list := range clusterScoped {
+		if err := r.deleteAllOf(list, labels); err != nil {
+			return err
+		}
+	}
+
+	if preserveVolumes {
+		return r.releaseClaims()
+	}
+	return nil
+}
+
+// CleanupClusterScopedResources deletes the cluster-scoped resources generated for the rbdcomponent, which are not
+// deleted with the namespace. The persistent volumes are left to their reclaim policies.
+func (r *RbdcomponentMgr) CleanupClusterScopedResources() error {
+	labels := client.MatchingLabels(handler.LabelsForRainbondComponent(r.cpt))
+	for _, list := range []client.ObjectList{&rbacv1.ClusterRoleBindingList{}, &rbacv1.ClusterRoleList{}} {
+		if err := r.deleteAllOf(list, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAllOf deletes the objects of the list matching the options.
+func (r *RbdcomponentMgr) deleteAllOf(list client.ObjectList, opts ...client.ListOption) error {
+	if err := r.client.List(r.ctx, list, opts...); err != nil {
+		if meta.IsNoMatchError(err) {
+			// the kind is not installed, such as ServiceMonitor.
+			return nil
+		}
+		return fmt.Errorf("list %T: %v", list, err)
+	}
+	objs, err := meta.ExtractList(list)
+	if err != nil {
+		return fmt.Errorf("extract %T: %v", list, err)
+	}
+	for _, obj := range objs {
+		o, ok := obj.(client.Object)
+		if !ok || !o.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if o.GetNamespace() == "" && rbdutil.CheckInstance(o, r.cpt.Namespace) != nil {
+			// the component of the rainbond in another namespace has the same labels.
+			continue
+		}
+		r.log.V(4).Info("delete the generated resource", "kind", fmt.Sprintf("%T", o), "name", o.GetName())
+		if err := r.client.Delete(r.ctx, o, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("delete %T %s: %v", o, o.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// releaseClaims removes the rbdcomponent from the owners of the claims, so that the claims are kept after it is deleted.
+func (r *RbdcomponentMgr) releaseClaims() error {
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := r.client.List(r.ctx, claims, client.InNamespace(r.cpt.Namespace)); err != nil {
+		return fmt.Errorf("list persistent volume claims: %v", err)
+	}
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		var owners []metav1.OwnerReference
+		for _, owner := range claim.OwnerReferences {
+			if owner.UID != r.cpt.UID {
+				owners = append(owners, owner)
+			}
+		}
+		if len(owners) == len(claim.OwnerReferences) {
+			continue
+		}
+		claim.OwnerReferences = owners
+		if err := r.client.Update(r.ctx, claim); err != nil {
+			return fmt.Errorf("release persistent volume claim %s: %v", claim.Name, err)
+		}
+		r.log.Info("keep the persistent volume claim", "name", claim.Name)
+	}
+	return nil
+}
@@ -12,14 +12,16 @@ import (
 	"github.com/goodrain/rainbond-operator/controllers/handler"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -33,6 +35,8 @@ type RbdcomponentMgr struct {
 
 	cpt        *rainbondv1alpha1.RbdComponent
 	replicaser handler.Replicaser
+	// readStatus is the status of the rbdcomponent when it was read, only the changes made since then are written.
+	readStatus *rainbondv1alpha1.RbdComponentStatus
 }
 
 //NewRbdcomponentMgr -
@@ -44,6 +48,9 @@ func NewRbdcomponentMgr(ctx context.Context, client client.Client, recorder reco
 		log:      log,
 		cpt:      cpt,
 	}
+	if cpt != nil {
+		mgr.readStatus = cpt.Status.DeepCopy()
+	}
 	return mgr
 }
 
@@ -61,38 +68,44 @@ func (r *RbdcomponentMgr) UpdateStatus() error {
 		condtion = rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse, "", "")
 		status.SetCondition(*condtion)
 	}
+	status.Phase = status.ComputePhase()
 	r.cpt.Status = *status
 
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		return r.client.Status().Update(r.ctx, r.cpt)
-	})
+	// only the changes made since the rbdcomponent was read are written, the conditions written by the others are kept.
+	if err := k8sutil.UpdateStatusChanges(r.ctx, r.client, r.cpt, r.readStatus, status, func(latest client.Object) interface{} {
+		return &latest.(*rainbondv1alpha1.RbdComponent).Status
+	}); err != nil {
+		return err
+	}
+	r.readStatus = r.cpt.Status.DeepCopy()
+	return nil
 }
 
 //SetConfigCompletedCondition -
 func (r *RbdcomponentMgr) SetConfigCompletedCondition() {
-	condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ClusterConfigCompeleted, corev1.ConditionTrue, "ConfigCompleted", "")
+	condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ClusterConfigCompeleted, corev1.ConditionTrue, rainbondv1alpha1.ReasonConfigCompleted, "")
 	_ = r.cpt.Status.UpdateCondition(condition)
 }
 
 //SetPackageReadyCondition -
 func (r *RbdcomponentMgr) SetPackageReadyCondition(pkg *rainbondv1alpha1.RainbondPackage) {
 	if pkg == nil {
-		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionTrue, "PackageReady", "")
+		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionTrue, rainbondv1alpha1.ReasonPackageReady, "")
 		_ = r.cpt.Status.UpdateCondition(condition)
 		return
 	}
 	_, pkgcondition := pkg.Status.GetCondition(rainbondv1alpha1.Ready)
 	if pkgcondition == nil {
-		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionFalse, "PackageNotReady", "")
+		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionFalse, rainbondv1alpha1.ReasonPackageNotReady, "")
 		_ = r.cpt.Status.UpdateCondition(condition)
 		return
 	}
 	if pkgcondition.Status != rainbondv1alpha1.Completed {
-		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionFalse, "PackageNotReady", pkgcondition.Message)
+		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionFalse, rainbondv1alpha1.ReasonPackageNotReady, pkgcondition.Message)
 		_ = r.cpt.Status.UpdateCondition(condition)
 		return
 	}
-	condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionTrue, "PackageReady", "")
+	condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionTrue, rainbondv1alpha1.ReasonPackageReady, "")
 	_ = r.cpt.Status.UpdateCondition(condition)
 }
 
@@ -147,9 +160,10 @@ func (r *RbdcomponentMgr) GenerateStatus(pods []corev1.Pod) {
 		newPods = append(newPods, newPod)
 	}
 	status.Pods = newPods
+	status.HostFiles = hostFilesStatus(pods, r.cpt.Spec.HostFiles)
 
 	if status.ReadyReplicas >= replicas {
-		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionTrue, "Ready", "")
+		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionTrue, rainbondv1alpha1.ReasonReady, "")
 		status.UpdateCondition(condition)
 	}
 
@@ -176,9 +190,30 @@ func (r *RbdcomponentMgr) ResourceCreateIfNotExists(obj client.Object) error {
 		r.log.V(4).Info(fmt.Sprintf("Creating a new %s", obj.GetObjectKind().GroupVersionKind().Kind), "Namespace", obj.GetNamespace(), "Name", obj.GetName())
 		return r.client.Create(r.ctx, obj)
 	}
+	if obj.GetNamespace() == "" {
+		// the cluster-scoped resources are named per namespace, refuse to share them among the rainbonds.
+		return rbdutil.CheckInstance(obj, r.cpt.Namespace)
+	}
 	return nil
 }
 
+// Render applies the settings shared by all the rbdcomponents to the resource returned by the handler.
+func (r *RbdcomponentMgr) Render(res client.Object, cluster *rainbondv1alpha1.RainbondCluster, dependencies []handler.Dependency,
+	imageFallback *rainbondv1alpha1.ImageFallback, architectures []string) {
+	handler.SetImageFallback(res, imageFallback)
+	handler.SetDependencies(res, cluster, dependencies)
+	handler.SetTimezone(res, cluster.Spec.Timezone)
+	handler.SetMaintenance(res, cluster)
+	handler.SetSuspension(res, r.cpt.Name, cluster)
+	handler.SetProbes(res, r.cpt.Spec.Probes)
+	handler.SetControlPlaneScheduling(res, r.cpt.Name, cluster.Spec.ControlPlaneScheduling)
+	handler.SetStorageNodeSelector(res, r.cpt.Name, cluster.Spec.NodeRoles)
+	handler.SetArchitectures(res, architectures)
+	handler.SetLinuxNodeSelector(res)
+	handler.SetServiceMesh(res, r.cpt.Name, cluster.Spec.ServiceMesh)
+	handler.SetAdditionalMetadata(res, cluster)
+}
+
 //UpdateOrCreateResource -
 func (r *RbdcomponentMgr) UpdateOrCreateResource(obj client.Object) (reconcile.Result, error) {
 	var oldOjb = reflect.New(reflect.ValueOf(obj).Elem().Type()).Interface().(client.Object)
@@ -203,6 +238,23 @@ func (r *RbdcomponentMgr) UpdateOrCreateResource(obj client.Object) (reconcile.R
 		return reconcile.Result{}, nil
 	}
 
+	if sts, ok := obj.(*appsv1.StatefulSet); ok {
+		old := oldOjb.(*appsv1.StatefulSet)
+		if !old.DeletionTimestamp.IsZero() {
+			// wait for the statefulset being recreated to be deleted.
+			return reconcile.Result{RequeueAfter: time.Second}, nil
+		}
+		if claimTemplatesChanged(old, sts) {
+			// spec.volumeClaimTemplates is immutable, the pods are orphaned and adopted by the new statefulset,
+			// and the claims of the templates kept are reused.
+			r.log.Info("recreate statefulset for the changed claim templates", "name", sts.Name)
+			if err := r.client.Delete(ctx, old, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil && !k8sErrors.IsNotFound(err) {
+				return reconcile.Result{}, fmt.Errorf("delete statefulset %s: %v", sts.Name, err)
+			}
+			return reconcile.Result{RequeueAfter: time.Second}, nil
+		}
+	}
+
 	obj = r.updateRuntimeObject(oldOjb, obj)
 
 	r.log.V(5).Info("Object exists.", "Kind", obj.GetObjectKind().GroupVersionKind().Kind,
@@ -225,6 +277,21 @@ func (r *RbdcomponentMgr) updateRuntimeObject(old, new client.Object) client.Obj
 		n.Spec.ClusterIP = o.Spec.ClusterIP
 		return n
 	}
+	if n, ok := new.(*appsv1.StatefulSet); ok {
+		// spec.volumeClaimTemplates is immutable, the claims are expanded by ExpandVolumes.
+		o := old.(*appsv1.StatefulSet)
+		n.Spec.VolumeClaimTemplates = o.Spec.VolumeClaimTemplates
+		// spec.serviceName is immutable, the statefulset has to be recreated by the handler to change it.
+		n.Spec.ServiceName = o.Spec.ServiceName
+		return n
+	}
+	if n, ok := new.(*corev1.ServiceAccount); ok {
+		// keep the token secrets generated by kubernetes.
+		o := old.(*corev1.ServiceAccount)
+		n.ResourceVersion = o.ResourceVersion
+		n.Secrets = o.Secrets
+		return n
+	}
 	if n, ok := new.(*mv1.ServiceMonitor); ok {
 		r.log.V(6).Info("copy necessary fields from old service before updating")
 		o := old.(*corev1.Service)
@@ -234,6 +301,19 @@ func (r *RbdcomponentMgr) updateRuntimeObject(old, new client.Object) client.Obj
 	return new
 }
 
+// claimTemplatesChanged returns true if claim templates are added to or removed from the statefulset, then the
+// volumes mounted by the new pod template don't match the templates kept by updateRuntimeObject.
+func claimTemplatesChanged(old, new *appsv1.StatefulSet) bool {
+	names := func(sts *appsv1.StatefulSet) map[string]bool {
+		m := make(map[string]bool, len(sts.Spec.VolumeClaimTemplates))
+		for _, template := range sts.Spec.VolumeClaimTemplates {
+			m[template.Name] = true
+		}
+		return m
+	}
+	return !reflect.DeepEqual(names(old), names(new))
+}
+
 func objectCanUpdate(obj client.Object) bool {
 	if obj.GetAnnotations()["ignore_controller_update"] == "true" {
 		return false
@@ -266,7 +346,7 @@ func (r *RbdcomponentMgr) DeleteResources(deleter handler.ResourcesDeleter) (*re
 		}
 		if err := r.deleteResourcesIfExists(res); err != nil {
 			condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady,
-				corev1.ConditionFalse, "ErrDeleteResource", err.Error())
+				corev1.ConditionFalse, rainbondv1alpha1.ReasonDeleteResourceFailed, err.Error())
 			changed := r.cpt.Status.UpdateCondition(condition)
 			if changed {
 				r.recorder.Event(r.cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -0,0 +1,32 @@
+package componentmgr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClaimTemplatesChanged(t *testing.T) {
+	sts := func(names ...string) *appsv1.StatefulSet {
+		s := &appsv1.StatefulSet{}
+		for _, name := range names {
+			s.Spec.VolumeClaimTemplates = append(s.Spec.VolumeClaimTemplates, corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			})
+		}
+		return s
+	}
+
+	assert.False(t, claimTemplatesChanged(sts(), sts()))
+	assert.False(t, claimTemplatesChanged(sts("data"), sts("data")))
+	// the size and the storage class are handled by ExpandVolumes.
+	resized := sts("data")
+	resized.Spec.VolumeClaimTemplates[0].Spec.StorageClassName = new(string)
+	assert.False(t, claimTemplatesChanged(sts("data"), resized))
+	assert.True(t, claimTemplatesChanged(sts(), sts("data")))
+	assert.True(t, claimTemplatesChanged(sts("data"), sts()))
+	assert.True(t, claimTemplatesChanged(sts("rbd-db"), sts("data")))
+}
@@ -0,0 +1,257 @@
+package componentmgr
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DriftAction is what the reconciliation will do to a resource.
+type DriftAction string
+
+const (
+	// DriftActionNone means the resource is as rendered, or the differences are kept.
+	DriftActionNone DriftAction = "None"
+	// DriftActionCreate means the resource doesn't exist and will be created.
+	DriftActionCreate DriftAction = "Create"
+	// DriftActionUpdate means the differences of the resource will be overwritten.
+	DriftActionUpdate DriftAction = "Update"
+	// DriftActionDelete means the resource is no longer needed and will be deleted.
+	DriftActionDelete DriftAction = "Delete"
+)
+
+// FieldDrift is a field of the live resource that differs from the rendered one.
+type FieldDrift struct {
+	Path    string      `json:"path"`
+	Live    interface{} `json:"live,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+// ResourceDrift tells how the live resource differs from the one rendered by the operator.
+type ResourceDrift struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Action DriftAction `json:"action"`
+	// Kept are the edits the reconciliation keeps, such as the immutable fields and the resources not updated by the operator.
+	Kept []FieldDrift `json:"kept,omitempty"`
+	// Overwritten are the edits the reconciliation reverts to the rendered values.
+	Overwritten []FieldDrift `json:"overwritten,omitempty"`
+	// Message explains why the edits are kept.
+	Message string `json:"message,omitempty"`
+}
+
+// Drift renders the resources of the rbdcomponent as the reconciliation does, and compares them with the live ones.
+// The image fallback in the status is used as is, instead of checking the images again.
+// Nothing is changed by the drift, unless the handler does in Before, so a dry-run client is recommended.
+func (r *RbdcomponentMgr) Drift(hdl handler.ComponentHandler, cluster *rainbondv1alpha1.RainbondCluster) ([]ResourceDrift, error) {
+	resources, err := r.renderResources(hdl, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []ResourceDrift
+	for _, res := range resources {
+		drift, err := r.resourceDrift(res)
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, *drift)
+	}
+
+	if deleter, ok := hdl.(handler.ResourcesDeleter); ok {
+		for _, res := range deleter.ResourcesNeedDelete() {
+			if res == nil {
+				continue
+			}
+			if err := r.client.Get(r.ctx, types.NamespacedName{Namespace: res.GetNamespace(), Name: res.GetName()}, res); err != nil {
+				if k8sErrors.IsNotFound(err) {
+					continue
+				}
+				return nil, fmt.Errorf("get %s %s: %v", kindOf(res), res.GetName(), err)
+			}
+			drifts = append(drifts, ResourceDrift{Kind: kindOf(res), Name: res.GetName(), Action: DriftActionDelete})
+		}
+	}
+	return drifts, nil
+}
+
+// renderResources renders the resources of the handler as the reconciliation does. The image fallback in the status
+// is used as is, instead of checking the images again.
+func (r *RbdcomponentMgr) renderResources(hdl handler.ComponentHandler, cluster *rainbondv1alpha1.RainbondCluster) ([]client.Object, error) {
+	if err := hdl.Before(); err != nil {
+		return nil, fmt.Errorf("prepare resources: %w", err)
+	}
+	architectures, err := r.Architectures(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var dependencies []handler.Dependency
+	if dependenter, ok := hdl.(handler.Dependenter); ok {
+		dependencies = dependenter.Dependencies()
+	}
+
+	var resources []client.Object
+	for _, res := range hdl.Resources() {
+		if res == nil {
+			continue
+		}
+		r.Render(res, cluster, dependencies, r.cpt.Status.ImageFallback, architectures)
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+// resourceDrift compares the rendered resource with the live one, the same way UpdateOrCreateResource updates it.
+func (r *RbdcomponentMgr) resourceDrift(desired client.Object) (*ResourceDrift, error) {
+	drift := &ResourceDrift{Kind: kindOf(desired), Name: desired.GetName(), Action: DriftActionNone}
+	live := reflect.New(reflect.ValueOf(desired).Elem().Type()).Interface().(client.Object)
+	if err := r.client.Get(r.ctx, types.NamespacedName{Namespace: desired.GetNamespace(), Name: desired.GetName()}, live); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			drift.Action = DriftActionCreate
+			return drift, nil
+		}
+		return nil, fmt.Errorf("get %s %s: %v", drift.Kind, drift.Name, err)
+	}
+
+	diffs, err := diffObjects(desired, live)
+	if err != nil {
+		return nil, err
+	}
+	if !objectCanUpdate(live) {
+		drift.Kept = diffs
+		if len(diffs) > 0 {
+			drift.Message = "the resource is not updated by the operator"
+		}
+		return drift, nil
+	}
+
+	// the fields copied from the live resource before updating are kept.
+	updated := r.updateRuntimeObject(live, desired.DeepCopyObject().(client.Object))
+	remaining, err := diffObjects(updated, live)
+	if err != nil {
+		return nil, err
+	}
+	overwritten := make(map[string]bool, len(remaining))
+	for _, diff := range remaining {
+		overwritten[diff.Path] = true
+	}
+	for _, diff := range diffs {
+		if !overwritten[diff.Path] {
+			drift.Kept = append(drift.Kept, diff)
+		}
+	}
+	drift.Overwritten = remaining
+	if len(remaining) > 0 {
+		drift.Action = DriftActionUpdate
+	}
+	return drift, nil
+}
+
+func kindOf(obj client.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	return reflect.ValueOf(obj).Elem().Type().Name()
+}
+
+// diffObjects returns the fields set in the desired object which differ in the live one. The metadata other than
+// the labels and the annotations, and the status are not compared. The fields only set in the live object are
+// ignored, since most of them are defaulted by kubernetes. Only the keys of the data of the secrets are reported.
+func diffObjects(desired, live client.Object) ([]FieldDrift, error) {
+	d, err := comparableFields(desired)
+	if err != nil {
+		return nil, err
+	}
+	l, err := comparableFields(live)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []FieldDrift
+	diffFields("", d, l, &diffs)
+	if _, ok := desired.(*corev1.Secret); ok {
+		redactSecretData(diffs)
+	}
+	return diffs, nil
+}
+
+// comparableFields converts the object to the fields compared, without the metadata other than the labels and
+// the annotations, and the status.
+func comparableFields(obj client.Object) (map[string]interface{}, error) {
+	fields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("convert %s %s: %v", kindOf(obj), obj.GetName(), err)
+	}
+	metadata, _ := fields["metadata"].(map[string]interface{})
+	fields["metadata"] = map[string]interface{}{
+		"labels":      metadata["labels"],
+		"annotations": metadata["annotations"],
+	}
+	delete(fields, "status")
+	delete(fields, "apiVersion")
+	delete(fields, "kind")
+	return fields, nil
+}
+
+// redactSecretData drops the values of the data of a secret, such as the keys of the certificates, from the drift.
+func redactSecretData(diffs []FieldDrift) {
+	for i := range diffs {
+		if strings.HasPrefix(diffs[i].Path, "data") || strings.HasPrefix(diffs[i].Path, "stringData") {
+			diffs[i].Live, diffs[i].Desired = nil, nil
+		}
+	}
+}
+
+func diffFields(path string, desired, live interface{}, diffs *[]FieldDrift) {
+	if desired == nil {
+		return
+	}
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, _ := live.(map[string]interface{})
+		keys := make([]string, 0, len(d))
+		for key := range d {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diffFields(fieldPath(path, key), d[key], l[key], diffs)
+		}
+		return
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if len(d) == 0 && len(l) == 0 {
+			return
+		}
+		if ok && len(l) == len(d) {
+			for i := range d {
+				diffFields(fmt.Sprintf("%s[%d]", path, i), d[i], l[i], diffs)
+			}
+			return
+		}
+	default:
+		if reflect.DeepEqual(desired, live) {
+			return
+		}
+	}
+	*diffs = append(*diffs, FieldDrift{Path: path, Live: live, Desired: desired})
+}
+
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, "./") {
+		return fmt.Sprintf("%s[%s]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
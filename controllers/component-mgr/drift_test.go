@@ -0,0 +1,172 @@
+package componentmgr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDiffFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired interface{}
+		live    interface{}
+		want    []FieldDrift
+	}{
+		{
+			name:    "equal",
+			desired: map[string]interface{}{"replicas": int64(1)},
+			live:    map[string]interface{}{"replicas": int64(1)},
+		},
+		{
+			name:    "changed",
+			desired: map[string]interface{}{"replicas": int64(2)},
+			live:    map[string]interface{}{"replicas": int64(1)},
+			want:    []FieldDrift{{Path: "replicas", Live: int64(1), Desired: int64(2)}},
+		},
+		{
+			name:    "missing in live",
+			desired: map[string]interface{}{"replicas": int64(2)},
+			live:    map[string]interface{}{},
+			want:    []FieldDrift{{Path: "replicas", Desired: int64(2)}},
+		},
+		{
+			name:    "only in live",
+			desired: map[string]interface{}{},
+			live:    map[string]interface{}{"clusterIP": "10.0.0.1"},
+		},
+		{
+			name:    "item of list",
+			desired: map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": int64(8080)}}},
+			live:    map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": int64(80)}}},
+			want:    []FieldDrift{{Path: "ports[0].port", Live: int64(80), Desired: int64(8080)}},
+		},
+		{
+			name:    "length of list",
+			desired: map[string]interface{}{"args": []interface{}{"--a", "--b"}},
+			live:    map[string]interface{}{"args": []interface{}{"--a"}},
+			want:    []FieldDrift{{Path: "args", Live: []interface{}{"--a"}, Desired: []interface{}{"--a", "--b"}}},
+		},
+		{
+			name:    "empty list",
+			desired: map[string]interface{}{"args": []interface{}{}},
+			live:    map[string]interface{}{},
+		},
+		{
+			name:    "key with dots",
+			desired: map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "rbd-api"}},
+			live:    map[string]interface{}{"labels": map[string]interface{}{}},
+			want:    []FieldDrift{{Path: "labels[app.kubernetes.io/name]", Desired: "rbd-api"}},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var diffs []FieldDrift
+			diffFields("", tc.desired, tc.live, &diffs)
+			assert.Equal(t, tc.want, diffs)
+		})
+	}
+}
+
+func TestResourceDrift(t *testing.T) {
+	service := func(port int32, annotations map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "rbd-api",
+				Namespace:   "rbd-system",
+				Labels:      map[string]string{"name": "rbd-api"},
+				Annotations: annotations,
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Name: "http", Port: port}},
+			},
+		}
+	}
+	secret := func(key string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-image-repository", Namespace: "rbd-system"},
+			Data:       map[string][]byte{"tls.key": []byte(key)},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		desired client.Object
+		live    client.Object
+		want    *ResourceDrift
+	}{
+		{
+			name:    "not found",
+			desired: service(8888, nil),
+			want:    &ResourceDrift{Kind: "Service", Name: "rbd-api", Action: DriftActionCreate},
+		},
+		{
+			name:    "overwritten",
+			desired: service(8888, nil),
+			live: func() client.Object {
+				svc := service(80, nil)
+				svc.Spec.ClusterIP = "10.0.0.1"
+				svc.Labels["edited"] = "true"
+				return svc
+			}(),
+			want: &ResourceDrift{
+				Kind:        "Service",
+				Name:        "rbd-api",
+				Action:      DriftActionUpdate,
+				Overwritten: []FieldDrift{{Path: "spec.ports[0].port", Live: int64(80), Desired: int64(8888)}},
+			},
+		},
+		{
+			name:    "kept",
+			desired: service(8888, nil),
+			live:    service(80, map[string]string{"ignore_controller_update": "true"}),
+			want: &ResourceDrift{
+				Kind:    "Service",
+				Name:    "rbd-api",
+				Action:  DriftActionNone,
+				Kept:    []FieldDrift{{Path: "spec.ports[0].port", Live: int64(80), Desired: int64(8888)}},
+				Message: "the resource is not updated by the operator",
+			},
+		},
+		{
+			name:    "secret redacted",
+			desired: secret("new"),
+			live:    secret("old"),
+			want: &ResourceDrift{
+				Kind:        "Secret",
+				Name:        "hub-image-repository",
+				Action:      DriftActionUpdate,
+				Overwritten: []FieldDrift{{Path: "data[tls.key]"}},
+			},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			var objs []runtime.Object
+			if tc.live != nil {
+				objs = append(objs, tc.live)
+			}
+			cli := fake.NewFakeClientWithScheme(scheme, objs...)
+			mgr := NewRbdcomponentMgr(context.Background(), cli, nil, logr.Discard(), &rainbondv1alpha1.RbdComponent{})
+			drift, err := mgr.resourceDrift(tc.desired)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.want, drift)
+		})
+	}
+}
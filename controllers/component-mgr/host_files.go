@@ -0,0 +1,84 @@
+package componentmgr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// hostFilesStatus returns the distribution of the host files to the nodes of the pods, by the statuses of the init
+// containers that copy them. The latest pod is taken if there are several ones on a node, such as during a rollout.
+func hostFilesStatus(pods []corev1.Pod, files []rainbondv1alpha1.HostFiles) []rainbondv1alpha1.NodeHostFiles {
+	if len(files) == 0 {
+		return nil
+	}
+	latest := make(map[string]*corev1.Pod)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if last, ok := latest[pod.Spec.NodeName]; ok && !last.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			continue
+		}
+		latest[pod.Spec.NodeName] = pod
+	}
+
+	statuses := make([]rainbondv1alpha1.NodeHostFiles, 0, len(latest))
+	for nodeName, pod := range latest {
+		statuses = append(statuses, nodeHostFiles(nodeName, pod))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NodeName < statuses[j].NodeName
+	})
+	return statuses
+}
+
+func nodeHostFiles(nodeName string, pod *corev1.Pod) rainbondv1alpha1.NodeHostFiles {
+	status := rainbondv1alpha1.NodeHostFiles{NodeName: nodeName}
+	var names []string
+	for _, container := range pod.Spec.InitContainers {
+		if strings.HasPrefix(container.Name, handler.HostFilesContainerPrefix) {
+			names = append(names, container.Name)
+			status.Image = container.Image
+		}
+	}
+	if len(names) == 0 {
+		status.Message = fmt.Sprintf("pod %s doesn't copy the host files, it will be recreated", pod.Name)
+		return status
+	}
+
+	containerStatuses := make(map[string]corev1.ContainerStatus)
+	for _, containerStatus := range pod.Status.InitContainerStatuses {
+		containerStatuses[containerStatus.Name] = containerStatus
+	}
+	for _, name := range names {
+		containerStatus, ok := containerStatuses[name]
+		if !ok {
+			status.Message = fmt.Sprintf("%s has not started", name)
+			return status
+		}
+		state := containerStatus.State
+		switch {
+		case state.Terminated != nil && state.Terminated.ExitCode == 0:
+			continue
+		case state.Terminated != nil:
+			status.Message = fmt.Sprintf("%s failed with exit code %d: %s", name, state.Terminated.ExitCode,
+				strings.TrimSpace(state.Terminated.Reason+" "+state.Terminated.Message))
+		case state.Waiting != nil && containerStatus.LastTerminationState.Terminated != nil:
+			last := containerStatus.LastTerminationState.Terminated
+			status.Message = fmt.Sprintf("%s failed with exit code %d, %s", name, last.ExitCode, state.Waiting.Reason)
+		case state.Waiting != nil:
+			status.Message = strings.TrimSpace(fmt.Sprintf("%s is waiting: %s %s", name, state.Waiting.Reason, state.Waiting.Message))
+		default:
+			status.Message = fmt.Sprintf("%s is copying the files", name)
+		}
+		return status
+	}
+	status.Distributed = true
+	return status
+}
@@ -0,0 +1,55 @@
+package componentmgr
+
+import (
+	"testing"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHostFilesStatus(t *testing.T) {
+	image := "goodrain.me/rbd-node:v5.3.3"
+	files := []rainbondv1alpha1.HostFiles{{Name: "grctl", Source: "/run/bin/grctl", HostPath: "/usr/local/bin"}}
+	now := metav1.Now()
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+	pod := func(name, nodeName string, created metav1.Time, state corev1.ContainerState) corev1.Pod {
+		p := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created},
+			Spec: corev1.PodSpec{
+				NodeName:       nodeName,
+				InitContainers: []corev1.Container{{Name: "host-files-grctl", Image: image}},
+			},
+		}
+		if state != (corev1.ContainerState{}) {
+			p.Status.InitContainerStatuses = []corev1.ContainerStatus{{Name: "host-files-grctl", State: state}}
+		}
+		return p
+	}
+	completed := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}
+	failed := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}}
+	legacy := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "rbd-node-legacy"},
+		Spec:       corev1.PodSpec{NodeName: "node-4"},
+	}
+
+	assert.Nil(t, hostFilesStatus([]corev1.Pod{pod("rbd-node-a", "node-1", now, completed)}, nil))
+
+	got := hostFilesStatus([]corev1.Pod{
+		pod("rbd-node-b", "node-2", now, failed),
+		pod("rbd-node-a", "node-1", now, completed),
+		// the old pod being replaced by the rollout is ignored.
+		pod("rbd-node-old", "node-1", earlier, failed),
+		pod("rbd-node-c", "node-3", now, corev1.ContainerState{}),
+		pod("rbd-node-pending", "", now, corev1.ContainerState{}),
+		legacy,
+	}, files)
+	assert.Equal(t, []rainbondv1alpha1.NodeHostFiles{
+		{NodeName: "node-1", Image: image, Distributed: true},
+		{NodeName: "node-2", Image: image, Message: "host-files-grctl failed with exit code 1: Error"},
+		{NodeName: "node-3", Image: image, Message: "host-files-grctl has not started"},
+		{NodeName: "node-4", Message: "pod rbd-node-legacy doesn't copy the host files, it will be recreated"},
+	}, got)
+}
@@ -0,0 +1,150 @@
+package componentmgr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// imageFallbackCheckInterval is how often the image is checked whether it is available again.
+	imageFallbackCheckInterval = 5 * time.Minute
+	imageFallbackCheckTimeout  = 10 * time.Second
+)
+
+// ImageFallback returns the fallback image the pods of the rbdcomponent run instead of the image, nil if they
+// run the image. The image is run again once its manifest is available, which is checked every
+// imageFallbackCheckInterval, or the image or the fallback image in the spec is changed.
+func (r *RbdcomponentMgr) ImageFallback(cluster *rainbondv1alpha1.RainbondCluster) *rainbondv1alpha1.ImageFallback {
+	fallback := r.cpt.Status.ImageFallback
+	if fallback == nil {
+		return nil
+	}
+	if fallback.Image != r.cpt.Spec.Image || r.cpt.Spec.FallbackImage == "" {
+		r.cpt.Status.ImageFallback = nil
+		return nil
+	}
+	if time.Since(fallback.CheckTime.Time) < imageFallbackCheckInterval {
+		return fallback
+	}
+
+	fallback.CheckTime = metav1.Now()
+	checker, err := r.manifestChecker(cluster)
+	if err != nil {
+		r.log.Info("check the image to recover from the fallback", "image", fallback.Image, "error", err.Error())
+		return fallback
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, imageFallbackCheckTimeout)
+	defer cancel()
+	if exists, err := checker.Exists(ctx, fallback.Image); err != nil || !exists {
+		return fallback
+	}
+	r.log.Info("the image is available again", "image", fallback.Image)
+	r.recorder.Event(r.cpt, corev1.EventTypeNormal, rainbondv1alpha1.ReasonImageRecovered,
+		fmt.Sprintf("run the image %s instead of the fallback image %s", fallback.Image, fallback.FallbackImage))
+	r.cpt.Status.ImageFallback = nil
+	return nil
+}
+
+// FallBackImage makes the pods run the fallback image if they can't pull the image of the rbdcomponent,
+// it returns true if the pods are going to run the fallback image.
+func (r *RbdcomponentMgr) FallBackImage(pods []corev1.Pod) bool {
+	spec := r.cpt.Spec
+	if spec.FallbackImage == "" || r.cpt.Status.ImageFallback != nil {
+		return false
+	}
+	msg := imagePullFailure(pods, spec.Image)
+	if msg == "" {
+		return false
+	}
+	digest := imageDigest(pods, spec.Image)
+	image, err := fallbackImage(spec.FallbackImage, digest)
+	if err != nil {
+		r.log.Info("fall back to the fallback image", "image", spec.FallbackImage, "error", err.Error())
+		return false
+	}
+
+	now := metav1.Now()
+	r.cpt.Status.ImageFallback = &rainbondv1alpha1.ImageFallback{
+		Image:         spec.Image,
+		FallbackImage: image,
+		Digest:        digest,
+		Message:       msg,
+		FallbackTime:  now,
+		CheckTime:     now,
+	}
+	r.log.Info("the image can't be pulled, fall back to the fallback image", "image", spec.Image, "fallbackImage", image, "message", msg)
+	r.recorder.Event(r.cpt, corev1.EventTypeWarning, rainbondv1alpha1.ReasonImageFallback,
+		fmt.Sprintf("the image %s can't be pulled, run the fallback image %s instead: %s", spec.Image, image, msg))
+	return true
+}
+
+// imagePullFailure returns the error of pulling the image if a container of the pods is backing off pulling it,
+// which means the pull has failed at least once.
+func imagePullFailure(pods []corev1.Pod, image string) string {
+	for _, pod := range pods {
+		containers := make(map[string]string)
+		for _, c := range pod.Spec.InitContainers {
+			containers[c.Name] = c.Image
+		}
+		for _, c := range pod.Spec.Containers {
+			containers[c.Name] = c.Image
+		}
+		var statuses []corev1.ContainerStatus
+		statuses = append(statuses, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			waiting := status.State.Waiting
+			if containers[status.Name] != image || waiting == nil || waiting.Reason != "ImagePullBackOff" {
+				continue
+			}
+			if waiting.Message != "" {
+				return waiting.Message
+			}
+			return waiting.Reason
+		}
+	}
+	return ""
+}
+
+// imageDigest returns the digest of the image pulled by the pods, which is found in the image id reported by the
+// container runtime, such as docker-pullable://goodrain.me/rbd-api@sha256:xxx. It is empty if unknown.
+func imageDigest(pods []corev1.Pod, image string) string {
+	for _, pod := range pods {
+		containers := make(map[string]string)
+		for _, c := range pod.Spec.Containers {
+			containers[c.Name] = c.Image
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if containers[status.Name] != image {
+				continue
+			}
+			if idx := strings.LastIndex(status.ImageID, "@"); idx != -1 {
+				return status.ImageID[idx+1:]
+			}
+		}
+	}
+	return ""
+}
+
+// fallbackImage returns the fallback image pinned to the digest, or the fallback image if the digest is empty.
+func fallbackImage(image, digest string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("parse image %s: %v", image, err)
+	}
+	if digest == "" {
+		return image, nil
+	}
+	pinned := reference.FamiliarString(reference.TrimNamed(named)) + "@" + digest
+	if _, err := reference.ParseNormalizedNamed(pinned); err != nil {
+		return "", fmt.Errorf("pin image %s to %s: %v", image, digest, err)
+	}
+	return pinned, nil
+}
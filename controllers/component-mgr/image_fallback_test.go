@@ -0,0 +1,189 @@
+package componentmgr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const pulledDigest = "sha256:4d8c2ba3e0b2d1e4d7ac8f0f7a7e8d8b8e1c4c6a3b9f2d5e6a7b8c9d0e1f2a3b"
+
+func fallbackPod(image string, waiting *corev1.ContainerStateWaiting, imageID string) corev1.Pod {
+	return corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "wait", Image: "busybox"}},
+			Containers:     []corev1.Container{{Name: "rbd-api", Image: image}},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:    "rbd-api",
+				State:   corev1.ContainerState{Waiting: waiting},
+				ImageID: imageID,
+			}},
+		},
+	}
+}
+
+func TestImagePullFailure(t *testing.T) {
+	image := "goodrain.me/rbd-api:v5.3.3"
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{
+			name: "backing off",
+			pods: []corev1.Pod{fallbackPod(image, &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}, "")},
+			want: "Back-off pulling image",
+		},
+		{
+			name: "backing off without message",
+			pods: []corev1.Pod{fallbackPod(image, &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}, "")},
+			want: "ImagePullBackOff",
+		},
+		{
+			name: "pulling",
+			pods: []corev1.Pod{fallbackPod(image, &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}, "")},
+		},
+		{
+			name: "another image",
+			pods: []corev1.Pod{fallbackPod("goodrain.me/rbd-api:v5.3.2", &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}, "")},
+		},
+		{
+			name: "running",
+			pods: []corev1.Pod{fallbackPod(image, nil, "")},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, imagePullFailure(tc.pods, image))
+		})
+	}
+}
+
+func TestImageDigest(t *testing.T) {
+	image := "goodrain.me/rbd-api:v5.3.3"
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{
+			name: "docker",
+			pods: []corev1.Pod{fallbackPod(image, nil, "docker-pullable://goodrain.me/rbd-api@"+pulledDigest)},
+			want: pulledDigest,
+		},
+		{
+			name: "containerd",
+			pods: []corev1.Pod{fallbackPod(image, nil, "goodrain.me/rbd-api@"+pulledDigest)},
+			want: pulledDigest,
+		},
+		{
+			name: "never pulled",
+			pods: []corev1.Pod{fallbackPod(image, &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}, "")},
+		},
+		{
+			name: "another image",
+			pods: []corev1.Pod{fallbackPod("goodrain.me/rbd-api:v5.3.2", nil, "goodrain.me/rbd-api@"+pulledDigest)},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, imageDigest(tc.pods, image))
+		})
+	}
+}
+
+func TestFallbackImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		digest  string
+		want    string
+		wantErr bool
+	}{
+		{name: "unpinned", image: "registry.cn-hangzhou.aliyuncs.com/goodrain/rbd-api:v5.3.3", want: "registry.cn-hangzhou.aliyuncs.com/goodrain/rbd-api:v5.3.3"},
+		{name: "pinned", image: "registry.cn-hangzhou.aliyuncs.com/goodrain/rbd-api:v5.3.3", digest: pulledDigest, want: "registry.cn-hangzhou.aliyuncs.com/goodrain/rbd-api@" + pulledDigest},
+		{name: "docker hub", image: "goodrain/rbd-api:v5.3.3", digest: pulledDigest, want: "goodrain/rbd-api@" + pulledDigest},
+		{name: "invalid image", image: "Goodrain/rbd-api", wantErr: true},
+		{name: "invalid digest", image: "goodrain/rbd-api:v5.3.3", digest: "sha256:short", wantErr: true},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := fallbackImage(tc.image, tc.digest)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFallBackImage(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{Name: "rbd-api", Namespace: "rbd-system"},
+		Spec: rainbondv1alpha1.RbdComponentSpec{
+			Image:         "goodrain.me/rbd-api:v5.3.3",
+			FallbackImage: "goodrain/rbd-api:v5.3.3",
+		},
+	}
+	mgr := NewRbdcomponentMgr(context.Background(), nil, record.NewFakeRecorder(10), logr.Discard(), cpt)
+	pods := []corev1.Pod{
+		fallbackPod(cpt.Spec.Image, nil, "docker-pullable://goodrain.me/rbd-api@"+pulledDigest),
+		fallbackPod(cpt.Spec.Image, &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "not found"}, ""),
+	}
+
+	assert.True(t, mgr.FallBackImage(pods))
+	if fallback := cpt.Status.ImageFallback; assert.NotNil(t, fallback) {
+		assert.Equal(t, cpt.Spec.Image, fallback.Image)
+		assert.Equal(t, "goodrain/rbd-api@"+pulledDigest, fallback.FallbackImage)
+		assert.Equal(t, pulledDigest, fallback.Digest)
+		assert.Equal(t, "not found", fallback.Message)
+	}
+	// already falling back.
+	assert.False(t, mgr.FallBackImage(pods))
+}
+
+func TestImageFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		image         string
+		fallbackImage string
+		wantFallback  bool
+	}{
+		{name: "falling back", image: "goodrain.me/rbd-api:v5.3.3", fallbackImage: "goodrain/rbd-api:v5.3.3", wantFallback: true},
+		{name: "image changed", image: "goodrain.me/rbd-api:v5.3.4", fallbackImage: "goodrain/rbd-api:v5.3.3"},
+		{name: "fallback image removed", image: "goodrain.me/rbd-api:v5.3.3"},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			cpt := &rainbondv1alpha1.RbdComponent{
+				ObjectMeta: metav1.ObjectMeta{Name: "rbd-api", Namespace: "rbd-system"},
+				Spec:       rainbondv1alpha1.RbdComponentSpec{Image: tc.image, FallbackImage: tc.fallbackImage},
+				Status: rainbondv1alpha1.RbdComponentStatus{
+					ImageFallback: &rainbondv1alpha1.ImageFallback{
+						Image:         "goodrain.me/rbd-api:v5.3.3",
+						FallbackImage: "goodrain/rbd-api:v5.3.3",
+						FallbackTime:  metav1.NewTime(time.Now().Add(-time.Hour)),
+						// not checked again within the interval.
+						CheckTime: metav1.Now(),
+					},
+				},
+			}
+			mgr := NewRbdcomponentMgr(context.Background(), nil, record.NewFakeRecorder(10), logr.Discard(), cpt)
+
+			fallback := mgr.ImageFallback(&rainbondv1alpha1.RainbondCluster{})
+			assert.Equal(t, tc.wantFallback, fallback != nil)
+			assert.Equal(t, tc.wantFallback, cpt.Status.ImageFallback != nil)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package componentmgr
+
+import (
+	"fmt"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ExpandVolumes expands the claims created from the claim templates of the statefulset if the size of
+// the claim templates is increased, and reports the progress with the StorageResized condition.
+// It returns true if the claims are being expanded.
+func (r *RbdcomponentMgr) ExpandVolumes(sts *appsv1.StatefulSet) (bool, error) {
+	var replicas int32 = 1
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	var resizing []string
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		desired, ok := template.Spec.Resources.Requests[corev1.ResourceStorage]
+		if !ok {
+			continue
+		}
+		for i := int32(0); i < replicas; i++ {
+			name := fmt.Sprintf("%s-%s-%d", template.Name, sts.Name, i)
+			pvc := &corev1.PersistentVolumeClaim{}
+			if err := r.client.Get(r.ctx, types.NamespacedName{Namespace: sts.Namespace, Name: name}, pvc); err != nil {
+				if k8sErrors.IsNotFound(err) {
+					continue
+				}
+				return false, fmt.Errorf("get claim %s: %v", name, err)
+			}
+
+			requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			if desired.Cmp(requested) > 0 {
+				if err := r.allowVolumeExpansion(pvc); err != nil {
+					condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.StorageResized,
+						corev1.ConditionFalse, rainbondv1alpha1.ReasonExpansionNotAllowed, err.Error())
+					r.cpt.Status.UpdateCondition(condition)
+					return false, nil
+				}
+				r.log.Info("expand claim", "name", name, "from", requested.String(), "to", desired.String())
+				pvc.Spec.Resources.Requests[corev1.ResourceStorage] = desired
+				if err := r.client.Update(r.ctx, pvc); err != nil {
+					return false, fmt.Errorf("expand claim %s: %v", name, err)
+				}
+				requested = desired
+			}
+
+			capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+			if ok && capacity.Cmp(requested) < 0 {
+				resizing = append(resizing, name)
+			}
+		}
+	}
+
+	if len(resizing) > 0 {
+		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.StorageResized, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonStorageResizing, fmt.Sprintf("expanding claims: %s", strings.Join(resizing, ", ")))
+		r.cpt.Status.UpdateCondition(condition)
+		return true, nil
+	}
+	// only report the condition after expanding.
+	if _, condition := r.cpt.Status.GetCondition(rainbondv1alpha1.StorageResized); condition != nil {
+		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.StorageResized, corev1.ConditionTrue, rainbondv1alpha1.ReasonResized, "")
+		r.cpt.Status.UpdateCondition(condition)
+	}
+	return false, nil
+}
+
+func (r *RbdcomponentMgr) allowVolumeExpansion(pvc *corev1.PersistentVolumeClaim) error {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return fmt.Errorf("claim %s without storage class can not be expanded", pvc.Name)
+	}
+	sc := &storagev1.StorageClass{}
+	if err := r.client.Get(r.ctx, types.NamespacedName{Name: *pvc.Spec.StorageClassName}, sc); err != nil {
+		return fmt.Errorf("get storage class %s: %v", *pvc.Spec.StorageClassName, err)
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return fmt.Errorf("storage class %s does not allow volume expansion", sc.Name)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package componentmgr
+
+import (
+	"errors"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// secretDataMask replaces the values of the data of the secrets rendered for the what-if. The handlers generate
+// some of them randomly, such as the passwords, which would be reported as changed by every proposal otherwise.
+const secretDataMask = "******"
+
+// ResourceChange is how a resource of the rbdcomponent is changed by a proposed spec of the rainbondcluster.
+type ResourceChange struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Action DriftAction `json:"action"`
+	// Restart is true if the pods of the workload are replaced by the change.
+	Restart bool `json:"restart,omitempty"`
+	// Fields are the changed fields, Live is the value rendered with the current spec, and Desired is the value
+	// rendered with the proposed one.
+	Fields []FieldDrift `json:"fields,omitempty"`
+}
+
+// WhatIf renders the resources of the rbdcomponent with the current and the proposed rainbondcluster, and returns
+// the resources the proposal changes. The resources are compared as rendered rather than with the live ones, so
+// that the edits made to the live resources are not mistaken for the changes of the proposal, see Drift for them.
+// The handlers are created with each of the rainbondclusters, a dry-run client is recommended for them.
+// The rbdcomponents skipped by the handlers, such as rbd-hub with an external image hub, have no resources.
+func (r *RbdcomponentMgr) WhatIf(current, proposed handler.ComponentHandler, cluster, proposedCluster *rainbondv1alpha1.RainbondCluster) ([]ResourceChange, error) {
+	currentResources, err := r.renderResourcesIfNotIgnored(current, cluster)
+	if err != nil {
+		return nil, err
+	}
+	proposedResources, err := r.renderResourcesIfNotIgnored(proposed, proposedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make(map[string]client.Object, len(currentResources))
+	for _, res := range currentResources {
+		rendered[kindOf(res)+"/"+res.GetName()] = res
+	}
+	var changes []ResourceChange
+	for _, res := range proposedResources {
+		key := kindOf(res) + "/" + res.GetName()
+		old, ok := rendered[key]
+		delete(rendered, key)
+		if !ok {
+			changes = append(changes, ResourceChange{Kind: kindOf(res), Name: res.GetName(), Action: DriftActionCreate})
+			continue
+		}
+		fields, err := changedFields(old, res)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		changes = append(changes, ResourceChange{
+			Kind:    kindOf(res),
+			Name:    res.GetName(),
+			Action:  DriftActionUpdate,
+			Restart: restartedBy(res, fields),
+			Fields:  fields,
+		})
+	}
+	for _, res := range currentResources {
+		if _, ok := rendered[kindOf(res)+"/"+res.GetName()]; ok {
+			changes = append(changes, ResourceChange{Kind: kindOf(res), Name: res.GetName(), Action: DriftActionDelete})
+		}
+	}
+	return changes, nil
+}
+
+func (r *RbdcomponentMgr) renderResourcesIfNotIgnored(hdl handler.ComponentHandler, cluster *rainbondv1alpha1.RainbondCluster) ([]client.Object, error) {
+	resources, err := r.renderResources(hdl, cluster)
+	var ignore *handler.IgnoreError
+	if errors.As(err, &ignore) {
+		return nil, nil
+	}
+	return resources, err
+}
+
+// changedFields returns the fields that differ between the resources rendered with the current and the proposed
+// spec, including the ones removed by the proposal.
+func changedFields(current, proposed client.Object) ([]FieldDrift, error) {
+	c, err := comparableFields(current)
+	if err != nil {
+		return nil, err
+	}
+	p, err := comparableFields(proposed)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := proposed.(*corev1.Secret); ok {
+		maskSecretData(c)
+		maskSecretData(p)
+	}
+
+	var fields []FieldDrift
+	diffFields("", p, c, &fields)
+	changed := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		changed[field.Path] = true
+	}
+	var reversed []FieldDrift
+	diffFields("", c, p, &reversed)
+	for _, field := range reversed {
+		if !changed[field.Path] {
+			fields = append(fields, FieldDrift{Path: field.Path, Live: field.Desired})
+		}
+	}
+	return fields, nil
+}
+
+// maskSecretData replaces the values of the data of the secret with the mask, so that only the keys added or removed
+// by the proposal are changed.
+func maskSecretData(fields map[string]interface{}) {
+	for _, key := range []string{"data", "stringData"} {
+		data, _ := fields[key].(map[string]interface{})
+		for k := range data {
+			data[k] = secretDataMask
+		}
+	}
+}
+
+// restartedBy checks if the changed fields are in the pod template of the workload, which replaces the pods.
+func restartedBy(obj client.Object, fields []FieldDrift) bool {
+	switch obj.(type) {
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.DaemonSet:
+	default:
+		return false
+	}
+	for _, field := range fields {
+		if field.Path == "spec.template" || strings.HasPrefix(field.Path, "spec.template.") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package componentmgr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// whatIfHandler renders a deployment and a service, and a configmap of the gateway VIP if it is set.
+type whatIfHandler struct {
+	cluster *rainbondv1alpha1.RainbondCluster
+}
+
+func (h *whatIfHandler) Before() error { return nil }
+
+func (h *whatIfHandler) Resources() []client.Object {
+	meta := metav1.ObjectMeta{Name: "rbd-api", Namespace: "rbd-system"}
+	resources := []client.Object{
+		&appsv1.Deployment{
+			ObjectMeta: meta,
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "rbd-api", Image: "rbd-api:v5.3.3"}}},
+				},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: meta,
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 8888}}},
+		},
+	}
+	if h.cluster.Spec.GatewayVIP != "" {
+		resources = append(resources, &corev1.ConfigMap{
+			ObjectMeta: meta,
+			Data:       map[string]string{"vip": h.cluster.Spec.GatewayVIP},
+		})
+	}
+	return resources
+}
+
+func (h *whatIfHandler) After() error { return nil }
+
+func (h *whatIfHandler) ListPods() ([]corev1.Pod, error) { return nil, nil }
+
+func TestWhatIf(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cli := fake.NewFakeClientWithScheme(scheme)
+	mgr := NewRbdcomponentMgr(context.Background(), cli, nil, logr.Discard(), &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{Name: "rbd-api", Namespace: "rbd-system"},
+	})
+
+	cluster := &rainbondv1alpha1.RainbondCluster{Spec: rainbondv1alpha1.RainbondClusterSpec{GatewayVIP: "192.168.0.10"}}
+	changes, err := mgr.WhatIf(&whatIfHandler{cluster: cluster}, &whatIfHandler{cluster: cluster}, cluster, cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, changes)
+
+	proposed := cluster.DeepCopy()
+	proposed.Spec.GatewayVIP = ""
+	proposed.Spec.Timezone = "Asia/Shanghai"
+	changes, err = mgr.WhatIf(&whatIfHandler{cluster: cluster}, &whatIfHandler{cluster: proposed}, cluster, proposed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "Deployment", changes[0].Kind)
+		assert.Equal(t, DriftActionUpdate, changes[0].Action)
+		assert.True(t, changes[0].Restart)
+		assert.Equal(t, ResourceChange{Kind: "ConfigMap", Name: "rbd-api", Action: DriftActionDelete}, changes[1])
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	current := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "rbd-api", Annotations: map[string]string{"a": "1"}},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 8888}}},
+	}
+	proposed := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "rbd-api", Labels: map[string]string{"b": "2"}},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 8080}}},
+	}
+	fields, err := changedFields(current, proposed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []FieldDrift{
+		{Path: "metadata.labels.b", Desired: "2"},
+		{Path: "spec.ports[0].port", Live: int64(8888), Desired: int64(8080)},
+		{Path: "metadata.annotations.a", Live: "1"},
+	}, fields)
+}
+
+func TestChangedFieldsOfSecret(t *testing.T) {
+	secret := func(data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "rbd-db"}, Data: map[string][]byte{}}
+		for key, value := range data {
+			s.Data[key] = []byte(value)
+		}
+		return s
+	}
+	tests := []struct {
+		name     string
+		current  *corev1.Secret
+		proposed *corev1.Secret
+		want     []FieldDrift
+	}{
+		{
+			name:     "random values",
+			current:  secret(map[string]string{"password": "Ahk2Eiqu"}),
+			proposed: secret(map[string]string{"password": "Xe9oothi"}),
+		},
+		{
+			name:     "key added",
+			current:  secret(map[string]string{"password": "Ahk2Eiqu"}),
+			proposed: secret(map[string]string{"password": "Xe9oothi", "username": "admin"}),
+			want:     []FieldDrift{{Path: "data.username", Desired: secretDataMask}},
+		},
+		{
+			name:     "key removed",
+			current:  secret(map[string]string{"password": "Ahk2Eiqu", "username": "admin"}),
+			proposed: secret(map[string]string{"password": "Ahk2Eiqu"}),
+			want:     []FieldDrift{{Path: "data.username", Live: secretDataMask}},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			fields, err := changedFields(tc.current, tc.proposed)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, fields)
+		})
+	}
+}
+
+func TestRestartedBy(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    client.Object
+		fields []FieldDrift
+		want   bool
+	}{
+		{
+			name:   "pod template",
+			obj:    &appsv1.DaemonSet{},
+			fields: []FieldDrift{{Path: "spec.template.spec.nodeSelector"}},
+			want:   true,
+		},
+		{
+			name:   "labels of the workload",
+			obj:    &appsv1.StatefulSet{},
+			fields: []FieldDrift{{Path: "metadata.labels"}},
+		},
+		{
+			name:   "not a workload",
+			obj:    &corev1.Service{},
+			fields: []FieldDrift{{Path: "spec.template"}},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, restartedBy(tc.obj, tc.fields))
+		})
+	}
+}
@@ -3,17 +3,20 @@ package handler
 import (
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
-	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -26,6 +29,10 @@ var APIName = "rbd-api"
 var apiServerSecretName = "rbd-api-server-cert"
 var apiCASecretName = "rbd-api-ca-cert"
 var apiClientSecretName = "rbd-api-client-cert"
+var apiWebsocketSecretName = "rbd-api-websocket-cert"
+
+// apiWebsocketSSLPath is the path where the certificate of the websocket of rbd-api is mounted.
+const apiWebsocketSSLPath = "/etc/goodrain/websocket/ssl"
 
 type api struct {
 	ctx                      context.Context
@@ -33,6 +40,7 @@ type api struct {
 	db                       *rainbondv1alpha1.Database
 	labels                   map[string]string
 	etcdSecret, serverSecret *corev1.Secret
+	websocketSecret          *corev1.Secret
 	component                *rainbondv1alpha1.RbdComponent
 	cluster                  *rainbondv1alpha1.RainbondCluster
 
@@ -44,6 +52,7 @@ type api struct {
 
 var _ ComponentHandler = &api{}
 var _ StorageClassRWXer = &api{}
+var _ HealthChecker = &api{}
 
 //NewAPI new api handle
 func NewAPI(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -75,6 +84,14 @@ func (a *api) Before() error {
 	}
 	a.etcdSecret = secret
 
+	if websocketTLS := a.cluster.Spec.WebsocketTLS; websocketTLS != nil && websocketTLS.SecretName != "" {
+		secret, err := a.getSecret(websocketTLS.SecretName)
+		if err != nil {
+			return fmt.Errorf("get websocket certificate %s: %v", websocketTLS.SecretName, err)
+		}
+		a.websocketSecret = secret
+	}
+
 	if err := setStorageCassName(a.ctx, a.client, a.component.Namespace, a); err != nil {
 		return err
 	}
@@ -84,6 +101,7 @@ func (a *api) Before() error {
 
 func (a *api) Resources() []client.Object {
 	resources := a.secretAndConfigMapForAPI()
+	resources = append(resources, a.websocketSecretForAPI())
 	resources = append(resources, a.deployment())
 	resources = append(resources, a.createService()...)
 	resources = append(resources, a.ingressForAPI())
@@ -100,6 +118,15 @@ func (a *api) ListPods() ([]corev1.Pod, error) {
 	return listPods(a.ctx, a.client, a.component.Namespace, a.labels)
 }
 
+func (a *api) Dependencies() []Dependency {
+	return []Dependency{dbDependency(a.db), etcdDependency(a.cluster)}
+}
+
+// HealthEndpoint returns the health endpoint of rbd-api, of which the readiness probe only checks the status code.
+func (a *api) HealthEndpoint() HealthEndpoint {
+	return HealthEndpoint{Port: 8888, Path: "/v2/health"}
+}
+
 func (a *api) SetStorageClassNameRWX(pvcParameters *pvcParameters) {
 	a.pvcParametersRWX = pvcParameters
 }
@@ -107,31 +134,22 @@ func (a *api) SetStorageClassNameRWX(pvcParameters *pvcParameters) {
 func (a *api) ResourcesCreateIfNotExists() []client.Object {
 	return []client.Object{
 		// pvc is immutable after creation except resources.requests for bound claims
-		createPersistentVolumeClaimRWX(a.component.Namespace, constants.GrDataPVC, a.pvcParametersRWX, a.labels),
+		grdataPVC(a.cluster, a.component.Namespace, a.pvcParametersRWX, a.labels),
 		createPersistentVolumeClaimRWX(a.component.Namespace, a.pvcName, a.pvcParametersRWX, a.labels),
 	}
 }
 
 func (a *api) deployment() client.Object {
+	grdataVolume, grdataMounts := volumeByGrdata(a.cluster, a.component)
 	volumeMounts := []corev1.VolumeMount{
-		{
-			Name:      "grdata",
-			MountPath: "/grdata",
-		},
 		{
 			Name:      "accesslog",
 			MountPath: "/logs",
 		},
 	}
+	volumeMounts = append(volumeMounts, grdataMounts...)
 	volumes := []corev1.Volume{
-		{
-			Name: "grdata",
-			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: constants.GrDataPVC,
-				},
-			},
-		},
+		grdataVolume,
 		{
 			Name: "accesslog",
 			VolumeSource: corev1.VolumeSource{
@@ -165,6 +183,25 @@ func (a *api) deployment() client.Object {
 			"--client-ca-file=/etc/goodrain/region.goodrain.me/ssl/ca.pem",
 		)
 	}
+	if a.websocketSecret != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "websocket-ssl",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: a.websocketSecret.Name,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "websocket-ssl",
+			MountPath: apiWebsocketSSLPath,
+			ReadOnly:  true,
+		})
+		args = append(args, "--ws-ssl-enable=true",
+			"--ws-ssl-certfile="+path.Join(apiWebsocketSSLPath, corev1.TLSCertKey),
+			"--ws-ssl-keyfile="+path.Join(apiWebsocketSSLPath, corev1.TLSPrivateKeyKey),
+		)
+	}
 	a.labels["name"] = APIName
 	envs := []corev1.EnvVar{
 		{
@@ -181,52 +218,22 @@ func (a *api) deployment() client.Object {
 		},
 	}
 
-	args = mergeArgs(args, a.component.Spec.Args)
-	envs = mergeEnvs(envs, a.component.Spec.Env)
-	volumeMounts = mergeVolumeMounts(volumeMounts, a.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, a.component.Spec.Volumes)
-
-	// prepare probe
-	readinessProbe := probeutil.MakeReadinessProbeHTTP("", "/v2/health", 8888)
-	ds := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      APIName,
-			Namespace: a.component.Namespace,
-			Labels:    a.labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: a.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: a.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   APIName,
-					Labels: a.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(a.component, a.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Containers: []corev1.Container{
-						{
-							Name:            APIName,
-							Image:           a.component.Spec.Image,
-							ImagePullPolicy: a.component.ImagePullPolicy(),
-							Env:             envs,
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							ReadinessProbe:  readinessProbe,
-							Resources:       a.component.Spec.Resources,
-						},
-					},
-					ServiceAccountName: "rainbond-operator",
-					Volumes:            volumes,
-				},
-			},
-		},
+	if a.cluster.Spec.LicenseSecretName != "" {
+		volume, mount, env := volumeByLicense(a.cluster.Spec.LicenseSecretName)
+		volumeMounts = append(volumeMounts, mount)
+		volumes = append(volumes, volume)
+		envs = append(envs, env)
 	}
 
-	return ds
+	return buildDeployment(APIName, a.component, a.cluster, a.labels, appsv1.RollingUpdateDeploymentStrategyType,
+		withArgs(args...),
+		withEnv(envs...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(probeutil.MakeLivenessProbeHTTP("", "/v2/health", 8888), probeutil.MakeReadinessProbeHTTP("", "/v2/health", 8888)),
+		withLifecycle(preStopSleep(defPreStopSleepSeconds)),
+		withTerminationGracePeriod(defTrafficGracePeriodSeconds),
+		withServiceAccount(rbdutil.ServiceAccountName()),
+	)
 }
 
 func (a *api) createService() []client.Object {
@@ -336,7 +343,8 @@ func (a *api) secretAndConfigMapForAPI() []client.Object {
 		a.serverSecret = serverSecret
 		//no change,do nothing
 		if availableips, ok := serverSecret.Labels["availableips"]; ok && availableips == ips {
-			return nil
+			// keep the certificates, but the scheme of the websocket may be changed.
+			return a.regionConfigForWebsocket()
 		}
 		caSecret, _ := a.getSecret(apiCASecretName)
 		if caSecret != nil {
@@ -407,7 +415,7 @@ func (a *api) secretAndConfigMapForAPI() []client.Object {
 		},
 		Data: map[string]string{
 			"apiAddress":          fmt.Sprintf("https://%s:%d", a.cluster.GatewayIngressIP(), 8443),
-			"websocketAddress":    fmt.Sprintf("ws://%s:%d", a.cluster.GatewayIngressIP(), 6060),
+			"websocketAddress":    a.websocketAddress(),
 			"defaultDomainSuffix": a.cluster.Spec.SuffixHTTPHost,
 			"defaultTCPHost":      a.cluster.GatewayIngressIP(),
 		},
@@ -420,6 +428,75 @@ func (a *api) secretAndConfigMapForAPI() []client.Object {
 	return re
 }
 
+func (a *api) websocketAddress() string {
+	scheme := "ws"
+	if a.cluster.Spec.WebsocketTLS != nil {
+		scheme = "wss"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, a.cluster.GatewayIngressIP(), 6060)
+}
+
+// regionConfigForWebsocket returns the existing region config with the updated websocket address,
+// or nothing if it is not changed.
+func (a *api) regionConfigForWebsocket() []client.Object {
+	cm := &corev1.ConfigMap{}
+	if err := a.client.Get(a.ctx, types.NamespacedName{Namespace: a.component.Namespace, Name: "region-config"}, cm); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			log.Error(err, "get region config")
+		}
+		return nil
+	}
+	if cm.Data["websocketAddress"] == a.websocketAddress() {
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data["websocketAddress"] = a.websocketAddress()
+	return []client.Object{cm}
+}
+
+// websocketSecretForAPI returns the self-signed certificate of the websocket of rbd-api if it is not
+// provided, or nothing if it has been generated for the gateway ingress IPs.
+func (a *api) websocketSecretForAPI() client.Object {
+	if a.cluster.Spec.WebsocketTLS == nil || a.cluster.Spec.WebsocketTLS.SecretName != "" {
+		return nil
+	}
+	var ips = strings.ReplaceAll(strings.Join(a.cluster.GatewayIngressIPs(), "-"), ".", "_")
+	secret, _ := a.getSecret(apiWebsocketSecretName)
+	if secret != nil && secret.Labels["availableips"] == ips {
+		a.websocketSecret = secret
+		return nil
+	}
+
+	ca, err := commonutil.CreateCA()
+	if err != nil {
+		log.Error(err, "create ca for the websocket of api")
+		return nil
+	}
+	certPem, keyPem, err := ca.CreateCert(a.cluster.GatewayIngressIPs(), APIName+"-websocket")
+	if err != nil {
+		log.Error(err, "create cert for the websocket of api")
+		return nil
+	}
+	labels := copyLabels(a.labels)
+	labels["availableips"] = ips
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      apiWebsocketSecretName,
+			Namespace: a.component.Namespace,
+			Labels:    labels,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPem,
+			corev1.TLSPrivateKeyKey: keyPem,
+		},
+	}
+	a.websocketSecret = secret
+	return secret
+}
+
 func (a *api) ingressForAPI() client.Object {
 	ing := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
@@ -447,6 +524,8 @@ func (a *api) ingressForAPI() client.Object {
 	return ing
 }
 
+// ingressForWebsocket exposes the websocket through the L4 listener of the gateway, which passes the TLS
+// connections through to rbd-api, so the terminal sessions are encrypted end to end if websocketTLS is set.
 func (a *api) ingressForWebsocket() client.Object {
 	ing := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
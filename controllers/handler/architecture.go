@@ -0,0 +1,61 @@
+package handler
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetArchitectures requires the pods of the given object, which is a deployment, statefulset or daemonset
+// running the image of the component, to be scheduled on the nodes of the architectures. The requirement
+// is added to each term of the required node affinity, as the terms are ORed. Other objects are left unchanged.
+func SetArchitectures(obj client.Object, architectures []string) {
+	if len(architectures) == 0 {
+		return
+	}
+	var template *corev1.PodTemplateSpec
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		template = &o.Spec.Template
+	case *appsv1.StatefulSet:
+		template = &o.Spec.Template
+	case *appsv1.DaemonSet:
+		template = &o.Spec.Template
+	default:
+		return
+	}
+
+	requirement := corev1.NodeSelectorRequirement{
+		Key:      corev1.LabelArchStable,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   architectures,
+	}
+	spec := &template.Spec
+	if spec.Affinity == nil {
+		spec.Affinity = &corev1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity == nil {
+		spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	selector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range selector.NodeSelectorTerms {
+		term := &selector.NodeSelectorTerms[i]
+		var exists bool
+		for j := range term.MatchExpressions {
+			if term.MatchExpressions[j].Key == corev1.LabelArchStable {
+				term.MatchExpressions[j] = requirement
+				exists = true
+			}
+		}
+		if !exists {
+			term.MatchExpressions = append(term.MatchExpressions, requirement)
+		}
+	}
+}
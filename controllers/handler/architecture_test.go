@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetArchitectures(t *testing.T) {
+	deploy := &appsv1.Deployment{}
+	SetArchitectures(deploy, []string{"amd64"})
+	terms := deploy.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if assert.Len(t, terms, 1) && assert.Len(t, terms[0].MatchExpressions, 1) {
+		assert.Equal(t, corev1.LabelArchStable, terms[0].MatchExpressions[0].Key)
+		assert.Equal(t, []string{"amd64"}, terms[0].MatchExpressions[0].Values)
+	}
+
+	// the requirement is added to the terms of the nodes of the daemonset.
+	ds := &appsv1.DaemonSet{}
+	ds.Spec.Template.Spec.Affinity = affinityForRequiredNodes([]string{"node1", "node2"})
+	SetArchitectures(ds, []string{"arm64"})
+	terms = ds.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if assert.Len(t, terms, 1) && assert.Len(t, terms[0].MatchExpressions, 2) {
+		assert.Equal(t, "kubernetes.io/hostname", terms[0].MatchExpressions[0].Key)
+		assert.Equal(t, []string{"arm64"}, terms[0].MatchExpressions[1].Values)
+	}
+
+	// replaced instead of added twice.
+	SetArchitectures(ds, []string{"amd64", "arm64"})
+	assert.Len(t, terms[0].MatchExpressions, 2)
+	assert.Equal(t, []string{"amd64", "arm64"}, terms[0].MatchExpressions[1].Values)
+
+	job := &batchv1.Job{}
+	SetArchitectures(job, []string{"amd64"})
+	assert.Nil(t, job.Spec.Template.Spec.Affinity)
+
+	sts := &appsv1.StatefulSet{}
+	SetArchitectures(sts, nil)
+	assert.Nil(t, sts.Spec.Template.Spec.Affinity)
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podOption customizes the pod template built for the rbdcomponent.
+type podOption func(b *podBuilder)
+
+// podBuilder builds the pod template of the rbdcomponent with a single container. The settings shared by
+// all the components, such as the image, the resources, the lifecycle and the args, envs and volumes
+// in the spec of the rbdcomponent, are applied in one place, the handlers only provide their defaults.
+type podBuilder struct {
+	name    string
+	cpt     *rainbondv1alpha1.RbdComponent
+	cluster *rainbondv1alpha1.RainbondCluster
+	labels  map[string]string
+
+	annotations     map[string]string
+	command         []string
+	args            []string
+	env             []corev1.EnvVar
+	ports           []corev1.ContainerPort
+	volumes         []corev1.Volume
+	volumeMounts    []corev1.VolumeMount
+	resources       corev1.ResourceRequirements
+	livenessProbe   *corev1.Probe
+	readinessProbe  *corev1.Probe
+	startupProbe    *corev1.Probe
+	lifecycle       *corev1.Lifecycle
+	securityContext *corev1.SecurityContext
+	gracePeriod     int64
+	affinity        *corev1.Affinity
+	affinitySet     bool
+	podSpec         func(spec *corev1.PodSpec)
+}
+
+// withArgs adds the default args of the container, the ones in the spec of the rbdcomponent take precedence.
+func withArgs(args ...string) podOption {
+	return func(b *podBuilder) {
+		b.args = append(b.args, args...)
+	}
+}
+
+// withCommand sets the command of the container.
+func withCommand(command ...string) podOption {
+	return func(b *podBuilder) {
+		b.command = command
+	}
+}
+
+// withEnv adds the default envs of the container, the ones in the spec of the rbdcomponent take precedence.
+func withEnv(env ...corev1.EnvVar) podOption {
+	return func(b *podBuilder) {
+		b.env = append(b.env, env...)
+	}
+}
+
+// withVolumes adds the default volumes and volume mounts, the ones in the spec of the rbdcomponent take precedence.
+func withVolumes(volumes []corev1.Volume, mounts []corev1.VolumeMount) podOption {
+	return func(b *podBuilder) {
+		b.volumes = append(b.volumes, volumes...)
+		b.volumeMounts = append(b.volumeMounts, mounts...)
+	}
+}
+
+// withPorts adds the ports of the container.
+func withPorts(ports ...corev1.ContainerPort) podOption {
+	return func(b *podBuilder) {
+		b.ports = append(b.ports, ports...)
+	}
+}
+
+// withProbes sets the probes of the container. They are not overridden here, but tuned by the probes in the spec
+// of the rbdcomponent once the resources are built, see SetProbes.
+func withProbes(liveness, readiness *corev1.Probe) podOption {
+	return func(b *podBuilder) {
+		b.livenessProbe, b.readinessProbe = liveness, readiness
+	}
+}
+
+// withStartupProbe sets the startup probe of the container, for the components that take a long time to start.
+func withStartupProbe(startup *corev1.Probe) podOption {
+	return func(b *podBuilder) {
+		b.startupProbe = startup
+	}
+}
+
+// withResources sets the default resources of the container, the ones in the spec of the rbdcomponent take precedence.
+func withResources(resources corev1.ResourceRequirements) podOption {
+	return func(b *podBuilder) {
+		b.resources = resources
+	}
+}
+
+// withLifecycle sets the default lifecycle hooks of the container.
+func withLifecycle(lifecycle *corev1.Lifecycle) podOption {
+	return func(b *podBuilder) {
+		b.lifecycle = lifecycle
+	}
+}
+
+// withSecurityContext sets the default security context of the container.
+func withSecurityContext(securityContext *corev1.SecurityContext) podOption {
+	return func(b *podBuilder) {
+		b.securityContext = securityContext
+	}
+}
+
+// withTerminationGracePeriod sets the default termination grace period of the pods.
+func withTerminationGracePeriod(seconds int64) podOption {
+	return func(b *podBuilder) {
+		b.gracePeriod = seconds
+	}
+}
+
+// withAffinity replaces the affinity of the pods, which spreads the pods of the deployments by default.
+func withAffinity(affinity *corev1.Affinity) podOption {
+	return func(b *podBuilder) {
+		b.affinity, b.affinitySet = affinity, true
+	}
+}
+
+// withPodAnnotations adds the annotations of the pods.
+func withPodAnnotations(annotations map[string]string) podOption {
+	return func(b *podBuilder) {
+		if len(annotations) == 0 {
+			return
+		}
+		if b.annotations == nil {
+			b.annotations = make(map[string]string)
+		}
+		for k, v := range annotations {
+			b.annotations[k] = v
+		}
+	}
+}
+
+// withServiceAccount sets the service account of the pods.
+func withServiceAccount(name string) podOption {
+	return withPodSpec(func(spec *corev1.PodSpec) {
+		spec.ServiceAccountName = name
+	})
+}
+
+// withPodSpec customizes the other fields of the pod spec, it is applied after the shared settings.
+func withPodSpec(fn func(spec *corev1.PodSpec)) podOption {
+	return func(b *podBuilder) {
+		previous := b.podSpec
+		b.podSpec = func(spec *corev1.PodSpec) {
+			if previous != nil {
+				previous(spec)
+			}
+			fn(spec)
+		}
+	}
+}
+
+func newPodBuilder(name string, cpt *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster, labels map[string]string, opts ...podOption) *podBuilder {
+	b := &podBuilder{
+		name:    name,
+		cpt:     cpt,
+		cluster: cluster,
+		labels:  labels,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *podBuilder) build() corev1.PodTemplateSpec {
+	spec := corev1.PodSpec{
+		TerminationGracePeriodSeconds: terminationGracePeriodSeconds(b.cpt, b.gracePeriod),
+		ImagePullSecrets:              imagePullSecrets(b.cpt, b.cluster),
+		Affinity:                      b.affinity,
+		Containers: []corev1.Container{
+			{
+				Name:            b.name,
+				Image:           b.cpt.Spec.Image,
+				ImagePullPolicy: b.cpt.ImagePullPolicy(),
+				Command:         b.command,
+				Args:            mergeArgs(b.args, b.cpt.Spec.Args),
+				Env:             mergeEnvs(b.env, b.cpt.Spec.Env),
+				Ports:           b.ports,
+				VolumeMounts:    mergeVolumeMounts(b.volumeMounts, b.cpt.Spec.VolumeMounts),
+				Resources:       mergeResources(b.resources, b.cpt.Spec.Resources),
+				LivenessProbe:   b.livenessProbe,
+				ReadinessProbe:  b.readinessProbe,
+				StartupProbe:    b.startupProbe,
+				Lifecycle:       lifecycle(b.cpt, b.lifecycle),
+				SecurityContext: securityContext(b.cpt, b.securityContext),
+			},
+		},
+		Volumes: mergeVolumes(b.volumes, b.cpt.Spec.Volumes),
+	}
+	if b.podSpec != nil {
+		b.podSpec(&spec)
+	}
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        b.name,
+			Labels:      b.labels,
+			Annotations: b.annotations,
+		},
+		Spec: spec,
+	}
+}
+
+// buildDeployment builds the deployment of the rbdcomponent, whose pods are spread over the nodes if HA is enabled.
+func buildDeployment(name string, cpt *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster, labels map[string]string, defStrategy appsv1.DeploymentStrategyType, opts ...podOption) *appsv1.Deployment {
+	b := newPodBuilder(name, cpt, cluster, labels, opts...)
+	if !b.affinitySet {
+		b.affinity = affinityForSpreadingPods(labels, cluster.Spec.EnableHA)
+	}
+	strategy := deploymentStrategy(cpt, defStrategy)
+	if requiredAntiAffinity(b.affinity) {
+		strategy = strategyWithoutSurge(strategy)
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cpt.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: cpt.Spec.Replicas,
+			Strategy: strategy,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: b.build(),
+		},
+	}
+}
+
+// buildStatefulSet builds the statefulset of the rbdcomponent, each replica of which keeps its own volumes claimed by
+// the claim templates. Unlike the deployments, the pods are not spread over the nodes unless the affinity is set.
+func buildStatefulSet(name string, cpt *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster, labels map[string]string, serviceName string, claimTemplates []corev1.PersistentVolumeClaim, opts ...podOption) *appsv1.StatefulSet {
+	b := newPodBuilder(name, cpt, cluster, labels, opts...)
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cpt.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    cpt.Spec.Replicas,
+			ServiceName: serviceName,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template:             b.build(),
+			VolumeClaimTemplates: claimTemplates,
+		},
+	}
+}
+
+// buildDaemonSet builds the daemonset of the rbdcomponent.
+func buildDaemonSet(name string, cpt *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster, labels map[string]string, opts ...podOption) *appsv1.DaemonSet {
+	b := newPodBuilder(name, cpt, cluster, labels, opts...)
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cpt.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			UpdateStrategy: daemonSetUpdateStrategy(cpt),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: b.build(),
+		},
+	}
+}
+
+// buildService builds the service selecting the pods with the labels.
+func buildService(name, namespace string, labels map[string]string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports:    ports,
+			Selector: labels,
+		},
+	}
+}
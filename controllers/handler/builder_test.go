@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestBuildDeployment(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system", Name: "rbd-foo"},
+		Spec: rainbondv1alpha1.RbdComponentSpec{
+			Image: "rbd-foo:v1",
+			Args:  []string{"--log-level=debug"},
+			Env:   []corev1.EnvVar{{Name: "FOO", Value: "spec"}},
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+		},
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{}
+	labels := map[string]string{"name": "rbd-foo"}
+	requests := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}
+
+	deploy := buildDeployment("rbd-foo", cpt, cluster, labels, appsv1.RecreateDeploymentStrategyType,
+		withArgs("--log-level=info", "--port=8080"),
+		withEnv(corev1.EnvVar{Name: "FOO", Value: "default"}, corev1.EnvVar{Name: "BAR", Value: "default"}),
+		withResources(corev1.ResourceRequirements{Requests: requests}),
+		withServiceAccount("rainbond-operator"),
+	)
+	assert.Equal(t, "rbd-system", deploy.Namespace)
+	assert.Equal(t, appsv1.RecreateDeploymentStrategyType, deploy.Spec.Strategy.Type)
+	assert.Equal(t, labels, deploy.Spec.Selector.MatchLabels)
+	assert.Equal(t, "rainbond-operator", deploy.Spec.Template.Spec.ServiceAccountName)
+
+	container := deploy.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "rbd-foo:v1", container.Image)
+	assert.Equal(t, []string{"--log-level=debug", "--port=8080"}, container.Args)
+	assert.Equal(t, []corev1.EnvVar{{Name: "FOO", Value: "spec"}, {Name: "BAR", Value: "default"}}, container.Env)
+	assert.Equal(t, requests, container.Resources.Requests)
+	assert.Equal(t, cpt.Spec.Resources.Limits, container.Resources.Limits)
+}
+
+func TestBuildDeploymentStrategy(t *testing.T) {
+	maxUnavailable := intstr.FromString("50%")
+	tests := []struct {
+		name           string
+		enableHA       bool
+		updateStrategy *rainbondv1alpha1.UpdateStrategy
+		want           appsv1.DeploymentStrategy
+	}{
+		{
+			name: "preferred anti-affinity",
+			want: appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType},
+		},
+		{
+			name:     "required anti-affinity",
+			enableHA: true,
+			want: appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxSurge:       intOrStringPtr(intstr.FromInt(0)),
+					MaxUnavailable: intOrStringPtr(intstr.FromInt(1)),
+				},
+			},
+		},
+		{
+			name:           "specified rolling update",
+			enableHA:       true,
+			updateStrategy: &rainbondv1alpha1.UpdateStrategy{MaxUnavailable: &maxUnavailable},
+			want: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable},
+			},
+		},
+		{
+			name:           "recreate",
+			enableHA:       true,
+			updateStrategy: &rainbondv1alpha1.UpdateStrategy{Type: rainbondv1alpha1.UpdateStrategyRecreate},
+			want:           appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			cpt := &rainbondv1alpha1.RbdComponent{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system", Name: "rbd-foo"},
+				Spec:       rainbondv1alpha1.RbdComponentSpec{UpdateStrategy: tc.updateStrategy},
+			}
+			cluster := &rainbondv1alpha1.RainbondCluster{Spec: rainbondv1alpha1.RainbondClusterSpec{EnableHA: tc.enableHA}}
+			deploy := buildDeployment("rbd-foo", cpt, cluster, map[string]string{"name": "rbd-foo"}, appsv1.RollingUpdateDeploymentStrategyType)
+			assert.Equal(t, tc.want, deploy.Spec.Strategy)
+		})
+	}
+}
+
+func intOrStringPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestBuildStatefulSet(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system", Name: "rbd-foo"},
+		Spec: rainbondv1alpha1.RbdComponentSpec{
+			Image:        "rbd-foo:v1",
+			VolumeMounts: []corev1.VolumeMount{{Name: "extra", MountPath: "/extra"}},
+		},
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{}
+	labels := map[string]string{"name": "rbd-foo"}
+	claim := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data"}}
+
+	sts := buildStatefulSet("rbd-foo", cpt, cluster, labels, "rbd-foo", []corev1.PersistentVolumeClaim{claim},
+		withVolumes(nil, []corev1.VolumeMount{{Name: "data", MountPath: "/data"}}),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.Containers = append(spec.Containers, corev1.Container{Name: "rbd-foo-exporter"})
+		}),
+	)
+	assert.Equal(t, "rbd-foo", sts.Spec.ServiceName)
+	assert.Equal(t, []corev1.PersistentVolumeClaim{claim}, sts.Spec.VolumeClaimTemplates)
+	// the pods of the statefulsets are not spread by default.
+	assert.Nil(t, sts.Spec.Template.Spec.Affinity)
+	if assert.Len(t, sts.Spec.Template.Spec.Containers, 2) {
+		assert.Equal(t, []corev1.VolumeMount{{Name: "extra", MountPath: "/extra"}, {Name: "data", MountPath: "/data"}},
+			sts.Spec.Template.Spec.Containers[0].VolumeMounts)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/proxyutil"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 
 	"github.com/goodrain/rainbond-operator/util/commonutil"
@@ -15,7 +16,7 @@ import (
 	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
 
-	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -42,6 +43,7 @@ type chaos struct {
 var _ ComponentHandler = &chaos{}
 var _ StorageClassRWXer = &chaos{}
 var _ Replicaser = &chaos{}
+var _ ResourcesDeleter = &chaos{}
 
 // NewChaos creates a new rbd-chaos handler.
 func NewChaos(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -72,6 +74,10 @@ func (c *chaos) Before() error {
 	}
 	c.etcdSecret = secret
 
+	if build := c.component.Spec.Build; build != nil && build.MaxConcurrentTasks != nil && *build.MaxConcurrentTasks <= 0 {
+		return NewPermanentError(fmt.Sprintf("invalid max concurrent tasks %d: must be greater than 0", *build.MaxConcurrentTasks))
+	}
+
 	if err := setStorageCassName(c.ctx, c.client, c.component.Namespace, c); err != nil {
 		return err
 	}
@@ -84,6 +90,21 @@ func (c *chaos) Resources() []client.Object {
 		c.deployment(),
 		c.service(),
 		c.defaultMavenSetting(),
+		c.cacheCleanupCronJob(),
+	}
+}
+
+func (c *chaos) ResourcesNeedDelete() []client.Object {
+	if c.cluster.Spec.BuildCache != nil && c.cluster.Spec.BuildCache.Cleanup != nil {
+		return nil
+	}
+	return []client.Object{
+		&batchv1beta1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cacheCleanupName,
+				Namespace: c.component.Namespace,
+			},
+		},
 	}
 }
 
@@ -94,14 +115,18 @@ func (c *chaos) ListPods() ([]corev1.Pod, error) {
 	return listPods(c.ctx, c.client, c.component.Namespace, c.labels)
 }
 
+func (c *chaos) Dependencies() []Dependency {
+	return append([]Dependency{dbDependency(c.db), etcdDependency(c.cluster)}, hubDependencies(c.cluster)...)
+}
+
 func (c *chaos) SetStorageClassNameRWX(pvcParametersRWX *pvcParameters) {
 	c.pvcParametersRWX = pvcParametersRWX
 }
 
 func (c *chaos) ResourcesCreateIfNotExists() []client.Object {
 	return []client.Object{
-		createPersistentVolumeClaimRWX(c.component.Namespace, constants.GrDataPVC, c.pvcParametersRWX, c.labels),
-		createPersistentVolumeClaimRWX(c.component.Namespace, constants.CachePVC, c.pvcParametersRWX, c.labels),
+		grdataPVC(c.cluster, c.component.Namespace, c.pvcParametersRWX, c.labels),
+		c.cachePVC(),
 	}
 }
 
@@ -110,11 +135,8 @@ func (c *chaos) Replicas() *int32 {
 }
 
 func (c *chaos) deployment() client.Object {
+	grdataVolume, grdataMounts := volumeByGrdata(c.cluster, c.component)
 	volumeMounts := []corev1.VolumeMount{
-		{
-			Name:      "grdata",
-			MountPath: "/grdata",
-		},
 		{
 			Name:      "dockersock",
 			MountPath: "/var/run/docker.sock",
@@ -129,15 +151,9 @@ func (c *chaos) deployment() client.Object {
 			SubPath:   "services/ssh",
 		},
 	}
+	volumeMounts = append(volumeMounts, grdataMounts...)
 	volumes := []corev1.Volume{
-		{
-			Name: "grdata",
-			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: constants.GrDataPVC,
-				},
-			},
-		},
+		grdataVolume,
 		{
 			Name: "dockersock",
 			VolumeSource: corev1.VolumeSource{
@@ -172,7 +188,7 @@ func (c *chaos) deployment() client.Object {
 		"--hostIP=$(POD_IP)",
 		c.db.RegionDataSource(),
 		"--etcd-endpoints=" + strings.Join(etcdEndpoints(c.cluster), ","),
-		"--pvc-grdata-name=" + constants.GrDataPVC,
+		"--pvc-grdata-name=" + grdataClaimName(c.cluster),
 		"--pvc-cache-name=" + constants.CachePVC,
 		"--rbd-namespace=" + c.component.Namespace,
 		"--rbd-repo=" + ResourceProxyName,
@@ -180,6 +196,8 @@ func (c *chaos) deployment() client.Object {
 	if c.cluster.Spec.CacheMode == "hostpath" {
 		args = append(args, "--cache-mode=hostpath")
 	}
+	buildArgs, buildEnvs := c.buildLimits()
+	args = append(args, buildArgs...)
 
 	if c.etcdSecret != nil {
 		volume, mount := volumeByEtcd(c.etcdSecret)
@@ -247,81 +265,39 @@ func (c *chaos) deployment() client.Object {
 		})
 	}
 
-	env = mergeEnvs(env, c.component.Spec.Env)
-	volumeMounts = mergeVolumeMounts(volumeMounts, c.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, c.component.Spec.Volumes)
-	args = mergeArgs(args, c.component.Spec.Args)
-
-	// prepare probe
-	readinessProbe := probeutil.MakeReadinessProbeHTTP("", "/v2/builder/health", 3228)
-	ds := &appsv1.DaemonSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      ChaosName,
-			Namespace: c.component.Namespace,
-			Labels:    c.labels,
-		},
-		Spec: appsv1.DaemonSetSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: c.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   ChaosName,
-					Labels: c.labels,
-				},
-				Spec: corev1.PodSpec{
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					ServiceAccountName:            "rainbond-operator",
-					ImagePullSecrets:              imagePullSecrets(c.component, c.cluster),
-					Tolerations: []corev1.Toleration{
-						{
-							Operator: corev1.TolerationOpExists, // tolerate everything.
-						},
-					},
-					HostAliases: hostsAliases(c.cluster),
-					Affinity:    affinity,
-					Containers: []corev1.Container{
-						{
-							Name:            ChaosName,
-							Image:           c.component.Spec.Image,
-							ImagePullPolicy: c.component.ImagePullPolicy(),
-							Env:             env,
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							ReadinessProbe:  readinessProbe,
-							Resources:       c.component.Spec.Resources,
-						},
-					},
-					Volumes: volumes,
-				},
-			},
-		},
+	if c.cluster.Spec.Proxy != nil {
+		// the builds download the sources and the dependencies through the proxy.
+		env = append(env, proxyutil.EnvVars()...)
 	}
 
-	return ds
-}
+	env = append(env, buildEnvs...)
 
-func (c *chaos) service() *corev1.Service {
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      ChaosName,
-			Namespace: c.component.Namespace,
-			Labels:    c.labels,
-		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
+	return buildDaemonSet(ChaosName, c.component, c.cluster, c.labels,
+		withArgs(args...),
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(probeutil.MakeLivenessProbeHTTP("", "/v2/builder/health", 3228), probeutil.MakeReadinessProbeHTTP("", "/v2/builder/health", 3228)),
+		withAffinity(affinity),
+		withServiceAccount(rbdutil.ServiceAccountName()),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.HostAliases = hostsAliases(c.cluster)
+			spec.Tolerations = []corev1.Toleration{
 				{
-					Name: "api",
-					Port: 3228,
-					TargetPort: intstr.IntOrString{
-						IntVal: 3228,
-					},
+					Operator: corev1.TolerationOpExists, // tolerate everything.
 				},
-			},
-			Selector: c.labels,
+			}
+		}),
+	)
+}
+
+func (c *chaos) service() *corev1.Service {
+	return buildService(ChaosName, c.component.Namespace, c.labels, corev1.ServicePort{
+		Name: "api",
+		Port: 3228,
+		TargetPort: intstr.IntOrString{
+			IntVal: 3228,
 		},
-	}
-	return svc
+	})
 }
 
 func (c *chaos) defaultMavenSetting() *corev1.ConfigMap {
@@ -399,3 +375,39 @@ func (c *chaos) defaultMavenSetting() *corev1.ConfigMap {
 		},
 	}
 }
+
+// buildLimits returns the arguments and the environment variables of the limits of the source builds.
+func (c *chaos) buildLimits() ([]string, []corev1.EnvVar) {
+	build := c.component.Spec.Build
+	if build == nil {
+		return nil, nil
+	}
+	var args []string
+	if build.MaxConcurrentTasks != nil {
+		args = append(args, fmt.Sprintf("--max-tasks=%d", *build.MaxConcurrentTasks))
+	}
+	var env []corev1.EnvVar
+	if build.Resources != nil {
+		quantities := []struct {
+			name string
+			list corev1.ResourceList
+			key  corev1.ResourceName
+		}{
+			{"BUILD_CPU_REQUEST", build.Resources.Requests, corev1.ResourceCPU},
+			{"BUILD_CPU_LIMIT", build.Resources.Limits, corev1.ResourceCPU},
+			{"BUILD_MEMORY_REQUEST", build.Resources.Requests, corev1.ResourceMemory},
+			{"BUILD_MEMORY_LIMIT", build.Resources.Limits, corev1.ResourceMemory},
+		}
+		for _, q := range quantities {
+			quantity, ok := q.list[q.key]
+			if !ok {
+				continue
+			}
+			env = append(env, corev1.EnvVar{
+				Name:  q.name,
+				Value: quantity.String(),
+			})
+		}
+	}
+	return args, env
+}
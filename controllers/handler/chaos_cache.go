@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// cacheCleanupName is the name of the CronJob that prunes the build caches.
+	cacheCleanupName = "rbd-chaos-cache-cleanup"
+	// defCacheCleanupSchedule is the default schedule of the cache cleanup.
+	defCacheCleanupSchedule = "0 3 * * *"
+	// defCacheMaxAge is how long the build caches are kept by default.
+	defCacheMaxAge = 7 * 24 * time.Hour
+	// DefCacheCleanupImage is the default image of the cache cleanup jobs, in the image repository of rainbond.
+	DefCacheCleanupImage = "docker:20.10"
+)
+
+// cacheCleanupScript removes the build caches that have not been modified for $MAX_AGE_MINUTES,
+// and the dangling images left by the builds.
+const cacheCleanupScript = `find "$CACHE_DIR" -mindepth 1 -type f -mmin +"$MAX_AGE_MINUTES" -delete 2>/dev/null
+find "$CACHE_DIR" -mindepth 1 -type d -empty -delete 2>/dev/null
+docker image prune -f
+exit 0
+`
+
+// cachePVC returns the claim of the build cache, or nil if the cache is on the host.
+func (c *chaos) cachePVC() client.Object {
+	if c.cluster.Spec.CacheMode == "hostpath" {
+		return nil
+	}
+	params := &pvcParameters{}
+	if c.pvcParametersRWX != nil {
+		params.storageClassName = c.pvcParametersRWX.storageClassName
+	}
+	if cache := c.cluster.Spec.BuildCache; cache != nil {
+		if cache.StorageClassName != "" {
+			params.storageClassName = cache.StorageClassName
+		}
+		params.storageRequest = cache.StorageRequest
+	}
+	accessModes := []corev1.PersistentVolumeAccessMode{
+		corev1.ReadWriteMany,
+	}
+	return createPersistentVolumeClaim(c.component.Namespace, constants.CachePVC, accessModes, params, c.labels, c.cacheStorageRequest)
+}
+
+// cacheCleanupCronJob returns the CronJob that prunes the build caches on the nodes for rbd-chaos,
+// or nil if the cleanup is disabled.
+func (c *chaos) cacheCleanupCronJob() client.Object {
+	if c.cluster.Spec.BuildCache == nil || c.cluster.Spec.BuildCache.Cleanup == nil {
+		return nil
+	}
+	cleanup := c.cluster.Spec.BuildCache.Cleanup
+	schedule := cleanup.Schedule
+	if schedule == "" {
+		schedule = defCacheCleanupSchedule
+	}
+	maxAge := defCacheMaxAge
+	if cleanup.MaxAge != nil && cleanup.MaxAge.Duration > 0 {
+		maxAge = cleanup.MaxAge.Duration
+	}
+	image := cleanup.Image
+	if image == "" {
+		image = path.Join(c.cluster.Spec.RainbondImageRepository, DefCacheCleanupImage)
+	}
+
+	labels := copyLabels(c.labels)
+	labels["name"] = cacheCleanupName
+
+	// run one pod on each node for rbd-chaos, so that the dangling images of every node will be pruned.
+	var nodeNames []string
+	for _, node := range c.cluster.Spec.NodesForChaos {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	parallelism := int32(len(nodeNames))
+	if parallelism == 0 {
+		parallelism = 1
+	}
+	affinity := &corev1.Affinity{}
+	if len(nodeNames) > 0 {
+		affinity = affinityForRequiredNodes(nodeNames)
+	}
+	affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+			{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: labels,
+				},
+				TopologyKey: "kubernetes.io/hostname",
+			},
+		},
+	}
+
+	cache := corev1.Volume{
+		Name: "cache",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: constants.CachePVC,
+			},
+		},
+	}
+	if c.cluster.Spec.CacheMode == "hostpath" {
+		cache.VolumeSource = corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: "/cache",
+				Type: k8sutil.HostPath(corev1.HostPathDirectoryOrCreate),
+			},
+		}
+	}
+
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cacheCleanupName,
+			Namespace: c.component.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: commonutil.Int32(1),
+			FailedJobsHistoryLimit:     commonutil.Int32(1),
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					Parallelism:  commonutil.Int32(parallelism),
+					Completions:  commonutil.Int32(parallelism),
+					BackoffLimit: commonutil.Int32(1),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: corev1.PodSpec{
+							RestartPolicy:    corev1.RestartPolicyNever,
+							ImagePullSecrets: imagePullSecrets(c.component, c.cluster),
+							Tolerations: []corev1.Toleration{
+								{
+									Operator: corev1.TolerationOpExists, // tolerate everything.
+								},
+							},
+							Affinity: affinity,
+							Containers: []corev1.Container{
+								{
+									Name:            cacheCleanupName,
+									Image:           image,
+									ImagePullPolicy: corev1.PullIfNotPresent,
+									Command:         []string{"/bin/sh", "-c", cacheCleanupScript},
+									Env: []corev1.EnvVar{
+										{
+											Name:  "CACHE_DIR",
+											Value: "/cache",
+										},
+										{
+											Name:  "MAX_AGE_MINUTES",
+											Value: fmt.Sprintf("%d", int64(maxAge/time.Minute)),
+										},
+									},
+									VolumeMounts: []corev1.VolumeMount{
+										{
+											Name:      "dockersock",
+											MountPath: "/var/run/docker.sock",
+										},
+										{
+											Name:      "cache",
+											MountPath: "/cache",
+										},
+									},
+								},
+							},
+							Volumes: []corev1.Volume{
+								{
+									Name: "dockersock",
+									VolumeSource: corev1.VolumeSource{
+										HostPath: &corev1.HostPathVolumeSource{
+											Path: "/var/run/docker.sock",
+											Type: k8sutil.HostPath(corev1.HostPathSocket),
+										},
+									},
+								},
+								cache,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -11,14 +11,17 @@ import (
 
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/licenseutil"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -28,12 +31,25 @@ var ErrNoDBEndpoints = errors.New("no ready endpoints for DB were found")
 const (
 	// EtcdSSLPath ssl file path for etcd
 	EtcdSSLPath = "/run/ssl/etcd"
+	// LicensePath is the path where the license file is mounted.
+	LicensePath = "/run/license"
 	// RegionDatabaseName -
 	RegionDatabaseName = "region"
 	// ConsoleDatabaseName -
 	ConsoleDatabaseName = "console"
 )
 
+// defGrdataPath is the default path where the shared storage is mounted.
+const defGrdataPath = "/grdata"
+
+const (
+	// defTrafficGracePeriodSeconds is the default termination grace period of the components serving traffic.
+	defTrafficGracePeriodSeconds = 30
+	// defPreStopSleepSeconds is how long the components serving traffic wait before stopping,
+	// so that they are removed from the endpoints before the in-flight requests are dropped.
+	defPreStopSleepSeconds = 5
+)
+
 // pvcParameters holds parameters to create pvc.
 type pvcParameters struct {
 	storageClassName string
@@ -58,7 +74,7 @@ func getDefaultDBInfo(ctx context.Context, cli client.Client, in *rainbondv1alph
 		if !k8sErrors.IsNotFound(err) {
 			return nil, fmt.Errorf("get secret %s/%s: %v", name, namespace, err)
 		}
-		return nil, NewIgnoreError(fmt.Sprintf("secret %s/%s not fount: %v", name, namespace, err))
+		return nil, NewWaitForDependencyError(fmt.Sprintf("secret %s/%s not fount: %v", name, namespace, err))
 	}
 	user := string(secret.Data[mysqlUserKey])
 	pass := string(secret.Data[mysqlPasswordKey])
@@ -112,6 +128,26 @@ func volumeByEtcd(etcdSecret *corev1.Secret) (corev1.Volume, corev1.VolumeMount)
 	return volume, mount
 }
 
+func volumeByLicense(secretName string) (corev1.Volume, corev1.VolumeMount, corev1.EnvVar) {
+	volume := corev1.Volume{
+		Name: "license",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secretName,
+			},
+		}}
+	mount := corev1.VolumeMount{
+		Name:      "license",
+		MountPath: LicensePath,
+		ReadOnly:  true,
+	}
+	env := corev1.EnvVar{
+		Name:  "LICENSE_PATH",
+		Value: path.Join(LicensePath, licenseutil.SecretKey),
+	}
+	return volume, mount, env
+}
+
 func volumeByAPISecret(apiServerSecret *corev1.Secret) (corev1.Volume, corev1.VolumeMount) {
 	volume := corev1.Volume{
 		Name: "region-api-ssl",
@@ -166,12 +202,12 @@ func storageClassNameFromRainbondVolume(ctx context.Context, cli client.Client,
 	}
 
 	if len(volumeList.Items) == 0 {
-		return nil, NewIgnoreError(rainbondVolumeNotFound)
+		return nil, NewWaitForDependencyError(rainbondVolumeNotFound)
 	}
 
 	volume := volumeList.Items[0]
 	if volume.Spec.StorageClassName == "" {
-		return nil, NewIgnoreError("storage class not ready")
+		return nil, NewWaitForDependencyError("storage class not ready")
 	}
 
 	pvcParameters := &pvcParameters{
@@ -205,6 +241,162 @@ func setStorageCassName(ctx context.Context, cli client.Client, ns string, obj i
 	return nil
 }
 
+// grdataClaimName returns the name of the claim of the shared storage.
+func grdataClaimName(cluster *rainbondv1alpha1.RainbondCluster) string {
+	if storage := cluster.Spec.SharedStorage; storage != nil && storage.ClaimName != "" {
+		return storage.ClaimName
+	}
+	// the claim migrated to.
+	if status := cluster.Status.SharedStorage; status != nil && status.ClaimName != "" {
+		return status.ClaimName
+	}
+	return constants.GrDataPVC
+}
+
+// grdataMountPath returns the path where the shared storage is mounted in the containers.
+func grdataMountPath(cluster *rainbondv1alpha1.RainbondCluster) string {
+	if storage := cluster.Spec.SharedStorage; storage != nil && storage.MountPath != "" {
+		return storage.MountPath
+	}
+	return defGrdataPath
+}
+
+// volumeByGrdata returns the volume of the shared storage, and the volume mounts of the
+// shared storage for the component.
+func volumeByGrdata(cluster *rainbondv1alpha1.RainbondCluster, cpt *rainbondv1alpha1.RbdComponent) (corev1.Volume, []corev1.VolumeMount) {
+	grdataPath := grdataMountPath(cluster)
+	volume := corev1.Volume{
+		Name: "grdata",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: grdataClaimName(cluster),
+			},
+		},
+	}
+	if len(cpt.Spec.SharedStorageMounts) == 0 {
+		return volume, []corev1.VolumeMount{
+			{
+				Name:      "grdata",
+				MountPath: grdataPath,
+			},
+		}
+	}
+	var mounts []corev1.VolumeMount
+	for _, mount := range cpt.Spec.SharedStorageMounts {
+		mountPath := mount.MountPath
+		if mountPath == "" {
+			mountPath = path.Join(grdataPath, mount.SubPath)
+		}
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "grdata",
+			MountPath: mountPath,
+			SubPath:   mount.SubPath,
+			ReadOnly:  mount.ReadOnly,
+		})
+	}
+	return volume, mounts
+}
+
+// grdataPVC returns the claim of the shared storage to be created, or nil if an existing claim is used.
+func grdataPVC(cluster *rainbondv1alpha1.RainbondCluster, ns string, parameters *pvcParameters, labels map[string]string) client.Object {
+	if status := cluster.Status.SharedStorage; status != nil && status.ClaimName != "" {
+		// the claim migrated to is created by the storage migration.
+		return nil
+	}
+	storage := cluster.Spec.SharedStorage
+	if storage == nil {
+		return createPersistentVolumeClaimRWX(ns, constants.GrDataPVC, parameters, labels)
+	}
+	if storage.ClaimName != "" {
+		return nil
+	}
+	params := &pvcParameters{}
+	if parameters != nil {
+		*params = *parameters
+	}
+	if storage.StorageClassName != "" {
+		params.storageClassName = storage.StorageClassName
+	}
+	if storage.StorageRequest != nil {
+		params.storageRequest = storage.StorageRequest
+	}
+	return createPersistentVolumeClaimRWX(ns, constants.GrDataPVC, params, labels)
+}
+
+// dataPVC returns the claim template of the data volume of each replica of the component.
+// The storage class and size of the data volume of the component take precedence over the given parameters.
+func dataPVC(cpt *rainbondv1alpha1.RbdComponent, claimName string, parameters *pvcParameters, labels map[string]string, storageRequest int64) *corev1.PersistentVolumeClaim {
+	params := &pvcParameters{}
+	if parameters != nil {
+		*params = *parameters
+	}
+	if dataVolume := cpt.Spec.DataVolume; dataVolume != nil {
+		if dataVolume.StorageClassName != "" {
+			params.storageClassName = dataVolume.StorageClassName
+		}
+		if dataVolume.StorageRequest != nil {
+			params.storageRequest = dataVolume.StorageRequest
+		}
+	}
+	return createPersistentVolumeClaimRWO(cpt.Namespace, claimName, params, labels, storageRequest)
+}
+
+// existingClaimName returns the name of the claim created beforehand for the data of the component, if any.
+func existingClaimName(cpt *rainbondv1alpha1.RbdComponent) string {
+	if cpt.Spec.DataVolume == nil {
+		return ""
+	}
+	return cpt.Spec.DataVolume.ExistingClaim
+}
+
+// claimVolume returns the volume of the claim.
+func claimVolume(name, claimName string) corev1.Volume {
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+			},
+		},
+	}
+}
+
+// checkExistingClaim checks the claim created beforehand for the data of the component exists, and has one of the
+// access modes.
+func checkExistingClaim(ctx context.Context, cli client.Client, cpt *rainbondv1alpha1.RbdComponent, accessModes ...corev1.PersistentVolumeAccessMode) error {
+	claimName := existingClaimName(cpt)
+	if claimName == "" {
+		return nil
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: cpt.Namespace, Name: claimName}, pvc); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return NewWaitForDependencyError(fmt.Sprintf("the existing claim %s not found", claimName))
+		}
+		return fmt.Errorf("get the existing claim %s: %v", claimName, err)
+	}
+	if pvc.Status.Phase == corev1.ClaimLost {
+		return NewPermanentError(fmt.Sprintf("the volume of the existing claim %s is lost", claimName))
+	}
+	// the access modes of the bound volume take precedence.
+	modes := pvc.Status.AccessModes
+	if len(modes) == 0 {
+		modes = pvc.Spec.AccessModes
+	}
+	for _, mode := range modes {
+		for _, accessMode := range accessModes {
+			if mode == accessMode {
+				return nil
+			}
+		}
+	}
+	var want []string
+	for _, accessMode := range accessModes {
+		want = append(want, string(accessMode))
+	}
+	return NewPermanentError(fmt.Sprintf("the existing claim %s is not %s", claimName, strings.Join(want, " or ")))
+}
+
 func createPersistentVolumeClaimRWX(ns, claimName string, pvcParameters *pvcParameters, labels map[string]string) *corev1.PersistentVolumeClaim {
 	accessModes := []corev1.PersistentVolumeAccessMode{
 		corev1.ReadWriteMany,
@@ -267,6 +459,31 @@ func affinityForRequiredNodes(nodeNames []string) *corev1.Affinity {
 	}
 }
 
+// affinityForSpreadingPods returns the pod anti-affinity that spreads the pods with the given
+// labels across the nodes. It is required if enableHA is true, otherwise preferred.
+func affinityForSpreadingPods(labels map[string]string, enableHA bool) *corev1.Affinity {
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: labels,
+		},
+		TopologyKey: "kubernetes.io/hostname",
+	}
+	antiAffinity := &corev1.PodAntiAffinity{}
+	if enableHA {
+		antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = []corev1.PodAffinityTerm{term}
+	} else {
+		antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = []corev1.WeightedPodAffinityTerm{
+			{
+				Weight:          100,
+				PodAffinityTerm: term,
+			},
+		}
+	}
+	return &corev1.Affinity{
+		PodAntiAffinity: antiAffinity,
+	}
+}
+
 func copyLabels(m map[string]string) map[string]string {
 	cp := make(map[string]string)
 	for k, v := range m {
@@ -278,6 +495,10 @@ func copyLabels(m map[string]string) map[string]string {
 
 func hostsAliases(cluster *rainbondv1alpha1.RainbondCluster) []corev1.HostAlias {
 	var hostAliases []corev1.HostAlias
+	// the image hub is resolved by coredns.
+	if cluster.Spec.CoreDNS != nil && cluster.Spec.CoreDNS.Enabled {
+		return hostAliases
+	}
 	if rbdutil.GetImageRepository(cluster) == constants.DefImageRepository {
 		hostAliases = append(hostAliases, corev1.HostAlias{
 			IP:        cluster.InnerGatewayIngressIP(),
@@ -301,23 +522,6 @@ func listPods(ctx context.Context, cli client.Client, namespace string, labels m
 	return podList.Items, nil
 }
 
-func isEtcdAvailable(ctx context.Context, cli client.Client, cpt *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) error {
-	if cluster.Spec.EtcdConfig != nil {
-		return nil
-	}
-
-	dbcpt := &rainbondv1alpha1.RbdComponent{}
-	if err := cli.Get(ctx, types.NamespacedName{Namespace: cpt.Namespace, Name: EtcdName}, dbcpt); err != nil {
-		return err
-	}
-
-	if dbcpt.Status.ReadyReplicas == 0 {
-		return errors.New("no ready replicas for rbdcomponent rbd-etcd")
-	}
-
-	return nil
-}
-
 func getStorageRequest(env string, defSize int64) int64 {
 	storageRequest, _ := strconv.ParseInt(os.Getenv(env), 10, 64)
 	if storageRequest == 0 {
@@ -339,6 +543,97 @@ func imagePullSecrets(cpt *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alp
 	}
 }
 
+// terminationGracePeriodSeconds returns the termination grace period of the component, or defSeconds if not specified.
+func terminationGracePeriodSeconds(cpt *rainbondv1alpha1.RbdComponent, defSeconds int64) *int64 {
+	if cpt.Spec.TerminationGracePeriodSeconds != nil {
+		return cpt.Spec.TerminationGracePeriodSeconds
+	}
+	return commonutil.Int64(defSeconds)
+}
+
+// deploymentStrategy returns the strategy of the deployment of the component, or the strategy with defType if not specified.
+func deploymentStrategy(cpt *rainbondv1alpha1.RbdComponent, defType appsv1.DeploymentStrategyType) appsv1.DeploymentStrategy {
+	strategy := cpt.Spec.UpdateStrategy
+	if strategy == nil {
+		return appsv1.DeploymentStrategy{Type: defType}
+	}
+	strategyType := appsv1.DeploymentStrategyType(strategy.Type)
+	if strategyType == "" {
+		strategyType = defType
+	}
+	if strategyType == appsv1.RecreateDeploymentStrategyType || (strategy.MaxSurge == nil && strategy.MaxUnavailable == nil) {
+		return appsv1.DeploymentStrategy{Type: strategyType}
+	}
+	return appsv1.DeploymentStrategy{
+		Type: strategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       strategy.MaxSurge,
+			MaxUnavailable: strategy.MaxUnavailable,
+		},
+	}
+}
+
+// strategyWithoutSurge replaces the old pods one by one in the rolling update unless maxSurge or maxUnavailable
+// is specified, since the surge pod can't be scheduled once every node runs a pod that it is required to avoid.
+func strategyWithoutSurge(strategy appsv1.DeploymentStrategy) appsv1.DeploymentStrategy {
+	if strategy.Type != appsv1.RollingUpdateDeploymentStrategyType || strategy.RollingUpdate != nil {
+		return strategy
+	}
+	maxSurge, maxUnavailable := intstr.FromInt(0), intstr.FromInt(1)
+	strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{
+		MaxSurge:       &maxSurge,
+		MaxUnavailable: &maxUnavailable,
+	}
+	return strategy
+}
+
+// requiredAntiAffinity returns true if the pods are required to avoid some pods.
+func requiredAntiAffinity(affinity *corev1.Affinity) bool {
+	return affinity != nil && affinity.PodAntiAffinity != nil && len(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0
+}
+
+// daemonSetUpdateStrategy returns the update strategy of the daemonset of the component.
+// The daemonsets run at most one pod on each node, so the strategy Recreate and maxSurge are ignored.
+func daemonSetUpdateStrategy(cpt *rainbondv1alpha1.RbdComponent) appsv1.DaemonSetUpdateStrategy {
+	strategy := cpt.Spec.UpdateStrategy
+	if strategy == nil || strategy.MaxUnavailable == nil {
+		return appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType}
+	}
+	return appsv1.DaemonSetUpdateStrategy{
+		Type: appsv1.RollingUpdateDaemonSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+			MaxUnavailable: strategy.MaxUnavailable,
+		},
+	}
+}
+
+// lifecycle returns the lifecycle hooks of the component, or defLifecycle if not specified.
+func lifecycle(cpt *rainbondv1alpha1.RbdComponent, defLifecycle *corev1.Lifecycle) *corev1.Lifecycle {
+	if cpt.Spec.Lifecycle != nil {
+		return cpt.Spec.Lifecycle
+	}
+	return defLifecycle
+}
+
+// securityContext returns the security context of the component, or defSecurityContext if not specified.
+func securityContext(cpt *rainbondv1alpha1.RbdComponent, defSecurityContext *corev1.SecurityContext) *corev1.SecurityContext {
+	if cpt.Spec.SecurityContext != nil {
+		return cpt.Spec.SecurityContext
+	}
+	return defSecurityContext
+}
+
+// preStopSleep returns the lifecycle hooks that wait for the given seconds before the container is stopped.
+func preStopSleep(seconds int) *corev1.Lifecycle {
+	return &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "-c", fmt.Sprintf("sleep %d", seconds)},
+			},
+		},
+	}
+}
+
 func mergeArgs(commonArgs, priorityArgs []string) []string {
 	prioritySet := make(map[string]struct{})
 	for _, arg := range priorityArgs {
@@ -405,3 +700,13 @@ func mergeVolumeMounts(commonMountVolumes, priorityMountVolumes []corev1.VolumeM
 	}
 	return priorityMountVolumes
 }
+
+// hostPortsConflict returns an error if the ports of the host network are used by the rainbond installed before
+// in another namespace, then rbd-gateway and rbd-node are not deployed, see the HostPorts condition of the cluster.
+func hostPortsConflict(cluster *rainbondv1alpha1.RainbondCluster) error {
+	_, condition := cluster.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeHostPorts)
+	if condition != nil && condition.Status == corev1.ConditionFalse && condition.Reason == rainbondv1alpha1.ReasonHostPortConflict {
+		return NewWaitForDependencyError(condition.Message)
+	}
+	return nil
+}
@@ -7,9 +7,11 @@ import (
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -77,7 +79,7 @@ func TestStorageClassRWXVolumeRWXNotReady(t *testing.T) {
 	ctx := context.Background()
 	_, err := storageClassNameFromRainbondVolumeRWX(ctx, cli, ns)
 	assert.NotNil(t, err)
-	assert.True(t, IsIgnoreError(err))
+	assert.Equal(t, ErrorKindWaitForDependency, ErrorKindOf(err))
 	assert.Equal(t, "storage class not ready", err.Error())
 }
 
@@ -277,3 +279,116 @@ func TestMergeArgs(t *testing.T) {
 	args := mergeArgs(commonArgs, priorityArgs)
 	assert.ElementsMatch(t, expectArgs, args)
 }
+
+func TestDeploymentStrategy(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{}
+	strategy := deploymentStrategy(cpt, appsv1.RecreateDeploymentStrategyType)
+	assert.Equal(t, appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}, strategy)
+
+	maxSurge := intstr.FromInt(0)
+	maxUnavailable := intstr.FromString("50%")
+	cpt.Spec.UpdateStrategy = &rainbondv1alpha1.UpdateStrategy{
+		MaxSurge:       &maxSurge,
+		MaxUnavailable: &maxUnavailable,
+	}
+	strategy = deploymentStrategy(cpt, appsv1.RollingUpdateDeploymentStrategyType)
+	assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, strategy.Type)
+	assert.Equal(t, &maxSurge, strategy.RollingUpdate.MaxSurge)
+	assert.Equal(t, &maxUnavailable, strategy.RollingUpdate.MaxUnavailable)
+
+	// the rolling update parameters are ignored with Recreate.
+	cpt.Spec.UpdateStrategy.Type = rainbondv1alpha1.UpdateStrategyRecreate
+	strategy = deploymentStrategy(cpt, appsv1.RollingUpdateDeploymentStrategyType)
+	assert.Equal(t, appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}, strategy)
+}
+
+func TestCheckExistingClaim(t *testing.T) {
+	claim := func(phase corev1.PersistentVolumeClaimPhase, modes ...corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "restored", Namespace: "rbd-system"},
+			Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: modes},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: phase},
+		}
+	}
+	tests := []struct {
+		name          string
+		existingClaim string
+		existing      []runtime.Object
+		accessModes   []corev1.PersistentVolumeAccessMode
+		wantKind      ErrorKind
+	}{
+		{
+			name: "no existing claim",
+		},
+		{
+			name:          "not found",
+			existingClaim: "restored",
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			wantKind:      ErrorKindWaitForDependency,
+		},
+		{
+			name:          "pending",
+			existingClaim: "restored",
+			existing:      []runtime.Object{claim(corev1.ClaimPending, corev1.ReadWriteOnce)},
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadWriteMany},
+		},
+		{
+			name:          "read only",
+			existingClaim: "restored",
+			existing:      []runtime.Object{claim(corev1.ClaimBound, corev1.ReadOnlyMany)},
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadWriteMany},
+			wantKind:      ErrorKindPermanent,
+		},
+		{
+			name:          "rwo for rwx",
+			existingClaim: "restored",
+			existing:      []runtime.Object{claim(corev1.ClaimBound, corev1.ReadWriteOnce)},
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			wantKind:      ErrorKindPermanent,
+		},
+		{
+			name:          "lost",
+			existingClaim: "restored",
+			existing:      []runtime.Object{claim(corev1.ClaimLost, corev1.ReadWriteOnce)},
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			wantKind:      ErrorKindPermanent,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			cpt := &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Name: DBName, Namespace: "rbd-system"}}
+			if tc.existingClaim != "" {
+				cpt.Spec.DataVolume = &rainbondv1alpha1.DataVolume{ExistingClaim: tc.existingClaim}
+			}
+			err := checkExistingClaim(context.Background(), fake.NewFakeClientWithScheme(scheme, tc.existing...), cpt, tc.accessModes...)
+			if tc.wantKind == "" {
+				assert.Nil(t, err)
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tc.wantKind, ErrorKindOf(err))
+			}
+		})
+	}
+}
+
+func TestMonitorExistingClaim(t *testing.T) {
+	m := &monitor{
+		component: &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: MonitorName, Namespace: "rbd-system"},
+			Spec: rainbondv1alpha1.RbdComponentSpec{
+				DataVolume: &rainbondv1alpha1.DataVolume{ExistingClaim: "restored"},
+			},
+		},
+		cluster: &rainbondv1alpha1.RainbondCluster{},
+		labels:  map[string]string{"name": MonitorName},
+	}
+	sts := m.statefulset().(*appsv1.StatefulSet)
+	assert.Empty(t, sts.Spec.VolumeClaimTemplates)
+	assert.Contains(t, sts.Spec.Template.Spec.Volumes, claimVolume("data", "restored"))
+}
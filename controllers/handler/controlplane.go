@@ -0,0 +1,86 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// controlPlaneTaintKeys are the keys of the taints of the control-plane nodes, the former is used before kubernetes 1.24.
+var controlPlaneTaintKeys = []string{"node-role.kubernetes.io/master", "node-role.kubernetes.io/control-plane"}
+
+// defControlPlaneComponents are the core components scheduled on the control-plane nodes by default.
+// The daemonsets, such as rbd-gateway and rbd-node-proxy, are placed by their own node settings.
+var defControlPlaneComponents = []string{APIName, WorkerName, MQName, EventLogName, DBName, EtcdName, HubName}
+
+// SetControlPlaneScheduling applies the control-plane tolerations and node selector to the pods of the
+// given object, which is a deployment or statefulset of the designated component. Other objects are left unchanged.
+func SetControlPlaneScheduling(obj client.Object, component string, scheduling *rainbondv1alpha1.ControlPlaneScheduling) {
+	if scheduling == nil || !scheduling.Enabled {
+		return
+	}
+	components := scheduling.Components
+	if len(components) == 0 {
+		components = defControlPlaneComponents
+	}
+	var designated bool
+	for _, name := range components {
+		if name == component {
+			designated = true
+			break
+		}
+	}
+	if !designated {
+		return
+	}
+	var template *corev1.PodTemplateSpec
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		template = &o.Spec.Template
+	case *appsv1.StatefulSet:
+		template = &o.Spec.Template
+	default:
+		return
+	}
+
+	for _, key := range controlPlaneTaintKeys {
+		if toleratesTaint(template.Spec.Tolerations, key) {
+			continue
+		}
+		template.Spec.Tolerations = append(template.Spec.Tolerations, corev1.Toleration{
+			Key:      key,
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		})
+	}
+	if len(scheduling.NodeSelector) > 0 {
+		if template.Spec.NodeSelector == nil {
+			template.Spec.NodeSelector = make(map[string]string, len(scheduling.NodeSelector))
+		}
+		for key, value := range scheduling.NodeSelector {
+			template.Spec.NodeSelector[key] = value
+		}
+	}
+}
+
+// SetLinuxNodeSelector keeps the pods of the given object, which is a workload generated by the handlers,
+// off the windows nodes. Other objects are left unchanged.
+func SetLinuxNodeSelector(obj client.Object) {
+	if template := podTemplate(obj); template != nil {
+		k8sutil.SetLinuxNodeSelector(&template.Spec)
+	}
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, key string) bool {
+	for _, toleration := range tolerations {
+		if toleration.Operator != corev1.TolerationOpExists {
+			continue
+		}
+		if (toleration.Key == "" || toleration.Key == key) && (toleration.Effect == "" || toleration.Effect == corev1.TaintEffectNoSchedule) {
+			return true
+		}
+	}
+	return false
+}
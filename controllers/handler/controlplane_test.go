@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetControlPlaneScheduling(t *testing.T) {
+	scheduling := &rainbondv1alpha1.ControlPlaneScheduling{
+		Enabled:      true,
+		NodeSelector: map[string]string{"node-role.kubernetes.io/master": ""},
+	}
+
+	deploy := &appsv1.Deployment{}
+	SetControlPlaneScheduling(deploy, APIName, scheduling)
+	assert.Len(t, deploy.Spec.Template.Spec.Tolerations, 2)
+	assert.Equal(t, scheduling.NodeSelector, deploy.Spec.Template.Spec.NodeSelector)
+
+	// tolerates everything already.
+	sts := &appsv1.StatefulSet{}
+	sts.Spec.Template.Spec.Tolerations = []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+	SetControlPlaneScheduling(sts, EtcdName, scheduling)
+	assert.Len(t, sts.Spec.Template.Spec.Tolerations, 1)
+
+	// not designated.
+	other := &appsv1.Deployment{}
+	SetControlPlaneScheduling(other, MonitorName, scheduling)
+	assert.Empty(t, other.Spec.Template.Spec.Tolerations)
+	assert.Nil(t, other.Spec.Template.Spec.NodeSelector)
+}
+
+func TestSetLinuxNodeSelector(t *testing.T) {
+	shared := map[string]string{"rainbond.io/gateway": ""}
+	ds := &appsv1.DaemonSet{}
+	ds.Spec.Template.Spec.NodeSelector = shared
+	SetLinuxNodeSelector(ds)
+	assert.Equal(t, map[string]string{"rainbond.io/gateway": "", corev1.LabelOSStable: "linux"}, ds.Spec.Template.Spec.NodeSelector)
+	assert.Len(t, shared, 1)
+
+	// the os is selected already.
+	sts := &appsv1.StatefulSet{}
+	sts.Spec.Template.Spec.NodeSelector = map[string]string{corev1.LabelOSStable: "windows"}
+	SetLinuxNodeSelector(sts)
+	assert.Equal(t, "windows", sts.Spec.Template.Spec.NodeSelector[corev1.LabelOSStable])
+}
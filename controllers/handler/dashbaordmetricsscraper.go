@@ -5,9 +5,10 @@ import (
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -60,92 +61,52 @@ func (k *dashboardMetricsScraper) deploymentForDashboardMetricsScraper() client.
 	labels := copyLabels(k.labels)
 	labels["name"] = DashboardMetricsScraperName
 
-	deploy := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      DashboardMetricsScraperName,
-			Namespace: k.component.Namespace,
-			Labels:    labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: k.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   DashboardMetricsScraperName,
-					Labels: labels,
-					Annotations: map[string]string{
-						"seccomp.security.alpha.kubernetes.io/pod": "runtime/default",
+	livenessProbe := probeutil.MakeLivenessProbeHTTP("", "/", 8000)
+	probeutil.SetProbeArgs(livenessProbe, 30, 30, 10, 0, 0)
+	return buildDeployment(DashboardMetricsScraperName, k.component, k.cluster, labels, appsv1.RollingUpdateDeploymentStrategyType,
+		withPodAnnotations(map[string]string{
+			"seccomp.security.alpha.kubernetes.io/pod": "runtime/default",
+		}),
+		withPorts(corev1.ContainerPort{
+			ContainerPort: 8000,
+			Protocol:      corev1.ProtocolTCP,
+		}),
+		withSecurityContext(&corev1.SecurityContext{
+			Privileged: commonutil.Bool(true),
+		}),
+		withVolumes(
+			[]corev1.Volume{
+				{
+					Name: "tmp-volume",
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{},
 					},
 				},
-				Spec: corev1.PodSpec{
-					ServiceAccountName: "rainbond-operator",
-					Tolerations: []corev1.Toleration{
-						{
-							Operator: corev1.TolerationOpExists, // tolerate everything.
-						},
-					},
-					ImagePullSecrets:              imagePullSecrets(k.component, k.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Containers: []corev1.Container{
-						{
-							Name:            DashboardMetricsScraperName,
-							Image:           k.component.Spec.Image,
-							ImagePullPolicy: k.component.ImagePullPolicy(),
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: 8000,
-									Protocol:      corev1.ProtocolTCP,
-								},
-							},
-							SecurityContext: &corev1.SecurityContext{
-								Privileged: commonutil.Bool(true),
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "tmp-volume",
-									MountPath: "/tmp",
-								},
-							},
-							Resources: k.component.Spec.Resources,
-						},
-					},
-					Volumes: []corev1.Volume{
-						{
-							Name: "tmp-volume",
-							VolumeSource: corev1.VolumeSource{
-								EmptyDir: &corev1.EmptyDirVolumeSource{},
-							},
-						},
-					},
+			},
+			[]corev1.VolumeMount{
+				{
+					Name:      "tmp-volume",
+					MountPath: "/tmp",
 				},
 			},
-		},
-	}
-
-	return deploy
+		),
+		withProbes(livenessProbe, nil),
+		withServiceAccount(rbdutil.ServiceAccountName()),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.Tolerations = []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists, // tolerate everything.
+				},
+			}
+		}),
+	)
 }
 
 func (k *dashboardMetricsScraper) serviceForDashboardMetricsScraper() client.Object {
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      DashboardMetricsScraperName,
-			Namespace: k.component.Namespace,
-			Labels:    k.labels,
-		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Port: 8000,
-					TargetPort: intstr.IntOrString{
-						IntVal: 8000,
-					},
-				},
-			},
-			Selector: k.labels,
+	return buildService(DashboardMetricsScraperName, k.component.Namespace, k.labels, corev1.ServicePort{
+		Port: 8000,
+		TargetPort: intstr.IntOrString{
+			IntVal: 8000,
 		},
-	}
-
-	return svc
+	})
 }
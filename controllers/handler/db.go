@@ -11,7 +11,8 @@ import (
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
-	appsv1 "k8s.io/api/apps/v1"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -95,6 +96,9 @@ func (d *db) Before() error {
 	if err := setStorageCassName(d.ctx, d.client, d.component.Namespace, d); err != nil {
 		return err
 	}
+	if err := checkExistingClaim(d.ctx, d.client, d.component, corev1.ReadWriteOnce, corev1.ReadWriteMany); err != nil {
+		return err
+	}
 
 	affinity, err := nodeAffnityNodesForChaos(d.cluster)
 	if err != nil {
@@ -142,6 +146,10 @@ func (d *db) Replicas() *int32 {
 }
 
 func (d *db) CreateClusterScoped() []client.Object {
+	if d.component.Spec.DataVolume != nil {
+		// the volume is provisioned by the storage class.
+		return nil
+	}
 	return []client.Object{
 		d.pv(),
 	}
@@ -184,6 +192,9 @@ func (d *db) statefulsetForDB() client.Object {
 	}
 
 	pvc := d.pvc()
+	if d.component.Spec.DataVolume != nil {
+		pvc = dataPVC(d.component, DBName, d.pvcParametersRWO, d.labels, d.storageRequest)
+	}
 	volumeMounts := []corev1.VolumeMount{
 		{
 			Name:      pvc.GetName(),
@@ -222,70 +233,59 @@ func (d *db) statefulsetForDB() client.Object {
 		},
 	}
 
-	env = mergeEnvs(env, d.component.Spec.Env)
-	volumeMounts = mergeVolumeMounts(volumeMounts, d.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, d.component.Spec.Volumes)
+	claimTemplates := []corev1.PersistentVolumeClaim{*pvc}
+	if claimName := existingClaimName(d.component); claimName != "" {
+		claimTemplates = nil
+		volumes = append(volumes, claimVolume(pvc.GetName(), claimName))
+	}
 
-	sts := &appsv1.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      DBName,
-			Namespace: d.component.Namespace,
-			Labels:    d.labels,
+	liveness := &corev1.Probe{
+		Handler: corev1.Handler{
+			// succeeds as long as the server is running.
+			Exec: &corev1.ExecAction{Command: []string{"mysqladmin", "ping"}},
 		},
-		Spec: appsv1.StatefulSetSpec{
-			Replicas: commonutil.Int32(1),
-			Selector: &metav1.LabelSelector{
-				MatchLabels: d.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   DBName,
-					Labels: d.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(d.component, d.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Tolerations: []corev1.Toleration{
-						{
-							Operator: corev1.TolerationOpExists, // tolerate everything.
-						},
-					},
-					Containers: []corev1.Container{
-						{
-							Name:            DBName,
-							Image:           d.component.Spec.Image,
-							ImagePullPolicy: d.component.ImagePullPolicy(),
-							Env:             env,
-							VolumeMounts:    volumeMounts,
-							ReadinessProbe: &corev1.Probe{
-								Handler: corev1.Handler{
-									Exec: &corev1.ExecAction{Command: []string{"mysql", "-u" + d.mysqlUser, "-p" + d.mysqlPassword, "-e", "SELECT 1"}},
-								},
-								InitialDelaySeconds: 5,
-								PeriodSeconds:       2,
-								TimeoutSeconds:      1,
-							},
-							Resources: d.component.Spec.Resources,
-						},
-						{
-							Name:            DBName + "-exporter",
-							Image:           exporterImage,
-							ImagePullPolicy: d.component.ImagePullPolicy(),
-							Env: []corev1.EnvVar{
-								{
-									Name:  "DATA_SOURCE_NAME",
-									Value: fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/", d.mysqlUser, d.mysqlPassword),
-								},
-							},
-						},
-					},
-					Volumes: volumes,
-				},
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       10,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+	readiness := &corev1.Probe{
+		Handler: corev1.Handler{
+			Exec: &corev1.ExecAction{Command: []string{"mysql", "-u" + d.mysqlUser, "-p" + d.mysqlPassword, "-e", "SELECT 1"}},
+		},
+		InitialDelaySeconds: 5,
+		PeriodSeconds:       2,
+		TimeoutSeconds:      1,
+	}
+	exporter := corev1.Container{
+		Name:            DBName + "-exporter",
+		Image:           exporterImage,
+		ImagePullPolicy: d.component.ImagePullPolicy(),
+		Env: []corev1.EnvVar{
+			{
+				Name:  "DATA_SOURCE_NAME",
+				Value: fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/", d.mysqlUser, d.mysqlPassword),
 			},
-			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{*pvc},
 		},
 	}
 
+	sts := buildStatefulSet(DBName, d.component, d.cluster, d.labels, "", claimTemplates,
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(liveness, readiness),
+		// the initialization of the schemas on the first boot takes a few minutes.
+		withStartupProbe(probeutil.MakeStartupProbe(liveness, 10, 30)),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.Tolerations = []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists, // tolerate everything.
+				},
+			}
+			spec.Containers = append(spec.Containers, exporter)
+		}),
+	)
+	sts.Spec.Replicas = commonutil.Int32(1)
 	return sts
 }
 
@@ -408,8 +408,8 @@ skip-name-resolve
 func (d *db) pv() *corev1.PersistentVolume {
 	pv := &corev1.PersistentVolume{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   DBName,
-			Labels: d.labels,
+			Name:   rbdutil.InstanceName(DBName, d.component.Namespace),
+			Labels: rbdutil.LabelsForInstance(d.labels, d.component.Namespace),
 		},
 	}
 
@@ -453,7 +453,7 @@ func (d *db) pvc() *corev1.PersistentVolumeClaim {
 					corev1.ResourceStorage: *size,
 				},
 			},
-			VolumeName:       DBName,
+			VolumeName:       rbdutil.InstanceName(DBName, d.component.Namespace),
 			StorageClassName: commonutil.String("manual"),
 		},
 	}
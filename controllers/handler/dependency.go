@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// waitForDependenciesName is the name of the init container that waits for the dependencies.
+const waitForDependenciesName = "wait-for-dependencies"
+
+// Dependency is a service that the pods of a component wait for before starting.
+type Dependency struct {
+	// Name of the dependency, such as rbd-db.
+	Name string
+	// URL is checked if not empty, the dependency is available once it responds with 2xx.
+	URL string
+	// Addresses in the form of host:port are checked if the URL is empty,
+	// the dependency is available once one of them accepts connections.
+	Addresses []string
+}
+
+func dbDependency(db *rainbondv1alpha1.Database) Dependency {
+	return Dependency{
+		Name:      DBName,
+		Addresses: []string{net.JoinHostPort(db.Host, strconv.Itoa(db.Port))},
+	}
+}
+
+func etcdDependency(cluster *rainbondv1alpha1.RainbondCluster) Dependency {
+	if cluster.Spec.EtcdConfig == nil {
+		return Dependency{
+			Name: EtcdName,
+			URL:  "http://rbd-etcd:2379/health",
+		}
+	}
+	// the health endpoint of the external etcd may require client certificates.
+	dependency := Dependency{Name: EtcdName}
+	for _, endpoint := range etcdEndpoints(cluster) {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		dependency.Addresses = append(dependency.Addresses, u.Host)
+	}
+	return dependency
+}
+
+// hubDependencies returns the dependency of rbd-hub, or nothing if an external image repository is used.
+func hubDependencies(cluster *rainbondv1alpha1.RainbondCluster) []Dependency {
+	if cluster.Spec.ImageHub != nil {
+		return nil
+	}
+	return []Dependency{
+		{
+			Name:      HubName,
+			Addresses: []string{"rbd-hub:5000"},
+		},
+	}
+}
+
+// SetDependencies adds an init container to the pods of the given object, which is a deployment, statefulset
+// or daemonset generated by the handlers, waiting for the dependencies to be available. Other objects,
+// including the jobs, are left unchanged.
+func SetDependencies(obj client.Object, cluster *rainbondv1alpha1.RainbondCluster, dependencies []Dependency) {
+	if len(dependencies) == 0 {
+		return
+	}
+	switch obj.(type) {
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.DaemonSet:
+	default:
+		return
+	}
+	template := podTemplate(obj)
+	if len(template.Spec.Containers) == 0 {
+		return
+	}
+	container := corev1.Container{
+		Name:            waitForDependenciesName,
+		Image:           initContainerImage(cluster),
+		ImagePullPolicy: template.Spec.Containers[0].ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c", waitForDependenciesScript(dependencies)},
+	}
+	// wait for the dependencies before the other init containers.
+	initContainers := []corev1.Container{container}
+	for _, c := range template.Spec.InitContainers {
+		if c.Name == waitForDependenciesName {
+			continue
+		}
+		initContainers = append(initContainers, c)
+	}
+	template.Spec.InitContainers = initContainers
+}
+
+// initContainerImage returns the image with a shell of the init containers.
+func initContainerImage(cluster *rainbondv1alpha1.RainbondCluster) string {
+	if cluster.Spec.InitContainerImage != "" {
+		return cluster.Spec.InitContainerImage
+	}
+	return path.Join(cluster.Spec.RainbondImageRepository, "busybox")
+}
+
+func waitForDependenciesScript(dependencies []Dependency) string {
+	var lines []string
+	for _, dependency := range dependencies {
+		var checks []string
+		if dependency.URL != "" {
+			checks = append(checks, "wget -q -T 2 -O /dev/null "+shellQuote(dependency.URL))
+		}
+		for _, address := range dependency.Addresses {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				continue
+			}
+			checks = append(checks, fmt.Sprintf("nc -z -w 2 %s %s", shellQuote(host), shellQuote(port)))
+		}
+		if len(checks) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("until %s; do echo %s; sleep 2; done",
+			strings.Join(checks, " || "), shellQuote("waiting for "+dependency.Name)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetDependencies(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			RainbondImageRepository: "registry.cn-hangzhou.aliyuncs.com/goodrain",
+			EtcdConfig: &rainbondv1alpha1.EtcdConfig{
+				Endpoints: []string{"https://192.168.0.1:2379", "https://192.168.0.2:2379"},
+			},
+		},
+	}
+	ds := &appsv1.DaemonSet{
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "sysctl"}},
+					Containers:     []corev1.Container{{Name: "rbd-gateway"}},
+				},
+			},
+		},
+	}
+	db := &rainbondv1alpha1.Database{Host: "rbd-db", Port: 3306}
+	SetDependencies(ds, cluster, []Dependency{dbDependency(db), etcdDependency(cluster)})
+
+	initContainers := ds.Spec.Template.Spec.InitContainers
+	assert.Len(t, initContainers, 2)
+	assert.Equal(t, waitForDependenciesName, initContainers[0].Name)
+	assert.Equal(t, "registry.cn-hangzhou.aliyuncs.com/goodrain/busybox", initContainers[0].Image)
+	assert.Equal(t, "until nc -z -w 2 'rbd-db' '3306'; do echo 'waiting for rbd-db'; sleep 2; done\n"+
+		"until nc -z -w 2 '192.168.0.1' '2379' || nc -z -w 2 '192.168.0.2' '2379'; do echo 'waiting for rbd-etcd'; sleep 2; done",
+		initContainers[0].Command[2])
+	assert.Equal(t, "sysctl", initContainers[1].Name)
+}
@@ -1,10 +1,69 @@
 package handler
 
+import (
+	"errors"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
 const (
 	// rainbondVolumeNotFound -
 	rainbondVolumeNotFound = "rainbond volume not found"
 )
 
+// ErrorKind classifies the errors of the handlers, the rbdcomponent controller decides how to retry the
+// reconciliation by it.
+type ErrorKind string
+
+const (
+	// ErrorKindRetryable is a transient error, such as a timeout of the apiserver. The reconciliation is retried
+	// with backoff.
+	ErrorKindRetryable ErrorKind = "Retryable"
+	// ErrorKindPermanent is an error that won't go away by retrying, such as an invalid spec. It is reported in
+	// the conditions, and the reconciliation stops until the rbdcomponent or the rainbondcluster is changed.
+	ErrorKindPermanent ErrorKind = "Permanent"
+	// ErrorKindWaitForDependency means a dependency of the component is not ready yet, such as the storage class.
+	// The reconciliation is retried after a while.
+	ErrorKindWaitForDependency ErrorKind = "WaitForDependency"
+)
+
+// Error is an error of the handlers with its kind.
+type Error struct {
+	kind ErrorKind
+	msg  string
+}
+
+// NewPermanentError creates an error that won't go away by retrying.
+func NewPermanentError(msg string) *Error {
+	return &Error{kind: ErrorKindPermanent, msg: msg}
+}
+
+// NewWaitForDependencyError creates an error that a dependency of the component is not ready yet.
+func NewWaitForDependencyError(msg string) *Error {
+	return &Error{kind: ErrorKindWaitForDependency, msg: msg}
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// ErrorKindOf returns the kind of the given error. The invalid requests rejected by the apiserver are permanent,
+// the IgnoreError is waiting for dependency, and the others are retryable.
+func ErrorKindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.kind
+	}
+	var ignore *IgnoreError
+	if errors.As(err, &ignore) {
+		return ErrorKindWaitForDependency
+	}
+	if k8sErrors.IsInvalid(err) || k8sErrors.IsBadRequest(err) {
+		return ErrorKindPermanent
+	}
+	return ErrorKindRetryable
+}
+
 // IgnoreError is the error with ignore by rbdcomponent controller.
 type IgnoreError struct {
 	msg string
@@ -27,7 +86,7 @@ func IsIgnoreError(err error) bool {
 
 // IsRainbondVolumeNotFound checks if the given error is RainbondVolumeNotFound.
 func IsRainbondVolumeNotFound(e error) bool {
-	err, ok := e.(*IgnoreError)
+	err, ok := e.(*Error)
 	if !ok {
 		return false
 	}
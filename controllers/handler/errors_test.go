@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{
+			name: "invalid spec",
+			err:  NewPermanentError("invalid sysctls"),
+			want: ErrorKindPermanent,
+		},
+		{
+			name: "wrapped invalid spec",
+			err:  fmt.Errorf("check prerequisites: %w", NewPermanentError("invalid sysctls")),
+			want: ErrorKindPermanent,
+		},
+		{
+			name: "storage class not ready",
+			err:  NewWaitForDependencyError("storage class not ready"),
+			want: ErrorKindWaitForDependency,
+		},
+		{
+			name: "ignore error",
+			err:  NewIgnoreError("use custom database"),
+			want: ErrorKindWaitForDependency,
+		},
+		{
+			name: "rejected by the apiserver",
+			err: k8sErrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "rbd-api",
+				field.ErrorList{field.Invalid(field.NewPath("spec", "replicas"), -1, "must be greater than or equal to 0")}),
+			want: ErrorKindPermanent,
+		},
+		{
+			name: "apiserver timeout",
+			err:  k8sErrors.NewServerTimeout(schema.GroupResource{Group: "apps", Resource: "deployments"}, "update", 1),
+			want: ErrorKindRetryable,
+		},
+		{
+			name: "flat error",
+			err:  errors.New("failed to get etcd secret"),
+			want: ErrorKindRetryable,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ErrorKindOf(tc.err))
+		})
+	}
+}
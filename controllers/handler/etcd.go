@@ -10,6 +10,8 @@ import (
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,6 +37,8 @@ var _ ComponentHandler = &etcd{}
 var _ StorageClassRWOer = &etcd{}
 var _ Replicaser = &etcd{}
 var _ ClusterScopedResourcesCreator = &etcd{}
+var _ ResourcesDeleter = &etcd{}
+var _ ConditionReporter = &etcd{}
 
 // NewETCD creates a new rbd-etcd handler.
 func NewETCD(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -72,12 +76,14 @@ func (e *etcd) Resources() []client.Object {
 		return []client.Object{
 			e.statefulsetForEtcdCluster(),
 			e.serviceForEtcd(),
+			e.etcdDefragCronJob(),
 		}
 	}
 
 	return []client.Object{
 		e.statefulsetForEtcd(),
 		e.serviceForEtcd(),
+		e.etcdDefragCronJob(),
 	}
 }
 
@@ -106,129 +112,8 @@ func (e *etcd) CreateClusterScoped() []client.Object {
 	}
 }
 
-func (e *etcd) statefulsetForEtcd() client.Object {
-	env := []corev1.EnvVar{
-		{
-			Name:  "ETCD_QUOTA_BACKEND_BYTES",
-			Value: "4294967296", // 4 Gi
-		},
-	}
-	env = mergeEnvs(env, e.component.Spec.Env)
-
-	pvc := e.pvc()
-	sts := &appsv1.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      EtcdName,
-			Namespace: e.component.Namespace,
-			Labels:    e.labels,
-		},
-		Spec: appsv1.StatefulSetSpec{
-			Replicas:    e.Replicas(),
-			ServiceName: EtcdName,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: e.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      EtcdName,
-					Namespace: e.component.Namespace,
-					Labels:    e.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(e.component, e.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Tolerations: []corev1.Toleration{
-						{
-							Operator: corev1.TolerationOpExists, // tolerate everything.
-						},
-					},
-					Containers: []corev1.Container{
-						{
-							Name:            EtcdName,
-							Image:           e.component.Spec.Image,
-							ImagePullPolicy: e.component.ImagePullPolicy(),
-							Command: []string{
-								"/usr/local/bin/etcd",
-								"--name",
-								EtcdName,
-								"--data-dir",
-								"/var/run/etcd/default.etcd",
-								"--initial-advertise-peer-urls",
-								fmt.Sprintf("http://%s:2380", EtcdName),
-								"--listen-peer-urls",
-								"http://0.0.0.0:2380",
-								"--listen-client-urls",
-								"http://0.0.0.0:2379",
-								"--advertise-client-urls",
-								fmt.Sprintf("http://%s:2379", EtcdName),
-								"--initial-cluster",
-								fmt.Sprintf("%s=http://%s:2380", EtcdName, EtcdName),
-								"--initial-cluster-state",
-								"new",
-								"--auto-compaction-retention",
-								"1",
-							},
-							Env: env,
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "client",
-									ContainerPort: 2379,
-								},
-								{
-									Name:          "server",
-									ContainerPort: 2380,
-								},
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      pvc.GetName(),
-									MountPath: "/var/run/etcd",
-								},
-							},
-							Resources: e.component.Spec.Resources,
-						},
-					},
-				},
-			},
-			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{*pvc},
-		},
-	}
-	return sts
-}
-
-func (e *etcd) statefulsetForEtcdCluster() *appsv1.StatefulSet {
-	claimName := "data"
-	pvc := createPersistentVolumeClaimRWO(e.component.Namespace, claimName, e.pvcParametersRWO, e.labels, e.storageRequest)
-
-	sts := &appsv1.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      EtcdName,
-			Namespace: e.component.Namespace,
-			Labels:    e.labels,
-		},
-		Spec: appsv1.StatefulSetSpec{
-			Replicas:    e.Replicas(),
-			ServiceName: EtcdName,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: e.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      EtcdName,
-					Namespace: e.component.Namespace,
-					Labels:    e.labels,
-				},
-				Spec: corev1.PodSpec{
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Containers: []corev1.Container{
-						{
-							Name:            EtcdName,
-							Image:           e.component.Spec.Image,
-							ImagePullPolicy: e.component.ImagePullPolicy(),
-							Command: []string{
-								"/bin/sh",
-								"-ec",
-								`
+// etcdClusterScript starts a member of the etcd cluster, the members beyond the initial ones are added to the cluster.
+const etcdClusterScript = `
 HOSTNAME=$(hostname)
           echo "etcd api version is ${ETCDAPI_VERSION}"
 
@@ -302,8 +187,7 @@ HOSTNAME=$(hostname)
                   --advertise-client-urls http://${HOSTNAME}.${SET_NAME}.${CLUSTER_NAMESPACE}:2379 \
                   --data-dir /var/run/etcd/default.etcd \
                   --initial-cluster ${ETCD_INITIAL_CLUSTER} \
-				  --initial-cluster-state ${ETCD_INITIAL_CLUSTER_STATE} \
-				  --auto-compaction-retention 1
+				  --initial-cluster-state ${ETCD_INITIAL_CLUSTER_STATE}
           fi
 
           for i in $(seq 0 $((${INITIAL_CLUSTER_SIZE} - 1))); do
@@ -324,51 +208,11 @@ HOSTNAME=$(hostname)
               --initial-cluster-token etcd-cluster-1 \
               --data-dir /var/run/etcd/default.etcd \
               --initial-cluster $(initial_peers) \
-			  --initial-cluster-state new \
-			  --auto-compaction-retention 1
-`,
-							},
-							Env: []corev1.EnvVar{
-								{
-									Name:  "ETCD_QUOTA_BACKEND_BYTES",
-									Value: "4294967296", // 4 Gi
-								},
-								{
-									Name:  "INITIAL_CLUSTER_SIZE",
-									Value: "3",
-								},
-								{
-									Name: "CLUSTER_NAMESPACE",
-									ValueFrom: &corev1.EnvVarSource{
-										FieldRef: &corev1.ObjectFieldSelector{
-											FieldPath: "metadata.namespace",
-										},
-									},
-								},
-								{
-									Name:  "ETCDAPI_VERSION",
-									Value: "3",
-								},
-								{
-									Name:  "ROOT_PASSWORD",
-									Value: "@123#",
-								},
-								{
-									Name:  "SET_NAME",
-									Value: EtcdName,
-								},
-								{
-									Name:  "GOMAXPROCS",
-									Value: "4",
-								},
-							},
-							Lifecycle: &corev1.Lifecycle{
-								PreStop: &corev1.Handler{
-									Exec: &corev1.ExecAction{
-										Command: []string{
-											"/bin/sh",
-											"-ec",
-											`
+			  --initial-cluster-state new
+`
+
+// etcdClusterPreStopScript removes the members beyond the initial ones from the etcd cluster before they are stopped.
+const etcdClusterPreStopScript = `
 HOSTNAME=$(hostname)
 
 member_hash() {
@@ -399,34 +243,123 @@ if [ "${SET_ID}" -ge ${INITIAL_CLUSTER_SIZE} ]; then
 		rm -rf /var/run/etcd/*
 	fi
 fi
-`,
-										},
-									},
-								},
-							},
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "client",
-									ContainerPort: 2379,
-								},
-								{
-									Name:          "server",
-									ContainerPort: 2380,
-								},
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      claimName,
-									MountPath: "/var/run/etcd",
-								},
-							},
-						},
-					},
+`
+
+func etcdPorts() []corev1.ContainerPort {
+	return []corev1.ContainerPort{
+		{
+			Name:          "client",
+			ContainerPort: 2379,
+		},
+		{
+			Name:          "server",
+			ContainerPort: 2380,
+		},
+	}
+}
+
+func (e *etcd) statefulsetForEtcd() client.Object {
+	// the quota and the auto compaction are read from the environment.
+	env := e.maintenanceEnv()
+	pvc := e.pvc()
+	sts := buildStatefulSet(EtcdName, e.component, e.cluster, e.labels, EtcdName, []corev1.PersistentVolumeClaim{*pvc},
+		withCommand(
+			"/usr/local/bin/etcd",
+			"--name",
+			EtcdName,
+			"--data-dir",
+			"/var/run/etcd/default.etcd",
+			"--initial-advertise-peer-urls",
+			fmt.Sprintf("http://%s:2380", EtcdName),
+			"--listen-peer-urls",
+			"http://0.0.0.0:2380",
+			"--listen-client-urls",
+			"http://0.0.0.0:2379",
+			"--advertise-client-urls",
+			fmt.Sprintf("http://%s:2379", EtcdName),
+			"--initial-cluster",
+			fmt.Sprintf("%s=http://%s:2380", EtcdName, EtcdName),
+			"--initial-cluster-state",
+			"new",
+		),
+		withEnv(env...),
+		withPorts(etcdPorts()...),
+		withVolumes(nil, []corev1.VolumeMount{
+			{
+				Name:      pvc.GetName(),
+				MountPath: "/var/run/etcd",
+			},
+		}),
+		withProbes(probeutil.MakeLivenessProbeTCP("", 2379), nil),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.Tolerations = []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists, // tolerate everything.
+				},
+			}
+		}),
+	)
+	sts.Spec.Replicas = e.Replicas()
+	return sts
+}
+
+func (e *etcd) statefulsetForEtcdCluster() *appsv1.StatefulSet {
+	claimName := "data"
+	pvc := createPersistentVolumeClaimRWO(e.component.Namespace, claimName, e.pvcParametersRWO, e.labels, e.storageRequest)
+
+	env := append(e.maintenanceEnv(), []corev1.EnvVar{
+		{
+			Name:  "INITIAL_CLUSTER_SIZE",
+			Value: "3",
+		},
+		{
+			Name: "CLUSTER_NAMESPACE",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.namespace",
 				},
 			},
-			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{*pvc},
+		},
+		{
+			Name:  "ETCDAPI_VERSION",
+			Value: "3",
+		},
+		{
+			Name:  "ROOT_PASSWORD",
+			Value: "@123#",
+		},
+		{
+			Name:  "SET_NAME",
+			Value: EtcdName,
+		},
+		{
+			Name:  "GOMAXPROCS",
+			Value: "4",
+		},
+	}...)
+	preStop := &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "-ec", etcdClusterPreStopScript},
+			},
 		},
 	}
+	sts := buildStatefulSet(EtcdName, e.component, e.cluster, e.labels, EtcdName, []corev1.PersistentVolumeClaim{*pvc},
+		withCommand("/bin/sh", "-ec", etcdClusterScript),
+		withEnv(env...),
+		withPorts(etcdPorts()...),
+		withVolumes(nil, []corev1.VolumeMount{
+			{
+				Name:      claimName,
+				MountPath: "/var/run/etcd",
+			},
+		}),
+		// no readiness probe, the members find each other through the headless service,
+		// which only resolves the ready pods.
+		withProbes(probeutil.MakeLivenessProbeTCP("", 2379), nil),
+		withLifecycle(preStop),
+	)
+	sts.Spec.Replicas = e.Replicas()
 	return sts
 }
 
@@ -459,8 +392,8 @@ func (e *etcd) serviceForEtcd() client.Object {
 func (e *etcd) pv() *corev1.PersistentVolume {
 	pv := &corev1.PersistentVolume{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   EtcdName,
-			Labels: e.labels,
+			Name:   rbdutil.InstanceName(EtcdName, e.component.Namespace),
+			Labels: rbdutil.LabelsForInstance(e.labels, e.component.Namespace),
 		},
 	}
 
@@ -503,7 +436,7 @@ func (e *etcd) pvc() *corev1.PersistentVolumeClaim {
 					corev1.ResourceStorage: *size,
 				},
 			},
-			VolumeName:       EtcdName,
+			VolumeName:       rbdutil.InstanceName(EtcdName, e.component.Namespace),
 			StorageClassName: commonutil.String("manual"),
 		},
 	}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EtcdDefragName is the name of the CronJob that defragments the members of rbd-etcd.
+var EtcdDefragName = "rbd-etcd-defrag"
+
+const (
+	// defEtcdQuotaBackendBytes is the quota of the database of etcd by default, 4Gi.
+	defEtcdQuotaBackendBytes = 4 * 1024 * 1024 * 1024
+	// defEtcdCompactionRetention keeps the revisions of the latest hour by default.
+	defEtcdCompactionRetention = "1"
+	// defEtcdDefragSchedule is the default schedule of the defragmentation.
+	defEtcdDefragSchedule = "0 3 * * *"
+	// defEtcdDefragHistoryLimit is the number of the finished jobs kept by default.
+	defEtcdDefragHistoryLimit = 3
+	// etcdSpaceWarningPercent is how much of the quota the database takes before EtcdSpaceAvailable turns false.
+	etcdSpaceWarningPercent = 80
+)
+
+// etcdDBSizeMetrics are the metrics of the size of the database, the latter is the one before etcd 3.4.
+var etcdDBSizeMetrics = []string{"etcd_mvcc_db_total_size_in_bytes", "etcd_debugging_mvcc_db_total_size_in_bytes"}
+
+// etcdDefragScript defragments the members one by one, then clears the NOSPACE alarm raised when the quota was
+// exceeded, which keeps etcd read-only until it is cleared.
+const etcdDefragScript = `etcdctl --endpoints=${ETCD_ENDPOINTS} --command-timeout=60s defrag
+etcdctl --endpoints=${ETCD_ENDPOINTS} alarm disarm
+`
+
+var etcdMetricsClient = &http.Client{Timeout: 5 * time.Second}
+
+func (e *etcd) maintenance() rainbondv1alpha1.EtcdMaintenance {
+	if e.cluster.Spec.EtcdMaintenance == nil {
+		return rainbondv1alpha1.EtcdMaintenance{}
+	}
+	return *e.cluster.Spec.EtcdMaintenance
+}
+
+func (e *etcd) quotaBackendBytes() int64 {
+	if quota := e.maintenance().QuotaBackendBytes; quota != nil && quota.Value() > 0 {
+		return quota.Value()
+	}
+	return defEtcdQuotaBackendBytes
+}
+
+// maintenanceEnv returns the environment variables of the quota and the auto compaction, which are read by etcd
+// as its flags.
+func (e *etcd) maintenanceEnv() []corev1.EnvVar {
+	m := e.maintenance()
+	mode := m.AutoCompactionMode
+	if mode == "" {
+		mode = rainbondv1alpha1.EtcdCompactionPeriodic
+	}
+	retention := m.AutoCompactionRetention
+	if retention == "" {
+		retention = defEtcdCompactionRetention
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  "ETCD_QUOTA_BACKEND_BYTES",
+			Value: strconv.FormatInt(e.quotaBackendBytes(), 10),
+		},
+		{
+			Name:  "ETCD_AUTO_COMPACTION_MODE",
+			Value: string(mode),
+		},
+		{
+			Name:  "ETCD_AUTO_COMPACTION_RETENTION",
+			Value: retention,
+		},
+	}
+}
+
+// memberEndpoints returns the client urls of the members of rbd-etcd, which are resolved by the headless service.
+func (e *etcd) memberEndpoints() []string {
+	var endpoints []string
+	for i := 0; i < int(*e.Replicas()); i++ {
+		endpoints = append(endpoints, fmt.Sprintf("http://%s-%d.%s.%s:2379", EtcdName, i, EtcdName, e.component.Namespace))
+	}
+	return endpoints
+}
+
+// etcdDefragCronJob returns the CronJob of the defragmentation, or nil if it is disabled.
+func (e *etcd) etcdDefragCronJob() client.Object {
+	defrag := e.maintenance().Defragmentation
+	if defrag == nil {
+		return nil
+	}
+	schedule := defrag.Schedule
+	if schedule == "" {
+		schedule = defEtcdDefragSchedule
+	}
+	historyLimit := int32(defEtcdDefragHistoryLimit)
+	if defrag.HistoryLimit != nil {
+		historyLimit = *defrag.HistoryLimit
+	}
+
+	labels := copyLabels(e.labels)
+	delete(labels, "etcd_node")
+	labels["name"] = EtcdDefragName
+
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      EtcdDefragName,
+			Namespace: e.component.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: commonutil.Int32(historyLimit),
+			FailedJobsHistoryLimit:     commonutil.Int32(historyLimit),
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: commonutil.Int32(1),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: corev1.PodSpec{
+							RestartPolicy:                 corev1.RestartPolicyNever,
+							ImagePullSecrets:              imagePullSecrets(e.component, e.cluster),
+							TerminationGracePeriodSeconds: commonutil.Int64(0),
+							Containers: []corev1.Container{
+								{
+									Name:            EtcdDefragName,
+									Image:           e.component.Spec.Image,
+									ImagePullPolicy: e.component.ImagePullPolicy(),
+									Command:         []string{"/bin/sh", "-ec", etcdDefragScript},
+									Env: []corev1.EnvVar{
+										{
+											Name:  "ETCDCTL_API",
+											Value: "3",
+										},
+										{
+											Name:  "ETCD_ENDPOINTS",
+											Value: strings.Join(e.memberEndpoints(), ","),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ResourcesNeedDelete returns the CronJob of the defragmentation once it is disabled.
+func (e *etcd) ResourcesNeedDelete() []client.Object {
+	if e.maintenance().Defragmentation != nil {
+		return nil
+	}
+	return []client.Object{
+		&batchv1beta1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      EtcdDefragName,
+				Namespace: e.component.Namespace,
+			},
+		},
+	}
+}
+
+// Conditions returns whether the databases of the members of rbd-etcd are well below the quota, the sizes are read
+// from the metrics of the members.
+func (e *etcd) Conditions() []*rainbondv1alpha1.RbdComponentCondition {
+	sizes := make(map[string]int64)
+	var failures []string
+	for _, endpoint := range e.memberEndpoints() {
+		size, err := etcdDBSize(e.ctx, endpoint)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		sizes[endpoint] = size
+	}
+	return []*rainbondv1alpha1.RbdComponentCondition{etcdSpaceCondition(sizes, failures, e.quotaBackendBytes())}
+}
+
+// etcdSpaceCondition returns the condition of the space of etcd from the sizes of the databases of the members.
+func etcdSpaceCondition(sizes map[string]int64, failures []string, quota int64) *rainbondv1alpha1.RbdComponentCondition {
+	var approaching []string
+	for endpoint, size := range sizes {
+		if size*100 >= quota*etcdSpaceWarningPercent {
+			approaching = append(approaching, fmt.Sprintf("%s takes %s", endpoint, resource.NewQuantity(size, resource.BinarySI)))
+		}
+	}
+	sort.Strings(approaching)
+
+	switch {
+	case len(approaching) > 0:
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.EtcdSpaceAvailable, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonEtcdQuotaApproaching, fmt.Sprintf("the database of %s of the quota %s, defragment it or raise the quota",
+				strings.Join(approaching, ", "), resource.NewQuantity(quota, resource.BinarySI)))
+	case len(failures) > 0:
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.EtcdSpaceAvailable, corev1.ConditionUnknown,
+			rainbondv1alpha1.ReasonEtcdStatusUnavailable, strings.Join(failures, "; "))
+	}
+	return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.EtcdSpaceAvailable, corev1.ConditionTrue, "", "")
+}
+
+// etcdDBSize reads the size of the database from the metrics of the member.
+func etcdDBSize(ctx context.Context, endpoint string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/metrics", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := etcdMetricsClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %d from %s/metrics", resp.StatusCode, endpoint)
+	}
+	size, err := parseEtcdDBSize(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", endpoint, err)
+	}
+	return size, nil
+}
+
+// parseEtcdDBSize finds the size of the database in the metrics of etcd, in the prometheus text format.
+func parseEtcdDBSize(r io.Reader) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		for _, metric := range etcdDBSizeMetrics {
+			if fields[0] != metric {
+				continue
+			}
+			size, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse %s: %v", metric, err)
+			}
+			return int64(size), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no %s in the metrics", etcdDBSizeMetrics[0])
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEtcdMaintenanceEnv(t *testing.T) {
+	quota := resource.MustParse("8Gi")
+	tests := []struct {
+		name        string
+		maintenance *rainbondv1alpha1.EtcdMaintenance
+		want        map[string]string
+	}{
+		{
+			name: "default",
+			want: map[string]string{
+				"ETCD_QUOTA_BACKEND_BYTES":       "4294967296",
+				"ETCD_AUTO_COMPACTION_MODE":      "periodic",
+				"ETCD_AUTO_COMPACTION_RETENTION": "1",
+			},
+		},
+		{
+			name: "revision",
+			maintenance: &rainbondv1alpha1.EtcdMaintenance{
+				AutoCompactionMode:      rainbondv1alpha1.EtcdCompactionRevision,
+				AutoCompactionRetention: "10000",
+				QuotaBackendBytes:       &quota,
+			},
+			want: map[string]string{
+				"ETCD_QUOTA_BACKEND_BYTES":       "8589934592",
+				"ETCD_AUTO_COMPACTION_MODE":      "revision",
+				"ETCD_AUTO_COMPACTION_RETENTION": "10000",
+			},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			e := &etcd{
+				cluster: &rainbondv1alpha1.RainbondCluster{
+					Spec: rainbondv1alpha1.RainbondClusterSpec{EtcdMaintenance: tc.maintenance},
+				},
+			}
+			got := make(map[string]string)
+			for _, env := range e.maintenanceEnv() {
+				got[env.Name] = env.Value
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEtcdDefragCronJob(t *testing.T) {
+	e := &etcd{
+		component: &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: EtcdName, Namespace: "rbd-system"},
+			Spec:       rainbondv1alpha1.RbdComponentSpec{Image: "rainbond/etcd:v3.3.18"},
+		},
+		cluster: &rainbondv1alpha1.RainbondCluster{
+			Spec: rainbondv1alpha1.RainbondClusterSpec{EnableHA: true},
+		},
+		labels: map[string]string{"name": EtcdName, "etcd_node": EtcdName},
+	}
+	assert.Nil(t, e.etcdDefragCronJob())
+	assert.Len(t, e.ResourcesNeedDelete(), 1)
+
+	e.cluster.Spec.EtcdMaintenance = &rainbondv1alpha1.EtcdMaintenance{
+		Defragmentation: &rainbondv1alpha1.EtcdDefragmentation{},
+	}
+	assert.Empty(t, e.ResourcesNeedDelete())
+	cronJob := e.etcdDefragCronJob().(*batchv1beta1.CronJob)
+	assert.Equal(t, defEtcdDefragSchedule, cronJob.Spec.Schedule)
+	assert.Equal(t, int32(defEtcdDefragHistoryLimit), *cronJob.Spec.SuccessfulJobsHistoryLimit)
+	// the pods of the job are not taken as the members of rbd-etcd.
+	labels := cronJob.Spec.JobTemplate.Spec.Template.Labels
+	assert.Equal(t, EtcdDefragName, labels["name"])
+	assert.NotContains(t, labels, "etcd_node")
+	container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "rainbond/etcd:v3.3.18", container.Image)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "ETCDCTL_API", Value: "3"},
+		{Name: "ETCD_ENDPOINTS", Value: "http://rbd-etcd-0.rbd-etcd.rbd-system:2379,http://rbd-etcd-1.rbd-etcd.rbd-system:2379,http://rbd-etcd-2.rbd-etcd.rbd-system:2379"},
+	}, container.Env)
+}
+
+func TestEtcdSpaceCondition(t *testing.T) {
+	const gi = 1024 * 1024 * 1024
+	tests := []struct {
+		name       string
+		sizes      map[string]int64
+		failures   []string
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "available",
+			sizes:      map[string]int64{"rbd-etcd-0": gi},
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:       "approaching",
+			sizes:      map[string]int64{"rbd-etcd-0": gi, "rbd-etcd-1": 3.5 * gi},
+			failures:   []string{"rbd-etcd-2: connection refused"},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonEtcdQuotaApproaching,
+		},
+		{
+			name:       "unavailable",
+			sizes:      map[string]int64{"rbd-etcd-0": gi},
+			failures:   []string{"rbd-etcd-1: connection refused"},
+			wantStatus: corev1.ConditionUnknown,
+			wantReason: rainbondv1alpha1.ReasonEtcdStatusUnavailable,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			condition := etcdSpaceCondition(tc.sizes, tc.failures, 4*gi)
+			assert.Equal(t, rainbondv1alpha1.EtcdSpaceAvailable, condition.Type)
+			assert.Equal(t, tc.wantStatus, condition.Status)
+			assert.Equal(t, tc.wantReason, condition.Reason)
+		})
+	}
+}
+
+func TestParseEtcdDBSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics string
+		want    int64
+		wantErr bool
+	}{
+		{
+			name: "etcd 3.4",
+			metrics: `# HELP etcd_mvcc_db_total_size_in_bytes Total size of the underlying database physically allocated in bytes.
+# TYPE etcd_mvcc_db_total_size_in_bytes gauge
+etcd_mvcc_db_total_size_in_bytes 2.097152e+07
+etcd_mvcc_db_total_size_in_use_in_bytes 1.6384e+04
+`,
+			want: 20971520,
+		},
+		{
+			name:    "etcd 3.3",
+			metrics: "etcd_debugging_mvcc_db_total_size_in_bytes 16384\n",
+			want:    16384,
+		},
+		{
+			name:    "missing",
+			metrics: "etcd_server_has_leader 1\n",
+			wantErr: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseEtcdDBSize(strings.NewReader(tc.metrics))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -3,47 +3,63 @@ package handler
 import (
 	"context"
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
-	"github.com/goodrain/rainbond-operator/util/commonutil"
-	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // EventLogName name for rbd-eventlog.
 var EventLogName = "rbd-eventlog"
 
+const (
+	// eventlogClusterName is the name of the headless service that gives the replicas of rbd-eventlog stable network identities.
+	eventlogClusterName = "rbd-eventlog-cluster"
+	// eventlogClusterPort is the port on which the replicas of rbd-eventlog communicate with each other.
+	eventlogClusterPort = 6365
+)
+
 type eventlog struct {
-	ctx              context.Context
-	client           client.Client
-	component        *rainbondv1alpha1.RbdComponent
-	cluster          *rainbondv1alpha1.RainbondCluster
-	labels           map[string]string
-	db               *rainbondv1alpha1.Database
-	pvcParametersRWX *pvcParameters
-	storageRequest   int64
+	ctx                context.Context
+	client             client.Client
+	component          *rainbondv1alpha1.RbdComponent
+	cluster            *rainbondv1alpha1.RainbondCluster
+	labels             map[string]string
+	db                 *rainbondv1alpha1.Database
+	etcdSecret         *corev1.Secret
+	pvcParametersRWX   *pvcParameters
+	pvcParametersRWO   *pvcParameters
+	storageRequest     int64
+	dataStorageRequest int64
 }
 
 var _ ComponentHandler = &eventlog{}
 var _ StorageClassRWXer = &eventlog{}
+var _ StorageClassRWOer = &eventlog{}
 var _ ResourcesCreator = &eventlog{}
 var _ ResourcesDeleter = &eventlog{}
+var _ HealthChecker = &eventlog{}
 
 // NewEventLog creates a new rbd-eventlog handler.
 func NewEventLog(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
 	return &eventlog{
-		ctx:            ctx,
-		client:         client,
-		component:      component,
-		cluster:        cluster,
-		labels:         LabelsForRainbondComponent(component),
-		storageRequest: getStorageRequest("GRDATA_STORAGE_REQUEST", 40),
+		ctx:                ctx,
+		client:             client,
+		component:          component,
+		cluster:            cluster,
+		labels:             LabelsForRainbondComponent(component),
+		storageRequest:     getStorageRequest("GRDATA_STORAGE_REQUEST", 40),
+		dataStorageRequest: getStorageRequest("EVENTLOG_DATA_STORAGE_REQUEST", 10),
 	}
 }
 
@@ -57,10 +73,22 @@ func (e *eventlog) Before() error {
 	}
 	e.db = db
 
+	secret, err := etcdSecret(e.ctx, e.client, e.cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get etcd secret: %v", err)
+	}
+	e.etcdSecret = secret
+
 	if err := setStorageCassName(e.ctx, e.client, e.component.Namespace, e); err != nil {
 		return err
 	}
 
+	if e.clusterMode() {
+		if err := e.recreateStatefulSetForCluster(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -68,6 +96,7 @@ func (e *eventlog) Resources() []client.Object {
 	return []client.Object{
 		e.statefulset(),
 		e.service(),
+		e.clusterService(),
 	}
 }
 
@@ -79,14 +108,28 @@ func (e *eventlog) ListPods() ([]corev1.Pod, error) {
 	return listPods(e.ctx, e.client, e.component.Namespace, e.labels)
 }
 
+func (e *eventlog) Dependencies() []Dependency {
+	return []Dependency{dbDependency(e.db), etcdDependency(e.cluster)}
+}
+
+// HealthEndpoint returns the health endpoint of rbd-eventlog served by the websocket server, the readiness probe
+// only checks its port.
+func (e *eventlog) HealthEndpoint() HealthEndpoint {
+	return HealthEndpoint{Port: 6363, Path: "/health"}
+}
+
 func (e *eventlog) SetStorageClassNameRWX(pvcParameters *pvcParameters) {
 	e.pvcParametersRWX = pvcParameters
 }
 
+func (e *eventlog) SetStorageClassNameRWO(pvcParameters *pvcParameters) {
+	e.pvcParametersRWO = pvcParameters
+}
+
 func (e *eventlog) ResourcesCreateIfNotExists() []client.Object {
 	return []client.Object{
 		// pvc is immutable after creation except resources.requests for bound claims
-		createPersistentVolumeClaimRWX(e.component.Namespace, constants.GrDataPVC, e.pvcParametersRWX, e.labels),
+		grdataPVC(e.cluster, e.component.Namespace, e.pvcParametersRWX, e.labels),
 	}
 }
 
@@ -104,32 +147,48 @@ func (e *eventlog) ResourcesNeedDelete() []client.Object {
 	}
 }
 
-func (e *eventlog) service() client.Object {
+// clusterMode returns true if there are more than one replicas, which register themselves in etcd and discover
+// each other from it.
+func (e *eventlog) clusterMode() bool {
+	return e.component.Spec.Replicas != nil && *e.component.Spec.Replicas >= 2
+}
+
+// recreateStatefulSetForCluster deletes the statefulset created without the headless service, whose service
+// name is immutable, so that it will be recreated with the stable network identities. The pods are orphaned
+// and adopted by the new statefulset.
+func (e *eventlog) recreateStatefulSetForCluster() error {
+	sts := &appsv1.StatefulSet{}
+	if err := e.client.Get(e.ctx, types.NamespacedName{Namespace: e.component.Namespace, Name: EventLogName}, sts); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("get statefulset %s: %v", EventLogName, err)
+	}
+	if sts.Spec.ServiceName == eventlogClusterName {
+		return nil
+	}
+	log.Info("recreate statefulset for the cluster mode", "name", EventLogName)
+	if err := e.client.Delete(e.ctx, sts, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil && !k8sErrors.IsNotFound(err) {
+		return fmt.Errorf("delete statefulset %s: %v", EventLogName, err)
+	}
+	return nil
+}
+
+func (e *eventlog) clusterService() client.Object {
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      EventLogName,
+			Name:      eventlogClusterName,
 			Namespace: e.component.Namespace,
 			Labels:    e.labels,
 		},
 		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			// the peers need to find each other before they are ready.
+			PublishNotReadyAddresses: true,
 			Ports: []corev1.ServicePort{
 				{
-					Name: "websocket",
-					Port: 6363,
-				},
-				{
-					Name: "dockerlog",
-					Port: 6362,
-				},
-				{
-					Name:     "monitorlog",
-					Port:     6166,
-					Protocol: corev1.ProtocolUDP,
-				},
-				{
-					Name:     "grpc",
-					Port:     6366,
-					Protocol: corev1.ProtocolTCP,
+					Name: "cluster",
+					Port: eventlogClusterPort,
 				},
 			},
 			Selector: e.labels,
@@ -137,6 +196,29 @@ func (e *eventlog) service() client.Object {
 	}
 }
 
+func (e *eventlog) service() client.Object {
+	return buildService(EventLogName, e.component.Namespace, e.labels,
+		corev1.ServicePort{
+			Name: "websocket",
+			Port: 6363,
+		},
+		corev1.ServicePort{
+			Name: "dockerlog",
+			Port: 6362,
+		},
+		corev1.ServicePort{
+			Name:     "monitorlog",
+			Port:     6166,
+			Protocol: corev1.ProtocolUDP,
+		},
+		corev1.ServicePort{
+			Name:     "grpc",
+			Port:     6366,
+			Protocol: corev1.ProtocolTCP,
+		},
+	)
+}
+
 func (e *eventlog) statefulset() client.Object {
 	args := []string{
 		"--cluster.bind.ip=$(POD_IP)",
@@ -144,23 +226,33 @@ func (e *eventlog) statefulset() client.Object {
 		"--eventlog.bind.ip=$(POD_IP)",
 		"--websocket.bind.ip=$(POD_IP)",
 		"--db.url=" + strings.Replace(e.db.RegionDataSource(), "--mysql=", "", 1),
+		"--discover.etcd.addr=" + strings.Join(etcdEndpoints(e.cluster), ","),
 	}
 
-	volumeMounts := []corev1.VolumeMount{
-		{
-			Name:      "grdata",
-			MountPath: "/grdata",
-		},
-	}
+	grdataVolume, grdataMounts := volumeByGrdata(e.cluster, e.component)
+	volumeMounts := grdataMounts
 	volumes := []corev1.Volume{
-		{
-			Name: "grdata",
-			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: constants.GrDataPVC,
-				},
-			},
-		},
+		grdataVolume,
+	}
+	if e.etcdSecret != nil {
+		volume, mount := volumeByEtcd(e.etcdSecret)
+		volumeMounts = append(volumeMounts, mount)
+		volumes = append(volumes, volume)
+		args = append(args,
+			"--discover.etcd.ca="+path.Join(EtcdSSLPath, "ca-file"),
+			"--discover.etcd.cert="+path.Join(EtcdSSLPath, "cert-file"),
+			"--discover.etcd.key="+path.Join(EtcdSSLPath, "key-file"),
+		)
+	}
+	var claimTemplates []corev1.PersistentVolumeClaim
+	if e.component.Spec.DataVolume != nil {
+		// the logs of each replica are kept in its own volume instead of the shared storage.
+		claimName := "data"
+		claimTemplates = append(claimTemplates, *dataPVC(e.component, claimName, e.pvcParametersRWO, e.labels, e.dataStorageRequest))
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      claimName,
+			MountPath: path.Join(grdataMountPath(e.cluster), "logs"),
+		})
 	}
 
 	env := []corev1.EnvVar{
@@ -186,54 +278,16 @@ func (e *eventlog) statefulset() client.Object {
 		},
 		{
 			Name:  "DOCKER_LOG_SAVE_DAY",
-			Value: "7",
-		},
-	}
-
-	env = mergeEnvs(env, e.component.Spec.Env)
-	volumeMounts = mergeVolumeMounts(volumeMounts, e.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, e.component.Spec.Volumes)
-	args = mergeArgs(args, e.component.Spec.Args)
-
-	// prepare probe
-	readinessProbe := probeutil.MakeReadinessProbeTCP("", 6363)
-	sts := &appsv1.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      EventLogName,
-			Namespace: e.component.Namespace,
-			Labels:    e.labels,
-		},
-		Spec: appsv1.StatefulSetSpec{
-			Replicas: e.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: e.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   EventLogName,
-					Labels: e.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(e.component, e.cluster),
-					ServiceAccountName:            "rainbond-operator",
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Containers: []corev1.Container{
-						{
-							Name:            EventLogName,
-							Image:           e.component.Spec.Image,
-							ImagePullPolicy: e.component.ImagePullPolicy(),
-							Env:             env,
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							ReadinessProbe:  readinessProbe,
-							Resources:       e.component.Spec.Resources,
-						},
-					},
-					Volumes: volumes,
-				},
-			},
+			Value: strconv.Itoa(int(retentionDays(e.component))),
 		},
 	}
 
-	return sts
+	return buildStatefulSet(EventLogName, e.component, e.cluster, e.labels, eventlogClusterName, claimTemplates,
+		withArgs(args...),
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(probeutil.MakeLivenessProbeTCP("", 6363), probeutil.MakeReadinessProbeTCP("", 6363)),
+		withAffinity(affinityForSpreadingPods(e.labels, e.cluster.Spec.EnableHA)),
+		withServiceAccount(rbdutil.ServiceAccountName()),
+	)
 }
@@ -2,24 +2,52 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // GatewayName name for rbd-gateway.
 var GatewayName = "rbd-gateway"
 
+const (
+	// gatewayDefaultCertPath is the path where the default certificate of rbd-gateway is mounted.
+	gatewayDefaultCertPath = "/run/ssl/default"
+	// gatewayLogPath is the path where the log files of rbd-gateway are stored.
+	gatewayLogPath = "/var/log/rbd-gateway"
+	// defGatewayDrainSeconds is how long rbd-gateway keeps serving after it is asked to stop by default.
+	defGatewayDrainSeconds = 15
+	// defGatewayMinReadySeconds is how long a new rbd-gateway must be ready during a rollout by default.
+	defGatewayMinReadySeconds = 10
+	// gatewayRolloutTimeout is how long the changes are deferred for a rollout that does not make progress.
+	gatewayRolloutTimeout = 10 * time.Minute
+)
+
 type gateway struct {
 	ctx        context.Context
 	client     client.Client
 	etcdSecret *corev1.Secret
+	certSecret *corev1.Secret
+	// rollingOut is the daemonset being rolled out, the changes are deferred until the rollout is completed.
+	rollingOut *appsv1.DaemonSet
 
 	component *rainbondv1alpha1.RbdComponent
 	cluster   *rainbondv1alpha1.RainbondCluster
@@ -28,6 +56,8 @@ type gateway struct {
 
 var _ ComponentHandler = &gateway{}
 var _ Replicaser = &gateway{}
+var _ ClusterScopedResourcesCreator = &gateway{}
+var _ ResourcesDeleter = &gateway{}
 
 // NewGateway returns a new rbd-gateway handler.
 func NewGateway(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -41,25 +71,73 @@ func NewGateway(ctx context.Context, client client.Client, component *rainbondv1
 }
 
 func (g *gateway) Before() error {
+	if err := hostPortsConflict(g.cluster); err != nil {
+		return err
+	}
 	secret, err := etcdSecret(g.ctx, g.client, g.cluster)
 	if err != nil {
 		return fmt.Errorf("failed to get etcd secret: %v", err)
 	}
 	g.etcdSecret = secret
 
-	if err := isEtcdAvailable(g.ctx, g.client, g.component, g.cluster); err != nil {
-		return fmt.Errorf("etcd not available: %v", err)
+	if g.cluster.Spec.Gateway != nil {
+		if err := rbdutil.ValidateSysctls(g.cluster.Spec.Gateway.Sysctls); err != nil {
+			return NewPermanentError(fmt.Sprintf("invalid sysctls: %v", err))
+		}
+		if drain := g.cluster.Spec.Gateway.DrainSeconds; drain != nil && *drain < 0 {
+			return NewPermanentError(fmt.Sprintf("invalid drain seconds %d: must not be negative", *drain))
+		}
+		if err := validateUDPPorts(g.cluster.Spec.Gateway.UDPPorts); err != nil {
+			return NewPermanentError(fmt.Sprintf("invalid udp ports: %v", err))
+		}
+		if err := rbdutil.ValidatePortRanges(g.cluster.Spec.Gateway.PortRanges); err != nil {
+			return NewPermanentError(fmt.Sprintf("invalid port ranges: %v", err))
+		}
+		if name := g.cluster.Spec.Gateway.DefaultCertificate; name != "" {
+			secret, err := g.defaultCertificate(name)
+			if err != nil {
+				return err
+			}
+			g.certSecret = secret
+		}
 	}
 
+	rollingOut, err := g.rolloutInProgress()
+	if err != nil {
+		return err
+	}
+	g.rollingOut = rollingOut
+
 	return nil
 }
 
 func (g *gateway) Resources() []client.Object {
+	resources := append(permissionResources(g.component, g.labels), g.portRangesService(), g.portRangesNetworkPolicy())
+	if g.rollingOut != nil {
+		// restarting the gateways that have been replaced would drop the traffic again.
+		log.Info("defer the changes of rbd-gateway until the rollout is completed")
+		return append(resources, g.rollingOut)
+	}
+	return append(resources, g.daemonset())
+}
+
+// ResourcesNeedDelete returns the Service and the NetworkPolicy of the port ranges once the port ranges are removed.
+func (g *gateway) ResourcesNeedDelete() []client.Object {
+	if len(g.portRanges()) > 0 {
+		return nil
+	}
+	meta := metav1.ObjectMeta{Name: GatewayName, Namespace: g.component.Namespace}
 	return []client.Object{
-		g.daemonset(),
+		&corev1.Service{ObjectMeta: meta},
+		&networkingv1.NetworkPolicy{ObjectMeta: meta},
 	}
 }
 
+// CreateClusterScoped returns the cluster role of rbd-gateway, which watches the ingresses of the tenants.
+func (g *gateway) CreateClusterScoped() []client.Object {
+	return clusterPermissionResources(g.component, g.labels)
+}
+
 func (g *gateway) After() error {
 	return nil
 }
@@ -68,19 +146,74 @@ func (g *gateway) ListPods() ([]corev1.Pod, error) {
 	return listPods(g.ctx, g.client, g.component.Namespace, g.labels)
 }
 
+func (g *gateway) Dependencies() []Dependency {
+	return []Dependency{etcdDependency(g.cluster)}
+}
+
 func (g *gateway) Replicas() *int32 {
 	return commonutil.Int32(int32(len(g.cluster.Spec.NodesForGateway)))
 }
 
+// rolloutInProgress returns the daemonset of rbd-gateway if it is being rolled out. A rollout is
+// considered stuck and won't be waited for if a new pod has not been ready for gatewayRolloutTimeout,
+// so that it can be fixed by new changes.
+func (g *gateway) rolloutInProgress() (*appsv1.DaemonSet, error) {
+	ds := &appsv1.DaemonSet{}
+	if err := g.client.Get(g.ctx, types.NamespacedName{Namespace: g.component.Namespace, Name: GatewayName}, ds); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get daemonset %s: %v", GatewayName, err)
+	}
+	status := ds.Status
+	if status.ObservedGeneration < ds.Generation {
+		return ds, nil
+	}
+	if status.UpdatedNumberScheduled >= status.DesiredNumberScheduled && status.NumberUnavailable == 0 {
+		return nil, nil
+	}
+
+	pods, err := g.ListPods()
+	if err != nil {
+		return nil, fmt.Errorf("list pods of %s: %v", GatewayName, err)
+	}
+	generation := strconv.FormatInt(ds.Generation, 10)
+	for i := range pods {
+		pod := &pods[i]
+		// the pods of the daemonset are labeled with the generation of the template they are created from.
+		if pod.Labels["pod-template-generation"] != generation || k8sutil.IsPodReady(pod) {
+			continue
+		}
+		if time.Since(pod.CreationTimestamp.Time) > gatewayRolloutTimeout {
+			return nil, nil
+		}
+	}
+	return ds, nil
+}
+
 func (g *gateway) daemonset() client.Object {
+	var drainSeconds, minReadySeconds int32 = defGatewayDrainSeconds, defGatewayMinReadySeconds
+	if g.cluster.Spec.Gateway != nil {
+		if g.cluster.Spec.Gateway.DrainSeconds != nil {
+			drainSeconds = *g.cluster.Spec.Gateway.DrainSeconds
+		}
+		if g.cluster.Spec.Gateway.MinReadySeconds != nil {
+			minReadySeconds = *g.cluster.Spec.Gateway.MinReadySeconds
+		}
+	}
+
 	args := []string{
-		"--error-log=/dev/stderr",
-		"--errlog-level=error",
 		"--etcd-endpoints=" + strings.Join(etcdEndpoints(g.cluster), ","),
 	}
+	logArgs, logVolume, logMount := g.logSettings()
+	args = append(args, logArgs...)
 
 	var volumeMounts []corev1.VolumeMount
 	var volumes []corev1.Volume
+	if logVolume != nil {
+		volumeMounts = append(volumeMounts, *logMount)
+		volumes = append(volumes, *logVolume)
+	}
 	if g.etcdSecret != nil {
 		volume, mount := volumeByEtcd(g.etcdSecret)
 		volumeMounts = append(volumeMounts, mount)
@@ -101,56 +234,287 @@ func (g *gateway) daemonset() client.Object {
 		return nil
 	}
 
-	// merge attributes
-	volumeMounts = mergeVolumeMounts(volumeMounts, g.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, g.component.Spec.Volumes)
-	args = mergeArgs(args, g.component.Spec.Args)
+	var annotations map[string]string
+	if g.certSecret != nil {
+		volume, mount, checksum := g.defaultCertificateVolume()
+		volumes = append(volumes, volume)
+		volumeMounts = append(volumeMounts, mount)
+		// restart rbd-gateway to reload the certificate once it changes.
+		annotations = map[string]string{"rainbond.io/default-certificate-checksum": checksum}
+	}
+
+	ds := buildDaemonSet(GatewayName, g.component, g.cluster, g.labels,
+		withPodAnnotations(annotations),
+		withArgs(args...),
+		withVolumes(volumes, volumeMounts),
+		withPorts(g.udpPorts()...),
+		withProbes(probeutil.MakeLivenessProbeHTTP("", "/healthz", 10254), probeutil.MakeReadinessProbeHTTP("", "/healthz", 10254)),
+		withLifecycle(preStopSleep(int(drainSeconds))),
+		withSecurityContext(&corev1.SecurityContext{
+			Privileged: commonutil.Bool(true),
+		}),
+		withTerminationGracePeriod(int64(drainSeconds)+defTrafficGracePeriodSeconds),
+		withAffinity(affinity),
+		withServiceAccount(serviceAccountName(g.component)),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.HostNetwork = true
+			spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+			spec.Tolerations = []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists, // tolerate everything.
+				},
+			}
+			spec.InitContainers = g.sysctlInitContainers()
+			if logVolume != nil {
+				spec.Containers = append(spec.Containers, g.logRotateContainer(logMount))
+			}
+		}),
+	)
+	ds.Spec.MinReadySeconds = minReadySeconds
+	return ds
+}
+
+// defaultCertificate returns the kubernetes.io/tls secret of the default certificate.
+func (g *gateway) defaultCertificate(name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := g.client.Get(g.ctx, types.NamespacedName{Namespace: g.component.Namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("get default certificate %s: %v", name, err)
+	}
+	if len(secret.Data[corev1.TLSCertKey]) == 0 || len(secret.Data[corev1.TLSPrivateKeyKey]) == 0 {
+		return nil, NewPermanentError(fmt.Sprintf("invalid default certificate %s: %s and %s are required",
+			name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey))
+	}
+	return secret, nil
+}
+
+// defaultCertificateVolume returns the volume of the default certificate, and the checksum of the certificate.
+func (g *gateway) defaultCertificateVolume() (corev1.Volume, corev1.VolumeMount, string) {
+	volume := corev1.Volume{
+		Name: "default-cert",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: g.certSecret.Name,
+				Items: []corev1.KeyToPath{
+					{Key: corev1.TLSCertKey, Path: corev1.TLSCertKey},
+					{Key: corev1.TLSPrivateKeyKey, Path: corev1.TLSPrivateKeyKey},
+				},
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      "default-cert",
+		MountPath: gatewayDefaultCertPath,
+		ReadOnly:  true,
+	}
+	hash := sha256.New()
+	hash.Write(g.certSecret.Data[corev1.TLSCertKey])
+	hash.Write(g.certSecret.Data[corev1.TLSPrivateKeyKey])
+	return volume, mount, hex.EncodeToString(hash.Sum(nil))
+}
+
+// portRanges returns the port ranges of the applications exposed through rbd-gateway.
+func (g *gateway) portRanges() []rainbondv1alpha1.PortRange {
+	if g.cluster.Spec.Gateway == nil {
+		return nil
+	}
+	return g.cluster.Spec.Gateway.PortRanges
+}
+
+// portRangesService returns the Service that publishes the port ranges of rbd-gateway inside the cluster, the ports
+// are enumerated since a Service port can't be a range.
+func (g *gateway) portRangesService() client.Object {
+	var ports []corev1.ServicePort
+	for _, r := range g.portRanges() {
+		for port := r.Start; port <= r.GetEnd(); port++ {
+			ports = append(ports, corev1.ServicePort{
+				Name:       fmt.Sprintf("%s-%d", strings.ToLower(string(r.GetProtocol())), port),
+				Protocol:   r.GetProtocol(),
+				Port:       port,
+				TargetPort: intstr.FromInt(int(port)),
+			})
+		}
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	return buildService(GatewayName, g.component.Namespace, g.labels, ports...)
+}
 
-	ds := &appsv1.DaemonSet{
+// portRangesNetworkPolicy returns the NetworkPolicy that allows the ingress traffic to the port ranges of
+// rbd-gateway. The ports are enumerated since the end port of a NetworkPolicy port is not supported by the
+// kubernetes versions before 1.21. The pods selected by a NetworkPolicy only accept the allowed ports, so the ports
+// of rbd-gateway itself and the udp ports are allowed too.
+func (g *gateway) portRangesNetworkPolicy() client.Object {
+	if len(g.portRanges()) == 0 {
+		return nil
+	}
+	var ports []networkingv1.NetworkPolicyPort
+	allow := func(protocol corev1.Protocol, port int32) {
+		target := intstr.FromInt(int(port))
+		ports = append(ports, networkingv1.NetworkPolicyPort{
+			Protocol: &protocol,
+			Port:     &target,
+		})
+	}
+	for _, port := range rbdutil.GatewayPorts {
+		allow(corev1.ProtocolTCP, int32(port))
+	}
+	for _, port := range g.cluster.Spec.Gateway.UDPPorts {
+		allow(corev1.ProtocolUDP, port)
+	}
+	for _, r := range g.portRanges() {
+		for port := r.Start; port <= r.GetEnd(); port++ {
+			allow(r.GetProtocol(), port)
+		}
+	}
+	return &networkingv1.NetworkPolicy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      GatewayName,
 			Namespace: g.component.Namespace,
 			Labels:    g.labels,
 		},
-		Spec: appsv1.DaemonSetSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: g.labels,
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: g.labels},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{Ports: ports},
 			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   GatewayName,
-					Labels: g.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(g.component, g.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					ServiceAccountName:            "rainbond-operator",
-					HostNetwork:                   true,
-					DNSPolicy:                     corev1.DNSClusterFirstWithHostNet,
-					Tolerations: []corev1.Toleration{
-						{
-							Operator: corev1.TolerationOpExists, // tolerate everything.
-						},
-					},
-					Affinity: affinity,
-					Containers: []corev1.Container{
-						{
-							Name:            GatewayName,
-							Image:           g.component.Spec.Image,
-							ImagePullPolicy: g.component.ImagePullPolicy(),
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							SecurityContext: &corev1.SecurityContext{
-								Privileged: commonutil.Bool(true),
-							},
-							Env: g.component.Spec.Env,
-						},
-					},
-					Volumes: volumes,
-				},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+}
+
+// udpPorts returns the udp ports of the container, rbd-gateway listens on them by the UDP rules of rbd-api.
+// rbd-gateway uses the host network, so the ports are declared as the host ports, by which the scheduler won't
+// place it on the nodes whose udp ports are declared by the other pods.
+func (g *gateway) udpPorts() []corev1.ContainerPort {
+	if g.cluster.Spec.Gateway == nil || len(g.cluster.Spec.Gateway.UDPPorts) == 0 {
+		return nil
+	}
+	var ports []corev1.ContainerPort
+	for _, port := range g.cluster.Spec.Gateway.UDPPorts {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          fmt.Sprintf("udp-%d", port),
+			ContainerPort: port,
+			HostPort:      port,
+			Protocol:      corev1.ProtocolUDP,
+		})
+	}
+	return ports
+}
+
+// validateUDPPorts checks if the udp ports are valid and not duplicated.
+func validateUDPPorts(ports []int32) error {
+	seen := make(map[int32]bool, len(ports))
+	for _, port := range ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("port %d: must be between 1 and 65535", port)
+		}
+		if seen[port] {
+			return fmt.Errorf("port %d: duplicated", port)
+		}
+		seen[port] = true
+	}
+	return nil
+}
+
+// logSettings returns the log arguments of rbd-gateway, and the volume to store
+// the log files if the logs are written to the files on the host.
+func (g *gateway) logSettings() ([]string, *corev1.Volume, *corev1.VolumeMount) {
+	log := g.component.Spec.Log
+	if log == nil {
+		log = &rainbondv1alpha1.ComponentLog{}
+	}
+	level := log.ErrorLogLevel
+	if level == "" {
+		level = "error"
+	}
+	args := []string{"--errlog-level=" + level}
+	if log.AccessLogFormat != "" {
+		args = append(args, "--access-log-format="+log.AccessLogFormat)
+	}
+
+	if log.Destination != rainbondv1alpha1.LogDestinationFile {
+		args = append(args, "--error-log=/dev/stderr")
+		if log.AccessLogEnabled {
+			args = append(args, "--access-log=/dev/stdout")
+		}
+		return args, nil, nil
+	}
+
+	hostPath := log.HostPath
+	if hostPath == "" {
+		hostPath = path.Join("/var/log/rainbond", GatewayName)
+	}
+	args = append(args, "--error-log="+path.Join(gatewayLogPath, "error.log"))
+	if log.AccessLogEnabled {
+		args = append(args, "--access-log="+path.Join(gatewayLogPath, "access.log"))
+	}
+	volume := &corev1.Volume{
+		Name: "log",
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: hostPath,
+				Type: k8sutil.HostPathDirectoryOrCreate(),
 			},
 		},
 	}
+	mount := &corev1.VolumeMount{
+		Name:      "log",
+		MountPath: gatewayLogPath,
+	}
+	return args, volume, mount
+}
 
-	return ds
+// logRotateContainer returns a sidecar that rotates the log files of rbd-gateway.
+// The log files are copied and truncated, so that rbd-gateway does not need to reopen them.
+func (g *gateway) logRotateContainer(mount *corev1.VolumeMount) corev1.Container {
+	maxSize, maxAge := g.component.Spec.Log.MaxSize, g.component.Spec.Log.MaxAge
+	if maxSize <= 0 {
+		maxSize = 100
+	}
+	if maxAge <= 0 {
+		maxAge = 7
+	}
+	script := fmt.Sprintf(`while true; do
+  for file in %[1]s/*.log; do
+    [ -f "$file" ] || continue
+    if [ $(du -m "$file" | cut -f1) -ge %[2]d ]; then
+      cp "$file" "$file.$(date +%%Y%%m%%d%%H%%M%%S)" && : > "$file"
+    fi
+  done
+  find %[1]s -name '*.log.*' -mtime +%[3]d -delete
+  sleep 60
+done`, gatewayLogPath, maxSize, maxAge)
+
+	return corev1.Container{
+		Name: "log-rotate",
+		// the image of rbd-gateway contains a shell.
+		Image:           g.component.Spec.Image,
+		ImagePullPolicy: g.component.ImagePullPolicy(),
+		Command:         []string{"/bin/sh", "-c", script},
+		VolumeMounts:    []corev1.VolumeMount{*mount},
+	}
+}
+
+// sysctlInitContainers returns the privileged init container that sets the sysctls of rbd-gateway.
+// The sysctls are passed as arguments of the sysctl command directly without a shell.
+func (g *gateway) sysctlInitContainers() []corev1.Container {
+	if g.cluster.Spec.Gateway == nil || len(g.cluster.Spec.Gateway.Sysctls) == 0 {
+		return nil
+	}
+	command := []string{"sysctl", "-w"}
+	for _, sysctl := range g.cluster.Spec.Gateway.Sysctls {
+		command = append(command, sysctl.Name+"="+sysctl.Value)
+	}
+	return []corev1.Container{
+		{
+			Name:            "sysctl",
+			Image:           g.component.Spec.Image,
+			ImagePullPolicy: g.component.ImagePullPolicy(),
+			Command:         command,
+			SecurityContext: &corev1.SecurityContext{
+				Privileged: commonutil.Bool(true),
+			},
+		},
+	}
 }
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateUDPPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		ports   []int32
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "valid",
+			ports: []int32{53, 27015},
+		},
+		{
+			name:    "out of range",
+			ports:   []int32{0},
+			wantErr: true,
+		},
+		{
+			name:    "duplicated",
+			ports:   []int32{53, 53},
+			wantErr: true,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateUDPPorts(tc.ports)
+			assert.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestGatewayUDPPorts(t *testing.T) {
+	g := &gateway{cluster: &rainbondv1alpha1.RainbondCluster{}}
+	assert.Nil(t, g.udpPorts())
+
+	g.cluster.Spec.Gateway = &rainbondv1alpha1.GatewayConfig{UDPPorts: []int32{27015, 53}}
+	ports := g.udpPorts()
+	assert.Equal(t, []corev1.ContainerPort{
+		{Name: "udp-27015", ContainerPort: 27015, HostPort: 27015, Protocol: corev1.ProtocolUDP},
+		{Name: "udp-53", ContainerPort: 53, HostPort: 53, Protocol: corev1.ProtocolUDP},
+	}, ports)
+}
+
+func TestGatewayPortRanges(t *testing.T) {
+	g := &gateway{
+		component: &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Name: GatewayName, Namespace: "rbd-system"}},
+		cluster:   &rainbondv1alpha1.RainbondCluster{},
+		labels:    map[string]string{"name": GatewayName},
+	}
+	assert.Nil(t, g.portRangesService())
+	assert.Nil(t, g.portRangesNetworkPolicy())
+	assert.Len(t, g.ResourcesNeedDelete(), 2)
+
+	g.cluster.Spec.Gateway = &rainbondv1alpha1.GatewayConfig{
+		UDPPorts: []int32{53},
+		PortRanges: []rainbondv1alpha1.PortRange{
+			{Start: 20000, End: 20001},
+			{Protocol: corev1.ProtocolUDP, Start: 20000},
+		},
+	}
+	assert.Empty(t, g.ResourcesNeedDelete())
+
+	svc := g.portRangesService().(*corev1.Service)
+	var names []string
+	for _, port := range svc.Spec.Ports {
+		names = append(names, port.Name)
+	}
+	assert.Equal(t, []string{"tcp-20000", "tcp-20001", "udp-20000"}, names)
+	assert.Equal(t, corev1.ProtocolUDP, svc.Spec.Ports[2].Protocol)
+
+	policy := g.portRangesNetworkPolicy().(*networkingv1.NetworkPolicy)
+	assert.Equal(t, g.labels, policy.Spec.PodSelector.MatchLabels)
+	ports := policy.Spec.Ingress[0].Ports
+	// the ports of rbd-gateway itself, the udp ports and the port ranges.
+	assert.Len(t, ports, len(rbdutil.GatewayPorts)+1+3)
+	last := ports[len(ports)-1]
+	assert.Equal(t, corev1.ProtocolUDP, *last.Protocol)
+	assert.Equal(t, 20000, last.Port.IntValue())
+}
+
+func TestGatewayDefaultCertificate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	cert := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "default-cert", Namespace: "rbd-system"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("cert"),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+	invalid := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "rbd-system"},
+		Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert")},
+	}
+	g := &gateway{
+		ctx:       context.Background(),
+		client:    fake.NewFakeClientWithScheme(scheme, cert, invalid),
+		component: &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Name: GatewayName, Namespace: "rbd-system"}},
+	}
+
+	_, err := g.defaultCertificate("not-found")
+	assert.Error(t, err)
+	assert.Equal(t, ErrorKindRetryable, ErrorKindOf(err))
+	_, err = g.defaultCertificate("invalid")
+	assert.Equal(t, ErrorKindPermanent, ErrorKindOf(err))
+
+	g.certSecret, err = g.defaultCertificate("default-cert")
+	assert.NoError(t, err)
+	volume, mount, checksum := g.defaultCertificateVolume()
+	assert.Equal(t, "default-cert", volume.Secret.SecretName)
+	assert.Equal(t, gatewayDefaultCertPath, mount.MountPath)
+
+	// the checksum changes with the certificate, by which rbd-gateway is restarted.
+	g.certSecret.Data[corev1.TLSCertKey] = []byte("renewed")
+	_, _, renewed := g.defaultCertificateVolume()
+	assert.NotEqual(t, checksum, renewed)
+}
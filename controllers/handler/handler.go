@@ -1,16 +1,27 @@
 package handler
 
 import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // ComponentHandler will check the prerequisites, create resources for rbdcomponent.
+//
+// A handler is created by its Factory, registered with Register, at every reconciliation of the rbdcomponent
+// with the same name. The controller calls Before, then creates or updates the resources returned by Resources
+// with the rbdcomponent as their owner, then calls After, and generates the status of the rbdcomponent from
+// the pods returned by ListPods. A handler may implement the optional interfaces below, such as ResourcesCreator,
+// ResourcesDeleter or Replicaser, to take part in the other steps of the reconciliation.
 type ComponentHandler interface {
 	// Before will do something before creating component, such as checking the prerequisites, etc.
+	// The reconciliation is retried later if it returns an error, by the kind of the error, see ErrorKindOf.
 	Before() error
+	// Resources returns the resources of the component, which are created or updated to match.
 	Resources() []client.Object
+	// After will do something after the resources are created or updated.
 	After() error
+	// ListPods returns the pods of the component.
 	ListPods() ([]corev1.Pod, error)
 }
 
@@ -53,3 +64,24 @@ type Replicaser interface {
 	// return replicas for rbdcomponent.
 	Replicas() *int32
 }
+
+// Dependenter provides the services that the pods of rbdcomponent wait for before starting,
+// so that they won't crash when the services are not ready.
+type Dependenter interface {
+	// returns the dependencies of rbdcomponent, called after Before.
+	Dependencies() []Dependency
+}
+
+// ConditionReporter provides the extra conditions of rbdcomponent, such as the result of a verification.
+type ConditionReporter interface {
+	// returns the conditions to update in the status of rbdcomponent, called after the pods are listed.
+	// The rbdcomponent is reconciled again later until all of them are true.
+	Conditions() []*rainbondv1alpha1.RbdComponentCondition
+}
+
+// HealthChecker provides the health endpoint of rbdcomponent, which reports whether the component can serve,
+// in addition to the readiness of the pods.
+type HealthChecker interface {
+	// returns the health endpoint probed on each ready pod, see HealthCondition.
+	HealthEndpoint() HealthEndpoint
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// healthProbeTimeout is the timeout of the probe of the health endpoint of a pod.
+	healthProbeTimeout = 5 * time.Second
+	// healthResponseLimit is the most bytes read from the body of the response of the health endpoint.
+	healthResponseLimit = 64 << 10
+)
+
+// healthyStatuses are the statuses in the bodies of the health endpoints of the components that mean healthy.
+var healthyStatuses = map[string]bool{
+	"health":  true,
+	"healthy": true,
+	"ok":      true,
+	"up":      true,
+}
+
+var healthProbeClient = &http.Client{Timeout: healthProbeTimeout}
+
+// HealthEndpoint is the http endpoint on the pods of a component that reports whether the component can serve.
+type HealthEndpoint struct {
+	Port int
+	Path string
+}
+
+// healthResponse is the body of the health endpoints of the components, such as {"status":"unusual","info":{...}}.
+type healthResponse struct {
+	Status string          `json:"status"`
+	Info   json.RawMessage `json:"info,omitempty"`
+}
+
+// HealthCondition probes the health endpoint on each ready pod, and returns the ServiceHealthy condition. It catches
+// the pods that are ready but can't serve, such as the ones that lost the connection to their dependencies.
+func HealthCondition(ctx context.Context, pods []corev1.Pod, endpoint HealthEndpoint) *rainbondv1alpha1.RbdComponentCondition {
+	var ready []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.PodIP != "" && k8sutil.IsPodReady(&pod) {
+			ready = append(ready, pod)
+		}
+	}
+	if len(ready) == 0 {
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ServiceHealthy, corev1.ConditionUnknown,
+			rainbondv1alpha1.ReasonInProgress, "waiting for the ready pods to probe")
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var unreachable, unexpected []string
+	for i := range ready {
+		pod := ready[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url := fmt.Sprintf("http://%s%s", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(endpoint.Port)), endpoint.Path)
+			reachable, err := probeHealth(ctx, url)
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			msg := fmt.Sprintf("%s: %v", pod.Name, err)
+			if reachable {
+				unexpected = append(unexpected, msg)
+			} else {
+				unreachable = append(unreachable, msg)
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Strings(unexpected)
+	sort.Strings(unreachable)
+
+	switch {
+	case len(unexpected) > 0:
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ServiceHealthy, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonHealthCheckUnexpectedResponse, strings.Join(append(unexpected, unreachable...), "; "))
+	case len(unreachable) > 0:
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ServiceHealthy, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonHealthCheckUnreachable, strings.Join(unreachable, "; "))
+	}
+	return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ServiceHealthy, corev1.ConditionTrue,
+		rainbondv1alpha1.ReasonHealthCheckPassed, "")
+}
+
+// probeHealth sends a GET request to the health endpoint, reachable is true if it responds. The endpoint is healthy
+// only if the status code is 2xx, and the status in the body, if any, is healthy.
+func probeHealth(ctx context.Context, url string) (reachable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := healthProbeClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, healthResponseLimit))
+	if err != nil {
+		return true, err
+	}
+	return true, checkHealthResponse(resp.StatusCode, body)
+}
+
+// checkHealthResponse checks the status code and the status in the body of the response of the health endpoint.
+func checkHealthResponse(code int, body []byte) error {
+	var health healthResponse
+	if err := json.Unmarshal(body, &health); err != nil || health.Status == "" {
+		// not a json with the status, only the status code counts.
+		if code < 200 || code >= 300 {
+			return fmt.Errorf("unexpected status code %d", code)
+		}
+		return nil
+	}
+	if code >= 200 && code < 300 && healthyStatuses[strings.ToLower(health.Status)] {
+		return nil
+	}
+	if len(health.Info) > 0 {
+		return fmt.Errorf("status %s: %s", health.Status, health.Info)
+	}
+	return fmt.Errorf("status %s", health.Status)
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckHealthResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		body    string
+		wantErr bool
+	}{
+		{
+			name: "healthy",
+			code: http.StatusOK,
+			body: `{"status":"health","info":{}}`,
+		},
+		{
+			name:    "unhealthy with status ok",
+			code:    http.StatusOK,
+			body:    `{"status":"unusual","info":{"db":"connection refused"}}`,
+			wantErr: true,
+		},
+		{
+			name: "plain text",
+			code: http.StatusOK,
+			body: "ok",
+		},
+		{
+			name:    "server error",
+			code:    http.StatusInternalServerError,
+			body:    "internal error",
+			wantErr: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkHealthResponse(tc.code, []byte(tc.body))
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestHealthCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		ready      bool
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "no ready pods",
+			wantStatus: corev1.ConditionUnknown,
+			wantReason: rainbondv1alpha1.ReasonInProgress,
+		},
+		{
+			name: "healthy",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"status":"health"}`))
+			},
+			ready:      true,
+			wantStatus: corev1.ConditionTrue,
+			wantReason: rainbondv1alpha1.ReasonHealthCheckPassed,
+		},
+		{
+			name:       "not found",
+			handler:    http.NotFound,
+			ready:      true,
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonHealthCheckUnexpectedResponse,
+		},
+		{
+			name: "large body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(strings.Repeat(" ", healthResponseLimit+1)))
+			},
+			ready:      true,
+			wantStatus: corev1.ConditionTrue,
+			wantReason: rainbondv1alpha1.ReasonHealthCheckPassed,
+		},
+		{
+			name: "unhealthy",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"status":"unusual","info":{"etcd":"context deadline exceeded"}}`))
+			},
+			ready:      true,
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonHealthCheckUnexpectedResponse,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			handler := tc.handler
+			if handler == nil {
+				handler = http.NotFound
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			portNum, _ := strconv.Atoi(port)
+
+			ready := corev1.ConditionFalse
+			if tc.ready {
+				ready = corev1.ConditionTrue
+			}
+			pods := []corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "rbd-api-0"},
+					Status: corev1.PodStatus{
+						PodIP:      host,
+						Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+					},
+				},
+			}
+			condition := HealthCondition(context.Background(), pods, HealthEndpoint{Port: portNum, Path: "/v2/health"})
+			assert.Equal(t, rainbondv1alpha1.ServiceHealthy, condition.Type)
+			assert.Equal(t, tc.wantStatus, condition.Status)
+			assert.Equal(t, tc.wantReason, condition.Reason)
+		})
+	}
+}
+
+func TestHealthConditionUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFound)
+	addr := server.Listener.Addr().String()
+	server.Close()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, _ := strconv.Atoi(port)
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "rbd-mq-0"},
+			Status: corev1.PodStatus{
+				PodIP:      host,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		},
+	}
+	condition := HealthCondition(context.Background(), pods, HealthEndpoint{Port: portNum, Path: "/health"})
+	assert.Equal(t, corev1.ConditionFalse, condition.Status)
+	assert.Equal(t, rainbondv1alpha1.ReasonHealthCheckUnreachable, condition.Reason)
+}
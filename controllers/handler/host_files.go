@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"fmt"
+	"path"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// HostFilesContainerPrefix is the prefix of the names of the init containers that copy the host files.
+const HostFilesContainerPrefix = "host-files-"
+
+// hostFilesMountPath is where the directory on the host is mounted in the init containers.
+const hostFilesMountPath = "/host"
+
+// hostFilesScript copies the files to a temporary directory on the same filesystem of the host directory first,
+// then renames them into place one by one, so that the binaries in use are replaced rather than overwritten,
+// and no partial file is left on the host if the copy is interrupted.
+const hostFilesScript = `set -e
+if [ -d "$SOURCE" ]; then
+  SOURCE="$SOURCE/."
+fi
+TMP=$(mktemp -d ` + hostFilesMountPath + `/.rbd-host-files.XXXXXX)
+trap 'rm -rf "$TMP"' EXIT
+cp -a "$SOURCE" "$TMP/"
+if [ -n "$MODE" ]; then
+  find "$TMP" -type f -exec chmod "$MODE" {} +
+fi
+cd "$TMP"
+find . -type d | while read -r DIR; do
+  mkdir -p "` + hostFilesMountPath + `/$DIR"
+done
+find . ! -type d | while read -r FILE; do
+  mv -f "$FILE" "` + hostFilesMountPath + `/$FILE"
+  echo "copied $FILE"
+done
+`
+
+// validateHostFiles checks the host files of the component are complete and don't conflict with each other.
+func validateHostFiles(files []rainbondv1alpha1.HostFiles) error {
+	names := make(map[string]struct{})
+	for _, file := range files {
+		if file.Name == "" || file.Source == "" || file.HostPath == "" {
+			return NewPermanentError("name, source and hostPath of the host files are required")
+		}
+		if _, ok := names[file.Name]; ok {
+			return NewPermanentError(fmt.Sprintf("duplicate host files %s", file.Name))
+		}
+		names[file.Name] = struct{}{}
+		if !path.IsAbs(file.Source) || !path.IsAbs(file.HostPath) {
+			return NewPermanentError(fmt.Sprintf("source and hostPath of the host files %s must be absolute paths", file.Name))
+		}
+		if path.Clean(file.HostPath) == "/" {
+			return NewPermanentError(fmt.Sprintf("hostPath of the host files %s can't be the root directory", file.Name))
+		}
+	}
+	return nil
+}
+
+// hostFilesInitContainers returns the init containers that copy the host files from the image of the component,
+// and the host path volumes they mount.
+func hostFilesInitContainers(cpt *rainbondv1alpha1.RbdComponent) ([]corev1.Container, []corev1.Volume) {
+	var containers []corev1.Container
+	var volumes []corev1.Volume
+	for _, file := range cpt.Spec.HostFiles {
+		name := HostFilesContainerPrefix + file.Name
+		volumes = append(volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: path.Clean(file.HostPath),
+					Type: k8sutil.HostPathDirectoryOrCreate(),
+				},
+			},
+		})
+		containers = append(containers, corev1.Container{
+			Name:            name,
+			Image:           cpt.Spec.Image,
+			ImagePullPolicy: cpt.ImagePullPolicy(),
+			Command:         []string{"/bin/sh", "-c", hostFilesScript},
+			Env: []corev1.EnvVar{
+				{
+					Name:  "SOURCE",
+					Value: path.Clean(file.Source),
+				},
+				{
+					Name:  "MODE",
+					Value: file.Mode,
+				},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      name,
+					MountPath: hostFilesMountPath,
+				},
+			},
+		})
+	}
+	return containers, volumes
+}
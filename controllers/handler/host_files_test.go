@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateHostFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []rainbondv1alpha1.HostFiles
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			files: []rainbondv1alpha1.HostFiles{
+				{Name: "grctl", Source: "/run/bin/grctl", HostPath: "/usr/local/bin"},
+				{Name: "config", Source: "/etc/rbd-node/", HostPath: "/opt/rainbond/etc"},
+			},
+		},
+		{
+			name:    "missing source",
+			files:   []rainbondv1alpha1.HostFiles{{Name: "grctl", HostPath: "/usr/local/bin"}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate",
+			files: []rainbondv1alpha1.HostFiles{
+				{Name: "grctl", Source: "/run/bin/grctl", HostPath: "/usr/local/bin"},
+				{Name: "grctl", Source: "/run/bin/grctl", HostPath: "/usr/bin"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "relative host path",
+			files:   []rainbondv1alpha1.HostFiles{{Name: "grctl", Source: "/run/bin/grctl", HostPath: "usr/local/bin"}},
+			wantErr: true,
+		},
+		{
+			name:    "root",
+			files:   []rainbondv1alpha1.HostFiles{{Name: "grctl", Source: "/run/bin/grctl", HostPath: "/usr/.."}},
+			wantErr: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateHostFiles(tc.files)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				assert.Equal(t, ErrorKindPermanent, ErrorKindOf(err))
+			}
+		})
+	}
+}
+
+func TestHostFilesInitContainers(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{Name: "rbd-node", Namespace: "rbd-system"},
+		Spec: rainbondv1alpha1.RbdComponentSpec{
+			Image: "goodrain.me/rbd-node:v5.3.3",
+			HostFiles: []rainbondv1alpha1.HostFiles{
+				{Name: "grctl", Source: "/run/bin/", HostPath: "/usr/local/bin/", Mode: "0755"},
+			},
+		},
+	}
+	containers, volumes := hostFilesInitContainers(cpt)
+	if assert.Len(t, containers, 1) && assert.Len(t, volumes, 1) {
+		assert.Equal(t, "host-files-grctl", containers[0].Name)
+		assert.Equal(t, "goodrain.me/rbd-node:v5.3.3", containers[0].Image)
+		assert.Equal(t, "/run/bin", containers[0].Env[0].Value)
+		assert.Equal(t, "0755", containers[0].Env[1].Value)
+		assert.Equal(t, volumes[0].Name, containers[0].VolumeMounts[0].Name)
+		assert.Equal(t, "/usr/local/bin", volumes[0].HostPath.Path)
+	}
+
+	containers, volumes = hostFilesInitContainers(&rainbondv1alpha1.RbdComponent{})
+	assert.Empty(t, containers)
+	assert.Empty(t, volumes)
+}
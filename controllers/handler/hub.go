@@ -9,9 +9,12 @@ import (
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	"github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
@@ -38,10 +41,15 @@ type hub struct {
 
 	pvcParametersRWX *pvcParameters
 	storageRequest   int64
+	// gcJobs are the jobs of the garbage collection that have not finished, the image hub is read-only meanwhile.
+	gcJobs []batchv1.Job
 }
 
 var _ ComponentHandler = &hub{}
 var _ StorageClassRWXer = &hub{}
+var _ ResourcesCreator = &hub{}
+var _ ConditionReporter = &hub{}
+var _ ResourcesDeleter = &hub{}
 
 //NewHub nw hub
 func NewHub(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -60,13 +68,27 @@ func (h *hub) Before() error {
 		return NewIgnoreError("use custom image repository")
 	}
 
-	if err := setStorageCassName(h.ctx, h.client, h.component.Namespace, h); err != nil {
-		return err
+	// the images stored in the object storage don't need the RWX volume.
+	if h.objectStorage() == nil {
+		if err := setStorageCassName(h.ctx, h.client, h.component.Namespace, h); err != nil {
+			return err
+		}
 	}
 
 	if h.cluster.Spec.ImageHub == nil {
 		return NewIgnoreError("imageHub is empty")
 	}
+	if err := h.checkObjectStorage(); err != nil {
+		return err
+	}
+	if err := h.checkReplication(); err != nil {
+		return err
+	}
+	if h.objectStorage() == nil {
+		if err := checkExistingClaim(h.ctx, h.client, h.component, corev1.ReadWriteMany); err != nil {
+			return err
+		}
+	}
 
 	htpasswd, err := h.generateHtpasswd()
 	if err != nil {
@@ -74,6 +96,12 @@ func (h *hub) Before() error {
 	}
 	h.htpasswd = htpasswd
 
+	gcJobs, err := h.activeGCJobs()
+	if err != nil {
+		return err
+	}
+	h.gcJobs = gcJobs
+
 	return nil
 }
 
@@ -85,11 +113,58 @@ func (h *hub) Resources() []client.Object {
 		h.serviceForHub(),
 		h.persistentVolumeClaimForHub(),
 		h.ingressForHub(),
+		h.daemonSetForHubTrust(),
+		h.hubGCCronJob(),
+		h.hubReplicationSecret(),
+		h.hubReplicationCronJob(),
+	}
+}
+
+func (h *hub) ResourcesNeedDelete() []client.Object {
+	var objs []client.Object
+	if h.cluster.Spec.ImageHub.GarbageCollection == nil {
+		objs = append(objs, &batchv1beta1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      HubGCName,
+				Namespace: h.component.Namespace,
+			},
+		})
+	}
+	if h.replication() == nil {
+		objs = append(objs, &batchv1beta1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      HubReplicationName,
+				Namespace: h.component.Namespace,
+			},
+		}, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      HubReplicationName,
+				Namespace: h.component.Namespace,
+			},
+		})
+	}
+	if !h.hubTrustEnabled() {
+		objs = append(objs, &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      hubTrustName,
+				Namespace: h.component.Namespace,
+			},
+		})
+	}
+	return objs
+}
+
+func (h *hub) ResourcesCreateIfNotExists() []client.Object {
+	if !h.hubTrustEnabled() {
+		return nil
+	}
+	return []client.Object{
+		h.imageSyncForHubTrust(),
 	}
 }
 
 func (h *hub) After() error {
-	return nil
+	return h.startGC()
 }
 
 func (h *hub) ListPods() ([]corev1.Pod, error) {
@@ -115,109 +190,53 @@ func (h *hub) deployment() client.Object {
 			Value: "/auth/htpasswd",
 		},
 	}
-	volumeMounts := []corev1.VolumeMount{
-		{
-			Name:      "hubdata",
-			MountPath: "/var/lib/registry",
-		},
-		{
-			Name:      "htpasswd",
-			MountPath: "/auth",
-			ReadOnly:  true,
-		},
-	}
-	volumes := []corev1.Volume{
-		{
-			Name: "hubdata",
-			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: hubDataPvcName,
-				},
-			},
-		},
-		{
-			Name: "htpasswd",
-			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: hubPasswordSecret,
-					Items: []corev1.KeyToPath{
-						{
-							Key:  "HTPASSWD",
-							Path: "htpasswd",
-						},
+	env = append(env, h.storageEnv()...)
+	volumes, volumeMounts := h.dataVolumes()
+	volumeMounts = append(volumeMounts, corev1.VolumeMount{
+		Name:      "htpasswd",
+		MountPath: "/auth",
+		ReadOnly:  true,
+	})
+	volumes = append(volumes, corev1.Volume{
+		Name: "htpasswd",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: hubPasswordSecret,
+				Items: []corev1.KeyToPath{
+					{
+						Key:  "HTPASSWD",
+						Path: "htpasswd",
 					},
 				},
 			},
 		},
-	}
-
-	env = mergeEnvs(env, h.component.Spec.Env)
-	volumeMounts = mergeVolumeMounts(volumeMounts, h.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, h.component.Spec.Volumes)
+	})
 
-	ds := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      HubName,
-			Namespace: h.component.Namespace,
-			Labels:    h.labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: h.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: h.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   HubName,
-					Labels: h.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(h.component, h.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Containers: []corev1.Container{
-						{
-							Name:            "rbd-hub",
-							Image:           h.component.Spec.Image,
-							ImagePullPolicy: h.component.ImagePullPolicy(),
-							Env:             env,
-							VolumeMounts:    volumeMounts,
-							Resources:       h.component.Spec.Resources,
-						},
-					},
-					Volumes: volumes,
-				},
-			},
-		},
+	if len(h.gcJobs) > 0 {
+		env = append(env, readOnlyEnv())
 	}
 
-	return ds
+	return buildDeployment(HubName, h.component, h.cluster, h.labels, appsv1.RollingUpdateDeploymentStrategyType,
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(probeutil.MakeLivenessProbeHTTP("", "/", 5000), probeutil.MakeReadinessProbeHTTP("", "/", 5000)),
+	)
 }
 
 func (h *hub) serviceForHub() client.Object {
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      HubName,
-			Namespace: h.component.Namespace,
-			Labels:    h.labels,
+	return buildService(HubName, h.component.Namespace, h.labels, corev1.ServicePort{
+		Name: "main",
+		Port: 5000,
+		TargetPort: intstr.IntOrString{
+			IntVal: 5000,
 		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name: "main",
-					Port: 5000,
-					TargetPort: intstr.IntOrString{
-						IntVal: 5000,
-					},
-				},
-			},
-			Selector: h.labels,
-		},
-	}
-
-	return svc
+	})
 }
 
-func (h *hub) persistentVolumeClaimForHub() *corev1.PersistentVolumeClaim {
+func (h *hub) persistentVolumeClaimForHub() client.Object {
+	if h.objectStorage() != nil || existingClaimName(h.component) != "" {
+		return nil
+	}
 	return createPersistentVolumeClaimRWX(h.component.Namespace, hubDataPvcName, h.pvcParametersRWX, h.labels)
 }
 
@@ -285,7 +304,7 @@ func (h *hub) secretForHub() client.Object {
 	}
 	labels := copyLabels(h.labels)
 	labels["name"] = hubImageRepository
-	_, pem, key, _ := commonutil.DomainSign(nil, rbdutil.GetImageRepository(h.cluster))
+	caPem, pem, key, _ := commonutil.DomainSign(nil, rbdutil.GetImageRepository(h.cluster))
 	return &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      hubImageRepository,
@@ -296,6 +315,8 @@ func (h *hub) secretForHub() client.Object {
 			"tls.crt": pem,
 			"tls.key": key,
 			"cert":    pem,
+			// ca.crt is distributed to the trust stores of the nodes by rbd-hub-trust.
+			"ca.crt": caPem,
 		},
 	}
 }
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HubGCName is the name of the CronJob that runs the garbage collection of the image hub.
+var HubGCName = "rbd-hub-gc"
+
+const (
+	// defHubGCSchedule is the default schedule of the garbage collection.
+	defHubGCSchedule = "0 2 * * 0"
+	// defHubGCMaxReadOnlyDuration is how long the image hub is kept read-only by a garbage collection by default.
+	defHubGCMaxReadOnlyDuration = time.Hour
+	// defHubGCHistoryLimit is the number of the finished jobs kept by default.
+	defHubGCHistoryLimit = 3
+	// hubReadOnlyAnnotation is set on the pods of the garbage collection once the image hub is read-only.
+	hubReadOnlyAnnotation = "rainbond.io/hub-readonly"
+	// hubReadOnlyEnv makes the image hub read-only, the pushes are rejected while the pulls still work.
+	hubReadOnlyEnv = "REGISTRY_STORAGE_MAINTENANCE_READONLY"
+)
+
+// hubGCScript waits until the image hub is read-only, which is told by the annotation of the pod,
+// then removes the layers no longer referenced.
+const hubGCScript = `until grep -q '` + hubReadOnlyAnnotation + `="true"' /etc/podinfo/annotations; do
+  echo "waiting for the image hub to be read-only"
+  sleep 5
+done
+exec registry garbage-collect $GC_ARGS /etc/docker/registry/config.yml
+`
+
+// activeGCJobs lists the jobs of the garbage collection that have not finished yet.
+func (h *hub) activeGCJobs() ([]batchv1.Job, error) {
+	jobs := &batchv1.JobList{}
+	if err := h.client.List(h.ctx, jobs, client.InNamespace(h.component.Namespace), client.MatchingLabels{"name": HubGCName}); err != nil {
+		return nil, fmt.Errorf("list jobs of %s: %v", HubGCName, err)
+	}
+	var active []batchv1.Job
+	for _, job := range jobs.Items {
+		if !jobFinished(&job) {
+			active = append(active, job)
+		}
+	}
+	return active, nil
+}
+
+func jobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// hubGCCronJob returns the CronJob of the garbage collection, or nil if it is disabled.
+func (h *hub) hubGCCronJob() client.Object {
+	gc := h.cluster.Spec.ImageHub.GarbageCollection
+	if gc == nil {
+		return nil
+	}
+	schedule := gc.Schedule
+	if schedule == "" {
+		schedule = defHubGCSchedule
+	}
+	maxReadOnly := defHubGCMaxReadOnlyDuration
+	if gc.MaxReadOnlyDuration != nil && gc.MaxReadOnlyDuration.Duration > 0 {
+		maxReadOnly = gc.MaxReadOnlyDuration.Duration
+	}
+	historyLimit := int32(defHubGCHistoryLimit)
+	if gc.HistoryLimit != nil {
+		historyLimit = *gc.HistoryLimit
+	}
+	var args []string
+	if gc.DeleteUntagged {
+		args = append(args, "--delete-untagged")
+	}
+
+	labels := copyLabels(h.labels)
+	labels["name"] = HubGCName
+
+	env := append([]corev1.EnvVar{
+		{
+			Name:  "GC_ARGS",
+			Value: strings.Join(args, " "),
+		},
+	}, h.storageEnv()...)
+	volumes, volumeMounts := h.dataVolumes()
+	volumeMounts = append(volumeMounts, corev1.VolumeMount{
+		Name:      "podinfo",
+		MountPath: "/etc/podinfo",
+	})
+	volumes = append(volumes, corev1.Volume{
+		// the annotations are updated in the volume once they are changed.
+		Name: "podinfo",
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{
+					{
+						Path:     "annotations",
+						FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations"},
+					},
+				},
+			},
+		},
+	})
+	var affinity *corev1.Affinity
+	if h.objectStorage() == nil {
+		// run with the image hub, in case the storage of the image hub can't be shared across the nodes.
+		affinity = &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: h.labels,
+						},
+						TopologyKey: "kubernetes.io/hostname",
+					},
+				},
+			},
+		}
+	}
+
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HubGCName,
+			Namespace: h.component.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: commonutil.Int32(historyLimit),
+			FailedJobsHistoryLimit:     commonutil.Int32(historyLimit),
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					// the image hub is writable again once the job is finished.
+					ActiveDeadlineSeconds: commonutil.Int64(int64(maxReadOnly / time.Second)),
+					BackoffLimit:          commonutil.Int32(0),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: corev1.PodSpec{
+							RestartPolicy:                 corev1.RestartPolicyNever,
+							ImagePullSecrets:              imagePullSecrets(h.component, h.cluster),
+							TerminationGracePeriodSeconds: commonutil.Int64(0),
+							Affinity:                      affinity,
+							Containers: []corev1.Container{
+								{
+									Name:            HubGCName,
+									Image:           h.component.Spec.Image,
+									ImagePullPolicy: h.component.ImagePullPolicy(),
+									Command:         []string{"/bin/sh", "-c", hubGCScript},
+									Env:             env,
+									VolumeMounts:    volumeMounts,
+								},
+							},
+							Volumes: volumes,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// readOnlyEnv returns the environment variable that makes the image hub read-only. The registry only reads it at the
+// start, so adding and removing it rolls out the image hub, twice per garbage collection.
+func readOnlyEnv() corev1.EnvVar {
+	return corev1.EnvVar{
+		Name:  hubReadOnlyEnv,
+		Value: `{"enabled": true}`,
+	}
+}
+
+// startGC tells the pods of the active garbage collection to start, once all the pods of the image hub are read-only.
+func (h *hub) startGC() error {
+	if len(h.gcJobs) == 0 {
+		return nil
+	}
+	deploy := &appsv1.Deployment{}
+	if err := h.client.Get(h.ctx, types.NamespacedName{Namespace: h.component.Namespace, Name: HubName}, deploy); err != nil {
+		return fmt.Errorf("get deployment %s: %v", HubName, err)
+	}
+	if !deploymentRolledOut(deploy) {
+		return nil
+	}
+
+	for _, job := range h.gcJobs {
+		pods := &corev1.PodList{}
+		if err := h.client.List(h.ctx, pods, client.InNamespace(h.component.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+			return fmt.Errorf("list pods of job %s: %v", job.Name, err)
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Annotations[hubReadOnlyAnnotation] == "true" {
+				continue
+			}
+			patch := client.MergeFrom(pod.DeepCopy())
+			if pod.Annotations == nil {
+				pod.Annotations = make(map[string]string)
+			}
+			pod.Annotations[hubReadOnlyAnnotation] = "true"
+			if err := h.client.Patch(h.ctx, pod, patch); err != nil {
+				return fmt.Errorf("annotate pod %s: %v", pod.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// deploymentRolledOut checks if all the pods of the deployment are of the latest template.
+func deploymentRolledOut(deploy *appsv1.Deployment) bool {
+	if deploy.Status.ObservedGeneration < deploy.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return deploy.Status.UpdatedReplicas == replicas && deploy.Status.Replicas == replicas
+}
+
+// hubWritableCondition returns whether the image hub accepts the pushes, which it doesn't during the garbage collection.
+func (h *hub) hubWritableCondition() *rainbondv1alpha1.RbdComponentCondition {
+	if len(h.gcJobs) == 0 {
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ImageHubWritable, corev1.ConditionTrue, "", "")
+	}
+	return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ImageHubWritable, corev1.ConditionFalse,
+		rainbondv1alpha1.ReasonGarbageCollecting, fmt.Sprintf("the image hub is read-only for the garbage collection %s", h.gcJobs[0].Name))
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHubGCCronJob(t *testing.T) {
+	h := &hub{
+		component: &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: HubName, Namespace: "rbd-system"},
+			Spec:       rainbondv1alpha1.RbdComponentSpec{Image: "registry:2.6.2"},
+		},
+		cluster: &rainbondv1alpha1.RainbondCluster{
+			Spec: rainbondv1alpha1.RainbondClusterSpec{
+				ImageHub: &rainbondv1alpha1.ImageHub{},
+			},
+		},
+		labels: map[string]string{"name": HubName},
+	}
+	assert.Nil(t, h.hubGCCronJob())
+
+	h.cluster.Spec.ImageHub.GarbageCollection = &rainbondv1alpha1.ImageHubGarbageCollection{
+		DeleteUntagged: true,
+		HistoryLimit:   commonutil.Int32(1),
+	}
+	cronJob := h.hubGCCronJob().(*batchv1beta1.CronJob)
+	assert.Equal(t, defHubGCSchedule, cronJob.Spec.Schedule)
+	assert.Equal(t, int32(1), *cronJob.Spec.FailedJobsHistoryLimit)
+	assert.Equal(t, int64(3600), *cronJob.Spec.JobTemplate.Spec.ActiveDeadlineSeconds)
+	container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "registry:2.6.2", container.Image)
+	assert.Equal(t, "--delete-untagged", container.Env[0].Value)
+	assert.Equal(t, HubGCName, cronJob.Spec.JobTemplate.Spec.Template.Labels["name"])
+}
+
+func TestDeploymentRolledOut(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: commonutil.Int32(1)},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 1},
+	}
+	assert.False(t, deploymentRolledOut(deploy))
+	deploy.Status.ObservedGeneration = 2
+	assert.False(t, deploymentRolledOut(deploy))
+	deploy.Status.Replicas = 1
+	assert.True(t, deploymentRolledOut(deploy))
+}
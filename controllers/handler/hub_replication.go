@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HubReplicationName is the name of the CronJob that replicates the image hub to the secondary registry,
+// and of the secret of the credentials of the image hub used by it.
+var HubReplicationName = "rbd-hub-replication"
+
+const (
+	// defHubReplicationSchedule is the default schedule of the replication.
+	defHubReplicationSchedule = "*/15 * * * *"
+	// DefHubReplicationImage is the default image of the replication in the image repository of rainbond, which
+	// must provide skopeo and curl.
+	DefHubReplicationImage = "skopeo:v1.9.2"
+	// defHubReplicationHistoryLimit is the number of the finished jobs kept by default.
+	defHubReplicationHistoryLimit = 3
+	// hubReplicationUsername and hubReplicationPassword are the keys of the credential secrets.
+	hubReplicationUsername = "username"
+	hubReplicationPassword = "password"
+)
+
+// hubReplicationCatalogScript parses the repositories separated by spaces into $REPOS from the response of the
+// catalog API of the registry in $CATALOG, such as {"repositories":["rainbond/rbd-api","runner"]}.
+const hubReplicationCatalogScript = `REPOS=$(echo "$CATALOG" | tr -d '{}[]" ' | sed 's/^repositories://' | tr ',' ' ')
+`
+
+// hubReplicationScript lists the repositories of the image hub, and syncs all the tags of each of them to the
+// secondary registry under the same path. skopeo skips the blobs that already exist in the secondary registry.
+// The other repositories are still synced if one of them fails, while the job fails in the end.
+const hubReplicationScript = `set -e
+CATALOG=$(curl -fsS -u "$SOURCE_USER:$SOURCE_PASS" "http://$SOURCE_REGISTRY/v2/_catalog?n=100000")
+` + hubReplicationCatalogScript + `set --
+if [ -n "$TARGET_USER" ]; then
+  set -- --dest-creds "$TARGET_USER:$TARGET_PASS"
+fi
+FAILED=0
+for REPO in $REPOS; do
+  case "$REPO" in
+    */*) DEST="$TARGET_PREFIX/${REPO%/*}" ;;
+    *) DEST="$TARGET_PREFIX" ;;
+  esac
+  echo "replicate $REPO to $DEST"
+  skopeo sync --all --src docker --dest docker --src-tls-verify=false --dest-tls-verify="$TARGET_TLS_VERIFY" \
+    --src-creds "$SOURCE_USER:$SOURCE_PASS" "$@" "$SOURCE_REGISTRY/$REPO" "$DEST" || FAILED=1
+done
+exit $FAILED
+`
+
+func (h *hub) replication() *rainbondv1alpha1.ImageHubReplication {
+	if h.cluster.Spec.ImageHub == nil {
+		return nil
+	}
+	return h.cluster.Spec.ImageHub.Replication
+}
+
+// checkReplication checks the replication is complete, and the credential secret of the secondary registry exists.
+func (h *hub) checkReplication() error {
+	replication := h.replication()
+	if replication == nil {
+		return nil
+	}
+	if replication.Registry == "" {
+		return NewPermanentError("registry of the replication of the image hub is required")
+	}
+	if replication.CredentialSecretName == "" {
+		return nil
+	}
+	secret, err := h.getSecret(replication.CredentialSecretName)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return NewWaitForDependencyError(fmt.Sprintf("secret %s of the replication not found", replication.CredentialSecretName))
+		}
+		return fmt.Errorf("get secret %s: %v", replication.CredentialSecretName, err)
+	}
+	for _, key := range []string{hubReplicationUsername, hubReplicationPassword} {
+		if len(secret.Data[key]) == 0 {
+			return NewPermanentError(fmt.Sprintf("no %s in secret %s of the replication", key, replication.CredentialSecretName))
+		}
+	}
+	return nil
+}
+
+// hubReplicationSecret returns the secret of the credentials of the image hub for the replication, or nil if it is
+// disabled.
+func (h *hub) hubReplicationSecret() client.Object {
+	if h.replication() == nil {
+		return nil
+	}
+	labels := copyLabels(h.labels)
+	labels["name"] = HubReplicationName
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HubReplicationName,
+			Namespace: h.component.Namespace,
+			Labels:    labels,
+		},
+		Data: map[string][]byte{
+			hubReplicationUsername: []byte(h.cluster.Spec.ImageHub.Username),
+			hubReplicationPassword: []byte(h.cluster.Spec.ImageHub.Password),
+		},
+	}
+}
+
+// hubReplicationCronJob returns the CronJob of the replication, or nil if it is disabled.
+func (h *hub) hubReplicationCronJob() client.Object {
+	replication := h.replication()
+	if replication == nil {
+		return nil
+	}
+	schedule := replication.Schedule
+	if schedule == "" {
+		schedule = defHubReplicationSchedule
+	}
+	image := replication.Image
+	if image == "" {
+		image = path.Join(h.cluster.Spec.RainbondImageRepository, DefHubReplicationImage)
+	}
+	historyLimit := int32(defHubReplicationHistoryLimit)
+	if replication.HistoryLimit != nil {
+		historyLimit = *replication.HistoryLimit
+	}
+	prefix := replication.Registry
+	if replication.Namespace != "" {
+		prefix += "/" + replication.Namespace
+	}
+
+	labels := copyLabels(h.labels)
+	labels["name"] = HubReplicationName
+
+	env := []corev1.EnvVar{
+		{
+			// the image hub is accessed by the service, rather than goodrain.me through the gateway.
+			Name:  "SOURCE_REGISTRY",
+			Value: fmt.Sprintf("%s:%d", HubName, 5000),
+		},
+		secretEnv("SOURCE_USER", HubReplicationName, hubReplicationUsername),
+		secretEnv("SOURCE_PASS", HubReplicationName, hubReplicationPassword),
+		{
+			Name:  "TARGET_PREFIX",
+			Value: prefix,
+		},
+		{
+			Name:  "TARGET_TLS_VERIFY",
+			Value: strconv.FormatBool(!replication.InsecureSkipVerify),
+		},
+	}
+	if replication.CredentialSecretName != "" {
+		env = append(env,
+			secretEnv("TARGET_USER", replication.CredentialSecretName, hubReplicationUsername),
+			secretEnv("TARGET_PASS", replication.CredentialSecretName, hubReplicationPassword),
+		)
+	}
+
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HubReplicationName,
+			Namespace: h.component.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule: schedule,
+			// the next replication is skipped if the last one is still running.
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: commonutil.Int32(historyLimit),
+			FailedJobsHistoryLimit:     commonutil.Int32(historyLimit),
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					// the failed replication is retried by the next schedule.
+					BackoffLimit: commonutil.Int32(0),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: corev1.PodSpec{
+							RestartPolicy:    corev1.RestartPolicyNever,
+							ImagePullSecrets: imagePullSecrets(h.component, h.cluster),
+							Containers: []corev1.Container{
+								{
+									Name:            HubReplicationName,
+									Image:           image,
+									ImagePullPolicy: h.component.ImagePullPolicy(),
+									Command:         []string{"/bin/sh", "-c", hubReplicationScript},
+									Env:             env,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func secretEnv(name, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHubReplicationCronJob(t *testing.T) {
+	h := &hub{
+		component: &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: HubName, Namespace: "rbd-system"},
+		},
+		cluster: &rainbondv1alpha1.RainbondCluster{
+			Spec: rainbondv1alpha1.RainbondClusterSpec{
+				RainbondImageRepository: "registry.cn-hangzhou.aliyuncs.com/goodrain",
+				ImageHub:                &rainbondv1alpha1.ImageHub{Username: "admin", Password: "pass"},
+			},
+		},
+		labels: map[string]string{"name": HubName},
+	}
+	assert.Nil(t, h.hubReplicationCronJob())
+	assert.Nil(t, h.hubReplicationSecret())
+
+	h.cluster.Spec.ImageHub.Replication = &rainbondv1alpha1.ImageHubReplication{
+		Registry:             "registry.example.com",
+		Namespace:            "rainbond/dr",
+		CredentialSecretName: "dr-registry",
+		InsecureSkipVerify:   true,
+	}
+	cronJob := h.hubReplicationCronJob().(*batchv1beta1.CronJob)
+	assert.Equal(t, defHubReplicationSchedule, cronJob.Spec.Schedule)
+	assert.Equal(t, batchv1beta1.ForbidConcurrent, cronJob.Spec.ConcurrencyPolicy)
+	assert.Equal(t, HubReplicationName, cronJob.Spec.JobTemplate.Spec.Template.Labels["name"])
+	container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "registry.cn-hangzhou.aliyuncs.com/goodrain/skopeo:v1.9.2", container.Image)
+	env := make(map[string]corev1.EnvVar)
+	for _, e := range container.Env {
+		env[e.Name] = e
+	}
+	assert.Equal(t, "rbd-hub:5000", env["SOURCE_REGISTRY"].Value)
+	assert.Equal(t, "registry.example.com/rainbond/dr", env["TARGET_PREFIX"].Value)
+	assert.Equal(t, "false", env["TARGET_TLS_VERIFY"].Value)
+	assert.Equal(t, HubReplicationName, env["SOURCE_PASS"].ValueFrom.SecretKeyRef.Name)
+	assert.Equal(t, "dr-registry", env["TARGET_PASS"].ValueFrom.SecretKeyRef.Name)
+
+	secret := h.hubReplicationSecret().(*corev1.Secret)
+	assert.Equal(t, []byte("pass"), secret.Data[hubReplicationPassword])
+
+	h.cluster.Spec.ImageHub.Replication.CredentialSecretName = ""
+	cronJob = h.hubReplicationCronJob().(*batchv1beta1.CronJob)
+	for _, e := range cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env {
+		assert.NotEqual(t, "TARGET_USER", e.Name)
+	}
+}
+
+func TestHubReplicationCatalogScript(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	tests := []struct {
+		name    string
+		catalog string
+		want    []string
+	}{
+		{
+			name:    "repositories",
+			catalog: `{"repositories":["rainbond/rbd-api","runner"]}`,
+			want:    []string{"rainbond/rbd-api", "runner"},
+		},
+		{
+			name:    "nested repositories",
+			catalog: `{"repositories":["goodrain.me/a/b/c"]}`,
+			want:    []string{"goodrain.me/a/b/c"},
+		},
+		{
+			name:    "no repositories",
+			catalog: `{"repositories":[]}`,
+			want:    []string{},
+		},
+		{
+			name: "indented",
+			catalog: `{
+  "repositories": [
+    "rainbond/rbd-api",
+    "runner"
+  ]
+}`,
+			want: []string{"rainbond/rbd-api", "runner"},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(sh, "-c", hubReplicationCatalogScript+`for REPO in $REPOS; do echo "$REPO"; done`)
+			cmd.Env = []string{"CATALOG=" + tc.catalog, "PATH=/usr/bin:/bin"}
+			out, err := cmd.Output()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, strings.Fields(string(out)))
+		})
+	}
+}
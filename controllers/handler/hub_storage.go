@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// defHubObjectStorageRegion is the region of the bucket by default, which is required by the registry
+	// even for the S3 compatible services.
+	defHubObjectStorageRegion = "us-east-1"
+	// hubObjectStorageAccessKey and hubObjectStorageSecretKey are the keys of the credential secret.
+	hubObjectStorageAccessKey = "accessKey"
+	hubObjectStorageSecretKey = "secretKey"
+)
+
+// objectStorage returns the object storage of the image hub, or nil if the images are stored in the volume.
+func (h *hub) objectStorage() *rainbondv1alpha1.ImageHubObjectStorage {
+	if h.cluster.Spec.ImageHub == nil {
+		return nil
+	}
+	return h.cluster.Spec.ImageHub.ObjectStorage
+}
+
+// checkObjectStorage checks the object storage is complete, and its credential secret exists.
+func (h *hub) checkObjectStorage() error {
+	storage := h.objectStorage()
+	if storage == nil {
+		return nil
+	}
+	if storage.Bucket == "" || storage.CredentialSecretName == "" {
+		return NewPermanentError("both bucket and credentialSecretName of the object storage of the image hub are required")
+	}
+	secret, err := h.getSecret(storage.CredentialSecretName)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return NewWaitForDependencyError(fmt.Sprintf("secret %s of the object storage not found", storage.CredentialSecretName))
+		}
+		return fmt.Errorf("get secret %s: %v", storage.CredentialSecretName, err)
+	}
+	for _, key := range []string{hubObjectStorageAccessKey, hubObjectStorageSecretKey} {
+		if len(secret.Data[key]) == 0 {
+			return NewPermanentError(fmt.Sprintf("no %s in secret %s of the object storage", key, storage.CredentialSecretName))
+		}
+	}
+	return nil
+}
+
+// storageEnv returns the environment variables that configure the registry to store the images in the object
+// storage, which override the filesystem storage of the configuration file.
+func (h *hub) storageEnv() []corev1.EnvVar {
+	storage := h.objectStorage()
+	if storage == nil {
+		return nil
+	}
+	region := storage.Region
+	if region == "" {
+		region = defHubObjectStorageRegion
+	}
+	env := []corev1.EnvVar{
+		{
+			Name:  "REGISTRY_STORAGE",
+			Value: "s3",
+		},
+		{
+			Name:  "REGISTRY_STORAGE_S3_REGION",
+			Value: region,
+		},
+		{
+			Name:  "REGISTRY_STORAGE_S3_BUCKET",
+			Value: storage.Bucket,
+		},
+		{
+			Name:  "REGISTRY_STORAGE_S3_SKIPVERIFY",
+			Value: strconv.FormatBool(storage.InsecureSkipVerify),
+		},
+		{
+			// serve the blobs through the image hub, the nodes may not reach the object storage.
+			Name:  "REGISTRY_STORAGE_REDIRECT_DISABLE",
+			Value: "true",
+		},
+	}
+	if storage.Endpoint != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "REGISTRY_STORAGE_S3_REGIONENDPOINT",
+			Value: storage.Endpoint,
+		})
+	}
+	if storage.RootDirectory != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "REGISTRY_STORAGE_S3_ROOTDIRECTORY",
+			Value: storage.RootDirectory,
+		})
+	}
+	for _, key := range []struct{ env, key string }{
+		{"REGISTRY_STORAGE_S3_ACCESSKEY", hubObjectStorageAccessKey},
+		{"REGISTRY_STORAGE_S3_SECRETKEY", hubObjectStorageSecretKey},
+	} {
+		env = append(env, corev1.EnvVar{
+			Name: key.env,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: storage.CredentialSecretName},
+					Key:                  key.key,
+				},
+			},
+		})
+	}
+	return env
+}
+
+// dataVolumes returns the volume that stores the images, none if they are stored in the object storage.
+func (h *hub) dataVolumes() ([]corev1.Volume, []corev1.VolumeMount) {
+	if h.objectStorage() != nil {
+		return nil, nil
+	}
+	claimName := hubDataPvcName
+	if existingClaim := existingClaimName(h.component); existingClaim != "" {
+		claimName = existingClaim
+	}
+	volumes := []corev1.Volume{claimVolume("hubdata", claimName)}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "hubdata",
+			MountPath: "/var/lib/registry",
+		},
+	}
+	return volumes, volumeMounts
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHubObjectStorage(t *testing.T) {
+	h := &hub{
+		component: &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: HubName, Namespace: "rbd-system"},
+			Spec:       rainbondv1alpha1.RbdComponentSpec{Image: "registry:2.6.2"},
+		},
+		cluster: &rainbondv1alpha1.RainbondCluster{
+			Spec: rainbondv1alpha1.RainbondClusterSpec{
+				ImageHub: &rainbondv1alpha1.ImageHub{
+					GarbageCollection: &rainbondv1alpha1.ImageHubGarbageCollection{},
+					ObjectStorage: &rainbondv1alpha1.ImageHubObjectStorage{
+						Endpoint:             "http://minio.minio:9000",
+						Bucket:               "rainbond",
+						CredentialSecretName: "hub-s3",
+					},
+				},
+			},
+		},
+		labels: map[string]string{"name": HubName},
+	}
+	assert.Nil(t, h.persistentVolumeClaimForHub())
+
+	env := make(map[string]corev1.EnvVar)
+	for _, e := range h.storageEnv() {
+		env[e.Name] = e
+	}
+	assert.Equal(t, "s3", env["REGISTRY_STORAGE"].Value)
+	assert.Equal(t, defHubObjectStorageRegion, env["REGISTRY_STORAGE_S3_REGION"].Value)
+	assert.Equal(t, "http://minio.minio:9000", env["REGISTRY_STORAGE_S3_REGIONENDPOINT"].Value)
+	assert.Equal(t, "true", env["REGISTRY_STORAGE_REDIRECT_DISABLE"].Value)
+	assert.NotContains(t, env, "REGISTRY_STORAGE_S3_ROOTDIRECTORY")
+	if ref := env["REGISTRY_STORAGE_S3_SECRETKEY"].ValueFrom; assert.NotNil(t, ref) {
+		assert.Equal(t, "hub-s3", ref.SecretKeyRef.Name)
+		assert.Equal(t, hubObjectStorageSecretKey, ref.SecretKeyRef.Key)
+	}
+
+	// neither the image hub nor the garbage collection mounts the volume.
+	deploy := h.deployment().(*appsv1.Deployment)
+	for _, volume := range deploy.Spec.Template.Spec.Volumes {
+		assert.Nil(t, volume.PersistentVolumeClaim)
+	}
+	cronJob := h.hubGCCronJob().(*batchv1beta1.CronJob)
+	podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+	assert.Nil(t, podSpec.Affinity)
+	for _, volume := range podSpec.Volumes {
+		assert.Nil(t, volume.PersistentVolumeClaim)
+	}
+	assert.Contains(t, podSpec.Containers[0].Env, corev1.EnvVar{Name: "REGISTRY_STORAGE", Value: "s3"})
+}
+
+func TestCheckObjectStorage(t *testing.T) {
+	secret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-s3", Namespace: "rbd-system"},
+			Data:       data,
+		}
+	}
+	tests := []struct {
+		name     string
+		storage  *rainbondv1alpha1.ImageHubObjectStorage
+		existing []runtime.Object
+		wantKind ErrorKind
+	}{
+		{
+			name: "volume",
+		},
+		{
+			name:     "no bucket",
+			storage:  &rainbondv1alpha1.ImageHubObjectStorage{CredentialSecretName: "hub-s3"},
+			wantKind: ErrorKindPermanent,
+		},
+		{
+			name:     "secret not found",
+			storage:  &rainbondv1alpha1.ImageHubObjectStorage{Bucket: "rainbond", CredentialSecretName: "hub-s3"},
+			wantKind: ErrorKindWaitForDependency,
+		},
+		{
+			name:     "no secret key",
+			storage:  &rainbondv1alpha1.ImageHubObjectStorage{Bucket: "rainbond", CredentialSecretName: "hub-s3"},
+			existing: []runtime.Object{secret(map[string][]byte{"accessKey": []byte("admin")})},
+			wantKind: ErrorKindPermanent,
+		},
+		{
+			name:    "complete",
+			storage: &rainbondv1alpha1.ImageHubObjectStorage{Bucket: "rainbond", CredentialSecretName: "hub-s3"},
+			existing: []runtime.Object{secret(map[string][]byte{
+				"accessKey": []byte("admin"),
+				"secretKey": []byte("pass"),
+			})},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			h := &hub{
+				ctx:       context.Background(),
+				client:    fake.NewFakeClientWithScheme(scheme, tc.existing...),
+				component: &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Name: HubName, Namespace: "rbd-system"}},
+				cluster: &rainbondv1alpha1.RainbondCluster{
+					Spec: rainbondv1alpha1.RainbondClusterSpec{
+						ImageHub: &rainbondv1alpha1.ImageHub{ObjectStorage: tc.storage},
+					},
+				},
+			}
+			err := h.checkObjectStorage()
+			if tc.wantKind == "" {
+				assert.Nil(t, err)
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tc.wantKind, ErrorKindOf(err))
+			}
+		})
+	}
+}
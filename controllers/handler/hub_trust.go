@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// hubTrustName is the name of the daemonset that installs the certificate of the image hub into the trust stores
+// of the container runtimes on each node, and verifies the image hub is trusted by pulling an image from it.
+// It is only deployed if imageHub.nodeTrust of the rainbondcluster is enabled.
+const hubTrustName = "rbd-hub-trust"
+
+// hubTrustPullContainer is the init container whose image is pulled from the image hub once the certificate is
+// installed, which exits at once.
+const hubTrustPullContainer = "pull-check"
+
+// imagePullErrors are the reasons of the waiting containers whose images can't be pulled.
+var imagePullErrors = map[string]bool{
+	"ErrImagePull":        true,
+	"ImagePullBackOff":    true,
+	"RegistryUnavailable": true,
+	"InvalidImageName":    true,
+}
+
+// hubTrustScript installs the certificate into the certs.d of the container runtimes found on the node. The certs.d
+// of docker is read as long as docker is installed, while the one of containerd is only read if it is the
+// config_path of the registry of the cri plugin, in which case the hosts.toml is written as well. The pod is
+// recreated to install the certificate again once it is renewed.
+const hubTrustScript = `cert=/hub-cert/ca.crt
+[ -f $cert ] || cert=/hub-cert/tls.crt
+install_cert() {
+  mkdir -p $1
+  cmp -s $cert $1/ca.crt || cp $cert $1/ca.crt
+}
+if [ -d /host/etc/docker ]; then
+  install_cert /host/etc/docker/certs.d/%[1]s
+fi
+config=/host/etc/containerd/config.toml
+if grep -qs 'config_path *= *"[^"]*/etc/containerd/certs.d' $config; then
+  dir=/host/etc/containerd/certs.d/%[1]s
+  install_cert $dir
+  [ -f $dir/hosts.toml ] || printf 'server = "https://%[1]s"\n\n[host."https://%[1]s"]\n  ca = "/etc/containerd/certs.d/%[1]s/ca.crt"\n' > $dir/hosts.toml
+elif [ -f $config ]; then
+  echo "the config_path of the registry of containerd on $NODE_NAME doesn't include /etc/containerd/certs.d, trust %[1]s on the node by hand"
+fi`
+
+func (h *hub) hubTrustLabels() map[string]string {
+	labels := copyLabels(h.labels)
+	labels["name"] = hubTrustName
+	return labels
+}
+
+// hubTrustImage returns the image pulled from the image hub, which is mirrored by imageSyncForHubTrust.
+func (h *hub) hubTrustImage() string {
+	return path.Join(rbdutil.GetImageRepository(h.cluster), "busybox:latest")
+}
+
+// imageSyncForHubTrust mirrors the busybox into the image hub, which is pulled to verify the image hub is trusted.
+func (h *hub) imageSyncForHubTrust() client.Object {
+	return &rainbondv1alpha1.ImageSync{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hubTrustName,
+			Namespace: h.component.Namespace,
+			Labels:    h.hubTrustLabels(),
+		},
+		Spec: rainbondv1alpha1.ImageSyncSpec{
+			Images: []string{path.Join(h.cluster.Spec.RainbondImageRepository, "busybox:latest")},
+		},
+	}
+}
+
+// hubTrustEnabled returns true if the certificate of the image hub is distributed to the nodes.
+func (h *hub) hubTrustEnabled() bool {
+	return h.cluster.Spec.ImageHub != nil && h.cluster.Spec.ImageHub.NodeTrust
+}
+
+// daemonSetForHubTrust returns the daemonset rbd-hub-trust, or nil if the node trust is disabled.
+func (h *hub) daemonSetForHubTrust() client.Object {
+	if !h.hubTrustEnabled() {
+		return nil
+	}
+	labels := h.hubTrustLabels()
+	secret, _ := h.getSecret(hubImageRepository)
+	var certHash string
+	if secret != nil {
+		cert := secret.Data["ca.crt"]
+		if len(cert) == 0 {
+			cert = secret.Data["tls.crt"]
+		}
+		certHash = fmt.Sprintf("%x", sha256.Sum256(cert))
+	}
+
+	// the image hub requires the credential, even if the hub itself is pulled without it.
+	var pullSecrets []corev1.LocalObjectReference
+	if secret := h.cluster.Status.ImagePullSecret; secret != nil {
+		pullSecrets = append(pullSecrets, *secret)
+	}
+	installer := path.Join(h.cluster.Spec.RainbondImageRepository, "busybox")
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hubTrustName,
+			Namespace: h.component.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					// install the certificate and verify the pulls again once it is renewed.
+					Annotations: map[string]string{
+						"rainbond.io/hub-cert-sha256": certHash,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName:            serviceAccountName(h.component),
+					TerminationGracePeriodSeconds: commonutil.Int64(0),
+					Tolerations: []corev1.Toleration{
+						{
+							Operator: corev1.TolerationOpExists, // tolerate everything.
+						},
+					},
+					ImagePullSecrets: pullSecrets,
+					InitContainers: []corev1.Container{
+						{
+							Name:            "install-cert",
+							Image:           installer,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"/bin/sh", "-c", fmt.Sprintf(hubTrustScript, constants.DefImageRepository)},
+							Env: []corev1.EnvVar{
+								{
+									Name: "NODE_NAME",
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "spec.nodeName",
+										},
+									},
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "hub-cert", MountPath: "/hub-cert", ReadOnly: true},
+								{Name: "host-etc", MountPath: "/host/etc"},
+							},
+						},
+						{
+							// the image is pulled by the container runtime of the node once the certificate
+							// is installed, which fails if the certificate is not trusted.
+							Name:            hubTrustPullContainer,
+							Image:           h.hubTrustImage(),
+							ImagePullPolicy: corev1.PullAlways,
+							Command:         []string{"/bin/true"},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							// keeps the pod, so that the verification is not repeated until the certificate is renewed.
+							Name:            "pause",
+							Image:           installer,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"/bin/sh", "-c", "trap 'exit 0' TERM; while true; do sleep 3600 & wait; done"},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "hub-cert",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: hubImageRepository},
+							},
+						},
+						{
+							Name: "host-etc",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/etc",
+									Type: k8sutil.HostPath(corev1.HostPathDirectory),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return ds
+}
+
+// Conditions returns whether the image hub is trusted by the nodes, which is verified by the pulls of the pods
+// of rbd-hub-trust if the node trust is enabled, and whether the image hub is writable.
+func (h *hub) Conditions() []*rainbondv1alpha1.RbdComponentCondition {
+	if !h.hubTrustEnabled() {
+		return []*rainbondv1alpha1.RbdComponentCondition{h.hubWritableCondition()}
+	}
+	pods, err := listPods(h.ctx, h.client, h.component.Namespace, h.hubTrustLabels())
+	if err != nil {
+		return []*rainbondv1alpha1.RbdComponentCondition{
+			rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ImageHubTrusted, corev1.ConditionUnknown,
+				rainbondv1alpha1.ReasonListPodsFailed, err.Error()),
+			h.hubWritableCondition(),
+		}
+	}
+	return []*rainbondv1alpha1.RbdComponentCondition{hubTrustCondition(pods), h.hubWritableCondition()}
+}
+
+// hubTrustCondition returns the condition of the image hub trust from the pulls of the pods of rbd-hub-trust.
+func hubTrustCondition(pods []corev1.Pod) *rainbondv1alpha1.RbdComponentCondition {
+	var failures, pending []string
+	for _, pod := range pods {
+		pulled, msg := imagePulled(pod, hubTrustPullContainer)
+		if pulled {
+			continue
+		}
+		if msg != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", pod.Spec.NodeName, msg))
+			continue
+		}
+		pending = append(pending, pod.Spec.NodeName)
+	}
+	sort.Strings(failures)
+	sort.Strings(pending)
+
+	switch {
+	case len(failures) > 0:
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ImageHubTrusted, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonImagePullFailed, "pull from the image hub failed on "+strings.Join(failures, "; "))
+	case len(pods) == 0 || len(pending) > 0:
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ImageHubTrusted, corev1.ConditionUnknown,
+			rainbondv1alpha1.ReasonInProgress, fmt.Sprintf("verifying the pulls from the image hub on %v", pending))
+	}
+	return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ImageHubTrusted, corev1.ConditionTrue,
+		rainbondv1alpha1.ReasonImagePullVerified, fmt.Sprintf("the image hub is trusted by %d nodes", len(pods)))
+}
+
+// imagePulled checks if the image of the container or the init container is pulled, the message is the error
+// of the pull if it failed.
+func imagePulled(pod corev1.Pod, container string) (bool, string) {
+	var statuses []corev1.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.Name != container {
+			continue
+		}
+		if status.ImageID != "" {
+			return true, ""
+		}
+		if waiting := status.State.Waiting; waiting != nil && imagePullErrors[waiting.Reason] {
+			if waiting.Message == "" {
+				return false, waiting.Reason
+			}
+			return false, waiting.Message
+		}
+	}
+	return false, ""
+}
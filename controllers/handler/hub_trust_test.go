@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func hubTrustPod(node string, state corev1.ContainerState, imageID string) corev1.Pod {
+	return corev1.Pod{
+		Spec: corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "install-cert", ImageID: "busybox@sha256:1"},
+				{Name: hubTrustPullContainer, State: state, ImageID: imageID},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "pause", ImageID: "busybox@sha256:1"},
+			},
+		},
+	}
+}
+
+func TestHubTrustCondition(t *testing.T) {
+	pulled := hubTrustPod("node1", corev1.ContainerState{}, "goodrain.me/busybox@sha256:1")
+	creating := hubTrustPod("node2", corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"},
+	}, "")
+	failed := hubTrustPod("node3", corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "x509: certificate signed by unknown authority"},
+	}, "")
+
+	condition := hubTrustCondition(nil)
+	assert.Equal(t, corev1.ConditionUnknown, condition.Status)
+
+	condition = hubTrustCondition([]corev1.Pod{pulled})
+	assert.Equal(t, rainbondv1alpha1.ImageHubTrusted, condition.Type)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Equal(t, rainbondv1alpha1.ReasonImagePullVerified, condition.Reason)
+
+	condition = hubTrustCondition([]corev1.Pod{pulled, creating})
+	assert.Equal(t, corev1.ConditionUnknown, condition.Status)
+	assert.Contains(t, condition.Message, "node2")
+
+	condition = hubTrustCondition([]corev1.Pod{pulled, creating, failed})
+	assert.Equal(t, corev1.ConditionFalse, condition.Status)
+	assert.Equal(t, rainbondv1alpha1.ReasonImagePullFailed, condition.Reason)
+	assert.Contains(t, condition.Message, "node3: x509: certificate signed by unknown authority")
+}
+
+func TestImagePulled(t *testing.T) {
+	backOff := hubTrustPod("node1", corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"},
+	}, "")
+	pulled, msg := imagePulled(backOff, hubTrustPullContainer)
+	assert.False(t, pulled)
+	assert.Equal(t, "ImagePullBackOff", msg)
+
+	pulled, msg = imagePulled(backOff, "install-cert")
+	assert.True(t, pulled)
+	assert.Empty(t, msg)
+
+	pulled, msg = imagePulled(backOff, "unknown")
+	assert.False(t, pulled)
+	assert.Empty(t, msg)
+}
+
+func TestHubTrustDisabled(t *testing.T) {
+	h := &hub{
+		component: &rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: HubName, Namespace: "rbd-system"},
+		},
+		cluster: &rainbondv1alpha1.RainbondCluster{
+			Spec: rainbondv1alpha1.RainbondClusterSpec{
+				ImageHub: &rainbondv1alpha1.ImageHub{},
+			},
+		},
+		labels: map[string]string{"name": HubName},
+	}
+	assert.Nil(t, h.daemonSetForHubTrust())
+	assert.Empty(t, h.ResourcesCreateIfNotExists())
+
+	var deleted []string
+	for _, obj := range h.ResourcesNeedDelete() {
+		if _, ok := obj.(*appsv1.DaemonSet); ok {
+			deleted = append(deleted, obj.GetName())
+		}
+	}
+	assert.Equal(t, []string{hubTrustName}, deleted)
+
+	conditions := h.Conditions()
+	if assert.Len(t, conditions, 1) {
+		assert.Equal(t, rainbondv1alpha1.ImageHubWritable, conditions[0].Type)
+	}
+}
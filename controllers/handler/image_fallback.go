@@ -0,0 +1,28 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetImageFallback replaces the image that can't be pulled with the fallback image in the containers of
+// the given object, which is a workload or a job generated by the handlers. Nothing is changed if fallback is nil.
+func SetImageFallback(obj client.Object, fallback *rainbondv1alpha1.ImageFallback) {
+	if fallback == nil {
+		return
+	}
+	template := podTemplate(obj)
+	if template == nil {
+		return
+	}
+	for i := range template.Spec.InitContainers {
+		if template.Spec.InitContainers[i].Image == fallback.Image {
+			template.Spec.InitContainers[i].Image = fallback.FallbackImage
+		}
+	}
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Image == fallback.Image {
+			template.Spec.Containers[i].Image = fallback.FallbackImage
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetImageFallback(t *testing.T) {
+	deploy := &appsv1.Deployment{}
+	deploy.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "rbd-api", Image: "goodrain.me/rbd-api:v5.3.3"},
+		{Name: "sidecar", Image: "goodrain.me/busybox"},
+	}
+	SetImageFallback(deploy, nil)
+	assert.Equal(t, "goodrain.me/rbd-api:v5.3.3", deploy.Spec.Template.Spec.Containers[0].Image)
+
+	SetImageFallback(deploy, &rainbondv1alpha1.ImageFallback{
+		Image:         "goodrain.me/rbd-api:v5.3.3",
+		FallbackImage: "registry.cn-hangzhou.aliyuncs.com/goodrain/rbd-api@sha256:aaa",
+	})
+	assert.Equal(t, "registry.cn-hangzhou.aliyuncs.com/goodrain/rbd-api@sha256:aaa", deploy.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "goodrain.me/busybox", deploy.Spec.Template.Spec.Containers[1].Image)
+}
@@ -6,6 +6,8 @@ import (
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -98,6 +100,8 @@ func (k *k8sdashbaord) deploymentForKubernetesDashboard() client.Object {
 	volumeMounts = mergeVolumeMounts(volumeMounts, k.component.Spec.VolumeMounts)
 	volumes = mergeVolumes(volumes, k.component.Spec.Volumes)
 
+	livenessProbe := probeutil.MakeProbe(probeutil.ProbeKindHTTP, "", "/", 8443, corev1.URISchemeHTTPS, nil)
+	probeutil.SetProbeArgs(livenessProbe, 30, 30, 10, 0, 0)
 	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      KubernetesDashboardName,
@@ -106,6 +110,7 @@ func (k *k8sdashbaord) deploymentForKubernetesDashboard() client.Object {
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: k.component.Spec.Replicas,
+			Strategy: deploymentStrategy(k.component, appsv1.RollingUpdateDeploymentStrategyType),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -115,19 +120,20 @@ func (k *k8sdashbaord) deploymentForKubernetesDashboard() client.Object {
 					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
-					ServiceAccountName: "rainbond-operator",
+					ServiceAccountName: rbdutil.ServiceAccountName(),
 					Tolerations: []corev1.Toleration{
 						{
 							Operator: corev1.TolerationOpExists, // tolerate everything.
 						},
 					},
 					ImagePullSecrets:              imagePullSecrets(k.component, k.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
+					TerminationGracePeriodSeconds: terminationGracePeriodSeconds(k.component, 0),
 					Containers: []corev1.Container{
 						{
 							Name:            KubernetesDashboardName,
 							Image:           k.component.Spec.Image,
 							ImagePullPolicy: k.component.ImagePullPolicy(),
+							Lifecycle:       lifecycle(k.component, nil),
 							Args:            args,
 							Ports: []corev1.ContainerPort{
 								{
@@ -142,8 +148,9 @@ func (k *k8sdashbaord) deploymentForKubernetesDashboard() client.Object {
 							SecurityContext: &corev1.SecurityContext{
 								Privileged: commonutil.Bool(true),
 							},
-							VolumeMounts: volumeMounts,
-							Resources:    k.component.Spec.Resources,
+							VolumeMounts:  volumeMounts,
+							Resources:     k.component.Spec.Resources,
+							LivenessProbe: livenessProbe,
 						},
 					},
 					Volumes: volumes,
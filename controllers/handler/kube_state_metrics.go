@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KubeStateMetricsName name for kube-state-metrics.
+var KubeStateMetricsName = "kube-state-metrics"
+
+// DefKubeStateMetricsImage is the image of kube-state-metrics in the image repository of rainbond, which supports
+// the flags of the deployment.
+const DefKubeStateMetricsImage = "kube-state-metrics:v2.3.0"
+
+// kubeStateMetricsResources are the resources collected by kube-state-metrics, which are also
+// the only resources it is allowed to read.
+var kubeStateMetricsResources = []struct {
+	apiGroup string
+	// resource is the name of the resource in the RBAC rules.
+	resource string
+	// collector is the name of the collector of kube-state-metrics.
+	collector string
+}{
+	{apiGroup: "", resource: "pods", collector: "pods"},
+	{apiGroup: "", resource: "services", collector: "services"},
+	{apiGroup: "", resource: "persistentvolumeclaims", collector: "persistentvolumeclaims"},
+	{apiGroup: "apps", resource: "deployments", collector: "deployments"},
+	{apiGroup: "apps", resource: "statefulsets", collector: "statefulsets"},
+	{apiGroup: "apps", resource: "daemonsets", collector: "daemonsets"},
+	{apiGroup: "apps", resource: "replicasets", collector: "replicasets"},
+	{apiGroup: "batch", resource: "jobs", collector: "jobs"},
+	{apiGroup: "batch", resource: "cronjobs", collector: "cronjobs"},
+	{apiGroup: "autoscaling", resource: "horizontalpodautoscalers", collector: "horizontalpodautoscalers"},
+}
+
+type kubeStateMetrics struct {
+	ctx       context.Context
+	client    client.Client
+	component *rainbondv1alpha1.RbdComponent
+	cluster   *rainbondv1alpha1.RainbondCluster
+	labels    map[string]string
+
+	namespaces []string
+}
+
+var _ ComponentHandler = &kubeStateMetrics{}
+var _ ClusterScopedResourcesCreator = &kubeStateMetrics{}
+
+// NewKubeStateMetrics creates a new kube-state-metrics handler.
+func NewKubeStateMetrics(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
+	return &kubeStateMetrics{
+		ctx:       ctx,
+		client:    client,
+		component: component,
+		cluster:   cluster,
+		labels:    LabelsForRainbondComponent(component),
+	}
+}
+
+func (k *kubeStateMetrics) Before() error {
+	// the namespace of Rainbond, and the namespaces of the tenants created by Rainbond.
+	namespaceList := &corev1.NamespaceList{}
+	if err := k.client.List(k.ctx, namespaceList, client.MatchingLabels{"creator": "Rainbond"}); err != nil {
+		return fmt.Errorf("list namespaces of rainbond: %v", err)
+	}
+	var namespaces []string
+	for _, ns := range namespaceList.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	k.namespaces = kubeStateMetricsNamespaces(k.component.Namespace, namespaces)
+	return nil
+}
+
+func (k *kubeStateMetrics) Resources() []client.Object {
+	return []client.Object{
+		k.serviceAccount(),
+		k.deployment(),
+		k.service(),
+		k.serviceMonitor(),
+	}
+}
+
+func (k *kubeStateMetrics) After() error {
+	return nil
+}
+
+func (k *kubeStateMetrics) ListPods() ([]corev1.Pod, error) {
+	return listPods(k.ctx, k.client, k.component.Namespace, k.labels)
+}
+
+// CreateClusterScoped returns the read-only cluster role of kube-state-metrics, which is limited to
+// the collected resources, rather than the cluster-admin of rainbond-operator.
+func (k *kubeStateMetrics) CreateClusterScoped() []client.Object {
+	return []client.Object{
+		k.clusterRole(),
+		k.clusterRoleBinding(),
+	}
+}
+
+// kubeStateMetricsNamespaces returns the sorted and distinct namespaces watched by kube-state-metrics.
+func kubeStateMetricsNamespaces(namespace string, namespaces []string) []string {
+	set := map[string]struct{}{namespace: {}}
+	for _, ns := range namespaces {
+		set[ns] = struct{}{}
+	}
+	result := make([]string, 0, len(set))
+	for ns := range set {
+		result = append(result, ns)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// rbacName returns the name of the cluster-scoped RBAC resources, which is unique among the namespaces.
+func (k *kubeStateMetrics) rbacName() string {
+	return KubeStateMetricsName + "-" + k.component.Namespace
+}
+
+func (k *kubeStateMetrics) serviceAccount() client.Object {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KubeStateMetricsName,
+			Namespace: k.component.Namespace,
+			Labels:    k.labels,
+		},
+	}
+}
+
+func (k *kubeStateMetrics) clusterRole() client.Object {
+	rules := map[string][]string{}
+	var apiGroups []string
+	for _, res := range kubeStateMetricsResources {
+		if _, ok := rules[res.apiGroup]; !ok {
+			apiGroups = append(apiGroups, res.apiGroup)
+		}
+		rules[res.apiGroup] = append(rules[res.apiGroup], res.resource)
+	}
+	cr := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   k.rbacName(),
+			Labels: rbdutil.LabelsForInstance(k.labels, k.component.Namespace),
+		},
+	}
+	for _, apiGroup := range apiGroups {
+		cr.Rules = append(cr.Rules, rbacv1.PolicyRule{
+			APIGroups: []string{apiGroup},
+			Resources: rules[apiGroup],
+			Verbs:     []string{"list", "watch"},
+		})
+	}
+	return cr
+}
+
+func (k *kubeStateMetrics) clusterRoleBinding() client.Object {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   k.rbacName(),
+			Labels: rbdutil.LabelsForInstance(k.labels, k.component.Namespace),
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     k.rbacName(),
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      KubeStateMetricsName,
+				Namespace: k.component.Namespace,
+			},
+		},
+	}
+}
+
+func (k *kubeStateMetrics) deployment() client.Object {
+	var collectors []string
+	for _, res := range kubeStateMetricsResources {
+		collectors = append(collectors, res.collector)
+	}
+	args := []string{
+		"--port=8080",
+		"--telemetry-port=8081",
+		"--namespaces=" + strings.Join(k.namespaces, ","),
+		"--resources=" + strings.Join(collectors, ","),
+	}
+
+	return buildDeployment(KubeStateMetricsName, k.component, k.cluster, k.labels, appsv1.RollingUpdateDeploymentStrategyType,
+		withArgs(args...),
+		withPorts(
+			corev1.ContainerPort{
+				Name:          "http-metrics",
+				ContainerPort: 8080,
+			},
+			corev1.ContainerPort{
+				Name:          "telemetry",
+				ContainerPort: 8081,
+			},
+		),
+		withProbes(probeutil.MakeLivenessProbeHTTP("", "/healthz", 8080), probeutil.MakeReadinessProbeHTTP("", "/", 8081)),
+		withServiceAccount(KubeStateMetricsName),
+	)
+}
+
+func (k *kubeStateMetrics) service() client.Object {
+	return buildService(KubeStateMetricsName, k.component.Namespace, k.labels, corev1.ServicePort{
+		Name:       "http-metrics",
+		Port:       8080,
+		TargetPort: intstr.FromString("http-metrics"),
+	})
+}
+
+// serviceMonitor adds kube-state-metrics to the scrape config of rbd-monitor.
+func (k *kubeStateMetrics) serviceMonitor() client.Object {
+	return &mv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KubeStateMetricsName,
+			Namespace: k.component.Namespace,
+			Labels:    k.labels,
+		},
+		Spec: mv1.ServiceMonitorSpec{
+			NamespaceSelector: mv1.NamespaceSelector{
+				MatchNames: []string{k.component.Namespace},
+			},
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"name": KubeStateMetricsName,
+				},
+			},
+			Endpoints: []mv1.Endpoint{
+				{
+					Port:          "http-metrics",
+					Path:          "/metrics",
+					Interval:      "1m",
+					ScrapeTimeout: "30s",
+					// keep the namespace and the pod of the objects rather than those of kube-state-metrics.
+					HonorLabels: true,
+				},
+			},
+			JobLabel: "name",
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKubeStateMetricsNamespaces(t *testing.T) {
+	namespaces := kubeStateMetricsNamespaces("rbd-system", []string{"tenant-b", "rbd-system", "tenant-a"})
+	assert.Equal(t, []string{"rbd-system", "tenant-a", "tenant-b"}, namespaces)
+}
+
+func TestKubeStateMetricsClusterRole(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{Name: KubeStateMetricsName, Namespace: "rbd-system"},
+	}
+	k := NewKubeStateMetrics(nil, nil, cpt, &rainbondv1alpha1.RainbondCluster{}).(*kubeStateMetrics)
+
+	cr := k.clusterRole().(*rbacv1.ClusterRole)
+	assert.Equal(t, "kube-state-metrics-rbd-system", cr.Name)
+	assert.Len(t, cr.Rules, 4)
+	for _, rule := range cr.Rules {
+		assert.Equal(t, []string{"list", "watch"}, rule.Verbs)
+	}
+	assert.Equal(t, []string{"pods", "services", "persistentvolumeclaims"}, cr.Rules[0].Resources)
+}
@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LogShipperName name for rbd-log-shipper.
+var LogShipperName = "rbd-log-shipper"
+
+const (
+	// DefLogShipperImage is the image of fluent-bit in the image repository of rainbond, which supports the
+	// outputs of Loki and Elasticsearch.
+	DefLogShipperImage = "fluent-bit:1.9.9"
+	// logShipperConfigPath is where the fluent-bit image reads the configuration.
+	logShipperConfigPath = "/fluent-bit/etc"
+	// defLogShippingIndex is the default prefix of the indexes of Elasticsearch.
+	defLogShippingIndex = "rainbond"
+)
+
+// logShipperParsers parses the logs of docker and containerd.
+const logShipperParsers = `[PARSER]
+    Name        docker
+    Format      json
+    Time_Key    time
+    Time_Format %Y-%m-%dT%H:%M:%S.%L
+    Time_Keep   On
+
+[PARSER]
+    Name        cri
+    Format      regex
+    Regex       ^(?<time>[^ ]+) (?<stream>stdout|stderr) (?<logtag>[^ ]*) (?<log>.*)$
+    Time_Key    time
+    Time_Format %Y-%m-%dT%H:%M:%S.%L%z
+`
+
+// logShipperInputs tails the logs of all the containers on the node, both the Rainbond components and the applications,
+// and adds the metadata of the pods.
+const logShipperInputs = `[SERVICE]
+    Flush         5
+    Log_Level     info
+    Parsers_File  parsers.conf
+    HTTP_Server   On
+    HTTP_Listen   0.0.0.0
+    HTTP_Port     2020
+
+[INPUT]
+    Name              tail
+    Tag               kube.*
+    Path              /var/log/containers/*.log
+    Exclude_Path      /var/log/containers/rbd-log-shipper-*.log
+    Parser            docker
+    DB                /var/lib/rbd-log-shipper/tail.db
+    Mem_Buf_Limit     10MB
+    Skip_Long_Lines   On
+    Refresh_Interval  10
+
+[FILTER]
+    Name                kubernetes
+    Match               kube.*
+    Merge_Log           On
+    Keep_Log            Off
+    K8S-Logging.Parser  On
+    K8S-Logging.Exclude On
+`
+
+type logShipper struct {
+	ctx       context.Context
+	client    client.Client
+	component *rainbondv1alpha1.RbdComponent
+	cluster   *rainbondv1alpha1.RainbondCluster
+	labels    map[string]string
+
+	config string
+	nodes  int32
+}
+
+var _ ComponentHandler = &logShipper{}
+var _ ResourcesDeleter = &logShipper{}
+var _ Replicaser = &logShipper{}
+
+// NewLogShipper creates a new rbd-log-shipper handler.
+func NewLogShipper(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
+	return &logShipper{
+		ctx:       ctx,
+		client:    client,
+		component: component,
+		cluster:   cluster,
+		labels:    LabelsForRainbondComponent(component),
+	}
+}
+
+func (l *logShipper) Before() error {
+	shipping := l.cluster.Spec.LogShipping
+	if shipping == nil {
+		return nil
+	}
+	output, err := fluentBitOutput(shipping)
+	if err != nil {
+		return NewPermanentError(fmt.Sprintf("invalid log shipping: %v", err))
+	}
+	l.config = logShipperInputs + "\n" + output
+
+	nodes, err := k8sutil.ListNodes(l.ctx, l.client)
+	if err != nil {
+		return fmt.Errorf("list nodes: %v", err)
+	}
+	l.nodes = int32(len(nodes))
+	return nil
+}
+
+func (l *logShipper) Resources() []client.Object {
+	if l.config == "" {
+		return nil
+	}
+	return []client.Object{
+		l.configMap(),
+		l.daemonSet(),
+	}
+}
+
+func (l *logShipper) After() error {
+	return nil
+}
+
+func (l *logShipper) ListPods() ([]corev1.Pod, error) {
+	return listPods(l.ctx, l.client, l.component.Namespace, l.labels)
+}
+
+// ResourcesNeedDelete returns the resources of the log shipper if the log shipping is disabled.
+func (l *logShipper) ResourcesNeedDelete() []client.Object {
+	if l.config != "" {
+		return nil
+	}
+	meta := metav1.ObjectMeta{
+		Name:      LogShipperName,
+		Namespace: l.component.Namespace,
+	}
+	return []client.Object{
+		&appsv1.DaemonSet{ObjectMeta: meta},
+		&corev1.ConfigMap{ObjectMeta: meta},
+	}
+}
+
+func (l *logShipper) Replicas() *int32 {
+	return commonutil.Int32(l.nodes)
+}
+
+func (l *logShipper) configMap() client.Object {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      LogShipperName,
+			Namespace: l.component.Namespace,
+			Labels:    l.labels,
+		},
+		Data: map[string]string{
+			"fluent-bit.conf": l.config,
+			"parsers.conf":    logShipperParsers,
+		},
+	}
+}
+
+func (l *logShipper) daemonSet() client.Object {
+	hostPaths := []struct {
+		name, path string
+		readOnly   bool
+	}{
+		{name: "varlog", path: "/var/log", readOnly: true},
+		{name: "dockercontainers", path: "/var/lib/docker/containers", readOnly: true},
+		{name: "state", path: "/var/lib/rbd-log-shipper"},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "config",
+			MountPath: logShipperConfigPath,
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: LogShipperName},
+				},
+			},
+		},
+	}
+	for _, hostPath := range hostPaths {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      hostPath.name,
+			MountPath: hostPath.path,
+			ReadOnly:  hostPath.readOnly,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: hostPath.name,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: hostPath.path,
+					Type: k8sutil.HostPath(corev1.HostPathDirectoryOrCreate),
+				},
+			},
+		})
+	}
+
+	var env []corev1.EnvVar
+	if secretName := l.cluster.Spec.LogShipping.CredentialSecretName; secretName != "" {
+		for _, key := range []struct{ env, key string }{{"LOG_SHIPPING_USER", "username"}, {"LOG_SHIPPING_PASSWORD", "password"}} {
+			env = append(env, corev1.EnvVar{
+				Name: key.env,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+						Key:                  key.key,
+					},
+				},
+			})
+		}
+	}
+	// restart rbd-log-shipper to reload the configuration once it changes.
+	hash := sha256.Sum256([]byte(l.config))
+
+	return buildDaemonSet(LogShipperName, l.component, l.cluster, l.labels,
+		withPodAnnotations(map[string]string{
+			"rainbond.io/config-checksum": hex.EncodeToString(hash[:]),
+		}),
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		// the metadata of the pods is read by the kubernetes filter.
+		withServiceAccount(rbdutil.ServiceAccountName()),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			// ship the logs of all the nodes.
+			spec.Tolerations = []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+			}
+		}),
+	)
+}
+
+// fluentBitOutput returns the output section of fluent-bit for the log storage.
+func fluentBitOutput(shipping *rainbondv1alpha1.LogShipping) (string, error) {
+	endpoint, err := url.Parse(shipping.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parse endpoint %s: %v", shipping.Endpoint, err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Hostname() == "" {
+		return "", fmt.Errorf("endpoint %s should be an http or https URL", shipping.Endpoint)
+	}
+	port := endpoint.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[endpoint.Scheme]
+	}
+	tls := "Off"
+	if endpoint.Scheme == "https" {
+		tls = "On"
+	}
+	basePath := strings.TrimSuffix(endpoint.Path, "/")
+
+	params := [][2]string{
+		{"Match", "kube.*"},
+		{"Host", endpoint.Hostname()},
+		{"Port", port},
+		{"tls", tls},
+	}
+	var userKey, passwordKey string
+	switch shipping.Output {
+	case rainbondv1alpha1.LogShippingOutputLoki:
+		userKey, passwordKey = "http_user", "http_passwd"
+		params = append([][2]string{{"Name", "loki"}}, params...)
+		params = append(params,
+			[2]string{"uri", basePath + "/loki/api/v1/push"},
+			[2]string{"labels", "job=rainbond"},
+			[2]string{"label_keys", "$kubernetes['namespace_name'],$kubernetes['pod_name'],$kubernetes['container_name']"},
+			[2]string{"line_format", "json"},
+		)
+	case rainbondv1alpha1.LogShippingOutputElasticsearch:
+		userKey, passwordKey = "HTTP_User", "HTTP_Passwd"
+		index := shipping.Index
+		if index == "" {
+			index = defLogShippingIndex
+		}
+		params = append([][2]string{{"Name", "es"}}, params...)
+		if basePath != "" {
+			params = append(params, [2]string{"Path", basePath})
+		}
+		params = append(params,
+			[2]string{"Logstash_Format", "On"},
+			[2]string{"Logstash_Prefix", index},
+			[2]string{"Replace_Dots", "On"},
+			[2]string{"Suppress_Type_Name", "On"},
+			[2]string{"Retry_Limit", "False"},
+		)
+	default:
+		return "", fmt.Errorf("unsupported output %q", shipping.Output)
+	}
+	if shipping.CredentialSecretName != "" {
+		params = append(params,
+			[2]string{userKey, "${LOG_SHIPPING_USER}"},
+			[2]string{passwordKey, "${LOG_SHIPPING_PASSWORD}"},
+		)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[OUTPUT]\n")
+	for _, param := range params {
+		fmt.Fprintf(&sb, "    %-18s %s\n", param[0], param[1])
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFluentBitOutput(t *testing.T) {
+	output, err := fluentBitOutput(&rainbondv1alpha1.LogShipping{
+		Output:   rainbondv1alpha1.LogShippingOutputLoki,
+		Endpoint: "http://loki.example.com:3100",
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, output, "Name               loki\n")
+	assert.Contains(t, output, "Host               loki.example.com\n")
+	assert.Contains(t, output, "Port               3100\n")
+	assert.Contains(t, output, "uri                /loki/api/v1/push\n")
+	assert.NotContains(t, output, "http_user")
+
+	output, err = fluentBitOutput(&rainbondv1alpha1.LogShipping{
+		Output:               rainbondv1alpha1.LogShippingOutputElasticsearch,
+		Endpoint:             "https://es.example.com/es/",
+		CredentialSecretName: "es-credential",
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, output, "Name               es\n")
+	assert.Contains(t, output, "Port               443\n")
+	assert.Contains(t, output, "tls                On\n")
+	assert.Contains(t, output, "Path               /es\n")
+	assert.Contains(t, output, "Logstash_Prefix    rainbond\n")
+	assert.Contains(t, output, "HTTP_Passwd        ${LOG_SHIPPING_PASSWORD}\n")
+
+	_, err = fluentBitOutput(&rainbondv1alpha1.LogShipping{
+		Output:   rainbondv1alpha1.LogShippingOutputLoki,
+		Endpoint: "loki.example.com:3100",
+	})
+	assert.NotNil(t, err)
+}
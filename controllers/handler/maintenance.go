@@ -0,0 +1,64 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maintenanceLabelKey is a node label that no node has, used to stop the pods of daemonsets.
+const maintenanceLabelKey = "rainbond.io/maintenance"
+
+// SetMaintenance stops the pods of the given object if they use the shared storage being migrated,
+// so that the data won't be changed while it is being copied. Other objects are left unchanged.
+func SetMaintenance(obj client.Object, cluster *rainbondv1alpha1.RainbondCluster) {
+	maintenance, claimName := cluster.InStorageMaintenance()
+	if !maintenance {
+		return
+	}
+	template := podTemplate(obj)
+	if template == nil || !usesClaim(&template.Spec, claimName) {
+		return
+	}
+	stopPods(obj)
+}
+
+// SetSuspension stops the pods of the given object if the rbdcomponent is stopped by the suspension
+// of the region, and suspends its cronjobs. The volumes are left untouched, so the data is kept.
+func SetSuspension(obj client.Object, name string, cluster *rainbondv1alpha1.RainbondCluster) {
+	if !cluster.ComponentSuspended(name) {
+		return
+	}
+	if cronJob, ok := obj.(*batchv1beta1.CronJob); ok {
+		cronJob.Spec.Suspend = commonutil.Bool(true)
+		return
+	}
+	stopPods(obj)
+}
+
+// stopPods scales the workload to zero, the pods of daemonsets are stopped by a node selector that no node matches.
+func stopPods(obj client.Object) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		o.Spec.Replicas = commonutil.Int32(0)
+	case *appsv1.StatefulSet:
+		o.Spec.Replicas = commonutil.Int32(0)
+	case *appsv1.DaemonSet:
+		if o.Spec.Template.Spec.NodeSelector == nil {
+			o.Spec.Template.Spec.NodeSelector = make(map[string]string)
+		}
+		o.Spec.Template.Spec.NodeSelector[maintenanceLabelKey] = "true"
+	}
+}
+
+func usesClaim(spec *corev1.PodSpec, claimName string) bool {
+	for _, volume := range spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+			return true
+		}
+	}
+	return false
+}
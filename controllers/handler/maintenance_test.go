@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetMaintenance(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Status: rainbondv1alpha1.RainbondClusterStatus{
+			SharedStorage: &rainbondv1alpha1.SharedStorageStatus{
+				Migration: &rainbondv1alpha1.StorageMigrationStatus{
+					Phase:           rainbondv1alpha1.StorageMigrationPhaseCopying,
+					SourceClaimName: "rbd-cpt-grdata",
+				},
+			},
+		},
+	}
+	podSpec := func(claimName string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{
+						Name: "grdata",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	deploy := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: podSpec("rbd-cpt-grdata")}}
+	SetMaintenance(deploy, cluster)
+	assert.Equal(t, int32(0), *deploy.Spec.Replicas)
+
+	ds := &appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: podSpec("rbd-cpt-grdata")}}
+	SetMaintenance(ds, cluster)
+	assert.Equal(t, "true", ds.Spec.Template.Spec.NodeSelector[maintenanceLabelKey])
+
+	// the pods without the claim being migrated keep running.
+	sts := &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: podSpec("data")}}
+	SetMaintenance(sts, cluster)
+	assert.Nil(t, sts.Spec.Replicas)
+
+	cluster.Status.SharedStorage.Migration.Phase = rainbondv1alpha1.StorageMigrationPhaseCompleted
+	deploy = &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: podSpec("rbd-cpt-grdata")}}
+	SetMaintenance(deploy, cluster)
+	assert.Nil(t, deploy.Spec.Replicas)
+}
+
+func TestSetSuspension(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Status: rainbondv1alpha1.RainbondClusterStatus{
+			Suspension: &rainbondv1alpha1.SuspensionStatus{
+				Phase:   rainbondv1alpha1.SuspensionPhaseSuspending,
+				Stopped: []string{GatewayName},
+			},
+		},
+	}
+
+	ds := &appsv1.DaemonSet{}
+	SetSuspension(ds, GatewayName, cluster)
+	assert.Equal(t, "true", ds.Spec.Template.Spec.NodeSelector[maintenanceLabelKey])
+
+	cronJob := &batchv1beta1.CronJob{}
+	SetSuspension(cronJob, GatewayName, cluster)
+	assert.True(t, *cronJob.Spec.Suspend)
+
+	// the components not stopped yet keep running.
+	deploy := &appsv1.Deployment{}
+	SetSuspension(deploy, APIName, cluster)
+	assert.Nil(t, deploy.Spec.Replicas)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SetAdditionalMetadata adds the additional labels and annotations of the cluster to the given object, as well as
+// the pods and the claim templates of the workloads. The labels and annotations set by the handlers are kept, so
+// that the selectors are not broken.
+func SetAdditionalMetadata(obj client.Object, cluster *rainbondv1alpha1.RainbondCluster) {
+	labels, annotations := cluster.Spec.AdditionalLabels, cluster.Spec.AdditionalAnnotations
+	if len(labels) == 0 && len(annotations) == 0 {
+		return
+	}
+	obj.SetLabels(mergeMetadata(labels, obj.GetLabels()))
+	obj.SetAnnotations(mergeMetadata(annotations, obj.GetAnnotations()))
+	if template := podTemplate(obj); template != nil {
+		template.Labels = mergeMetadata(labels, template.Labels)
+		template.Annotations = mergeMetadata(annotations, template.Annotations)
+	}
+	switch o := obj.(type) {
+	case *appsv1.StatefulSet:
+		// the claim templates only take effect on the claims to create, they are immutable once created.
+		for i := range o.Spec.VolumeClaimTemplates {
+			template := &o.Spec.VolumeClaimTemplates[i]
+			template.Labels = mergeMetadata(labels, template.Labels)
+			template.Annotations = mergeMetadata(annotations, template.Annotations)
+		}
+	case *batchv1beta1.CronJob:
+		template := &o.Spec.JobTemplate
+		template.Labels = mergeMetadata(labels, template.Labels)
+		template.Annotations = mergeMetadata(annotations, template.Annotations)
+	}
+}
+
+// mergeMetadata returns the additional labels or annotations merged with the existing ones, which take precedence.
+func mergeMetadata(additional, existing map[string]string) map[string]string {
+	if len(additional) == 0 {
+		return existing
+	}
+	merged := make(map[string]string, len(additional)+len(existing))
+	for k, v := range additional {
+		merged[k] = v
+	}
+	for k, v := range existing {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetAdditionalMetadata(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			AdditionalLabels:      map[string]string{"cost-center": "infra", "name": "overridden"},
+			AdditionalAnnotations: map[string]string{"prometheus.io/scrape": "true"},
+		},
+	}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "rbd-db", Labels: map[string]string{"name": "rbd-db"}},
+		Spec: appsv1.StatefulSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"name": "rbd-db"}},
+			},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "data"}},
+			},
+		},
+	}
+
+	SetAdditionalMetadata(sts, cluster)
+
+	// the labels of the selectors are kept.
+	want := map[string]string{"cost-center": "infra", "name": "rbd-db"}
+	assert.Equal(t, want, sts.Labels)
+	assert.Equal(t, want, sts.Spec.Template.Labels)
+	assert.Equal(t, map[string]string{"cost-center": "infra", "name": "overridden"}, sts.Spec.VolumeClaimTemplates[0].Labels)
+	for _, annotations := range []map[string]string{sts.Annotations, sts.Spec.Template.Annotations, sts.Spec.VolumeClaimTemplates[0].Annotations} {
+		assert.Equal(t, "true", annotations["prometheus.io/scrape"])
+	}
+	// the additional labels of the cluster are not changed.
+	assert.Equal(t, "overridden", cluster.Spec.AdditionalLabels["name"])
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "rbd-db"}}
+	SetAdditionalMetadata(svc, &rainbondv1alpha1.RainbondCluster{})
+	assert.Nil(t, svc.Labels)
+	assert.Nil(t, svc.Annotations)
+}
@@ -2,15 +2,22 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/docker/distribution/reference"
 	"k8s.io/client-go/kubernetes"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -19,6 +26,7 @@ import (
 	plabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/version"
 	kubeaggregatorv1beta1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -38,12 +46,47 @@ type metricsServer struct {
 	component  *rainbondv1alpha1.RbdComponent
 	cluster    *rainbondv1alpha1.RainbondCluster
 	apiservice *kubeaggregatorv1beta1.APIService
+	// condition is the availability of the metrics API served by the others, which is checked in Before.
+	condition *rainbondv1alpha1.RbdComponentCondition
+	// adopted is true if the metrics API served by the others is taken over in this reconciliation.
+	adopted bool
 
 	pods []corev1.Pod
 }
 
+// minMetricsServerVersion is the oldest metrics-server Rainbond works with.
+var minMetricsServerVersion = version.MustParseGeneric("v0.3.6")
+
+// metricsAPIProbe probes the metrics API, it is replaced in the tests.
+var metricsAPIProbe = probeMetricsAPI
+
+// metricsAPIProbeInterval is how long the result of the probe of the metrics API is reused, the probe takes up to
+// 10 seconds and the rbdcomponent is reconciled much more often.
+const metricsAPIProbeInterval = time.Minute
+
+// metricsAPIProbeResult is the latest result of the probe of the metrics API, which is shared by the reconciliations.
+var metricsAPIProbeResult struct {
+	sync.Mutex
+	probed time.Time
+	err    error
+}
+
+// probeMetricsAPICached probes the metrics API at most once in metricsAPIProbeInterval, the latest result is
+// returned otherwise.
+func probeMetricsAPICached(ctx context.Context) error {
+	metricsAPIProbeResult.Lock()
+	defer metricsAPIProbeResult.Unlock()
+	if !metricsAPIProbeResult.probed.IsZero() && time.Since(metricsAPIProbeResult.probed) < metricsAPIProbeInterval {
+		return metricsAPIProbeResult.err
+	}
+	metricsAPIProbeResult.err = metricsAPIProbe(ctx)
+	metricsAPIProbeResult.probed = time.Now()
+	return metricsAPIProbeResult.err
+}
+
 var _ ComponentHandler = &metricsServer{}
 var _ Replicaser = &metricsServer{}
+var _ ConditionReporter = &metricsServer{}
 
 // NewMetricsServer creates a new metrics-server handler
 func NewMetricsServer(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -65,15 +108,48 @@ func (m *metricsServer) Before() error {
 		return nil
 	}
 	m.apiservice = apiservice
+	if m.apiServiceCreatedByRainbond() {
+		return nil
+	}
+
+	// the metrics API served by the others is checked, in case it is broken or too old for Rainbond.
+	pods, err := m.externalPods()
+	if err != nil {
+		// the version is unknown, the probe tells if the metrics API is broken.
+		log.V(4).Info("list pods of the existing metrics-server", "error", err.Error())
+	}
+	m.condition = metricsAPICondition(probeMetricsAPICached(m.ctx), metricsServerVersion(pods))
+	if m.condition.Status == corev1.ConditionTrue {
+		return nil
+	}
+	if m.component.Annotations[constants.AdoptMetricsServerAnnotation] != "true" {
+		m.condition.Message += fmt.Sprintf(", annotate the rbdcomponent %s with %s=true to take it over", MetricsServerName, constants.AdoptMetricsServerAnnotation)
+		return nil
+	}
+	service := "local"
+	if svcRef := m.apiservice.Spec.Service; svcRef != nil {
+		service = fmt.Sprintf("%s/%s", svcRef.Namespace, svcRef.Name)
+	}
+	log.Info("take over the metrics API from the existing metrics-server", "reason", m.condition.Reason, "service", service)
+	m.adopted = true
+	m.condition = rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.MetricsAPIAvailable, corev1.ConditionFalse,
+		rainbondv1alpha1.ReasonMetricsServerAdopted, "taken over from the existing metrics-server: "+m.condition.Message)
 	return nil
 }
 
+// apiServiceCreatedByRainbond checks if the apiservice, which is unique in the cluster, is served by the metrics-server
+// of the rainbond in the namespace. The metrics-server is only run by the first rainbond in the cluster. The local
+// apiservice without a service is served by the apiserver itself.
 func (m *metricsServer) apiServiceCreatedByRainbond() bool {
 	apiservice := m.apiservice
-	if apiservice == nil {
+	if apiservice == nil || m.adopted {
 		return true
 	}
-	return apiservice.Spec.Service.Namespace == m.component.Namespace && apiservice.Spec.Service.Name == MetricsServerName
+	svcRef := apiservice.Spec.Service
+	if svcRef == nil {
+		return false
+	}
+	return svcRef.Namespace == m.component.Namespace && svcRef.Name == MetricsServerName
 }
 
 func (m *metricsServer) Resources() []client.Object {
@@ -127,110 +203,169 @@ func apiServiceNeedUpgrade(old, new *kubeaggregatorv1beta1.APIService) bool {
 }
 
 func (m *metricsServer) ListPods() ([]corev1.Pod, error) {
-
-	labels := m.labels
 	if !m.apiServiceCreatedByRainbond() {
-		restConfig := k8sutil.MustNewKubeConfig("")
-		clientset := kubernetes.NewForConfigOrDie(restConfig)
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-		defer cancel()
-		svcRef := m.apiservice.Spec.Service
-		svc, err := clientset.CoreV1().Services(svcRef.Namespace).Get(ctx, svcRef.Name, metav1.GetOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("get svc(%s/%s) based on apiservice %s: %v", svcRef.Namespace, svcRef.Name, m.apiservice.Name, err)
-		}
-
-		labels = svc.Spec.Selector
-		selector := plabels.SelectorFromSet(labels)
-		opts := metav1.ListOptions{
-			LabelSelector: selector.String(),
-		}
-		podList, err := clientset.CoreV1().Pods(svcRef.Namespace).List(ctx, opts)
+		pods, err := m.externalPods()
 		if err != nil {
 			return nil, err
 		}
-		m.pods = podList.Items
-		return podList.Items, nil
+		m.pods = pods
+		return pods, nil
 	}
-	pods, err := listPods(m.ctx, m.client, m.component.Namespace, labels)
+	pods, err := listPods(m.ctx, m.client, m.component.Namespace, m.labels)
 	m.pods = pods
 	return pods, err
 }
 
+// externalPods returns the pods of the metrics-server serving the apiservice, which is not created by rainbond.
+func (m *metricsServer) externalPods() ([]corev1.Pod, error) {
+	svcRef := m.apiservice.Spec.Service
+	if svcRef == nil {
+		// the local apiservice is served by the apiserver, there are no pods.
+		return nil, nil
+	}
+	restConfig := k8sutil.MustNewKubeConfig("")
+	clientset := kubernetes.NewForConfigOrDie(restConfig)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	svc, err := clientset.CoreV1().Services(svcRef.Namespace).Get(ctx, svcRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get svc(%s/%s) based on apiservice %s: %v", svcRef.Namespace, svcRef.Name, m.apiservice.Name, err)
+	}
+
+	selector := plabels.SelectorFromSet(svc.Spec.Selector)
+	opts := metav1.ListOptions{
+		LabelSelector: selector.String(),
+	}
+	podList, err := clientset.CoreV1().Pods(svcRef.Namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+// Conditions returns whether the metrics API is available. The metrics API served by the others is checked in
+// Before, against the version of metrics-server as well.
+func (m *metricsServer) Conditions() []*rainbondv1alpha1.RbdComponentCondition {
+	if m.condition != nil {
+		return []*rainbondv1alpha1.RbdComponentCondition{m.condition}
+	}
+	return []*rainbondv1alpha1.RbdComponentCondition{metricsAPICondition(probeMetricsAPICached(m.ctx), nil)}
+}
+
+// metricsAPICondition returns the condition of the metrics API from the result of the probe, and the version of the
+// metrics-server serving it, which is nil if unknown.
+func metricsAPICondition(probeErr error, v *version.Version) *rainbondv1alpha1.RbdComponentCondition {
+	if probeErr != nil {
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.MetricsAPIAvailable, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonMetricsAPIUnavailable, probeErr.Error())
+	}
+	if v != nil && v.LessThan(minMetricsServerVersion) {
+		return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.MetricsAPIAvailable, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonMetricsServerTooOld, fmt.Sprintf("metrics-server v%s is older than v%s", v, minMetricsServerVersion))
+	}
+	return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.MetricsAPIAvailable, corev1.ConditionTrue, "", "")
+}
+
+// metricsServerVersion returns the lowest version of metrics-server run by the pods, which is read from the tags of
+// the images, or nil if unknown, such as the images tagged latest.
+func metricsServerVersion(pods []corev1.Pod) *version.Version {
+	var lowest *version.Version
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if !strings.Contains(container.Image, MetricsServerName) {
+				continue
+			}
+			ref, err := reference.ParseNormalizedNamed(container.Image)
+			if err != nil {
+				continue
+			}
+			tagged, ok := ref.(reference.Tagged)
+			if !ok {
+				continue
+			}
+			v, err := version.ParseGeneric(tagged.Tag())
+			if err != nil {
+				continue
+			}
+			if lowest == nil || v.LessThan(lowest) {
+				lowest = v
+			}
+		}
+	}
+	return lowest
+}
+
+// probeMetricsAPI checks the metrics API serves the metrics of the nodes, which are read by the monitor and the
+// autoscalers of Rainbond.
+func probeMetricsAPI(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	data, err := k8sutil.GetClientSet().Discovery().RESTClient().Get().AbsPath("/apis/metrics.k8s.io/v1beta1/nodes").Do(ctx).Raw()
+	if err != nil {
+		return fmt.Errorf("probe /apis/metrics.k8s.io/v1beta1/nodes: %v", err)
+	}
+	var metrics struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return fmt.Errorf("decode the metrics of the nodes: %v", err)
+	}
+	if len(metrics.Items) == 0 {
+		return errors.New("no metrics of the nodes are served by the metrics API")
+	}
+	return nil
+}
+
 func (m *metricsServer) Replicas() *int32 {
 	return commonutil.Int32(int32(len(m.pods)))
 }
 
 func (m *metricsServer) deployment() client.Object {
-	ds := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      MetricsServerName,
-			Namespace: m.component.Namespace,
-			Labels:    m.labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: m.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: m.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   MetricsServerName,
-					Labels: m.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(m.component, m.cluster),
-					ServiceAccountName:            "rainbond-operator",
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					NodeSelector: map[string]string{
-						"beta.kubernetes.io/os": "linux",
-						"kubernetes.io/arch":    "amd64",
-					},
-					Containers: []corev1.Container{
-						{
-							Name:            MetricsServerName,
-							Image:           m.component.Spec.Image,
-							ImagePullPolicy: m.component.ImagePullPolicy(),
-							Args: []string{
-								"--cert-dir=/tmp",
-								"--secure-port=4443",
-								"--kubelet-insecure-tls",
-								"--kubelet-preferred-address-types=InternalIP",
-							},
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "main-port",
-									ContainerPort: 4443,
-								},
-							},
-							SecurityContext: &corev1.SecurityContext{
-								ReadOnlyRootFilesystem: commonutil.Bool(true),
-								RunAsNonRoot:           commonutil.Bool(true),
-								RunAsUser:              commonutil.Int64(1000),
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "tmp-dir",
-									MountPath: "/tmp",
-								},
-							},
-							Resources: m.component.Spec.Resources,
-						},
-					},
-					Volumes: []corev1.Volume{
-						{
-							Name: "tmp-dir",
-							VolumeSource: corev1.VolumeSource{
-								EmptyDir: &corev1.EmptyDirVolumeSource{},
-							},
-						},
+	livenessProbe := probeutil.MakeProbe(probeutil.ProbeKindHTTP, "", "/healthz", 4443, corev1.URISchemeHTTPS, nil)
+	probeutil.SetProbeArgs(livenessProbe, 10, 5, 10, 0, 0)
+	readinessProbe := probeutil.MakeProbe(probeutil.ProbeKindHTTP, "", "/healthz", 4443, corev1.URISchemeHTTPS, nil)
+	probeutil.SetProbeArgs(readinessProbe, 5, 5, 5, 0, 0)
+	return buildDeployment(MetricsServerName, m.component, m.cluster, m.labels, appsv1.RollingUpdateDeploymentStrategyType,
+		withArgs(
+			"--cert-dir=/tmp",
+			"--secure-port=4443",
+			"--kubelet-insecure-tls",
+			"--kubelet-preferred-address-types=InternalIP",
+		),
+		withPorts(corev1.ContainerPort{
+			Name:          "main-port",
+			ContainerPort: 4443,
+		}),
+		withSecurityContext(&corev1.SecurityContext{
+			ReadOnlyRootFilesystem: commonutil.Bool(true),
+			RunAsNonRoot:           commonutil.Bool(true),
+			RunAsUser:              commonutil.Int64(1000),
+		}),
+		withVolumes(
+			[]corev1.Volume{
+				{
+					Name: "tmp-dir",
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{},
 					},
 				},
 			},
-		},
-	}
-
-	return ds
+			[]corev1.VolumeMount{
+				{
+					Name:      "tmp-dir",
+					MountPath: "/tmp",
+				},
+			},
+		),
+		withProbes(livenessProbe, readinessProbe),
+		withServiceAccount(rbdutil.ServiceAccountName()),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.NodeSelector = map[string]string{
+				"beta.kubernetes.io/os": "linux",
+				"kubernetes.io/arch":    "amd64",
+			}
+		}),
+	)
 }
 
 func (m *metricsServer) serviceForMetricsServer() client.Object {
@@ -262,7 +397,8 @@ func (m *metricsServer) serviceForMetricsServer() client.Object {
 func (m *metricsServer) apiserviceForMetricsServer() *kubeaggregatorv1beta1.APIService {
 	return &kubeaggregatorv1beta1.APIService{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: metricsGroupAPI,
+			Name:   metricsGroupAPI,
+			Labels: rbdutil.LabelsForInstance(nil, m.cluster.Namespace),
 		},
 		Spec: kubeaggregatorv1beta1.APIServiceSpec{
 			Service: &kubeaggregatorv1beta1.ServiceReference{
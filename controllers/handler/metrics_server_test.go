@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	kubeaggregatorv1beta1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1beta1"
+)
+
+func TestMetricsAPICondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		probeErr   error
+		version    string
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "available",
+			version:    "v0.4.1",
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:       "unknown version",
+			wantStatus: corev1.ConditionTrue,
+		},
+		{
+			name:       "unavailable",
+			probeErr:   errors.New("the server is currently unable to handle the request"),
+			version:    "v0.4.1",
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonMetricsAPIUnavailable,
+		},
+		{
+			name:       "too old",
+			version:    "v0.2.1",
+			wantStatus: corev1.ConditionFalse,
+			wantReason: rainbondv1alpha1.ReasonMetricsServerTooOld,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var v *version.Version
+			if tc.version != "" {
+				v = version.MustParseGeneric(tc.version)
+			}
+			condition := metricsAPICondition(tc.probeErr, v)
+			assert.Equal(t, rainbondv1alpha1.MetricsAPIAvailable, condition.Type)
+			assert.Equal(t, tc.wantStatus, condition.Status)
+			assert.Equal(t, tc.wantReason, condition.Reason)
+		})
+	}
+}
+
+func TestMetricsServerVersion(t *testing.T) {
+	pod := func(images ...string) corev1.Pod {
+		var pod corev1.Pod
+		for _, image := range images {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Image: image})
+		}
+		return pod
+	}
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{
+			name: "no pods",
+		},
+		{
+			name: "tagged",
+			pods: []corev1.Pod{pod("k8s.gcr.io/metrics-server/metrics-server:v0.5.0", "k8s.gcr.io/addon-resizer:1.8.11")},
+			want: "0.5.0",
+		},
+		{
+			name: "the lowest of the pods",
+			pods: []corev1.Pod{
+				pod("k8s.gcr.io/metrics-server/metrics-server:v0.5.0"),
+				pod("k8s.gcr.io/metrics-server-amd64:v0.3.1"),
+			},
+			want: "0.3.1",
+		},
+		{
+			name: "latest",
+			pods: []corev1.Pod{pod("rancher/metrics-server:latest"), pod("rancher/metrics-server")},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			v := metricsServerVersion(tc.pods)
+			if tc.want == "" {
+				assert.Nil(t, v)
+				return
+			}
+			if assert.NotNil(t, v) {
+				assert.Equal(t, tc.want, v.String())
+			}
+		})
+	}
+}
+
+func TestAPIServiceCreatedByRainbond(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *kubeaggregatorv1beta1.ServiceReference
+		want    bool
+	}{
+		{
+			name:    "rainbond",
+			service: &kubeaggregatorv1beta1.ServiceReference{Namespace: "rbd-system", Name: MetricsServerName},
+			want:    true,
+		},
+		{
+			name:    "others",
+			service: &kubeaggregatorv1beta1.ServiceReference{Namespace: "kube-system", Name: MetricsServerName},
+		},
+		{
+			name: "local",
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			m := &metricsServer{
+				component: &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system"}},
+				apiservice: &kubeaggregatorv1beta1.APIService{
+					Spec: kubeaggregatorv1beta1.APIServiceSpec{Service: tc.service},
+				},
+			}
+			assert.Equal(t, tc.want, m.apiServiceCreatedByRainbond())
+		})
+	}
+}
+
+func TestProbeMetricsAPICached(t *testing.T) {
+	defer func(probe func(context.Context) error) { metricsAPIProbe = probe }(metricsAPIProbe)
+	probes := 0
+	metricsAPIProbe = func(context.Context) error {
+		probes++
+		return errors.New("unavailable")
+	}
+	metricsAPIProbeResult.probed = time.Time{}
+
+	for i := 0; i < 3; i++ {
+		assert.EqualError(t, probeMetricsAPICached(context.Background()), "unavailable")
+	}
+	assert.Equal(t, 1, probes)
+
+	// probed again once the result expires.
+	metricsAPIProbeResult.probed = time.Now().Add(-metricsAPIProbeInterval)
+	probeMetricsAPICached(context.Background())
+	assert.Equal(t, 2, probes)
+}
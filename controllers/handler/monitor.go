@@ -2,16 +2,17 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"path"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
-	"github.com/goodrain/rainbond-operator/util/commonutil"
 	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -29,10 +30,13 @@ type monitor struct {
 	labels           map[string]string
 	pvcParametersRWO *pvcParameters
 	storageRequest   int64
+	// remoteWrite is the remote_write configuration of Prometheus, empty if there is no remote write.
+	remoteWrite string
 }
 
 var _ ComponentHandler = &monitor{}
 var _ StorageClassRWOer = &monitor{}
+var _ ResourcesDeleter = &monitor{}
 
 // NewMonitor returns a new rbd-monitor handler.
 func NewMonitor(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -51,12 +55,32 @@ func (m *monitor) Before() error {
 	if err := setStorageCassName(m.ctx, m.client, m.component.Namespace, m); err != nil {
 		return err
 	}
+	if existingClaimName(m.component) != "" && m.component.Spec.Replicas != nil && *m.component.Spec.Replicas > 1 {
+		// the replicas can't share the data of prometheus.
+		return NewPermanentError("the existing claim can't be used by more than one replica of rbd-monitor")
+	}
+	if err := checkExistingClaim(m.ctx, m.client, m.component, corev1.ReadWriteOnce, corev1.ReadWriteMany); err != nil {
+		return err
+	}
+
+	if len(m.component.Spec.RemoteWrite) > 0 {
+		secrets, err := m.remoteWriteSecrets()
+		if err != nil {
+			return err
+		}
+		remoteWrite, err := remoteWriteConfig(m.component.Spec.RemoteWrite, secrets)
+		if err != nil {
+			return NewPermanentError(err.Error())
+		}
+		m.remoteWrite = remoteWrite
+	}
 
 	return nil
 }
 
 func (m *monitor) Resources() []client.Object {
 	return []client.Object{
+		m.remoteWriteSecret(),
 		m.statefulset(),
 		m.serviceForMonitor(),
 		m.serviceMonitorForMonitor(),
@@ -71,19 +95,37 @@ func (m *monitor) ListPods() ([]corev1.Pod, error) {
 	return listPods(m.ctx, m.client, m.component.Namespace, m.labels)
 }
 
+// ResourcesNeedDelete returns the secret of the remote write configuration if there is no remote write.
+func (m *monitor) ResourcesNeedDelete() []client.Object {
+	if m.remoteWrite != "" {
+		return nil
+	}
+	return []client.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      remoteWriteName,
+				Namespace: m.component.Namespace,
+			},
+		},
+	}
+}
+
 func (m *monitor) SetStorageClassNameRWO(pvcParameters *pvcParameters) {
 	m.pvcParametersRWO = pvcParameters
 }
 
 func (m *monitor) statefulset() client.Object {
 	claimName := "data" // unnecessary
-	promDataPVC := createPersistentVolumeClaimRWO(m.component.Namespace, claimName, m.pvcParametersRWO, m.labels, m.storageRequest)
+	promDataPVC := dataPVC(m.component, claimName, m.pvcParametersRWO, m.labels, m.storageRequest)
 
 	args := []string{
 		"--alertmanager-address=$(POD_IP):9093",
 		"--storage.tsdb.path=/prometheusdata",
 		"--storage.tsdb.no-lockfile",
-		"--storage.tsdb.retention=7d",
+		fmt.Sprintf("--storage.tsdb.retention=%dd", retentionDays(m.component)),
+	}
+	if size := prometheusRetentionSize(m.component); size != "" {
+		args = append(args, "--storage.tsdb.retention.size="+size)
 	}
 	volumeMounts := []corev1.VolumeMount{
 		{
@@ -92,6 +134,11 @@ func (m *monitor) statefulset() client.Object {
 		},
 	}
 	var volumes []corev1.Volume
+	claimTemplates := []corev1.PersistentVolumeClaim{*promDataPVC}
+	if existingClaim := existingClaimName(m.component); existingClaim != "" {
+		claimTemplates = nil
+		volumes = append(volumes, claimVolume(claimName, existingClaim))
+	}
 	env := []corev1.EnvVar{
 		{
 			Name: "POD_IP",
@@ -114,78 +161,42 @@ func (m *monitor) statefulset() client.Object {
 		},
 	}
 
-	env = mergeEnvs(env, m.component.Spec.Env)
-	resources = mergeResources(resources, m.component.Spec.Resources)
-	args = mergeArgs(args, m.component.Spec.Args)
-	volumeMounts = mergeVolumeMounts(volumeMounts, m.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, m.component.Spec.Volumes)
-
-	// prepare probe
-	readinessProbe := probeutil.MakeReadinessProbeHTTP("", "/monitor/health", 3329)
-	ds := &appsv1.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      MonitorName,
-			Namespace: m.component.Namespace,
-			Labels:    m.labels,
-		},
-		Spec: appsv1.StatefulSetSpec{
-			Replicas: m.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: m.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   MonitorName,
-					Labels: m.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(m.component, m.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(30),
-					ServiceAccountName:            "rainbond-operator",
-					Containers: []corev1.Container{
-						{
-							Name:            MonitorName,
-							Image:           m.component.Spec.Image,
-							ImagePullPolicy: m.component.ImagePullPolicy(),
-							Env:             env,
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							ReadinessProbe:  readinessProbe,
-							Resources:       resources,
-						},
-					},
-					Volumes: volumes,
-				},
-			},
-			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{*promDataPVC},
-		},
+	var annotations map[string]string
+	var initContainers []corev1.Container
+	if rwVolumes, mount, container, checksum := m.remoteWritePod(); container != nil {
+		volumes = append(volumes, rwVolumes...)
+		volumeMounts = append(volumeMounts, *mount)
+		initContainers = append(initContainers, *container)
+		args = append(args, "--config.file="+path.Join(prometheusConfigPath, prometheusConfigFile))
+		annotations = map[string]string{"rainbond.io/remote-write-checksum": checksum}
 	}
 
-	return ds
+	liveness := probeutil.MakeLivenessProbeHTTP("", "/monitor/health", 3329)
+	return buildStatefulSet(MonitorName, m.component, m.cluster, m.labels, "", claimTemplates,
+		withArgs(args...),
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		withResources(resources),
+		withProbes(liveness, probeutil.MakeReadinessProbeHTTP("", "/monitor/health", 3329)),
+		// replaying the write-ahead log of a large tsdb takes up to several minutes.
+		withStartupProbe(probeutil.MakeStartupProbe(liveness, 10, 60)),
+		withTerminationGracePeriod(30),
+		withPodAnnotations(annotations),
+		withServiceAccount(rbdutil.ServiceAccountName()),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.InitContainers = initContainers
+		}),
+	)
 }
 
 func (m *monitor) serviceForMonitor() client.Object {
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      MonitorName,
-			Namespace: m.component.Namespace,
-			Labels:    m.labels,
+	return buildService(MonitorName, m.component.Namespace, m.labels, corev1.ServicePort{
+		Name: "http",
+		Port: 9999,
+		TargetPort: intstr.IntOrString{
+			IntVal: 9999,
 		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name: "http",
-					Port: 9999,
-					TargetPort: intstr.IntOrString{
-						IntVal: 9999,
-					},
-				},
-			},
-			Selector: m.labels,
-		},
-	}
-
-	return svc
+	})
 }
 
 func (m *monitor) serviceMonitorForMonitor() client.Object {
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// remoteWriteName is the name of the secret of the remote write configuration of rbd-monitor.
+	// It is a secret rather than a configmap for it contains the credentials of the remote storages.
+	remoteWriteName = "rbd-monitor-remote-write"
+	// remoteWriteKey is the key of the remote write configuration in the secret.
+	remoteWriteKey = "remote-write.yml"
+	// remoteWriteMountPath is where the remote write configuration is mounted in the init container.
+	remoteWriteMountPath = "/etc/rbd-monitor/remote-write"
+	// prometheusConfigName is the name of the volume of the configuration of Prometheus loaded by rbd-monitor.
+	prometheusConfigName = "prometheus-config"
+	prometheusConfigPath = "/etc/prometheus"
+	prometheusConfigFile = "prometheus.yml"
+)
+
+// promRemoteWrite is the remote_write section of the configuration of Prometheus.
+type promRemoteWrite struct {
+	URL         string         `json:"url"`
+	Name        string         `json:"name,omitempty"`
+	BasicAuth   *promBasicAuth `json:"basic_auth,omitempty"`
+	BearerToken string         `json:"bearer_token,omitempty"`
+}
+
+type promBasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// remoteWriteSecrets gets the secrets referenced by the remote writes.
+func (m *monitor) remoteWriteSecrets() (map[string]*corev1.Secret, error) {
+	secrets := make(map[string]*corev1.Secret)
+	for _, rw := range m.component.Spec.RemoteWrite {
+		if rw.AuthSecretName == "" || secrets[rw.AuthSecretName] != nil {
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := m.client.Get(m.ctx, types.NamespacedName{Namespace: m.component.Namespace, Name: rw.AuthSecretName}, secret); err != nil {
+			return nil, fmt.Errorf("get auth secret %s of remote write: %v", rw.AuthSecretName, err)
+		}
+		secrets[rw.AuthSecretName] = secret
+	}
+	return secrets, nil
+}
+
+// remoteWriteConfig renders the remote_write section of Prometheus for the remote writes.
+func remoteWriteConfig(remoteWrites []rainbondv1alpha1.RemoteWrite, secrets map[string]*corev1.Secret) (string, error) {
+	var configs []promRemoteWrite
+	for idx, rw := range remoteWrites {
+		u, err := url.Parse(rw.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("invalid url %q of remote write %d: should be an http or https URL", rw.URL, idx)
+		}
+		config := promRemoteWrite{
+			URL:  rw.URL,
+			Name: rw.Name,
+		}
+		if config.Name == "" {
+			config.Name = strconv.Itoa(idx)
+		}
+		if rw.AuthSecretName != "" {
+			secret := secrets[rw.AuthSecretName]
+			if secret == nil {
+				return "", fmt.Errorf("auth secret %s of remote write %s not found", rw.AuthSecretName, config.Name)
+			}
+			if token := string(secret.Data["token"]); token != "" {
+				config.BearerToken = token
+			} else if username := string(secret.Data["username"]); username != "" {
+				config.BasicAuth = &promBasicAuth{
+					Username: username,
+					Password: string(secret.Data["password"]),
+				}
+			} else {
+				return "", fmt.Errorf("auth secret %s of remote write %s should contain either username and password, or token", rw.AuthSecretName, config.Name)
+			}
+		}
+		configs = append(configs, config)
+	}
+
+	body, err := yaml.Marshal(map[string][]promRemoteWrite{"remote_write": configs})
+	if err != nil {
+		return "", fmt.Errorf("marshal remote write: %v", err)
+	}
+	return string(body), nil
+}
+
+// remoteWriteSecret returns the secret of the remote write configuration, or nil if there is no remote write.
+func (m *monitor) remoteWriteSecret() client.Object {
+	if m.remoteWrite == "" {
+		return nil
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remoteWriteName,
+			Namespace: m.component.Namespace,
+			Labels:    m.labels,
+		},
+		Data: map[string][]byte{
+			remoteWriteKey: []byte(m.remoteWrite),
+		},
+	}
+}
+
+// remoteWritePod returns the volumes, the mount of rbd-monitor and the init container of the remote write
+// configuration, and the checksum to restart rbd-monitor once the configuration changes.
+// rbd-monitor loads the configuration of Prometheus from the file of --config.file at start, and keeps its
+// remote_write section when it writes the scrape configs back, so the init container seeds the file with the
+// remote write configuration in a volume writable by rbd-monitor.
+func (m *monitor) remoteWritePod() ([]corev1.Volume, *corev1.VolumeMount, *corev1.Container, string) {
+	if m.remoteWrite == "" {
+		return nil, nil, nil, ""
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: remoteWriteName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: remoteWriteName,
+				},
+			},
+		},
+		{
+			Name: prometheusConfigName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+	}
+	mount := &corev1.VolumeMount{
+		Name:      prometheusConfigName,
+		MountPath: prometheusConfigPath,
+	}
+	container := &corev1.Container{
+		Name:            "remote-write-config",
+		Image:           initContainerImage(m.cluster),
+		ImagePullPolicy: m.component.ImagePullPolicy(),
+		Command: []string{"cp",
+			path.Join(remoteWriteMountPath, remoteWriteKey),
+			path.Join(prometheusConfigPath, prometheusConfigFile),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      remoteWriteName,
+				MountPath: remoteWriteMountPath,
+				ReadOnly:  true,
+			},
+			*mount,
+		},
+	}
+	hash := sha256.Sum256([]byte(m.remoteWrite))
+	return volumes, mount, container, hex.EncodeToString(hash[:])
+}
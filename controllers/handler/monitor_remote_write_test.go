@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRemoteWriteConfig(t *testing.T) {
+	secrets := map[string]*corev1.Secret{
+		"thanos": {Data: map[string][]byte{"username": []byte("admin"), "password": []byte("secret")}},
+		"vm":     {Data: map[string][]byte{"token": []byte("abc")}},
+		"empty":  {},
+	}
+
+	config, err := remoteWriteConfig([]rainbondv1alpha1.RemoteWrite{
+		{URL: "http://thanos-receive:19291/api/v1/receive", AuthSecretName: "thanos"},
+		{URL: "https://vm.example.com/api/v1/write", Name: "vm", AuthSecretName: "vm"},
+	}, secrets)
+	assert.Nil(t, err)
+	assert.Equal(t, `remote_write:
+- basic_auth:
+    password: secret
+    username: admin
+  name: "0"
+  url: http://thanos-receive:19291/api/v1/receive
+- bearer_token: abc
+  name: vm
+  url: https://vm.example.com/api/v1/write
+`, config)
+
+	_, err = remoteWriteConfig([]rainbondv1alpha1.RemoteWrite{{URL: "thanos-receive:19291"}}, secrets)
+	assert.NotNil(t, err)
+
+	_, err = remoteWriteConfig([]rainbondv1alpha1.RemoteWrite{{URL: "http://thanos-receive:19291", AuthSecretName: "empty"}}, secrets)
+	assert.NotNil(t, err)
+}
+
+func TestMonitorRemoteWritePod(t *testing.T) {
+	m := &monitor{
+		component: &rainbondv1alpha1.RbdComponent{},
+		cluster:   &rainbondv1alpha1.RainbondCluster{},
+	}
+	volumes, mount, container, _ := m.remoteWritePod()
+	assert.Nil(t, volumes)
+	assert.Nil(t, mount)
+	assert.Nil(t, container)
+
+	m.remoteWrite = "remote_write: []\n"
+	volumes, mount, container, checksum := m.remoteWritePod()
+	assert.Len(t, volumes, 2)
+	assert.Equal(t, "/etc/prometheus", mount.MountPath)
+	assert.Equal(t, []string{"cp", "/etc/rbd-monitor/remote-write/remote-write.yml", "/etc/prometheus/prometheus.yml"}, container.Command)
+	assert.NotEmpty(t, checksum)
+}
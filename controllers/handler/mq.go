@@ -6,10 +6,9 @@ import (
 	"strings"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
-	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -27,6 +26,7 @@ type mq struct {
 }
 
 var _ ComponentHandler = &mq{}
+var _ HealthChecker = &mq{}
 
 // NewMQ creates a new rbd-mq handler.
 func NewMQ(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -63,6 +63,16 @@ func (m *mq) ListPods() ([]corev1.Pod, error) {
 	return listPods(m.ctx, m.client, m.component.Namespace, m.labels)
 }
 
+func (m *mq) Dependencies() []Dependency {
+	return []Dependency{etcdDependency(m.cluster)}
+}
+
+// HealthEndpoint returns the health endpoint of rbd-mq served with the metrics, the readiness probe only checks
+// the port of the api.
+func (m *mq) HealthEndpoint() HealthEndpoint {
+	return HealthEndpoint{Port: 6301, Path: "/health"}
+}
+
 func (m *mq) deployment() client.Object {
 	args := []string{
 		"--etcd-endpoints=" + strings.Join(etcdEndpoints(m.cluster), ","),
@@ -87,69 +97,21 @@ func (m *mq) deployment() client.Object {
 		},
 	}
 
-	env = mergeEnvs(env, m.component.Spec.Env)
-	args = mergeArgs(args, m.component.Spec.Args)
-	volumeMounts = mergeVolumeMounts(volumeMounts, m.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, m.component.Spec.Volumes)
-
-	ds := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      MQName,
-			Namespace: m.component.Namespace,
-			Labels:    m.labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: m.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: m.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   MQName,
-					Labels: m.labels,
-				},
-				Spec: corev1.PodSpec{
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					ImagePullSecrets:              imagePullSecrets(m.component, m.cluster),
-					Containers: []corev1.Container{
-						{
-							Name:            MQName,
-							Image:           m.component.Spec.Image,
-							ImagePullPolicy: m.component.ImagePullPolicy(),
-							Env:             env,
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							Resources:       m.component.Spec.Resources,
-						},
-					},
-					Volumes: volumes,
-				},
-			},
-		},
-	}
-
-	return ds
+	// the queues are stored in etcd, so all the replicas serve them and any of them can fail over for the others.
+	return buildDeployment(MQName, m.component, m.cluster, m.labels, appsv1.RollingUpdateDeploymentStrategyType,
+		withArgs(args...),
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(probeutil.MakeLivenessProbeTCP("", 6300), probeutil.MakeReadinessProbeTCP("", 6300)),
+	)
 }
 
 func (m *mq) service() client.Object {
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      MQName,
-			Namespace: m.component.Namespace,
-			Labels:    m.labels,
+	return buildService(MQName, m.component.Namespace, m.labels, corev1.ServicePort{
+		Name: "api",
+		Port: 6300,
+		TargetPort: intstr.IntOrString{
+			IntVal: 6300,
 		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name: "api",
-					Port: 6300,
-					TargetPort: intstr.IntOrString{
-						IntVal: 6300,
-					},
-				},
-			},
-			Selector: m.labels,
-		},
-	}
-	return svc
+	})
 }
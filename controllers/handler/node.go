@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/go-logr/logr"
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
@@ -12,9 +13,9 @@ import (
 	"github.com/goodrain/rainbond-operator/util/probeutil"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -34,6 +35,7 @@ type node struct {
 var _ ComponentHandler = &node{}
 var _ ResourcesCreator = &node{}
 var _ Replicaser = &node{}
+var _ ClusterScopedResourcesCreator = &node{}
 
 // NewNode creates a new rbd-node handler.
 func NewNode(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -48,16 +50,29 @@ func NewNode(ctx context.Context, client client.Client, component *rainbondv1alp
 }
 
 func (n *node) Before() error {
-	return nil
+	if err := hostPortsConflict(n.cluster); err != nil {
+		return err
+	}
+	if n.cluster.Spec.NodeSelectorForNode != nil {
+		if _, err := metav1.LabelSelectorAsSelector(n.cluster.Spec.NodeSelectorForNode); err != nil {
+			return NewPermanentError(fmt.Sprintf("invalid nodeSelectorForNode: %v", err))
+		}
+	}
+	return validateHostFiles(n.component.Spec.HostFiles)
 }
 
 func (n *node) Resources() []client.Object {
-	return []client.Object{
+	return append(permissionResources(n.component, n.labels),
 		n.daemonSetForRainbondNode(),
 		n.serviceForNode(),
 		n.serviceMonitorForNode(),
 		n.prometheusRuleForNode(),
-	}
+	)
+}
+
+// CreateClusterScoped returns the cluster role of rbd-node-proxy, which reports the status of the nodes.
+func (n *node) CreateClusterScoped() []client.Object {
+	return clusterPermissionResources(n.component, n.labels)
 }
 
 func (n *node) After() error {
@@ -77,7 +92,76 @@ func (n *node) Replicas() *int32 {
 		n.log.V(6).Info(fmt.Sprintf("list nodes: %v", err))
 		return nil
 	}
-	return commonutil.Int32(int32(len(nodeList.Items)))
+	selector := labels.Everything()
+	if n.cluster.Spec.NodeSelectorForNode != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(n.cluster.Spec.NodeSelectorForNode)
+		if err != nil {
+			n.log.V(6).Info(fmt.Sprintf("invalid nodeSelectorForNode: %v", err))
+			return nil
+		}
+	}
+	var replicas int32
+	for _, node := range nodeList.Items {
+		if node.Labels[constants.ExcludeNodeLabelKey] == "true" || !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
+		replicas++
+	}
+	return commonutil.Int32(replicas)
+}
+
+// affinityForNode returns the node affinity that excludes the nodes labeled with rainbond.io/exclude-rbd-node=true,
+// and the nodes not selected by nodeSelectorForNode.
+func (n *node) affinityForNode() *corev1.Affinity {
+	requirements := []corev1.NodeSelectorRequirement{
+		{
+			Key:      constants.ExcludeNodeLabelKey,
+			Operator: corev1.NodeSelectorOpNotIn,
+			Values:   []string{"true"},
+		},
+	}
+	requirements = append(requirements, nodeSelectorRequirements(n.cluster.Spec.NodeSelectorForNode)...)
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: requirements,
+					},
+				},
+			},
+		},
+	}
+}
+
+// nodeSelectorRequirements converts the label selector to the node selector requirements, which are ANDed as well.
+func nodeSelectorRequirements(selector *metav1.LabelSelector) []corev1.NodeSelectorRequirement {
+	if selector == nil {
+		return nil
+	}
+	var requirements []corev1.NodeSelectorRequirement
+	keys := make([]string, 0, len(selector.MatchLabels))
+	for key := range selector.MatchLabels {
+		keys = append(keys, key)
+	}
+	// keep the order stable, or the daemonset will be updated every time.
+	sort.Strings(keys)
+	for _, key := range keys {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{selector.MatchLabels[key]},
+		})
+	}
+	for _, expression := range selector.MatchExpressions {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      expression.Key,
+			Operator: corev1.NodeSelectorOperator(expression.Operator),
+			Values:   expression.Values,
+		})
+	}
+	return requirements
 }
 
 func (n *node) daemonSetForRainbondNode() client.Object {
@@ -184,9 +268,6 @@ func (n *node) daemonSetForRainbondNode() client.Object {
 	if n.cluster.Spec.GatewayVIP != "" {
 		args = append(args, "--gateway-vip="+n.cluster.Spec.GatewayVIP)
 	}
-	volumeMounts = mergeVolumeMounts(volumeMounts, n.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, n.component.Spec.Volumes)
-
 	envs := []corev1.EnvVar{
 		{
 			Name: "POD_IP",
@@ -215,80 +296,38 @@ func (n *node) daemonSetForRainbondNode() client.Object {
 			Value: hubImageRepository,
 		})
 	}
-	envs = mergeEnvs(envs, n.component.Spec.Env)
-
-	// prepare probe
-	readinessProbe := probeutil.MakeReadinessProbeHTTP("", "/v2/ping", 6100)
-	args = mergeArgs(args, n.component.Spec.Args)
-	ds := &appsv1.DaemonSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      NodeName,
-			Namespace: n.component.Namespace,
-			Labels:    n.labels,
-		},
-		Spec: appsv1.DaemonSetSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: n.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   NodeName,
-					Labels: n.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(n.component, n.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					ServiceAccountName:            "rainbond-operator",
-					HostAliases:                   hostsAliases(n.cluster),
-					HostPID:                       true,
-					DNSPolicy:                     corev1.DNSClusterFirstWithHostNet,
-					HostNetwork:                   true,
-					Tolerations: []corev1.Toleration{
-						{
-							Operator: corev1.TolerationOpExists, // tolerate everything.
-						},
-					},
-					Containers: []corev1.Container{
-						{
-							Name:            NodeName,
-							Image:           n.component.Spec.Image,
-							ImagePullPolicy: n.component.ImagePullPolicy(),
-							Env:             envs,
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							ReadinessProbe:  readinessProbe,
-						},
-					},
-					Volumes: volumes,
+	initContainers, hostFilesVolumes := hostFilesInitContainers(n.component)
+	return buildDaemonSet(NodeName, n.component, n.cluster, n.labels,
+		withArgs(args...),
+		withEnv(envs...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(probeutil.MakeLivenessProbeHTTP("", "/v2/ping", 6100), probeutil.MakeReadinessProbeHTTP("", "/v2/ping", 6100)),
+		withAffinity(n.affinityForNode()),
+		withServiceAccount(serviceAccountName(n.component)),
+		withPodSpec(func(spec *corev1.PodSpec) {
+			spec.HostAliases = hostsAliases(n.cluster)
+			spec.HostPID = true
+			spec.HostNetwork = true
+			spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+			spec.InitContainers = initContainers
+			spec.Volumes = append(spec.Volumes, hostFilesVolumes...)
+			spec.Tolerations = []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists, // tolerate everything.
 				},
-			},
-		},
-	}
-
-	return ds
+			}
+		}),
+	)
 }
 
 func (n *node) serviceForNode() client.Object {
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      NodeName,
-			Namespace: n.component.Namespace,
-			Labels:    n.labels,
+	return buildService(NodeName, n.component.Namespace, n.labels, corev1.ServicePort{
+		Name: "api",
+		Port: 6100,
+		TargetPort: intstr.IntOrString{
+			IntVal: 6100,
 		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name: "api",
-					Port: 6100,
-					TargetPort: intstr.IntOrString{
-						IntVal: 6100,
-					},
-				},
-			},
-			Selector: n.labels,
-		},
-	}
-	return svc
+	})
 }
 
 func (n *node) serviceMonitorForNode() client.Object {
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// storageComponents are the data stores restricted to the nodes of the storage role.
+var storageComponents = []string{DBName, EtcdName, HubName, MonitorName, NFSName}
+
+// SetStorageNodeSelector restricts the pods of the given object, which is a workload of the data stores, to the
+// nodes of the storage role, if any are assigned. Other objects are left unchanged.
+func SetStorageNodeSelector(obj client.Object, component string, roles *rainbondv1alpha1.NodeRoles) {
+	if roles == nil || len(roles.Storage) == 0 || !containsComponent(storageComponents, component) {
+		return
+	}
+	template := podTemplate(obj)
+	if template == nil {
+		return
+	}
+	if template.Spec.NodeSelector == nil {
+		template.Spec.NodeSelector = make(map[string]string)
+	}
+	template.Spec.NodeSelector[constants.SpecialStorageLabelKey] = ""
+}
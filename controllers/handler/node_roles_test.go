@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestSetStorageNodeSelector(t *testing.T) {
+	roles := &rainbondv1alpha1.NodeRoles{Storage: []string{"node1"}}
+
+	db := &appsv1.StatefulSet{}
+	SetStorageNodeSelector(db, DBName, roles)
+	assert.Equal(t, map[string]string{constants.SpecialStorageLabelKey: ""}, db.Spec.Template.Spec.NodeSelector)
+
+	api := &appsv1.Deployment{}
+	SetStorageNodeSelector(api, APIName, roles)
+	assert.Nil(t, api.Spec.Template.Spec.NodeSelector)
+
+	// the data stores run anywhere if no storage nodes are assigned.
+	hub := &appsv1.Deployment{}
+	SetStorageNodeSelector(hub, HubName, &rainbondv1alpha1.NodeRoles{Gateway: []string{"node1"}})
+	assert.Nil(t, hub.Spec.Template.Spec.NodeSelector)
+}
@@ -23,6 +23,8 @@ import (
 	"testing"
 
 	"github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
@@ -37,3 +39,18 @@ func TestPrometheusRuleForNode(t *testing.T) {
 	out, _ := yaml.Marshal(no.prometheusRuleForNode())
 	fmt.Println(string(out))
 }
+
+func TestNodeSelectorRequirements(t *testing.T) {
+	selector := &v1.LabelSelector{
+		MatchLabels: map[string]string{"zone": "a", "arch": "amd64"},
+		MatchExpressions: []v1.LabelSelectorRequirement{
+			{Key: "node-role.kubernetes.io/edge", Operator: v1.LabelSelectorOpDoesNotExist},
+		},
+	}
+	assert.Equal(t, []corev1.NodeSelectorRequirement{
+		{Key: "arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}},
+		{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+		{Key: "node-role.kubernetes.io/edge", Operator: corev1.NodeSelectorOpDoesNotExist},
+	}, nodeSelectorRequirements(selector))
+	assert.Nil(t, nodeSelectorRequirements(nil))
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NvidiaDevicePluginName name for nvidia-device-plugin.
+var NvidiaDevicePluginName = "nvidia-device-plugin"
+
+// gpuResourceName is the extended resource advertised by the NVIDIA device plugin.
+const gpuResourceName = "nvidia.com/gpu"
+
+type nvidiaDevicePlugin struct {
+	ctx       context.Context
+	client    client.Client
+	component *rainbondv1alpha1.RbdComponent
+	cluster   *rainbondv1alpha1.RainbondCluster
+	labels    map[string]string
+
+	gpuNodes int32
+}
+
+var _ ComponentHandler = &nvidiaDevicePlugin{}
+var _ ResourcesDeleter = &nvidiaDevicePlugin{}
+var _ Replicaser = &nvidiaDevicePlugin{}
+
+// NewNvidiaDevicePlugin creates a new nvidia-device-plugin handler.
+func NewNvidiaDevicePlugin(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
+	return &nvidiaDevicePlugin{
+		ctx:       ctx,
+		client:    client,
+		component: component,
+		cluster:   cluster,
+		labels:    LabelsForRainbondComponent(component),
+	}
+}
+
+func (n *nvidiaDevicePlugin) Before() error {
+	if !gpuEnabled(n.cluster) {
+		return nil
+	}
+	nodeList := &corev1.NodeList{}
+	if err := n.client.List(n.ctx, nodeList, client.MatchingLabels(gpuNodeSelector(n.cluster))); err != nil {
+		return fmt.Errorf("list gpu nodes: %v", err)
+	}
+	n.gpuNodes = int32(len(nodeList.Items))
+	return nil
+}
+
+func (n *nvidiaDevicePlugin) Resources() []client.Object {
+	if n.gpuNodes == 0 {
+		return nil
+	}
+	return []client.Object{
+		n.daemonSet(),
+	}
+}
+
+func (n *nvidiaDevicePlugin) After() error {
+	return nil
+}
+
+func (n *nvidiaDevicePlugin) ListPods() ([]corev1.Pod, error) {
+	return listPods(n.ctx, n.client, n.component.Namespace, n.labels)
+}
+
+// ResourcesNeedDelete returns the device plugin if the GPU scheduling is disabled or there are no GPU nodes.
+func (n *nvidiaDevicePlugin) ResourcesNeedDelete() []client.Object {
+	if n.gpuNodes > 0 {
+		return nil
+	}
+	return []client.Object{
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      NvidiaDevicePluginName,
+				Namespace: n.component.Namespace,
+			},
+		},
+	}
+}
+
+func (n *nvidiaDevicePlugin) Replicas() *int32 {
+	return commonutil.Int32(n.gpuNodes)
+}
+
+func (n *nvidiaDevicePlugin) daemonSet() client.Object {
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "device-plugin",
+			MountPath: "/var/lib/kubelet/device-plugins",
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "device-plugin",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: "/var/lib/kubelet/device-plugins",
+					Type: k8sutil.HostPath(corev1.HostPathDirectory),
+				},
+			},
+		},
+	}
+	env := []corev1.EnvVar{
+		{
+			// keep running on the nodes whose drivers are not ready yet.
+			Name:  "FAIL_ON_INIT_ERROR",
+			Value: "false",
+		},
+	}
+	env = mergeEnvs(env, n.component.Spec.Env)
+	volumeMounts = mergeVolumeMounts(volumeMounts, n.component.Spec.VolumeMounts)
+	volumes = mergeVolumes(volumes, n.component.Spec.Volumes)
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      NvidiaDevicePluginName,
+			Namespace: n.component.Namespace,
+			Labels:    n.labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: n.labels,
+			},
+			UpdateStrategy: daemonSetUpdateStrategy(n.component),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   NvidiaDevicePluginName,
+					Labels: n.labels,
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets:              imagePullSecrets(n.component, n.cluster),
+					TerminationGracePeriodSeconds: terminationGracePeriodSeconds(n.component, 0),
+					PriorityClassName:             "system-node-critical",
+					NodeSelector:                  gpuNodeSelector(n.cluster),
+					Tolerations: []corev1.Toleration{
+						{
+							Key:      "CriticalAddonsOnly",
+							Operator: corev1.TolerationOpExists,
+						},
+						{
+							Key:      gpuResourceName,
+							Operator: corev1.TolerationOpExists,
+							Effect:   corev1.TaintEffectNoSchedule,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:            NvidiaDevicePluginName,
+							Image:           n.component.Spec.Image,
+							ImagePullPolicy: n.component.ImagePullPolicy(),
+							Env:             env,
+							Args:            n.component.Spec.Args,
+							VolumeMounts:    volumeMounts,
+							Resources:       n.component.Spec.Resources,
+							SecurityContext: securityContext(n.component, &corev1.SecurityContext{
+								AllowPrivilegeEscalation: commonutil.Bool(false),
+								Capabilities: &corev1.Capabilities{
+									Drop: []corev1.Capability{"ALL"},
+								},
+							}),
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+
+	return ds
+}
+
+func gpuEnabled(cluster *rainbondv1alpha1.RainbondCluster) bool {
+	return cluster.Spec.GPU != nil && cluster.Spec.GPU.Enabled
+}
+
+func gpuNodeSelector(cluster *rainbondv1alpha1.RainbondCluster) map[string]string {
+	if cluster.Spec.GPU != nil && len(cluster.Spec.GPU.NodeSelector) > 0 {
+		return cluster.Spec.GPU.NodeSelector
+	}
+	return map[string]string{constants.SpecialGPULabelKey: "true"}
+}
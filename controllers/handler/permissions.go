@@ -0,0 +1,170 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	readVerbs  = []string{"get", "list", "watch"}
+	writeVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}
+)
+
+// permissions are the RBAC rules of a component talking to the kubernetes API.
+type permissions struct {
+	// namespaced rules are granted in the namespace of the rbdcomponent by a Role.
+	namespaced []rbacv1.PolicyRule
+	// cluster rules are granted by a ClusterRole, for the resources in the namespaces of the tenants
+	// and the cluster-scoped resources.
+	cluster []rbacv1.PolicyRule
+}
+
+// componentPermissions is the permission manifest of the components, each of which runs with its own
+// service account granted the exact rules below, rather than the service account of rainbond-operator.
+// Keep it in sync with the requests the components make.
+var componentPermissions = map[string]permissions{
+	// rbd-worker runs the applications of the tenants, and builds the images with jobs.
+	WorkerName: {
+		namespaced: []rbacv1.PolicyRule{
+			{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: writeVerbs},
+		},
+		cluster: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get", "list", "watch", "create", "update", "patch"}},
+			{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: readVerbs},
+			// the provisioners of rbd-worker create the storage classes rainbondsslc and rainbondslsc on start,
+			// and provision the volumes of the claims of the tenants.
+			{APIGroups: []string{""}, Resources: []string{"persistentvolumes"}, Verbs: writeVerbs},
+			{APIGroups: []string{""}, Resources: []string{"pods", "pods/log", "services", "endpoints", "configmaps", "secrets",
+				"persistentvolumeclaims", "serviceaccounts", "events"}, Verbs: writeVerbs},
+			{APIGroups: []string{"apps"}, Resources: []string{"deployments", "statefulsets", "daemonsets", "replicasets"}, Verbs: writeVerbs},
+			{APIGroups: []string{"batch"}, Resources: []string{"jobs", "cronjobs"}, Verbs: writeVerbs},
+			{APIGroups: []string{"autoscaling"}, Resources: []string{"horizontalpodautoscalers"}, Verbs: writeVerbs},
+			{APIGroups: []string{"networking.k8s.io", "extensions"}, Resources: []string{"ingresses"}, Verbs: writeVerbs},
+			{APIGroups: []string{"storage.k8s.io"}, Resources: []string{"storageclasses"}, Verbs: writeVerbs},
+			{APIGroups: []string{"monitoring.coreos.com"}, Resources: []string{"servicemonitors"}, Verbs: writeVerbs},
+			{APIGroups: []string{"rainbond.io"}, Resources: []string{"helmapps", "helmapps/status", "componentdefinitions",
+				"thirdcomponents", "thirdcomponents/status"}, Verbs: writeVerbs},
+			{APIGroups: []string{"metrics.k8s.io"}, Resources: []string{"pods", "nodes"}, Verbs: readVerbs},
+		},
+	},
+	// rbd-node-proxy reports the status of the nodes, and proxies the services of the tenants.
+	NodeName: {
+		cluster: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"nodes", "nodes/status"}, Verbs: []string{"get", "list", "watch", "update", "patch"}},
+			{APIGroups: []string{""}, Resources: []string{"pods", "services", "endpoints", "configmaps"}, Verbs: readVerbs},
+			{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "patch"}},
+		},
+	},
+	// rbd-gateway routes the traffic of the ingresses of the tenants.
+	GatewayName: {
+		namespaced: []rbacv1.PolicyRule{
+			{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: writeVerbs},
+		},
+		cluster: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"namespaces", "nodes", "pods", "services", "endpoints", "configmaps", "secrets"}, Verbs: readVerbs},
+			{APIGroups: []string{"networking.k8s.io", "extensions"}, Resources: []string{"ingresses"}, Verbs: readVerbs},
+			{APIGroups: []string{"networking.k8s.io", "extensions"}, Resources: []string{"ingresses/status"}, Verbs: []string{"update", "patch"}},
+			{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "patch"}},
+		},
+	},
+}
+
+// serviceAccountName returns the name of the service account of the component, which is the name of the
+// component if it is in the permission manifest, or the service account of rainbond-operator otherwise.
+func serviceAccountName(cpt *rainbondv1alpha1.RbdComponent) string {
+	if _, ok := componentPermissions[cpt.Name]; ok {
+		return cpt.Name
+	}
+	return rbdutil.ServiceAccountName()
+}
+
+// permissionResources returns the service account, the Role and the RoleBinding of the component.
+func permissionResources(cpt *rainbondv1alpha1.RbdComponent, labels map[string]string) []client.Object {
+	perms, ok := componentPermissions[cpt.Name]
+	if !ok {
+		return nil
+	}
+	objs := []client.Object{
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cpt.Name,
+				Namespace: cpt.Namespace,
+				Labels:    labels,
+			},
+		},
+	}
+	if len(perms.namespaced) == 0 {
+		return objs
+	}
+	return append(objs,
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cpt.Name,
+				Namespace: cpt.Namespace,
+				Labels:    labels,
+			},
+			Rules: perms.namespaced,
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cpt.Name,
+				Namespace: cpt.Namespace,
+				Labels:    labels,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "Role",
+				Name:     cpt.Name,
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					Kind:      rbacv1.ServiceAccountKind,
+					Name:      cpt.Name,
+					Namespace: cpt.Namespace,
+				},
+			},
+		},
+	)
+}
+
+// clusterPermissionResources returns the ClusterRole and the ClusterRoleBinding of the component,
+// whose names are suffixed with the namespace to be unique among the regions.
+func clusterPermissionResources(cpt *rainbondv1alpha1.RbdComponent, labels map[string]string) []client.Object {
+	perms, ok := componentPermissions[cpt.Name]
+	if !ok || len(perms.cluster) == 0 {
+		return nil
+	}
+	name := cpt.Name + "-" + cpt.Namespace
+	labels = rbdutil.LabelsForInstance(labels, cpt.Namespace)
+	return []client.Object{
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+			Rules: perms.cluster,
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     name,
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					Kind:      rbacv1.ServiceAccountKind,
+					Name:      cpt.Name,
+					Namespace: cpt.Namespace,
+				},
+			},
+		},
+	}
+}
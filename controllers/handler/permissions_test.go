@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPermissionResources(t *testing.T) {
+	gateway := &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system", Name: GatewayName}}
+	assert.Equal(t, GatewayName, serviceAccountName(gateway))
+
+	objs := permissionResources(gateway, nil)
+	assert.Len(t, objs, 3)
+	assert.IsType(t, &corev1.ServiceAccount{}, objs[0])
+	assert.Equal(t, componentPermissions[GatewayName].namespaced, objs[1].(*rbacv1.Role).Rules)
+
+	clusterObjs := clusterPermissionResources(gateway, nil)
+	assert.Len(t, clusterObjs, 2)
+	binding := clusterObjs[1].(*rbacv1.ClusterRoleBinding)
+	assert.Equal(t, "rbd-gateway-rbd-system", binding.RoleRef.Name)
+	assert.Equal(t, GatewayName, binding.Subjects[0].Name)
+
+	// rbd-node-proxy has no namespaced rules.
+	node := &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system", Name: NodeName}}
+	assert.Len(t, permissionResources(node, nil), 1)
+
+	mq := &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Namespace: "rbd-system", Name: MQName}}
+	assert.Equal(t, "rainbond-operator", serviceAccountName(mq))
+	assert.Nil(t, permissionResources(mq, nil))
+	assert.Nil(t, clusterPermissionResources(mq, nil))
+}
+
+func TestWorkerPermissions(t *testing.T) {
+	// the provisioners of rbd-worker create the storage classes and the persistent volumes.
+	has := func(items []string, item string) bool {
+		for _, i := range items {
+			if i == item {
+				return true
+			}
+		}
+		return false
+	}
+	allowed := func(group, resource, verb string) bool {
+		for _, rule := range componentPermissions[WorkerName].cluster {
+			if has(rule.APIGroups, group) && has(rule.Resources, resource) && has(rule.Verbs, verb) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, verb := range []string{"create", "update", "delete"} {
+		assert.True(t, allowed("", "persistentvolumes", verb), verb)
+		assert.True(t, allowed("storage.k8s.io", "storageclasses", verb), verb)
+	}
+	assert.False(t, allowed("", "nodes", "update"))
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defStartupPeriod and defStartupFailureThreshold are the default budget of the startup probes that are
+// derived from the liveness probes, which is 5 minutes.
+const (
+	defStartupPeriod           = 10
+	defStartupFailureThreshold = 30
+)
+
+// SetProbes tunes the probes of the containers of the given object, which is a workload
+// generated by the handlers. The containers without probes are left unchanged, except that
+// a startup probe is derived from the liveness probe if the startup thresholds are given.
+func SetProbes(obj client.Object, probes *rainbondv1alpha1.ComponentProbes) {
+	if probes == nil {
+		return
+	}
+	template := podTemplate(obj)
+	if template == nil {
+		return
+	}
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		if container.StartupProbe == nil && probes.Startup != nil {
+			container.StartupProbe = probeutil.MakeStartupProbe(container.LivenessProbe, defStartupPeriod, defStartupFailureThreshold)
+		}
+		container.StartupProbe = tuneProbe(container.StartupProbe, probes.Startup)
+		container.LivenessProbe = tuneProbe(container.LivenessProbe, probes.Liveness)
+		container.ReadinessProbe = tuneProbe(container.ReadinessProbe, probes.Readiness)
+	}
+}
+
+func tuneProbe(probe *corev1.Probe, thresholds *rainbondv1alpha1.ProbeThresholds) *corev1.Probe {
+	if probe == nil || thresholds == nil {
+		return probe
+	}
+	if thresholds.Disabled {
+		return nil
+	}
+	initialDelay, timeout, period := probe.InitialDelaySeconds, probe.TimeoutSeconds, probe.PeriodSeconds
+	successThreshold, failureThreshold := probe.SuccessThreshold, probe.FailureThreshold
+	if thresholds.InitialDelaySeconds != nil {
+		initialDelay = *thresholds.InitialDelaySeconds
+	}
+	if thresholds.TimeoutSeconds != nil {
+		timeout = *thresholds.TimeoutSeconds
+	}
+	if thresholds.PeriodSeconds != nil {
+		period = *thresholds.PeriodSeconds
+	}
+	if thresholds.SuccessThreshold != nil {
+		successThreshold = *thresholds.SuccessThreshold
+	}
+	if thresholds.FailureThreshold != nil {
+		failureThreshold = *thresholds.FailureThreshold
+	}
+	probeutil.SetProbeArgs(probe, initialDelay, timeout, period, successThreshold, failureThreshold)
+	return probe
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetProbes(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:           "rbd-api",
+							LivenessProbe:  probeutil.MakeLivenessProbeHTTP("", "/v2/health", 8888),
+							ReadinessProbe: probeutil.MakeReadinessProbeHTTP("", "/v2/health", 8888),
+						},
+						{
+							Name: "sidecar",
+						},
+					},
+				},
+			},
+		},
+	}
+	SetProbes(deploy, &rainbondv1alpha1.ComponentProbes{
+		Liveness: &rainbondv1alpha1.ProbeThresholds{
+			InitialDelaySeconds: commonutil.Int32(60),
+			FailureThreshold:    commonutil.Int32(6),
+		},
+		Readiness: &rainbondv1alpha1.ProbeThresholds{
+			Disabled: true,
+		},
+	})
+
+	containers := deploy.Spec.Template.Spec.Containers
+	liveness := containers[0].LivenessProbe
+	assert.Equal(t, int32(60), liveness.InitialDelaySeconds)
+	assert.Equal(t, int32(6), liveness.FailureThreshold)
+	// the thresholds not set are left unchanged.
+	assert.Equal(t, int32(5), liveness.TimeoutSeconds)
+	assert.Equal(t, int32(10), liveness.PeriodSeconds)
+	assert.Nil(t, containers[0].ReadinessProbe)
+	assert.Nil(t, containers[1].LivenessProbe)
+}
+
+func TestSetStartupProbes(t *testing.T) {
+	newDeploy := func(startup *corev1.Probe) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:          "rbd-monitor",
+								LivenessProbe: probeutil.MakeLivenessProbeHTTP("", "/monitor/health", 3329),
+								StartupProbe:  startup,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name                 string
+		startup              *corev1.Probe
+		thresholds           *rainbondv1alpha1.ProbeThresholds
+		wantFailureThreshold int32
+		wantNil              bool
+	}{
+		{
+			name:    "no startup probe by default",
+			wantNil: true,
+		},
+		{
+			name:                 "default of the component",
+			startup:              probeutil.MakeStartupProbe(probeutil.MakeLivenessProbeHTTP("", "/monitor/health", 3329), 10, 60),
+			wantFailureThreshold: 60,
+		},
+		{
+			name:                 "tune the default of the component",
+			startup:              probeutil.MakeStartupProbe(probeutil.MakeLivenessProbeHTTP("", "/monitor/health", 3329), 10, 60),
+			thresholds:           &rainbondv1alpha1.ProbeThresholds{FailureThreshold: commonutil.Int32(120)},
+			wantFailureThreshold: 120,
+		},
+		{
+			name:                 "derived from the liveness probe",
+			thresholds:           &rainbondv1alpha1.ProbeThresholds{},
+			wantFailureThreshold: defStartupFailureThreshold,
+		},
+		{
+			name:       "disabled",
+			startup:    probeutil.MakeStartupProbe(probeutil.MakeLivenessProbeHTTP("", "/monitor/health", 3329), 10, 60),
+			thresholds: &rainbondv1alpha1.ProbeThresholds{Disabled: true},
+			wantNil:    true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			deploy := newDeploy(tc.startup)
+			SetProbes(deploy, &rainbondv1alpha1.ComponentProbes{Startup: tc.thresholds})
+
+			container := deploy.Spec.Template.Spec.Containers[0]
+			if tc.wantNil {
+				assert.Nil(t, container.StartupProbe)
+				return
+			}
+			if assert.NotNil(t, container.StartupProbe) {
+				assert.Equal(t, tc.wantFailureThreshold, container.StartupProbe.FailureThreshold)
+				assert.Equal(t, container.LivenessProbe.HTTPGet, container.StartupProbe.HTTPGet)
+			}
+			assert.NotNil(t, container.LivenessProbe)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Factory creates the ComponentHandler of the rbdcomponent. It is called at every reconciliation
+// of the rbdcomponent, so the handler can keep the state of one reconciliation in its fields.
+type Factory func(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler
+
+// Registry maps the names of the rbdcomponents to the factories of their handlers.
+// It is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// DefaultRegistry is the registry used by the rbdcomponent controller, the builtin handlers are registered in it.
+var DefaultRegistry = NewRegistry()
+
+// Register registers the factory of the handler for the rbdcomponent with the name.
+// It fails if the name is empty or a handler is already registered for it.
+func (r *Registry) Register(name string, factory Factory) error {
+	if name == "" {
+		return fmt.Errorf("register handler: empty component name")
+	}
+	if factory == nil {
+		return fmt.Errorf("register handler %s: nil factory", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("register handler %s: already registered", name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// Get returns the factory of the handler for the rbdcomponent with the name.
+func (r *Registry) Get(name string) (Factory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	factory, ok := r.factories[name]
+	return factory, ok
+}
+
+// Names returns the sorted names of the rbdcomponents with a registered handler.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Register registers the factory in the DefaultRegistry, it panics on failure.
+// It is meant to be called in the init function of the package providing the handler, such as:
+//
+//	func init() {
+//		handler.Register("rbd-foo", NewFoo)
+//	}
+func Register(name string, factory Factory) {
+	if err := DefaultRegistry.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
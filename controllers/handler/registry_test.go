@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register(APIName, NewAPI))
+	assert.Nil(t, registry.Register(WorkerName, NewWorker))
+	assert.NotNil(t, registry.Register(APIName, NewAPI))
+	assert.NotNil(t, registry.Register("", NewAPI))
+	assert.NotNil(t, registry.Register(ChaosName, nil))
+
+	_, ok := registry.Get(APIName)
+	assert.True(t, ok)
+	_, ok = registry.Get(ChaosName)
+	assert.False(t, ok)
+	assert.Equal(t, []string{APIName, WorkerName}, registry.Names())
+}
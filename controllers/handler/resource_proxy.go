@@ -6,10 +6,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
-	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -76,8 +75,6 @@ func (r *resourceProxy) resource() []client.Object {
 		},
 	}
 
-	volumeMounts = mergeVolumeMounts(volumeMounts, r.component.Spec.VolumeMounts)
-
 	resources := corev1.ResourceRequirements{
 		Limits: corev1.ResourceList{
 			corev1.ResourceMemory: resource.MustParse("512Mi"),
@@ -89,67 +86,27 @@ func (r *resourceProxy) resource() []client.Object {
 		},
 	}
 
-	resources = mergeResources(resources, r.component.Spec.Resources)
-
-	ds := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      ResourceProxyName,
-			Namespace: r.component.Namespace,
-			Labels:    r.labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: r.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: r.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   ResourceProxyName,
-					Labels: r.labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets:              imagePullSecrets(r.component, r.cluster),
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					Containers: []corev1.Container{
-						{
-							Name:            ResourceProxyName,
-							Image:           r.component.Spec.Image,
-							ImagePullPolicy: r.component.ImagePullPolicy(),
-							VolumeMounts:    volumeMounts,
-							Resources:       resources,
-							Args:            r.component.Spec.Args,
-						},
-					},
-					Volumes: []corev1.Volume{
-						{
-							Name: claimName,
-							VolumeSource: corev1.VolumeSource{
-								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-									ClaimName: claimName,
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      ResourceProxyName,
-			Namespace: r.component.Namespace,
-			Labels:    r.labels,
-		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name: "http",
-					Port: 80,
+	volumes := []corev1.Volume{
+		{
+			Name: claimName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: claimName,
 				},
 			},
-			Selector: r.labels,
 		},
 	}
+	// the claim of the cache can only be attached to one node.
+	ds := buildDeployment(ResourceProxyName, r.component, r.cluster, r.labels, appsv1.RecreateDeploymentStrategyType,
+		withVolumes(volumes, volumeMounts),
+		withResources(resources),
+		withProbes(probeutil.MakeLivenessProbeTCP("", 80), probeutil.MakeReadinessProbeTCP("", 80)),
+		withAffinity(nil),
+	)
+
+	svc := buildService(ResourceProxyName, r.component.Namespace, r.labels, corev1.ServicePort{
+		Name: "http",
+		Port: 80,
+	})
 	return []client.Object{ds, resourceProxyDataPVC, svc}
 }
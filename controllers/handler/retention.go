@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"fmt"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+)
+
+// defRetentionDays is how many days the data of the components is kept by default.
+const defRetentionDays = 7
+
+// retentionDays returns how many days the data of the component is kept.
+func retentionDays(cpt *rainbondv1alpha1.RbdComponent) int32 {
+	if retention := cpt.Spec.Retention; retention != nil && retention.Days != nil && *retention.Days > 0 {
+		return *retention.Days
+	}
+	return defRetentionDays
+}
+
+// prometheusRetentionSize returns the maximum size of the data in the units of prometheus, such as 512MB,
+// or an empty string if there is no limit.
+func prometheusRetentionSize(cpt *rainbondv1alpha1.RbdComponent) string {
+	retention := cpt.Spec.Retention
+	if retention == nil || retention.Size == nil || retention.Size.Value() <= 0 {
+		return ""
+	}
+	// the units of prometheus are based on 1024, and MB is fine-grained enough.
+	mb := retention.Size.Value() / (1 << 20)
+	if mb == 0 {
+		mb = 1
+	}
+	return fmt.Sprintf("%dMB", mb)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRetention(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{}
+	assert.Equal(t, int32(defRetentionDays), retentionDays(cpt))
+	assert.Empty(t, prometheusRetentionSize(cpt))
+
+	size := resource.MustParse("50Gi")
+	cpt.Spec.Retention = &rainbondv1alpha1.DataRetention{Days: commonutil.Int32(30), Size: &size}
+	assert.Equal(t, int32(30), retentionDays(cpt))
+	assert.Equal(t, "51200MB", prometheusRetentionSize(cpt))
+
+	size = resource.MustParse("100Ki")
+	assert.Equal(t, "1MB", prometheusRetentionSize(cpt))
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defUninjectedComponents are the components whose sidecar injection is disabled by default.
+var defUninjectedComponents = []string{EtcdName}
+
+// meshExcludedOutboundPorts are the ports of rbd-etcd and rbd-db, the traffic to which bypasses the sidecars,
+// whether they are injected or not.
+var meshExcludedOutboundPorts = []int32{2379, 2380, 3306}
+
+// meshExcludedInboundPorts are the ports of the data stores not intercepted by their own sidecars, if injected.
+var meshExcludedInboundPorts = map[string][]int32{
+	EtcdName: {2379, 2380},
+	DBName:   {3306},
+}
+
+// meshAnnotations are the annotations of the pods read by the sidecar injector of a service mesh.
+type meshAnnotations struct {
+	inject          string
+	injectDisabled  string
+	excludeInbound  string
+	excludeOutbound string
+}
+
+var serviceMeshAnnotations = map[rainbondv1alpha1.ServiceMeshType]meshAnnotations{
+	rainbondv1alpha1.ServiceMeshIstio: {
+		inject:          "sidecar.istio.io/inject",
+		injectDisabled:  "false",
+		excludeInbound:  "traffic.sidecar.istio.io/excludeInboundPorts",
+		excludeOutbound: "traffic.sidecar.istio.io/excludeOutboundPorts",
+	},
+	rainbondv1alpha1.ServiceMeshLinkerd: {
+		inject:          "linkerd.io/inject",
+		injectDisabled:  "disabled",
+		excludeInbound:  "config.linkerd.io/skip-inbound-ports",
+		excludeOutbound: "config.linkerd.io/skip-outbound-ports",
+	},
+}
+
+// SetServiceMesh annotates the pods of the given object, which is a workload of the component, to coexist with the
+// service mesh. The injection is disabled for the pods on the host network, the jobs, whose sidecars never exit, and
+// the uninjected components. The sidecars of the others don't intercept the traffic of the data stores. The
+// annotations set by the handlers are kept.
+func SetServiceMesh(obj client.Object, component string, mesh *rainbondv1alpha1.ServiceMesh) {
+	if mesh == nil {
+		return
+	}
+	keys, ok := serviceMeshAnnotations[mesh.Type]
+	if !ok {
+		return
+	}
+	template := podTemplate(obj)
+	if template == nil {
+		return
+	}
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	setAnnotation := func(key, value string) {
+		if _, ok := template.Annotations[key]; !ok && value != "" {
+			template.Annotations[key] = value
+		}
+	}
+
+	uninjected := mesh.UninjectedComponents
+	if len(uninjected) == 0 {
+		uninjected = defUninjectedComponents
+	}
+	_, isJob := obj.(*batchv1.Job)
+	_, isCronJob := obj.(*batchv1beta1.CronJob)
+	if template.Spec.HostNetwork || isJob || isCronJob || containsComponent(uninjected, component) {
+		setAnnotation(keys.inject, keys.injectDisabled)
+		return
+	}
+	setAnnotation(keys.excludeInbound, joinPorts(meshExcludedInboundPorts[component]))
+	setAnnotation(keys.excludeOutbound, joinPorts(append(append([]int32{}, meshExcludedOutboundPorts...), mesh.ExcludeOutboundPorts...)))
+}
+
+func containsComponent(components []string, component string) bool {
+	for _, name := range components {
+		if name == component {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPorts returns the sorted, deduplicated ports separated by commas.
+func joinPorts(ports []int32) string {
+	seen := make(map[int32]bool, len(ports))
+	var sorted []int
+	for _, port := range ports {
+		if seen[port] {
+			continue
+		}
+		seen[port] = true
+		sorted = append(sorted, int(port))
+	}
+	sort.Ints(sorted)
+	values := make([]string, 0, len(sorted))
+	for _, port := range sorted {
+		values = append(values, strconv.Itoa(port))
+	}
+	return strings.Join(values, ",")
+}
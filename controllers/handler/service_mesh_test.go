@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSetServiceMesh(t *testing.T) {
+	tests := []struct {
+		name      string
+		obj       func() (client.Object, *corev1.PodTemplateSpec)
+		component string
+		mesh      *rainbondv1alpha1.ServiceMesh
+		want      map[string]string
+	}{
+		{
+			name: "no mesh",
+			obj: func() (client.Object, *corev1.PodTemplateSpec) {
+				deploy := &appsv1.Deployment{}
+				return deploy, &deploy.Spec.Template
+			},
+			component: APIName,
+		},
+		{
+			name: "host network",
+			obj: func() (client.Object, *corev1.PodTemplateSpec) {
+				ds := &appsv1.DaemonSet{}
+				ds.Spec.Template.Spec.HostNetwork = true
+				return ds, &ds.Spec.Template
+			},
+			component: GatewayName,
+			mesh:      &rainbondv1alpha1.ServiceMesh{Type: rainbondv1alpha1.ServiceMeshIstio},
+			want:      map[string]string{"sidecar.istio.io/inject": "false"},
+		},
+		{
+			name: "cronjob",
+			obj: func() (client.Object, *corev1.PodTemplateSpec) {
+				cronJob := &batchv1beta1.CronJob{}
+				return cronJob, &cronJob.Spec.JobTemplate.Spec.Template
+			},
+			component: HubName,
+			mesh:      &rainbondv1alpha1.ServiceMesh{Type: rainbondv1alpha1.ServiceMeshLinkerd},
+			want:      map[string]string{"linkerd.io/inject": "disabled"},
+		},
+		{
+			name: "etcd uninjected by default",
+			obj: func() (client.Object, *corev1.PodTemplateSpec) {
+				sts := &appsv1.StatefulSet{}
+				return sts, &sts.Spec.Template
+			},
+			component: EtcdName,
+			mesh:      &rainbondv1alpha1.ServiceMesh{Type: rainbondv1alpha1.ServiceMeshIstio},
+			want:      map[string]string{"sidecar.istio.io/inject": "false"},
+		},
+		{
+			name: "injected etcd",
+			obj: func() (client.Object, *corev1.PodTemplateSpec) {
+				sts := &appsv1.StatefulSet{}
+				return sts, &sts.Spec.Template
+			},
+			component: EtcdName,
+			mesh: &rainbondv1alpha1.ServiceMesh{
+				Type:                 rainbondv1alpha1.ServiceMeshLinkerd,
+				UninjectedComponents: []string{DBName},
+			},
+			want: map[string]string{
+				"config.linkerd.io/skip-inbound-ports":  "2379,2380",
+				"config.linkerd.io/skip-outbound-ports": "2379,2380,3306",
+			},
+		},
+		{
+			name: "injected",
+			obj: func() (client.Object, *corev1.PodTemplateSpec) {
+				deploy := &appsv1.Deployment{}
+				deploy.Spec.Template.Annotations = map[string]string{"sidecar.istio.io/inject": "true"}
+				return deploy, &deploy.Spec.Template
+			},
+			component: APIName,
+			mesh: &rainbondv1alpha1.ServiceMesh{
+				Type:                 rainbondv1alpha1.ServiceMeshIstio,
+				ExcludeOutboundPorts: []int32{6379, 3306},
+			},
+			want: map[string]string{
+				"sidecar.istio.io/inject":                       "true",
+				"traffic.sidecar.istio.io/excludeOutboundPorts": "2379,2380,3306,6379",
+			},
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			obj, template := tc.obj()
+			SetServiceMesh(obj, tc.component, tc.mesh)
+			if tc.want == nil {
+				assert.Empty(t, template.Annotations)
+				return
+			}
+			assert.Equal(t, tc.want, template.Annotations)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"path"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	zoneinfoPath = "/usr/share/zoneinfo"
+	// hostZoneinfoPath is where the zoneinfo of the host is mounted, which must not hide the one of the images.
+	hostZoneinfoPath = "/host/usr/share/zoneinfo"
+	localtimePath    = "/etc/localtime"
+)
+
+// SetTimezone sets the timezone of the pods of the given object, which is a
+// workload generated by the handlers. Other objects are left unchanged.
+func SetTimezone(obj client.Object, timezone *rainbondv1alpha1.Timezone) {
+	if timezone == nil || (timezone.Name == "" && !timezone.HostLocaltime) {
+		return
+	}
+	if template := podTemplate(obj); template != nil {
+		setPodTimezone(&template.Spec, timezone)
+	}
+}
+
+func podTemplate(obj client.Object) *corev1.PodTemplateSpec {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &o.Spec.Template
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template
+	case *batchv1.Job:
+		return &o.Spec.Template
+	case *batchv1beta1.CronJob:
+		return &o.Spec.JobTemplate.Spec.Template
+	}
+	return nil
+}
+
+func setPodTimezone(spec *corev1.PodSpec, timezone *rainbondv1alpha1.Timezone) {
+	var volumes []corev1.Volume
+	var volumeMounts []corev1.VolumeMount
+	var env []corev1.EnvVar
+	if timezone.Name != "" {
+		// the absolute path of the timezone is honored by glibc, musl and go.
+		env = append(env, corev1.EnvVar{
+			Name:  "TZ",
+			Value: path.Join(hostZoneinfoPath, timezone.Name),
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "zoneinfo",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: zoneinfoPath,
+					Type: k8sutil.HostPath(corev1.HostPathDirectory),
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "zoneinfo",
+			MountPath: hostZoneinfoPath,
+			ReadOnly:  true,
+		})
+	}
+	if timezone.HostLocaltime {
+		volumes = append(volumes, corev1.Volume{
+			Name: "localtime",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: localtimePath,
+					Type: k8sutil.HostPath(corev1.HostPathFile),
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "localtime",
+			MountPath: localtimePath,
+			ReadOnly:  true,
+		})
+	}
+
+	spec.Volumes = mergeVolumes(volumes, spec.Volumes)
+	for i := range spec.InitContainers {
+		setContainerTimezone(&spec.InitContainers[i], env, volumeMounts)
+	}
+	for i := range spec.Containers {
+		setContainerTimezone(&spec.Containers[i], env, volumeMounts)
+	}
+}
+
+// setContainerTimezone keeps the environment variables and volume mounts that are set by the container.
+func setContainerTimezone(container *corev1.Container, env []corev1.EnvVar, volumeMounts []corev1.VolumeMount) {
+	container.Env = mergeEnvs(env, container.Env)
+	mountPaths := make(map[string]struct{})
+	for _, mount := range container.VolumeMounts {
+		mountPaths[mount.MountPath] = struct{}{}
+	}
+	for _, mount := range volumeMounts {
+		if _, ok := mountPaths[mount.MountPath]; ok {
+			continue
+		}
+		container.VolumeMounts = mergeVolumeMounts([]corev1.VolumeMount{mount}, container.VolumeMounts)
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetTimezone(t *testing.T) {
+	ds := &appsv1.DaemonSet{
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "foo",
+							Env: []corev1.EnvVar{
+								{Name: "TZ", Value: "UTC"},
+							},
+						},
+						{
+							Name: "bar",
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "etc", MountPath: localtimePath},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	SetTimezone(ds, &rainbondv1alpha1.Timezone{Name: "Asia/Shanghai", HostLocaltime: true})
+
+	spec := ds.Spec.Template.Spec
+	assert.Len(t, spec.Volumes, 2)
+	// the environment variable set by the container takes precedence.
+	assert.Equal(t, []corev1.EnvVar{{Name: "TZ", Value: "UTC"}}, spec.Containers[0].Env)
+	assert.Len(t, spec.Containers[0].VolumeMounts, 2)
+	assert.Equal(t, []corev1.EnvVar{{Name: "TZ", Value: "/host/usr/share/zoneinfo/Asia/Shanghai"}}, spec.Containers[1].Env)
+	// the zoneinfo of the images is not hidden.
+	assert.Equal(t, hostZoneinfoPath, spec.Containers[1].VolumeMounts[1].MountPath)
+	assert.Equal(t, corev1.HostPathDirectory, *spec.Volumes[0].HostPath.Type)
+	// /etc/localtime is already mounted by the container.
+	assert.Len(t, spec.Containers[1].VolumeMounts, 2)
+
+	svc := &corev1.Service{}
+	SetTimezone(svc, &rainbondv1alpha1.Timezone{Name: "Asia/Shanghai"})
+	assert.Equal(t, &corev1.Service{}, svc)
+}
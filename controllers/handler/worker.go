@@ -5,14 +5,10 @@ import (
 	"fmt"
 	"path"
 
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/probeutil"
 	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 
-	"github.com/goodrain/rainbond-operator/util/commonutil"
-	"github.com/goodrain/rainbond-operator/util/constants"
-
-	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
-
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,6 +32,8 @@ type worker struct {
 
 var _ ComponentHandler = &worker{}
 var _ StorageClassRWXer = &worker{}
+var _ ClusterScopedResourcesCreator = &worker{}
+var _ HealthChecker = &worker{}
 
 // NewWorker creates a new rbd-worker hanlder.
 func NewWorker(ctx context.Context, client client.Client, component *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) ComponentHandler {
@@ -67,11 +65,16 @@ func (w *worker) Before() error {
 }
 
 func (w *worker) Resources() []client.Object {
-	return []client.Object{
+	return append(permissionResources(w.component, w.labels),
 		w.deployment(),
 		w.serviceForWorker(),
 		w.serviceMonitorForWorker(),
-	}
+	)
+}
+
+// CreateClusterScoped returns the cluster role of rbd-worker, which manages the applications of the tenants.
+func (w *worker) CreateClusterScoped() []client.Object {
+	return clusterPermissionResources(w.component, w.labels)
 }
 
 func (w *worker) After() error {
@@ -82,6 +85,15 @@ func (w *worker) ListPods() ([]corev1.Pod, error) {
 	return listPods(w.ctx, w.client, w.component.Namespace, w.labels)
 }
 
+func (w *worker) Dependencies() []Dependency {
+	return []Dependency{dbDependency(w.db)}
+}
+
+// HealthEndpoint returns the health endpoint of rbd-worker, of which the readiness probe only checks the status code.
+func (w *worker) HealthEndpoint() HealthEndpoint {
+	return HealthEndpoint{Port: 6369, Path: "/worker/health"}
+}
+
 func (w *worker) SetStorageClassNameRWX(pvcParameters *pvcParameters) {
 	w.pvcParametersRWX = pvcParameters
 }
@@ -89,26 +101,15 @@ func (w *worker) SetStorageClassNameRWX(pvcParameters *pvcParameters) {
 func (w *worker) ResourcesCreateIfNotExists() []client.Object {
 	return []client.Object{
 		// pvc is immutable after creation except resources.requests for bound claims
-		createPersistentVolumeClaimRWX(w.component.Namespace, constants.GrDataPVC, w.pvcParametersRWX, w.labels),
+		grdataPVC(w.cluster, w.component.Namespace, w.pvcParametersRWX, w.labels),
 	}
 }
 
 func (w *worker) deployment() client.Object {
-	volumeMounts := []corev1.VolumeMount{
-		{
-			Name:      "grdata",
-			MountPath: "/grdata",
-		},
-	}
+	grdataVolume, grdataMounts := volumeByGrdata(w.cluster, w.component)
+	volumeMounts := grdataMounts
 	volumes := []corev1.Volume{
-		{
-			Name: "grdata",
-			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: constants.GrDataPVC,
-				},
-			},
-		},
+		grdataVolume,
 	}
 	args := []string{
 		"--host-ip=$(POD_IP)",
@@ -159,82 +160,39 @@ func (w *worker) deployment() client.Object {
 		})
 	}
 
-	args = mergeArgs(args, w.component.Spec.Args)
-	env = mergeEnvs(env, w.component.Spec.Env)
-	volumeMounts = mergeVolumeMounts(volumeMounts, w.component.Spec.VolumeMounts)
-	volumes = mergeVolumes(volumes, w.component.Spec.Volumes)
-
-	// prepare probe
-	readinessProbe := probeutil.MakeReadinessProbeHTTP("", "/worker/health", 6369)
-	ds := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      WorkerName,
-			Namespace: w.component.Namespace,
-			Labels:    w.labels,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: w.component.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: w.labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:   WorkerName,
-					Labels: w.labels,
-				},
-				Spec: corev1.PodSpec{
-					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					ServiceAccountName:            "rainbond-operator",
-					ImagePullSecrets:              imagePullSecrets(w.component, w.cluster),
-					Containers: []corev1.Container{
-						{
-							Name:            WorkerName,
-							Image:           w.component.Spec.Image,
-							ImagePullPolicy: w.component.ImagePullPolicy(),
-							Env:             env,
-							Args:            args,
-							VolumeMounts:    volumeMounts,
-							ReadinessProbe:  readinessProbe,
-							Resources:       w.component.Spec.Resources,
-						},
-					},
-					Volumes: volumes,
-				},
-			},
-		},
+	if w.cluster.Spec.LicenseSecretName != "" {
+		volume, mount, licenseEnv := volumeByLicense(w.cluster.Spec.LicenseSecretName)
+		volumeMounts = append(volumeMounts, mount)
+		volumes = append(volumes, volume)
+		env = append(env, licenseEnv)
 	}
 
-	return ds
+	return buildDeployment(WorkerName, w.component, w.cluster, w.labels, appsv1.RollingUpdateDeploymentStrategyType,
+		withArgs(args...),
+		withEnv(env...),
+		withVolumes(volumes, volumeMounts),
+		withProbes(probeutil.MakeLivenessProbeHTTP("", "/worker/health", 6369), probeutil.MakeReadinessProbeHTTP("", "/worker/health", 6369)),
+		withServiceAccount(serviceAccountName(w.component)),
+	)
 }
 
 func (w *worker) serviceForWorker() client.Object {
-	svc := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      WorkerName,
-			Namespace: w.component.Namespace,
-			Labels:    w.labels,
+	return buildService(WorkerName, w.component.Namespace, w.labels,
+		corev1.ServicePort{
+			Name: "grpc",
+			Port: 6535,
+			TargetPort: intstr.IntOrString{
+				IntVal: 6535,
+			},
 		},
-		Spec: corev1.ServiceSpec{
-			Ports: []corev1.ServicePort{
-				{
-					Name: "grpc",
-					Port: 6535,
-					TargetPort: intstr.IntOrString{
-						IntVal: 6535,
-					},
-				},
-				{
-					Name: "metric",
-					Port: 6369,
-					TargetPort: intstr.IntOrString{
-						IntVal: 6369,
-					},
-				},
+		corev1.ServicePort{
+			Name: "metric",
+			Port: 6369,
+			TargetPort: intstr.IntOrString{
+				IntVal: 6369,
 			},
-			Selector: w.labels,
 		},
-	}
-	return svc
+	)
 }
 
 func (w *worker) serviceMonitorForWorker() client.Object {
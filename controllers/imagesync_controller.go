@@ -0,0 +1,288 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/operatorconfig"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+)
+
+const (
+	// imageSyncJobName is the name label of the jobs that synchronize images.
+	imageSyncJobName = "rbd-image-sync"
+	// defImageSyncInterval is the default interval to synchronize the images again.
+	defImageSyncInterval = 24 * time.Hour
+	// imageSyncRetryDelay is the delay before retrying a failed synchronization.
+	imageSyncRetryDelay = 5 * time.Minute
+)
+
+// ImageSyncReconciler reconciles a ImageSync object
+type ImageSyncReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Settings are the settings of the operator, the defaults are used if nil.
+	Settings *operatorconfig.Settings
+}
+
+// imageSyncItem is an image of the ImageSync and the task to synchronize it.
+type imageSyncItem struct {
+	name string
+	task imageTask
+	err  error
+}
+
+// +kubebuilder:rbac:groups=rainbond.io,resources=imagesyncs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rainbond.io,resources=imagesyncs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=rainbond.io,resources=imagesyncs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile creates a job for each image that is due to be synchronized into the image hub,
+// and records the result of the jobs in the status of the ImageSync.
+func (r *ImageSyncReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("imagesync", request.NamespacedName)
+
+	imageSync := &rainbondv1alpha1.ImageSync{}
+	if err := r.Get(ctx, request.NamespacedName, imageSync); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	cluster := &rainbondv1alpha1.RainbondCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: imageSync.Namespace, Name: constants.RainbondClusterName}, cluster); err != nil {
+		log.Error(err, "get rainbondcluster.")
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	items := imageSyncItems(imageSync, cluster)
+
+	interval := defImageSyncInterval
+	if imageSync.Spec.Interval != nil && imageSync.Spec.Interval.Duration > 0 {
+		interval = imageSync.Spec.Interval.Duration
+	}
+
+	now := metav1.Now()
+	var running int
+	var requeueAfter time.Duration
+	var images []rainbondv1alpha1.ImageSyncImageStatus
+	for _, item := range items {
+		status := rainbondv1alpha1.ImageSyncImageStatus{
+			Name:   item.name,
+			Target: item.task.target,
+			Phase:  rainbondv1alpha1.ImageSyncPhasePending,
+		}
+		if old := imageSync.Status.GetImage(item.name); old != nil {
+			status = *old
+			status.Target = item.task.target
+		}
+		if item.err != nil {
+			status.Phase = rainbondv1alpha1.ImageSyncPhaseFailed
+			status.Message = item.err.Error()
+			images = append(images, status)
+			continue
+		}
+
+		jobName := item.task.jobName(imageSyncJobName)
+		job := &batchv1.Job{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: imageSync.Namespace, Name: jobName}, job)
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("get job %s: %v", jobName, err)
+		}
+
+		if err == nil {
+			switch {
+			case job.Status.Succeeded > 0:
+				log.Info("successfully synchronize image", "image", item.name)
+				status.Phase = rainbondv1alpha1.ImageSyncPhaseSynced
+				status.LastSyncTime = &now
+				status.Message = ""
+				r.deleteJob(ctx, log, job)
+			case isJobFailed(job):
+				status.Phase = rainbondv1alpha1.ImageSyncPhaseFailed
+				status.Message = fmt.Sprintf("job %s failed", job.Name)
+				r.deleteJob(ctx, log, job)
+			default:
+				status.Phase = rainbondv1alpha1.ImageSyncPhaseSyncing
+				running++
+			}
+			images = append(images, status)
+			continue
+		}
+
+		if due := imageSyncDue(status, interval, now.Time); due > 0 {
+			if requeueAfter == 0 || due < requeueAfter {
+				requeueAfter = due
+			}
+			images = append(images, status)
+			continue
+		}
+		if running >= maxRunningImageJobs {
+			status.Phase = rainbondv1alpha1.ImageSyncPhasePending
+			images = append(images, status)
+			continue
+		}
+
+		job = newImageJob(imageSyncJobName, imageSync.Namespace, imageJobImage(imageSync.Spec.JobImage, cluster), item.task)
+		if err := controllerutil.SetControllerReference(imageSync, job, r.Scheme); err != nil {
+			return reconcile.Result{}, fmt.Errorf("set controller reference: %v", err)
+		}
+		log.Info("create job for image", "job", job.Name, "image", item.name)
+		if err := createImageJob(ctx, r.Client, r.Scheme, job, item.task); err != nil {
+			return reconcile.Result{}, fmt.Errorf("create job for image %s: %v", item.name, err)
+		}
+		status.Phase = rainbondv1alpha1.ImageSyncPhaseSyncing
+		status.LastAttemptTime = &now
+		running++
+		images = append(images, status)
+	}
+
+	readStatus := imageSync.Status.DeepCopy()
+	imageSync.Status.Images = images
+	imageSync.Status.SyncedNumber = 0
+	for _, image := range images {
+		if image.Phase == rainbondv1alpha1.ImageSyncPhaseSynced {
+			imageSync.Status.SyncedNumber++
+		}
+	}
+	status := imageSync.Status.DeepCopy()
+	if err := k8sutil.UpdateStatusChanges(ctx, r.Client, imageSync, readStatus, status, func(latest client.Object) interface{} {
+		return &latest.(*rainbondv1alpha1.ImageSync).Status
+	}); err != nil {
+		return reconcile.Result{}, fmt.Errorf("update imagesync status: %v", err)
+	}
+
+	// the running jobs will trigger the reconciliation once they are finished.
+	if requeueAfter > 0 {
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImageSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(r.Settings.ControllerOptions()).
+		WithEventFilter(r.Settings.NamespacePredicate()).
+		For(&rainbondv1alpha1.ImageSync{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+func (r *ImageSyncReconciler) deleteJob(ctx context.Context, log logr.Logger, job *batchv1.Job) {
+	err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "delete image sync job", "job", job.Name)
+	}
+}
+
+// imageSyncItems returns the images of the ImageSync in order.
+func imageSyncItems(imageSync *rainbondv1alpha1.ImageSync, cluster *rainbondv1alpha1.RainbondCluster) []imageSyncItem {
+	repo := rbdutil.GetImageRepository(cluster)
+	var targetUser, targetPass string
+	if imageHub := cluster.Spec.ImageHub; imageHub != nil {
+		targetUser, targetPass = imageHub.Username, imageHub.Password
+	}
+
+	var items []imageSyncItem
+	for _, image := range imageSync.Spec.Images {
+		source, target, err := syncImageNames(image, imageSync.Spec.Mirror, repo)
+		if err != nil {
+			items = append(items, imageSyncItem{name: image, err: err})
+			continue
+		}
+		items = append(items, imageSyncItem{
+			name: image,
+			task: imageTask{
+				source:       source,
+				target:       target,
+				sourceSecret: imageSync.Spec.MirrorCredentialSecretName,
+				targetUser:   targetUser,
+				targetPass:   targetPass,
+			},
+		})
+	}
+	for _, bundle := range imageSync.Spec.Bundles {
+		items = append(items, imageSyncItem{
+			name: bundle.Path,
+			task: imageTask{
+				file:         bundle.Path,
+				nodeName:     bundle.NodeName,
+				targetDomain: repo,
+				targetUser:   targetUser,
+				targetPass:   targetPass,
+			},
+		})
+	}
+	return items
+}
+
+// syncImageNames returns the image to pull and the image to push for the upstream image.
+// If the mirror is not empty, the repository of the upstream image is replaced by the mirror.
+func syncImageNames(image, mirror, repo string) (string, string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid image %s: %v", image, err)
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return "", "", fmt.Errorf("invalid image %s: image without tag is not supported", image)
+	}
+	name := path.Base(reference.Path(named)) + ":" + tagged.Tag()
+
+	source := reference.FamiliarString(tagged)
+	if mirror != "" {
+		source = path.Join(mirror, name)
+	}
+	return source, path.Join(repo, name), nil
+}
+
+// imageSyncDue returns how long to wait before synchronizing the image again.
+func imageSyncDue(status rainbondv1alpha1.ImageSyncImageStatus, interval time.Duration, now time.Time) time.Duration {
+	switch status.Phase {
+	case rainbondv1alpha1.ImageSyncPhaseSynced:
+		if status.LastSyncTime != nil {
+			return status.LastSyncTime.Add(interval).Sub(now)
+		}
+	case rainbondv1alpha1.ImageSyncPhaseFailed:
+		if status.LastAttemptTime != nil {
+			return status.LastAttemptTime.Add(imageSyncRetryDelay).Sub(now)
+		}
+	}
+	return 0
+}
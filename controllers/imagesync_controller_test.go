@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncImageNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		image      string
+		mirror     string
+		wantSource string
+		wantTarget string
+		wantErr    bool
+	}{
+		{
+			name:       "docker hub",
+			image:      "goodrain/runner:v5.3.3",
+			wantSource: "goodrain/runner:v5.3.3",
+			wantTarget: "goodrain.me/runner:v5.3.3",
+		},
+		{
+			name:       "latest by default",
+			image:      "registry.cn-hangzhou.aliyuncs.com/goodrain/builder",
+			wantSource: "registry.cn-hangzhou.aliyuncs.com/goodrain/builder:latest",
+			wantTarget: "goodrain.me/builder:latest",
+		},
+		{
+			name:       "mirror",
+			image:      "goodrain/runner:v5.3.3",
+			mirror:     "registry.cn-hangzhou.aliyuncs.com/goodrain",
+			wantSource: "registry.cn-hangzhou.aliyuncs.com/goodrain/runner:v5.3.3",
+			wantTarget: "goodrain.me/runner:v5.3.3",
+		},
+		{
+			name:    "digest",
+			image:   "goodrain/runner@sha256:4d8c2ba3e0b2d1e4d7ac8f0f7a7e8d8b8e1c4c6a3b9f2d5e6a7b8c9d0e1f2a3b",
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			image:   "Goodrain/runner",
+			wantErr: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			source, target, err := syncImageNames(tc.image, tc.mirror, "goodrain.me")
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantSource, source)
+			assert.Equal(t, tc.wantTarget, target)
+		})
+	}
+}
+
+func TestImageSyncDue(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *metav1.Time {
+		v := metav1.NewTime(now.Add(d))
+		return &v
+	}
+	tests := []struct {
+		name   string
+		status rainbondv1alpha1.ImageSyncImageStatus
+		want   time.Duration
+	}{
+		{
+			name:   "pending",
+			status: rainbondv1alpha1.ImageSyncImageStatus{Phase: rainbondv1alpha1.ImageSyncPhasePending},
+		},
+		{
+			name:   "synced recently",
+			status: rainbondv1alpha1.ImageSyncImageStatus{Phase: rainbondv1alpha1.ImageSyncPhaseSynced, LastSyncTime: at(-time.Hour)},
+			want:   time.Hour,
+		},
+		{
+			name:   "synced long ago",
+			status: rainbondv1alpha1.ImageSyncImageStatus{Phase: rainbondv1alpha1.ImageSyncPhaseSynced, LastSyncTime: at(-3 * time.Hour)},
+			want:   -time.Hour,
+		},
+		{
+			name:   "failed",
+			status: rainbondv1alpha1.ImageSyncImageStatus{Phase: rainbondv1alpha1.ImageSyncPhaseFailed, LastAttemptTime: at(-time.Minute)},
+			want:   imageSyncRetryDelay - time.Minute,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, imageSyncDue(tc.status, 2*time.Hour, now))
+		})
+	}
+}
+
+func TestImageSyncMirrorCredential(t *testing.T) {
+	imageSync := &rainbondv1alpha1.ImageSync{
+		ObjectMeta: metav1.ObjectMeta{Name: "imagesync", Namespace: "rbd-system"},
+		Spec: rainbondv1alpha1.ImageSyncSpec{
+			Images:                     []string{"goodrain/runner:v5.3.3"},
+			Mirror:                     "registry.cn-hangzhou.aliyuncs.com/goodrain",
+			MirrorCredentialSecretName: "mirror-credential",
+		},
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			ImageHub: &rainbondv1alpha1.ImageHub{Domain: "goodrain.me", Username: "admin", Password: "pass"},
+		},
+	}
+	items := imageSyncItems(imageSync, cluster)
+	if !assert.Len(t, items, 1) {
+		return
+	}
+	task := items[0].task
+
+	// the password of the mirror is never copied into the secret of the job.
+	assert.Equal(t, map[string][]byte{
+		"TARGET_USER": []byte("admin"),
+		"TARGET_PASS": []byte("pass"),
+	}, imageJobCredentials(task))
+
+	job := newImageJob(imageSyncJobName, imageSync.Namespace, "", task)
+	env := make(map[string]*corev1.SecretKeySelector)
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		if e.ValueFrom != nil {
+			env[e.Name] = e.ValueFrom.SecretKeyRef
+		}
+	}
+	if ref := env["SOURCE_USER"]; assert.NotNil(t, ref) {
+		assert.Equal(t, "mirror-credential", ref.Name)
+		assert.Equal(t, "username", ref.Key)
+	}
+	if ref := env["SOURCE_PASS"]; assert.NotNil(t, ref) {
+		assert.Equal(t, "mirror-credential", ref.Name)
+		assert.Equal(t, "password", ref.Key)
+	}
+	if ref := env["TARGET_PASS"]; assert.NotNil(t, ref) {
+		assert.Equal(t, job.Name, ref.Name)
+	}
+}
+
+func TestImageSyncReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{rainbondv1alpha1.AddToScheme, corev1.AddToScheme, batchv1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	imageSync := &rainbondv1alpha1.ImageSync{
+		ObjectMeta: metav1.ObjectMeta{Name: "imagesync", Namespace: "rbd-system"},
+		Spec: rainbondv1alpha1.ImageSyncSpec{
+			Images: []string{"goodrain/runner:v5.3.3", "Goodrain/invalid"},
+		},
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+	}
+	r := &ImageSyncReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, imageSync, cluster),
+		Log:    logr.Discard(),
+		Scheme: scheme,
+	}
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "rbd-system", Name: "imagesync"}
+	reconcile := func() (ctrl.Result, rainbondv1alpha1.ImageSyncStatus) {
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := &rainbondv1alpha1.ImageSync{}
+		if err := r.Get(ctx, key, got); err != nil {
+			t.Fatal(err)
+		}
+		return result, got.Status
+	}
+
+	_, status := reconcile()
+	if assert.Len(t, status.Images, 2) {
+		assert.Equal(t, rainbondv1alpha1.ImageSyncPhaseSyncing, status.Images[0].Phase)
+		assert.Equal(t, "goodrain.me/runner:v5.3.3", status.Images[0].Target)
+		assert.Equal(t, rainbondv1alpha1.ImageSyncPhaseFailed, status.Images[1].Phase)
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs); err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, jobs.Items, 1) {
+		return
+	}
+	job := &jobs.Items[0]
+	job.Status.Succeeded = 1
+	if err := r.Status().Update(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	_, status = reconcile()
+	assert.Equal(t, int32(1), status.SyncedNumber)
+	assert.Equal(t, rainbondv1alpha1.ImageSyncPhaseSynced, status.Images[0].Phase)
+	assert.NotNil(t, status.Images[0].LastSyncTime)
+
+	// the finished job is deleted, and the image is synchronized again after the interval.
+	if err := r.List(ctx, jobs); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, jobs.Items)
+	result, _ := reconcile()
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= defImageSyncInterval)
+}
+
+func TestImageJobImage(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Spec: rainbondv1alpha1.RainbondClusterSpec{RainbondImageRepository: "registry.cn-hangzhou.aliyuncs.com/goodrain"},
+	}
+	assert.Equal(t, "registry.cn-hangzhou.aliyuncs.com/goodrain/docker:20.10", imageJobImage("", cluster))
+	assert.Equal(t, "docker:19.03", imageJobImage("docker:19.03", cluster))
+}
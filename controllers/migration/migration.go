@@ -0,0 +1,173 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Migration upgrades a stored object to the schema version.
+type Migration struct {
+	// Version is the schema version after the migration, starting from 1.
+	Version int
+	// Description is a short summary of the migration, used in the logs.
+	Description string
+	// Migrate changes the object in place.
+	Migrate func(obj *unstructured.Unstructured) error
+}
+
+type kindMigrations struct {
+	gvk        schema.GroupVersionKind
+	migrations []Migration
+}
+
+// migrations of the custom resources, in the ascending order of the versions. There is no incompatible change yet.
+// Add the kind with a migration of the next version when its schema is changed incompatibly, such as:
+//
+//	{
+//		gvk: rainbondv1alpha1.GroupVersion.WithKind("RbdComponent"),
+//		migrations: []Migration{
+//			{Version: 1, Description: "fill spec.foo", Migrate: fillFoo},
+//		},
+//	},
+var migrations []kindMigrations
+
+// Migrator upgrades the stored custom resources to the latest schema versions, so that they won't become invalid
+// or lose data after the operator is upgraded. The schema version is recorded in the annotation rainbond.io/schema-version.
+type Migrator struct {
+	client    client.Client
+	namespace string
+	log       logr.Logger
+}
+
+// NewMigrator creates a new migrator for the custom resources in the given namespace.
+// The client should read from the apiserver directly, since the migrator runs before the manager is started.
+func NewMigrator(client client.Client, namespace string, log logr.Logger) *Migrator {
+	return &Migrator{
+		client:    client,
+		namespace: namespace,
+		log:       log,
+	}
+}
+
+// Run migrates all the custom resources with migrations. The objects failed to be migrated are kept as they are,
+// and reported by the condition SchemaMigration of the rainbondclusters, rather than stopping the operator.
+func (m *Migrator) Run(ctx context.Context) error {
+	var failures []string
+	for _, km := range migrations {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(km.gvk.GroupVersion().WithKind(km.gvk.Kind + "List"))
+		if err := m.client.List(ctx, list, client.InNamespace(m.namespace)); err != nil {
+			failures = append(failures, fmt.Sprintf("list %s: %v", km.gvk.Kind, err))
+			continue
+		}
+		for i := range list.Items {
+			if err := m.migrate(ctx, &list.Items[i], km.migrations); err != nil {
+				failures = append(failures, fmt.Sprintf("migrate %s %s: %v", km.gvk.Kind, list.Items[i].GetName(), err))
+			}
+		}
+	}
+	if err := m.recordCondition(ctx, failures); err != nil {
+		failures = append(failures, fmt.Sprintf("record condition: %v", err))
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// recordCondition records the result of the migration in the condition SchemaMigration of the rainbondclusters.
+// The condition is only recorded once a migration fails, and updated by the following migrations.
+func (m *Migrator) recordCondition(ctx context.Context, failures []string) error {
+	clusters := &rainbondv1alpha1.RainbondClusterList{}
+	if err := m.client.List(ctx, clusters, client.InNamespace(m.namespace)); err != nil {
+		return fmt.Errorf("list rainbondclusters: %v", err)
+	}
+	condition := rainbondv1alpha1.RainbondClusterCondition{
+		Type:   rainbondv1alpha1.RainbondClusterConditionTypeSchemaMigration,
+		Status: corev1.ConditionTrue,
+		Reason: rainbondv1alpha1.ReasonSchemaMigrated,
+	}
+	if len(failures) > 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = rainbondv1alpha1.ReasonSchemaMigrationFailed
+		condition.Message = strings.Join(failures, "; ")
+	}
+	for i := range clusters.Items {
+		key := types.NamespacedName{Namespace: clusters.Items[i].Namespace, Name: clusters.Items[i].Name}
+		err := k8sutil.UpdateStatus(ctx, m.client, clusters.Items[i].DeepCopy(), func(latest client.Object) {
+			cluster := latest.(*rainbondv1alpha1.RainbondCluster)
+			if idx, _ := cluster.Status.GetCondition(condition.Type); idx == -1 && len(failures) == 0 {
+				return
+			}
+			c := condition
+			cluster.Status.UpdateCondition(&c)
+		})
+		if client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("update status of rainbondcluster %s: %v", key.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) migrate(ctx context.Context, obj *unstructured.Unstructured, migrations []Migration) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := m.client.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, obj); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		from := schemaVersion(obj)
+		changed, err := migrateObject(obj, migrations)
+		if err != nil || !changed {
+			return err
+		}
+		m.log.Info("migrate the schema", "kind", obj.GetKind(), "name", obj.GetName(),
+			"from", from, "to", schemaVersion(obj))
+		return m.client.Update(ctx, obj)
+	})
+}
+
+// migrateObject applies the migrations newer than the schema version of the object, and records the latest version.
+// It returns false if the object is up to date, or it is written by a newer operator.
+func migrateObject(obj *unstructured.Unstructured, migrations []Migration) (bool, error) {
+	version := schemaVersion(obj)
+	var changed bool
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+		if err := migration.Migrate(obj); err != nil {
+			return false, fmt.Errorf("%s: %v", migration.Description, err)
+		}
+		version = migration.Version
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[constants.SchemaVersionAnnotation] = strconv.Itoa(version)
+	obj.SetAnnotations(annotations)
+	return true, nil
+}
+
+// schemaVersion returns the schema version of the object, 0 if not recorded.
+func schemaVersion(obj *unstructured.Unstructured) int {
+	version, _ := strconv.Atoi(obj.GetAnnotations()[constants.SchemaVersionAnnotation])
+	return version
+}
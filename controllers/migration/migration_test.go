@@ -0,0 +1,102 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMigrateObject(t *testing.T) {
+	setFoo := func(obj *unstructured.Unstructured) error {
+		return unstructured.SetNestedField(obj.Object, "bar", "spec", "foo")
+	}
+	migrations := []Migration{
+		{Version: 1, Description: "fill foo", Migrate: setFoo},
+		{Version: 2, Description: "remove old", Migrate: func(obj *unstructured.Unstructured) error {
+			unstructured.RemoveNestedField(obj.Object, "spec", "old")
+			return nil
+		}},
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"old": "value"},
+	}}
+
+	changed, err := migrateObject(obj, migrations)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, obj.Object["spec"])
+	assert.Equal(t, "2", obj.GetAnnotations()[constants.SchemaVersionAnnotation])
+
+	// up to date.
+	changed, err = migrateObject(obj, migrations)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	// only the newer migrations are applied.
+	obj = &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"old": "value"},
+	}}
+	obj.SetAnnotations(map[string]string{constants.SchemaVersionAnnotation: "2"})
+	changed, err = migrateObject(obj, migrations)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, map[string]interface{}{"old": "value"}, obj.Object["spec"])
+
+	// the failed object is not changed.
+	obj = &unstructured.Unstructured{Object: map[string]interface{}{}}
+	changed, err = migrateObject(obj, []Migration{{Version: 1, Description: "fail", Migrate: func(*unstructured.Unstructured) error {
+		return errors.New("invalid")
+	}}})
+	assert.NotNil(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, obj.GetAnnotations())
+}
+
+func TestRecordCondition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system"},
+	}
+	cli := fake.NewFakeClientWithScheme(scheme, cluster)
+	m := NewMigrator(cli, "rbd-system", logr.Discard())
+	key := types.NamespacedName{Namespace: "rbd-system", Name: "rainbondcluster"}
+	condition := func() *rainbondv1alpha1.RainbondClusterCondition {
+		got := &rainbondv1alpha1.RainbondCluster{}
+		if err := cli.Get(context.Background(), key, got); err != nil {
+			t.Fatal(err)
+		}
+		_, c := got.Status.GetCondition(rainbondv1alpha1.RainbondClusterConditionTypeSchemaMigration)
+		return c
+	}
+
+	// not recorded until a migration fails.
+	assert.Nil(t, m.recordCondition(context.Background(), nil))
+	assert.Nil(t, condition())
+
+	assert.Nil(t, m.recordCondition(context.Background(), []string{"migrate RbdComponent rbd-api: invalid"}))
+	if c := condition(); assert.NotNil(t, c) {
+		assert.Equal(t, corev1.ConditionFalse, c.Status)
+		assert.Equal(t, rainbondv1alpha1.ReasonSchemaMigrationFailed, c.Reason)
+		assert.Equal(t, "migrate RbdComponent rbd-api: invalid", c.Message)
+	}
+
+	assert.Nil(t, m.recordCondition(context.Background(), nil))
+	if c := condition(); assert.NotNil(t, c) {
+		assert.Equal(t, corev1.ConditionTrue, c.Status)
+		assert.Equal(t, rainbondv1alpha1.ReasonSchemaMigrated, c.Reason)
+	}
+}
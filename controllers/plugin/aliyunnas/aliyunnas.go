@@ -86,9 +86,10 @@ func (p *aliyunnasPlugin) csiDriver() *storagev1beta1.CSIDriver {
 	return &storagev1beta1.CSIDriver{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: provisioner,
-			Labels: rbdutil.LabelsForRainbond(map[string]string{
+			// the csi driver is unique in the cluster, it can only be run by one of the rainbonds.
+			Labels: rbdutil.LabelsForInstance(rbdutil.LabelsForRainbond(map[string]string{
 				"name": provisioner,
-			}),
+			}), p.volume.Namespace),
 		},
 		Spec: storagev1beta1.CSIDriverSpec{
 			AttachRequired: commonutil.Bool(false),
@@ -115,7 +116,7 @@ func (p *aliyunnasPlugin) daemonset() *appsv1.DaemonSet {
 				},
 				Spec: corev1.PodSpec{
 					TerminationGracePeriodSeconds: commonutil.Int64(0),
-					ServiceAccountName:            "rainbond-operator",
+					ServiceAccountName:            rbdutil.ServiceAccountName(),
 					HostNetwork:                   true,
 					HostPID:                       true,
 					Tolerations: []corev1.Toleration{
@@ -250,6 +251,7 @@ func (p *aliyunnasPlugin) daemonset() *appsv1.DaemonSet {
 		},
 	}
 
+	k8sutil.SetLinuxNodeSelector(&ds.Spec.Template.Spec)
 	return ds
 }
 
@@ -319,7 +321,7 @@ func (p *aliyunnasPlugin) statefulset() client.Object {
 							},
 						},
 					},
-					ServiceAccountName: "rainbond-operator",
+					ServiceAccountName: rbdutil.ServiceAccountName(),
 					HostNetwork:        true,
 					Containers: []corev1.Container{
 						{
@@ -437,5 +439,6 @@ func (p *aliyunnasPlugin) statefulset() client.Object {
 		},
 	}
 
+	k8sutil.SetLinuxNodeSelector(&sts.Spec.Template.Spec)
 	return sts
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/goodrain/rainbond-operator/controllers/plugin"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/probeutil"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -26,6 +27,13 @@ var log = logf.Log.WithName("nfs_plugin")
 
 const (
 	provisioner = "rainbond.io/nfs"
+
+	defaultHostPath = "/opt/rainbond/data/nfs"
+	// defaultBackingStorageRequest is the size of the backing disk provisioned by the storage class, in GiB.
+	defaultBackingStorageRequest = 100
+	// failoverTolerationSeconds is how long the nfs server with the backing disk of a storage class stays on
+	// the failed node before it is evicted and rescheduled.
+	failoverTolerationSeconds = 30
 )
 
 // CSIPlugins is the primary entrypoint for csi plugins.
@@ -75,16 +83,50 @@ func (p *nfsPlugin) IsPluginReady() bool {
 	return sts.Status.ReadyReplicas == sts.Status.Replicas
 }
 
+// GetProvisioner returns the provisioner of the nfs plugin, which is unique among the rainbonds in the cluster,
+// or the provisioners of the rainbonds would provision the volumes of each other.
 func (p *nfsPlugin) GetProvisioner() string {
-	return provisioner
+	return rbdutil.InstanceName(provisioner, p.volume.Namespace)
 }
 
 func (p *nfsPlugin) GetClusterScopedResources() []client.Object {
+	if p.highlyAvailable() {
+		// the backing disk is provisioned by the storage class.
+		return nil
+	}
 	return []client.Object{
 		p.pv(),
 	}
 }
 
+// highlyAvailable returns true if the backing disk is provisioned by a storage class rather than the host path,
+// so that the nfs server fails over to another node with it.
+func (p *nfsPlugin) highlyAvailable() bool {
+	return p.volume.Spec.CSIPlugin.NFS.BackingStorageClassName != ""
+}
+
+// tolerations returns the tolerations of the nfs server. The one on a host path tolerates everything, for it
+// can't run elsewhere, while the highly available one is evicted from the failed node soon to fail over.
+func (p *nfsPlugin) tolerations() []corev1.Toleration {
+	if !p.highlyAvailable() {
+		return []corev1.Toleration{
+			{
+				Operator: corev1.TolerationOpExists,
+			},
+		}
+	}
+	var tolerations []corev1.Toleration
+	for _, key := range []string{corev1.TaintNodeNotReady, corev1.TaintNodeUnreachable} {
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:               key,
+			Operator:          corev1.TolerationOpExists,
+			Effect:            corev1.TaintEffectNoExecute,
+			TolerationSeconds: commonutil.Int64(failoverTolerationSeconds),
+		})
+	}
+	return tolerations
+}
+
 func (p *nfsPlugin) GetSubResources() []client.Object {
 	return []client.Object{
 		p.service(),
@@ -95,6 +137,18 @@ func (p *nfsPlugin) GetSubResources() []client.Object {
 func (p *nfsPlugin) statefulset() client.Object {
 	labels := p.labels
 	pvc := p.pvc()
+	args := []string{
+		"-provisioner=" + p.GetProvisioner(),
+	}
+	if export := p.volume.Spec.CSIPlugin.NFS.Export; export != nil {
+		if export.RootSquash != nil {
+			args = append(args, fmt.Sprintf("-root-squash=%t", *export.RootSquash))
+		}
+		if export.GracePeriodSeconds != nil {
+			args = append(args, fmt.Sprintf("-grace-period=%d", *export.GracePeriodSeconds))
+		}
+	}
+	image := path.Join(p.volume.Spec.ImageRepository, "nfs-provisioner")
 	sts := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      p.name,
@@ -113,16 +167,12 @@ func (p *nfsPlugin) statefulset() client.Object {
 					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
-					ServiceAccountName: "rainbond-operator", // TODO: do not hard code, get sa from configuration.
-					Tolerations: []corev1.Toleration{
-						{
-							Operator: corev1.TolerationOpExists,
-						},
-					},
+					ServiceAccountName: rbdutil.ServiceAccountName(),
+					Tolerations:        p.tolerations(),
 					Containers: []corev1.Container{
 						{
 							Name:            p.name,
-							Image:           path.Join(p.volume.Spec.ImageRepository, "nfs-provisioner"),
+							Image:           image,
 							ImagePullPolicy: corev1.PullIfNotPresent,
 							Ports: []corev1.ContainerPort{
 								{
@@ -203,9 +253,7 @@ func (p *nfsPlugin) statefulset() client.Object {
 									Value: p.name,
 								},
 							},
-							Args: []string{
-								"-provisioner=" + provisioner,
-							},
+							Args: args,
 							SecurityContext: &corev1.SecurityContext{
 								Privileged: commonutil.Bool(true),
 							},
@@ -215,6 +263,8 @@ func (p *nfsPlugin) statefulset() client.Object {
 									MountPath: "/export",
 								},
 							},
+							LivenessProbe:  probeutil.MakeLivenessProbeTCP("", 2049),
+							ReadinessProbe: probeutil.MakeReadinessProbeTCP("", 2049),
 						},
 					},
 				},
@@ -315,7 +365,11 @@ func (p *nfsPlugin) service() *corev1.Service {
 	return svc
 }
 
-func (p *nfsPlugin) pv() *corev1.PersistentVolume {
+// backingNode returns the node of the backing disk, which is the given one or the one with the largest ephemeral storage.
+func (p *nfsPlugin) backingNode() string {
+	if nodeName := p.volume.Spec.CSIPlugin.NFS.NodeName; nodeName != "" {
+		return nodeName
+	}
 	nodeList := &corev1.NodeList{}
 	if err := p.cli.List(p.ctx, nodeList); err != nil {
 		log.V(3).Info(fmt.Sprintf("list nodes: %v", err))
@@ -332,16 +386,25 @@ func (p *nfsPlugin) pv() *corev1.PersistentVolume {
 			largeStorageNode = &node
 		}
 	}
+	if largeStorageNode == nil {
+		return ""
+	}
+	return largeStorageNode.Name
+}
+
+// pv returns the persistent volume of the backing disk, whose node affinity pins the nfs server to the node.
+func (p *nfsPlugin) pv() *corev1.PersistentVolume {
+	nodeName := p.backingNode()
 
 	pv := &corev1.PersistentVolume{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   p.name,
-			Labels: p.labels,
+			Name:   p.pvName(),
+			Labels: rbdutil.LabelsForInstance(p.labels, p.volume.Namespace),
 		},
 	}
 
 	var affnity *corev1.VolumeNodeAffinity
-	if largeStorageNode != nil {
+	if nodeName != "" {
 		affnity = &corev1.VolumeNodeAffinity{
 			Required: &corev1.NodeSelector{
 				NodeSelectorTerms: []corev1.NodeSelectorTerm{
@@ -350,7 +413,7 @@ func (p *nfsPlugin) pv() *corev1.PersistentVolume {
 							{
 								Key:      "kubernetes.io/hostname",
 								Operator: corev1.NodeSelectorOpIn,
-								Values:   []string{largeStorageNode.Name},
+								Values:   []string{nodeName},
 							},
 						},
 					},
@@ -373,8 +436,12 @@ func (p *nfsPlugin) pv() *corev1.PersistentVolume {
 		spec.NodeAffinity = affnity
 	}
 
+	hostPathDir := p.volume.Spec.CSIPlugin.NFS.HostPath
+	if hostPathDir == "" {
+		hostPathDir = rbdutil.InstanceName(defaultHostPath, p.volume.Namespace)
+	}
 	hostPath := &corev1.HostPathVolumeSource{
-		Path: "/opt/rainbond/data/nfs",
+		Path: hostPathDir,
 		Type: k8sutil.HostPath(corev1.HostPathDirectoryOrCreate),
 	}
 	spec.HostPath = hostPath
@@ -385,6 +452,9 @@ func (p *nfsPlugin) pv() *corev1.PersistentVolume {
 }
 
 func (p *nfsPlugin) pvc() *corev1.PersistentVolumeClaim {
+	if p.highlyAvailable() {
+		return p.backingPVC()
+	}
 	size := resource.NewQuantity(1*1024*1024*1024, resource.BinarySI)
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
@@ -400,9 +470,39 @@ func (p *nfsPlugin) pvc() *corev1.PersistentVolumeClaim {
 					corev1.ResourceStorage: *size,
 				},
 			},
-			VolumeName:       p.name,
+			VolumeName:       p.pvName(),
 			StorageClassName: commonutil.String("manual"),
 		},
 	}
 	return pvc
 }
+
+// backingPVC returns the claim of the backing disk provisioned by the storage class.
+func (p *nfsPlugin) backingPVC() *corev1.PersistentVolumeClaim {
+	size := int64(defaultBackingStorageRequest)
+	if p.volume.Spec.StorageRequest != nil {
+		size = int64(*p.volume.Spec.StorageRequest)
+	}
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "data",
+			Labels: p.labels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: map[corev1.ResourceName]resource.Quantity{
+					corev1.ResourceStorage: *resource.NewQuantity(size*1024*1024*1024, resource.BinarySI),
+				},
+			},
+			StorageClassName: commonutil.String(p.volume.Spec.CSIPlugin.NFS.BackingStorageClassName),
+		},
+	}
+}
+
+// pvName returns the name of the persistent volume holding the data of the nfs server.
+func (p *nfsPlugin) pvName() string {
+	return rbdutil.InstanceName(p.name, p.volume.Namespace)
+}
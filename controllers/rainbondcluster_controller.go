@@ -3,21 +3,30 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/go-logr/logr"
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	clustermgr "github.com/goodrain/rainbond-operator/controllers/cluster-mgr"
 	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/operatorconfig"
 	"github.com/goodrain/rainbond-operator/util/uuidutil"
 	"github.com/juju/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // RainbondClusterReconciler reconciles a RainbondCluster object
@@ -26,6 +35,10 @@ type RainbondClusterReconciler struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Settings are the settings of the operator, the defaults are used if nil.
+	Settings *operatorconfig.Settings
+	// APIReader reads the objects out of the watched namespaces, such as the rainbondclusters of the other regions.
+	APIReader client.Reader
 }
 
 // +kubebuilder:rbac:groups=rainbond.io,resources=rainbondclusters,verbs=get;list;watch;create;update;patch;delete
@@ -59,8 +72,11 @@ func (r *RainbondClusterReconciler) Reconcile(ctx context.Context, request ctrl.
 	}
 
 	mgr := clustermgr.NewClusterMgr(ctx, r.Client, reqLogger, rainbondcluster, r.Scheme)
+	mgr.SetAPIReader(r.APIReader)
 
-	// generate status for rainbond cluster
+	// generate status for rainbond cluster, the conditions of the old one are updated in place.
+	old := rainbondcluster.Status.DeepCopy()
+	installed := rainbondcluster.Installed()
 	reqLogger.V(6).Info("start generate status")
 	status, err := mgr.GenerateRainbondClusterStatus()
 	if err != nil {
@@ -68,19 +84,28 @@ func (r *RainbondClusterReconciler) Reconcile(ctx context.Context, request ctrl.
 		return reconcile.Result{RequeueAfter: time.Second * 2}, err
 	}
 
-	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		rc := &rainbondv1alpha1.RainbondCluster{}
-		if err := r.Get(ctx, request.NamespacedName, rc); err != nil {
-			return err
-		}
-		rc.Status = *status
-		return r.Status().Update(ctx, rc)
+	// only the changes of the generated status are written, the suspension and the conditions written by the others,
+	// such as the schema migration, are kept as the latest.
+	if err := k8sutil.UpdateStatusChanges(ctx, r.Client, rainbondcluster, old, status, func(latest client.Object) interface{} {
+		return &latest.(*rainbondv1alpha1.RainbondCluster).Status
 	}); err != nil {
 		reqLogger.Error(err, "update rainbondcluster status")
 		return reconcile.Result{RequeueAfter: time.Second * 2}, err
 	}
 	reqLogger.V(6).Info("update status success")
 
+	if !installed && rainbondcluster.Installed() {
+		reqLogger.Info("the region is installed, stop the prechecks of the environment and the bootstrap")
+	}
+	if err := mgr.Notify(old, status); err != nil {
+		// notifications must not affect the installation
+		reqLogger.Error(err, "notify")
+	}
+	if err := mgr.ReportTelemetry(); err != nil {
+		// telemetry must not affect the installation
+		reqLogger.V(4).Info(fmt.Sprintf("report telemetry: %v", err))
+	}
+
 	// setup imageHub if empty
 	if rainbondcluster.Spec.ImageHub == nil {
 		reqLogger.V(6).Info("create new image hub info")
@@ -106,6 +131,61 @@ func (r *RainbondClusterReconciler) Reconcile(ctx context.Context, request ctrl.
 		return reconcile.Result{Requeue: true}, err
 	}
 
+	// resolve the nodes for rbd-chaos from the chaos node selector.
+	if rainbondcluster.Spec.ChaosNodeSelector != nil {
+		nodes, err := mgr.LabelChaosNodes()
+		if err != nil {
+			reqLogger.Error(err, "label nodes for rbd-chaos")
+			return reconcile.Result{RequeueAfter: time.Second * 2}, nil
+		}
+		if !reflect.DeepEqual(nodes, rainbondcluster.Spec.NodesForChaos) {
+			if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				rc := &rainbondv1alpha1.RainbondCluster{}
+				if err := r.Get(ctx, request.NamespacedName, rc); err != nil {
+					return err
+				}
+				rc.Spec.NodesForChaos = nodes
+				rainbondcluster = rc
+				return r.Update(ctx, rc)
+			}); err != nil {
+				reqLogger.Error(err, "update nodes for rbd-chaos")
+				return reconcile.Result{RequeueAfter: time.Second * 2}, err
+			}
+			reqLogger.Info("update nodes for rbd-chaos", "nodes", len(nodes))
+		}
+	}
+
+	// label the nodes assigned to the roles, and resolve the nodes of the components from them.
+	if rainbondcluster.Spec.NodeRoles != nil {
+		gatewayNodes, buildNodes, err := mgr.LabelRoleNodes()
+		if err != nil {
+			reqLogger.Error(err, "label nodes for the roles")
+			return reconcile.Result{RequeueAfter: time.Second * 2}, nil
+		}
+		gatewayChanged := gatewayNodes != nil && !reflect.DeepEqual(gatewayNodes, rainbondcluster.Spec.NodesForGateway)
+		buildChanged := buildNodes != nil && !reflect.DeepEqual(buildNodes, rainbondcluster.Spec.NodesForChaos)
+		if gatewayChanged || buildChanged {
+			if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				rc := &rainbondv1alpha1.RainbondCluster{}
+				if err := r.Get(ctx, request.NamespacedName, rc); err != nil {
+					return err
+				}
+				if gatewayChanged {
+					rc.Spec.NodesForGateway = gatewayNodes
+				}
+				if buildChanged {
+					rc.Spec.NodesForChaos = buildNodes
+				}
+				rainbondcluster = rc
+				return r.Update(ctx, rc)
+			}); err != nil {
+				reqLogger.Error(err, "update nodes for the roles")
+				return reconcile.Result{RequeueAfter: time.Second * 2}, err
+			}
+			reqLogger.Info("update nodes for the roles", "gateway", len(gatewayNodes), "build", len(buildNodes))
+		}
+	}
+
 	// create secret for pulling images.
 	if rainbondcluster.Spec.ImageHub != nil && rainbondcluster.Spec.ImageHub.Username != "" && rainbondcluster.Spec.ImageHub.Password != "" {
 		err := mgr.CreateImagePullSecret()
@@ -114,9 +194,48 @@ func (r *RainbondClusterReconciler) Reconcile(ctx context.Context, request ctrl.
 		}
 	}
 
-	// create pvc for grdata if not exists
-	if err := mgr.CreateFoobarPVCIfNotExists(); err != nil {
-		return reconcile.Result{}, err
+	// create secret of the proxy for rbd-chaos.
+	if err := mgr.CreateProxySecret(); err != nil {
+		reqLogger.Error(err, "create secret for proxy")
+		return reconcile.Result{RequeueAfter: time.Second * 2}, nil
+	}
+
+	// resolve the image hub and the builder domains by coredns.
+	if err := mgr.ConfigureCoreDNS(); err != nil {
+		reqLogger.Error(err, "configure coredns")
+		return reconcile.Result{RequeueAfter: time.Second * 2}, nil
+	}
+
+	// deploy the NVIDIA device plugin for the GPU scheduling.
+	if err := mgr.ConfigureGPU(); err != nil {
+		reqLogger.Error(err, "configure gpu")
+		return reconcile.Result{RequeueAfter: time.Second * 2}, nil
+	}
+
+	// prepare the resources for the installation until the region is installed.
+	if !rainbondcluster.Installed() {
+		if err := mgr.Bootstrap(); err != nil {
+			reqLogger.Error(err, "bootstrap the installation")
+			return reconcile.Result{RequeueAfter: time.Second * 2}, nil
+		}
+	}
+
+	// wait for the data of the shared storage to be copied.
+	if maintenance, _ := rainbondcluster.InStorageMaintenance(); maintenance {
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	// stop or start the next tier of the components.
+	suspending, err := mgr.ReconcileSuspension()
+	if err != nil {
+		reqLogger.Error(err, "reconcile suspension")
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+	if suspending {
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+	if rainbondcluster.Spec.Suspend {
+		return reconcile.Result{}, nil
 	}
 
 	for _, con := range rainbondcluster.Status.Conditions {
@@ -125,16 +244,51 @@ func (r *RainbondClusterReconciler) Reconcile(ctx context.Context, request ctrl.
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// the next health check, no requeue if it is disabled.
+	return ctrl.Result{RequeueAfter: mgr.HealthCheckRequeueAfter()}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RainbondClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(r.Settings.ControllerOptions()).
+		WithEventFilter(r.Settings.NamespacePredicate()).
 		For(&rainbondv1alpha1.RainbondCluster{}).
+		// the running condition and the phase summarize the rbdcomponents.
+		Watches(&source.Kind{Type: &rainbondv1alpha1.RbdComponent{}}, handler.EnqueueRequestsFromMapFunc(clusterForComponent),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return true },
+				DeleteFunc:  func(event.DeleteEvent) bool { return true },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					// the component starts being deleted, or degrades or recovers.
+					if e.ObjectOld.GetDeletionTimestamp().IsZero() && !e.ObjectNew.GetDeletionTimestamp().IsZero() {
+						return true
+					}
+					return e.ObjectOld.(*rainbondv1alpha1.RbdComponent).Status.Phase != e.ObjectNew.(*rainbondv1alpha1.RbdComponent).Status.Phase
+				},
+			})).
+		// the region is running once the rainbondpackage is completed.
+		Watches(&source.Kind{Type: &rainbondv1alpha1.RainbondPackage{}}, handler.EnqueueRequestsFromMapFunc(clusterForComponent),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return true },
+				DeleteFunc:  func(event.DeleteEvent) bool { return true },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return e.ObjectOld.(*rainbondv1alpha1.RainbondPackage).Status.ComputePhase() != e.ObjectNew.(*rainbondv1alpha1.RainbondPackage).Status.ComputePhase()
+				},
+			})).
 		Complete(r)
 }
 
+// clusterForComponent returns the request of the rainbondcluster in the namespace of the rbdcomponent or the
+// rainbondpackage.
+func clusterForComponent(obj client.Object) []reconcile.Request {
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: constants.RainbondClusterName}},
+	}
+}
+
 func (r *RainbondClusterReconciler) getImageHub(cluster *rainbondv1alpha1.RainbondCluster) (*rainbondv1alpha1.ImageHub, error) {
 	return &rainbondv1alpha1.ImageHub{
 		Domain:   constants.DefImageRepository,
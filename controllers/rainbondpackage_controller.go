@@ -18,44 +18,56 @@ package controllers
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
+	"crypto/sha256"
 	"fmt"
-	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/docker/distribution/reference"
-	dtypes "github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
-	dclient "github.com/docker/docker/client"
-	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/go-logr/logr"
+	chandler "github.com/goodrain/rainbond-operator/controllers/handler"
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/downloadutil"
-	"github.com/goodrain/rainbond-operator/util/rbdutil"
-	"github.com/goodrain/rainbond-operator/util/retryutil"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/operatorconfig"
+	"github.com/goodrain/rainbond-operator/util/proxyutil"
 	"github.com/goodrain/rainbond-operator/util/tarutil"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 )
 
 var errorClusterConfigNotReady = fmt.Errorf("cluster config can not be ready")
 var errorClusterConfigNoLocalHub = fmt.Errorf("cluster spec not have local image hub info ")
+
+var errorImageJobsRunning = fmt.Errorf("image jobs are still running")
+
+// unpackExpansionFactor is the ratio of the space needed to extract the package to the package size.
+const unpackExpansionFactor = 2
+
+// imageDirRescanInterval is the interval to discover the new tarballs in the image directory.
+const imageDirRescanInterval = time.Minute
+
+// nodeNameEnv is the env of the node name of the operator, where the image files are located.
+const nodeNameEnv = "NODE_NAME"
+
 var pkgDst = "/opt/rainbond/pkg/files"
 
 // RainbondPackageReconciler reconciles a RainbondPackage object
@@ -64,6 +76,8 @@ type RainbondPackageReconciler struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Settings are the settings of the operator, the defaults are used if nil.
+	Settings *operatorconfig.Settings
 }
 
 // +kubebuilder:rbac:groups=rainbond.io,resources=rainbondpackages,verbs=get;list;watch;create;update;patch;delete
@@ -97,6 +111,7 @@ func (r *RainbondPackageReconciler) Reconcile(ctx context.Context, request ctrl.
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
+	readStatus := pkg.Status.DeepCopy()
 
 	cluster := &rainbondv1alpha1.RainbondCluster{}
 	if err := r.Get(ctx, types.NamespacedName{Namespace: pkg.Namespace, Name: constants.RainbondClusterName}, cluster); err != nil {
@@ -109,35 +124,47 @@ func (r *RainbondPackageReconciler) Reconcile(ctx context.Context, request ctrl.
 		return reconcile.Result{RequeueAfter: 3 * time.Second}, nil
 	}
 
+	if err := r.reconcileImagePreload(ctx, pkg, cluster); err != nil {
+		log.Error(err, "reconcile image preload")
+	}
+
 	// if instsall mode is full online, set package to ready directly
 	if cluster.Spec.InstallMode == rainbondv1alpha1.InstallationModeFullOnline {
+		if pkg.Status.ComputePhase() == rainbondv1alpha1.RainbondPackageCompleted {
+			return reconcile.Result{}, nil
+		}
 		log.Info("set package to ready directly", "install mode", cluster.Spec.InstallMode)
 		pkg.Status = initPackageStatus(rainbondv1alpha1.Completed)
-		if err := updateCRStatus(r.Client, pkg); err != nil {
+		if err := updateCRStatus(r.Client, pkg, readStatus); err != nil {
 			log.Error(err, "update package status")
 			return reconcile.Result{RequeueAfter: time.Second * 5}, nil
 		}
 		return reconcile.Result{}, nil
 	}
 
+	if pkg.Status.ComputePhase() == rainbondv1alpha1.RainbondPackageCompleted {
+		return r.reconcileCompleted(ctx, pkg, cluster, log)
+	}
+
 	updateStatus, re := checkStatusCanReturn(pkg)
 	if updateStatus {
-		if err := updateCRStatus(r.Client, pkg); err != nil {
+		if err := updateCRStatus(r.Client, pkg, readStatus); err != nil {
 			log.Error(err, "update package status failure ")
 			return reconcile.Result{RequeueAfter: time.Second * 5}, nil
 		}
-		return reconcile.Result{}, nil
+		// the updates of the status are ignored, requeue to handle the initialized status.
+		return reconcile.Result{Requeue: true}, nil
 	}
 	if re != nil {
 		return *re, nil
 	}
 
 	//need handle condition
-	p, err := newpkg(ctx, r.Client, pkg, cluster, log)
+	p, err := newpkg(ctx, r.Client, r.Scheme, pkg, cluster, log)
 	if err != nil {
 		if p != nil {
 			p.updateConditionStatus(rainbondv1alpha1.Init, rainbondv1alpha1.Failed)
-			p.updateConditionResion(rainbondv1alpha1.Init, err.Error(), "create package handle failure")
+			p.updateConditionResion(rainbondv1alpha1.Init, rainbondv1alpha1.ReasonInitFailed, "create package handle failure: "+err.Error())
 			p.updateCRStatus()
 		}
 		log.Error(err, "create package handle failure ")
@@ -148,6 +175,10 @@ func (r *RainbondPackageReconciler) Reconcile(ctx context.Context, request ctrl.
 	if err = p.handle(); err != nil {
 		if err == errorClusterConfigNoLocalHub {
 			log.V(4).Info("waiting local image hub ready")
+		} else if err == errorImageJobsRunning {
+			// the changes of the owned jobs trigger the reconciliation.
+			log.V(4).Info("waiting image jobs completed")
+			return reconcile.Result{}, nil
 		} else if err == errorClusterConfigNotReady {
 			log.Info("waiting cluster config ready")
 		} else {
@@ -155,17 +186,80 @@ func (r *RainbondPackageReconciler) Reconcile(ctx context.Context, request ctrl.
 		}
 		return reconcile.Result{RequeueAfter: 8 * time.Second}, nil
 	}
+	if p.pkg.Status.ComputePhase() != rainbondv1alpha1.RainbondPackageCompleted {
+		// continue with the next step.
+		return reconcile.Result{Requeue: true}, nil
+	}
 
 	return reconcile.Result{}, nil
 }
 
+// reconcileCompleted redoes the steps of the completed package whose inputs are changed since they completed.
+func (r *RainbondPackageReconciler) reconcileCompleted(ctx context.Context, pkg *rainbondv1alpha1.RainbondPackage,
+	cluster *rainbondv1alpha1.RainbondCluster, log logr.Logger) (ctrl.Result, error) {
+	p, err := newpkg(ctx, r.Client, r.Scheme, pkg, cluster, log)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := p.checkClusterConfig(); err != nil {
+		// the inputs can't be figured out, check them again once the rainbondcluster is changed.
+		log.V(6).Info("check cluster config", "msg", err.Error())
+		return reconcile.Result{}, nil
+	}
+
+	var adopted bool
+	for i := range p.pkg.Status.Conditions {
+		con := &p.pkg.Status.Conditions[i]
+		hash := p.inputHash(con.Type)
+		if con.InputHash == hash {
+			continue
+		}
+		if con.InputHash == "" {
+			// completed by an old operator which doesn't record the hashes.
+			con.InputHash = hash
+			adopted = true
+			continue
+		}
+		log.Info("the inputs of the step are changed, redo it", "step", con.Type)
+		p.resetConditions(con.Type)
+		if err := p.updateCRStatus(); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{Requeue: true}, nil
+	}
+	if adopted {
+		return reconcile.Result{}, p.updateCRStatus()
+	}
+	log.V(6).Info("rainbond package is up to date")
+	if p.imageDir != "" {
+		// there are no events when the tarballs are added.
+		return reconcile.Result{RequeueAfter: imageDirRescanInterval}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *RainbondPackageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&rainbondv1alpha1.RainbondPackage{}).
+		WithOptions(r.Settings.ControllerOptions()).
+		WithEventFilter(r.Settings.NamespacePredicate()).
+		// the controller updates the status itself, only the changes of the spec are watched.
+		For(&rainbondv1alpha1.RainbondPackage{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&batchv1.Job{}).
+		Owns(&appsv1.DaemonSet{}).
+		// the images to push are changed with the rainbondcluster.
+		Watches(&source.Kind{Type: &rainbondv1alpha1.RainbondCluster{}}, handler.EnqueueRequestsFromMapFunc(packageForCluster),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
 
+// packageForCluster returns the request of the rainbondpackage in the namespace of the rainbondcluster.
+func packageForCluster(obj client.Object) []reconcile.Request {
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: constants.RainbondPackageName}},
+	}
+}
+
 func initPackageStatus(status rainbondv1alpha1.PackageConditionStatus) rainbondv1alpha1.RainbondPackageStatus {
 	return rainbondv1alpha1.RainbondPackageStatus{
 		Conditions: []rainbondv1alpha1.PackageCondition{
@@ -212,8 +306,10 @@ func checkStatusCanReturn(pkg *rainbondv1alpha1.RainbondPackage) (updateStatus b
 	}
 	completedCount := 0
 	for _, cond := range pkg.Status.Conditions {
+		// running conditions are handled again, the images that have
+		// been pushed are recorded in the status, so it can be resumed.
 		if cond.Status == rainbondv1alpha1.Running {
-			return false, &reconcile.Result{}
+			return false, nil
 		}
 		//have failed conditions, retry
 		if cond.Status == rainbondv1alpha1.Failed {
@@ -233,12 +329,14 @@ func checkStatusCanReturn(pkg *rainbondv1alpha1.RainbondPackage) (updateStatus b
 type pkg struct {
 	ctx              context.Context
 	client           client.Client
-	dcli             *dclient.Client
+	scheme           *runtime.Scheme
 	pkg              *rainbondv1alpha1.RainbondPackage
 	cluster          *rainbondv1alpha1.RainbondCluster
 	log              logr.Logger
 	downloadPackage  bool
 	localPackagePath string
+	// imageDir is the directory containing the image tarballs, if the package path is a directory.
+	imageDir string
 	// Deprecated: no longer download installation package.
 	downloadPackageURL string
 	// Deprecated: no longer download installation package.
@@ -250,25 +348,23 @@ type pkg struct {
 	//need download images
 	images  map[string]string
 	version string
+	// readStatus is the status of the rainbondpackage when it was read, only the changes made since then are written.
+	readStatus *rainbondv1alpha1.RainbondPackageStatus
 }
 
-func newpkg(ctx context.Context, client client.Client, p *rainbondv1alpha1.RainbondPackage, cluster *rainbondv1alpha1.RainbondCluster, reqLogger logr.Logger) (*pkg, error) {
-	dcli, err := newDockerClient(ctx)
-	if err != nil {
-		reqLogger.Error(err, "failed to create docker client")
-		return nil, err
-	}
+func newpkg(ctx context.Context, client client.Client, scheme *runtime.Scheme, p *rainbondv1alpha1.RainbondPackage, cluster *rainbondv1alpha1.RainbondCluster, reqLogger logr.Logger) (*pkg, error) {
 	pkg := &pkg{
 		ctx:    ctx,
 		client: client,
+		scheme: scheme,
 		pkg:    p.DeepCopy(),
-		dcli:   dcli,
 		// Deprecated: no longer download installation package.
 		totalImageNum: 23,
 		images:        make(map[string]string, 23),
 		log:           reqLogger,
 		version:       cluster.Spec.InstallVersion,
 		cluster:       cluster,
+		readStatus:    p.Status.DeepCopy(),
 	}
 	return pkg, nil
 }
@@ -287,6 +383,9 @@ func (p *pkg) configByCluster(c *rainbondv1alpha1.RainbondCluster) error {
 		p.version = c.Spec.InstallVersion
 	}
 	p.localPackagePath = p.pkg.Spec.PkgPath
+	if info, err := os.Stat(p.localPackagePath); err == nil && info.IsDir() {
+		p.imageDir = p.localPackagePath
+	}
 	ciVersion := c.Spec.CIVersion
 	if ciVersion == "" {
 		ciVersion = "v5.3.3"
@@ -302,19 +401,21 @@ func (p *pkg) configByCluster(c *rainbondv1alpha1.RainbondCluster) error {
 }
 
 func (p *pkg) updateCRStatus() error {
-	return updateCRStatus(p.client, p.pkg)
+	if err := updateCRStatus(p.client, p.pkg, p.readStatus); err != nil {
+		return err
+	}
+	p.readStatus = p.pkg.Status.DeepCopy()
+	return nil
 }
 
-func updateCRStatus(client client.Client, pkg *rainbondv1alpha1.RainbondPackage) error {
+// updateCRStatus writes the changes of the status of the rainbondpackage since it was read with the status before.
+func updateCRStatus(cli client.Client, pkg *rainbondv1alpha1.RainbondPackage, before *rainbondv1alpha1.RainbondPackageStatus) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		latest := &rainbondv1alpha1.RainbondPackage{}
-		if err := client.Get(ctx, types.NamespacedName{Namespace: pkg.Namespace, Name: pkg.Name}, latest); err != nil {
-			return fmt.Errorf("getting latest rainbond package: %v", err)
-		}
-		pkg.ResourceVersion = latest.ResourceVersion
-		return client.Status().Update(ctx, pkg)
+	pkg.Status.Phase = pkg.Status.ComputePhase()
+	status := pkg.Status.DeepCopy()
+	if err := k8sutil.UpdateStatusChanges(ctx, cli, pkg, before, status, func(latest client.Object) interface{} {
+		return &latest.(*rainbondv1alpha1.RainbondPackage).Status
 	}); err != nil {
 		return fmt.Errorf("failed to update rainbondpackage status: %v", err)
 	}
@@ -357,14 +458,20 @@ func (p *pkg) updateConditionStatus(typ3 rainbondv1alpha1.PackageConditionType,
 	for i, condition := range p.pkg.Status.Conditions {
 		if condition.Type == typ3 {
 			if p.pkg.Status.Conditions[i].Status != status {
-				p.pkg.Status.Conditions[i].LastTransitionTime = metav1.Now()
+				p.pkg.Status.Conditions[i].LastTransitionTime = rainbondv1alpha1.ConditionTime(p.pkg.Status.Conditions[i].LastTransitionTime)
+				if status == rainbondv1alpha1.Running {
+					// the step is retried or run again, the timing of the previous run is discarded.
+					resetConditionTiming(&p.pkg.Status.Conditions[i])
+				}
 			}
-			p.pkg.Status.Conditions[i].LastHeartbeatTime = metav1.Now()
+			p.pkg.Status.Conditions[i].LastHeartbeatTime = rainbondv1alpha1.ConditionTime(p.pkg.Status.Conditions[i].LastHeartbeatTime)
 			p.pkg.Status.Conditions[i].Status = status
 			if status == rainbondv1alpha1.Completed {
 				p.pkg.Status.Conditions[i].Progress = 100
 				p.pkg.Status.Conditions[i].Reason = ""
 				p.pkg.Status.Conditions[i].Message = ""
+				p.pkg.Status.Conditions[i].InputHash = p.inputHash(typ3)
+				completeConditionTiming(&p.pkg.Status.Conditions[i])
 			}
 			break
 		}
@@ -373,7 +480,7 @@ func (p *pkg) updateConditionStatus(typ3 rainbondv1alpha1.PackageConditionType,
 func (p *pkg) updateConditionResion(typ3 rainbondv1alpha1.PackageConditionType, resion, message string) {
 	for i, condition := range p.pkg.Status.Conditions {
 		if condition.Type == typ3 {
-			p.pkg.Status.Conditions[i].LastHeartbeatTime = metav1.Now()
+			p.pkg.Status.Conditions[i].LastHeartbeatTime = rainbondv1alpha1.ConditionTime(p.pkg.Status.Conditions[i].LastHeartbeatTime)
 			p.pkg.Status.Conditions[i].Reason = resion
 			p.pkg.Status.Conditions[i].Message = message
 			break
@@ -386,7 +493,7 @@ func (p *pkg) updateConditionProgress(typ3 rainbondv1alpha1.PackageConditionType
 	}
 	for i, condition := range p.pkg.Status.Conditions {
 		if condition.Type == typ3 {
-			p.pkg.Status.Conditions[i].LastHeartbeatTime = metav1.Now()
+			p.pkg.Status.Conditions[i].LastHeartbeatTime = rainbondv1alpha1.ConditionTime(p.pkg.Status.Conditions[i].LastHeartbeatTime)
 			if p.pkg.Status.Conditions[i].Progress != int(progress) {
 				p.pkg.Status.Conditions[i].Progress = int(progress)
 				return true
@@ -395,6 +502,121 @@ func (p *pkg) updateConditionProgress(typ3 rainbondv1alpha1.PackageConditionType
 	}
 	return false
 }
+
+// resetConditions resets the step and the following ones to waiting, so that they will be redone.
+func (p *pkg) resetConditions(from rainbondv1alpha1.PackageConditionType) {
+	reset := false
+	for i, condition := range p.pkg.Status.Conditions {
+		reset = reset || condition.Type == from
+		if !reset {
+			continue
+		}
+		p.pkg.Status.Conditions[i] = rainbondv1alpha1.PackageCondition{
+			Type:               condition.Type,
+			Status:             rainbondv1alpha1.Waiting,
+			LastHeartbeatTime:  metav1.Now(),
+			LastTransitionTime: metav1.Now(),
+		}
+		if condition.Type == rainbondv1alpha1.PushImage {
+			p.pkg.Status.ImagesNumber = 0
+			p.pkg.Status.ImagesPushed = p.validPushedImages()
+		}
+	}
+}
+
+// validPushedImages returns the pushed images which are still wanted, so that only the new images will be pushed.
+// The image files are identified by their paths.
+func (p *pkg) validPushedImages() []rainbondv1alpha1.RainbondPackageImage {
+	pushed := []rainbondv1alpha1.RainbondPackageImage{}
+	tasks, err := p.imageTasks()
+	if err != nil {
+		p.log.V(4).Info("list images to keep the pushed ones", "msg", err.Error())
+		return pushed
+	}
+	keys := make(map[string]struct{}, len(tasks))
+	for _, task := range tasks {
+		keys[task.key()] = struct{}{}
+	}
+	for _, image := range p.pkg.Status.ImagesPushed {
+		if _, ok := keys[image.Name]; ok {
+			pushed = append(pushed, image)
+		}
+	}
+	return pushed
+}
+
+// inputHash returns the hash of the inputs of the step, or empty if the step has no inputs.
+// The files are fingerprinted by their sizes and modification times, they are too large to hash the contents.
+func (p *pkg) inputHash(typ3 rainbondv1alpha1.PackageConditionType) string {
+	var inputs []string
+	switch typ3 {
+	case rainbondv1alpha1.DownloadPackage, rainbondv1alpha1.UnpackPackage:
+		if !p.downloadPackage {
+			return ""
+		}
+		inputs = append(inputs, fileFingerprint(p.pkg.Spec.PkgPath))
+	case rainbondv1alpha1.PushImage:
+		tasks, err := p.imageTasks()
+		if err != nil {
+			p.log.V(4).Info("list images to hash the inputs", "msg", err.Error())
+			return ""
+		}
+		inputs = append(inputs, p.pushImageDomain)
+		for _, task := range tasks {
+			if task.file != "" {
+				inputs = append(inputs, fileFingerprint(task.file))
+				continue
+			}
+			inputs = append(inputs, task.source+"->"+task.target)
+		}
+	default:
+		return ""
+	}
+	h := sha256.New()
+	for _, input := range inputs {
+		_, _ = h.Write([]byte(input + "\n"))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func fileFingerprint(file string) string {
+	info, err := os.Stat(file)
+	if err != nil {
+		return file
+	}
+	return fmt.Sprintf("%s:%d:%d", file, info.Size(), info.ModTime().Unix())
+}
+
+// addConditionBytes adds the number of bytes processed by the step.
+func (p *pkg) addConditionBytes(typ3 rainbondv1alpha1.PackageConditionType, bytes int64) {
+	if con := p.findCondition(typ3); con != nil {
+		con.BytesProcessed += bytes
+	}
+}
+
+// resetConditionTiming starts the timing of the step from now.
+func resetConditionTiming(con *rainbondv1alpha1.PackageCondition) {
+	now := metav1.Now()
+	con.StartTime = &now
+	con.CompletionTime = nil
+	con.BytesProcessed = 0
+	con.Throughput = ""
+}
+
+// completeConditionTiming records the completion time and the throughput of the step.
+// The steps that never started running, such as the skipped steps, have no timing.
+func completeConditionTiming(con *rainbondv1alpha1.PackageCondition) {
+	if con.StartTime == nil || con.CompletionTime != nil {
+		return
+	}
+	now := metav1.Now()
+	con.CompletionTime = &now
+	seconds := now.Sub(con.StartTime.Time).Seconds()
+	if con.BytesProcessed > 0 && seconds > 0 {
+		con.Throughput = fmt.Sprintf("%.2fMB/s", float64(con.BytesProcessed)/1024/1024/seconds)
+	}
+}
+
 func (p *pkg) completeCondition(con *rainbondv1alpha1.PackageCondition) error {
 	if con == nil {
 		return nil
@@ -489,10 +711,15 @@ func (p *pkg) setInitStatus() error {
 //donwnloadPackage download package
 func (p *pkg) donwnloadPackage() error {
 	p.log.Info(fmt.Sprintf("start download package from %s", p.downloadPackageURL))
+	proxy, err := proxyutil.Get(p.ctx, p.client, p.cluster)
+	if err != nil {
+		return err
+	}
 	downloadListener := &downloadutil.DownloadWithProgress{
 		URL:       p.downloadPackageURL,
 		SavedPath: p.localPackagePath,
 		Wanted:    p.downloadPackageMD5,
+		Client:    proxy.Client(),
 	}
 	// first chack exist file md5
 	file, _ := os.Open(p.localPackagePath)
@@ -527,7 +754,7 @@ func (p *pkg) donwnloadPackage() error {
 	}()
 	if err := downloadListener.Download(); err != nil {
 		p.log.Error(err, "download rainbond package error, will retry")
-		p.updateConditionResion(rainbondv1alpha1.Init, err.Error(), "download rainbond package error, will retry")
+		p.updateConditionResion(rainbondv1alpha1.Init, rainbondv1alpha1.ReasonDownloadFailed, "download rainbond package error, will retry: "+err.Error())
 		p.updateCRStatus()
 		err = downloadListener.Download()
 		if err != nil {
@@ -552,7 +779,7 @@ func (p *pkg) handle() error {
 			return err
 		}
 		p.updateConditionStatus(rainbondv1alpha1.Init, rainbondv1alpha1.Waiting)
-		p.updateConditionResion(rainbondv1alpha1.Init, err.Error(), "get rainbond cluster config failure")
+		p.updateConditionResion(rainbondv1alpha1.Init, rainbondv1alpha1.ReasonClusterNotReady, "get rainbond cluster config failure: "+err.Error())
 		p.updateCRStatus()
 		return err
 	}
@@ -560,7 +787,7 @@ func (p *pkg) handle() error {
 	if err := p.setInitStatus(); err != nil {
 		p.log.Error(err, "set init status")
 		p.updateConditionStatus(rainbondv1alpha1.Init, rainbondv1alpha1.Failed)
-		p.updateConditionResion(rainbondv1alpha1.Init, err.Error(), "set init status failure")
+		p.updateConditionResion(rainbondv1alpha1.Init, rainbondv1alpha1.ReasonInitFailed, "set init status failure: "+err.Error())
 		p.updateCRStatus()
 		return err
 	}
@@ -569,46 +796,73 @@ func (p *pkg) handle() error {
 		if err := p.donwnloadPackage(); err != nil {
 			p.log.Error(err, "download package")
 			p.updateConditionStatus(rainbondv1alpha1.DownloadPackage, rainbondv1alpha1.Failed)
-			p.updateConditionResion(rainbondv1alpha1.DownloadPackage, err.Error(), "download package failure")
+			p.updateConditionResion(rainbondv1alpha1.DownloadPackage, rainbondv1alpha1.ReasonDownloadFailed, "download package failure: "+err.Error())
 			p.updateCRStatus()
 			return fmt.Errorf("failed to download package %s", err.Error())
 		}
+		if info, err := os.Stat(p.localPackagePath); err == nil {
+			p.addConditionBytes(rainbondv1alpha1.DownloadPackage, info.Size())
+		}
 		p.log.Info("handle downlaod package success")
 		p.updateConditionStatus(rainbondv1alpha1.DownloadPackage, rainbondv1alpha1.Completed)
 		return p.updateCRStatus()
 	}
 
 	if p.canUnpack() {
+		if err := p.checkDiskSpace(); err != nil {
+			p.updateConditionStatus(rainbondv1alpha1.UnpackPackage, rainbondv1alpha1.Failed)
+			p.updateConditionResion(rainbondv1alpha1.UnpackPackage, rainbondv1alpha1.ReasonInsufficientDiskSpace, err.Error())
+			p.updateCRStatus()
+			return fmt.Errorf("check disk space: %v", err)
+		}
 		//unstar the installation package
 		if err := p.untartar(); err != nil {
 			p.updateConditionStatus(rainbondv1alpha1.UnpackPackage, rainbondv1alpha1.Failed)
-			p.updateConditionResion(rainbondv1alpha1.UnpackPackage, err.Error(), "unpack package failure")
+			p.updateConditionResion(rainbondv1alpha1.UnpackPackage, rainbondv1alpha1.ReasonUnpackFailed, "unpack package failure: "+err.Error())
 			p.updateCRStatus()
 			return fmt.Errorf("failed to untar %s: %v", p.pkg.Spec.PkgPath, err)
 		}
+		if info, err := os.Stat(p.pkg.Spec.PkgPath); err == nil {
+			p.addConditionBytes(rainbondv1alpha1.UnpackPackage, info.Size())
+		}
 		p.log.Info("handle package unpack success")
 		p.updateConditionStatus(rainbondv1alpha1.UnpackPackage, rainbondv1alpha1.Completed)
 		return p.updateCRStatus()
 	}
 
 	if p.canPushImage() {
-		// Deprecated: No longer download the installation package
-		if p.downloadPackage {
-			p.log.Info("start load and push images")
-			if err := p.imagesLoadAndPush(); err != nil {
-				p.updateConditionStatus(rainbondv1alpha1.PushImage, rainbondv1alpha1.Failed)
-				p.updateConditionResion(rainbondv1alpha1.PushImage, err.Error(), "load and push images failure")
-				p.updateCRStatus()
-				return fmt.Errorf("failed to load and push images: %v", err)
+		tasks, err := p.imageTasks()
+		if err != nil {
+			p.updateConditionStatus(rainbondv1alpha1.PushImage, rainbondv1alpha1.Failed)
+			p.updateConditionResion(rainbondv1alpha1.PushImage, rainbondv1alpha1.ReasonListImagesFailed, "list images failure: "+err.Error())
+			p.updateCRStatus()
+			return fmt.Errorf("failed to list images: %v", err)
+		}
+		p.updateConditionStatus(rainbondv1alpha1.PushImage, rainbondv1alpha1.Running)
+		if err := p.pushImagesByJob(tasks); err != nil {
+			if err == errorImageJobsRunning {
+				return err
 			}
-		} else {
-			p.log.Info("start pull and push images")
-			if err := p.imagePullAndPush(); err != nil {
-				p.updateConditionStatus(rainbondv1alpha1.PushImage, rainbondv1alpha1.Failed)
-				p.updateConditionResion(rainbondv1alpha1.PushImage, err.Error(), "pull and push images failure")
+			p.updateConditionStatus(rainbondv1alpha1.PushImage, rainbondv1alpha1.Failed)
+			reason := rainbondv1alpha1.ReasonImagePushFailed
+			if _, ok := err.(*imagePushAuthError); ok {
+				reason = rainbondv1alpha1.ReasonImagePushAuthFailed
+			}
+			p.updateConditionResion(rainbondv1alpha1.PushImage, reason, "push images failure: "+err.Error())
+			p.updateCRStatus()
+			return fmt.Errorf("failed to push images: %v", err)
+		}
+		if missing, err := p.verifyPushedImages(tasks); err != nil || len(missing) > 0 {
+			p.updateConditionStatus(rainbondv1alpha1.PushImage, rainbondv1alpha1.Failed)
+			if err != nil {
+				p.updateConditionResion(rainbondv1alpha1.PushImage, rainbondv1alpha1.ReasonImageVerifyFailed, "verify pushed images failure: "+err.Error())
 				p.updateCRStatus()
-				return fmt.Errorf("failed to pull and push images: %v", err)
+				return fmt.Errorf("failed to verify pushed images: %v", err)
 			}
+			msg := fmt.Sprintf("%d images are missing in the image repository, push them again: %s", len(missing), strings.Join(missing, ", "))
+			p.updateConditionResion(rainbondv1alpha1.PushImage, rainbondv1alpha1.ReasonImagesMissing, msg)
+			p.updateCRStatus()
+			return fmt.Errorf("%s", msg)
 		}
 		p.log.Info("handle images success")
 		p.updateConditionStatus(rainbondv1alpha1.PushImage, rainbondv1alpha1.Completed)
@@ -623,6 +877,54 @@ func (p *pkg) handle() error {
 	return nil
 }
 
+// checkDiskSpace checks if there is enough free space to extract the package, and to push the images in it.
+func (p *pkg) checkDiskSpace() error {
+	info, err := os.Stat(p.pkg.Spec.PkgPath)
+	if err != nil {
+		return err
+	}
+	_ = os.MkdirAll(pkgDst, os.ModePerm)
+	available, err := commonutil.DiskAvailable(pkgDst)
+	if err != nil {
+		// not all filesystems support statfs, skip the check.
+		p.log.Info(fmt.Sprintf("get available disk space of %s: %v", pkgDst, err))
+	} else if required := uint64(info.Size()) * unpackExpansionFactor; available < required {
+		return fmt.Errorf("insufficient disk space in %s to extract %s: required %s, available %s",
+			pkgDst, p.pkg.Spec.PkgPath, commonutil.BytesSize(required), commonutil.BytesSize(available))
+	}
+	return p.checkHubSpace(uint64(info.Size()))
+}
+
+// checkHubSpace checks if there is enough free space in the storage of the builtin image hub to push the images,
+// which take about the size of the package. It is skipped for the external image hubs and the object storage, or if
+// the kubelet does not report the space of the storage.
+func (p *pkg) checkHubSpace(required uint64) error {
+	if hub := p.cluster.Spec.ImageHub; hub != nil && (hub.Domain != constants.DefImageRepository || hub.ObjectStorage != nil) {
+		return nil
+	}
+	pods := &corev1.PodList{}
+	if err := p.client.List(p.ctx, pods, client.InNamespace(p.pkg.Namespace), client.MatchingLabels{"name": chandler.HubName}); err != nil {
+		return fmt.Errorf("list the pods of %s: %v", chandler.HubName, err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		available, ok, err := k8sutil.ClaimAvailable(p.ctx, pod, chandler.HubName)
+		if err != nil || !ok {
+			p.log.Info(fmt.Sprintf("get available disk space of %s: %v", chandler.HubName, err))
+			return nil
+		}
+		if available < required {
+			return fmt.Errorf("insufficient disk space in %s to push the images of %s: required %s, available %s",
+				chandler.HubName, p.pkg.Spec.PkgPath, commonutil.BytesSize(required), commonutil.BytesSize(available))
+		}
+		return nil
+	}
+	return nil
+}
+
 func (p *pkg) untartar() error {
 	p.log.Info(fmt.Sprintf("start untartaring %s", p.pkg.Spec.PkgPath))
 	f, err := os.Open(p.pkg.Spec.PkgPath)
@@ -659,294 +961,6 @@ func (p *pkg) untartar() error {
 	stop <- struct{}{}
 	return nil
 }
-func (p *pkg) imagePullAndPush() error {
-	p.pkg.Status.ImagesNumber = int32(len(p.images))
-	p.pkg.Status.ImagesPushed = nil
-	var count int32
-	handleImgae := func(remoteImage, localImage string) error {
-		return retryutil.Retry(time.Second*2, 3, func() (bool, error) {
-			exists, err := p.checkIfImageExists(remoteImage)
-			if err != nil {
-				return false, fmt.Errorf("check if image exists: %v", err)
-			}
-			if !exists {
-				p.log.V(4).Info("image does not exists, start pulling", "image name", remoteImage)
-				if err := p.imagePull(remoteImage); err != nil {
-					return false, fmt.Errorf("pull image %s failure %s", remoteImage, err.Error())
-				}
-			}
-			if err := p.dcli.ImageTag(p.ctx, remoteImage, localImage); err != nil {
-				return false, fmt.Errorf("change image tag(%s => %s) failure: %v", remoteImage, localImage, err)
-			}
-			if err := p.imagePush(localImage); err != nil {
-				return false, fmt.Errorf("push image %s failure %s", localImage, err.Error())
-			}
-			return true, nil
-		})
-	}
-
-	for old, new := range p.images {
-		remoteImage := path.Join(p.downloadImageDomain, old)
-		localImage := path.Join(p.pushImageDomain, new)
-		if err := handleImgae(remoteImage, localImage); err != nil {
-			return err
-		}
-		count++
-		p.pkg.Status.ImagesPushed = append(p.pkg.Status.ImagesPushed, rainbondv1alpha1.RainbondPackageImage{Name: localImage})
-		progress := count * 100 / p.pkg.Status.ImagesNumber
-		if p.updateConditionProgress(rainbondv1alpha1.PushImage, progress) {
-			if err := p.updateCRStatus(); err != nil {
-				return fmt.Errorf("update cr status: %v", err)
-			}
-		}
-		p.log.Info("successfully load image", "image", localImage)
-	}
-	return nil
-}
-func (p *pkg) imagesLoadAndPush() error {
-	p.pkg.Status.ImagesNumber = countImages(pkgDst)
-	p.pkg.Status.ImagesPushed = nil
-	var count int32
-	walkFn := func(pstr string, info os.FileInfo, err error) error {
-		l := p.log.WithValues("file", pstr)
-		if err != nil {
-			l.Info(fmt.Sprintf("prevent panic by handling failure accessing a path %q: %v\n", pstr, err))
-			return fmt.Errorf("prevent panic by handling failure accessing a path %q: %v", pstr, err)
-		}
-		if !commonutil.IsFile(pstr) {
-			return nil
-		}
-		if !validateFile(pstr) {
-			l.Info("invalid file, skip it1")
-			return nil
-		}
-
-		f := func() (bool, error) {
-			image, err := p.imageLoad(pstr)
-			if err != nil {
-				l.Error(err, "load image")
-				return false, fmt.Errorf("load image: %v", err)
-			}
-
-			newImage := newImageWithNewDomain(image, rbdutil.GetImageRepository(p.cluster))
-			if newImage == "" {
-				return false, fmt.Errorf("parse image name failure")
-			}
-
-			if err := p.dcli.ImageTag(p.ctx, image, newImage); err != nil {
-				l.Error(err, "tag image", "source", image, "target", newImage)
-				return false, fmt.Errorf("tag image: %v", err)
-			}
-
-			if err = p.imagePush(newImage); err != nil {
-				l.Error(err, "push image", "image", newImage)
-				return false, fmt.Errorf("push image %s: %v", newImage, err)
-			}
-			count++
-			p.pkg.Status.ImagesPushed = append(p.pkg.Status.ImagesPushed, rainbondv1alpha1.RainbondPackageImage{Name: newImage})
-			progress := count * 100 / p.pkg.Status.ImagesNumber
-			if p.updateConditionProgress(rainbondv1alpha1.PushImage, progress) {
-				if err := p.updateCRStatus(); err != nil {
-					return false, fmt.Errorf("update cr status: %v", err)
-				}
-			}
-			l.Info("successfully load image", "image", newImage)
-			return true, nil
-		}
-
-		return retryutil.Retry(1*time.Second, 3, f)
-	}
-
-	return filepath.Walk(pkgDst, walkFn)
-}
-
-func (p *pkg) imageLoad(file string) (string, error) {
-	p.log.Info("start loading image", "file", file)
-	f, err := os.Open(file)
-	if err != nil {
-		return "", fmt.Errorf("open file %s: %v", file, err)
-	}
-	defer f.Close()
-	res, err := p.dcli.ImageLoad(p.ctx, f, true) // load one, push one.
-	if err != nil {
-		return "", fmt.Errorf("path: %s; failed to load images: %v", file, err)
-	}
-	var imageName string
-	if res.Body != nil {
-		defer res.Body.Close()
-		dec := json.NewDecoder(res.Body)
-		for {
-			select {
-			case <-p.ctx.Done():
-				p.log.Error(p.ctx.Err(), "error form context")
-				return "", p.ctx.Err()
-			default:
-			}
-			var jm jsonmessage.JSONMessage
-			if err := dec.Decode(&jm); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return "", fmt.Errorf("failed to decode json message: %v", err)
-			}
-			if jm.Error != nil {
-				return "", fmt.Errorf("error detail: %v", jm.Error)
-			}
-			msg := jm.Stream
-			//the domain of image in package is goodrain.me,not need change tag
-			image := parseImageName(msg)
-			if image != "" {
-				imageName = image
-			}
-		}
-	}
-	if imageName == "" {
-		return "", fmt.Errorf("not parse image name")
-	}
-	p.log.Info("success loading image", "image", imageName)
-	return imageName, nil
-}
-
-func (p *pkg) imagePush(image string) error {
-	p.log.Info("start push image", "image", image)
-	var pullipo dtypes.ImagePushOptions
-	if p.cluster != nil && p.cluster.Spec.ImageHub != nil && p.cluster.Spec.ImageHub.Username != "" {
-		auth, err := EncodeAuthToBase64(dtypes.AuthConfig{
-			Username: p.cluster.Spec.ImageHub.Username,
-			Password: p.cluster.Spec.ImageHub.Password,
-		})
-		if err != nil {
-			p.log.Error(err, "Encode image hub user and password failure")
-			return err
-		}
-		pullipo = dtypes.ImagePushOptions{
-			RegistryAuth: auth,
-		}
-	} else {
-		pullipo = dtypes.ImagePushOptions{}
-	}
-	ctx, cancel := context.WithCancel(p.ctx)
-	defer cancel()
-	var res io.ReadCloser
-	res, err := p.dcli.ImagePush(ctx, image, pullipo)
-	if err != nil {
-		p.log.Error(err, "failed to push image", "image", image)
-		return err
-	}
-	if res != nil {
-		defer res.Close()
-
-		dec := json.NewDecoder(res)
-		for {
-			select {
-			case <-ctx.Done():
-				p.log.Error(p.ctx.Err(), "error form context")
-				return p.ctx.Err()
-			default:
-			}
-			var jm jsonmessage.JSONMessage
-			if err := dec.Decode(&jm); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return fmt.Errorf("failed to decode json message: %v", err)
-			}
-			if jm.Error != nil {
-				return fmt.Errorf("error detail: %v", jm.Error)
-			}
-		}
-	}
-	p.log.Info("success push image", "image", image)
-	return nil
-}
-
-func (p *pkg) imagePull(image string) error {
-	p.log.Info("start pull image", "image", image)
-	ctx, cancel := context.WithCancel(p.ctx)
-	defer cancel()
-	rf, err := reference.ParseAnyReference(image)
-	if err != nil {
-		p.log.Error(err, "reference image error")
-		return err
-	}
-	var pullipo dtypes.ImagePullOptions
-	if p.pkg.Spec.ImageHubUser != "" {
-		auth, err := EncodeAuthToBase64(dtypes.AuthConfig{Username: p.pkg.Spec.ImageHubUser, Password: p.pkg.Spec.ImageHubPass})
-		if err != nil {
-			p.log.Error(err, "Encode image hub user and password failure")
-			return err
-		}
-		pullipo = dtypes.ImagePullOptions{
-			RegistryAuth: auth,
-		}
-	} else {
-		pullipo = dtypes.ImagePullOptions{}
-	}
-	res, err := p.dcli.ImagePull(ctx, rf.String(), pullipo)
-	if err != nil {
-		return fmt.Errorf("pull image %s failure %s", image, err.Error())
-	}
-	if res != nil {
-		defer res.Close()
-		dec := json.NewDecoder(res)
-		for {
-			select {
-			case <-ctx.Done():
-				p.log.Error(ctx.Err(), "error form context")
-				return ctx.Err()
-			default:
-			}
-			var jm jsonmessage.JSONMessage
-			if err := dec.Decode(&jm); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return fmt.Errorf("failed to decode json message: %v", err)
-			}
-			if jm.Error != nil {
-				return fmt.Errorf("error detail: %v", jm.Error)
-			}
-		}
-	}
-	p.log.Info("success pull image", "image", image)
-	return nil
-}
-
-// EncodeAuthToBase64 serializes the auth configuration as JSON base64 payload
-func EncodeAuthToBase64(authConfig dtypes.AuthConfig) (string, error) {
-	buf, err := json.Marshal(authConfig)
-	if err != nil {
-		return "", err
-	}
-	return base64.URLEncoding.EncodeToString(buf), nil
-}
-
-func newDockerClient(ctx context.Context) (*dclient.Client, error) {
-	cli, err := dclient.NewClientWithOpts(dclient.FromEnv)
-	if err != nil {
-		return nil, fmt.Errorf("create new docker client: %v", err)
-	}
-	cli.NegotiateAPIVersion(ctx)
-
-	return cli, nil
-}
-
-func parseImageName(str string) string {
-	if !strings.Contains(str, "Loaded image: ") {
-		return ""
-	}
-	str = strings.Replace(str, "Loaded image: ", "", -1)
-	str = strings.Replace(str, "\n", "", -1)
-	str = trimLatest(str)
-	return str
-}
-
-func trimLatest(str string) string {
-	if !strings.HasSuffix(str, ":latest") {
-		return str
-	}
-	return str[:len(str)-len(":latest")]
-}
 
 func countImages(dir string) int32 {
 	var count int32
@@ -967,57 +981,18 @@ func countImages(dir string) int32 {
 	return count
 }
 
+// validateFile checks if the file is an image tarball that can be loaded by docker load.
 func validateFile(file string) bool {
 	base := path.Base(file)
-	if path.Ext(base) != ".tgz" || strings.HasPrefix(base, "._") {
+	if strings.HasPrefix(base, "._") {
 		return false
 	}
-	return true
-}
-
-func newImageWithNewDomain(image string, newDomain string) string {
-	repo, err := reference.Parse(image)
-	if err != nil {
-		return ""
-	}
-	named := repo.(reference.Named)
-	remoteName := reference.Path(named)
-	tag := "latest"
-	if t, ok := repo.(reference.Tagged); ok {
-		tag = t.Tag()
-	}
-	return path.Join(newDomain, remoteName+":"+tag)
-}
-
-func (p *pkg) checkIfImageExists(image string) (bool, error) {
-	repo, err := reference.Parse(image)
-	if err != nil {
-		p.log.V(6).Info("parse image", "image", image, "error", err)
-		return false, fmt.Errorf("parse image %s: %v", image, err)
-	}
-	named := repo.(reference.Named)
-	tag := "latest"
-	if t, ok := repo.(reference.Tagged); ok {
-		tag = t.Tag()
-	}
-	imageFullName := named.Name() + ":" + tag
-
-	ctx, cancel := context.WithCancel(p.ctx)
-	defer cancel()
-
-	imageSummarys, err := p.dcli.ImageList(ctx, dtypes.ImageListOptions{
-		Filters: filters.NewArgs(filters.KeyValuePair{Key: "reference", Value: imageFullName}),
-	})
-	if err != nil {
-		return false, fmt.Errorf("list images: %v", err)
-	}
-	for _, imageSummary := range imageSummarys {
-		fmt.Printf("%#v", imageSummary.RepoTags)
+	for _, ext := range []string{".tgz", ".tar", ".tar.gz"} {
+		if strings.HasSuffix(base, ext) {
+			return true
+		}
 	}
-
-	_ = imageSummarys
-
-	return len(imageSummarys) > 0, nil
+	return false
 }
 
 func (p *pkg) isImageRepositoryReady() bool {
@@ -0,0 +1,557 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/imageutil"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/proxyutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// imageJobName is the name label of the jobs that load and push images.
+	imageJobName = "rbd-package-image"
+	// DefImageJobImage is the default image for the jobs that load and push images, in the image repository of
+	// rainbond.
+	DefImageJobImage = "docker:20.10"
+	// maxRunningImageJobs is the maximum number of image jobs running at the same time.
+	maxRunningImageJobs = 3
+	// imageJobTargetAnnotation records the image that the job pushes.
+	imageJobTargetAnnotation = "rainbond.io/target-image"
+	// imageAuthExitCode is the exit code of the image jobs if the credential is rejected by the target image hub,
+	// it must be the same as the one in the scripts.
+	imageAuthExitCode = 3
+)
+
+// pushAuthScript pushes the target image, and exits with imageAuthExitCode if the credential is rejected.
+const pushAuthScript = `if [ -n "$TARGET_USER" ]; then
+  echo "$TARGET_PASS" | docker login -u "$TARGET_USER" --password-stdin "$TARGET_REGISTRY" || exit 3
+fi
+if ! OUTPUT=$(docker push "$TARGET_IMAGE" 2>&1); then
+  echo "$OUTPUT"
+  case "$OUTPUT" in
+    *unauthorized*|*denied*|*"authentication required"*) exit 3 ;;
+  esac
+  exit 1
+fi
+echo "$OUTPUT"
+`
+
+// pullAndPushScript pulls the source image if it does not exist, and pushes it to the target image hub.
+const pullAndPushScript = `set -e
+if ! docker image inspect "$SOURCE_IMAGE" >/dev/null 2>&1; then
+  if [ -n "$SOURCE_USER" ]; then
+    echo "$SOURCE_PASS" | docker login -u "$SOURCE_USER" --password-stdin "$SOURCE_REGISTRY"
+  fi
+  docker pull "$SOURCE_IMAGE"
+fi
+docker tag "$SOURCE_IMAGE" "$TARGET_IMAGE"
+` + pushAuthScript
+
+// loadAndPushScript loads the image from the tarball, and pushes it to the target image hub.
+const loadAndPushScript = `set -e
+IMAGE=$(docker load -i "$IMAGE_FILE" | sed -n 's/^Loaded image: //p' | tail -n 1)
+if [ -z "$IMAGE" ]; then
+  echo "no image loaded from $IMAGE_FILE"
+  exit 1
+fi
+TARGET_IMAGE="$TARGET_DOMAIN/${IMAGE#*/}"
+docker tag "$IMAGE" "$TARGET_IMAGE"
+` + pushAuthScript
+
+// imageTask describes an image that should be pushed to the image hub.
+// The image is either pulled from the source image, or loaded from the file.
+type imageTask struct {
+	source string
+	target string
+	file   string
+
+	sourceUser string
+	sourcePass string
+	// sourceSecret is the secret with the keys username and password of the source, which is used instead of
+	// sourceUser and sourcePass.
+	sourceSecret string
+	targetUser   string
+	targetPass   string
+	// targetDomain is the image repository that the image loaded from the file is pushed to.
+	targetDomain string
+	// nodeName is the node where the file is located.
+	nodeName string
+}
+
+// key returns the unique name of the task, which is recorded in the status once the image has been pushed.
+func (t imageTask) key() string {
+	if t.file != "" {
+		return t.file
+	}
+	return t.target
+}
+
+func (t imageTask) jobName(prefix string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(t.key()))
+	return fmt.Sprintf("%s-%x", prefix, h.Sum32())
+}
+
+// imageTasks returns the images that should be pushed to the image hub.
+func (p *pkg) imageTasks() ([]imageTask, error) {
+	var tasks []imageTask
+	if p.downloadPackage || p.imageDir != "" {
+		dir := pkgDst
+		if p.imageDir != "" {
+			dir = p.imageDir
+		}
+		// the jobs load the files on the node of the operator.
+		nodeName := os.Getenv(nodeNameEnv)
+		err := filepath.Walk(dir, func(pstr string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("prevent panic by handling failure accessing a path %q: %v", pstr, err)
+			}
+			if !commonutil.IsFile(pstr) || !validateFile(pstr) {
+				return nil
+			}
+			tasks = append(tasks, imageTask{file: pstr, nodeName: nodeName})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for old, new := range p.images {
+			tasks = append(tasks, imageTask{
+				source: path.Join(p.downloadImageDomain, old),
+				target: path.Join(p.pushImageDomain, new),
+			})
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].key() < tasks[j].key()
+	})
+	return tasks, nil
+}
+
+// pushImagesByJob creates a job for each image that has not been pushed, and
+// returns errorImageJobsRunning until all the images have been pushed.
+// The pushed images are recorded in the status, so that the progress will not
+// be lost if the operator restarts.
+func (p *pkg) pushImagesByJob(tasks []imageTask) error {
+	pushed := make(map[string]struct{})
+	for _, image := range p.pkg.Status.ImagesPushed {
+		pushed[image.Name] = struct{}{}
+	}
+
+	var running, completed int
+	for _, task := range tasks {
+		if _, ok := pushed[task.key()]; ok {
+			completed++
+			continue
+		}
+
+		job := &batchv1.Job{}
+		err := p.client.Get(p.ctx, types.NamespacedName{Namespace: p.pkg.Namespace, Name: task.jobName(imageJobName)}, job)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return fmt.Errorf("get job %s: %v", task.jobName(imageJobName), err)
+			}
+			if running >= maxRunningImageJobs {
+				continue
+			}
+			if err := p.createImageJob(task); err != nil {
+				return fmt.Errorf("create job for image %s: %v", task.key(), err)
+			}
+			running++
+			continue
+		}
+
+		if job.Status.Succeeded > 0 {
+			p.log.Info("successfully push image", "image", task.key())
+			p.pkg.Status.ImagesPushed = append(p.pkg.Status.ImagesPushed, rainbondv1alpha1.RainbondPackageImage{Name: task.key()})
+			if task.file != "" {
+				if info, err := os.Stat(task.file); err == nil {
+					p.addConditionBytes(rainbondv1alpha1.PushImage, info.Size())
+				}
+			}
+			completed++
+			p.deleteImageJob(job)
+			continue
+		}
+		if isJobFailed(job) {
+			unauthorized := p.isImageJobUnauthorized(job)
+			// delete the failed job, it will be created again when retrying.
+			p.deleteImageJob(job)
+			if unauthorized {
+				return &imagePushAuthError{job: job.Name, image: task.key()}
+			}
+			return fmt.Errorf("job %s for image %s failed", job.Name, task.key())
+		}
+		running++
+	}
+
+	p.pkg.Status.ImagesNumber = int32(len(tasks))
+	if len(tasks) > 0 {
+		p.updateConditionProgress(rainbondv1alpha1.PushImage, int32(completed*100/len(tasks)))
+	}
+	if err := p.updateCRStatus(); err != nil {
+		return fmt.Errorf("update cr status: %v", err)
+	}
+
+	if completed < len(tasks) {
+		return errorImageJobsRunning
+	}
+	return nil
+}
+
+// verifyPushedImages checks if the pushed images exist in the image repository, in case of the silent partial pushes.
+// The missing images are removed from the pushed images, so that they will be pushed again.
+// The images loaded from the files are verified by the config digests in the manifests of the tarballs.
+func (p *pkg) verifyPushedImages(tasks []imageTask) ([]string, error) {
+	var username, password string
+	if imageHub := p.cluster.Spec.ImageHub; imageHub != nil {
+		username, password = imageHub.Username, imageHub.Password
+	}
+	// goodrain.me is resolved to the gateway by the host aliases of the components, but not the operator.
+	addresses := make(map[string]string)
+	if ip := p.cluster.InnerGatewayIngressIP(); ip != "" {
+		addresses[constants.DefImageRepository] = ip
+	}
+	proxy, err := proxyutil.Get(p.ctx, p.client, p.cluster)
+	if err != nil {
+		return nil, err
+	}
+	checker := imageutil.NewManifestChecker(username, password, addresses, proxy.ProxyFunc())
+
+	var missing []string
+	missingSet := make(map[string]struct{})
+	for _, task := range tasks {
+		var exists bool
+		if task.file != "" {
+			image, err := imageutil.ReadTarballImage(task.file)
+			if err != nil {
+				return nil, fmt.Errorf("read image from %s: %v", task.file, err)
+			}
+			target := tarballTarget(task.targetDomain, image.Name)
+			digest, err := checker.ConfigDigest(p.ctx, target)
+			if err != nil {
+				return nil, fmt.Errorf("check image %s: %v", target, err)
+			}
+			exists = digest == image.ConfigDigest
+		} else {
+			exists, err = checker.Exists(p.ctx, task.target)
+			if err != nil {
+				return nil, fmt.Errorf("check image %s: %v", task.target, err)
+			}
+		}
+		if !exists {
+			missing = append(missing, task.key())
+			missingSet[task.key()] = struct{}{}
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	p.log.Info("images are missing after pushed", "images", missing)
+	var pushed []rainbondv1alpha1.RainbondPackageImage
+	for _, image := range p.pkg.Status.ImagesPushed {
+		if _, ok := missingSet[image.Name]; !ok {
+			pushed = append(pushed, image)
+		}
+	}
+	p.pkg.Status.ImagesPushed = pushed
+	return missing, nil
+}
+
+// tarballTarget returns the image that the image loaded from the tarball is pushed to, it must be the same as
+// TARGET_IMAGE in loadAndPushScript.
+func tarballTarget(domain, image string) string {
+	if idx := strings.Index(image, "/"); idx >= 0 {
+		image = image[idx+1:]
+	}
+	return domain + "/" + image
+}
+
+func (p *pkg) createImageJob(task imageTask) error {
+	job := p.imageJob(task)
+	if err := controllerutil.SetControllerReference(p.pkg, job, p.scheme); err != nil {
+		return fmt.Errorf("set controller reference: %v", err)
+	}
+	p.log.Info("create job for image", "job", job.Name, "image", task.key())
+	return createImageJob(p.ctx, p.client, p.scheme, job, task)
+}
+
+// createImageJob creates the job of the task, and the secret of the credentials referred by it.
+// The pods of the job wait for the secret, which is created once the job is, for it is owned by the job.
+func createImageJob(ctx context.Context, c client.Client, scheme *runtime.Scheme, job *batchv1.Job, task imageTask) error {
+	if err := k8sutil.CreateIfNotExists(ctx, c, job); err != nil {
+		return err
+	}
+	data := imageJobCredentials(task)
+	if len(data) == 0 {
+		return nil
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      job.Name,
+			Namespace: job.Namespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.Labels = job.Labels
+		secret.Data = data
+		// the secret left by the deleted job of the same name is taken over.
+		secret.OwnerReferences = nil
+		return controllerutil.SetControllerReference(job, secret, scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("create secret of job %s: %v", job.Name, err)
+	}
+	return nil
+}
+
+// imageJobCredentials returns the credentials of the task by the names of the envs of the jobs.
+func imageJobCredentials(task imageTask) map[string][]byte {
+	data := make(map[string][]byte)
+	if task.targetUser != "" {
+		data["TARGET_USER"] = []byte(task.targetUser)
+		data["TARGET_PASS"] = []byte(task.targetPass)
+	}
+	if task.file == "" && task.sourceUser != "" {
+		data["SOURCE_USER"] = []byte(task.sourceUser)
+		data["SOURCE_PASS"] = []byte(task.sourcePass)
+	}
+	return data
+}
+
+// secretEnv returns the env read from the key of the same name in the secret.
+func secretEnv(name, secretName string) corev1.EnvVar {
+	return secretKeyEnv(name, secretName, name)
+}
+
+// secretKeyEnv returns the env read from the key in the secret.
+func secretKeyEnv(name, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// imagePushAuthError means the credential of the target image hub is rejected.
+type imagePushAuthError struct {
+	job, image string
+}
+
+func (e *imagePushAuthError) Error() string {
+	return fmt.Sprintf("job %s for image %s failed: the credential is rejected by the image repository", e.job, e.image)
+}
+
+// isImageJobUnauthorized checks if one of the pods of the failed job exits with imageAuthExitCode.
+func (p *pkg) isImageJobUnauthorized(job *batchv1.Job) bool {
+	pods := &corev1.PodList{}
+	if err := p.client.List(p.ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		p.log.V(4).Info("list pods of the image job", "job", job.Name, "msg", err.Error())
+		return false
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode == imageAuthExitCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *pkg) deleteImageJob(job *batchv1.Job) {
+	err := p.client.Delete(p.ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		p.log.Error(err, "delete image job", "job", job.Name)
+	}
+}
+
+func (p *pkg) imageJob(task imageTask) *batchv1.Job {
+	if imageHub := p.cluster.Spec.ImageHub; imageHub != nil {
+		task.targetUser, task.targetPass = imageHub.Username, imageHub.Password
+	}
+	if task.file != "" {
+		task.targetDomain = rbdutil.GetImageRepository(p.cluster)
+	} else {
+		task.sourceUser, task.sourcePass = p.pkg.Spec.ImageHubUser, p.pkg.Spec.ImageHubPass
+	}
+	return newImageJob(imageJobName, p.pkg.Namespace, imageJobImage(p.pkg.Spec.JobImage, p.cluster), task)
+}
+
+// imageJobImage returns the image of the jobs that load and push images, which is DefImageJobImage in the image
+// repository of rainbond if not set, so that it can be pulled in the offline installation.
+func imageJobImage(image string, cluster *rainbondv1alpha1.RainbondCluster) string {
+	if image != "" {
+		return image
+	}
+	return path.Join(cluster.Spec.RainbondImageRepository, DefImageJobImage)
+}
+
+// newImageJob creates a job that pushes the image of the task to the target image hub.
+func newImageJob(name, namespace, image string, task imageTask) *batchv1.Job {
+	labels := rbdutil.LabelsForRainbond(map[string]string{
+		"name": name,
+	})
+
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "dockersock",
+			MountPath: "/var/run/docker.sock",
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "dockersock",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: "/var/run/docker.sock",
+					Type: k8sutil.HostPath(corev1.HostPathSocket),
+				},
+			},
+		},
+	}
+
+	// the credentials are read from the secret of the job, see imageJobSecret.
+	jobName := task.jobName(name)
+	var env []corev1.EnvVar
+	if task.targetUser != "" {
+		env = append(env, secretEnv("TARGET_USER", jobName), secretEnv("TARGET_PASS", jobName))
+	}
+
+	script := pullAndPushScript
+	if task.file != "" {
+		script = loadAndPushScript
+		dir := path.Dir(task.file)
+		env = append(env, corev1.EnvVar{
+			Name:  "IMAGE_FILE",
+			Value: task.file,
+		}, corev1.EnvVar{
+			Name:  "TARGET_DOMAIN",
+			Value: task.targetDomain,
+		}, corev1.EnvVar{
+			Name:  "TARGET_REGISTRY",
+			Value: strings.SplitN(task.targetDomain, "/", 2)[0],
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "images",
+			MountPath: dir,
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "images",
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: dir,
+					Type: k8sutil.HostPath(corev1.HostPathDirectory),
+				},
+			},
+		})
+	} else {
+		env = append(env, corev1.EnvVar{
+			Name:  "SOURCE_IMAGE",
+			Value: task.source,
+		}, corev1.EnvVar{
+			Name:  "SOURCE_REGISTRY",
+			Value: imageRegistry(task.source),
+		}, corev1.EnvVar{
+			Name:  "TARGET_IMAGE",
+			Value: task.target,
+		}, corev1.EnvVar{
+			Name:  "TARGET_REGISTRY",
+			Value: imageRegistry(task.target),
+		})
+		if task.sourceSecret != "" {
+			env = append(env, secretKeyEnv("SOURCE_USER", task.sourceSecret, "username"),
+				secretKeyEnv("SOURCE_PASS", task.sourceSecret, "password"))
+		} else if task.sourceUser != "" {
+			env = append(env, secretEnv("SOURCE_USER", jobName), secretEnv("SOURCE_PASS", jobName))
+		}
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				imageJobTargetAnnotation: task.key(),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: commonutil.Int32(2),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: rbdutil.ServiceAccountName(),
+					NodeName:           task.nodeName,
+					Tolerations: []corev1.Toleration{
+						{
+							Operator: corev1.TolerationOpExists, // tolerate everything.
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:            name,
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"/bin/sh", "-c", script},
+							Env:             env,
+							VolumeMounts:    volumeMounts,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+	k8sutil.SetLinuxNodeSelector(&job.Spec.Template.Spec)
+	return job
+}
+
+func isJobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRegistry returns the registry of the given image or image repository.
+func imageRegistry(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	return reference.Domain(named)
+}
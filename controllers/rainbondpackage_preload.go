@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// imagePreloadName is the name of the DaemonSet that preloads images.
+	imagePreloadName = "rbd-image-preload"
+	// defImagePreloadPath is the default directory on each node that contains the image tarballs.
+	defImagePreloadPath = "/opt/rainbond/pkg/images"
+)
+
+// imagePreloadScript loads all the image tarballs in $IMAGES_DIR into the container runtime.
+const imagePreloadScript = `for file in "$IMAGES_DIR"/*.tgz "$IMAGES_DIR"/*.tar; do
+  [ -f "$file" ] || continue
+  case "$(basename "$file")" in ._*) continue ;; esac
+  echo "loading $file"
+  docker load -i "$file" || echo "failed to load $file"
+done
+`
+
+// reconcileImagePreload makes sure the DaemonSet that preloads images exists if
+// image preloading is enabled, or deletes it otherwise.
+func (r *RainbondPackageReconciler) reconcileImagePreload(ctx context.Context, pkg *rainbondv1alpha1.RainbondPackage, cluster *rainbondv1alpha1.RainbondCluster) error {
+	old := &appsv1.DaemonSet{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: pkg.Namespace, Name: imagePreloadName}, old)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("get daemonset %s: %v", imagePreloadName, err)
+	}
+	exists := err == nil
+
+	if pkg.Spec.ImagePreload == nil {
+		if !exists {
+			return nil
+		}
+		r.Log.Info("image preload is disabled, delete daemonset", "name", imagePreloadName)
+		if err := r.Delete(ctx, old); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("delete daemonset %s: %v", imagePreloadName, err)
+		}
+		return nil
+	}
+
+	ds := imagePreloadDaemonSet(pkg, cluster)
+	if err := controllerutil.SetControllerReference(pkg, ds, r.Scheme); err != nil {
+		return fmt.Errorf("set controller reference: %v", err)
+	}
+	if !exists {
+		r.Log.Info("create daemonset for image preload", "name", imagePreloadName)
+		return k8sutil.CreateIfNotExists(ctx, r.Client, ds)
+	}
+	if equality.Semantic.DeepDerivative(ds.Spec.Template, old.Spec.Template) {
+		return nil
+	}
+	old.Spec.Template = ds.Spec.Template
+	r.Log.Info("update daemonset for image preload", "name", imagePreloadName)
+	return r.Update(ctx, old)
+}
+
+func imagePreloadDaemonSet(pkg *rainbondv1alpha1.RainbondPackage, cluster *rainbondv1alpha1.RainbondCluster) *appsv1.DaemonSet {
+	preload := pkg.Spec.ImagePreload
+	hostPath := preload.HostPath
+	if hostPath == "" {
+		hostPath = defImagePreloadPath
+	}
+	image := imageJobImage(pkg.Spec.JobImage, cluster)
+	labels := rbdutil.LabelsForRainbond(map[string]string{
+		"name": imagePreloadName,
+	})
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      imagePreloadName,
+			Namespace: pkg.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   imagePreloadName,
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName:            rbdutil.ServiceAccountName(),
+					TerminationGracePeriodSeconds: commonutil.Int64(0),
+					NodeSelector:                  preload.NodeSelector,
+					Tolerations: []corev1.Toleration{
+						{
+							Operator: corev1.TolerationOpExists, // tolerate everything.
+						},
+					},
+					// load the images in the init container, and keep the pod running
+					// so that the images will not be loaded again.
+					InitContainers: []corev1.Container{
+						{
+							Name:            "load",
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"/bin/sh", "-c", imagePreloadScript},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "IMAGES_DIR",
+									Value: "/images",
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "dockersock",
+									MountPath: "/var/run/docker.sock",
+								},
+								{
+									Name:      "images",
+									MountPath: "/images",
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:            imagePreloadName,
+							Image:           image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"/bin/sh", "-c", "while true; do sleep 3600; done"},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "dockersock",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/var/run/docker.sock",
+									Type: k8sutil.HostPath(corev1.HostPathSocket),
+								},
+							},
+						},
+						{
+							Name: "images",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: hostPath,
+									Type: k8sutil.HostPathDirectoryOrCreate(),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	k8sutil.SetLinuxNodeSelector(&ds.Spec.Template.Spec)
+	return ds
+}
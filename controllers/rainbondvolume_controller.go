@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -37,7 +38,9 @@ import (
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/controllers/plugin"
+	"github.com/goodrain/rainbond-operator/util/constants"
 	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/operatorconfig"
 	"github.com/goodrain/rainbond-operator/util/rbdutil"
 )
 
@@ -46,6 +49,8 @@ type RainbondVolumeReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// Settings are the settings of the operator, the defaults are used if nil.
+	Settings *operatorconfig.Settings
 }
 
 //ErrCSIPluginNotReady -
@@ -84,8 +89,38 @@ func (r *RainbondVolumeReconciler) Reconcile(ctx context.Context, request ctrl.R
 		return ctrl.Result{}, err
 	}
 
+	if volume.Spec.Preset != nil && volume.Spec.StorageClassName == "" && volume.Spec.StorageClassParameters == nil {
+		params, err := presetStorageClassParameters(volume)
+		if err != nil {
+			log.Info("invalid preset", "msg", err.Error())
+			return reconcile.Result{}, r.updateMountableCondition(ctx, volume, corev1.ConditionFalse, "InvalidPreset", err.Error())
+		}
+		volume.Spec.StorageClassParameters = params
+		if err := r.updateVolumeRetryOnConflict(ctx, volume); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.Info("generate the storage class parameters from the preset", "provisioner", params.Provisioner)
+		return reconcile.Result{Requeue: true}, nil
+	}
+
 	useStorageClassName := volume.Spec.StorageClassName != ""
 	if useStorageClassName {
+		if !mountVerified(volume) {
+			mountable, reason, msg, err := r.probeMount(ctx, volume)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			if !mountable {
+				log.V(4).Info("the storage class is not verified to be mountable", "reason", reason, "msg", msg)
+				if err := r.updateMountableCondition(ctx, volume, corev1.ConditionFalse, reason, msg); err != nil {
+					return reconcile.Result{}, err
+				}
+				return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+			}
+			if err := r.updateMountableCondition(ctx, volume, corev1.ConditionTrue, "Mounted", ""); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
 		if err := r.updateVolumeStatus(ctx, volume); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -98,12 +133,34 @@ func (r *RainbondVolumeReconciler) Reconcile(ctx context.Context, request ctrl.R
 		log.Info("rainbond volume storage class is config, will sync storageclass", "provisioner", volume.Spec.StorageClassParameters.Provisioner)
 		className, err := r.createIfNotExistStorageClass(ctx, volume)
 		if err != nil {
+			if conflict, ok := err.(*storageClassConflictError); ok {
+				log.Info("refuse to use the conflicting storage class", "msg", conflict.Error())
+				if err := r.updateStorageClassConflict(ctx, volume, conflict); err != nil {
+					return reconcile.Result{}, err
+				}
+				// the conflicting storage classes may be deleted.
+				return reconcile.Result{RequeueAfter: time.Minute}, nil
+			}
 			return reconcile.Result{}, err
 		}
+		if err := r.updateStorageClassConflict(ctx, volume, nil); err != nil {
+			return reconcile.Result{}, err
+		}
+		if volume.Status.StorageClassName != className {
+			before := volume.Status.DeepCopy()
+			volume.Status.StorageClassName = className
+			if err := r.updateVolumeStatusRetryOnConflict(ctx, volume, before); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
 		volume.Spec.StorageClassName = className
 		if err := r.updateVolumeRetryOnConflict(ctx, volume); err != nil {
 			return reconcile.Result{}, err
 		}
+		if !mountVerified(volume) {
+			// verify the storage class before the volume is ready.
+			return reconcile.Result{Requeue: true}, nil
+		}
 		if err := r.updateVolumeStatus(ctx, volume); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -131,6 +188,9 @@ func (r *RainbondVolumeReconciler) Reconcile(ctx context.Context, request ctrl.R
 			}
 			return reconcile.Result{}, err
 		}
+		if err := r.updatePluginCondition(ctx, volume, corev1.ConditionTrue, "PluginReady", ""); err != nil {
+			return reconcile.Result{}, err
+		}
 		if err := r.updateVolumeRetryOnConflict(ctx, volume); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -144,6 +204,8 @@ func (r *RainbondVolumeReconciler) Reconcile(ctx context.Context, request ctrl.R
 // SetupWithManager sets up the controller with the Manager.
 func (r *RainbondVolumeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(r.Settings.ControllerOptions()).
+		WithEventFilter(r.Settings.NamespacePredicate()).
 		For(&rainbondv1alpha1.RainbondVolume{}).
 		Complete(r)
 }
@@ -166,6 +228,9 @@ func (r *RainbondVolumeReconciler) applyCSIPlugin(ctx context.Context, plugin pl
 		if err := r.createIfNotExists(ctx, res); err != nil {
 			return err
 		}
+		if err := rbdutil.CheckInstance(res, volume.Namespace); err != nil {
+			return fmt.Errorf("%T %v", res, err)
+		}
 	}
 
 	subResources := plugin.GetSubResources()
@@ -210,10 +275,10 @@ func (r *RainbondVolumeReconciler) createIfNotExists(ctx context.Context, obj cl
 }
 
 func (r *RainbondVolumeReconciler) updateVolumeStatus(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume) error {
-	status := volume.Status.DeepCopy()
-	_, condtion := status.GetRainbondVolumeCondition(rainbondv1alpha1.RainbondVolumeReady)
-	if condtion == nil {
-		condtion = &rainbondv1alpha1.RainbondVolumeCondition{Type: rainbondv1alpha1.RainbondVolumeReady}
+	before := volume.Status.DeepCopy()
+	condtion := &rainbondv1alpha1.RainbondVolumeCondition{Type: rainbondv1alpha1.RainbondVolumeReady}
+	if _, old := before.GetRainbondVolumeCondition(rainbondv1alpha1.RainbondVolumeReady); old != nil {
+		condtion = old.DeepCopy()
 	}
 	if volume.Spec.StorageClassName == "" {
 		condtion.Status = corev1.ConditionFalse
@@ -221,16 +286,18 @@ func (r *RainbondVolumeReconciler) updateVolumeStatus(ctx context.Context, volum
 		condtion.Status = corev1.ConditionTrue
 	}
 
-	volume.Status.UpdateRainbondVolumeCondition(condtion)
-	if updated := status.UpdateRainbondVolumeCondition(condtion); updated {
-		return r.updateVolumeStatusRetryOnConflict(ctx, volume)
+	if updated := volume.Status.UpdateRainbondVolumeCondition(condtion); updated {
+		return r.updateVolumeStatusRetryOnConflict(ctx, volume, before)
 	}
 	return nil
 }
 
-func (r *RainbondVolumeReconciler) updateVolumeStatusRetryOnConflict(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume) error {
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		return r.Status().Update(ctx, volume)
+// updateVolumeStatusRetryOnConflict writes the changes of the status of the volume since the status before, the
+// conditions written by the others in the meantime are kept.
+func (r *RainbondVolumeReconciler) updateVolumeStatusRetryOnConflict(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume, before *rainbondv1alpha1.RainbondVolumeStatus) error {
+	status := volume.Status.DeepCopy()
+	return k8sutil.UpdateStatusChanges(ctx, r.Client, volume, before, status, func(latest client.Object) interface{} {
+		return &latest.(*rainbondv1alpha1.RainbondVolume).Status
 	})
 }
 
@@ -248,29 +315,113 @@ func (r *RainbondVolumeReconciler) updateVolumeRetryOnConflict(ctx context.Conte
 	})
 }
 
+// storageClassConflictError means the names of the storage class are taken by the ones with different provisioners,
+// or by the ones of the rainbonds in other namespaces.
+type storageClassConflictError struct {
+	provisioner string
+	conflicts   []string
+}
+
+func (e *storageClassConflictError) Error() string {
+	return fmt.Sprintf("storage class %s already exists and can't be used for provisioner %s",
+		strings.Join(e.conflicts, ", "), e.provisioner)
+}
+
+// createIfNotExistStorageClass returns the name of the storage class with the provisioner of the volume,
+// which is created if not exists. The existing storage class with a different provisioner, or of the rainbond
+// in another namespace, is never used nor changed, a *storageClassConflictError is returned if all the names are taken.
 func (r *RainbondVolumeReconciler) createIfNotExistStorageClass(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume) (string, error) {
-	old := &storagev1.StorageClass{}
-	// check if the storageclass based on the given sc exists.
-	err := r.Get(ctx, types.NamespacedName{Name: volume.Name}, old)
-	if err == nil {
-		return old.Name, nil
+	provisioner := volume.Spec.StorageClassParameters.Provisioner
+	var conflicts []string
+	for _, name := range storageClassNames(volume) {
+		old := &storagev1.StorageClass{}
+		// check if the storageclass based on the given sc exists.
+		err := r.Get(ctx, types.NamespacedName{Name: name}, old)
+		if err == nil {
+			if err := rbdutil.CheckInstance(old, volume.Namespace); err != nil {
+				// the parameters of the storage class may be different even with the same provisioner.
+				conflicts = append(conflicts, fmt.Sprintf("%s(rainbond: %s)", old.Name, old.Labels[constants.InstanceLabelKey]))
+				continue
+			}
+			if old.Provisioner == provisioner {
+				return old.Name, nil
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s(provisioner: %s)", old.Name, old.Provisioner))
+			continue
+		}
+		if !k8sErrors.IsNotFound(err) {
+			return "", err
+		}
+		// create a new one
+		sc := storageClassForRainbondVolume(volume, name)
+		if err := r.Create(ctx, sc); err != nil {
+			return "", err
+		}
+		return sc.Name, nil
+	}
+	return "", &storageClassConflictError{provisioner: provisioner, conflicts: conflicts}
+}
+
+// storageClassNames returns the candidate names of the storage class of the volume, by the naming strategy.
+// The strategy defaults to Suffix outside the default namespace, where the volume may be of another rainbond.
+func storageClassNames(volume *rainbondv1alpha1.RainbondVolume) []string {
+	names := []string{volume.Name}
+	naming := volume.Spec.StorageClassNaming
+	if naming == "" && volume.Namespace != constants.Namespace {
+		naming = rainbondv1alpha1.StorageClassNamingSuffix
+	}
+	if naming == rainbondv1alpha1.StorageClassNamingSuffix {
+		names = append(names, volume.Name+"-"+volume.Namespace)
+	}
+	return names
+}
+
+// updatePluginCondition updates the condition PluginReady of the volume.
+func (r *RainbondVolumeReconciler) updatePluginCondition(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume, status corev1.ConditionStatus, reason, msg string) error {
+	condition := &rainbondv1alpha1.RainbondVolumeCondition{
+		Type:    rainbondv1alpha1.RainbondVolumePluginReady,
+		Status:  status,
+		Reason:  reason,
+		Message: msg,
+	}
+	before := volume.Status.DeepCopy()
+	if volume.Status.UpdateRainbondVolumeCondition(condition) {
+		return r.updateVolumeStatusRetryOnConflict(ctx, volume, before)
+	}
+	return nil
+}
+
+// updateStorageClassConflict updates the condition StorageClassConflict of the volume, the conflict is resolved if conflict is nil.
+func (r *RainbondVolumeReconciler) updateStorageClassConflict(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume, conflict *storageClassConflictError) error {
+	_, old := volume.Status.GetRainbondVolumeCondition(rainbondv1alpha1.RainbondVolumeStorageClassConflict)
+	if conflict == nil && old == nil {
+		return nil
+	}
+	condition := &rainbondv1alpha1.RainbondVolumeCondition{
+		Type:   rainbondv1alpha1.RainbondVolumeStorageClassConflict,
+		Status: corev1.ConditionFalse,
+		Reason: "Resolved",
 	}
-	if !k8sErrors.IsNotFound(err) {
-		return "", err
+	if conflict != nil {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "StorageClassConflict"
+		condition.Message = conflict.Error()
+		if volume.Spec.StorageClassNaming != rainbondv1alpha1.StorageClassNamingSuffix {
+			condition.Message += fmt.Sprintf("; set storageClassNaming to %s to use another name", rainbondv1alpha1.StorageClassNamingSuffix)
+		}
 	}
-	// create a new one
-	sc := storageClassForRainbondVolume(volume)
-	if err := r.Create(ctx, sc); err != nil {
-		return "", err
+	before := volume.Status.DeepCopy()
+	if volume.Status.UpdateRainbondVolumeCondition(condition) {
+		return r.updateVolumeStatusRetryOnConflict(ctx, volume, before)
 	}
-	return sc.Name, nil
+	return nil
 }
 
-func storageClassForRainbondVolume(volume *rainbondv1alpha1.RainbondVolume) *storagev1.StorageClass {
+func storageClassForRainbondVolume(volume *rainbondv1alpha1.RainbondVolume, name string) *storagev1.StorageClass {
 	class := &storagev1.StorageClass{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   volume.Name,
-			Labels: rbdutil.LabelsForRainbond(nil),
+			Name:   name,
+			Labels: rbdutil.LabelsForInstance(rbdutil.LabelsForRainbond(nil), volume.Namespace),
 		},
 		MountOptions:  volume.Spec.StorageClassParameters.MountOptions,
 		Provisioner:   volume.Spec.StorageClassParameters.Provisioner,
@@ -278,6 +429,15 @@ func storageClassForRainbondVolume(volume *rainbondv1alpha1.RainbondVolume) *sto
 		ReclaimPolicy: k8sutil.PersistentVolumeReclaimPolicy(corev1.PersistentVolumeReclaimRetain),
 	}
 
+	if volume.Spec.CSIPlugin != nil && volume.Spec.CSIPlugin.NFS != nil && len(class.MountOptions) == 0 {
+		// the kernels may not support the latest version the clients negotiate.
+		version := "4.0"
+		if export := volume.Spec.CSIPlugin.NFS.Export; export != nil && export.Version != "" {
+			version = export.Version
+		}
+		class.MountOptions = []string{"vers=" + version}
+	}
+
 	if volume.Spec.CSIPlugin != nil && volume.Spec.CSIPlugin.AliyunNas != nil && len(class.MountOptions) == 0 {
 		class.MountOptions = []string{
 			"nolock,tcp,noresvport",
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	"github.com/stretchr/testify/assert"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCreateIfNotExistStorageClass(t *testing.T) {
+	class := func(name, provisioner, instance string) *storagev1.StorageClass {
+		sc := &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: name},
+			Provisioner: provisioner,
+		}
+		if instance != "" {
+			sc.Labels = rbdutil.LabelsForInstance(nil, instance)
+		}
+		return sc
+	}
+	tests := []struct {
+		name         string
+		namespace    string
+		naming       rainbondv1alpha1.StorageClassNamingStrategy
+		existing     []runtime.Object
+		want         string
+		wantConflict bool
+	}{
+		{
+			name:      "create",
+			namespace: "rbd-system",
+			want:      "rainbondvolumerwx",
+		},
+		{
+			name:      "adopt the same provisioner",
+			namespace: "rbd-system",
+			existing:  []runtime.Object{class("rainbondvolumerwx", "rainbond.io/nfs", "")},
+			want:      "rainbondvolumerwx",
+		},
+		{
+			name:         "refuse another provisioner",
+			namespace:    "rbd-system",
+			existing:     []runtime.Object{class("rainbondvolumerwx", "nas.csi.aliyun.com", "")},
+			wantConflict: true,
+		},
+		{
+			name:      "suffix another provisioner",
+			namespace: "rbd-system",
+			naming:    rainbondv1alpha1.StorageClassNamingSuffix,
+			existing:  []runtime.Object{class("rainbondvolumerwx", "nas.csi.aliyun.com", "")},
+			want:      "rainbondvolumerwx-rbd-system",
+		},
+		{
+			name:      "suffix another rainbond by default",
+			namespace: "rbd-other",
+			existing:  []runtime.Object{class("rainbondvolumerwx", "rainbond.io/nfs", "rbd-system")},
+			want:      "rainbondvolumerwx-rbd-other",
+		},
+		{
+			name:      "adopt the suffixed one",
+			namespace: "rbd-other",
+			existing: []runtime.Object{
+				class("rainbondvolumerwx", "rainbond.io/nfs", "rbd-system"),
+				class("rainbondvolumerwx-rbd-other", "rainbond.io/nfs", "rbd-other"),
+			},
+			want: "rainbondvolumerwx-rbd-other",
+		},
+		{
+			name:      "refuse the taken suffixed one",
+			namespace: "rbd-other",
+			existing: []runtime.Object{
+				class("rainbondvolumerwx", "rainbond.io/nfs", "rbd-system"),
+				class("rainbondvolumerwx-rbd-other", "nas.csi.aliyun.com", ""),
+			},
+			wantConflict: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := storagev1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			r := &RainbondVolumeReconciler{
+				Client: fake.NewFakeClientWithScheme(scheme, tc.existing...),
+				Log:    logr.Discard(),
+				Scheme: scheme,
+			}
+			volume := &rainbondv1alpha1.RainbondVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "rainbondvolumerwx", Namespace: tc.namespace},
+				Spec: rainbondv1alpha1.RainbondVolumeSpec{
+					StorageClassParameters: &rainbondv1alpha1.StorageClassParameters{Provisioner: "rainbond.io/nfs"},
+					StorageClassNaming:     tc.naming,
+				},
+			}
+
+			name, err := r.createIfNotExistStorageClass(context.Background(), volume)
+			_, conflict := err.(*storageClassConflictError)
+			assert.Equal(t, tc.wantConflict, conflict)
+			assert.Equal(t, tc.want, name)
+			if tc.want == "" {
+				return
+			}
+			sc := &storagev1.StorageClass{}
+			if assert.Nil(t, r.Get(context.Background(), client.ObjectKey{Name: tc.want}, sc)) {
+				assert.Equal(t, "rainbond.io/nfs", sc.Provisioner)
+			}
+		})
+	}
+}
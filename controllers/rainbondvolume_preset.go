@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	cephFSProvisioner   = "cephfs.csi.ceph.com"
+	longhornProvisioner = "driver.longhorn.io"
+	juiceFSProvisioner  = "csi.juicefs.com"
+
+	// mountProbeFile is written and read by the probe pod to verify the volume is mountable and writable.
+	mountProbeFile = "/data/.rainbond-mount-probe"
+)
+
+// presetStorageClassParameters returns the storage class parameters generated from the preset of the volume.
+func presetStorageClassParameters(volume *rainbondv1alpha1.RainbondVolume) (*rainbondv1alpha1.StorageClassParameters, error) {
+	preset := volume.Spec.Preset
+	secretNamespace := func(namespace string) string {
+		if namespace != "" {
+			return namespace
+		}
+		return volume.Namespace
+	}
+	switch {
+	case preset.CephFS != nil:
+		cephfs := preset.CephFS
+		if cephfs.ClusterID == "" || cephfs.FSName == "" || cephfs.SecretName == "" {
+			return nil, errors.New("clusterID, fsName and secretName of the cephfs preset are required")
+		}
+		namespace := secretNamespace(cephfs.SecretNamespace)
+		return &rainbondv1alpha1.StorageClassParameters{
+			Provisioner: cephFSProvisioner,
+			Parameters: map[string]string{
+				"clusterID": cephfs.ClusterID,
+				"fsName":    cephfs.FSName,
+				"csi.storage.k8s.io/provisioner-secret-name":            cephfs.SecretName,
+				"csi.storage.k8s.io/provisioner-secret-namespace":       namespace,
+				"csi.storage.k8s.io/controller-expand-secret-name":      cephfs.SecretName,
+				"csi.storage.k8s.io/controller-expand-secret-namespace": namespace,
+				"csi.storage.k8s.io/node-stage-secret-name":             cephfs.SecretName,
+				"csi.storage.k8s.io/node-stage-secret-namespace":        namespace,
+			},
+		}, nil
+	case preset.Longhorn != nil:
+		replicas := int32(3)
+		if preset.Longhorn.NumberOfReplicas != nil {
+			replicas = *preset.Longhorn.NumberOfReplicas
+		}
+		return &rainbondv1alpha1.StorageClassParameters{
+			Provisioner: longhornProvisioner,
+			Parameters: map[string]string{
+				"numberOfReplicas":    strconv.Itoa(int(replicas)),
+				"staleReplicaTimeout": "2880",
+			},
+		}, nil
+	case preset.JuiceFS != nil:
+		juicefs := preset.JuiceFS
+		if juicefs.SecretName == "" {
+			return nil, errors.New("secretName of the juicefs preset is required")
+		}
+		namespace := secretNamespace(juicefs.SecretNamespace)
+		return &rainbondv1alpha1.StorageClassParameters{
+			Provisioner: juiceFSProvisioner,
+			Parameters: map[string]string{
+				"csi.storage.k8s.io/provisioner-secret-name":       juicefs.SecretName,
+				"csi.storage.k8s.io/provisioner-secret-namespace":  namespace,
+				"csi.storage.k8s.io/node-publish-secret-name":      juicefs.SecretName,
+				"csi.storage.k8s.io/node-publish-secret-namespace": namespace,
+			},
+		}, nil
+	}
+	return nil, errors.New("one of cephfs, longhorn and juicefs of the preset is required")
+}
+
+// probeMount verifies the storage class of the volume is mountable by a pod writing and reading a file in
+// a claim of it. It returns true once verified, or the reason and the message why it is not yet.
+// The probe pod and the claim are deleted once the probe succeeds, the failed pod is recreated to retry.
+func (r *RainbondVolumeReconciler) probeMount(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume) (bool, string, string, error) {
+	name := volume.Name + "-mount-probe"
+	labels := rbdutil.LabelsForRainbond(map[string]string{"name": name})
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: volume.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			StorageClassName: commonutil.String(volume.Spec.StorageClassName),
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: volume.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:            "probe",
+					Image:           path.Join(volume.Spec.ImageRepository, "busybox"),
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Command: []string{"/bin/sh", "-c",
+						fmt.Sprintf("echo ok > %[1]s && grep -q ok %[1]s && rm -f %[1]s", mountProbeFile)},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "data",
+							MountPath: path.Dir(mountProbeFile),
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: name},
+					},
+				},
+			},
+		},
+	}
+	k8sutil.SetLinuxNodeSelector(&pod.Spec)
+	for _, obj := range []client.Object{claim, pod} {
+		if err := controllerutil.SetControllerReference(volume, obj, r.Scheme); err != nil {
+			return false, "", "", err
+		}
+		if err := r.createIfNotExists(ctx, obj); err != nil {
+			return false, "", "", fmt.Errorf("create mount probe: %v", err)
+		}
+	}
+
+	current := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, current); err != nil {
+		return false, "", "", fmt.Errorf("get mount probe: %v", err)
+	}
+	switch current.Status.Phase {
+	case corev1.PodSucceeded:
+		for _, obj := range []client.Object{current, claim} {
+			if err := r.Delete(ctx, obj); err != nil && !k8sErrors.IsNotFound(err) {
+				return false, "", "", fmt.Errorf("delete mount probe: %v", err)
+			}
+		}
+		return true, "", "", nil
+	case corev1.PodFailed:
+		msg := fmt.Sprintf("the probe pod %s failed to write the volume", current.Name)
+		if statuses := current.Status.ContainerStatuses; len(statuses) > 0 && statuses[0].State.Terminated != nil {
+			msg += ": " + statuses[0].State.Terminated.Reason
+		}
+		// recreate the probe pod to retry.
+		if err := r.Delete(ctx, current); err != nil && !k8sErrors.IsNotFound(err) {
+			return false, "", "", fmt.Errorf("delete mount probe: %v", err)
+		}
+		return false, "MountFailed", msg, nil
+	}
+	msg := fmt.Sprintf("waiting for the probe pod %s to mount the volume, check its events if it takes long", current.Name)
+	for _, status := range current.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Message != "" {
+			msg += ": " + status.State.Waiting.Message
+		}
+	}
+	if failure := lastMountFailure(current); failure != "" {
+		if volume.Spec.CSIPlugin != nil && volume.Spec.CSIPlugin.NFS != nil && nfsClientMissing(failure) {
+			return false, "NFSClientMissing", fmt.Sprintf("the node %s lacks the nfs client, install nfs-utils or nfs-common on the nodes: %s",
+				current.Spec.NodeName, failure), nil
+		}
+		msg += ": " + failure
+	}
+	return false, "Probing", msg, nil
+}
+
+// lastMountFailure returns the message of the last FailedMount event of the pod, or empty if there is none.
+func lastMountFailure(pod *corev1.Pod) string {
+	events, err := k8sutil.EventsForPod(pod)
+	if err != nil {
+		return ""
+	}
+	var last *corev1.Event
+	for i := range events.Items {
+		event := &events.Items[i]
+		if event.Reason != "FailedMount" {
+			continue
+		}
+		if last == nil || last.LastTimestamp.Before(&event.LastTimestamp) {
+			last = event
+		}
+	}
+	if last == nil {
+		return ""
+	}
+	return last.Message
+}
+
+// nfsClientMissing checks if the mount failed because the node lacks the mount helper of nfs, in which case
+// mount suggests the /sbin/mount.<type> helper program.
+func nfsClientMissing(message string) bool {
+	return strings.Contains(message, "helper program")
+}
+
+// updateMountableCondition updates the condition Mountable of the volume.
+func (r *RainbondVolumeReconciler) updateMountableCondition(ctx context.Context, volume *rainbondv1alpha1.RainbondVolume, status corev1.ConditionStatus, reason, msg string) error {
+	condition := &rainbondv1alpha1.RainbondVolumeCondition{
+		Type:    rainbondv1alpha1.RainbondVolumeMountable,
+		Status:  status,
+		Reason:  reason,
+		Message: msg,
+	}
+	before := volume.Status.DeepCopy()
+	if volume.Status.UpdateRainbondVolumeCondition(condition) {
+		return r.updateVolumeStatusRetryOnConflict(ctx, volume, before)
+	}
+	return nil
+}
+
+// mountVerified returns true if the storage class of the volume doesn't need verifying or has been verified.
+// The storage classes of the presets and the nfs plugin are verified, for the nodes may lack their clients.
+func mountVerified(volume *rainbondv1alpha1.RainbondVolume) bool {
+	if volume.Spec.Preset == nil && (volume.Spec.CSIPlugin == nil || volume.Spec.CSIPlugin.NFS == nil) {
+		return true
+	}
+	_, condition := volume.Status.GetRainbondVolumeCondition(rainbondv1alpha1.RainbondVolumeMountable)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
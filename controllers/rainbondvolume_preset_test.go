@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPresetStorageClassParameters(t *testing.T) {
+	replicas := int32(2)
+	tests := []struct {
+		name    string
+		preset  rainbondv1alpha1.StoragePreset
+		want    *rainbondv1alpha1.StorageClassParameters
+		wantErr bool
+	}{
+		{
+			name: "cephfs",
+			preset: rainbondv1alpha1.StoragePreset{CephFS: &rainbondv1alpha1.CephFSPreset{
+				ClusterID:  "b9127830-b0cc-4e34-aa47-9d1a2e9949a8",
+				FSName:     "myfs",
+				SecretName: "csi-cephfs-secret",
+			}},
+			want: &rainbondv1alpha1.StorageClassParameters{
+				Provisioner: cephFSProvisioner,
+				Parameters: map[string]string{
+					"clusterID": "b9127830-b0cc-4e34-aa47-9d1a2e9949a8",
+					"fsName":    "myfs",
+					"csi.storage.k8s.io/provisioner-secret-name":            "csi-cephfs-secret",
+					"csi.storage.k8s.io/provisioner-secret-namespace":       "rbd-system",
+					"csi.storage.k8s.io/controller-expand-secret-name":      "csi-cephfs-secret",
+					"csi.storage.k8s.io/controller-expand-secret-namespace": "rbd-system",
+					"csi.storage.k8s.io/node-stage-secret-name":             "csi-cephfs-secret",
+					"csi.storage.k8s.io/node-stage-secret-namespace":        "rbd-system",
+				},
+			},
+		},
+		{
+			name:    "cephfs without fsName",
+			preset:  rainbondv1alpha1.StoragePreset{CephFS: &rainbondv1alpha1.CephFSPreset{ClusterID: "ceph", SecretName: "csi-cephfs-secret"}},
+			wantErr: true,
+		},
+		{
+			name:   "longhorn",
+			preset: rainbondv1alpha1.StoragePreset{Longhorn: &rainbondv1alpha1.LonghornPreset{}},
+			want: &rainbondv1alpha1.StorageClassParameters{
+				Provisioner: longhornProvisioner,
+				Parameters:  map[string]string{"numberOfReplicas": "3", "staleReplicaTimeout": "2880"},
+			},
+		},
+		{
+			name:   "longhorn with replicas",
+			preset: rainbondv1alpha1.StoragePreset{Longhorn: &rainbondv1alpha1.LonghornPreset{NumberOfReplicas: &replicas}},
+			want: &rainbondv1alpha1.StorageClassParameters{
+				Provisioner: longhornProvisioner,
+				Parameters:  map[string]string{"numberOfReplicas": "2", "staleReplicaTimeout": "2880"},
+			},
+		},
+		{
+			name:   "juicefs in another namespace",
+			preset: rainbondv1alpha1.StoragePreset{JuiceFS: &rainbondv1alpha1.JuiceFSPreset{SecretName: "juicefs-secret", SecretNamespace: "kube-system"}},
+			want: &rainbondv1alpha1.StorageClassParameters{
+				Provisioner: juiceFSProvisioner,
+				Parameters: map[string]string{
+					"csi.storage.k8s.io/provisioner-secret-name":       "juicefs-secret",
+					"csi.storage.k8s.io/provisioner-secret-namespace":  "kube-system",
+					"csi.storage.k8s.io/node-publish-secret-name":      "juicefs-secret",
+					"csi.storage.k8s.io/node-publish-secret-namespace": "kube-system",
+				},
+			},
+		},
+		{
+			name:    "juicefs without secret",
+			preset:  rainbondv1alpha1.StoragePreset{JuiceFS: &rainbondv1alpha1.JuiceFSPreset{}},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			volume := &rainbondv1alpha1.RainbondVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "rainbondvolumerwx", Namespace: "rbd-system"},
+				Spec:       rainbondv1alpha1.RainbondVolumeSpec{Preset: &tc.preset},
+			}
+			got, err := presetStorageClassParameters(volume)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
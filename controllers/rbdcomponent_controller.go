@@ -18,33 +18,73 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	componentmgr "github.com/goodrain/rainbond-operator/controllers/component-mgr"
 	chandler "github.com/goodrain/rainbond-operator/controllers/handler"
 	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/operatorconfig"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// maxConsecutiveFailures is the retry budget of a component, the reconciliation is halted once it is exhausted
+	// and the component has not been ready for failureWindow.
+	maxConsecutiveFailures = 10
+	// failureWindow is longer than the rollouts of the components, which are not ready for a while as well.
+	failureWindow = 10 * time.Minute
+	// dependencyCheckInterval is how often the dependencies that are not ready are checked again.
+	dependencyCheckInterval = 3 * time.Second
+	// healthCheckInterval is how often the health endpoints of the ready components are probed, a pod may be ready
+	// but not serve any more, which is not observed by the watches.
+	healthCheckInterval = time.Minute
+)
+
+// readinessFailures are the consecutive readiness checks that a component failed.
+type readinessFailures struct {
+	count int
+	// since is the time of the first failure.
+	since time.Time
+}
+
 // RbdComponentReconciler reconciles a RbdComponent object
 type RbdComponentReconciler struct {
 	client.Client
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Settings are the settings of the operator, the defaults are used if nil.
+	Settings *operatorconfig.Settings
+	// Handlers are the handlers of the rbdcomponents, handler.DefaultRegistry is used if nil.
+	Handlers *chandler.Registry
+
+	// failures counts the consecutive failed readiness checks of the components.
+	failures   map[types.NamespacedName]*readinessFailures
+	failuresMu sync.Mutex
 }
 
 // +kubebuilder:rbac:groups=rainbond.io,resources=rbdcomponents,verbs=get;list;watch;create;update;patch;delete
@@ -73,8 +113,8 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 		if k8sErrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
-			// Return and don't requeue
-			return reconcile.Result{}, nil
+			// Return and don't requeue, unless it is a mandatory component to be recreated.
+			return r.recreate(ctx, request.NamespacedName)
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{Requeue: true}, err
@@ -82,10 +122,32 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 
 	mgr := componentmgr.NewRbdcomponentMgr(ctx, r.Client, r.Recorder, log, cpt)
 
-	fn, ok := handlerFuncs[cpt.Name]
+	if !cpt.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, r.finalize(ctx, mgr, cpt)
+	}
+	if !hasFinalizer(cpt, constants.RbdComponentFinalizer) {
+		controllerutil.AddFinalizer(cpt, constants.RbdComponentFinalizer)
+		if err := r.Update(ctx, cpt); err != nil {
+			return reconcile.Result{}, fmt.Errorf("add finalizer: %v", err)
+		}
+	}
+
+	if cpt.Status.IsHalted() {
+		resumed, err := r.resume(ctx, mgr, cpt)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !resumed {
+			log.V(6).Info("the reconciliation is halted, change the spec or annotate with " + constants.ResumeAnnotation + " to resume")
+			return reconcile.Result{}, nil
+		}
+	}
+
+	handlers := r.handlers()
+	fn, ok := handlers.Get(cpt.Name)
 	if !ok {
-		reason := "UnsupportedType"
-		msg := fmt.Sprintf("only supports the following types of rbdcomponent: %s", supportedComponents())
+		reason := rainbondv1alpha1.ReasonUnsupportedType
+		msg := fmt.Sprintf("only supports the following types of rbdcomponent: %s", strings.Join(handlers.Names(), ","))
 
 		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse, reason, msg)
 		changed := cpt.Status.UpdateCondition(condition)
@@ -110,7 +172,7 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 	if !cluster.Spec.ConfigCompleted {
 		log.V(6).Info("rainbondcluster configuration is not complete")
 		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.ClusterConfigCompeleted,
-			corev1.ConditionFalse, "ConfigNotCompleted", "rainbondcluster configuration is not complete")
+			corev1.ConditionFalse, rainbondv1alpha1.ReasonConfigNotCompleted, "rainbondcluster configuration is not complete")
 		changed := cpt.Status.UpdateCondition(condition)
 		if changed {
 			r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -138,7 +200,7 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 
 	if !mgr.CheckPrerequisites(cluster, pkg) {
 		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse,
-			"PrerequisitesFailed", "failed to check prerequisites")
+			rainbondv1alpha1.ReasonPrerequisitesFailed, "failed to check prerequisites")
 		changed := cpt.Status.UpdateCondition(condition)
 		if changed {
 			r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -155,14 +217,7 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 		} else {
 			log.V(6).Info("checking the prerequisites", "msg", err.Error())
 		}
-
-		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse, "PrerequisitesFailed", err.Error())
-		changed := cpt.Status.UpdateCondition(condition)
-		if changed {
-			r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
-			return reconcile.Result{RequeueAfter: 3 * time.Second}, mgr.UpdateStatus()
-		}
-		return reconcile.Result{RequeueAfter: 3 * time.Second}, nil
+		return r.handleError(mgr, cpt, rainbondv1alpha1.ReasonPrerequisitesFailed, err)
 	}
 
 	resourcesDeleter, ok := hdl.(chandler.ResourcesDeleter)
@@ -188,7 +243,7 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 			if err := controllerutil.SetControllerReference(cpt, res.(metav1.Object), r.Scheme); err != nil {
 				log.Error(err, "set controller reference")
 				condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady,
-					corev1.ConditionFalse, "SetControllerReferenceFailed", err.Error())
+					corev1.ConditionFalse, rainbondv1alpha1.ReasonSetControllerReferenceFailed, err.Error())
 				changed := cpt.Status.UpdateCondition(condition)
 				if changed {
 					r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -196,10 +251,11 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 				}
 				return reconcile.Result{}, err
 			}
+			chandler.SetAdditionalMetadata(res, cluster)
 			if err := mgr.ResourceCreateIfNotExists(res); err != nil {
 				log.Error(err, "create resouce if not exists")
 				condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady,
-					corev1.ConditionFalse, "ErrCreateResources", err.Error())
+					corev1.ConditionFalse, rainbondv1alpha1.ReasonCreateResourcesFailed, err.Error())
 				changed := cpt.Status.UpdateCondition(condition)
 				if changed {
 					r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -219,10 +275,11 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 				continue
 			}
 
+			chandler.SetAdditionalMetadata(res, cluster)
 			if err := mgr.ResourceCreateIfNotExists(res); err != nil {
 				log.Error(err, "create resouce if not exists")
 				condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady,
-					corev1.ConditionFalse, "ErrCreateResources", err.Error())
+					corev1.ConditionFalse, rainbondv1alpha1.ReasonCreateResourcesFailed, err.Error())
 				changed := cpt.Status.UpdateCondition(condition)
 				if changed {
 					r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -239,15 +296,57 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 	}
 
 	resources := hdl.Resources()
+	var dependencies []chandler.Dependency
+	if dependenter, ok := hdl.(chandler.Dependenter); ok {
+		dependencies = dependenter.Dependencies()
+	}
+	// expand the claims before updating the statefulsets, whose claim templates are immutable.
+	var storageResizing bool
+	for _, res := range resources {
+		sts, ok := res.(*appsv1.StatefulSet)
+		if !ok || len(sts.Spec.VolumeClaimTemplates) == 0 {
+			continue
+		}
+		resizing, err := mgr.ExpandVolumes(sts)
+		if err != nil {
+			log.Error(err, "expand volumes")
+			condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.StorageResized, corev1.ConditionFalse, rainbondv1alpha1.ReasonExpandVolumesFailed, err.Error())
+			changed := cpt.Status.UpdateCondition(condition)
+			if changed {
+				r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
+				return reconcile.Result{Requeue: true}, mgr.UpdateStatus()
+			}
+			return reconcile.Result{}, err
+		}
+		storageResizing = storageResizing || resizing
+	}
+
+	// pin the pods to the nodes of the architectures supported by the image.
+	architectures, err := mgr.Architectures(cluster)
+	if err != nil {
+		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse,
+			rainbondv1alpha1.ReasonNoCompatibleNodes, err.Error())
+		changed := cpt.Status.UpdateCondition(condition)
+		if changed {
+			r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
+			return reconcile.Result{RequeueAfter: 30 * time.Second}, mgr.UpdateStatus()
+		}
+		return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	// run the fallback image if the image can't be pulled.
+	imageFallback := mgr.ImageFallback(cluster)
+
 	for _, res := range resources {
 		if res == nil {
 			continue
 		}
+		mgr.Render(res, cluster, dependencies, imageFallback, architectures)
 		// Set RbdComponent cpt as the owner and controller
 		if err := controllerutil.SetControllerReference(cpt, res.(metav1.Object), r.Scheme); err != nil {
 			log.Error(err, "set controller reference")
 			condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse,
-				"SetControllerReferenceFailed", err.Error())
+				rainbondv1alpha1.ReasonSetControllerReferenceFailed, err.Error())
 			changed := cpt.Status.UpdateCondition(condition)
 			if changed {
 				r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -256,35 +355,21 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 			return reconcile.Result{}, err
 		}
 		// Check if the resource already exists, if not create a new one
-		reconcileResult, err := mgr.UpdateOrCreateResource(res)
-		if err != nil {
+		if _, err := mgr.UpdateOrCreateResource(res); err != nil {
 			log.Error(err, "update or create resource")
-			condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse, "ErrCreateResources", err.Error())
-			changed := cpt.Status.UpdateCondition(condition)
-			if changed {
-				r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
-				return reconcile.Result{Requeue: true}, mgr.UpdateStatus()
-			}
-			return reconcileResult, err
+			return r.handleError(mgr, cpt, rainbondv1alpha1.ReasonCreateResourcesFailed, err)
 		}
 	}
 
 	if err := hdl.After(); err != nil {
 		log.Error(err, "failed to execute after process")
-		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse,
-			"ErrAfterProcess", err.Error())
-		changed := cpt.Status.UpdateCondition(condition)
-		if changed {
-			r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
-			return reconcile.Result{Requeue: true}, mgr.UpdateStatus()
-		}
-		return reconcile.Result{Requeue: true}, err
+		return r.handleError(mgr, cpt, rainbondv1alpha1.ReasonAfterProcessFailed, err)
 	}
 
 	pods, err := hdl.ListPods()
 	if err != nil {
 		condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse,
-			"ErrListPods", err.Error())
+			rainbondv1alpha1.ReasonListPodsFailed, err.Error())
 		changed := cpt.Status.UpdateCondition(condition)
 		if changed {
 			r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
@@ -294,30 +379,471 @@ func (r *RbdComponentReconciler) Reconcile(ctx context.Context, request ctrl.Req
 	}
 
 	mgr.GenerateStatus(pods)
+	if mgr.FallBackImage(pods) {
+		// update the workloads with the fallback image right away.
+		return reconcile.Result{Requeue: true}, mgr.UpdateStatus()
+	}
+	var conditions []*rainbondv1alpha1.RbdComponentCondition
+	if reporter, ok := hdl.(chandler.ConditionReporter); ok {
+		conditions = append(conditions, reporter.Conditions()...)
+	}
+	healthChecker, healthChecked := hdl.(chandler.HealthChecker)
+	if healthChecked {
+		conditions = append(conditions, chandler.HealthCondition(ctx, pods, healthChecker.HealthEndpoint()))
+	}
+	var conditionPending bool
+	for _, condition := range conditions {
+		if cpt.Status.UpdateCondition(condition) && condition.Status == corev1.ConditionFalse {
+			r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
+		}
+		conditionPending = conditionPending || condition.Status != corev1.ConditionTrue
+	}
 
 	if err := mgr.UpdateStatus(); err != nil {
 		log.Error(err, "update rainbond component status failure %s")
 	}
 
-	if !mgr.IsRbdComponentReady() {
+	// the errors of the reconciliation are retried with backoff, only the readiness is counted.
+	ready := mgr.IsRbdComponentReady()
+	r.recordResult(mgr, cpt, !ready)
+	if !ready {
 		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
 	}
+	if storageResizing {
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	if conditionPending {
+		// the conditions are not observed by the watches, check them until they are all true.
+		return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+	if cpt.Status.ImageFallback != nil {
+		// check whether the image is available again.
+		return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+	}
+	if healthChecked {
+		return reconcile.Result{RequeueAfter: healthCheckInterval}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// handleError reports the error of the reconciliation in the Ready condition, and decides how to retry it by the kind
+// of the error: the retryable errors are returned to be retried with backoff, the dependencies are checked again
+// after a while, and the permanent errors stop the reconciliation until the component or the cluster is changed.
+func (r *RbdComponentReconciler) handleError(mgr *componentmgr.RbdcomponentMgr, cpt *rainbondv1alpha1.RbdComponent, reason string, err error) (reconcile.Result, error) {
+	kind := chandler.ErrorKindOf(err)
+	if kind == chandler.ErrorKindPermanent {
+		reason = rainbondv1alpha1.ReasonInvalidSpec
+	}
+	condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentReady, corev1.ConditionFalse, reason, err.Error())
+	if cpt.Status.UpdateCondition(condition) {
+		r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
+		if err := mgr.UpdateStatus(); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	switch kind {
+	case chandler.ErrorKindPermanent:
+		return reconcile.Result{}, nil
+	case chandler.ErrorKindWaitForDependency:
+		return reconcile.Result{RequeueAfter: dependencyCheckInterval}, nil
+	}
+	return reconcile.Result{}, err
+}
+
+func (r *RbdComponentReconciler) handlers() *chandler.Registry {
+	if r.Handlers != nil {
+		return r.Handlers
+	}
+	return chandler.DefaultRegistry
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *RbdComponentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(r.Settings.ControllerOptions()).
+		WithEventFilter(r.Settings.NamespacePredicate()).
 		For(&rainbondv1alpha1.RbdComponent{}).
+		// the components are stopped or restarted during the migration of the shared storage,
+		// and the suspension of the region. The deleted mandatory components are recreated once they are recorded,
+		// or the operator starts.
+		Watches(&source.Kind{Type: &rainbondv1alpha1.RainbondCluster{}}, handler.EnqueueRequestsFromMapFunc(r.componentsForCluster),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc: func(e event.CreateEvent) bool {
+					return e.Object.GetAnnotations()[constants.RecreateComponentsAnnotation] != ""
+				},
+				DeleteFunc:  func(event.DeleteEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					old, new := e.ObjectOld.(*rainbondv1alpha1.RainbondCluster), e.ObjectNew.(*rainbondv1alpha1.RainbondCluster)
+					// the components stopped by the invalid spec of the cluster are reconciled once it is changed.
+					return old.Generation != new.Generation || sharedStorageChanged(old, new) || suspensionChanged(old, new) ||
+						old.Annotations[constants.RecreateComponentsAnnotation] != new.Annotations[constants.RecreateComponentsAnnotation]
+				},
+			})).
+		// the garbage collection of the image hub starts once its job is created by the CronJob, and the image hub
+		// is writable again once the job is finished.
+		Watches(&source.Kind{Type: &batchv1.Job{}}, handler.EnqueueRequestsFromMapFunc(hubForGCJob),
+			builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return obj.GetLabels()["name"] == chandler.HubGCName
+			}))).
 		Complete(r)
 }
 
+// hubForGCJob returns the request of the image hub in the namespace of the job of the garbage collection.
+func hubForGCJob(obj client.Object) []reconcile.Request {
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: chandler.HubName}},
+	}
+}
+
+// sharedStorageChanged returns true if the components using the shared storage should be stopped or restarted.
+func sharedStorageChanged(old, new *rainbondv1alpha1.RainbondCluster) bool {
+	oldMaintenance, _ := old.InStorageMaintenance()
+	newMaintenance, _ := new.InStorageMaintenance()
+	claimName := func(cluster *rainbondv1alpha1.RainbondCluster) string {
+		if cluster.Status.SharedStorage == nil {
+			return ""
+		}
+		return cluster.Status.SharedStorage.ClaimName
+	}
+	return oldMaintenance != newMaintenance || claimName(old) != claimName(new)
+}
+
+// suspensionChanged returns true if some components should be stopped or started for the suspension of the region.
+func suspensionChanged(old, new *rainbondv1alpha1.RainbondCluster) bool {
+	stopped := func(cluster *rainbondv1alpha1.RainbondCluster) []string {
+		if cluster.Status.Suspension == nil {
+			return nil
+		}
+		return cluster.Status.Suspension.Stopped
+	}
+	return strings.Join(stopped(old), ",") != strings.Join(stopped(new), ",")
+}
+
+// componentsForCluster returns the requests of the rbdcomponents in the namespace of the rainbondcluster,
+// and the ones to be recreated.
+func (r *RbdComponentReconciler) componentsForCluster(obj client.Object) []reconcile.Request {
+	cpts := &rainbondv1alpha1.RbdComponentList{}
+	if err := r.List(context.Background(), cpts, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "list rbdcomponents")
+		return nil
+	}
+	var requests []reconcile.Request
+	existing := make(map[string]bool)
+	for _, cpt := range cpts.Items {
+		existing[cpt.Name] = true
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: cpt.Namespace, Name: cpt.Name},
+		})
+	}
+	if cluster, ok := obj.(*rainbondv1alpha1.RainbondCluster); ok {
+		recreations, err := recreationsOf(cluster)
+		if err != nil {
+			r.Log.Error(err, "read the rbdcomponents to be recreated")
+		}
+		for name := range recreations {
+			if !existing[name] {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: cluster.Namespace, Name: name},
+				})
+			}
+		}
+	}
+	return requests
+}
+
+// finalize deletes the resources generated for the component, then removes the finalizer so that it can be deleted.
+// The persistent volume claims are kept if the component is annotated with rainbond.io/preserve-volumes=true,
+// or the component is mandatory and will be recreated.
+// If the namespace is being deleted, only the cluster-scoped resources are deleted in the best effort, so that
+// the finalizer never blocks the deletion of the namespace.
+func (r *RbdComponentReconciler) finalize(ctx context.Context, mgr *componentmgr.RbdcomponentMgr, cpt *rainbondv1alpha1.RbdComponent) error {
+	if !hasFinalizer(cpt, constants.RbdComponentFinalizer) {
+		return nil
+	}
+
+	terminating, err := r.namespaceTerminating(ctx, cpt.Namespace)
+	if err != nil {
+		return err
+	}
+	if terminating {
+		if err := mgr.CleanupClusterScopedResources(); err != nil {
+			r.Log.Error(err, "cleanup the cluster-scoped resources", "rbdcomponent", cpt.Name)
+		}
+		return r.removeFinalizer(ctx, cpt)
+	}
+
+	recreate, err := r.shouldRecreate(ctx, cpt)
+	if err != nil {
+		return err
+	}
+	// the volumes are always kept for the recreated component, the other resources will be generated again.
+	preserveVolumes := recreate || cpt.Annotations[constants.PreserveVolumesAnnotation] == "true"
+	if err := mgr.CleanupResources(preserveVolumes); err != nil {
+		r.Recorder.Event(cpt, corev1.EventTypeWarning, rainbondv1alpha1.ReasonDeleteResourceFailed, err.Error())
+		return fmt.Errorf("cleanup resources: %v", err)
+	}
+	if recreate {
+		// recorded before the component is gone, so that it is recreated even if the operator restarts meanwhile.
+		err := r.updateRecreations(ctx, cpt.Namespace, func(recreations map[string]*rainbondv1alpha1.RbdComponent) {
+			recreations[cpt.Name] = recreation(cpt)
+		})
+		if err != nil {
+			return fmt.Errorf("record the recreation: %v", err)
+		}
+		r.Recorder.Event(cpt, corev1.EventTypeWarning, rainbondv1alpha1.ReasonRecreating,
+			"the component is mandatory, it will be recreated according to the componentDeletionPolicy of the rainbondcluster")
+	}
+
+	if err := r.removeFinalizer(ctx, cpt); err != nil {
+		return err
+	}
+	if recreate {
+		r.Log.Info("the mandatory component is deleted, waiting to recreate it", "rbdcomponent", cpt.Name)
+		return nil
+	}
+	r.Log.Info("the generated resources are deleted", "rbdcomponent", cpt.Name, "preserveVolumes", preserveVolumes)
+	return nil
+}
+
+// removeFinalizer removes the finalizer so that the component is deleted, and forgets its failures.
+func (r *RbdComponentReconciler) removeFinalizer(ctx context.Context, cpt *rainbondv1alpha1.RbdComponent) error {
+	controllerutil.RemoveFinalizer(cpt, constants.RbdComponentFinalizer)
+	if err := r.Update(ctx, cpt); err != nil {
+		return fmt.Errorf("remove finalizer: %v", err)
+	}
+	r.failuresMu.Lock()
+	delete(r.failures, types.NamespacedName{Namespace: cpt.Namespace, Name: cpt.Name})
+	r.failuresMu.Unlock()
+	return nil
+}
+
+// namespaceTerminating returns true if the namespace is being deleted, or already gone.
+func (r *RbdComponentReconciler) namespaceTerminating(ctx context.Context, name string) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("get namespace: %v", err)
+	}
+	return !ns.DeletionTimestamp.IsZero(), nil
+}
+
+// shouldRecreate returns true if the deleted component is mandatory and
+// the componentDeletionPolicy of the rainbondcluster is Recreate.
+func (r *RbdComponentReconciler) shouldRecreate(ctx context.Context, cpt *rainbondv1alpha1.RbdComponent) (bool, error) {
+	cluster := &rainbondv1alpha1.RainbondCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: cpt.Namespace, Name: constants.RainbondClusterName}, cluster); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			// the whole region is being uninstalled.
+			return false, nil
+		}
+		return false, fmt.Errorf("get rainbondcluster: %v", err)
+	}
+	if !cluster.DeletionTimestamp.IsZero() || cluster.Spec.ComponentDeletionPolicy != rainbondv1alpha1.ComponentDeletionRecreate {
+		return false, nil
+	}
+	return mandatoryComponent(cluster, cpt.Name), nil
+}
+
+// mandatoryComponent returns true if the region does not work without the component.
+func mandatoryComponent(cluster *rainbondv1alpha1.RainbondCluster, name string) bool {
+	switch name {
+	case chandler.APIName, chandler.WorkerName, chandler.ChaosName, chandler.GatewayName,
+		chandler.NodeName, chandler.MQName, chandler.EventLogName:
+		return true
+	case chandler.DBName:
+		return cluster.Spec.RegionDatabase == nil
+	case chandler.EtcdName:
+		return cluster.Spec.EtcdConfig == nil
+	}
+	return false
+}
+
+// recreation returns the component to be recreated from the deleted one, with the same spec, labels and owners.
+func recreation(cpt *rainbondv1alpha1.RbdComponent) *rainbondv1alpha1.RbdComponent {
+	return &rainbondv1alpha1.RbdComponent{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       cpt.Namespace,
+			Name:            cpt.Name,
+			Labels:          cpt.Labels,
+			Annotations:     cpt.Annotations,
+			OwnerReferences: cpt.OwnerReferences,
+		},
+		Spec: *cpt.Spec.DeepCopy(),
+	}
+}
+
+// recreate recreates the mandatory component once its deletion completes.
+// The components to be recreated are recorded in the annotation of the rainbondcluster, and removed from it once
+// they are recreated.
+func (r *RbdComponentReconciler) recreate(ctx context.Context, key types.NamespacedName) (reconcile.Result, error) {
+	cluster := &rainbondv1alpha1.RainbondCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: constants.RainbondClusterName}, cluster); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if !cluster.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	recreations, err := recreationsOf(cluster)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	cpt, ok := recreations[key.Name]
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+	if err := r.Create(ctx, cpt); err != nil {
+		if k8sErrors.IsAlreadyExists(err) {
+			// the deletion is not completed yet.
+			return reconcile.Result{RequeueAfter: time.Second}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("recreate rbdcomponent: %v", err)
+	}
+	err = r.updateRecreations(ctx, key.Namespace, func(recreations map[string]*rainbondv1alpha1.RbdComponent) {
+		delete(recreations, key.Name)
+	})
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("remove the recreation: %v", err)
+	}
+	r.Log.Info("the mandatory component is recreated", "rbdcomponent", key)
+	return reconcile.Result{}, nil
+}
+
+// updateRecreations updates the components to be recreated in the annotation of the rainbondcluster.
+func (r *RbdComponentReconciler) updateRecreations(ctx context.Context, namespace string, update func(recreations map[string]*rainbondv1alpha1.RbdComponent)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster := &rainbondv1alpha1.RainbondCluster{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: constants.RainbondClusterName}, cluster); err != nil {
+			return err
+		}
+		recreations, err := recreationsOf(cluster)
+		if err != nil {
+			return err
+		}
+		update(recreations)
+		if len(recreations) == 0 {
+			delete(cluster.Annotations, constants.RecreateComponentsAnnotation)
+		} else {
+			data, err := json.Marshal(recreations)
+			if err != nil {
+				return err
+			}
+			if cluster.Annotations == nil {
+				cluster.Annotations = make(map[string]string)
+			}
+			cluster.Annotations[constants.RecreateComponentsAnnotation] = string(data)
+		}
+		return r.Update(ctx, cluster)
+	})
+}
+
+// recreationsOf returns the components to be recreated recorded in the annotation of the rainbondcluster, by names.
+func recreationsOf(cluster *rainbondv1alpha1.RainbondCluster) (map[string]*rainbondv1alpha1.RbdComponent, error) {
+	recreations := make(map[string]*rainbondv1alpha1.RbdComponent)
+	value := cluster.Annotations[constants.RecreateComponentsAnnotation]
+	if value == "" {
+		return recreations, nil
+	}
+	if err := json.Unmarshal([]byte(value), &recreations); err != nil {
+		return nil, fmt.Errorf("decode annotation %s: %v", constants.RecreateComponentsAnnotation, err)
+	}
+	return recreations, nil
+}
+
+func hasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// recordResult records the result of the readiness check, and halts the component once it failed
+// maxConsecutiveFailures times in a row, and has not been ready for failureWindow.
+func (r *RbdComponentReconciler) recordResult(mgr *componentmgr.RbdcomponentMgr, cpt *rainbondv1alpha1.RbdComponent, failed bool) {
+	key := types.NamespacedName{Namespace: cpt.Namespace, Name: cpt.Name}
+	r.failuresMu.Lock()
+	defer r.failuresMu.Unlock()
+	if r.failures == nil {
+		r.failures = make(map[types.NamespacedName]*readinessFailures)
+	}
+	if !failed {
+		delete(r.failures, key)
+		return
+	}
+	failures, ok := r.failures[key]
+	if !ok {
+		failures = &readinessFailures{since: time.Now()}
+		r.failures[key] = failures
+	}
+	failures.count++
+	if failures.count < maxConsecutiveFailures || time.Since(failures.since) < failureWindow {
+		return
+	}
+	delete(r.failures, key)
+
+	msg := fmt.Sprintf("halted after %d consecutive readiness failures in %s", failures.count, time.Since(failures.since).Round(time.Second))
+	if cause := rootCause(&cpt.Status); cause != nil {
+		msg += fmt.Sprintf(", the last one is %s: %s", cause.Reason, cause.Message)
+	}
+	msg += fmt.Sprintf("; change the spec or annotate with %s to resume", constants.ResumeAnnotation)
+	condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentHalted, corev1.ConditionTrue,
+		rainbondv1alpha1.ReasonRetryBudgetExhausted, msg)
+	cpt.Status.UpdateCondition(condition)
+	cpt.Status.HaltedGeneration = cpt.Generation
+	r.Recorder.Event(cpt, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	if err := mgr.UpdateStatus(); err != nil {
+		r.Log.Error(err, "halt the reconciliation", "rbdcomponent", key)
+	}
+}
+
+// resume resumes the reconciliation of the halted component if its spec is changed,
+// or it is annotated with rainbond.io/resume, which is removed then.
+func (r *RbdComponentReconciler) resume(ctx context.Context, mgr *componentmgr.RbdcomponentMgr, cpt *rainbondv1alpha1.RbdComponent) (bool, error) {
+	reason := rainbondv1alpha1.ReasonSpecChanged
+	msg := "the spec is changed"
+	if _, ok := cpt.Annotations[constants.ResumeAnnotation]; ok {
+		delete(cpt.Annotations, constants.ResumeAnnotation)
+		if err := r.Update(ctx, cpt); err != nil {
+			return false, fmt.Errorf("remove annotation %s: %v", constants.ResumeAnnotation, err)
+		}
+		reason = rainbondv1alpha1.ReasonResumed
+		msg = "resumed by annotation " + constants.ResumeAnnotation
+	} else if cpt.Generation == cpt.Status.HaltedGeneration {
+		return false, nil
+	}
+
+	condition := rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RbdComponentHalted, corev1.ConditionFalse, reason, msg)
+	cpt.Status.UpdateCondition(condition)
+	cpt.Status.HaltedGeneration = 0
+	r.Recorder.Event(cpt, corev1.EventTypeNormal, condition.Reason, condition.Message)
+	return true, mgr.UpdateStatus()
+}
+
+// rootCause returns the failed condition which transitioned lastly.
+func rootCause(status *rainbondv1alpha1.RbdComponentStatus) *rainbondv1alpha1.RbdComponentCondition {
+	var cause *rainbondv1alpha1.RbdComponentCondition
+	for i := range status.Conditions {
+		condition := &status.Conditions[i]
+		if condition.Status != corev1.ConditionFalse || condition.Type == rainbondv1alpha1.RbdComponentHalted {
+			continue
+		}
+		if cause == nil || !condition.LastTransitionTime.Before(&cause.LastTransitionTime) {
+			cause = condition
+		}
+	}
+	return cause
+}
+
 func clusterCondition(err error) *rainbondv1alpha1.RbdComponentCondition {
-	reason := "ClusterNotFound"
+	reason := rainbondv1alpha1.ReasonClusterNotFound
 	msg := "rainbondcluster not found"
 	if !k8sErrors.IsNotFound(err) {
-		reason = "UnknownErr"
+		reason = rainbondv1alpha1.ReasonUnknownError
 		msg = fmt.Sprintf("failed to get rainbondcluster: %v", err)
 	}
 
@@ -325,10 +851,10 @@ func clusterCondition(err error) *rainbondv1alpha1.RbdComponentCondition {
 }
 
 func packageCondition(err error) *rainbondv1alpha1.RbdComponentCondition {
-	reason := "PackageNotFound"
+	reason := rainbondv1alpha1.ReasonPackageNotFound
 	msg := "rainbondpackage not found"
 	if !k8sErrors.IsNotFound(err) {
-		reason = "UnknownErr"
+		reason = rainbondv1alpha1.ReasonUnknownError
 		msg = fmt.Sprintf("failed to get rainbondpackage: %v", err)
 	}
 	return rainbondv1alpha1.NewRbdComponentCondition(rainbondv1alpha1.RainbondPackageReady, corev1.ConditionFalse, reason, msg)
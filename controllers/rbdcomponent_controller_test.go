@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	componentmgr "github.com/goodrain/rainbond-operator/controllers/component-mgr"
+	chandler "github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestHandleError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantResult reconcile.Result
+		wantErr    bool
+		wantReason string
+	}{
+		{
+			name:       "permanent",
+			err:        chandler.NewPermanentError("invalid sysctls"),
+			wantReason: rainbondv1alpha1.ReasonInvalidSpec,
+		},
+		{
+			name:       "wait for dependency",
+			err:        chandler.NewWaitForDependencyError("storage class not ready"),
+			wantResult: reconcile.Result{RequeueAfter: dependencyCheckInterval},
+			wantReason: rainbondv1alpha1.ReasonPrerequisitesFailed,
+		},
+		{
+			name:       "retryable",
+			err:        errors.New("failed to get etcd secret"),
+			wantErr:    true,
+			wantReason: rainbondv1alpha1.ReasonPrerequisitesFailed,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			cpt := &rainbondv1alpha1.RbdComponent{
+				ObjectMeta: metav1.ObjectMeta{Name: "rbd-gateway", Namespace: "rbd-system"},
+			}
+			r := &RbdComponentReconciler{
+				Client:   fake.NewFakeClientWithScheme(scheme, cpt),
+				Log:      logr.Discard(),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+			ctx := context.Background()
+			mgr := componentmgr.NewRbdcomponentMgr(ctx, r.Client, r.Recorder, logr.Discard(), cpt)
+
+			result, err := r.handleError(mgr, cpt, rainbondv1alpha1.ReasonPrerequisitesFailed, tc.err)
+			assert.Equal(t, tc.wantResult, result)
+			assert.Equal(t, tc.wantErr, err != nil)
+
+			got := &rainbondv1alpha1.RbdComponent{}
+			if err := r.Get(ctx, types.NamespacedName{Namespace: "rbd-system", Name: "rbd-gateway"}, got); err != nil {
+				t.Fatal(err)
+			}
+			_, condition := got.Status.GetCondition(rainbondv1alpha1.RbdComponentReady)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, tc.wantReason, condition.Reason)
+				assert.Equal(t, tc.err.Error(), condition.Message)
+			}
+		})
+	}
+}
@@ -27,10 +27,13 @@ require (
 	github.com/opencontainers/image-spec v1.0.1 // indirect
 	github.com/pquerna/ffjson v0.0.0-20190930134022-aa0246cd15f7
 	github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring v0.50.0
+	github.com/prometheus/client_golang v1.7.1
 	github.com/satori/go.uuid v1.2.0 // indirect
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.6.1
 	github.com/twinj/uuid v1.0.0
+	go.uber.org/zap v1.15.0
+	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	gopkg.in/stretchr/testify.v1 v1.2.2 // indirect
 	k8s.io/api v0.20.1
 	k8s.io/apimachinery v0.20.1
@@ -17,23 +17,44 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "github.com/go-sql-driver/mysql"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	kubeaggregatorv1beta1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	rainbondiov1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/controllers"
+	"github.com/goodrain/rainbond-operator/controllers/migration"
+	"github.com/goodrain/rainbond-operator/offline"
+	"github.com/goodrain/rainbond-operator/openapi"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/logutil"
+	"github.com/goodrain/rainbond-operator/util/operatorconfig"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
 	mv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	// +kubebuilder:scaffold:imports
 )
@@ -55,11 +76,47 @@ func init() {
 }
 
 func main() {
+	// the subcommands run without the manager.
+	if len(os.Args) > 1 && os.Args[1] == offline.Command {
+		os.Exit(offline.Main(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var openapiAddr string
+	var openapiAllowedOrigins string
+	var openapiCertFile, openapiKeyFile string
+	var openapiShutdownTimeout, gracefulShutdownTimeout time.Duration
+	var rainbondNamespace string
+	var singleNamespace bool
+	var serviceAccountName string
+	var logFormat string
+	var logLevel int
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&openapiAddr, "openapi-bind-address", ":8082", "The address the openapi endpoint binds to.")
+	flag.StringVar(&openapiAllowedOrigins, "openapi-allowed-origins", "", "The comma separated origins allowed to call the openapi from the browsers.")
+	flag.StringVar(&openapiCertFile, "openapi-tls-cert-file", "", "The certificate file the openapi is served with over https, which is reloaded once it is rotated. "+
+		"The openapi is served over http if it is empty.")
+	flag.StringVar(&openapiKeyFile, "openapi-tls-key-file", "", "The private key file of --openapi-tls-cert-file.")
+	flag.DurationVar(&openapiShutdownTimeout, "openapi-shutdown-timeout", 20*time.Second,
+		"How long the in-flight requests of the openapi are waited for on shutdown.")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 30*time.Second,
+		"How long the controllers and the servers are waited for to stop on shutdown, which should be longer than --openapi-shutdown-timeout, "+
+			"and shorter than the termination grace period of the pod.")
+	flag.StringVar(&rainbondNamespace, "rainbond-namespace", constants.Namespace, "The namespace where the rainbond cluster is installed.")
+	flag.BoolVar(&singleNamespace, "single-namespace", false,
+		"Manage the rainbond cluster in --rainbond-namespace only, so that the rainbond clusters in the other namespaces "+
+			"can be managed by their own operators. The RainbondOperatorConfig is named after the namespace, "+
+			"and only the objects in the namespace are cached then.")
+	flag.StringVar(&serviceAccountName, "service-account-name", os.Getenv("SERVICE_ACCOUNT_NAME"),
+		"The service account of the operator, which is used by the components without their own. "+
+			"Defaults to SERVICE_ACCOUNT_NAME, or "+constants.ServiceAccountName+".")
+	flag.StringVar(&logFormat, "log-format", os.Getenv("LOG_FORMAT"), "The format of the logs, console or json. Defaults to LOG_FORMAT, or the encoder given by --zap-encoder.")
+	flag.IntVar(&logLevel, "log-level", 0, "The default verbosity of the logs, the larger the more verbose. "+
+		"Defaults to LOG_LEVEL, or the level given by --zap-log-level. "+
+		"The verbosities can be adjusted per controller at runtime by the ConfigMap "+constants.LogConfigMapName+".")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -69,25 +126,73 @@ func main() {
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	verbosity := logVerbosity(logLevel, logutil.VerbosityOf(opts.Level))
+	logOpts := []zap.Opts{zap.UseFlagOptions(&opts), zap.Level(logutil.MostVerbose)}
+	switch logFormat {
+	case "json":
+		logOpts = append(logOpts, zap.JSONEncoder())
+	case "console":
+		logOpts = append(logOpts, zap.ConsoleEncoder())
+	}
+	levels := logutil.NewLevels(verbosity)
+	ctrl.SetLogger(logutil.New(zap.New(logOpts...), levels))
+	if logFormat != "" && logFormat != "json" && logFormat != "console" {
+		setupLog.Info("ignore the unknown log format", "format", logFormat)
+	}
+
+	// migrate the stored custom resources before the controllers reconcile them.
+	restConfig := ctrl.GetConfigOrDie()
+	directClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+	if err := migration.NewMigrator(directClient, rainbondNamespace, ctrl.Log.WithName("migration")).Run(context.Background()); err != nil {
+		// the objects failed to be migrated are reported by the condition of the rainbondclusters.
+		setupLog.Error(err, "unable to migrate custom resources")
+	}
+	if singleNamespace {
+		operatorconfig.SetInstance(rainbondNamespace)
+	}
+	// the controllers are set up with the settings the operator starts with.
+	operatorConfig, err := operatorconfig.Load(context.Background(), directClient)
+	if err != nil {
+		setupLog.Error(err, "unable to load operator config")
+		os.Exit(1)
+	}
+	settings := operatorconfig.Resolve(operatorConfig)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "c3e7a49c.rainbond.io",
+		// the operators of the rainbonds in different namespaces cache their own namespaces only.
+		Namespace:             settings.CacheNamespace(),
+		NewCache:              settings.NewCache(),
+		ClientDisableCacheFor: uncachedObjects(settings),
+
+		// the runnables, such as the openapi server draining the in-flight requests, are waited for on shutdown.
+		GracefulShutdownTimeout: &gracefulShutdownTimeout,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
+	rbdutil.SetServiceAccountName(serviceAccountName)
+	if err := validateServiceAccount(context.Background(), directClient, rainbondNamespace, rbdutil.ServiceAccountName()); err != nil {
+		setupLog.Error(err, "invalid service account, set it by --service-account-name or SERVICE_ACCOUNT_NAME")
+		os.Exit(1)
+	}
 	if err = (&controllers.RainbondClusterReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("RainbondCluster"),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("RainbondPackage"),
+		Client:    mgr.GetClient(),
+		Log:       ctrl.Log.WithName("controllers").WithName("RainbondCluster"),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("RainbondPackage"),
+		Settings:  settings,
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RainbondCluster")
 		os.Exit(1)
@@ -97,14 +202,16 @@ func main() {
 		Log:      ctrl.Log.WithName("controllers").WithName("RainbondPackage"),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("RainbondPackage"),
+		Settings: settings,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RainbondPackage")
 		os.Exit(1)
 	}
 	if err = (&controllers.RainbondVolumeReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("RainbondVolume"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("RainbondVolume"),
+		Scheme:   mgr.GetScheme(),
+		Settings: settings,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RainbondVolume")
 		os.Exit(1)
@@ -114,12 +221,50 @@ func main() {
 		Log:      ctrl.Log.WithName("controllers").WithName("RbdComponent"),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("RbdComponent"),
+		Settings: settings,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RbdComponent")
 		os.Exit(1)
 	}
+	if err = (&controllers.ImageSyncReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("ImageSync"),
+		Scheme:   mgr.GetScheme(),
+		Settings: settings,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageSync")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
+	openapiConfig := openapi.Config{
+		Addr:            openapiAddr,
+		CertFile:        openapiCertFile,
+		KeyFile:         openapiKeyFile,
+		ShutdownTimeout: openapiShutdownTimeout,
+		AllowedOrigins:  splitList(openapiAllowedOrigins),
+	}
+	if (openapiCertFile == "") != (openapiKeyFile == "") {
+		setupLog.Error(nil, "both --openapi-tls-cert-file and --openapi-tls-key-file are required to serve the openapi over https")
+		os.Exit(1)
+	}
+	if err := mgr.Add(openapi.NewServer(openapiConfig, rainbondNamespace, mgr.GetClient(), ctrl.Log.WithName("openapi"))); err != nil {
+		setupLog.Error(err, "unable to set up openapi server")
+		os.Exit(1)
+	}
+
+	logWatcher := logutil.NewConfigMapWatcher(mgr.GetClient(), rainbondNamespace, constants.LogConfigMapName, levels, verbosity, ctrl.Log.WithName("logutil"))
+	if err := mgr.Add(logWatcher); err != nil {
+		setupLog.Error(err, "unable to set up log levels watcher")
+		os.Exit(1)
+	}
+
+	configWatcher := operatorconfig.NewWatcher(mgr.GetClient(), settings, logWatcher, verbosity, ctrl.Log.WithName("operatorconfig"))
+	if err := mgr.Add(configWatcher); err != nil {
+		setupLog.Error(err, "unable to set up operator config watcher")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -131,7 +276,75 @@ func main() {
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		if errors.Is(err, operatorconfig.ErrRestartRequired) {
+			// exit to be restarted by the kubelet with the new settings.
+			setupLog.Info("restarting to apply the operator config")
+			os.Exit(0)
+		}
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// uncachedObjects returns the objects read from the API server directly, which are out of the watched namespaces,
+// such as the ConfigMap of CoreDNS and the DaemonSets of the CNI plugins, or are cluster-scoped while the cache
+// of multiple namespaces does not serve them.
+func uncachedObjects(settings *operatorconfig.Settings) []client.Object {
+	objs := []client.Object{&corev1.ConfigMap{}, &appsv1.DaemonSet{}}
+	if settings.NewCache() == nil {
+		return objs
+	}
+	return append(objs,
+		&corev1.Node{},
+		&corev1.Namespace{},
+		&corev1.PersistentVolume{},
+		&storagev1.StorageClass{},
+		&storagev1beta1.CSIDriver{},
+		&rbacv1.ClusterRole{},
+		&rbacv1.ClusterRoleBinding{},
+		&kubeaggregatorv1beta1.APIService{},
+		&rainbondiov1alpha1.RainbondOperatorConfig{},
+	)
+}
+
+// logVerbosity returns the default verbosity of the logs, which is given by --log-level, LOG_LEVEL,
+// or --zap-log-level in order.
+func logVerbosity(logLevel, zapVerbosity int) int {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "log-level" {
+			set = true
+		}
+	})
+	if set {
+		return logLevel
+	}
+	if level, err := strconv.Atoi(os.Getenv("LOG_LEVEL")); err == nil {
+		return level
+	}
+	return zapVerbosity
+}
+
+// validateServiceAccount checks the service account of the operator exists in the namespace of the rainbond cluster,
+// otherwise the pods using it are never created.
+func validateServiceAccount(ctx context.Context, c client.Reader, namespace, name string) error {
+	sa := &corev1.ServiceAccount{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, sa); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("service account %s not found in namespace %s", name, namespace)
+		}
+		return fmt.Errorf("get service account %s: %v", name, err)
+	}
+	return nil
+}
+
+// splitList splits the comma separated values of a flag, the empty values are dropped.
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,145 @@
+package offline
+
+import (
+	"path"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers"
+	clustermgr "github.com/goodrain/rainbond-operator/controllers/cluster-mgr"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defCIVersion is the version of the builder and the runner if not specified, the same as the rainbondpackage.
+const defCIVersion = "v5.3.3"
+
+// component is a rbdcomponent of the standard installation of Rainbond.
+type component struct {
+	name string
+	// image is the name of the image in the image repository, without the tag.
+	image string
+	// tag is the tag of the image, the version of Rainbond if empty.
+	tag string
+	// priority components are started before the image hub is ready, their images are pulled from the image
+	// repository of Rainbond rather than goodrain.me.
+	priority bool
+}
+
+// components are the rbdcomponents of the standard installation. The images not built by Rainbond have their own
+// versions.
+var components = []component{
+	{name: handler.EtcdName, image: "etcd", tag: "v3.3.18", priority: true},
+	{name: handler.HubName, image: "registry", tag: "2.6.2", priority: true},
+	{name: handler.DBName, image: "rbd-db", tag: "8.0.19", priority: true},
+	{name: handler.NFSName, image: "nfs-provisioner", tag: "v2.2.1-k8s1.12", priority: true},
+	{name: handler.GatewayName, image: "rbd-gateway", priority: true},
+	{name: handler.NodeName, image: "rbd-node", priority: true},
+	{name: handler.APIName, image: "rbd-api"},
+	{name: handler.ChaosName, image: "rbd-chaos"},
+	{name: handler.EventLogName, image: "rbd-eventlog"},
+	{name: handler.MonitorName, image: "rbd-monitor"},
+	{name: handler.MQName, image: "rbd-mq"},
+	{name: handler.WorkerName, image: "rbd-worker"},
+	{name: handler.ResourceProxyName, image: "rbd-resource-proxy"},
+	{name: handler.MetricsServerName, image: "metrics-server", tag: "v0.3.6"},
+	{name: handler.KubernetesDashboardName, image: "kubernetes-dashboard", tag: "v2.6.1"},
+	{name: handler.DashboardMetricsScraperName, image: "metrics-scraper", tag: "v1.0.4"},
+}
+
+// defaultImages are the default images the operator resolves from the image repository of Rainbond, for the
+// optional rbdcomponents and the jobs. The defragmentation of rbd-etcd runs the image of rbd-etcd.
+var defaultImages = []string{
+	handler.DefKubeStateMetricsImage,
+	handler.DefLogShipperImage,
+	clustermgr.DefNvidiaDevicePluginImage,
+	handler.DefHubReplicationImage,
+	handler.DefCacheCleanupImage,
+	controllers.DefImageJobImage,
+	clustermgr.DefStorageMigrationImage,
+}
+
+// image is an image of the offline bundle.
+type image struct {
+	// Source is the image pulled from the image repository of Rainbond.
+	Source string `json:"source"`
+	// Target is the name the image is saved as in the tarball. The images in the tarballs are pushed to goodrain.me
+	// by the rainbondpackage under the same path, and the priority images are loaded to the nodes as they are.
+	Target string `json:"target"`
+	// File is the path of the tarball in the bundle.
+	File string `json:"file"`
+}
+
+func (c component) tagOf(version string) string {
+	if c.tag != "" {
+		return c.tag
+	}
+	return version
+}
+
+// rbdComponents returns the rbdcomponents of the version, whose images are in the image repository.
+func rbdComponents(version, repository string) []rainbondv1alpha1.RbdComponent {
+	var cpts []rainbondv1alpha1.RbdComponent
+	for _, c := range components {
+		domain := constants.DefImageRepository
+		if c.priority {
+			domain = repository
+		}
+		cpts = append(cpts, rainbondv1alpha1.RbdComponent{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   c.name,
+				Labels: rbdutil.LabelsForRainbond(map[string]string{"name": c.name}),
+			},
+			Spec: rainbondv1alpha1.RbdComponentSpec{
+				Image:             path.Join(domain, c.image) + ":" + c.tagOf(version),
+				PriorityComponent: c.priority,
+			},
+		})
+	}
+	return cpts
+}
+
+// images returns the distinct images of the version, including the ones of the builds, the init containers and the
+// default images, which are not rbdcomponents.
+func images(version, ciVersion, repository string) []image {
+	var images []image
+	added := make(map[string]bool)
+	add := func(name, tag string, priority bool) {
+		source := path.Join(repository, name) + ":" + tag
+		if added[source] {
+			return
+		}
+		added[source] = true
+		target := path.Join(constants.DefImageRepository, name) + ":" + tag
+		if priority {
+			target = path.Join(repository, name) + ":" + tag
+		}
+		images = append(images, image{
+			Source: source,
+			Target: target,
+			File:   path.Join("images", name+"-"+tag+".tar"),
+		})
+	}
+	for _, c := range components {
+		add(c.image, c.tagOf(version), c.priority)
+	}
+	// the images the rainbondpackage pushes for the builds and the plugins.
+	add("builder", ciVersion, false)
+	add("runner", ciVersion, false)
+	add("rbd-init-probe", version, false)
+	add("rbd-mesh-data-panel", version, false)
+	add("plugins-tcm", "5.1.7", false)
+	// the init containers waiting for the dependencies, and the exporter of rbd-db.
+	add("busybox", "latest", true)
+	add("mysqld-exporter", "latest", true)
+	for _, ref := range defaultImages {
+		name, tag := ref, "latest"
+		if i := strings.LastIndex(ref, ":"); i > 0 {
+			name, tag = ref[:i], ref[i+1:]
+		}
+		add(name, tag, true)
+	}
+	return images
+}
@@ -0,0 +1,199 @@
+// Package offline builds the offline bundle of a version of Rainbond, from which the installation packages of the
+// air-gapped clusters are constructed.
+package offline
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// Command is the subcommand of the operator that builds the offline bundle.
+const Command = "offline-bundle"
+
+// defImageRepository is the image repository of Rainbond the images are pulled from by default.
+const defImageRepository = "registry.cn-hangzhou.aliyuncs.com/goodrain"
+
+// Options are the options of the offline bundle.
+type Options struct {
+	// Version is the version of Rainbond, which is the tag of the images built by Rainbond.
+	Version string
+	// CIVersion is the version of the builder and the runner, v5.3.3 by default.
+	CIVersion string
+	// ImageRepository is the image repository of Rainbond the images are pulled from.
+	ImageRepository string
+	// OperatorImage is the image of the operator in the values of the chart, the one of the chart if empty.
+	OperatorImage string
+	// CRDDir is the directory of the CRDs, and ChartDir is the directory of the chart of the operator.
+	CRDDir   string
+	ChartDir string
+	// Output is the directory the bundle is written to, which must not exist.
+	Output string
+}
+
+// Main runs the subcommand with the arguments after it, and returns the exit code.
+func Main(args []string) int {
+	fs := flag.NewFlagSet(Command, flag.ContinueOnError)
+	var opts Options
+	fs.StringVar(&opts.Version, "version", "", "The version of Rainbond, such as v5.10.0-release, required.")
+	fs.StringVar(&opts.CIVersion, "ci-version", defCIVersion, "The version of the builder and the runner.")
+	fs.StringVar(&opts.ImageRepository, "image-repository", defImageRepository, "The image repository of Rainbond the images are pulled from.")
+	fs.StringVar(&opts.OperatorImage, "operator-image", "", "The image of the operator, the one in the values of the chart if empty.")
+	fs.StringVar(&opts.CRDDir, "crd-dir", "config/crd/bases", "The directory of the CRDs in the source of the operator.")
+	fs.StringVar(&opts.ChartDir, "chart-dir", "chart", "The directory of the chart of the operator in the source of the operator.")
+	fs.StringVar(&opts.Output, "output", "", "The directory the bundle is written to, rainbond-offline-<version> by default.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if opts.Version == "" {
+		fmt.Fprintln(os.Stderr, "--version is required")
+		fs.Usage()
+		return 2
+	}
+	if opts.Output == "" {
+		opts.Output = "rainbond-offline-" + opts.Version
+	}
+	if err := Build(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "build the offline bundle: %v\n", err)
+		return 1
+	}
+	fmt.Printf("the offline bundle of %s is written to %s\n", opts.Version, opts.Output)
+	return 0
+}
+
+// Build writes the offline bundle to the output directory:
+//
+//	crds/                   the CRDs, applied by kubectl apply -f crds/
+//	chart/                  the chart of the operator
+//	operator-values.yaml    the values of the chart with the image of the operator
+//	rbdcomponents.yaml      the rbdcomponents of the version, applied to the namespace of Rainbond
+//	images.txt              the images to pull from the image repository
+//	images.yaml             the tarball manifest, which tells the name each image is saved as and the tarball of it
+//
+// The tarballs are saved to the images directory by docker save, which is the package path of the rainbondpackage.
+// The priority images are needed before the image hub is ready, so they are loaded to the nodes or mirrored in the
+// image repository of the air-gapped cluster as well.
+func Build(opts Options) error {
+	if opts.CIVersion == "" {
+		opts.CIVersion = defCIVersion
+	}
+	if opts.ImageRepository == "" {
+		opts.ImageRepository = defImageRepository
+	}
+	opts.ImageRepository = strings.TrimSuffix(opts.ImageRepository, "/")
+	if _, err := os.Stat(opts.Output); err == nil {
+		return fmt.Errorf("%s already exists", opts.Output)
+	}
+	if err := os.MkdirAll(opts.Output, 0755); err != nil {
+		return err
+	}
+
+	if err := copyDir(opts.CRDDir, filepath.Join(opts.Output, "crds")); err != nil {
+		return fmt.Errorf("copy crds: %v", err)
+	}
+	if err := copyDir(opts.ChartDir, filepath.Join(opts.Output, "chart")); err != nil {
+		return fmt.Errorf("copy chart: %v", err)
+	}
+	if opts.OperatorImage != "" {
+		if err := writeYAML(filepath.Join(opts.Output, "operator-values.yaml"), operatorValues(opts.OperatorImage)); err != nil {
+			return err
+		}
+	}
+
+	var items []interface{}
+	for _, cpt := range rbdComponents(opts.Version, opts.ImageRepository) {
+		items = append(items, map[string]interface{}{
+			"apiVersion": rainbondv1alpha1.GroupVersion.String(),
+			"kind":       "RbdComponent",
+			"metadata": map[string]interface{}{
+				"name":   cpt.Name,
+				"labels": cpt.Labels,
+			},
+			"spec": cpt.Spec,
+		})
+	}
+	list := map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items}
+	if err := writeYAML(filepath.Join(opts.Output, "rbdcomponents.yaml"), list); err != nil {
+		return err
+	}
+
+	images := images(opts.Version, opts.CIVersion, opts.ImageRepository)
+	var buf bytes.Buffer
+	for _, image := range images {
+		buf.WriteString(image.Source + "\n")
+	}
+	if err := ioutil.WriteFile(filepath.Join(opts.Output, "images.txt"), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return writeYAML(filepath.Join(opts.Output, "images.yaml"), map[string]interface{}{"images": images})
+}
+
+// operatorValues returns the values of the chart with the image of the operator.
+func operatorValues(operatorImage string) map[string]interface{} {
+	name, tag := operatorImage, "latest"
+	if idx := strings.LastIndex(operatorImage, ":"); idx > strings.LastIndex(operatorImage, "/") {
+		name, tag = operatorImage[:idx], operatorImage[idx+1:]
+	}
+	return map[string]interface{}{
+		"operator": map[string]interface{}{
+			"image": map[string]interface{}{
+				"name": name,
+				"tag":  tag,
+			},
+		},
+	}
+}
+
+func writeYAML(file string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %v", filepath.Base(file), err)
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// copyDir copies the regular files in the directory recursively.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(file, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,142 @@
+package offline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestImages(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   image
+	}{
+		{
+			name:   "rainbond image",
+			source: "registry.example.com/rainbond/rbd-api:v5.10.0",
+			want: image{
+				Source: "registry.example.com/rainbond/rbd-api:v5.10.0",
+				Target: "goodrain.me/rbd-api:v5.10.0",
+				File:   "images/rbd-api-v5.10.0.tar",
+			},
+		},
+		{
+			name:   "priority image",
+			source: "registry.example.com/rainbond/registry:2.6.2",
+			want: image{
+				Source: "registry.example.com/rainbond/registry:2.6.2",
+				Target: "registry.example.com/rainbond/registry:2.6.2",
+				File:   "images/registry-2.6.2.tar",
+			},
+		},
+		{
+			name:   "builder",
+			source: "registry.example.com/rainbond/builder:v5.3.3",
+			want: image{
+				Source: "registry.example.com/rainbond/builder:v5.3.3",
+				Target: "goodrain.me/builder:v5.3.3",
+				File:   "images/builder-v5.3.3.tar",
+			},
+		},
+	}
+
+	for _, ref := range []string{"kube-state-metrics:v2.3.0", "fluent-bit:1.9.9", "k8s-device-plugin:v0.9.0",
+		"skopeo:v1.9.2", "docker:20.10", "rsync-ssh:latest", "etcd:v3.3.18"} {
+		tests = append(tests, struct {
+			name   string
+			source string
+			want   image
+		}{
+			name:   ref,
+			source: "registry.example.com/rainbond/" + ref,
+			want: image{
+				Source: "registry.example.com/rainbond/" + ref,
+				Target: "registry.example.com/rainbond/" + ref,
+				File:   "images/" + strings.Replace(ref, ":", "-", 1) + ".tar",
+			},
+		})
+	}
+
+	images := images("v5.10.0", defCIVersion, "registry.example.com/rainbond")
+	sources := make(map[string]bool)
+	for _, image := range images {
+		assert.False(t, sources[image.Source], "duplicated image %s", image.Source)
+		sources[image.Source] = true
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			for _, image := range images {
+				if image.Source == tc.source {
+					assert.Equal(t, tc.want, image)
+					return
+				}
+			}
+			t.Errorf("image %s not found", tc.source)
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offline")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	crdDir := filepath.Join(dir, "crd")
+	chartDir := filepath.Join(dir, "chart")
+	assert.Nil(t, os.MkdirAll(crdDir, 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(chartDir, "templates"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(crdDir, "rainbond.io_rbdcomponents.yaml"), []byte("kind: CustomResourceDefinition\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(chartDir, "templates", "deployment.yaml"), []byte("kind: Deployment\n"), 0644))
+
+	output := filepath.Join(dir, "bundle")
+	err = Build(Options{
+		Version:         "v5.10.0",
+		ImageRepository: "registry.example.com/rainbond/",
+		OperatorImage:   "registry.example.com/rainbond/rainbond-operator:v2.3.0",
+		CRDDir:          crdDir,
+		ChartDir:        chartDir,
+		Output:          output,
+	})
+	assert.Nil(t, err)
+
+	for _, file := range []string{"crds/rainbond.io_rbdcomponents.yaml", "chart/templates/deployment.yaml", "images.txt", "images.yaml"} {
+		_, err := os.Stat(filepath.Join(output, file))
+		assert.Nil(t, err, file)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(output, "operator-values.yaml"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "name: registry.example.com/rainbond/rainbond-operator")
+	assert.Contains(t, string(data), "tag: v2.3.0")
+
+	data, err = ioutil.ReadFile(filepath.Join(output, "rbdcomponents.yaml"))
+	assert.Nil(t, err)
+	var list struct {
+		Items []rainbondv1alpha1.RbdComponent `json:"items"`
+	}
+	assert.Nil(t, yaml.Unmarshal(data, &list))
+	assert.Len(t, list.Items, len(components))
+	specs := make(map[string]rainbondv1alpha1.RbdComponentSpec)
+	for _, cpt := range list.Items {
+		specs[cpt.Name] = cpt.Spec
+	}
+	assert.Equal(t, "goodrain.me/rbd-api:v5.10.0", specs[handler.APIName].Image)
+	assert.Equal(t, "registry.example.com/rainbond/rbd-gateway:v5.10.0", specs[handler.GatewayName].Image)
+	assert.True(t, specs[handler.GatewayName].PriorityComponent)
+
+	data, err = ioutil.ReadFile(filepath.Join(output, "images.txt"))
+	assert.Nil(t, err)
+	assert.Contains(t, strings.Split(string(data), "\n"), "registry.example.com/rainbond/rbd-api:v5.10.0")
+
+	// the output is never overwritten.
+	assert.NotNil(t, Build(Options{Version: "v5.10.0", CRDDir: crdDir, ChartDir: chartDir, Output: output}))
+}
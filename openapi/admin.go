@@ -0,0 +1,52 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// getAdminCredential returns the credential of the console administrator generated at the first boot, to the users
+// who are allowed to get the secret rbd-console-admin. The credential can only be retrieved once by the openapi,
+// the subsequent requests will get 410 Gone, while it can still be read from the secret by kubectl.
+func (s *Server) getAdminCredential(c *gin.Context) {
+	ctx := c.Request.Context()
+	secret := &corev1.Secret{}
+	err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: constants.ConsoleAdminSecretName}, secret)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			errorResponse(c, http.StatusNotFound, "admin credential not generated yet")
+			return
+		}
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if secret.Annotations[constants.ConsoleAdminRetrievedAnnotation] == "true" {
+		errorResponse(c, http.StatusGone, "admin credential has been retrieved")
+		return
+	}
+
+	// the update fails with a conflict if the secret has been retrieved by another request.
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[constants.ConsoleAdminRetrievedAnnotation] = "true"
+	if err := s.client.Update(ctx, secret); err != nil {
+		if k8sErrors.IsConflict(err) {
+			errorResponse(c, http.StatusGone, "admin credential has been retrieved")
+			return
+		}
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.log.Info("admin credential retrieved")
+	c.JSON(http.StatusOK, gin.H{
+		"username": string(secret.Data["username"]),
+		"password": string(secret.Data["password"]),
+	})
+}
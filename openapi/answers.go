@@ -0,0 +1,211 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// answersList is the bundle of the install configuration, a List of kubernetes applied by kubectl as it is.
+type answersList struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Items      []answersItem `json:"items"`
+}
+
+// answersItem is a rainbondcluster or a rbdcomponent without the status and the metadata of the region.
+type answersItem struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   answersMetadata `json:"metadata"`
+	Spec       interface{}     `json:"spec"`
+}
+
+// answersMetadata leaves out the namespace, so that the bundle is applied to the namespace of the other region.
+type answersMetadata struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// answers is the install configuration exported from the region.
+type answers struct {
+	list answersList
+	// secrets are the names of the secrets referenced by the specs, which must be created before the bundle is
+	// applied.
+	secrets []string
+	// redacted are the credentials removed from the specs, which must be filled in before the bundle is applied.
+	redacted []string
+}
+
+// getAnswers exports the specs of the rainbondcluster and the rbdcomponents as a YAML bundle, which is applied to
+// another cluster by kubectl apply -n <namespace> -f, so that a validated configuration is installed repeatedly.
+// The credentials in the specs are removed rather than exported, and the secrets are referenced by names rather
+// than inlined, both of which are listed in the comments of the bundle. The fields that address the nodes of this
+// cluster, such as the gateway nodes and the ingress IPs, are removed too.
+func (s *Server) getAnswers(c *gin.Context) {
+	ctx := c.Request.Context()
+	cluster, err := s.getCluster(ctx)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			errorResponse(c, http.StatusNotFound, "rainbondcluster not found")
+			return
+		}
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	components := &rainbondv1alpha1.RbdComponentList{}
+	if err := s.client.List(ctx, components, client.InNamespace(s.namespace)); err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	answers, err := exportAnswers(cluster, components.Items)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	data, err := answers.marshal(s.namespace)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="rainbond-answers.yaml"`)
+	c.Data(http.StatusOK, "application/yaml", data)
+}
+
+func exportAnswers(cluster *rainbondv1alpha1.RainbondCluster, components []rainbondv1alpha1.RbdComponent) (*answers, error) {
+	a := &answers{
+		list: answersList{APIVersion: "v1", Kind: "List"},
+	}
+	secrets := make(map[string]struct{})
+
+	spec := cluster.Spec.DeepCopy()
+	if spec.ImageHub != nil && spec.ImageHub.Password != "" {
+		spec.ImageHub.Password = ""
+		a.redacted = append(a.redacted, fmt.Sprintf("RainbondCluster %s: spec.imageHub.password", cluster.Name))
+	}
+	if spec.RegionDatabase != nil && spec.RegionDatabase.Password != "" {
+		spec.RegionDatabase.Password = ""
+		a.redacted = append(a.redacted, fmt.Sprintf("RainbondCluster %s: spec.regionDatabase.password", cluster.Name))
+	}
+	// the nodes and the addresses of this cluster.
+	spec.NodesForGateway = nil
+	spec.NodesForChaos = nil
+	spec.NodeRoles = nil
+	spec.GatewayIngressIPs = nil
+	spec.GatewayVIP = ""
+	if err := a.add("RainbondCluster", cluster.ObjectMeta, spec, secrets); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].Name < components[j].Name
+	})
+	for i := range components {
+		if err := a.add("RbdComponent", components[i].ObjectMeta, &components[i].Spec, secrets); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range secrets {
+		a.secrets = append(a.secrets, name)
+	}
+	sort.Strings(a.secrets)
+	return a, nil
+}
+
+func (a *answers) add(kind string, meta metav1.ObjectMeta, spec interface{}, secrets map[string]struct{}) error {
+	// the spec is converted to a plain object, so that the secrets it references are found by the field names.
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("marshal spec of %s %s: %v", kind, meta.Name, err)
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("unmarshal spec of %s %s: %v", kind, meta.Name, err)
+	}
+	referencedSecrets(obj, secrets)
+
+	annotations := make(map[string]string)
+	for key, value := range meta.Annotations {
+		if key != corev1.LastAppliedConfigAnnotation {
+			annotations[key] = value
+		}
+	}
+	a.list.Items = append(a.list.Items, answersItem{
+		APIVersion: rainbondv1alpha1.GroupVersion.String(),
+		Kind:       kind,
+		Metadata: answersMetadata{
+			Name:        meta.Name,
+			Labels:      meta.Labels,
+			Annotations: annotations,
+		},
+		Spec: obj,
+	})
+	return nil
+}
+
+// referencedSecrets collects the names of the secrets referenced by the fields named secretName, such as
+// spec.licenseSecretName and the secret volumes, and by the secretKeyRef and secretRef of the env.
+func referencedSecrets(obj interface{}, secrets map[string]struct{}) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch {
+			case strings.HasSuffix(strings.ToLower(key), "secretname"):
+				if name, ok := value.(string); ok && name != "" {
+					secrets[name] = struct{}{}
+				}
+			case key == "secretKeyRef" || key == "secretRef":
+				if ref, ok := value.(map[string]interface{}); ok {
+					if name, ok := ref["name"].(string); ok && name != "" {
+						secrets[name] = struct{}{}
+					}
+				}
+			default:
+				referencedSecrets(value, secrets)
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			referencedSecrets(value, secrets)
+		}
+	}
+}
+
+// marshal returns the bundle in YAML, with the secrets to create and the credentials to fill in as the comments.
+func (a *answers) marshal(namespace string) ([]byte, error) {
+	data, err := yaml.Marshal(a.list)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# The install configuration of Rainbond exported from the namespace %s.\n", namespace)
+	if len(a.secrets) > 0 {
+		buf.WriteString("# Create the secrets referenced by the specs in the namespace before applying it:\n")
+		for _, name := range a.secrets {
+			fmt.Fprintf(&buf, "#   - %s\n", name)
+		}
+	}
+	if len(a.redacted) > 0 {
+		buf.WriteString("# Fill in the credentials removed from the specs:\n")
+		for _, field := range a.redacted {
+			fmt.Fprintf(&buf, "#   - %s\n", field)
+		}
+	}
+	buf.WriteString("# Apply it by: kubectl apply -n <namespace> -f rainbond-answers.yaml\n")
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
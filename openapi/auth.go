@@ -0,0 +1,110 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// userKey is the key of the authenticated user in the gin context.
+const userKey = "user"
+
+// authenticate authenticates the bearer token of the request by a TokenReview, so that the openapi is called with
+// the same credentials as the kubernetes api, such as the token of a service account.
+func (s *Server) authenticate(c *gin.Context) {
+	token := bearerToken(c.GetHeader("Authorization"))
+	if token == "" {
+		errorResponse(c, http.StatusUnauthorized, "bearer token required")
+		c.Abort()
+		return
+	}
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := s.client.Create(c.Request.Context(), review); err != nil {
+		s.log.Error(err, "review token")
+		errorResponse(c, http.StatusInternalServerError, "review token failed")
+		c.Abort()
+		return
+	}
+	if !review.Status.Authenticated {
+		errorResponse(c, http.StatusUnauthorized, "invalid bearer token")
+		c.Abort()
+		return
+	}
+	c.Set(userKey, review.Status.User)
+	c.Next()
+}
+
+// authorize returns the middleware which only allows the users who are permitted to perform the verb on the
+// resource in the namespace of the rainbond, which is checked by a SubjectAccessReview.
+func (s *Server) authorize(verb, group, resource, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet(userKey).(authenticationv1.UserInfo)
+		extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+		for k, v := range user.Extra {
+			extra[k] = authorizationv1.ExtraValue(v)
+		}
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: s.namespace,
+					Verb:      verb,
+					Group:     group,
+					Resource:  resource,
+					Name:      name,
+				},
+				User:   user.Username,
+				UID:    user.UID,
+				Groups: user.Groups,
+				Extra:  extra,
+			},
+		}
+		if err := s.client.Create(c.Request.Context(), review); err != nil {
+			s.log.Error(err, "review subject access")
+			errorResponse(c, http.StatusInternalServerError, "review subject access failed")
+			c.Abort()
+			return
+		}
+		if !review.Status.Allowed {
+			errorResponse(c, http.StatusForbidden, "forbidden")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func bearerToken(header string) string {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// setCORS allows the browsers to call the openapi from the allowed origins only.
+func (s *Server) setCORS(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if origin == "" || !s.allowedOrigin(origin) {
+		return
+	}
+	c.Writer.Header().Add("Access-Control-Allow-Origin", origin)
+	c.Writer.Header().Add("Vary", "Origin")
+	c.Writer.Header().Add("Access-Control-Allow-Methods", "GET,PUT,POST,OPTIONS")
+	c.Writer.Header().Add("Access-Control-Allow-Headers", "content-type,Authorization")
+	// the pod of the logs is told by the header.
+	c.Writer.Header().Add("Access-Control-Expose-Headers", "X-Rainbond-Pod")
+}
+
+func (s *Server) allowedOrigin(origin string) bool {
+	for _, allowed := range s.config.AllowedOrigins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
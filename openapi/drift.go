@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	componentmgr "github.com/goodrain/rainbond-operator/controllers/component-mgr"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// componentDrift is the drift of the resources of a rbdcomponent.
+type componentDrift struct {
+	Component string `json:"component"`
+	// Halted is true if the reconciliation is halted, the drift is applied once it is resumed.
+	Halted    bool                         `json:"halted"`
+	Resources []componentmgr.ResourceDrift `json:"resources,omitempty"`
+	Error     string                       `json:"error,omitempty"`
+}
+
+// getDrift reports how the live resources differ from the ones rendered by the operator, and which of the edits
+// will be kept or overwritten by the reconciliation, so that the admins know what will be changed before resuming
+// the halted rbdcomponents. The report can be limited to a rbdcomponent by the query parameter component.
+func (s *Server) getDrift(c *gin.Context) {
+	ctx := c.Request.Context()
+	cluster, err := s.getCluster(ctx)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			errorResponse(c, http.StatusNotFound, "rainbondcluster not found")
+			return
+		}
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	components := &rainbondv1alpha1.RbdComponentList{}
+	if err := s.client.List(ctx, components, client.InNamespace(s.namespace)); err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	name := c.Query("component")
+	// the handlers prepare the resources with a dry-run client, in case they change something.
+	cli := client.NewDryRunClient(s.client)
+	drifts := []componentDrift{}
+	for i := range components.Items {
+		cpt := &components.Items[i]
+		if name != "" && cpt.Name != name {
+			continue
+		}
+		drifts = append(drifts, s.componentDrift(ctx, cli, cpt, cluster))
+	}
+	if name != "" && len(drifts) == 0 {
+		errorResponse(c, http.StatusNotFound, fmt.Sprintf("rbdcomponent %s not found", name))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"components": drifts})
+}
+
+func (s *Server) componentDrift(ctx context.Context, cli client.Client, cpt *rainbondv1alpha1.RbdComponent, cluster *rainbondv1alpha1.RainbondCluster) componentDrift {
+	drift := componentDrift{
+		Component: cpt.Name,
+		Halted:    cpt.Status.IsHalted(),
+	}
+	factory, ok := handler.DefaultRegistry.Get(cpt.Name)
+	if !ok {
+		drift.Error = "unsupported type of rbdcomponent"
+		return drift
+	}
+	// no event is recorded by the drift.
+	mgr := componentmgr.NewRbdcomponentMgr(ctx, cli, nil, s.log.WithValues("rbdcomponent", cpt.Name), cpt)
+	resources, err := mgr.Drift(factory(ctx, cli, cpt, cluster), cluster)
+	if err != nil {
+		drift.Error = err.Error()
+		return drift
+	}
+	drift.Resources = resources
+	return drift
+}
@@ -0,0 +1,93 @@
+package openapi
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodrain/rainbond-operator/util/licenseutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// licenseSecretName is the name of the secret created for the license installed through the openapi.
+const licenseSecretName = "rbd-license"
+
+// getLicense returns the status of the license.
+func (s *Server) getLicense(c *gin.Context) {
+	cluster, err := s.getCluster(c.Request.Context())
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if cluster.Status.License == nil {
+		errorResponse(c, http.StatusNotFound, "license not found")
+		return
+	}
+	c.JSON(http.StatusOK, cluster.Status.License)
+}
+
+// putLicense installs or renews the license, the request body is the license file.
+func (s *Server) putLicense(c *gin.Context) {
+	ctx := c.Request.Context()
+	data, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := licenseutil.Parse(data); err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret := &corev1.Secret{}
+	err = s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: licenseSecretName}, secret)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if k8sErrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      licenseSecretName,
+				Namespace: s.namespace,
+				Labels:    rbdutil.LabelsForRainbond(nil),
+			},
+			Data: map[string][]byte{
+				licenseutil.SecretKey: data,
+			},
+		}
+		err = s.client.Create(ctx, secret)
+	} else {
+		secret.Data = map[string][]byte{
+			licenseutil.SecretKey: data,
+		}
+		err = s.client.Update(ctx, secret)
+	}
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := s.getCluster(ctx)
+		if err != nil {
+			return err
+		}
+		if cluster.Spec.LicenseSecretName == licenseSecretName {
+			return nil
+		}
+		cluster.Spec.LicenseSecretName = licenseSecretName
+		return s.client.Update(ctx, cluster)
+	})
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.log.Info("license installed")
+	c.Status(http.StatusOK)
+}
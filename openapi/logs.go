@@ -0,0 +1,174 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defLogTailLines is the number of the lines returned from the end of the logs if tail is not specified.
+	defLogTailLines = 200
+	// maxLogTailLines limits the lines returned at once, the logs of a long-running pod are huge.
+	maxLogTailLines = 10000
+)
+
+// getComponentLogs returns the logs of a pod of the rbdcomponent, so that the logs are viewed by the install UI
+// without the access to the namespace by kubectl. The query parameters are:
+//
+//	tail       the number of the lines from the end of the logs, 200 by default.
+//	follow     streams the new logs until the request is canceled or the pod is gone.
+//	pod        the pod of the rbdcomponent, the latest running one by default.
+//	container  the container of the pod, the first one by default.
+//	previous   returns the logs of the previous instance of the container, such as the one crashed.
+//
+// The pod is returned by the header X-Rainbond-Pod.
+func (s *Server) getComponentLogs(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+	tail, err := queryInt(c, "tail", defLogTailLines)
+	if err != nil || tail < 0 || tail > maxLogTailLines {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("tail must be between 0 and %d", maxLogTailLines))
+		return
+	}
+	follow, err := queryBool(c, "follow")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "follow must be a boolean")
+		return
+	}
+	previous, err := queryBool(c, "previous")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "previous must be a boolean")
+		return
+	}
+
+	cpt := &rainbondv1alpha1.RbdComponent{}
+	if err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: name}, cpt); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			errorResponse(c, http.StatusNotFound, fmt.Sprintf("rbdcomponent %s not found", name))
+			return
+		}
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pod, err := s.componentPod(ctx, name, c.Query("pod"))
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if pod == nil {
+		errorResponse(c, http.StatusNotFound, fmt.Sprintf("no pod of rbdcomponent %s found", name))
+		return
+	}
+	container := c.Query("container")
+	if container == "" {
+		container = pod.Spec.Containers[0].Name
+	}
+
+	if follow {
+		// the streams are closed once the server is shut down, rather than holding the shutdown until it times out.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-s.streams.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	tailLines := int64(tail)
+	stream, err := k8sutil.GetClientSet().CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Follow:    follow,
+		Previous:  previous,
+		TailLines: &tailLines,
+	}).Stream(ctx)
+	if err != nil {
+		switch {
+		case k8sErrors.IsBadRequest(err):
+			// such as the container is not found or not started.
+			errorResponse(c, http.StatusBadRequest, err.Error())
+		case k8sErrors.IsNotFound(err):
+			errorResponse(c, http.StatusNotFound, err.Error())
+		default:
+			errorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	defer stream.Close()
+
+	c.Header("X-Rainbond-Pod", pod.Name)
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, err := c.Writer.Write(buf[:n]); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				s.log.V(4).Info("read the logs", "pod", pod.Name, "error", err.Error())
+			}
+			return
+		}
+	}
+}
+
+// componentPod returns the pod of the rbdcomponent with the name, or the latest running one if the name is empty.
+// Only the pods of the rbdcomponent are returned, the pod of the name belonging to the others is not found.
+func (s *Server) componentPod(ctx context.Context, component, name string) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := s.client.List(ctx, pods, client.InNamespace(s.namespace),
+		client.MatchingLabels(rbdutil.LabelsForRainbond(map[string]string{"name": component}))); err != nil {
+		return nil, fmt.Errorf("list pods of rbdcomponent %s: %v", component, err)
+	}
+	items := pods.Items
+	sort.Slice(items, func(i, j int) bool {
+		// the running pods first, then the latest ones.
+		ri, rj := items[i].Status.Phase == corev1.PodRunning, items[j].Status.Phase == corev1.PodRunning
+		if ri != rj {
+			return ri
+		}
+		return items[j].CreationTimestamp.Before(&items[i].CreationTimestamp)
+	})
+	for i := range items {
+		if name == "" || items[i].Name == name {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func queryInt(c *gin.Context, key string, def int) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func queryBool(c *gin.Context, key string) (bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
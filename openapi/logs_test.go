@@ -0,0 +1,224 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testToken = "token"
+
+// reviewClient reviews the tokens and the subject accesses as the apiserver does, the token is authenticated if it
+// is testToken, and the access is allowed if allowed is true.
+type reviewClient struct {
+	client.Client
+	allowed bool
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		review.Status.Authenticated = review.Spec.Token == testToken
+		review.Status.User = authenticationv1.UserInfo{Username: "admin"}
+		return nil
+	case *authorizationv1.SubjectAccessReview:
+		review.Status.Allowed = c.allowed
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// newTestRouter returns the router of the openapi of the server with the objects, the same as the one started.
+func newTestRouter(t *testing.T, allowed bool, objs ...runtime.Object) *gin.Engine {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cli := &reviewClient{Client: fake.NewFakeClientWithScheme(scheme, objs...), allowed: allowed}
+	s := NewServer(Config{}, "rbd-system", cli, logr.Discard())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	s.routes(r.Group("/openapi/v1", s.authenticate))
+	return r
+}
+
+func testPod(name, component string, phase corev1.PodPhase, created time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "rbd-system",
+			Labels:            rbdutil.LabelsForRainbond(map[string]string{"name": component}),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: component}}},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestGetComponentLogs(t *testing.T) {
+	cpt := &rainbondv1alpha1.RbdComponent{ObjectMeta: metav1.ObjectMeta{Name: "rbd-api", Namespace: "rbd-system"}}
+	other := testPod("rbd-worker-0", "rbd-worker", corev1.PodRunning, time.Now())
+
+	tests := []struct {
+		name     string
+		query    string
+		token    string
+		denied   bool
+		objs     []runtime.Object
+		wantCode int
+	}{
+		{
+			name:     "unauthenticated",
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "invalid token",
+			token:    "invalid",
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "forbidden",
+			token:    testToken,
+			denied:   true,
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "negative tail",
+			query:    "tail=-1",
+			token:    testToken,
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "tail too large",
+			query:    "tail=10001",
+			token:    testToken,
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "tail not a number",
+			query:    "tail=all",
+			token:    testToken,
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "follow not a boolean",
+			query:    "follow=maybe",
+			token:    testToken,
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "previous not a boolean",
+			query:    "previous=maybe",
+			token:    testToken,
+			objs:     []runtime.Object{cpt},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "rbdcomponent not found",
+			token:    testToken,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "no pods",
+			query:    "tail=10&follow=true",
+			token:    testToken,
+			objs:     []runtime.Object{cpt, other},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "pod of another rbdcomponent",
+			query:    "pod=rbd-worker-0",
+			token:    testToken,
+			objs:     []runtime.Object{cpt, other},
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestRouter(t, !tc.denied, tc.objs...)
+			req := httptest.NewRequest(http.MethodGet, "/openapi/v1/components/rbd-api/logs?"+tc.query, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.wantCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestComponentPod(t *testing.T) {
+	now := time.Now()
+	s := &Server{namespace: "rbd-system"}
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	s.client = fake.NewFakeClientWithScheme(scheme,
+		testPod("rbd-api-old", "rbd-api", corev1.PodRunning, now.Add(-time.Hour)),
+		testPod("rbd-api-new", "rbd-api", corev1.PodRunning, now),
+		testPod("rbd-api-pending", "rbd-api", corev1.PodPending, now.Add(time.Minute)),
+		testPod("rbd-worker-0", "rbd-worker", corev1.PodRunning, now),
+	)
+
+	tests := []struct {
+		name string
+		pod  string
+		want string
+	}{
+		{
+			name: "the latest running pod",
+			want: "rbd-api-new",
+		},
+		{
+			name: "pod of the name",
+			pod:  "rbd-api-pending",
+			want: "rbd-api-pending",
+		},
+		{
+			name: "pod of another rbdcomponent",
+			pod:  "rbd-worker-0",
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			pod, err := s.componentPod(context.Background(), "rbd-api", tc.pod)
+			assert.NoError(t, err)
+			if tc.want == "" {
+				assert.Nil(t, pod)
+				return
+			}
+			if assert.NotNil(t, pod) {
+				assert.Equal(t, tc.want, pod.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// defShutdownTimeout is how long the in-flight requests are waited for on shutdown, if not specified.
+const defShutdownTimeout = 20 * time.Second
+
+// Config is the configuration of the openapi server.
+type Config struct {
+	// Addr is the address the openapi server listens on, such as :8082.
+	Addr string
+	// CertFile and KeyFile are the certificate and the private key the openapi is served with over https,
+	// which are reloaded once they are rotated. The openapi is served over http if they are empty.
+	CertFile string
+	KeyFile  string
+	// ShutdownTimeout is how long the in-flight requests are waited for on shutdown. Defaults to 20s.
+	ShutdownTimeout time.Duration
+	// AllowedOrigins are the origins of the browsers allowed to call the openapi, none by default.
+	AllowedOrigins []string
+}
+
+// Server is the openapi server of rainbond-operator.
+type Server struct {
+	config    Config
+	namespace string
+	client    client.Client
+	log       logr.Logger
+	// streams is canceled once the server is shut down, which ends the streaming responses, such as the logs
+	// followed.
+	streams       context.Context
+	cancelStreams context.CancelFunc
+}
+
+var _ manager.Runnable = &Server{}
+var _ manager.LeaderElectionRunnable = &Server{}
+
+// NewServer creates a new openapi server with the given config. The requests are authenticated by the bearer
+// tokens of kubernetes, and authorized by the RBAC of the resources they read or change.
+func NewServer(config Config, namespace string, client client.Client, log logr.Logger) *Server {
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = defShutdownTimeout
+	}
+	streams, cancelStreams := context.WithCancel(context.Background())
+	return &Server{
+		config:        config,
+		namespace:     namespace,
+		client:        client,
+		log:           log,
+		streams:       streams,
+		cancelStreams: cancelStreams,
+	}
+}
+
+// Start starts the openapi server, and shuts it down gracefully when the context is done, which is canceled by
+// the manager on the termination of the operator. The new connections are refused then, and the in-flight
+// requests are waited for until the shutdown timeout.
+func (s *Server) Start(ctx context.Context) error {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery(), func(c *gin.Context) {
+		s.setCORS(c)
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	})
+	s.routes(r.Group("/openapi/v1", s.authenticate))
+
+	srv := &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srv.RegisterOnShutdown(s.cancelStreams)
+	tlsEnabled := s.config.CertFile != "" || s.config.KeyFile != ""
+	if tlsEnabled {
+		certs, err := newCertificateLoader(s.config.CertFile, s.config.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load the certificate of openapi: %v", err)
+		}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+	// listen before serving, so that the address in use fails the manager right away.
+	ln, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %v", s.config.Addr, err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.log.Info("starting openapi server", "addr", s.config.Addr, "tls", tlsEnabled)
+		var err error
+		if tlsEnabled {
+			// the certificate is given by the TLS config.
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		s.log.Info("shutting down openapi server", "timeout", s.config.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			s.log.Error(err, "in-flight requests are dropped")
+			return srv.Close()
+		}
+		s.log.Info("openapi server is shut down")
+		return nil
+	}
+}
+
+// NeedLeaderElection returns false, so that all the replicas of rainbond-operator serve the openapi.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) routes(r *gin.RouterGroup) {
+	r.GET("/license", s.authorize("get", rainbondv1alpha1.GroupVersion.Group, "rainbondclusters", ""), s.getLicense)
+	r.PUT("/license", s.authorize("update", rainbondv1alpha1.GroupVersion.Group, "rainbondclusters", ""), s.putLicense)
+	// the same as reading the secret by kubectl.
+	r.GET("/admin-credential", s.authorize("get", "", "secrets", constants.ConsoleAdminSecretName), s.getAdminCredential)
+	r.GET("/drift", s.authorize("list", rainbondv1alpha1.GroupVersion.Group, "rbdcomponents", ""), s.getDrift)
+	// the logs of the rbdcomponents are readable by the ones allowed to read the rbdcomponents, without the access
+	// to the pods.
+	r.GET("/components/:name/logs", s.authorize("get", rainbondv1alpha1.GroupVersion.Group, "rbdcomponents", ""), s.getComponentLogs)
+	// only the admins allowed to change the rainbondcluster evaluate the changes.
+	r.POST("/whatif", s.authorize("update", rainbondv1alpha1.GroupVersion.Group, "rainbondclusters", ""), s.postWhatIf)
+	// the credentials are removed from the answers, the same as reading the rainbondcluster otherwise.
+	r.GET("/answers", s.authorize("get", rainbondv1alpha1.GroupVersion.Group, "rainbondclusters", ""), s.getAnswers)
+}
+
+func (s *Server) getCluster(ctx context.Context) (*rainbondv1alpha1.RainbondCluster, error) {
+	cluster := &rainbondv1alpha1.RainbondCluster{}
+	err := s.client.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: constants.RainbondClusterName}, cluster)
+	return cluster, err
+}
+
+func errorResponse(c *gin.Context, code int, msg string) {
+	c.JSON(code, gin.H{"msg": msg})
+}
@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateLoader serves the certificate of the files, and reloads it once the files are modified, such as the
+// secret mounted is rotated by cert-manager.
+type certificateLoader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertificateLoader(certFile, keyFile string) (*certificateLoader, error) {
+	l := &certificateLoader{certFile: certFile, keyFile: keyFile}
+	if _, err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetCertificate returns the certificate for the TLS handshakes. The last loaded one is kept if the files fail to
+// be reloaded, such as they are being written.
+func (l *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cert, err := l.load(); err == nil {
+		return cert, nil
+	}
+	return l.cert, nil
+}
+
+// load loads the certificate if the files are modified since the last load. It must be called with the lock held,
+// except in newCertificateLoader.
+func (l *certificateLoader) load() (*tls.Certificate, error) {
+	modTime, err := latestModTime(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if l.cert != nil && modTime.Equal(l.modTime) {
+		return l.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	l.cert, l.modTime = &cert, modTime
+	return l.cert, nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
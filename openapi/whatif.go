@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	componentmgr "github.com/goodrain/rainbond-operator/controllers/component-mgr"
+	"github.com/goodrain/rainbond-operator/controllers/handler"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// whatIfResult is the result of a proposed spec of the rainbondcluster.
+type whatIfResult struct {
+	// Valid is false if the proposed spec is rejected by the apiserver, or by the handlers of the rbdcomponents.
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+	// Components are the rbdcomponents changed by the proposed spec.
+	Components []componentWhatIf `json:"components"`
+}
+
+// componentWhatIf is how the resources of a rbdcomponent are changed by the proposed spec.
+type componentWhatIf struct {
+	Component string `json:"component"`
+	// Restart is true if the pods of the rbdcomponent are replaced.
+	Restart   bool                          `json:"restart"`
+	Resources []componentmgr.ResourceChange `json:"resources,omitempty"`
+	Error     string                        `json:"error,omitempty"`
+}
+
+// postWhatIf evaluates a proposed change of the rainbondcluster without applying it, so that the admins know its
+// blast radius beforehand. The request body is a JSON merge patch of the rainbondcluster, such as
+// {"spec": {"timezone": "Asia/Shanghai"}}, of which only the spec is taken. The proposed rainbondcluster is validated
+// by a dry-run update, then the resources of the rbdcomponents are rendered with it and compared with the ones
+// rendered with the current rainbondcluster.
+func (s *Server) postWhatIf(c *gin.Context) {
+	ctx := c.Request.Context()
+	data, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		errorResponse(c, http.StatusBadRequest, fmt.Sprintf("the body must be a JSON merge patch of the rainbondcluster: %v", err))
+		return
+	}
+
+	cluster, err := s.getCluster(ctx)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			errorResponse(c, http.StatusNotFound, "rainbondcluster not found")
+			return
+		}
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	proposed, err := proposedCluster(cluster, patch)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := whatIfResult{Valid: true, Components: []componentWhatIf{}}
+	// the defaults of the schema are applied to the proposed rainbondcluster by the dry-run update.
+	if err := s.client.Update(ctx, proposed, client.DryRunAll); err != nil {
+		if !k8sErrors.IsInvalid(err) && !k8sErrors.IsBadRequest(err) {
+			errorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		result.Valid = false
+		result.Errors = append(result.Errors, err.Error())
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	components := &rainbondv1alpha1.RbdComponentList{}
+	if err := s.client.List(ctx, components, client.InNamespace(s.namespace)); err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// the handlers prepare the resources with a dry-run client, in case they change something.
+	cli := client.NewDryRunClient(s.client)
+	for i := range components.Items {
+		cpt := &components.Items[i]
+		whatIf, err := s.componentWhatIf(ctx, cli, cpt, cluster, proposed)
+		if err != nil {
+			whatIf.Error = err.Error()
+			if handler.ErrorKindOf(err) == handler.ErrorKindPermanent {
+				result.Valid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", cpt.Name, err))
+			}
+		}
+		if whatIf.Error != "" || len(whatIf.Resources) > 0 {
+			result.Components = append(result.Components, whatIf)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) componentWhatIf(ctx context.Context, cli client.Client, cpt *rainbondv1alpha1.RbdComponent, cluster, proposed *rainbondv1alpha1.RainbondCluster) (componentWhatIf, error) {
+	whatIf := componentWhatIf{Component: cpt.Name}
+	factory, ok := handler.DefaultRegistry.Get(cpt.Name)
+	if !ok {
+		whatIf.Error = "unsupported type of rbdcomponent"
+		return whatIf, nil
+	}
+	// no event is recorded by the what-if.
+	mgr := componentmgr.NewRbdcomponentMgr(ctx, cli, nil, s.log.WithValues("rbdcomponent", cpt.Name), cpt)
+	changes, err := mgr.WhatIf(factory(ctx, cli, cpt, cluster), factory(ctx, cli, cpt, proposed), cluster, proposed)
+	if err != nil {
+		return whatIf, err
+	}
+	whatIf.Resources = changes
+	for _, change := range changes {
+		whatIf.Restart = whatIf.Restart || change.Restart
+	}
+	return whatIf, nil
+}
+
+// proposedCluster applies the JSON merge patch to the rainbondcluster, and returns a copy of it with the patched
+// spec. The other parts of the patch, such as the metadata, are ignored.
+func proposedCluster(cluster *rainbondv1alpha1.RainbondCluster, patch map[string]interface{}) (*rainbondv1alpha1.RainbondCluster, error) {
+	data, err := json.Marshal(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(mergePatch(doc, patch))
+	if err != nil {
+		return nil, err
+	}
+	patched := &rainbondv1alpha1.RainbondCluster{}
+	if err := json.Unmarshal(data, patched); err != nil {
+		return nil, fmt.Errorf("invalid spec of the rainbondcluster: %v", err)
+	}
+	proposed := cluster.DeepCopy()
+	proposed.Spec = patched.Spec
+	return proposed, nil
+}
+
+// mergePatch applies the JSON merge patch to the document, see RFC 7386.
+func mergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = make(map[string]interface{})
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(d, key)
+			continue
+		}
+		d[key] = mergePatch(d[key], value)
+	}
+	return d
+}
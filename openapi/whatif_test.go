@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "replace",
+			doc:   `{"a":"b"}`,
+			patch: `{"a":"c"}`,
+			want:  `{"a":"c"}`,
+		},
+		{
+			name:  "add",
+			doc:   `{"a":"b"}`,
+			patch: `{"b":"c"}`,
+			want:  `{"a":"b","b":"c"}`,
+		},
+		{
+			name:  "remove",
+			doc:   `{"a":"b","b":"c"}`,
+			patch: `{"a":null}`,
+			want:  `{"b":"c"}`,
+		},
+		{
+			name:  "nested",
+			doc:   `{"a":{"b":"c","d":"e"}}`,
+			patch: `{"a":{"b":"f","d":null}}`,
+			want:  `{"a":{"b":"f"}}`,
+		},
+		{
+			name:  "arrays replaced",
+			doc:   `{"a":[1,2]}`,
+			patch: `{"a":[3]}`,
+			want:  `{"a":[3]}`,
+		},
+		{
+			name:  "object replaces scalar",
+			doc:   `{"a":"b"}`,
+			patch: `{"a":{"c":"d"}}`,
+			want:  `{"a":{"c":"d"}}`,
+		},
+		{
+			name:  "remove missing",
+			doc:   `{"a":"b"}`,
+			patch: `{"c":null}`,
+			want:  `{"a":"b"}`,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var doc, patch interface{}
+			assert.NoError(t, json.Unmarshal([]byte(tc.doc), &doc))
+			assert.NoError(t, json.Unmarshal([]byte(tc.patch), &patch))
+			got, err := json.Marshal(mergePatch(doc, patch))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestProposedCluster(t *testing.T) {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rainbondcluster", Namespace: "rbd-system", ResourceVersion: "10"},
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			GatewayVIP: "192.168.0.10",
+			Timezone:   &rainbondv1alpha1.Timezone{Name: "UTC", HostLocaltime: true},
+		},
+	}
+	tests := []struct {
+		name    string
+		patch   string
+		want    rainbondv1alpha1.RainbondClusterSpec
+		wantErr bool
+	}{
+		{
+			name:  "spec merged",
+			patch: `{"spec":{"timezone":{"name":"Asia/Shanghai"}}}`,
+			want: rainbondv1alpha1.RainbondClusterSpec{
+				GatewayVIP: "192.168.0.10",
+				Timezone:   &rainbondv1alpha1.Timezone{Name: "Asia/Shanghai", HostLocaltime: true},
+			},
+		},
+		{
+			name:  "field removed",
+			patch: `{"spec":{"gatewayVIP":null}}`,
+			want: rainbondv1alpha1.RainbondClusterSpec{
+				Timezone: &rainbondv1alpha1.Timezone{Name: "UTC", HostLocaltime: true},
+			},
+		},
+		{
+			name:  "metadata ignored",
+			patch: `{"metadata":{"name":"other","resourceVersion":"1"}}`,
+			want:  cluster.Spec,
+		},
+		{
+			name:    "invalid type",
+			patch:   `{"spec":{"gatewayVIP":8}}`,
+			wantErr: true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			var patch map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(tc.patch), &patch))
+			proposed, err := proposedCluster(cluster, patch)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, proposed.Spec)
+			assert.Equal(t, cluster.ObjectMeta, proposed.ObjectMeta)
+			// the current rainbondcluster is not changed.
+			assert.Equal(t, "UTC", cluster.Spec.Timezone.Name)
+			assert.Equal(t, "192.168.0.10", cluster.Spec.GatewayVIP)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package commonutil
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// DiskAvailable returns the number of bytes available to unprivileged users on the filesystem of the given path.
+func DiskAvailable(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// BytesSize returns a human-readable size in bytes, such as 1.5GB.
+func BytesSize(size uint64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	s := float64(size)
+	i := 0
+	for s >= 1024 && i < len(units)-1 {
+		s /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.4g%s", s, units[i])
+}
@@ -0,0 +1,22 @@
+package commonutil
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const passwordLetters = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// RandomPassword returns a random password of the given length, which is generated by crypto/rand.
+func RandomPassword(length int) (string, error) {
+	password := make([]byte, length)
+	max := big.NewInt(int64(len(passwordLetters)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordLetters[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -19,8 +19,25 @@ const (
 	FoobarPVC = "foobar"
 	// SpecialGatewayLabelKey is a special node label, used to specify where to install the rbd-gateway
 	SpecialGatewayLabelKey = "rainbond.io/gateway"
+	// GatewayLabeledAnnotation records the namespace of the rainbond that labeled the node with SpecialGatewayLabelKey
+	// by the node roles, so that the label is removed once the node is no longer assigned.
+	GatewayLabeledAnnotation = "rainbond.io/gateway-labeled"
 	// SpecialChaosLabelKey is a special node label, used to specify where to install the rbd-chaos
 	SpecialChaosLabelKey = "rainbond.io/chaos"
+	// ChaosLabeledAnnotation records the namespace of the rainbond that labeled the node with SpecialChaosLabelKey
+	// by the chaos node selector or the node roles, so that the label is removed once the node is no longer selected.
+	ChaosLabeledAnnotation = "rainbond.io/chaos-labeled"
+	// SpecialStorageLabelKey is a special node label, used to specify where to run the data stores, such as rbd-db
+	SpecialStorageLabelKey = "rainbond.io/storage"
+	// StorageLabeledAnnotation records the namespace of the rainbond that labeled the node with SpecialStorageLabelKey
+	// by the node roles, so that the label is removed once the node is no longer assigned.
+	StorageLabeledAnnotation = "rainbond.io/storage-labeled"
+	// ExcludeNodeLabelKey is a special node label, the nodes labeled with it to true won't run the rbd-node-proxy
+	ExcludeNodeLabelKey = "rainbond.io/exclude-rbd-node"
+	// SpecialGPULabelKey is a special node label, used to specify where to install the nvidia-device-plugin
+	SpecialGPULabelKey = "rainbond.io/gpu"
+	// InstanceLabelKey labels the cluster-scoped resources with the namespace of the rainbond they belong to.
+	InstanceLabelKey = "rainbond.io/instance"
 	// DefHTTPDomainSuffix -
 	DefHTTPDomainSuffix = "grapps.cn"
 
@@ -33,6 +50,31 @@ const (
 	// AliyunCSINasProvisioner name for aliyun csi nas provisioner
 	AliyunCSINasProvisioner = "aliyun-csi-nas-provisioner"
 
-	// ServiceAccountName is the name of service account
+	// ServiceAccountName is the default name of the service account of the operator, see rbdutil.ServiceAccountName.
 	ServiceAccountName = "rainbond-operator"
+
+	// ConsoleAdminSecretName is the name of the secret that contains the credential of the console administrator.
+	ConsoleAdminSecretName = "rbd-console-admin"
+	// ConsoleAdminRetrievedAnnotation marks the credential of the console administrator has been retrieved.
+	ConsoleAdminRetrievedAnnotation = "rainbond.io/retrieved"
+	// SchemaVersionAnnotation records the schema version of the stored custom resources, which are migrated by the operator.
+	SchemaVersionAnnotation = "rainbond.io/schema-version"
+	// ResumeAnnotation resumes the reconciliation of the halted rbdcomponent, it is removed by the operator once resumed.
+	ResumeAnnotation = "rainbond.io/resume"
+	// AdoptMetricsServerAnnotation lets the metrics-server rbdcomponent take over the metrics API from the existing
+	// metrics-server, once it is found broken or too old.
+	AdoptMetricsServerAnnotation = "rainbond.io/adopt-metrics-server"
+	// ImageInventoryConfigMapName is the name of the ConfigMap that records the images running in the region.
+	ImageInventoryConfigMapName = "rbd-image-inventory"
+	// RbdComponentFinalizer is the finalizer of the rbdcomponents, which deletes the resources generated for them.
+	RbdComponentFinalizer = "rainbond.io/rbdcomponent-cleanup"
+	// PreserveVolumesAnnotation keeps the persistent volume claims and volumes of the rbdcomponent after it is deleted.
+	PreserveVolumesAnnotation = "rainbond.io/preserve-volumes"
+	// RecreateComponentsAnnotation records the deleted mandatory rbdcomponents on the rainbondcluster, which are
+	// recreated once their deletion completes.
+	RecreateComponentsAnnotation = "rainbond.io/recreate-components"
+	// LogConfigMapName is the name of the ConfigMap that adjusts the log verbosity of the operator at runtime.
+	LogConfigMapName = "rainbond-operator-log"
+	// RainbondOperatorConfigName is the name of the RainbondOperatorConfig that holds the settings of the operator.
+	RainbondOperatorConfigName = "rainbondoperatorconfig"
 )
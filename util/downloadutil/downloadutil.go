@@ -23,12 +23,18 @@ type DownloadWithProgress struct {
 	URL          string
 	SavedPath    string
 	Wanted       string
+	// Client is the http client to download with, http.DefaultClient if it is nil.
+	Client *http.Client
 }
 
 // Download download
 func (listener *DownloadWithProgress) Download() error {
+	client := listener.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
 	// Get the data
-	resp, err := http.Get(listener.URL)
+	resp, err := client.Get(listener.URL)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,11 @@
 package etcdutil
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
 	"github.com/coreos/etcd/clientv3"
 )
 
@@ -12,3 +17,25 @@ func NewClient(endpoints []string) (*clientv3.Client, error) {
 
 	return clientv3.New(cfg)
 }
+
+// NewTLSClient creates a new etcd client with the PEM encoded CA certificate, client certificate and key.
+func NewTLSClient(endpoints []string, ca, cert, key []byte) (*clientv3.Client, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no valid CA certificate found")
+	}
+	certificate, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %v", err)
+	}
+	cfg := clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+		TLS: &tls.Config{
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{certificate},
+		},
+	}
+
+	return clientv3.New(cfg)
+}
@@ -0,0 +1,357 @@
+package imageutil
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+)
+
+var challengeParams = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// errNotFound means the manifest or the blob requested does not exist.
+var errNotFound = errors.New("not found")
+
+// maxManifestSize is the limit of the size of the manifests and the configs read.
+const maxManifestSize = 4 << 20
+
+// manifestMediaTypes are the media types of the manifests accepted when checking images.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// ManifestChecker checks if the images exist in the registries by the Docker Registry HTTP API V2.
+type ManifestChecker struct {
+	client   *http.Client
+	username string
+	password string
+}
+
+// NewManifestChecker creates a new manifest checker with the credential of the registries.
+// The addresses map the domains of the registries to the addresses to dial, for the domains can't be resolved,
+// such as goodrain.me. The registries are requested through the proxy, or the one of the environment if it is nil.
+func NewManifestChecker(username, password string, addresses map[string]string, proxy func(*http.Request) (*url.URL, error)) *ManifestChecker {
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, port, err := net.SplitHostPort(addr); err == nil {
+				if address, ok := addresses[host]; ok {
+					addr = net.JoinHostPort(address, port)
+				}
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+		// the image repositories are usually signed by themselves, such as goodrain.me.
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	return &ManifestChecker{
+		client:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		username: username,
+		password: password,
+	}
+}
+
+// Exists checks if the manifest of the image exists with a HEAD request.
+func (m *ManifestChecker) Exists(ctx context.Context, image string) (bool, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false, fmt.Errorf("parse image %s: %v", image, err)
+	}
+	named = reference.TagNameOnly(named)
+	var ref string
+	if canonical, ok := named.(reference.Canonical); ok {
+		ref = canonical.Digest().String()
+	} else {
+		ref = named.(reference.Tagged).Tag()
+	}
+	domain := reference.Domain(named)
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", domain, reference.Path(named), ref)
+
+	status, challenge, err := m.head(ctx, manifestURL, "")
+	if err != nil && strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") {
+		manifestURL = "http" + strings.TrimPrefix(manifestURL, "https")
+		status, challenge, err = m.head(ctx, manifestURL, "")
+	}
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusUnauthorized {
+		authorization, err := m.authorize(ctx, challenge)
+		if err != nil {
+			return false, fmt.Errorf("authorize: %v", err)
+		}
+		if status, _, err = m.head(ctx, manifestURL, authorization); err != nil {
+			return false, err
+		}
+	}
+
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected status %d of manifest %s", status, manifestURL)
+}
+
+func (m *ManifestChecker) head(ctx context.Context, manifestURL, authorization string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	res, err := m.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	_ = res.Body.Close()
+	return res.StatusCode, res.Header.Get("WWW-Authenticate"), nil
+}
+
+// authorize returns the Authorization header for the challenge of the registry, either Basic or Bearer.
+func (m *ManifestChecker) authorize(ctx context.Context, challenge string) (string, error) {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch scheme {
+	case "basic":
+		if m.username == "" {
+			return "", fmt.Errorf("no credential for basic auth")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(m.username+":"+m.password)), nil
+	case "bearer":
+		token, err := m.token(ctx, challenge)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+	return "", fmt.Errorf("unsupported challenge %q", challenge)
+}
+
+// token requests a bearer token from the realm of the challenge.
+func (m *ManifestChecker) token(ctx context.Context, challenge string) (string, error) {
+	params := make(map[string]string)
+	for _, match := range challengeParams.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid realm of challenge %q", challenge)
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if m.username != "" {
+		req.SetBasicAuth(m.username, m.password)
+	}
+	res, err := m.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request token: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request token: unexpected status %d", res.StatusCode)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode token: %v", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// Architectures returns the architectures of the linux platforms of the image. They are read from the manifest
+// list of a multi-arch image, or from the config of a single-arch image.
+func (m *ManifestChecker) Architectures(ctx context.Context, image string) ([]string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, fmt.Errorf("parse image %s: %v", image, err)
+	}
+	named = reference.TagNameOnly(named)
+	var ref string
+	if canonical, ok := named.(reference.Canonical); ok {
+		ref = canonical.Digest().String()
+	} else {
+		ref = named.(reference.Tagged).Tag()
+	}
+	domain := reference.Domain(named)
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+	repoURL := fmt.Sprintf("https://%s/v2/%s", domain, reference.Path(named))
+
+	body, err := m.get(ctx, repoURL+"/manifests/"+ref, strings.Join(manifestMediaTypes, ", "))
+	if err != nil && strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") {
+		repoURL = "http" + strings.TrimPrefix(repoURL, "https")
+		body, err = m.get(ctx, repoURL+"/manifests/"+ref, strings.Join(manifestMediaTypes, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Manifests []struct {
+			Platform struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest of %s: %v", image, err)
+	}
+
+	var architectures []string
+	if len(manifest.Manifests) > 0 {
+		seen := make(map[string]bool)
+		for _, desc := range manifest.Manifests {
+			// the attestations of the buildkit are of the platform unknown/unknown.
+			arch := desc.Platform.Architecture
+			if desc.Platform.OS != "linux" || arch == "" || seen[arch] {
+				continue
+			}
+			seen[arch] = true
+			architectures = append(architectures, arch)
+		}
+		return architectures, nil
+	}
+
+	if manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("no config in manifest of %s", image)
+	}
+	body, err = m.get(ctx, repoURL+"/blobs/"+manifest.Config.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Architecture string `json:"architecture"`
+	}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("decode config of %s: %v", image, err)
+	}
+	if config.Architecture == "" {
+		return nil, fmt.Errorf("no architecture in config of %s", image)
+	}
+	return []string{config.Architecture}, nil
+}
+
+// get returns the body of the GET request, which is authorized on the challenge of the registry.
+func (m *ManifestChecker) get(ctx context.Context, rawURL, accept string) ([]byte, error) {
+	do := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return m.client.Do(req)
+	}
+	res, err := do("")
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusUnauthorized {
+		_ = res.Body.Close()
+		authorization, err := m.authorize(ctx, res.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, fmt.Errorf("authorize: %v", err)
+		}
+		if res, err = do(authorization); err != nil {
+			return nil, err
+		}
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d of %s", res.StatusCode, rawURL)
+	}
+	return ioutil.ReadAll(io.LimitReader(res.Body, maxManifestSize))
+}
+
+// ConfigDigest returns the digest of the config in the manifest of the image, or empty if the image does not exist.
+// It is compared with the one of the image loaded from a tarball, in case of the stale image of the same tag.
+func (m *ManifestChecker) ConfigDigest(ctx context.Context, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("parse image %s: %v", image, err)
+	}
+	named = reference.TagNameOnly(named)
+	var ref string
+	if canonical, ok := named.(reference.Canonical); ok {
+		ref = canonical.Digest().String()
+	} else {
+		ref = named.(reference.Tagged).Tag()
+	}
+	domain := reference.Domain(named)
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", domain, reference.Path(named), ref)
+
+	body, err := m.get(ctx, manifestURL, strings.Join(manifestMediaTypes, ", "))
+	if err != nil && strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") {
+		manifestURL = "http" + strings.TrimPrefix(manifestURL, "https")
+		body, err = m.get(ctx, manifestURL, strings.Join(manifestMediaTypes, ", "))
+	}
+	if err == errNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return "", fmt.Errorf("decode manifest of %s: %v", image, err)
+	}
+	return manifest.Config.Digest, nil
+}
@@ -0,0 +1,103 @@
+package imageutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestCheckerExists(t *testing.T) {
+	var realm string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"token":"abc"}`)
+		case "/v2/rainbond/builder/manifests/v5.3.3":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="hub",scope="repository:rainbond/builder:pull"`, realm))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	realm = server.URL + "/token"
+	u, _ := url.Parse(server.URL)
+
+	// hub.test is resolved to the test server.
+	checker := NewManifestChecker("admin", "secret", map[string]string{"hub.test": u.Hostname()}, nil)
+	exists, err := checker.Exists(context.Background(), "hub.test:"+u.Port()+"/rainbond/builder:v5.3.3")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	exists, err = checker.Exists(context.Background(), "hub.test:"+u.Port()+"/rainbond/runner:v5.3.3")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	checker = NewManifestChecker("admin", "wrong", map[string]string{"hub.test": u.Hostname()}, nil)
+	_, err = checker.Exists(context.Background(), "hub.test:"+u.Port()+"/rainbond/builder:v5.3.3")
+	assert.NotNil(t, err)
+}
+
+func TestManifestCheckerArchitectures(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/rainbond/multi/manifests/v5.3.3":
+			fmt.Fprint(w, `{"manifests":[{"platform":{"architecture":"amd64","os":"linux"}},{"platform":{"architecture":"arm64","os":"linux"}},{"platform":{"architecture":"unknown","os":"unknown"}}]}`)
+		case "/v2/rainbond/single/manifests/v5.3.3":
+			fmt.Fprint(w, `{"config":{"digest":"sha256:abc"}}`)
+		case "/v2/rainbond/single/blobs/sha256:abc":
+			fmt.Fprint(w, `{"architecture":"arm64","os":"linux"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	checker := NewManifestChecker("", "", map[string]string{"hub.test": u.Hostname()}, nil)
+	architectures, err := checker.Architectures(context.Background(), "hub.test:"+u.Port()+"/rainbond/multi:v5.3.3")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"amd64", "arm64"}, architectures)
+
+	architectures, err = checker.Architectures(context.Background(), "hub.test:"+u.Port()+"/rainbond/single:v5.3.3")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"arm64"}, architectures)
+
+	_, err = checker.Architectures(context.Background(), "hub.test:"+u.Port()+"/rainbond/missing:v5.3.3")
+	assert.NotNil(t, err)
+}
+
+func TestManifestCheckerConfigDigest(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/rainbond/builder/manifests/v5.3.3":
+			fmt.Fprint(w, `{"config":{"digest":"sha256:abc"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	checker := NewManifestChecker("", "", map[string]string{"hub.test": u.Hostname()}, nil)
+	digest, err := checker.ConfigDigest(context.Background(), "hub.test:"+u.Port()+"/rainbond/builder:v5.3.3")
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:abc", digest)
+
+	digest, err = checker.ConfigDigest(context.Background(), "hub.test:"+u.Port()+"/rainbond/runner:v5.3.3")
+	assert.Nil(t, err)
+	assert.Equal(t, "", digest)
+}
@@ -0,0 +1,82 @@
+package imageutil
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// TarballImage is the last image in the manifest of a tarball saved by docker save, which is the one tagged and
+// pushed by the image jobs.
+type TarballImage struct {
+	// Name is the last tag of the image.
+	Name string
+	// ConfigDigest is the digest of the config of the image, which is the same in the manifest of the pushed image.
+	ConfigDigest string
+}
+
+// ReadTarballImage reads the image from the manifest.json of the tarball, which may be compressed by gzip.
+func ReadTarballImage(file string) (*TarballImage, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip %s: %v", file, err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no manifest.json in %s", file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tarball %s: %v", file, err)
+		}
+		if path.Clean(hdr.Name) != "manifest.json" {
+			continue
+		}
+		var manifest []struct {
+			Config   string
+			RepoTags []string
+		}
+		if err := json.NewDecoder(io.LimitReader(tr, maxManifestSize)).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("decode manifest.json of %s: %v", file, err)
+		}
+		if len(manifest) == 0 || len(manifest[len(manifest)-1].RepoTags) == 0 {
+			return nil, fmt.Errorf("no tagged image in %s", file)
+		}
+		last := manifest[len(manifest)-1]
+		return &TarballImage{
+			Name:         last.RepoTags[len(last.RepoTags)-1],
+			ConfigDigest: configDigest(last.Config),
+		}, nil
+	}
+}
+
+// configDigest returns the digest of the config in the tarball, which is either <hex>.json, or blobs/sha256/<hex>
+// of the OCI layout.
+func configDigest(config string) string {
+	hex := strings.TrimSuffix(path.Base(config), ".json")
+	algorithm := "sha256"
+	if dir := path.Dir(config); strings.HasPrefix(dir, "blobs/") {
+		algorithm = path.Base(dir)
+	}
+	return algorithm + ":" + hex
+}
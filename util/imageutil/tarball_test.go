@@ -0,0 +1,86 @@
+package imageutil
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTarball(t *testing.T, name, manifest string, compress bool) string {
+	dir, err := ioutil.TempDir("", "tarball")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, name)
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	if compress {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	if err := tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(manifest)); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestReadTarballImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		compress bool
+		want     *TarballImage
+		wantErr  bool
+	}{
+		{
+			name:     "docker save",
+			manifest: `[{"Config":"abc.json","RepoTags":["rainbond/rbd-api:v5.3.3"],"Layers":["a/layer.tar"]}]`,
+			want:     &TarballImage{Name: "rainbond/rbd-api:v5.3.3", ConfigDigest: "sha256:abc"},
+		},
+		{
+			name:     "oci layout",
+			manifest: `[{"Config":"blobs/sha256/abc","RepoTags":["rainbond/rbd-api:v5.3.3"],"Layers":["blobs/sha256/def"]}]`,
+			want:     &TarballImage{Name: "rainbond/rbd-api:v5.3.3", ConfigDigest: "sha256:abc"},
+		},
+		{
+			name:     "gzip with images",
+			manifest: `[{"Config":"abc.json","RepoTags":["rainbond/rbd-api:v5.3.3"]},{"Config":"def.json","RepoTags":["rainbond/rbd-gateway:v5.3.3"]}]`,
+			compress: true,
+			want:     &TarballImage{Name: "rainbond/rbd-gateway:v5.3.3", ConfigDigest: "sha256:def"},
+		},
+		{
+			name:     "untagged",
+			manifest: `[{"Config":"abc.json"}]`,
+			wantErr:  true,
+		},
+	}
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			image, err := ReadTarballImage(writeTarball(t, "image.tar", tc.manifest, tc.compress))
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, image)
+		})
+	}
+}
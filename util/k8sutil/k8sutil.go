@@ -2,11 +2,12 @@ package k8sutil
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
+	"reflect"
 	"sync"
-	"time"
 
 	"github.com/goodrain/rainbond-operator/util/commonutil"
 	"github.com/goodrain/rainbond-operator/util/constants"
@@ -16,11 +17,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/reference"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -119,17 +122,74 @@ func PersistentVolumeReclaimPolicy(persistentVolumeReclaimPolicy corev1.Persiste
 	return &persistentVolumeReclaimPolicy
 }
 
-//UpdateCRStatus udpate cr status
-func UpdateCRStatus(client client.Client, obj client.Object) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-	err := client.Status().Update(ctx, obj)
-	if err != nil {
-		err = client.Status().Update(ctx, obj)
-		if err != nil {
-			return fmt.Errorf("update custom resource status: %v", err)
+// UpdateStatus applies mutate to the latest object, and patches the status with the changes. The patch carries the
+// resourceVersion of the latest object, so the status written by the others in the meantime is not overwritten, and
+// the object is fetched and mutated again on conflicts. mutate should only set the status fields owned by the caller,
+// and nothing is written if it changes nothing. obj is replaced with the latest object on success.
+func UpdateStatus(ctx context.Context, c client.Client, obj client.Object, mutate func(latest client.Object)) error {
+	key := client.ObjectKeyFromObject(obj)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// fetch into an empty object, the fields absent in the latest one are not left over from obj.
+		latest := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+		if err := c.Get(ctx, key, latest); err != nil {
+			return err
 		}
+		old := latest.DeepCopyObject().(client.Object)
+		mutate(latest)
+		if !reflect.DeepEqual(old, latest) {
+			if err := c.Status().Patch(ctx, latest, client.MergeFromWithOptions(old, client.MergeFromWithOptimisticLock{})); err != nil {
+				return err
+			}
+		}
+		reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(latest).Elem())
+		return nil
+	})
+}
+
+// UpdateStatusChanges updates the status of obj with the changes from before to after, which are the status when obj
+// was read and the one desired by the caller. Only the changed fields are written to the latest object, and the lists
+// with the patch merge keys, such as the conditions, are merged by the keys, so that the fields and the conditions
+// written by the others since obj was read are kept. status returns the pointer to the status of the latest object.
+func UpdateStatusChanges(ctx context.Context, c client.Client, obj client.Object, before, after interface{}, status func(latest client.Object) interface{}) error {
+	beforeData, err := json.Marshal(before)
+	if err != nil {
+		return fmt.Errorf("marshal status: %v", err)
+	}
+	afterData, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("marshal status: %v", err)
+	}
+	patch, err := strategicpatch.CreateTwoWayMergePatch(beforeData, afterData, after)
+	if err != nil {
+		return fmt.Errorf("create patch of status: %v", err)
 	}
+
+	var patchErr error
+	if err := UpdateStatus(ctx, c, obj, func(latest client.Object) {
+		patchErr = applyStatusPatch(status(latest), patch, after)
+	}); err != nil {
+		return err
+	}
+	return patchErr
+}
+
+// applyStatusPatch applies the strategic merge patch to the status in place.
+func applyStatusPatch(status interface{}, patch []byte, dataStruct interface{}) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("marshal status: %v", err)
+	}
+	patched, err := strategicpatch.StrategicMergePatch(data, patch, dataStruct)
+	if err != nil {
+		return fmt.Errorf("apply patch of status: %v", err)
+	}
+	// unmarshal into an empty status, the fields removed by the patch are not left over.
+	value := reflect.ValueOf(status).Elem()
+	result := reflect.New(value.Type())
+	if err := json.Unmarshal(patched, result.Interface()); err != nil {
+		return fmt.Errorf("unmarshal status: %v", err)
+	}
+	value.Set(result.Elem())
 	return nil
 }
 
@@ -197,6 +257,54 @@ func EventsForPersistentVolumeClaim(pvc *corev1.PersistentVolumeClaim) (*corev1.
 	return events, err
 }
 
+// EventsForPod returns the events of the pod.
+func EventsForPod(pod *corev1.Pod) (*corev1.EventList, error) {
+	ref, err := reference.GetReference(scheme.Scheme, pod)
+	if err != nil {
+		return nil, err
+	}
+	ref.Kind = ""
+	return GetClientSet().CoreV1().Events(pod.GetNamespace()).Search(scheme.Scheme, ref)
+}
+
+// ClaimAvailable returns the bytes available in the persistent volume claim mounted by the pod, which is reported
+// by the summary API of the kubelet on its node. ok is false if the kubelet does not report it.
+func ClaimAvailable(ctx context.Context, pod *corev1.Pod, claimName string) (available uint64, ok bool, err error) {
+	data, err := GetClientSet().CoreV1().RESTClient().Get().
+		Resource("nodes").Name(pod.Spec.NodeName).SubResource("proxy").Suffix("stats/summary").DoRaw(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("get the stats summary of node %s: %v", pod.Spec.NodeName, err)
+	}
+	var summary struct {
+		Pods []struct {
+			PodRef struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"podRef"`
+			Volume []struct {
+				AvailableBytes *uint64 `json:"availableBytes"`
+				PVCRef         *struct {
+					Name string `json:"name"`
+				} `json:"pvcRef"`
+			} `json:"volume"`
+		} `json:"pods"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return 0, false, fmt.Errorf("decode the stats summary of node %s: %v", pod.Spec.NodeName, err)
+	}
+	for _, stats := range summary.Pods {
+		if stats.PodRef.Name != pod.Name || stats.PodRef.Namespace != pod.Namespace {
+			continue
+		}
+		for _, volume := range stats.Volume {
+			if volume.PVCRef != nil && volume.PVCRef.Name == claimName && volume.AvailableBytes != nil {
+				return *volume.AvailableBytes, true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
+
 // IsPodReady checks if the given pod is ready or not.
 func IsPodReady(pod *corev1.Pod) bool {
 	for _, condition := range pod.Status.Conditions {
@@ -237,3 +345,25 @@ func ListNodes(ctx context.Context, c client.Client) ([]corev1.Node, error) {
 	}
 	return nodeList.Items, nil
 }
+
+// IsLinuxNode checks if the given node runs linux, the nodes without the os label are taken as linux.
+func IsLinuxNode(node *corev1.Node) bool {
+	os, ok := node.Labels[corev1.LabelOSStable]
+	if !ok {
+		os = node.Status.NodeInfo.OperatingSystem
+	}
+	return os == "" || os == "linux"
+}
+
+// SetLinuxNodeSelector keeps the pods off the windows nodes, unless the os is selected already.
+func SetLinuxNodeSelector(spec *corev1.PodSpec) {
+	if _, ok := spec.NodeSelector[corev1.LabelOSStable]; ok {
+		return
+	}
+	// the selector may be shared with the spec of the custom resource, so it is copied.
+	nodeSelector := map[string]string{corev1.LabelOSStable: "linux"}
+	for key, value := range spec.NodeSelector {
+		nodeSelector[key] = value
+	}
+	spec.NodeSelector = nodeSelector
+}
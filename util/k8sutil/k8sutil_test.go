@@ -0,0 +1,125 @@
+package k8sutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		// concurrent is the status written by the others after the first fetch.
+		concurrent func(pod *corev1.Pod)
+		mutate     func(pod *corev1.Pod)
+		wantCalls  int
+		want       corev1.PodStatus
+	}{
+		{
+			name: "patch the status",
+			mutate: func(pod *corev1.Pod) {
+				pod.Status.Message = "updated"
+			},
+			wantCalls: 1,
+			want:      corev1.PodStatus{Phase: corev1.PodPending, Message: "updated"},
+		},
+		{
+			name: "retry on conflict and keep the concurrent status",
+			concurrent: func(pod *corev1.Pod) {
+				pod.Status.Phase = corev1.PodRunning
+			},
+			mutate: func(pod *corev1.Pod) {
+				pod.Status.Message = "updated"
+			},
+			wantCalls: 2,
+			want:      corev1.PodStatus{Phase: corev1.PodRunning, Message: "updated"},
+		},
+		{
+			name:      "nothing changed",
+			mutate:    func(pod *corev1.Pod) {},
+			wantCalls: 1,
+			want:      corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "rbd-system"},
+				Status:     corev1.PodStatus{Phase: corev1.PodPending},
+			}
+			cli := fake.NewFakeClientWithScheme(scheme.Scheme, pod.DeepCopy())
+
+			calls := 0
+			obj := pod.DeepCopy()
+			err := UpdateStatus(ctx, cli, obj, func(latest client.Object) {
+				calls++
+				if calls == 1 && tc.concurrent != nil {
+					other := &corev1.Pod{}
+					assert.Nil(t, cli.Get(ctx, client.ObjectKeyFromObject(pod), other))
+					tc.concurrent(other)
+					assert.Nil(t, cli.Status().Update(ctx, other))
+				}
+				tc.mutate(latest.(*corev1.Pod))
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantCalls, calls)
+
+			got := &corev1.Pod{}
+			assert.Nil(t, cli.Get(ctx, client.ObjectKeyFromObject(pod), got))
+			assert.Equal(t, tc.want, got.Status)
+			assert.Equal(t, got.ResourceVersion, obj.ResourceVersion, "obj is replaced with the latest")
+		})
+	}
+}
+
+func TestUpdateStatusChanges(t *testing.T) {
+	ctx := context.Background()
+	before := corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{
+			{Type: corev1.PodScheduled, Status: corev1.ConditionFalse},
+			{Type: corev1.PodInitialized, Status: corev1.ConditionFalse},
+			{Type: corev1.ContainersReady, Status: corev1.ConditionFalse},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "rbd-system"},
+		Status:     before,
+	}
+	// the others update a condition and add a new one after the pod is read.
+	concurrent := pod.DeepCopy()
+	concurrent.Status.Conditions[2].Status = corev1.ConditionTrue
+	concurrent.Status.Conditions = append(concurrent.Status.Conditions, corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue})
+	cli := fake.NewFakeClientWithScheme(scheme.Scheme, concurrent)
+
+	// the caller changes the message, updates a condition and removes another one.
+	after := before.DeepCopy()
+	after.Message = "updated"
+	after.Conditions[0].Status = corev1.ConditionTrue
+	after.Conditions = append(after.Conditions[:1], after.Conditions[2])
+	obj := pod.DeepCopy()
+	err := UpdateStatusChanges(ctx, cli, obj, &before, after, func(latest client.Object) interface{} {
+		return &latest.(*corev1.Pod).Status
+	})
+	assert.Nil(t, err)
+
+	got := &corev1.Pod{}
+	assert.Nil(t, cli.Get(ctx, client.ObjectKeyFromObject(pod), got))
+	assert.Equal(t, "updated", got.Status.Message)
+	assert.Equal(t, corev1.PodPending, got.Status.Phase)
+	assert.ElementsMatch(t, []corev1.PodCondition{
+		{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+		{Type: corev1.ContainersReady, Status: corev1.ConditionTrue},
+		{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+	}, got.Status.Conditions)
+	assert.Equal(t, got.Status, obj.Status, "obj is replaced with the latest")
+}
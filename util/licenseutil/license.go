@@ -0,0 +1,54 @@
+package licenseutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SecretKey is the key of the license file in the license secret.
+const SecretKey = "license"
+
+// License is the license of rainbond enterprise edition.
+// The license file is a json document, which may be base64 encoded:
+//
+//	{"company": "goodrain", "expire_time": "2022-01-01T00:00:00Z", "node": 10}
+type License struct {
+	Company    string    `json:"company"`
+	ExpireTime time.Time `json:"expire_time"`
+	// The maximum number of nodes, 0 means no limit.
+	Node int `json:"node"`
+}
+
+// Parse parses the license file.
+func Parse(data []byte) (*License, error) {
+	content := strings.TrimSpace(string(data))
+	if content == "" {
+		return nil, fmt.Errorf("empty license")
+	}
+	if !strings.HasPrefix(content, "{") {
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("decode license: %v", err)
+		}
+		content = string(decoded)
+	}
+	var license License
+	if err := json.Unmarshal([]byte(content), &license); err != nil {
+		return nil, fmt.Errorf("unmarshal license: %v", err)
+	}
+	return &license, nil
+}
+
+// Validate checks if the license is expired, or the number of nodes exceeds the limit.
+func (l *License) Validate(now time.Time, nodes int) error {
+	if !l.ExpireTime.IsZero() && now.After(l.ExpireTime) {
+		return fmt.Errorf("license expired at %s", l.ExpireTime.Format(time.RFC3339))
+	}
+	if l.Node > 0 && nodes > l.Node {
+		return fmt.Errorf("the number of nodes %d exceeds the limit %d of the license", nodes, l.Node)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package licenseutil
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	content := `{"company": "goodrain", "expire_time": "2022-01-01T00:00:00Z", "node": 10}`
+
+	license, err := Parse([]byte(content))
+	assert.Nil(t, err)
+	assert.Equal(t, "goodrain", license.Company)
+	assert.Equal(t, 10, license.Node)
+
+	encoded, err := Parse([]byte(base64.StdEncoding.EncodeToString([]byte(content))))
+	assert.Nil(t, err)
+	assert.Equal(t, license, encoded)
+
+	_, err = Parse([]byte("foobar"))
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	license := &License{
+		ExpireTime: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Node:       3,
+	}
+	assert.Nil(t, license.Validate(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 3))
+	assert.NotNil(t, license.Validate(time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), 3))
+	assert.NotNil(t, license.Validate(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 4))
+}
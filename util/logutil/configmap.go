@@ -0,0 +1,121 @@
+package logutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultKey is the key of the default verbosity in the ConfigMap.
+const defaultKey = "default"
+
+// resyncInterval is how often the ConfigMap is read.
+const resyncInterval = 30 * time.Second
+
+// ConfigMapWatcher applies the verbosities in the ConfigMap to the levels. The key default is the default
+// verbosity, and the other keys are the names of the loggers, such as controllers.RbdComponent.
+// The verbosities given by the flags are restored once the ConfigMap is deleted.
+type ConfigMapWatcher struct {
+	client client.Client
+	key    types.NamespacedName
+	levels *Levels
+	log    logr.Logger
+
+	mu  sync.Mutex
+	def int
+
+	// the verbosities applied last time.
+	applied    bool
+	appliedDef int
+	overrides  map[string]int
+}
+
+// NewConfigMapWatcher creates a new watcher of the ConfigMap, def is the default verbosity given by the flags.
+func NewConfigMapWatcher(client client.Client, namespace, name string, levels *Levels, def int, log logr.Logger) *ConfigMapWatcher {
+	return &ConfigMapWatcher{
+		client: client,
+		key:    types.NamespacedName{Namespace: namespace, Name: name},
+		levels: levels,
+		def:    def,
+		log:    log,
+	}
+}
+
+// SetDefault replaces the default verbosity given by the flags, such as by the RainbondOperatorConfig.
+// It is applied on the next sync, unless the ConfigMap gives the default verbosity.
+func (w *ConfigMapWatcher) SetDefault(def int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.def = def
+}
+
+// Start reads the ConfigMap periodically until the context is done.
+func (w *ConfigMapWatcher) Start(ctx context.Context) error {
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.sync(ctx); err != nil {
+			w.log.Error(err, "sync log levels")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns false, for the log levels apply to all the replicas of the operator.
+func (w *ConfigMapWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *ConfigMapWatcher) sync(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cm := &corev1.ConfigMap{}
+	if err := w.client.Get(ctx, w.key, cm); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return fmt.Errorf("get configmap %s: %v", w.key, err)
+		}
+		cm.Data = nil
+	}
+
+	w.mu.Lock()
+	def := w.def
+	w.mu.Unlock()
+	def, overrides := ParseLevels(cm.Data, def)
+	if w.applied && def == w.appliedDef && reflect.DeepEqual(overrides, w.overrides) {
+		return nil
+	}
+	w.levels.Set(def, overrides)
+	w.applied, w.appliedDef, w.overrides = true, def, overrides
+	w.log.Info("log levels changed", "default", def, "overrides", overrides)
+	return nil
+}
+
+// ParseLevels parses the default verbosity and the verbosities of the loggers, the invalid ones are ignored.
+func ParseLevels(data map[string]string, def int) (int, map[string]int) {
+	overrides := make(map[string]int)
+	for key, value := range data {
+		verbosity, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		if key == defaultKey {
+			def = verbosity
+			continue
+		}
+		overrides[key] = verbosity
+	}
+	return def, overrides
+}
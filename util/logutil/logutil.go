@@ -0,0 +1,124 @@
+package logutil
+
+import (
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// MostVerbose is the zap level enabling all the verbosities, the verbosities are filtered by the levels instead.
+var MostVerbose = zap.NewAtomicLevelAt(zapcore.Level(math.MinInt8))
+
+// VerbosityOf returns the verbosity of the zap level, such as 0 for info, and 4 for the level given by --zap-log-level=4.
+func VerbosityOf(level zapcore.LevelEnabler) int {
+	switch l := level.(type) {
+	case zap.AtomicLevel:
+		return -int(l.Level())
+	case zapcore.Level:
+		return -int(l)
+	}
+	return 0
+}
+
+// Levels are the verbosities of the loggers, which can be changed at runtime.
+// The verbosity of a logger is the one of the longest configured prefix of its name,
+// such as controllers.RbdComponent for the logger named controllers.RbdComponent.etcd,
+// or the default verbosity if no prefix is configured.
+type Levels struct {
+	mu        sync.RWMutex
+	def       int
+	overrides map[string]int
+}
+
+// NewLevels creates new levels with the default verbosity.
+func NewLevels(def int) *Levels {
+	return &Levels{def: def}
+}
+
+// Set replaces the default verbosity and the verbosities of the loggers.
+func (l *Levels) Set(def int, overrides map[string]int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.def = def
+	l.overrides = overrides
+}
+
+// Verbosity returns the verbosity of the logger with the name.
+func (l *Levels) Verbosity(name string) int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	verbosity, matched := l.def, -1
+	for prefix, v := range l.overrides {
+		if len(prefix) <= matched {
+			continue
+		}
+		if name == prefix || strings.HasPrefix(name, prefix+".") {
+			verbosity, matched = v, len(prefix)
+		}
+	}
+	return verbosity
+}
+
+// logger filters the info logs of the delegate by the verbosity of its name.
+type logger struct {
+	delegate logr.Logger
+	levels   *Levels
+	name     string
+	level    int
+}
+
+// New returns a logger whose verbosity is controlled by the levels.
+// The delegate should be as verbose as the most verbose logger could be.
+func New(delegate logr.Logger, levels *Levels) logr.Logger {
+	return &logger{delegate: delegate, levels: levels}
+}
+
+func (l *logger) Enabled() bool {
+	return l.level <= l.levels.Verbosity(l.name) && l.delegate.Enabled()
+}
+
+func (l *logger) Info(msg string, keysAndValues ...interface{}) {
+	if l.level > l.levels.Verbosity(l.name) {
+		return
+	}
+	l.delegate.Info(msg, keysAndValues...)
+}
+
+func (l *logger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.delegate.Error(err, msg, keysAndValues...)
+}
+
+func (l *logger) V(level int) logr.Logger {
+	return &logger{
+		delegate: l.delegate.V(level),
+		levels:   l.levels,
+		name:     l.name,
+		level:    l.level + level,
+	}
+}
+
+func (l *logger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &logger{
+		delegate: l.delegate.WithValues(keysAndValues...),
+		levels:   l.levels,
+		name:     l.name,
+		level:    l.level,
+	}
+}
+
+func (l *logger) WithName(name string) logr.Logger {
+	fullName := name
+	if l.name != "" {
+		fullName = l.name + "." + name
+	}
+	return &logger{
+		delegate: l.delegate.WithName(name),
+		levels:   l.levels,
+		name:     fullName,
+		level:    l.level,
+	}
+}
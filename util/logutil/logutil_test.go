@@ -0,0 +1,26 @@
+package logutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevels(t *testing.T) {
+	def, overrides := ParseLevels(map[string]string{
+		"default":                     "2",
+		"controllers":                 "4",
+		"controllers.RbdComponent":    "6",
+		"controllers.RainbondPackage": "invalid",
+	}, 0)
+	assert.Equal(t, 2, def)
+	assert.Equal(t, map[string]int{"controllers": 4, "controllers.RbdComponent": 6}, overrides)
+
+	levels := NewLevels(0)
+	levels.Set(def, overrides)
+	assert.Equal(t, 6, levels.Verbosity("controllers.RbdComponent"))
+	assert.Equal(t, 6, levels.Verbosity("controllers.RbdComponent.etcd"))
+	assert.Equal(t, 4, levels.Verbosity("controllers.RbdComponentX"))
+	assert.Equal(t, 4, levels.Verbosity("controllers.RainbondPackage"))
+	assert.Equal(t, 2, levels.Verbosity("setup"))
+}
@@ -0,0 +1,95 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Reason is the reason of the notification.
+type Reason string
+
+const (
+	// ReasonPhaseChanged means the phase of the region changed.
+	ReasonPhaseChanged Reason = "PhaseChanged"
+	// ReasonComponentDegraded means a component is no longer ready.
+	ReasonComponentDegraded Reason = "ComponentDegraded"
+	// ReasonComponentRecovered means a degraded component is ready again.
+	ReasonComponentRecovered Reason = "ComponentRecovered"
+)
+
+// Event is the change of the region notified.
+type Event struct {
+	Reason Reason `json:"reason"`
+	// Region is the namespace/name of the rainbondcluster.
+	Region string `json:"region"`
+	// Component is the name of the rbdcomponent, empty if the event is about the region.
+	Component string `json:"component,omitempty"`
+	// From and To are the phases of the region, only for the reason PhaseChanged.
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Text returns the event as a line of text for the chat tools.
+func (e *Event) Text() string {
+	var text string
+	switch e.Reason {
+	case ReasonPhaseChanged:
+		text = fmt.Sprintf("[Rainbond] region %s: %s -> %s", e.Region, e.From, e.To)
+	case ReasonComponentDegraded:
+		text = fmt.Sprintf("[Rainbond] region %s: %s is degraded", e.Region, e.Component)
+	case ReasonComponentRecovered:
+		text = fmt.Sprintf("[Rainbond] region %s: %s is recovered", e.Region, e.Component)
+	default:
+		text = fmt.Sprintf("[Rainbond] region %s: %s", e.Region, e.Reason)
+	}
+	if e.Message != "" {
+		text += ": " + e.Message
+	}
+	return text
+}
+
+// Body returns the body of the event in the format, one of JSON, Slack and DingTalk.
+func Body(format string, event *Event) ([]byte, error) {
+	switch format {
+	case "", "JSON":
+		return json.Marshal(event)
+	case "Slack":
+		return json.Marshal(map[string]string{"text": event.Text()})
+	case "DingTalk":
+		return json.Marshal(map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": event.Text()},
+		})
+	}
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+// Send posts the event to the webhook in the format.
+func Send(ctx context.Context, url, format string, event *Event) error {
+	body, err := Body(format, event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
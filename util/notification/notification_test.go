@@ -0,0 +1,33 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBody(t *testing.T) {
+	event := &Event{
+		Reason:    ReasonComponentDegraded,
+		Region:    "rbd-system/rainbondcluster",
+		Component: "rbd-api",
+		Message:   "phase Failed",
+		Time:      time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	body, err := Body("Slack", event)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"text":"[Rainbond] region rbd-system/rainbondcluster: rbd-api is degraded: phase Failed"}`, string(body))
+
+	body, err = Body("DingTalk", event)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"msgtype":"text","text":{"content":"[Rainbond] region rbd-system/rainbondcluster: rbd-api is degraded: phase Failed"}}`, string(body))
+
+	body, err = Body("", event)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"reason":"ComponentDegraded","region":"rbd-system/rainbondcluster","component":"rbd-api","message":"phase Failed","time":"2021-01-01T00:00:00Z"}`, string(body))
+
+	_, err = Body("WeChat", event)
+	assert.NotNil(t, err)
+}
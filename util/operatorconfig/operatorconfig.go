@@ -0,0 +1,194 @@
+package operatorconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"github.com/goodrain/rainbond-operator/util/rbdutil"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// ImageArchitectureDetection detects the architectures of the images of the rbdcomponents in the clusters
+	// with nodes of mixed architectures, and pins the components to the compatible nodes.
+	ImageArchitectureDetection = "ImageArchitectureDetection"
+)
+
+// defaultFeatureGates are the known feature gates and whether they are enabled by default.
+var defaultFeatureGates = map[string]bool{
+	ImageArchitectureDetection: true,
+}
+
+var (
+	featureGatesMu sync.RWMutex
+	featureGates   = mergeFeatureGates(nil)
+)
+
+// Enabled checks if the feature gate is enabled, the unknown ones are disabled.
+func Enabled(name string) bool {
+	featureGatesMu.RLock()
+	defer featureGatesMu.RUnlock()
+	return featureGates[name]
+}
+
+func setFeatureGates(gates map[string]bool) {
+	featureGatesMu.Lock()
+	defer featureGatesMu.Unlock()
+	featureGates = gates
+}
+
+var (
+	// configName is the name of the RainbondOperatorConfig of the operator.
+	configName = constants.RainbondOperatorConfigName
+	// defaultWatchNamespaces are watched if the RainbondOperatorConfig doesn't give the namespaces, all if empty.
+	defaultWatchNamespaces []string
+)
+
+// SetInstance makes the operator manage the rainbond in the namespace only, so that the rainbonds in the other
+// namespaces of the cluster can be managed by their own operators. The RainbondOperatorConfig is named after
+// the namespace, and only the namespace is watched unless the config gives the namespaces.
+// It is called once at startup, before the config is loaded.
+func SetInstance(namespace string) {
+	configName = rbdutil.InstanceName(constants.RainbondOperatorConfigName, namespace)
+	defaultWatchNamespaces = []string{namespace}
+}
+
+// Settings are the settings of the operator resolved from the RainbondOperatorConfig.
+type Settings struct {
+	// MaxConcurrentReconciles is the maximum number of concurrent reconciles of each controller.
+	MaxConcurrentReconciles int
+	// LogLevel is the default verbosity of the logs, nil to use the one given by the flags.
+	LogLevel *int
+	// WatchNamespaces are the sorted namespaces whose custom resources are reconciled, all if empty.
+	// The namespace of the instance is watched by default, see SetInstance.
+	WatchNamespaces []string
+	// FeatureGates are the known feature gates, with the defaults of the ones not configured.
+	FeatureGates map[string]bool
+	// UnknownFeatureGates are the configured feature gates that are not known, which are ignored.
+	UnknownFeatureGates []string
+}
+
+// Load returns the RainbondOperatorConfig, nil if it or its CRD does not exist.
+func Load(ctx context.Context, c client.Reader) (*rainbondv1alpha1.RainbondOperatorConfig, error) {
+	config := &rainbondv1alpha1.RainbondOperatorConfig{}
+	if err := c.Get(ctx, types.NamespacedName{Name: configName}, config); err != nil {
+		if k8sErrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get rainbondoperatorconfig %s: %v", configName, err)
+	}
+	return config, nil
+}
+
+// Resolve returns the settings of the config, the defaults if config is nil.
+func Resolve(config *rainbondv1alpha1.RainbondOperatorConfig) *Settings {
+	settings := &Settings{
+		MaxConcurrentReconciles: 1,
+		FeatureGates:            mergeFeatureGates(nil),
+	}
+	if config == nil {
+		settings.WatchNamespaces = append(settings.WatchNamespaces, defaultWatchNamespaces...)
+		return settings
+	}
+
+	spec := config.Spec
+	if spec.MaxConcurrentReconciles > 0 {
+		settings.MaxConcurrentReconciles = int(spec.MaxConcurrentReconciles)
+	}
+	if spec.LogLevel != nil {
+		level := int(*spec.LogLevel)
+		settings.LogLevel = &level
+	}
+	seen := make(map[string]bool)
+	for _, ns := range spec.WatchNamespaces {
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		settings.WatchNamespaces = append(settings.WatchNamespaces, ns)
+	}
+	if len(settings.WatchNamespaces) == 0 {
+		settings.WatchNamespaces = append(settings.WatchNamespaces, defaultWatchNamespaces...)
+	}
+	sort.Strings(settings.WatchNamespaces)
+	settings.FeatureGates = mergeFeatureGates(spec.FeatureGates)
+	for name := range spec.FeatureGates {
+		if _, ok := defaultFeatureGates[name]; !ok {
+			settings.UnknownFeatureGates = append(settings.UnknownFeatureGates, name)
+		}
+	}
+	sort.Strings(settings.UnknownFeatureGates)
+	return settings
+}
+
+// CacheNamespace returns the namespace that the cache of the manager is limited to if only one namespace is watched,
+// or empty to cache all the namespaces.
+func (s *Settings) CacheNamespace() string {
+	if s == nil || len(s.WatchNamespaces) != 1 {
+		return ""
+	}
+	return s.WatchNamespaces[0]
+}
+
+// NewCache returns the function creating the cache of the manager limited to the namespaces if more than one
+// namespace is watched, or nil otherwise. The cluster-scoped objects are not served by the cache then.
+func (s *Settings) NewCache() cache.NewCacheFunc {
+	if s == nil || len(s.WatchNamespaces) < 2 {
+		return nil
+	}
+	return cache.MultiNamespacedCacheBuilder(s.WatchNamespaces)
+}
+
+// ControllerOptions returns the options of the controllers, the defaults if s is nil.
+func (s *Settings) ControllerOptions() controller.Options {
+	if s == nil {
+		return controller.Options{}
+	}
+	return controller.Options{MaxConcurrentReconciles: s.MaxConcurrentReconciles}
+}
+
+// Watched checks if the custom resources in the namespace are reconciled.
+func (s *Settings) Watched(namespace string) bool {
+	if s == nil || len(s.WatchNamespaces) == 0 {
+		return true
+	}
+	i := sort.SearchStrings(s.WatchNamespaces, namespace)
+	return i < len(s.WatchNamespaces) && s.WatchNamespaces[i] == namespace
+}
+
+// NamespacePredicate filters out the events of the objects in the namespaces not watched.
+func (s *Settings) NamespacePredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return s.Watched(obj.GetNamespace())
+	})
+}
+
+// needRestart checks if the operator needs to restart to apply the other settings,
+// for the controllers are set up with them.
+func (s *Settings) needRestart(other *Settings) bool {
+	return s.MaxConcurrentReconciles != other.MaxConcurrentReconciles ||
+		!reflect.DeepEqual(s.WatchNamespaces, other.WatchNamespaces)
+}
+
+// mergeFeatureGates returns the known feature gates, with the defaults of the ones not given.
+func mergeFeatureGates(gates map[string]bool) map[string]bool {
+	merged := make(map[string]bool, len(defaultFeatureGates))
+	for name, enabled := range defaultFeatureGates {
+		if value, ok := gates[name]; ok {
+			enabled = value
+		}
+		merged[name] = enabled
+	}
+	return merged
+}
@@ -0,0 +1,89 @@
+package operatorconfig
+
+import (
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	defaults := Resolve(nil)
+	assert.Equal(t, 1, defaults.MaxConcurrentReconciles)
+	assert.Nil(t, defaults.LogLevel)
+	assert.True(t, defaults.Watched("rbd-system"))
+	assert.True(t, defaults.FeatureGates[ImageArchitectureDetection])
+
+	logLevel := int32(2)
+	config := &rainbondv1alpha1.RainbondOperatorConfig{
+		Spec: rainbondv1alpha1.RainbondOperatorConfigSpec{
+			MaxConcurrentReconciles: 3,
+			LogLevel:                &logLevel,
+			WatchNamespaces:         []string{"rbd-system", "", "default", "rbd-system"},
+			FeatureGates: map[string]bool{
+				ImageArchitectureDetection: false,
+				"Unknown":                  true,
+			},
+		},
+	}
+	settings := Resolve(config)
+	assert.Equal(t, 3, settings.MaxConcurrentReconciles)
+	assert.Equal(t, 3, settings.ControllerOptions().MaxConcurrentReconciles)
+	if assert.NotNil(t, settings.LogLevel) {
+		assert.Equal(t, 2, *settings.LogLevel)
+	}
+	assert.Equal(t, []string{"default", "rbd-system"}, settings.WatchNamespaces)
+	assert.True(t, settings.Watched("rbd-system"))
+	assert.False(t, settings.Watched("kube-system"))
+	assert.Equal(t, map[string]bool{ImageArchitectureDetection: false}, settings.FeatureGates)
+	assert.Equal(t, []string{"Unknown"}, settings.UnknownFeatureGates)
+
+	assert.True(t, defaults.needRestart(settings))
+	config.Spec.MaxConcurrentReconciles = 1
+	config.Spec.WatchNamespaces = nil
+	assert.False(t, defaults.needRestart(Resolve(config)))
+}
+
+func TestSetInstance(t *testing.T) {
+	defer func() {
+		configName = "rainbondoperatorconfig"
+		defaultWatchNamespaces = nil
+	}()
+
+	SetInstance("region-b")
+	assert.Equal(t, "rainbondoperatorconfig-region-b", configName)
+	settings := Resolve(nil)
+	assert.True(t, settings.Watched("region-b"))
+	assert.False(t, settings.Watched("rbd-system"))
+
+	settings = Resolve(&rainbondv1alpha1.RainbondOperatorConfig{
+		Spec: rainbondv1alpha1.RainbondOperatorConfigSpec{
+			WatchNamespaces: []string{"region-b", "region-c"},
+		},
+	})
+	assert.Equal(t, []string{"region-b", "region-c"}, settings.WatchNamespaces)
+}
+
+func TestWatcherApply(t *testing.T) {
+	settings := Resolve(&rainbondv1alpha1.RainbondOperatorConfig{
+		Spec: rainbondv1alpha1.RainbondOperatorConfigSpec{
+			FeatureGates: map[string]bool{ImageArchitectureDetection: false},
+		},
+	})
+	w := NewWatcher(nil, settings, nil, 0, nil)
+	assert.False(t, Enabled(ImageArchitectureDetection))
+	assert.False(t, Enabled("Unknown"))
+
+	w.apply(Resolve(nil))
+	assert.True(t, Enabled(ImageArchitectureDetection))
+}
+
+func TestCacheNamespace(t *testing.T) {
+	assert.Equal(t, "", Resolve(nil).CacheNamespace())
+	assert.Equal(t, "region-b", (&Settings{WatchNamespaces: []string{"region-b"}}).CacheNamespace())
+	assert.Equal(t, "", (&Settings{WatchNamespaces: []string{"region-b", "region-c"}}).CacheNamespace())
+
+	assert.Nil(t, Resolve(nil).NewCache())
+	assert.Nil(t, (&Settings{WatchNamespaces: []string{"region-b"}}).NewCache())
+	assert.NotNil(t, (&Settings{WatchNamespaces: []string{"region-b", "region-c"}}).NewCache())
+}
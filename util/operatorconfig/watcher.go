@@ -0,0 +1,125 @@
+package operatorconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/k8sutil"
+	"github.com/goodrain/rainbond-operator/util/logutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resyncInterval is how often the RainbondOperatorConfig is read.
+const resyncInterval = 30 * time.Second
+
+// ErrRestartRequired is returned by the watcher once the settings the controllers are set up with change,
+// the operator exits and is restarted to apply them.
+var ErrRestartRequired = errors.New("restart required to apply the rainbondoperatorconfig")
+
+// +kubebuilder:rbac:groups=rainbond.io,resources=rainbondoperatorconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rainbond.io,resources=rainbondoperatorconfigs/status,verbs=get;update;patch
+
+// Watcher applies the RainbondOperatorConfig at runtime. The log level and the feature gates are applied in place,
+// while the change of the concurrency or the watch namespaces stops the watcher with ErrRestartRequired.
+type Watcher struct {
+	client     client.Client
+	settings   *Settings
+	logWatcher *logutil.ConfigMapWatcher
+	logLevel   int
+	log        logr.Logger
+}
+
+// NewWatcher creates a new watcher and applies the settings the operator starts with.
+// logLevel is the default verbosity given by the flags.
+func NewWatcher(client client.Client, settings *Settings, logWatcher *logutil.ConfigMapWatcher, logLevel int, log logr.Logger) *Watcher {
+	w := &Watcher{
+		client:     client,
+		settings:   settings,
+		logWatcher: logWatcher,
+		logLevel:   logLevel,
+		log:        log,
+	}
+	w.apply(settings)
+	return w
+}
+
+// Start reads the RainbondOperatorConfig periodically until the context is done, or a restart is required.
+func (w *Watcher) Start(ctx context.Context) error {
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.sync(ctx); err != nil {
+			if errors.Is(err, ErrRestartRequired) {
+				return err
+			}
+			w.log.Error(err, "sync operator config")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns false, for the settings apply to all the replicas of the operator.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *Watcher) sync(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	config, err := Load(ctx, w.client)
+	if err != nil {
+		return err
+	}
+
+	settings := Resolve(config)
+	if w.settings.needRestart(settings) {
+		w.log.Info("operator config changed, restart to apply it",
+			"maxConcurrentReconciles", settings.MaxConcurrentReconciles, "watchNamespaces", settings.WatchNamespaces)
+		return ErrRestartRequired
+	}
+	w.apply(settings)
+	if config == nil {
+		return nil
+	}
+	return w.updateStatus(ctx, config, settings)
+}
+
+func (w *Watcher) apply(settings *Settings) {
+	setFeatureGates(settings.FeatureGates)
+	logLevel := w.logLevel
+	if settings.LogLevel != nil {
+		logLevel = *settings.LogLevel
+	}
+	if w.logWatcher != nil {
+		w.logWatcher.SetDefault(logLevel)
+	}
+}
+
+func (w *Watcher) updateStatus(ctx context.Context, config *rainbondv1alpha1.RainbondOperatorConfig, settings *Settings) error {
+	status := rainbondv1alpha1.RainbondOperatorConfigStatus{
+		ObservedGeneration: config.Generation,
+		FeatureGates:       settings.FeatureGates,
+	}
+	if len(settings.UnknownFeatureGates) > 0 {
+		status.Message = fmt.Sprintf("unknown feature gates are ignored: %s", strings.Join(settings.UnknownFeatureGates, ", "))
+	}
+	if reflect.DeepEqual(config.Status, status) {
+		return nil
+	}
+	if err := k8sutil.UpdateStatus(ctx, w.client, config, func(latest client.Object) {
+		latest.(*rainbondv1alpha1.RainbondOperatorConfig).Status = status
+	}); err != nil {
+		return fmt.Errorf("update status of rainbondoperatorconfig: %v", err)
+	}
+	return nil
+}
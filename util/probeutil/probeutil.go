@@ -38,6 +38,17 @@ func MakeReadinessProbeTCP(host string, port int) *corev1.Probe {
 	return probe
 }
 
+// MakeStartupProbe makes a startup probe of the same handler as the liveness probe. The container is given
+// period * failureThreshold seconds to start before it is restarted.
+func MakeStartupProbe(liveness *corev1.Probe, period, failureThreshold int32) *corev1.Probe {
+	if liveness == nil {
+		return nil
+	}
+	probe := &corev1.Probe{Handler: *liveness.Handler.DeepCopy()}
+	SetProbeArgs(probe, 0, liveness.TimeoutSeconds, period, 1, failureThreshold)
+	return probe
+}
+
 // MakeProbe -
 func MakeProbe(kind ProbeKind, host, path string, port int, scheme corev1.URIScheme, headers []corev1.HTTPHeader) *corev1.Probe {
 	handler := corev1.Handler{}
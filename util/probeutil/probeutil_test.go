@@ -48,3 +48,40 @@ func TestMakeReadinessProbeTCP(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeStartupProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		liveness *corev1.Probe
+		want     *corev1.Probe
+	}{
+		{
+			name:     "http",
+			liveness: MakeLivenessProbeHTTP("", "/monitor/health", 3329),
+			want: &corev1.Probe{
+				Handler: corev1.Handler{
+					HTTPGet: &corev1.HTTPGetAction{
+						Path:   "/monitor/health",
+						Port:   intstr.FromInt(3329),
+						Scheme: corev1.URISchemeHTTP,
+					},
+				},
+				InitialDelaySeconds: 0,
+				TimeoutSeconds:      5,
+				PeriodSeconds:       10,
+				SuccessThreshold:    1,
+				FailureThreshold:    60,
+			},
+		},
+		{
+			name: "without liveness probe",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MakeStartupProbe(tt.liveness, 10, 60); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MakeStartupProbe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package proxyutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/goodrain/rainbond-operator/util/commonutil"
+	"github.com/goodrain/rainbond-operator/util/constants"
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// UsernameKey is the key of the username in the credential secret of the proxy.
+	UsernameKey = "username"
+	// PasswordKey is the key of the password in the credential secret of the proxy.
+	PasswordKey = "password"
+	// SecretName is the name of the secret created by the operator, which contains the proxy environment
+	// variables with the credential for the pods of the region.
+	SecretName = "rbd-proxy"
+)
+
+// envNames are the proxy environment variables, in both cases as the tools read either of them.
+var envNames = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// Proxy is the resolved proxy of a region, the credential is in the urls.
+type Proxy struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// Get returns the proxy of the cluster with the credential in the secret, nil if there is no proxy.
+// The addresses in the cluster and the extra ones are added to the no proxy list.
+func Get(ctx context.Context, c client.Reader, cluster *rainbondv1alpha1.RainbondCluster, noProxy ...string) (*Proxy, error) {
+	config := cluster.Spec.Proxy
+	if config == nil || (config.HTTPProxy == "" && config.HTTPSProxy == "") {
+		return nil, nil
+	}
+
+	var user *url.Userinfo
+	if name := config.CredentialSecretName; name != "" {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: cluster.Namespace, Name: name}, secret); err != nil {
+			return nil, fmt.Errorf("get credential secret %s of proxy: %v", name, err)
+		}
+		username := string(secret.Data[UsernameKey])
+		if username == "" {
+			return nil, fmt.Errorf("no %s in credential secret %s of proxy", UsernameKey, name)
+		}
+		user = url.UserPassword(username, string(secret.Data[PasswordKey]))
+	}
+
+	httpProxy, err := withUser(config.HTTPProxy, user)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http proxy: %v", err)
+	}
+	httpsProxy, err := withUser(config.HTTPSProxy, user)
+	if err != nil {
+		return nil, fmt.Errorf("invalid https proxy: %v", err)
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", ".svc", ".cluster.local", "." + cluster.Namespace, constants.DefImageRepository}
+	hosts = append(hosts, cluster.GatewayIngressIPs()...)
+	hosts = append(hosts, config.NoProxy...)
+	hosts = append(hosts, noProxy...)
+	return &Proxy{
+		HTTPProxy:  httpProxy,
+		HTTPSProxy: httpsProxy,
+		NoProxy:    strings.Join(uniqueHosts(hosts), ","),
+	}, nil
+}
+
+// ProxyFunc returns the function for the Proxy of http.Transport, which uses the environment if p is nil.
+func (p *Proxy) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	if p == nil {
+		return http.ProxyFromEnvironment
+	}
+	config := &httpproxy.Config{
+		HTTPProxy:  p.HTTPProxy,
+		HTTPSProxy: p.HTTPSProxy,
+		NoProxy:    p.NoProxy,
+	}
+	proxy := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// Client returns a http client with the proxy.
+func (p *Proxy) Client() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = p.ProxyFunc()
+	return &http.Client{Transport: transport}
+}
+
+// SecretData returns the proxy environment variables as the data of SecretName.
+func (p *Proxy) SecretData() map[string][]byte {
+	data := make(map[string][]byte)
+	for _, name := range envNames {
+		var value string
+		switch strings.ToUpper(name) {
+		case "HTTP_PROXY":
+			value = p.HTTPProxy
+		case "HTTPS_PROXY":
+			value = p.HTTPSProxy
+		case "NO_PROXY":
+			value = p.NoProxy
+		}
+		data[name] = []byte(value)
+	}
+	return data
+}
+
+// EnvVars returns the proxy environment variables from SecretName for the containers.
+func EnvVars() []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, name := range envNames {
+		env = append(env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: SecretName},
+					Key:                  name,
+					Optional:             commonutil.Bool(true),
+				},
+			},
+		})
+	}
+	return env
+}
+
+// withUser adds the user to the proxy url, the scheme defaults to http.
+func withUser(proxy string, user *url.Userinfo) (string, error) {
+	if proxy == "" {
+		return "", nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("no host in %s", proxy)
+	}
+	if user != nil {
+		u.User = user
+	}
+	return u.String(), nil
+}
+
+func uniqueHosts(hosts []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		result = append(result, host)
+	}
+	return result
+}
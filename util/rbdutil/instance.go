@@ -0,0 +1,38 @@
+package rbdutil
+
+import (
+	"fmt"
+
+	"github.com/goodrain/rainbond-operator/util/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InstanceName returns the name of the cluster-scoped resource of the rainbond installed in the namespace.
+// The name is kept in the default namespace for compatibility, and suffixed with the namespace in the others,
+// so that the rainbonds installed in different namespaces of a cluster don't collide.
+func InstanceName(name, namespace string) string {
+	if namespace == "" || namespace == constants.Namespace {
+		return name
+	}
+	return name + "-" + namespace
+}
+
+// LabelsForInstance returns a copy of the labels, with the instance label of the rainbond installed in the namespace.
+func LabelsForInstance(labels map[string]string, namespace string) map[string]string {
+	result := make(map[string]string, len(labels)+1)
+	for key, val := range labels {
+		result[key] = val
+	}
+	result[constants.InstanceLabelKey] = namespace
+	return result
+}
+
+// CheckInstance returns an error if the cluster-scoped resource belongs to the rainbond installed in another namespace.
+// The resources without the instance label, which are created by the former versions, are not checked.
+func CheckInstance(obj metav1.Object, namespace string) error {
+	instance, ok := obj.GetLabels()[constants.InstanceLabelKey]
+	if !ok || instance == namespace {
+		return nil
+	}
+	return fmt.Errorf("%s belongs to the rainbond in namespace %s", obj.GetName(), instance)
+}
@@ -1,9 +1,11 @@
 package rbdutil
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"path"
+	"regexp"
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/goodrain/rainbond-operator/util/constants"
@@ -48,14 +50,19 @@ func LabelsForAccessModeRWO() map[string]string {
 	}
 }
 
+// GatewayPorts are the ports used by rbd-gateway itself.
+var GatewayPorts = []int{80, 443, 10254, 18080, 18081, 8443, 6060, 7070}
+
+// NodePortRange is the default service node port range of kubernetes.
+var NodePortRange = rainbondv1alpha1.PortRange{Start: 30000, End: 32767}
+
 // FilterNodesWithPortConflicts -
 func FilterNodesWithPortConflicts(nodes []*rainbondv1alpha1.K8sNode) []*rainbondv1alpha1.K8sNode {
 	var result []*rainbondv1alpha1.K8sNode
-	gatewayPorts := []int{80, 443, 10254, 18080, 18081, 8443, 6060, 7070}
 	for idx := range nodes {
 		node := nodes[idx]
 		ok := true
-		for _, port := range gatewayPorts {
+		for _, port := range GatewayPorts {
 			if isPortOccupied(fmt.Sprintf("%s:%d", node.InternalIP, port)) {
 				ok = false
 				break
@@ -68,6 +75,67 @@ func FilterNodesWithPortConflicts(nodes []*rainbondv1alpha1.K8sNode) []*rainbond
 	return result
 }
 
+// MaxPortRangesPorts is the max number of the ports in the port ranges of rbd-gateway, since the ports are
+// enumerated in the Service and the NetworkPolicy of rbd-gateway.
+const MaxPortRangesPorts = 1000
+
+// ErrPortConflict is wrapped by the errors of ValidatePortRanges if a port range conflicts with other ports.
+var ErrPortConflict = errors.New("conflicts")
+
+// ValidatePortRanges checks if the port ranges are valid, and do not conflict with
+// the ports of rbd-gateway and the service node port range.
+func ValidatePortRanges(ranges []rainbondv1alpha1.PortRange) error {
+	var total int32
+	for i, r := range ranges {
+		protocol := r.GetProtocol()
+		if protocol != corev1.ProtocolTCP && protocol != corev1.ProtocolUDP {
+			return fmt.Errorf("port range %s: unsupported protocol %s", r, r.Protocol)
+		}
+		if r.Start < 1 || r.GetEnd() > 65535 || r.Start > r.GetEnd() {
+			return fmt.Errorf("port range %s: invalid range", r)
+		}
+		total += r.GetEnd() - r.Start + 1
+		if total > MaxPortRangesPorts {
+			return fmt.Errorf("the port ranges have more than %d ports", MaxPortRangesPorts)
+		}
+		for _, port := range GatewayPorts {
+			if int32(port) >= r.Start && int32(port) <= r.GetEnd() {
+				return fmt.Errorf("port range %s: %w with port %d of rbd-gateway", r, ErrPortConflict, port)
+			}
+		}
+		if r.Start <= NodePortRange.End && r.GetEnd() >= NodePortRange.Start {
+			return fmt.Errorf("port range %s: %w with the service node port range %d-%d", r, ErrPortConflict, NodePortRange.Start, NodePortRange.End)
+		}
+		for _, other := range ranges[:i] {
+			if other.GetProtocol() == protocol && r.Start <= other.GetEnd() && r.GetEnd() >= other.Start {
+				return fmt.Errorf("port range %s: %w with %s", r, ErrPortConflict, other)
+			}
+		}
+	}
+	return nil
+}
+
+// sysctlNameRegexp is the format of the sysctl names, such as net.core.somaxconn.
+var sysctlNameRegexp = regexp.MustCompile(`^[a-z0-9]([-_a-z0-9]*[a-z0-9])?([./][a-z0-9]([-_a-z0-9]*[a-z0-9])?)*$`)
+
+// ValidateSysctls checks if the names of the sysctls are valid and not duplicated.
+func ValidateSysctls(sysctls []corev1.Sysctl) error {
+	names := make(map[string]struct{})
+	for _, sysctl := range sysctls {
+		if !sysctlNameRegexp.MatchString(sysctl.Name) {
+			return fmt.Errorf("sysctl %s: invalid name", sysctl.Name)
+		}
+		if _, ok := names[sysctl.Name]; ok {
+			return fmt.Errorf("sysctl %s: duplicated", sysctl.Name)
+		}
+		names[sysctl.Name] = struct{}{}
+		if sysctl.Value == "" {
+			return fmt.Errorf("sysctl %s: empty value", sysctl.Name)
+		}
+	}
+	return nil
+}
+
 func isPortOccupied(address string) bool {
 	conn, err := net.Dial("tcp", address)
 	if err != nil {
@@ -0,0 +1,160 @@
+package rbdutil
+
+import (
+	"errors"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidatePortRanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		ranges       []rainbondv1alpha1.PortRange
+		wantErr      bool
+		wantConflict bool
+	}{
+		{
+			name: "valid",
+			ranges: []rainbondv1alpha1.PortRange{
+				{Start: 20000, End: 20499},
+				{Protocol: "UDP", Start: 20000, End: 20499},
+			},
+		},
+		{
+			name: "too many ports",
+			ranges: []rainbondv1alpha1.PortRange{
+				{Start: 20000, End: 20999},
+				{Protocol: "UDP", Start: 20000},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid range",
+			ranges:  []rainbondv1alpha1.PortRange{{Start: 20000, End: 10000}},
+			wantErr: true,
+		},
+		{
+			name:         "gateway port",
+			ranges:       []rainbondv1alpha1.PortRange{{Start: 7000, End: 8000}},
+			wantErr:      true,
+			wantConflict: true,
+		},
+		{
+			name:         "node port range",
+			ranges:       []rainbondv1alpha1.PortRange{{Start: 29000, End: 30001}},
+			wantErr:      true,
+			wantConflict: true,
+		},
+		{
+			name: "overlap",
+			ranges: []rainbondv1alpha1.PortRange{
+				{Start: 20000, End: 21000},
+				{Protocol: "TCP", Start: 21000, End: 22000},
+			},
+			wantErr:      true,
+			wantConflict: true,
+		},
+		{
+			name:    "unsupported protocol",
+			ranges:  []rainbondv1alpha1.PortRange{{Protocol: "SCTP", Start: 20000}},
+			wantErr: true,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePortRanges(tc.ranges)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("want error: %v, but got %v", tc.wantErr, err)
+			}
+			if errors.Is(err, ErrPortConflict) != tc.wantConflict {
+				t.Errorf("want conflict: %v, but got %v", tc.wantConflict, err)
+			}
+		})
+	}
+}
+
+func TestValidateSysctls(t *testing.T) {
+	tests := []struct {
+		name    string
+		sysctls []corev1.Sysctl
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			sysctls: []corev1.Sysctl{
+				{Name: "net.core.somaxconn", Value: "65535"},
+				{Name: "net.ipv4.ip_local_port_range", Value: "1024 65000"},
+				{Name: "net/netfilter/nf_conntrack_max", Value: "1048576"},
+			},
+		},
+		{
+			name:    "invalid name",
+			sysctls: []corev1.Sysctl{{Name: "net.core.somaxconn;reboot", Value: "1"}},
+			wantErr: true,
+		},
+		{
+			name: "duplicated",
+			sysctls: []corev1.Sysctl{
+				{Name: "net.core.somaxconn", Value: "1024"},
+				{Name: "net.core.somaxconn", Value: "65535"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "empty value",
+			sysctls: []corev1.Sysctl{{Name: "net.core.somaxconn"}},
+			wantErr: true,
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSysctls(tc.sysctls)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("want error: %v, but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceAccountName(t *testing.T) {
+	defer SetServiceAccountName(ServiceAccountName())
+
+	SetServiceAccountName("")
+	if got := ServiceAccountName(); got != "rainbond-operator" {
+		t.Errorf("ServiceAccountName() = %s, want rainbond-operator", got)
+	}
+	SetServiceAccountName("rbd-operator")
+	if got := ServiceAccountName(); got != "rbd-operator" {
+		t.Errorf("ServiceAccountName() = %s, want rbd-operator", got)
+	}
+}
+
+func TestInstanceName(t *testing.T) {
+	if got := InstanceName("rbd-db", "rbd-system"); got != "rbd-db" {
+		t.Errorf("InstanceName() = %s, want rbd-db", got)
+	}
+	if got := InstanceName("rbd-db", "region-b"); got != "rbd-db-region-b" {
+		t.Errorf("InstanceName() = %s, want rbd-db-region-b", got)
+	}
+}
+
+func TestCheckInstance(t *testing.T) {
+	obj := &corev1.PersistentVolume{}
+	obj.Name = "rbd-db"
+	if err := CheckInstance(obj, "region-b"); err != nil {
+		t.Errorf("want no error for the resource without the instance label, but got %v", err)
+	}
+	obj.Labels = LabelsForInstance(nil, "region-a")
+	if err := CheckInstance(obj, "region-a"); err != nil {
+		t.Errorf("want no error for the resource of the same instance, but got %v", err)
+	}
+	if err := CheckInstance(obj, "region-b"); err == nil {
+		t.Errorf("want error for the resource of another instance")
+	}
+}
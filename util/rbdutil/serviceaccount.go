@@ -0,0 +1,20 @@
+package rbdutil
+
+import "github.com/goodrain/rainbond-operator/util/constants"
+
+// serviceAccountName is the name of the service account of the operator.
+var serviceAccountName = constants.ServiceAccountName
+
+// ServiceAccountName returns the name of the service account of the operator, which is used by the components
+// and plugins without their own service account.
+func ServiceAccountName() string {
+	return serviceAccountName
+}
+
+// SetServiceAccountName sets the name of the service account of the operator.
+// It is called once at startup, before the controllers start.
+func SetServiceAccountName(name string) {
+	if name != "" {
+		serviceAccountName = name
+	}
+}
@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Result is the result of the installation.
+type Result string
+
+const (
+	// ResultSuccess means rainbond is installed successfully.
+	ResultSuccess Result = "success"
+	// ResultFailure means rainbond failed to be installed.
+	ResultFailure Result = "failure"
+)
+
+// Report is the anonymous report of an installation. It contains no
+// information that can identify the user, such as ip addresses or domains.
+type Report struct {
+	// InstallID is the hash of the uid of the rainbondcluster.
+	InstallID         string            `json:"install_id"`
+	Result            Result            `json:"result"`
+	InstallVersion    string            `json:"install_version"`
+	InstallMode       string            `json:"install_mode"`
+	KubernetesVersion string            `json:"kubernetes_version"`
+	ComponentVersions map[string]string `json:"component_versions,omitempty"`
+	// FailureReasons are the reasons of the terminal failures of the installation, such as
+	// UnsupportedKubernetesVersion, which are the codes of the conditions rather than the messages.
+	FailureReasons []string `json:"failure_reasons,omitempty"`
+}
+
+// Send sends the report to the endpoint.
+func Send(ctx context.Context, endpoint string, report *Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}